@@ -12,9 +12,11 @@ require (
 	github.com/gorilla/handlers v1.5.1
 	github.com/gorilla/mux v1.8.0
 	github.com/jmoiron/sqlx v1.3.3
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/liamylian/jsontime/v2 v2.0.0
 	github.com/lib/pq v1.2.0
 	github.com/myesui/uuid v1.0.0 // indirect
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.7.0
 	github.com/twinj/uuid v1.0.0
 	golang.org/x/crypto v0.0.0-20210415154028-4f45737414dc
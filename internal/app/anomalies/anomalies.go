@@ -0,0 +1,239 @@
+// Package anomalies finds the health signals vets want to be flagged in the pet statistics
+package anomalies
+
+import (
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"sort"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+const (
+	// WeightWindow is the period the latest weight is compared with the peak weight over
+	WeightWindow = 30 * day
+	// WeightLossWarning and WeightLossCritical are the shares of the peak weight lost
+	WeightLossWarning  = 0.05
+	WeightLossCritical = 0.10
+
+	// IntakeDays is the count of the last complete days the food intake is compared with MER over,
+	// the signal needs MinIntakeDays of them on the same side of the thresholds
+	IntakeDays    = 7
+	MinIntakeDays = 5
+	// IntakeHighRatio and IntakeLowRatio are the thresholds of the intake to MER ratio of the day,
+	// the critical ones are applied to the mean ratio of the window. MER already has the life stage,
+	// neuter status and goal factors, bare RER is below the usual intake of most pets.
+	IntakeHighRatio         = 1.25
+	IntakeLowRatio          = 0.75
+	IntakeHighCriticalRatio = 1.5
+	IntakeLowCriticalRatio  = 0.5
+
+	// ActivityDays is the count of the last complete days compared with the baseline of BaselineDays before them.
+	// Days without activity records are unknown rather than idle, the collar may be off.
+	ActivityDays      = 7
+	BaselineDays      = 28
+	MinActivityDays   = 4
+	MinBaselineDays   = 14
+	ActivityDropRatio = 0.5
+	// ActivityDropCriticalRatio is the share of the baseline distance below which the drop is critical
+	ActivityDropCriticalRatio = 0.25
+)
+
+// Statistics is what PetRepository.GetPetStatistics returns
+type Statistics struct {
+	FoodCalories  []models.FoodCaloriesReport
+	RERCalories   []models.RERCaloriesReport
+	Anthropometry []models.AnthropometryReport
+	Activity      []models.ActivityReport
+}
+
+// Signal is the anomaly found, it becomes the pet alert
+type Signal struct {
+	Kind     string
+	Severity string
+	Value    float64
+	Baseline float64
+	Message  string
+}
+
+// Alert is the alert of the pet raised by the signal
+func (s *Signal) Alert(petID int) models.HealthAlert {
+	return models.HealthAlert{
+		PetID:    petID,
+		Kind:     s.Kind,
+		Severity: s.Severity,
+		Message:  s.Message,
+		Value:    s.Value,
+		Baseline: s.Baseline,
+	}
+}
+
+// Analyze returns the signals found in the statistics, today is the day of the analysis and is not complete yet
+func Analyze(statistics *Statistics, today time.Time) []Signal {
+	today = today.UTC().Truncate(day)
+	signals := make([]Signal, 0)
+	if signal := weightLoss(statistics.Anthropometry, today); signal != nil {
+		signals = append(signals, *signal)
+	}
+	if signal := intake(statistics.FoodCalories, statistics.RERCalories, today); signal != nil {
+		signals = append(signals, *signal)
+	}
+	if signal := activityDrop(statistics.Activity, today); signal != nil {
+		signals = append(signals, *signal)
+	}
+	return signals
+}
+
+// weightLoss compares the latest weight with the peak weight of WeightWindow before it,
+// the latest weight older than WeightWindow is stale and tells nothing
+func weightLoss(records []models.AnthropometryReport, today time.Time) *Signal {
+	if len(records) < 2 {
+		return nil
+	}
+	sorted := append([]models.AnthropometryReport(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+	latest := sorted[len(sorted)-1]
+	if latest.Date.Before(today.Add(-WeightWindow)) || latest.Weight <= 0 {
+		return nil
+	}
+
+	peak := 0.0
+	for _, record := range sorted[:len(sorted)-1] {
+		if !record.Date.Before(latest.Date.Add(-WeightWindow)) && record.Weight > peak {
+			peak = record.Weight
+		}
+	}
+	if peak <= 0 {
+		return nil
+	}
+	loss := (peak - latest.Weight) / peak
+	if loss < WeightLossWarning {
+		return nil
+	}
+	severity := models.AlertSeverityWarning
+	if loss >= WeightLossCritical {
+		severity = models.AlertSeverityCritical
+	}
+	return &Signal{
+		Kind:     models.AlertWeightLoss,
+		Severity: severity,
+		Value:    latest.Weight,
+		Baseline: peak,
+		Message: fmt.Sprintf(
+			"Weight dropped by %.1f%% from %.2f to %.2f within %d days",
+			loss*100, peak, latest.Weight, int(WeightWindow/day),
+		),
+	}
+}
+
+// intake compares the daily food calories with MER of the weight known on that day
+func intake(food []models.FoodCaloriesReport, requirements []models.RERCaloriesReport, today time.Time) *Signal {
+	merSorted := append([]models.RERCaloriesReport(nil), requirements...)
+	sort.SliceStable(merSorted, func(i, j int) bool { return merSorted[i].Date.Before(merSorted[j].Date) })
+	merOn := func(date time.Time) float64 {
+		value := 0.0
+		for _, record := range merSorted {
+			if record.Date.After(date) {
+				break
+			}
+			value = record.MERTotalCalories
+		}
+		return value
+	}
+
+	start := today.Add(-IntakeDays * day)
+	var days, above, below int
+	var totalFood, totalMER float64
+	for _, record := range food {
+		date := record.Date.UTC().Truncate(day)
+		if date.Before(start) || !date.Before(today) {
+			continue
+		}
+		dayMER := merOn(date)
+		if dayMER <= 0 {
+			continue
+		}
+		days++
+		totalFood += record.FoodTotalCalories
+		totalMER += dayMER
+		switch ratio := record.FoodTotalCalories / dayMER; {
+		case ratio > IntakeHighRatio:
+			above++
+		case ratio < IntakeLowRatio:
+			below++
+		}
+	}
+	if days < MinIntakeDays {
+		return nil
+	}
+
+	meanFood, meanMER := totalFood/float64(days), totalMER/float64(days)
+	ratio := meanFood / meanMER
+	signal := &Signal{Value: meanFood, Baseline: meanMER, Severity: models.AlertSeverityWarning}
+	switch {
+	case above >= MinIntakeDays:
+		signal.Kind = models.AlertIntakeAboveMER
+		if ratio > IntakeHighCriticalRatio {
+			signal.Severity = models.AlertSeverityCritical
+		}
+		signal.Message = fmt.Sprintf(
+			"Food intake was above the energy requirement on %d of the last %d days, %.0f kcal a day against %.0f kcal",
+			above, IntakeDays, meanFood, meanMER,
+		)
+	case below >= MinIntakeDays:
+		signal.Kind = models.AlertIntakeBelowMER
+		if ratio < IntakeLowCriticalRatio {
+			signal.Severity = models.AlertSeverityCritical
+		}
+		signal.Message = fmt.Sprintf(
+			"Food intake was below the energy requirement on %d of the last %d days, %.0f kcal a day against %.0f kcal",
+			below, IntakeDays, meanFood, meanMER,
+		)
+	default:
+		return nil
+	}
+	return signal
+}
+
+// activityDrop compares the mean daily distance of the last days with the rolling baseline before them
+func activityDrop(records []models.ActivityReport, today time.Time) *Signal {
+	recentStart := today.Add(-ActivityDays * day)
+	baselineStart := recentStart.Add(-BaselineDays * day)
+	var recentDays, baselineDays int
+	var recentDistance, baselineDistance float64
+	for _, record := range records {
+		date := record.Date.UTC().Truncate(day)
+		switch {
+		case !date.Before(recentStart) && date.Before(today):
+			recentDays++
+			recentDistance += record.TotalDistance
+		case !date.Before(baselineStart) && date.Before(recentStart):
+			baselineDays++
+			baselineDistance += record.TotalDistance
+		}
+	}
+	if recentDays < MinActivityDays || baselineDays < MinBaselineDays || baselineDistance <= 0 {
+		return nil
+	}
+
+	recent, baseline := recentDistance/float64(recentDays), baselineDistance/float64(baselineDays)
+	ratio := recent / baseline
+	if ratio >= ActivityDropRatio {
+		return nil
+	}
+	severity := models.AlertSeverityWarning
+	if ratio < ActivityDropCriticalRatio {
+		severity = models.AlertSeverityCritical
+	}
+	return &Signal{
+		Kind:     models.AlertActivityDrop,
+		Severity: severity,
+		Value:    recent,
+		Baseline: baseline,
+		Message: fmt.Sprintf(
+			"Activity dropped by %.0f%%, %.0f m a day over the last %d days against %.0f m a day before",
+			(1-ratio)*100, recent, ActivityDays, baseline,
+		),
+	}
+}
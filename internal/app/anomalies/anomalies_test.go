@@ -0,0 +1,92 @@
+package anomalies
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var today = time.Date(2026, 6, 1, 9, 30, 0, 0, time.UTC)
+
+func daysAgo(days int) time.Time {
+	return today.Truncate(day).AddDate(0, 0, -days)
+}
+
+func TestAnalyze_WeightLoss(t *testing.T) {
+	statistics := &Statistics{Anthropometry: []models.AnthropometryReport{
+		{Date: daysAgo(2), Weight: 10.7},
+		{Date: daysAgo(60), Weight: 14},
+		{Date: daysAgo(20), Weight: 12},
+	}}
+	signals := Analyze(statistics, today)
+	require.Len(t, signals, 1)
+	assert.Equal(t, models.AlertWeightLoss, signals[0].Kind)
+	assert.Equal(t, models.AlertSeverityCritical, signals[0].Severity)
+	assert.Equal(t, 10.7, signals[0].Value)
+	assert.Equal(t, 12.0, signals[0].Baseline)
+
+	statistics.Anthropometry[0].Weight = 11.3
+	signals = Analyze(statistics, today)
+	require.Len(t, signals, 1)
+	assert.Equal(t, models.AlertSeverityWarning, signals[0].Severity)
+
+	statistics.Anthropometry[0].Weight = 11.8
+	assert.Empty(t, Analyze(statistics, today))
+
+	// The latest weight is stale
+	statistics.Anthropometry = []models.AnthropometryReport{{Date: daysAgo(70), Weight: 12}, {Date: daysAgo(40), Weight: 9}}
+	assert.Empty(t, Analyze(statistics, today))
+}
+
+func TestAnalyze_Intake(t *testing.T) {
+	statistics := &Statistics{RERCalories: []models.RERCaloriesReport{
+		{Date: daysAgo(30), MERTotalCalories: 500},
+		{Date: daysAgo(4), RERTotalCalories: 300, MERTotalCalories: 400},
+	}}
+	for days := 1; days <= IntakeDays; days++ {
+		statistics.FoodCalories = append(statistics.FoodCalories, models.FoodCaloriesReport{Date: daysAgo(days), FoodTotalCalories: 660})
+	}
+	// Today is not complete and is not analyzed
+	statistics.FoodCalories = append(statistics.FoodCalories, models.FoodCaloriesReport{Date: daysAgo(0), FoodTotalCalories: 10})
+
+	signals := Analyze(statistics, today)
+	require.Len(t, signals, 1)
+	assert.Equal(t, models.AlertIntakeAboveMER, signals[0].Kind)
+	assert.Equal(t, models.AlertSeverityWarning, signals[0].Severity)
+	assert.Equal(t, 660.0, signals[0].Value)
+
+	for idx := 0; idx < IntakeDays; idx++ {
+		statistics.FoodCalories[idx].FoodTotalCalories = 150
+	}
+	signals = Analyze(statistics, today)
+	require.Len(t, signals, 1)
+	assert.Equal(t, models.AlertIntakeBelowMER, signals[0].Kind)
+	assert.Equal(t, models.AlertSeverityCritical, signals[0].Severity)
+
+	// Too few logged days
+	statistics.FoodCalories = statistics.FoodCalories[:MinIntakeDays-1]
+	assert.Empty(t, Analyze(statistics, today))
+}
+
+func TestAnalyze_ActivityDrop(t *testing.T) {
+	statistics := &Statistics{}
+	for days := ActivityDays + 1; days <= ActivityDays+BaselineDays; days++ {
+		statistics.Activity = append(statistics.Activity, models.ActivityReport{Date: daysAgo(days), TotalDistance: 4000})
+	}
+	for days := 1; days <= MinActivityDays; days++ {
+		statistics.Activity = append(statistics.Activity, models.ActivityReport{Date: daysAgo(days), TotalDistance: 1500})
+	}
+
+	signals := Analyze(statistics, today)
+	require.Len(t, signals, 1)
+	assert.Equal(t, models.AlertActivityDrop, signals[0].Kind)
+	assert.Equal(t, models.AlertSeverityWarning, signals[0].Severity)
+	assert.Equal(t, 1500.0, signals[0].Value)
+	assert.Equal(t, 4000.0, signals[0].Baseline)
+
+	// Days without records are unknown, so fewer recorded days do not raise the alert
+	statistics.Activity = statistics.Activity[:len(statistics.Activity)-1]
+	assert.Empty(t, Analyze(statistics, today))
+}
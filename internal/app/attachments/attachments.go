@@ -0,0 +1,74 @@
+// Package attachments checks the files uploaded to the pets and their health reports and makes the image thumbnails
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	TypeJPEG  = "image/jpeg"
+	TypePNG   = "image/png"
+	TypeGIF   = "image/gif"
+	TypeWebP  = "image/webp"
+	TypePDF   = "application/pdf"
+	TypeDICOM = "application/dicom"
+
+	// SniffLength is how much of the file beginning the content type is detected by
+	SniffLength = 512
+
+	MaxImageSize    = 10 << 20
+	MaxDocumentSize = 50 << 20
+)
+
+var (
+	ErrUnsupportedType = errors.New("file type is not supported, images, PDF and DICOM files are accepted")
+	ErrTooLarge        = errors.New("file is too large for its type")
+)
+
+// limits are the accepted content types with their size limits, the X-ray DICOM files are the largest
+var limits = map[string]int64{
+	TypeJPEG:  MaxImageSize,
+	TypePNG:   MaxImageSize,
+	TypeGIF:   MaxImageSize,
+	TypeWebP:  MaxImageSize,
+	TypePDF:   MaxDocumentSize,
+	TypeDICOM: MaxDocumentSize,
+}
+
+// dicomMagic follows the 128 bytes preamble of the DICOM file
+var dicomMagic = []byte("DICM")
+
+/*
+Sniff detects the content type by the beginning of the file, the type declared by the client is not trusted.
+The file is checked against the type limits, so the stored type is always the one it is served with.
+*/
+func Sniff(head []byte, size int64) (string, error) {
+	contentType := http.DetectContentType(head)
+	if idx := strings.IndexByte(contentType, ';'); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	if len(head) >= 132 && bytes.Equal(head[128:132], dicomMagic) {
+		contentType = TypeDICOM
+	}
+	limit, ok := limits[contentType]
+	if !ok {
+		return "", ErrUnsupportedType
+	}
+	if size > limit {
+		return "", ErrTooLarge
+	}
+	return contentType, nil
+}
+
+// HasThumbnail reports whether the thumbnail can be made of the content type, WebP can not be decoded
+func HasThumbnail(contentType string) bool {
+	return contentType == TypeJPEG || contentType == TypePNG || contentType == TypeGIF
+}
+
+// IsInline reports whether the browsers can show the content type instead of downloading it
+func IsInline(contentType string) bool {
+	return contentType != TypeDICOM
+}
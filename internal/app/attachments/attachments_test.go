@@ -0,0 +1,70 @@
+package attachments
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, img))
+	return encoded.Bytes()
+}
+
+func TestSniff(t *testing.T) {
+	photo := encodePNG(t, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	contentType, err := Sniff(photo, int64(len(photo)))
+	assert.NoError(t, err)
+	assert.Equal(t, TypePNG, contentType)
+	_, err = Sniff(photo, MaxImageSize+1)
+	assert.Equal(t, ErrTooLarge, err)
+
+	contentType, err = Sniff([]byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n"), MaxImageSize+1)
+	assert.NoError(t, err)
+	assert.Equal(t, TypePDF, contentType)
+
+	xRay := append(make([]byte, 128), []byte("DICM\x02\x00\x00\x00UL")...)
+	contentType, err = Sniff(xRay, MaxDocumentSize)
+	assert.NoError(t, err)
+	assert.Equal(t, TypeDICOM, contentType)
+
+	// The declared type does not matter, the script is not accepted as the image
+	_, err = Sniff([]byte("<html><script>alert(1)</script></html>"), 38)
+	assert.Equal(t, ErrUnsupportedType, err)
+}
+
+func TestThumbnail(t *testing.T) {
+	transparent := image.NewNRGBA(image.Rect(0, 0, 1000, 500))
+	for x := 0; x < 500; x++ {
+		for y := 0; y < 500; y++ {
+			transparent.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	thumbnail, err := Thumbnail(bytes.NewReader(encodePNG(t, transparent)))
+	require.NoError(t, err)
+
+	decoded, err := jpeg.Decode(bytes.NewReader(thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, ThumbnailSize, ThumbnailSize/2), decoded.Bounds())
+	r, g, b, _ := decoded.At(ThumbnailSize/4, ThumbnailSize/4).RGBA()
+	assert.True(t, r>>8 > 240 && g>>8 < 16 && b>>8 < 16)
+	r, g, b, _ = decoded.At(ThumbnailSize*3/4, ThumbnailSize/4).RGBA()
+	assert.True(t, r>>8 > 240 && g>>8 > 240 && b>>8 > 240)
+
+	small := encodePNG(t, image.NewGray(image.Rect(0, 0, 40, 90)))
+	thumbnail, err = Thumbnail(bytes.NewReader(small))
+	require.NoError(t, err)
+	config, err := jpeg.DecodeConfig(bytes.NewReader(thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, 40, config.Width)
+	assert.Equal(t, 90, config.Height)
+
+	_, err = Thumbnail(bytes.NewReader([]byte("%PDF-1.7")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const (
+	// ThumbnailSize is the longest side of the thumbnail
+	ThumbnailSize = 320
+	// ThumbnailType is the content type of the thumbnails
+	ThumbnailType    = TypeJPEG
+	thumbnailQuality = 80
+	// maxImagePixels keeps the decoded image within the memory, the small file may claim huge dimensions
+	maxImagePixels = 40000000
+)
+
+var ErrImageTooLarge = errors.New("image dimensions are too large")
+
+// Thumbnail scales the image down to fit the thumbnail size, the transparent parts are made white
+func Thumbnail(content io.ReadSeeker) ([]byte, error) {
+	config, _, err := image.DecodeConfig(content)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	source, _, err := image.Decode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := source.Bounds()
+	flattened := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flattened, flattened.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), source, bounds.Min, draw.Over)
+
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, scaleDown(flattened, ThumbnailSize), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+// scaleDown fits the image into the square of the size averaging the source pixels, smaller images are kept
+func scaleDown(source *image.RGBA, size int) *image.RGBA {
+	width, height := source.Rect.Dx(), source.Rect.Dy()
+	if width <= size && height <= size {
+		return source
+	}
+	scaledWidth, scaledHeight := size, height*size/width
+	if height > width {
+		scaledWidth, scaledHeight = width*size/height, size
+	}
+	if scaledWidth < 1 {
+		scaledWidth = 1
+	}
+	if scaledHeight < 1 {
+		scaledHeight = 1
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	for y := 0; y < scaledHeight; y++ {
+		top, bottom := y*height/scaledHeight, (y+1)*height/scaledHeight
+		for x := 0; x < scaledWidth; x++ {
+			left, right := x*width/scaledWidth, (x+1)*width/scaledWidth
+			var sum [4]int
+			for sy := top; sy < bottom; sy++ {
+				offset := sy*source.Stride + left*4
+				for sx := left; sx < right; sx++ {
+					for channel := 0; channel < 4; channel++ {
+						sum[channel] += int(source.Pix[offset+channel])
+					}
+					offset += 4
+				}
+			}
+			count := (bottom - top) * (right - left)
+			offset := y*scaled.Stride + x*4
+			for channel := 0; channel < 4; channel++ {
+				scaled.Pix[offset+channel] = uint8(sum[channel] / count)
+			}
+		}
+	}
+	return scaled
+}
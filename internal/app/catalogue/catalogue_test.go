@@ -0,0 +1,147 @@
+package catalogue
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const catalogueCSV = `food_name,manufacturer,energy_per_100g,protein,fat,carbohydrate,moisture,barcodes,cup_grams
+Adult Chicken,Acme,370,26,16,40,10,4006381333931,95
+Puppy Lamb,Acme,,30,18,35,,036000291452;96385074,
+Senior Fish,Acme,high,,,,,,
+`
+
+func grams(value float64) *float64 {
+	return &value
+}
+
+func TestParseCSV(t *testing.T) {
+	entries, err := ParseCSV(strings.NewReader(catalogueCSV))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	adult := entries[0]
+	assert.Equal(t, 1, adult.Row)
+	assert.Equal(t, "Adult Chicken", adult.Food.FoodName)
+	assert.Equal(t, "Acme", adult.Food.SpecifiedManufacturer)
+	assert.Equal(t, 370.0, adult.Food.EnergyPer100g)
+	assert.Equal(t, 26.0, *adult.Food.SpecifiedProtein)
+	assert.Nil(t, adult.Food.SpecifiedFibre)
+	assert.Equal(t, []string{"4006381333931"}, adult.Food.Barcodes)
+	assert.Equal(t, []models.FoodPortionUnit{{Unit: models.UnitCup, Grams: 95}}, adult.Food.PortionUnits)
+	assert.Empty(t, adult.Errors)
+
+	assert.Equal(t, []string{"036000291452", "96385074"}, entries[1].Food.Barcodes)
+	assert.Nil(t, entries[1].Food.SpecifiedMoisture)
+	assert.Len(t, entries[2].Errors, 1)
+}
+
+func TestParseCSV_Malformed(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("food_name,sugar\nKibble,1\n"))
+	assert.EqualError(t, err, `catalogue: unknown column "sugar"`)
+	_, err = ParseCSV(strings.NewReader("manufacturer\nAcme\n"))
+	assert.Equal(t, ErrNoFoodNameColumn, err)
+	_, err = ParseCSV(strings.NewReader("food_name\n"))
+	assert.Equal(t, ErrEmpty, err)
+	_, err = ParseCSV(strings.NewReader("food_name,fat\nKibble\n"))
+	assert.Error(t, err)
+}
+
+func TestParseJSON(t *testing.T) {
+	entries, err := ParseJSON(strings.NewReader(`[
+		{"food_name": "Wet Beef", "manufacturer": "Acme", "protein": 10, "fat": 6, "carbohydrate": 4,
+		 "portion_units": [{"unit": "can", "grams": 400}], "barcodes": ["4006381333931"]}
+	]`))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 10.0, *entries[0].Food.SpecifiedProtein)
+	assert.Equal(t, models.UnitCan, entries[0].Food.PortionUnits[0].Unit)
+
+	_, err = ParseJSON(strings.NewReader(`[]`))
+	assert.Equal(t, ErrEmpty, err)
+	_, err = ParseJSON(strings.NewReader(`{"food_name": "Wet Beef"}`))
+	assert.Error(t, err)
+}
+
+func TestFormatOf(t *testing.T) {
+	format, err := FormatOf("text/csv; charset=utf-8", "")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	format, err = FormatOf("application/octet-stream", "acme.JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	_, err = FormatOf("application/xml", "acme.xml")
+	assert.Equal(t, ErrUnsupportedFormat, err)
+}
+
+func existingFoods() []models.Food {
+	food := models.Food{
+		FoodID:                7,
+		FoodName:              "Adult Chicken",
+		EnergyPer100g:         360,
+		SpecifiedProtein:      grams(25),
+		SpecifiedManufacturer: "ACME",
+		PortionUnits:          []models.FoodPortionUnit{{FoodID: 7, Unit: models.UnitCup, Grams: 95}},
+		Barcodes:              []string{"04006381333931"},
+	}
+	food.BeforeCreate()
+	food.AfterCreate()
+	return []models.Food{food}
+}
+
+func TestNewPlan(t *testing.T) {
+	entries := []Entry{
+		{Row: 1, Food: models.Food{FoodName: "adult chicken ", SpecifiedManufacturer: "Acme", EnergyPer100g: 370, SpecifiedFat: grams(16),
+			PortionUnits: []models.FoodPortionUnit{{Unit: models.UnitCan, Grams: 400}}}},
+		{Row: 2, Food: models.Food{FoodName: "Puppy Lamb", SpecifiedManufacturer: "Acme", SpecifiedProtein: grams(30), SpecifiedFat: grams(18),
+			SpecifiedCarbohydrate: grams(35), Barcodes: []string{"036000291452"}}},
+		{Row: 3, Food: models.Food{FoodName: "Puppy Lamb", SpecifiedManufacturer: "acme", EnergyPer100g: 380}},
+		{Row: 4, Food: models.Food{FoodName: "Senior Fish", SpecifiedManufacturer: "Acme", EnergyPer100g: 340, Barcodes: []string{"4006381333931"}}},
+		{Row: 5, Food: models.Food{FoodName: "Kitten Duck", EnergyPer100g: 400}, Errors: []string{"fat: \"x\" is not a number"}},
+	}
+	plan := NewPlan(entries, existingFoods(), map[string]int{"04006381333931": 7})
+	assert.False(t, plan.IsValid())
+
+	report := plan.Report(true)
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 3, report.Invalid)
+
+	assert.Equal(t, ActionUpdate, report.Rows[0].Action)
+	assert.Equal(t, 7, report.Rows[0].FoodID)
+	require.Len(t, plan.Updates, 1)
+	updated := plan.Updates[0]
+	assert.Equal(t, "Adult Chicken", updated.FoodName)
+	assert.Equal(t, 370.0, updated.EnergyPer100g)
+	assert.Equal(t, 25.0, *updated.SpecifiedProtein)
+	assert.Equal(t, 16.0, updated.Fat.Float64)
+	assert.Len(t, updated.PortionUnits, 2)
+	assert.Equal(t, []string{"04006381333931"}, updated.Barcodes)
+
+	assert.Equal(t, ActionCreate, report.Rows[1].Action)
+	require.Len(t, plan.Creates, 1)
+	assert.InDelta(t, 380.5, plan.Creates[0].EnergyPer100g, 0.001)
+	assert.Equal(t, []string{"00036000291452"}, plan.Creates[0].Barcodes)
+
+	assert.Equal(t, []string{"duplicates the food of row 2"}, report.Rows[2].Errors)
+	assert.Equal(t, []string{"barcode 04006381333931 belongs to food 7"}, report.Rows[3].Errors)
+	assert.Equal(t, ActionInvalid, report.Rows[4].Action)
+
+	plan.Creates[0].FoodID = 12
+	assert.Equal(t, 12, plan.Report(false).Rows[1].FoodID)
+}
+
+func TestNewPlan_Unchanged(t *testing.T) {
+	entries := []Entry{
+		{Row: 1, Food: models.Food{FoodName: "Adult Chicken", SpecifiedManufacturer: "Acme", EnergyPer100g: 360,
+			Barcodes: []string{"4006381333931"}, PortionUnits: []models.FoodPortionUnit{{Unit: models.UnitCup, Grams: 95}}}},
+	}
+	plan := NewPlan(entries, existingFoods(), map[string]int{"04006381333931": 7})
+	assert.True(t, plan.IsValid())
+	assert.Equal(t, 1, plan.Report(true).Unchanged)
+	assert.Empty(t, plan.Updates)
+}
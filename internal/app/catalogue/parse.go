@@ -0,0 +1,257 @@
+/*
+Package catalogue imports the food catalogues of the manufacturers from CSV and JSON files.
+
+The foods of the catalogue are matched with the existing ones by name and manufacturer, case insensitively.
+Matched foods get the values the catalogue specifies and keep the rest, the other foods are created.
+*/
+package catalogue
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"io"
+	"mime"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Formats of the catalogue files
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+const (
+	MaxEntries  = 5000
+	MaxFileSize = 10 << 20
+)
+
+// Columns of the CSV catalogue, the grams of the portion units are in <unit>_grams columns, e.g. cup_grams
+const (
+	ColumnFoodName      = "food_name"
+	ColumnManufacturer  = "manufacturer"
+	ColumnDescription   = "description"
+	ColumnEnergyPer100g = "energy_per_100g"
+	ColumnBarcodes      = "barcodes"
+
+	unitColumnSuffix = "_grams"
+)
+
+var (
+	ErrEmpty             = errors.New("catalogue: no foods in the file")
+	ErrTooManyEntries    = fmt.Errorf("catalogue: more than %d foods in the file", MaxEntries)
+	ErrNoFoodNameColumn  = errors.New("catalogue: food_name column is required")
+	ErrUnsupportedFormat = errors.New("catalogue: file must be CSV or JSON")
+)
+
+var nutrientColumns = map[string]func(food *models.Food) **float64{
+	models.NutrientProtein:      func(food *models.Food) **float64 { return &food.SpecifiedProtein },
+	models.NutrientFat:          func(food *models.Food) **float64 { return &food.SpecifiedFat },
+	models.NutrientCarbohydrate: func(food *models.Food) **float64 { return &food.SpecifiedCarbohydrate },
+	models.NutrientFibre:        func(food *models.Food) **float64 { return &food.SpecifiedFibre },
+	models.NutrientMoisture:     func(food *models.Food) **float64 { return &food.SpecifiedMoisture },
+	models.NutrientCalcium:      func(food *models.Food) **float64 { return &food.SpecifiedCalcium },
+	models.NutrientPhosphorus:   func(food *models.Food) **float64 { return &food.SpecifiedPhosphorus },
+}
+
+// Entry is the food at the row of the catalogue, rows start at 1 and the CSV header is not counted
+type Entry struct {
+	Row    int
+	Food   models.Food
+	Errors []string
+}
+
+// FormatOf is the format of the file by its content type, or by the extension when the type is generic
+func FormatOf(contentType string, fileName string) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return FormatCSV, nil
+	case "application/json":
+		return FormatJSON, nil
+	}
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+func Parse(format string, reader io.Reader) ([]Entry, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(reader)
+	case FormatJSON:
+		return ParseJSON(reader)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+/*
+ParseCSV reads the catalogue with the header row naming the columns. Empty cells are not specified values,
+several barcodes of a food are separated with semicolons. Values that are not numbers make the row invalid,
+malformed files and unknown columns fail the whole catalogue.
+*/
+func ParseCSV(reader io.Reader) ([]Entry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, ErrEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("catalogue: %w", err)
+	}
+	columns := make([]string, len(header))
+	hasFoodName := false
+	for idx, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !isKnownColumn(column) {
+			return nil, fmt.Errorf("catalogue: unknown column %q", column)
+		}
+		hasFoodName = hasFoodName || column == ColumnFoodName
+		columns[idx] = column
+	}
+	if !hasFoodName {
+		return nil, ErrNoFoodNameColumn
+	}
+
+	var entries []Entry
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("catalogue: %w", err)
+		}
+		if len(entries) == MaxEntries {
+			return nil, ErrTooManyEntries
+		}
+		entry := Entry{Row: len(entries) + 1}
+		for idx, value := range record {
+			if value = strings.TrimSpace(value); value != "" {
+				entry.set(columns[idx], value)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, ErrEmpty
+	}
+	return entries, nil
+}
+
+// ParseJSON reads the catalogue as the array of the foods in the format of the foods API
+func ParseJSON(reader io.Reader) ([]Entry, error) {
+	var foods []struct {
+		FoodName      string                   `json:"food_name"`
+		Manufacturer  string                   `json:"manufacturer"`
+		Description   string                   `json:"description"`
+		EnergyPer100g float64                  `json:"energy_per_100g"`
+		Protein       *float64                 `json:"protein"`
+		Fat           *float64                 `json:"fat"`
+		Carbohydrate  *float64                 `json:"carbohydrate"`
+		Fibre         *float64                 `json:"fibre"`
+		Moisture      *float64                 `json:"moisture"`
+		Calcium       *float64                 `json:"calcium"`
+		Phosphorus    *float64                 `json:"phosphorus"`
+		Barcodes      []string                 `json:"barcodes"`
+		PortionUnits  []models.FoodPortionUnit `json:"portion_units"`
+	}
+	if err := json.NewDecoder(reader).Decode(&foods); err != nil {
+		return nil, fmt.Errorf("catalogue: %w", err)
+	}
+	if len(foods) == 0 {
+		return nil, ErrEmpty
+	}
+	if len(foods) > MaxEntries {
+		return nil, ErrTooManyEntries
+	}
+
+	entries := make([]Entry, len(foods))
+	for idx, food := range foods {
+		entries[idx] = Entry{
+			Row: idx + 1,
+			Food: models.Food{
+				FoodName:              food.FoodName,
+				EnergyPer100g:         food.EnergyPer100g,
+				SpecifiedProtein:      food.Protein,
+				SpecifiedFat:          food.Fat,
+				SpecifiedCarbohydrate: food.Carbohydrate,
+				SpecifiedFibre:        food.Fibre,
+				SpecifiedMoisture:     food.Moisture,
+				SpecifiedCalcium:      food.Calcium,
+				SpecifiedPhosphorus:   food.Phosphorus,
+				SpecifiedDescription:  food.Description,
+				SpecifiedManufacturer: food.Manufacturer,
+				Barcodes:              food.Barcodes,
+				PortionUnits:          food.PortionUnits,
+			},
+		}
+	}
+	return entries, nil
+}
+
+func isKnownColumn(column string) bool {
+	switch column {
+	case ColumnFoodName, ColumnManufacturer, ColumnDescription, ColumnEnergyPer100g, ColumnBarcodes:
+		return true
+	}
+	if _, ok := nutrientColumns[column]; ok {
+		return true
+	}
+	if unit := strings.TrimSuffix(column, unitColumnSuffix); unit != column {
+		for _, portionUnit := range models.FoodPortionUnits {
+			if unit == portionUnit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *Entry) set(column string, value string) {
+	switch column {
+	case ColumnFoodName:
+		e.Food.FoodName = value
+		return
+	case ColumnManufacturer:
+		e.Food.SpecifiedManufacturer = value
+		return
+	case ColumnDescription:
+		e.Food.SpecifiedDescription = value
+		return
+	case ColumnBarcodes:
+		for _, code := range strings.Split(value, ";") {
+			if code = strings.TrimSpace(code); code != "" {
+				e.Food.Barcodes = append(e.Food.Barcodes, code)
+			}
+		}
+		return
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.Errors = append(e.Errors, fmt.Sprintf("%s: %q is not a number", column, value))
+		return
+	}
+	switch nutrient, isNutrient := nutrientColumns[column]; {
+	case column == ColumnEnergyPer100g:
+		e.Food.EnergyPer100g = number
+	case isNutrient:
+		*nutrient(&e.Food) = &number
+	default:
+		e.Food.PortionUnits = append(e.Food.PortionUnits, models.FoodPortionUnit{
+			Unit:  strings.TrimSuffix(column, unitColumnSuffix),
+			Grams: number,
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package catalogue
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/gtin"
+	"sort"
+	"strings"
+)
+
+// Actions of the catalogue rows
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+	ActionInvalid   = "invalid"
+)
+
+type RowReport struct {
+	Row          int      `json:"row"`
+	FoodName     string   `json:"food_name"`
+	Manufacturer string   `json:"manufacturer,omitempty"`
+	Action       string   `json:"action"`
+	FoodID       int      `json:"food_id,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// Report is the outcome of the import, of the dry run it is what the import would do
+type Report struct {
+	DryRun    bool        `json:"dry_run"`
+	Total     int         `json:"total"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Invalid   int         `json:"invalid"`
+	Rows      []RowReport `json:"rows"`
+}
+
+// Plan is what the import of the catalogue does, the foods are saved only if every row is valid
+type Plan struct {
+	Creates []*models.Food
+	Updates []*models.Food
+
+	rows []RowReport
+	// created are the new foods of the rows, their IDs are known once they are saved
+	created map[int]*models.Food
+}
+
+// Key identifies the food by name and manufacturer
+func Key(name string, manufacturer string) string {
+	return strings.ToLower(strings.TrimSpace(name)) + "\x00" + strings.ToLower(strings.TrimSpace(manufacturer))
+}
+
+// Names are the food names of the entries
+func Names(entries []Entry) []string {
+	names := make([]string, len(entries))
+	for idx := range entries {
+		names[idx] = entries[idx].Food.FoodName
+	}
+	return names
+}
+
+// Barcodes are the normalized valid barcodes of the entries
+func Barcodes(entries []Entry) []string {
+	codes := make([]string, 0)
+	for idx := range entries {
+		for _, code := range entries[idx].Food.Barcodes {
+			if normalized, err := gtin.Normalize(code); err == nil {
+				codes = append(codes, normalized)
+			}
+		}
+	}
+	return codes
+}
+
+/*
+NewPlan matches the entries with the existing foods and checks them.
+
+A row is invalid when the food does not validate, when it repeats the name and manufacturer of a previous row,
+or when its barcode belongs to another food or to another row. barcodeOwners maps the normalized barcodes of
+the entries to the IDs of the foods they belong to.
+*/
+func NewPlan(entries []Entry, existing []models.Food, barcodeOwners map[string]int) *Plan {
+	plan := &Plan{
+		Creates: make([]*models.Food, 0),
+		Updates: make([]*models.Food, 0),
+		rows:    make([]RowReport, len(entries)),
+		created: make(map[int]*models.Food),
+	}
+	existingByKey := make(map[string]*models.Food, len(existing))
+	for idx := range existing {
+		key := Key(existing[idx].FoodName, existing[idx].SpecifiedManufacturer)
+		if _, ok := existingByKey[key]; !ok {
+			existingByKey[key] = &existing[idx]
+		}
+	}
+	rowsByKey := make(map[string]int)
+	rowsByBarcode := make(map[string]int)
+
+	for idx := range entries {
+		entry := entries[idx]
+		row := RowReport{
+			Row:          entry.Row,
+			FoodName:     entry.Food.FoodName,
+			Manufacturer: entry.Food.SpecifiedManufacturer,
+			Errors:       append([]string(nil), entry.Errors...),
+		}
+
+		key := Key(entry.Food.FoodName, entry.Food.SpecifiedManufacturer)
+		if previous, ok := rowsByKey[key]; ok {
+			row.Errors = append(row.Errors, fmt.Sprintf("duplicates the food of row %d", previous))
+		} else {
+			rowsByKey[key] = entry.Row
+		}
+
+		food := entry.Food
+		food.BeforeCreate()
+		// barcodes the row specifies, the merged food also keeps the ones it had
+		barcodes := food.Barcodes
+		current, isExisting := existingByKey[key]
+		if isExisting {
+			food = merge(current, &food)
+			row.FoodID = current.FoodID
+		}
+		if err := food.Validate(); err != nil {
+			row.Errors = append(row.Errors, err.Error())
+		}
+		for _, code := range barcodes {
+			if owner, ok := barcodeOwners[code]; ok && (!isExisting || owner != current.FoodID) {
+				row.Errors = append(row.Errors, fmt.Sprintf("barcode %s belongs to food %d", code, owner))
+			}
+			if previous, ok := rowsByBarcode[code]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("barcode %s is also in row %d", code, previous))
+			} else {
+				rowsByBarcode[code] = entry.Row
+			}
+		}
+
+		switch {
+		case len(row.Errors) > 0:
+			row.Action = ActionInvalid
+		case !isExisting:
+			row.Action = ActionCreate
+			plan.Creates = append(plan.Creates, &food)
+			plan.created[idx] = &food
+		case isSame(current, &food):
+			row.Action = ActionUnchanged
+		default:
+			row.Action = ActionUpdate
+			plan.Updates = append(plan.Updates, &food)
+		}
+		plan.rows[idx] = row
+	}
+	return plan
+}
+
+func (p *Plan) IsValid() bool {
+	for _, row := range p.rows {
+		if row.Action == ActionInvalid {
+			return false
+		}
+	}
+	return true
+}
+
+// Report of the plan, the rows of the created foods get their IDs once the foods are saved
+func (p *Plan) Report(dryRun bool) *Report {
+	report := &Report{DryRun: dryRun, Total: len(p.rows), Rows: make([]RowReport, len(p.rows))}
+	for idx, row := range p.rows {
+		if food, ok := p.created[idx]; ok {
+			row.FoodID = food.FoodID
+		}
+		switch row.Action {
+		case ActionCreate:
+			report.Created++
+		case ActionUpdate:
+			report.Updated++
+		case ActionUnchanged:
+			report.Unchanged++
+		case ActionInvalid:
+			report.Invalid++
+		}
+		report.Rows[idx] = row
+	}
+	return report
+}
+
+// merge applies the values the entry specifies to the existing food, portion units and barcodes are added
+func merge(current *models.Food, entry *models.Food) models.Food {
+	merged := *current
+	merged.PortionUnits = append(make([]models.FoodPortionUnit, 0, len(current.PortionUnits)), current.PortionUnits...)
+	merged.Barcodes = append(make([]string, 0, len(current.Barcodes)), current.Barcodes...)
+
+	if entry.EnergyPer100g > 0 {
+		merged.EnergyPer100g = entry.EnergyPer100g
+	}
+	for _, nutrient := range nutrientColumns {
+		if value := *nutrient(entry); value != nil {
+			*nutrient(&merged) = value
+		}
+	}
+	if entry.SpecifiedDescription != "" {
+		merged.SpecifiedDescription = entry.SpecifiedDescription
+	}
+
+	for _, unit := range entry.PortionUnits {
+		replaced := false
+		for idx := range merged.PortionUnits {
+			if merged.PortionUnits[idx].Unit == unit.Unit {
+				merged.PortionUnits[idx].Grams = unit.Grams
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.PortionUnits = append(merged.PortionUnits, unit)
+		}
+	}
+	sort.Slice(merged.PortionUnits, func(i, j int) bool {
+		return merged.PortionUnits[i].Unit < merged.PortionUnits[j].Unit
+	})
+
+	known := make(map[string]bool, len(merged.Barcodes))
+	for _, code := range merged.Barcodes {
+		known[code] = true
+	}
+	for _, code := range entry.Barcodes {
+		if !known[code] {
+			merged.Barcodes = append(merged.Barcodes, code)
+			known[code] = true
+		}
+	}
+	sort.Strings(merged.Barcodes)
+
+	merged.BeforeCreate()
+	return merged
+}
+
+// isSame compares the foods as the API shows them
+func isSame(current *models.Food, updated *models.Food) bool {
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return false
+	}
+	updatedJSON, err := json.Marshal(updated)
+	if err != nil {
+		return false
+	}
+	return string(currentJSON) == string(updatedJSON)
+}
@@ -14,6 +14,17 @@ type ServerConfig struct {
 	DatabaseLogsOutStream *os.File
 
 	DatabaseDumpsDir string
+
+	MQTTAddr    string
+	MQTTTLSCert string
+	MQTTTLSKey  string
+
+	AttachmentsDir    string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
 }
 
 func NewServerConfig() *ServerConfig {
@@ -27,5 +38,16 @@ func NewServerConfig() *ServerConfig {
 		DatabaseLogPrefix:     DatabaseLogPrefix,
 
 		DatabaseDumpsDir: DatabaseDumpsDir,
+
+		MQTTAddr:    MQTTAddr,
+		MQTTTLSCert: MQTTTLSCert,
+		MQTTTLSKey:  MQTTTLSKey,
+
+		AttachmentsDir:    AttachmentsDir,
+		S3Endpoint:        S3Endpoint,
+		S3Region:          S3Region,
+		S3Bucket:          S3Bucket,
+		S3AccessKeyID:     S3AccessKeyID,
+		S3SecretAccessKey: S3SecretAccessKey,
 	}
 }
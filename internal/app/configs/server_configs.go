@@ -3,6 +3,7 @@ package configs
 import (
 	"log"
 	"os"
+	"strings"
 )
 
 var (
@@ -16,6 +17,27 @@ var (
 	DatabaseLogPrefix = "DATABASE: "
 
 	DatabaseDumpsDir = getCWD() + os.Getenv("DATABASE_DUMP_DIR")
+
+	// PublicBaseURL is the scheme and host the server is reachable at, the signed URLs printed in the documents
+	// start with it and are not built from the request headers
+	PublicBaseURL = strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/")
+
+	// MQTTAddr is the address of the MQTT listener for the collars, it is disabled when empty
+	MQTTAddr = os.Getenv("MQTT_ADDR")
+	// MQTTTLSCert and MQTTTLSKey are the PEM files of the MQTT listener certificate, without them
+	// the listener is only allowed on the loopback address
+	MQTTTLSCert = os.Getenv("MQTT_TLS_CERT")
+	MQTTTLSKey  = os.Getenv("MQTT_TLS_KEY")
+
+	// AttachmentsDir keeps the uploaded attachments when the S3 storage is not configured
+	AttachmentsDir = getCWD() + os.Getenv("ATTACHMENTS_DIR")
+
+	// S3Endpoint is the URL of the S3 compatible storage of the attachments, the local disk is used when it is empty
+	S3Endpoint        = os.Getenv("S3_ENDPOINT")
+	S3Region          = os.Getenv("S3_REGION")
+	S3Bucket          = os.Getenv("S3_BUCKET")
+	S3AccessKeyID     = os.Getenv("S3_ACCESS_KEY_ID")
+	S3SecretAccessKey = os.Getenv("S3_SECRET_ACCESS_KEY")
 )
 
 func getCWD() string {
@@ -0,0 +1,65 @@
+package documents
+
+import (
+	"bytes"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/skip2/go-qrcode"
+	"time"
+)
+
+const (
+	qrCodeSize   = 35.0
+	qrCodePixels = 512
+	qrCodeImage  = "verification"
+)
+
+// Certificate is the vaccination certificate of the pet issued by the veterinarian of the clinic
+type Certificate struct {
+	Profile
+	Vaccinations []VaccinationRow
+	Veterinarian *models.User
+	Clinic       *models.VetClinic
+	// VerificationURL is the public page the QR code of the certificate points at
+	VerificationURL string
+	IssuedAt        time.Time
+}
+
+// RenderCertificate renders the certificate with the clinic, the identity and vaccinations of the pet and the QR code
+func RenderCertificate(certificate *Certificate) ([]byte, error) {
+	qrCode, err := qrcode.Encode(certificate.VerificationURL, qrcode.Medium, qrCodePixels)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDocument("Vaccination certificate", certificate.IssuedAt)
+	top := d.pdf.GetY()
+	pageWidth, _ := d.pdf.GetPageSize()
+	d.pdf.RegisterImageOptionsReader(qrCodeImage, gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(qrCode))
+	d.pdf.ImageOptions(qrCodeImage, pageWidth-pageMargin-qrCodeSize, top, qrCodeSize, qrCodeSize, false,
+		gofpdf.ImageOptions{ImageType: "PNG"}, 0, certificate.VerificationURL)
+
+	d.pdf.SetRightMargin(pageMargin + qrCodeSize + 5)
+	d.pdf.SetFont(fontFamily, "B", 12)
+	d.pdf.MultiCell(0, lineHeight+1, d.translate(certificate.Clinic.ClinicName), "", "L", false)
+	d.field("Clinic registration", certificate.Clinic.ClinicID)
+	if certificate.Veterinarian != nil {
+		d.field("Veterinarian", certificate.Veterinarian.FullName)
+	}
+	d.field("Issued", certificate.IssuedAt.Format(dateLayout))
+	d.note("Scan the QR code to verify the certificate.")
+	d.pdf.SetRightMargin(pageMargin)
+	if bottom := top + qrCodeSize; d.pdf.GetY() < bottom {
+		d.pdf.SetY(bottom)
+	}
+
+	d.profile(&certificate.Profile)
+	d.section("Vaccinations")
+	d.vaccinations(certificate.Vaccinations)
+
+	d.ensureSpace(4 * lineHeight)
+	d.pdf.Ln(3 * lineHeight)
+	d.pdf.SetFont(fontFamily, "", 10)
+	d.pdf.CellFormat(80, lineHeight, "Veterinarian signature and stamp", "T", 1, "L", false, 0, "")
+	return d.output()
+}
@@ -0,0 +1,276 @@
+/*
+Package documents renders the printable PDF documents of the pets: the medical summary handed over after the visit
+and the vaccination certificate for the boarding kennels and travel.
+
+The documents use the standard PDF fonts, so the text is printed in the Windows-1252 code page and the characters
+out of it are replaced.
+*/
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/jung-kurt/gofpdf"
+	"time"
+)
+
+const (
+	// dateLayout is how the dates are printed
+	dateLayout = models.DateLayout
+
+	pageMargin   = 15.0
+	footerHeight = 15.0
+	lineHeight   = 6.0
+	labelWidth   = 45.0
+	fontFamily   = "Helvetica"
+)
+
+// compress is turned off by the tests to look for the text in the content streams
+var compress = true
+
+// Profile is the identity of the pet printed on every document
+type Profile struct {
+	Pet     *models.Pet
+	PetType *models.PetType
+	Owner   *models.User
+	Mother  *models.Pet
+	Father  *models.Pet
+}
+
+// VaccinationRow is the shot made to the pet, the latest shot of the vaccine carries the date the next one is due
+type VaccinationRow struct {
+	Name        string     `json:"name"`
+	DoseNumber  int        `json:"dose_number"`
+	IsBooster   bool       `json:"is_booster"`
+	Date        time.Time  `json:"date"`
+	NextDueDate *time.Time `json:"next_due_date,omitempty"`
+}
+
+/*
+Vaccinations lists the completed shots of the vaccination schedule, which are ordered the earliest first. The next
+shot of the catalogue vaccine is due at the date the schedule expects it, no date means no more shots are needed.
+The vaccines out of the catalogue are boosted after the default interval.
+*/
+func Vaccinations(schedule []models.ScheduledVaccination) []VaccinationRow {
+	type vaccineKey struct {
+		catalogueID int
+		name        string
+	}
+	dueDates := make(map[int]time.Time)
+	latest := make(map[vaccineKey]int)
+	var rows []VaccinationRow
+	for _, shot := range schedule {
+		if shot.Status != models.VaccinationCompleted {
+			if shot.CatalogueID != 0 {
+				dueDates[shot.CatalogueID] = shot.Date
+			}
+			continue
+		}
+		key := vaccineKey{catalogueID: shot.CatalogueID}
+		if shot.CatalogueID == 0 {
+			key.name = shot.Name
+		}
+		latest[key] = len(rows)
+		rows = append(rows, VaccinationRow{
+			Name:       shot.Name,
+			DoseNumber: shot.DoseNumber,
+			IsBooster:  shot.IsBooster,
+			Date:       shot.Date,
+		})
+	}
+
+	for key, idx := range latest {
+		dueDate, ok := dueDates[key.catalogueID]
+		if key.catalogueID == 0 {
+			dueDate, ok = rows[idx].Date.AddDate(models.VaccineBoosterInterval, 0, 0), true
+		}
+		if ok {
+			rows[idx].NextDueDate = &dueDate
+		}
+	}
+	return rows
+}
+
+// document is the A4 page flow with the title in the header and the page numbers in the footer
+type document struct {
+	pdf       *gofpdf.Fpdf
+	translate func(string) string
+}
+
+func newDocument(title string, issuedAt time.Time) *document {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin+footerHeight)
+	pdf.SetCompression(compress)
+	pdf.SetCreationDate(issuedAt)
+	pdf.SetModificationDate(issuedAt)
+	pdf.SetCreator("StoryPet", false)
+	pdf.AliasNbPages("")
+
+	d := &document{pdf: pdf, translate: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.SetTitle(d.translate(title), false)
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont(fontFamily, "B", 16)
+		pdf.CellFormat(0, 10, d.translate(title), "B", 1, "L", false, 0, "")
+		pdf.Ln(4)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pageMargin - footerHeight/2)
+		pdf.SetFont(fontFamily, "", 8)
+		pdf.SetTextColor(110, 110, 110)
+		pageWidth, _ := pdf.GetPageSize()
+		halfWidth := (pageWidth - 2*pageMargin) / 2
+		pdf.CellFormat(halfWidth, 5, "StoryPet, issued "+issuedAt.Format(dateLayout), "T", 0, "L", false, 0, "")
+		pdf.CellFormat(halfWidth, 5, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "T", 0, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+	pdf.AddPage()
+	return d
+}
+
+func (d *document) section(title string) {
+	d.ensureSpace(3 * lineHeight)
+	d.pdf.Ln(3)
+	d.pdf.SetFont(fontFamily, "B", 12)
+	d.pdf.SetFillColor(235, 240, 245)
+	d.pdf.CellFormat(0, 8, d.translate(title), "", 1, "L", true, 0, "")
+	d.pdf.Ln(1)
+}
+
+// field prints the label with its value, the empty values are printed as the dash
+func (d *document) field(label string, value string) {
+	if value == "" {
+		value = "-"
+	}
+	d.pdf.SetFont(fontFamily, "B", 10)
+	d.pdf.CellFormat(labelWidth, lineHeight, d.translate(label), "", 0, "L", false, 0, "")
+	d.pdf.SetFont(fontFamily, "", 10)
+	d.pdf.MultiCell(0, lineHeight, d.translate(value), "", "L", false)
+}
+
+func (d *document) paragraph(text string) {
+	d.pdf.SetFont(fontFamily, "", 10)
+	d.pdf.MultiCell(0, lineHeight-1, d.translate(text), "", "L", false)
+}
+
+func (d *document) note(text string) {
+	d.pdf.SetFont(fontFamily, "I", 10)
+	d.pdf.SetTextColor(110, 110, 110)
+	d.pdf.MultiCell(0, lineHeight, d.translate(text), "", "L", false)
+	d.pdf.SetTextColor(0, 0, 0)
+}
+
+// table prints the rows under the header, the header is repeated on every page the table continues on
+func (d *document) table(widths []float64, header []string, rows [][]string) {
+	printHeader := func() {
+		d.pdf.SetFont(fontFamily, "B", 10)
+		d.pdf.SetFillColor(245, 245, 245)
+		for idx, title := range header {
+			d.pdf.CellFormat(widths[idx], lineHeight+1, d.translate(title), "1", 0, "L", true, 0, "")
+		}
+		d.pdf.Ln(-1)
+		d.pdf.SetFont(fontFamily, "", 10)
+	}
+	d.ensureSpace(2 * (lineHeight + 1))
+	printHeader()
+	for _, row := range rows {
+		if d.ensureSpace(lineHeight) {
+			printHeader()
+		}
+		for idx, value := range row {
+			d.pdf.CellFormat(widths[idx], lineHeight, d.translate(value), "1", 0, "L", false, 0, "")
+		}
+		d.pdf.Ln(-1)
+	}
+}
+
+// ensureSpace starts the new page when the height does not fit the current one and reports whether it has
+func (d *document) ensureSpace(height float64) bool {
+	_, pageHeight := d.pdf.GetPageSize()
+	if d.pdf.GetY()+height <= pageHeight-pageMargin-footerHeight {
+		return false
+	}
+	d.pdf.AddPage()
+	return true
+}
+
+func (d *document) output() ([]byte, error) {
+	var content bytes.Buffer
+	if err := d.pdf.Output(&content); err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// profile prints the identity of the pet with its owner and parents
+func (d *document) profile(profile *Profile) {
+	pet := profile.Pet
+	d.section("Pet")
+	d.field("Name", pet.Name)
+	d.field("Family name", pet.SpecifiedFamilyName)
+	if profile.PetType != nil {
+		d.field("Type", profile.PetType.TypeName)
+	}
+	d.field("Breed", pet.SpecifiedBreed)
+	d.field("Sex", sexName(pet))
+	d.field("Birth date", pet.SpecifiedBirthDate)
+	if profile.Owner != nil {
+		d.field("Owner", profile.Owner.FullName)
+	}
+
+	d.section("Parents")
+	d.field("Mother", parentName(profile.Mother, pet.MotherVerified))
+	d.field("Father", parentName(profile.Father, pet.FatherVerified))
+}
+
+// vaccinations prints the vaccinations table
+func (d *document) vaccinations(rows []VaccinationRow) {
+	if len(rows) == 0 {
+		d.note("No vaccinations are recorded.")
+		return
+	}
+	table := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		dose := fmt.Sprintf("%d", row.DoseNumber)
+		if row.IsBooster {
+			dose += " (booster)"
+		}
+		nextDue := ""
+		if row.NextDueDate != nil {
+			nextDue = row.NextDueDate.Format(dateLayout)
+		}
+		table = append(table, []string{row.Name, dose, row.Date.Format(dateLayout), nextDue})
+	}
+	d.table([]float64{80, 30, 35, 35}, []string{"Vaccine", "Dose", "Date", "Next due"}, table)
+}
+
+func sexName(pet *models.Pet) string {
+	switch pet.SpecifiedSex {
+	case models.SexMale:
+		if pet.IsNeutered {
+			return "Male, neutered"
+		}
+		return "Male"
+	case models.SexFemale:
+		if pet.IsNeutered {
+			return "Female, spayed"
+		}
+		return "Female"
+	}
+	return ""
+}
+
+func parentName(parent *models.Pet, verified bool) string {
+	if parent == nil {
+		return ""
+	}
+	name := parent.Name
+	if parent.SpecifiedBreed != "" {
+		name += ", " + parent.SpecifiedBreed
+	}
+	if verified {
+		return name + " (verified)"
+	}
+	return name + " (not verified)"
+}
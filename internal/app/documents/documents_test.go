@@ -0,0 +1,145 @@
+package documents
+
+import (
+	"bytes"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func testProfile() Profile {
+	pet := &models.Pet{
+		PetID:              1,
+		Name:               "Rex",
+		MotherVerified:     true,
+		SpecifiedBreed:     "Beagle",
+		SpecifiedSex:       models.SexMale,
+		SpecifiedBirthDate: "2019-04-02",
+	}
+	return Profile{
+		Pet:     pet,
+		PetType: &models.PetType{TypeName: "Dog"},
+		Owner:   &models.User{FullName: "Jane Doe"},
+		Mother:  &models.Pet{Name: "Bella"},
+	}
+}
+
+func TestVaccinations(t *testing.T) {
+	schedule := []models.ScheduledVaccination{
+		{CatalogueID: 1, Name: "Rabies", DoseNumber: 1, Status: models.VaccinationCompleted, Date: date(2020, 3, 1)},
+		{Name: "Leptospirosis", DoseNumber: 1, Status: models.VaccinationCompleted, Date: date(2020, 5, 1)},
+		{CatalogueID: 1, Name: "Rabies", DoseNumber: 2, IsBooster: true, Status: models.VaccinationCompleted, Date: date(2021, 3, 1)},
+		{CatalogueID: 2, Name: "Parvovirus", DoseNumber: 1, Status: models.VaccinationCompleted, Date: date(2021, 4, 1)},
+		{CatalogueID: 1, Name: "Rabies", DoseNumber: 3, IsBooster: true, Status: models.VaccinationDue, Date: date(2022, 3, 1)},
+		{CatalogueID: 3, Name: "Distemper", DoseNumber: 1, Status: models.VaccinationOverdue, Date: date(2021, 6, 1)},
+	}
+	rows := Vaccinations(schedule)
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, "Rabies", rows[0].Name)
+	assert.Nil(t, rows[0].NextDueDate)
+
+	require.NotNil(t, rows[1].NextDueDate)
+	assert.Equal(t, date(2021, 5, 1), *rows[1].NextDueDate)
+
+	assert.True(t, rows[2].IsBooster)
+	require.NotNil(t, rows[2].NextDueDate)
+	assert.Equal(t, date(2022, 3, 1), *rows[2].NextDueDate)
+
+	// The primary series of the vaccine is complete and it needs no boosters
+	assert.Equal(t, "Parvovirus", rows[3].Name)
+	assert.Nil(t, rows[3].NextDueDate)
+}
+
+func TestRecentRecords(t *testing.T) {
+	now := date(2021, 6, 1)
+	records := []models.Anthropometry{
+		{RecordID: 3, Time: date(2021, 5, 1)},
+		{RecordID: 2, Time: date(2020, 12, 1)},
+		{RecordID: 1, Time: date(2020, 1, 1)},
+	}
+	recent := recentAnthropometry(records, now)
+	require.Len(t, recent, 2)
+	assert.Equal(t, 2, recent[0].RecordID)
+	assert.Equal(t, 3, recent[1].RecordID)
+
+	var reports []models.PetHealthReport
+	for day := 1; day <= MaxRecentReports+2; day++ {
+		reports = append(reports, models.PetHealthReport{ReportID: day, ReportTimestamp: date(2021, 5, day)})
+	}
+	reports = append(reports, models.PetHealthReport{ReportID: 100, ReportTimestamp: date(2021, 7, 1)})
+	latest := recentReports(reports, now)
+	require.Len(t, latest, MaxRecentReports)
+	assert.Equal(t, MaxRecentReports+2, latest[0].ReportID)
+	assert.Equal(t, 3, latest[MaxRecentReports-1].ReportID)
+}
+
+func TestChartPoints(t *testing.T) {
+	times := []time.Time{date(2021, 1, 1), date(2021, 1, 3), date(2021, 1, 5)}
+	points, minValue, maxValue := chartPoints(times, []float64{10, 20, 15}, 10, 100, 80, 50)
+	require.Len(t, points, 3)
+	assert.Equal(t, 9.0, minValue)
+	assert.Equal(t, 21.0, maxValue)
+	assert.InDelta(t, 10, points[0].X, 1e-9)
+	assert.InDelta(t, 50, points[1].X, 1e-9)
+	assert.InDelta(t, 90, points[2].X, 1e-9)
+	assert.InDelta(t, 100+50*11.0/12, points[0].Y, 1e-9)
+	assert.InDelta(t, 100+50*1.0/12, points[1].Y, 1e-9)
+
+	points, minValue, maxValue = chartPoints(times[:1], []float64{0.5}, 10, 100, 80, 50)
+	require.Len(t, points, 1)
+	assert.Equal(t, 0.0, minValue)
+	assert.Equal(t, 1.5, maxValue)
+	assert.InDelta(t, 50, points[0].X, 1e-9)
+}
+
+func TestRender(t *testing.T) {
+	compress = false
+	defer func() {
+		compress = true
+	}()
+	nextDue := date(2022, 3, 1)
+	vaccinations := []VaccinationRow{{Name: "Rabies", DoseNumber: 1, Date: date(2021, 3, 1), NextDueDate: &nextDue}}
+
+	summary, err := RenderSummary(&Summary{
+		Profile:      testProfile(),
+		Vaccinations: vaccinations,
+		Anthropometry: []models.Anthropometry{
+			{Time: date(2021, 2, 1), Weight: 11.5, Height: 38},
+			{Time: date(2021, 5, 1), Weight: 12, Height: 39},
+		},
+		Reports: []models.PetHealthReport{{
+			ReportTimestamp:  date(2021, 5, 1),
+			ReportConclusion: "Healthy (routine check-up)",
+			Status:           models.ReportSigned,
+			Version:          1,
+		}},
+		IssuedAt: date(2021, 6, 1),
+	})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(summary, []byte("%PDF-")))
+	assert.Contains(t, string(summary), "(Rex)")
+	assert.Contains(t, string(summary), "(Bella \\(verified\\))")
+	assert.Contains(t, string(summary), "(Healthy \\(routine check-up\\))")
+	assert.Contains(t, string(summary), "(2022-03-01)")
+
+	certificate, err := RenderCertificate(&Certificate{
+		Profile:         testProfile(),
+		Vaccinations:    vaccinations,
+		Veterinarian:    &models.User{FullName: "John Smith"},
+		Clinic:          &models.VetClinic{ClinicID: "UA-1234", ClinicName: "Happy Paws"},
+		VerificationURL: "https://storypet.example/certificates/1?issued=1622505600",
+		IssuedAt:        date(2021, 6, 1),
+	})
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(certificate, []byte("%PDF-")))
+	assert.Contains(t, string(certificate), "(Happy Paws)")
+	assert.Contains(t, string(certificate), "/Subtype /Image")
+	assert.Contains(t, string(certificate), "/URI (https://storypet.example/certificates/1?issued=1622505600)")
+}
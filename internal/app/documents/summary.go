@@ -0,0 +1,214 @@
+package documents
+
+import (
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/jung-kurt/gofpdf"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// RecentPeriod is how far back the anthropometry records and the health reports are summarized
+	RecentPeriod = 365 * 24 * time.Hour
+	// MaxRecentReports limits the health reports of the summary, the latest ones are kept
+	MaxRecentReports = 10
+
+	chartWidth  = 85.0
+	chartHeight = 50.0
+	chartGap    = 10.0
+	// chartPadding is the part of the value range added above and below the values
+	chartPadding = 0.1
+)
+
+// Summary is the medical summary of the pet, the records of the recent period are printed
+type Summary struct {
+	Profile
+	Vaccinations  []VaccinationRow
+	Anthropometry []models.Anthropometry
+	Reports       []models.PetHealthReport
+	IssuedAt      time.Time
+}
+
+// RenderSummary renders the summary with the identity of the pet, its vaccinations, anthropometry charts and reports
+func RenderSummary(summary *Summary) ([]byte, error) {
+	d := newDocument("Medical summary", summary.IssuedAt)
+	d.profile(&summary.Profile)
+
+	d.section("Vaccinations")
+	d.vaccinations(summary.Vaccinations)
+
+	d.section("Anthropometry")
+	records := recentAnthropometry(summary.Anthropometry, summary.IssuedAt)
+	if len(records) == 0 {
+		d.note("No anthropometry is recorded in the last 12 months.")
+	} else {
+		d.anthropometry(records)
+	}
+
+	d.section("Health reports")
+	reports := recentReports(summary.Reports, summary.IssuedAt)
+	if len(reports) == 0 {
+		d.note("No health reports are made in the last 12 months.")
+	}
+	for idx := range reports {
+		d.report(&reports[idx])
+	}
+	return d.output()
+}
+
+// recentAnthropometry keeps the records of the recent period before the time, the earliest first as they are charted
+func recentAnthropometry(records []models.Anthropometry, now time.Time) []models.Anthropometry {
+	since := now.Add(-RecentPeriod)
+	var recent []models.Anthropometry
+	for _, record := range records {
+		if !record.Time.Before(since) && !record.Time.After(now) {
+			recent = append(recent, record)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Time.Before(recent[j].Time)
+	})
+	return recent
+}
+
+// recentReports keeps the latest health reports of the recent period before the time, the latest first
+func recentReports(reports []models.PetHealthReport, now time.Time) []models.PetHealthReport {
+	since := now.Add(-RecentPeriod)
+	var recent []models.PetHealthReport
+	for _, report := range reports {
+		if !report.ReportTimestamp.Before(since) && !report.ReportTimestamp.After(now) {
+			recent = append(recent, report)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].ReportTimestamp.After(recent[j].ReportTimestamp)
+	})
+	if len(recent) > MaxRecentReports {
+		recent = recent[:MaxRecentReports]
+	}
+	return recent
+}
+
+// anthropometry charts the weight and the height of the records side by side
+func (d *document) anthropometry(records []models.Anthropometry) {
+	times := make([]time.Time, len(records))
+	weights := make([]float64, len(records))
+	heights := make([]float64, len(records))
+	for idx, record := range records {
+		times[idx] = record.Time
+		weights[idx] = record.Weight
+		heights[idx] = record.Height
+	}
+
+	d.ensureSpace(chartHeight + 3*lineHeight)
+	left, top := d.pdf.GetX(), d.pdf.GetY()
+	d.chart("Weight", times, weights, left, top)
+	d.chart("Height", times, heights, left+chartWidth+chartGap, top)
+	d.pdf.SetXY(left, top+chartHeight+2*lineHeight)
+
+	latest := records[len(records)-1]
+	d.field("Latest record", fmt.Sprintf("%s: weight %s, height %s",
+		latest.Time.Format(dateLayout), formatValue(latest.Weight), formatValue(latest.Height)))
+}
+
+// chart draws the line chart of the values with its title above and the value and date ranges on its axes
+func (d *document) chart(title string, times []time.Time, values []float64, left float64, top float64) {
+	pdf := d.pdf
+	pdf.SetFont(fontFamily, "B", 10)
+	pdf.SetXY(left, top)
+	pdf.CellFormat(chartWidth, lineHeight, d.translate(title), "", 0, "L", false, 0, "")
+
+	plotTop := top + lineHeight
+	pdf.SetDrawColor(160, 160, 160)
+	pdf.SetLineWidth(0.2)
+	pdf.Rect(left, plotTop, chartWidth, chartHeight, "D")
+
+	points, minValue, maxValue := chartPoints(times, values, left, plotTop, chartWidth, chartHeight)
+	pdf.SetDrawColor(40, 90, 160)
+	pdf.SetFillColor(40, 90, 160)
+	pdf.SetLineWidth(0.5)
+	for idx := 1; idx < len(points); idx++ {
+		pdf.Line(points[idx-1].X, points[idx-1].Y, points[idx].X, points[idx].Y)
+	}
+	for _, point := range points {
+		pdf.Circle(point.X, point.Y, 0.8, "F")
+	}
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.2)
+
+	pdf.SetFont(fontFamily, "", 7)
+	pdf.SetXY(left+1, plotTop+1)
+	pdf.CellFormat(chartWidth-2, 3, formatValue(maxValue), "", 0, "L", false, 0, "")
+	pdf.SetXY(left+1, plotTop+chartHeight-4)
+	pdf.CellFormat(chartWidth-2, 3, formatValue(minValue), "", 0, "L", false, 0, "")
+	pdf.SetXY(left, plotTop+chartHeight+1)
+	pdf.CellFormat(chartWidth/2, 4, times[0].Format(dateLayout), "", 0, "L", false, 0, "")
+	pdf.CellFormat(chartWidth/2, 4, times[len(times)-1].Format(dateLayout), "", 0, "R", false, 0, "")
+}
+
+/*
+chartPoints places the values at their times into the chart box, the times are ordered the earliest first.
+The value range is padded, so the lines do not touch the box, the equal values are drawn at the middle height
+and the single value in the middle of the box. The returned range is the one the box height is spanned by.
+*/
+func chartPoints(times []time.Time, values []float64, left float64, top float64, width float64, height float64) ([]gofpdf.PointType, float64, float64) {
+	if len(values) == 0 {
+		return nil, 0, 0
+	}
+	minValue, maxValue := values[0], values[0]
+	for _, value := range values {
+		if value < minValue {
+			minValue = value
+		}
+		if value > maxValue {
+			maxValue = value
+		}
+	}
+	padding := (maxValue - minValue) * chartPadding
+	if padding == 0 {
+		padding = 1
+	}
+	minValue, maxValue = minValue-padding, maxValue+padding
+	if minValue < 0 && values[0] >= 0 {
+		minValue = 0
+	}
+
+	first, last := times[0], times[len(times)-1]
+	span := last.Sub(first).Seconds()
+	points := make([]gofpdf.PointType, len(values))
+	for idx, value := range values {
+		x := left + width/2
+		if span > 0 {
+			x = left + width*times[idx].Sub(first).Seconds()/span
+		}
+		y := top + height*(maxValue-value)/(maxValue-minValue)
+		points[idx] = gofpdf.PointType{X: x, Y: y}
+	}
+	return points, minValue, maxValue
+}
+
+// report prints the health report with its status, conclusion and comments
+func (d *document) report(report *models.PetHealthReport) {
+	d.ensureSpace(4 * lineHeight)
+	d.pdf.SetFont(fontFamily, "B", 10)
+	status := report.Status
+	if status == "" {
+		status = models.ReportDraft
+	}
+	heading := fmt.Sprintf("%s, %s, version %d", report.ReportTimestamp.Format(dateLayout), status, report.Version)
+	d.pdf.CellFormat(0, lineHeight, d.translate(heading), "B", 1, "L", false, 0, "")
+	d.pdf.Ln(1)
+	d.paragraph(report.ReportConclusion)
+	if comments := strings.TrimSpace(report.SpecifiedReportComments); comments != "" {
+		d.pdf.Ln(1)
+		d.note(comments)
+	}
+	d.pdf.Ln(2)
+}
+
+// formatValue prints the measurement without the trailing zeros
+func formatValue(value float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+}
@@ -0,0 +1,197 @@
+/*
+Package feeding expands the feeding plans into the expected meals and matches the logged eatings against them.
+
+A meal is matched with the eating logged within the tolerance of the plan around its time, the eating of the
+planned food is preferred, then the closest one. Every eating matches a single meal, the eatings left are
+unplanned. Meals without an eating are missed once their tolerance has passed and pending until then.
+
+The eatings keep the local time of the server without the zone, as the eating_timestamp column does,
+so their times are read as the local ones before they are compared with the meals.
+*/
+package feeding
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// Statuses of the meals
+const (
+	StatusFed         = "fed"
+	StatusSubstituted = "substituted"
+	StatusMissed      = "missed"
+	StatusPending     = "pending"
+)
+
+// Meal is the meal the plan expects at the time
+type Meal struct {
+	PlanID        int       `json:"plan_id"`
+	MealID        int       `json:"meal_id"`
+	FoodID        int       `json:"food_id"`
+	ScheduledAt   time.Time `json:"scheduled_at"`
+	PortionWeight float64   `json:"portion_weight"`
+
+	tolerance time.Duration
+}
+
+// Outcome is the meal and the eating it is matched with
+type Outcome struct {
+	Meal
+	Status      string     `json:"status"`
+	EatenAt     *time.Time `json:"eaten_at,omitempty"`
+	EatenFoodID int        `json:"eaten_food_id,omitempty"`
+	EatenWeight float64    `json:"eaten_weight,omitempty"`
+}
+
+// Adherence of the pet to its plans in [From, To), Rate is the share of the due meals that were fed
+type Adherence struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Expected  int             `json:"expected"`
+	Fed       int             `json:"fed"`
+	Missed    int             `json:"missed"`
+	Pending   int             `json:"pending"`
+	Rate      *float64        `json:"rate"`
+	Meals     []Outcome       `json:"meals"`
+	Unplanned []models.Eating `json:"unplanned"`
+}
+
+// Expand returns the meals of the plans scheduled in [from, to), ordered by time
+func Expand(plans []models.FeedingPlan, from time.Time, to time.Time) []Meal {
+	meals := make([]Meal, 0)
+	for idx := range plans {
+		plan := &plans[idx]
+		location := plan.Location()
+		tolerance := time.Duration(plan.ToleranceMinutes) * time.Minute
+
+		start := from.In(location)
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+		for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+			if !plan.IsValidOn(day.Year(), day.Month(), day.Day()) {
+				continue
+			}
+			for _, meal := range plan.Meals {
+				clock, err := time.Parse(models.ClockTimeLayout, meal.TimeOfDay)
+				if err != nil || !meal.IsServedOn(day.Weekday()) {
+					continue
+				}
+				scheduledAt := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+				if scheduledAt.Before(from) || !scheduledAt.Before(to) {
+					continue
+				}
+				meals = append(meals, Meal{
+					PlanID:        plan.PlanID,
+					MealID:        meal.MealID,
+					FoodID:        meal.FoodID,
+					ScheduledAt:   scheduledAt,
+					PortionWeight: meal.PortionWeight,
+					tolerance:     tolerance,
+				})
+			}
+		}
+	}
+	sort.SliceStable(meals, func(i, j int) bool {
+		return meals[i].ScheduledAt.Before(meals[j].ScheduledAt)
+	})
+	return meals
+}
+
+// Match matches the eatings with the meals, the meals are expected in time order
+func Match(meals []Meal, logged []models.Eating, now time.Time) ([]Outcome, []models.Eating) {
+	eatings := make([]models.Eating, len(logged))
+	for idx := range logged {
+		eatings[idx] = logged[idx]
+		eatings[idx].Time = localTime(logged[idx].Time)
+	}
+	outcomes := make([]Outcome, len(meals))
+	matched := make([]bool, len(eatings))
+	for idx, meal := range meals {
+		outcomes[idx] = Outcome{Meal: meal}
+		best := -1
+		for candidate := range eatings {
+			if matched[candidate] || !isWithin(meal, eatings[candidate].Time) {
+				continue
+			}
+			if best < 0 || isBetter(meal, eatings[candidate], eatings[best]) {
+				best = candidate
+			}
+		}
+
+		switch {
+		case best >= 0:
+			matched[best] = true
+			eatenAt := eatings[best].Time
+			outcomes[idx].EatenAt = &eatenAt
+			outcomes[idx].EatenFoodID = eatings[best].FoodID
+			outcomes[idx].EatenWeight = eatings[best].PortionWeight
+			outcomes[idx].Status = StatusFed
+			if eatings[best].FoodID != meal.FoodID {
+				outcomes[idx].Status = StatusSubstituted
+			}
+		case now.After(meal.ScheduledAt.Add(meal.tolerance)):
+			outcomes[idx].Status = StatusMissed
+		default:
+			outcomes[idx].Status = StatusPending
+		}
+	}
+
+	unplanned := make([]models.Eating, 0)
+	for idx := range eatings {
+		if !matched[idx] {
+			unplanned = append(unplanned, eatings[idx])
+		}
+	}
+	return outcomes, unplanned
+}
+
+// Assess is the adherence to the plans in [from, to) as known at now, eatings are the ones logged in the period
+func Assess(plans []models.FeedingPlan, eatings []models.Eating, from time.Time, to time.Time, now time.Time) *Adherence {
+	meals := Expand(plans, from, to)
+	outcomes, unplanned := Match(meals, eatings, now)
+	adherence := &Adherence{
+		From:      from,
+		To:        to,
+		Expected:  len(outcomes),
+		Meals:     outcomes,
+		Unplanned: unplanned,
+	}
+	for _, outcome := range outcomes {
+		switch outcome.Status {
+		case StatusFed, StatusSubstituted:
+			adherence.Fed++
+		case StatusMissed:
+			adherence.Missed++
+		case StatusPending:
+			adherence.Pending++
+		}
+	}
+	if due := adherence.Fed + adherence.Missed; due > 0 {
+		rate := float64(adherence.Fed) / float64(due)
+		adherence.Rate = &rate
+	}
+	return adherence
+}
+
+// localTime reads the wall clock of the time stored without the zone as the local time of the server
+func localTime(stored time.Time) time.Time {
+	return time.Date(
+		stored.Year(), stored.Month(), stored.Day(),
+		stored.Hour(), stored.Minute(), stored.Second(), stored.Nanosecond(),
+		time.Local,
+	)
+}
+
+func isWithin(meal Meal, eatenAt time.Time) bool {
+	return math.Abs(float64(eatenAt.Sub(meal.ScheduledAt))) <= float64(meal.tolerance)
+}
+
+// isBetter prefers the eating of the planned food, then the one closer to the time of the meal
+func isBetter(meal Meal, candidate models.Eating, best models.Eating) bool {
+	candidatePlanned, bestPlanned := candidate.FoodID == meal.FoodID, best.FoodID == meal.FoodID
+	if candidatePlanned != bestPlanned {
+		return candidatePlanned
+	}
+	return math.Abs(float64(candidate.Time.Sub(meal.ScheduledAt))) < math.Abs(float64(best.Time.Sub(meal.ScheduledAt)))
+}
@@ -0,0 +1,107 @@
+package feeding
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var monday = time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+
+func plan() models.FeedingPlan {
+	return models.FeedingPlan{
+		PlanID:           1,
+		TimeZone:         "UTC",
+		StartsOn:         monday,
+		ToleranceMinutes: 60,
+		Meals: []models.FeedingPlanMeal{
+			{MealID: 1, TimeOfDay: "08:00", FoodID: 10, PortionWeight: 100},
+			{MealID: 2, TimeOfDay: "18:30", FoodID: 10, PortionWeight: 120},
+			{MealID: 3, TimeOfDay: "12:00", FoodID: 20, PortionWeight: 30, Weekdays: pq.Int64Array{int64(time.Saturday), int64(time.Sunday)}},
+		},
+	}
+}
+
+func eating(foodID int, at time.Time) models.Eating {
+	return models.Eating{FoodID: foodID, Time: at, PortionWeight: 100}
+}
+
+func TestExpand(t *testing.T) {
+	meals := Expand([]models.FeedingPlan{plan()}, monday.AddDate(0, 0, -1), monday.AddDate(0, 0, 7))
+	// the plan starts on monday, the weekend meal is served on saturday and sunday
+	require.Len(t, meals, 16)
+	assert.Equal(t, monday.Add(8*time.Hour), meals[0].ScheduledAt)
+	assert.Equal(t, 3, meals[len(meals)-2].MealID)
+	assert.Equal(t, time.Sunday, meals[len(meals)-2].ScheduledAt.Weekday())
+
+	ended := plan()
+	ended.EndsOn = &sql.NullTime{Time: monday, Valid: true}
+	assert.Len(t, Expand([]models.FeedingPlan{ended}, monday, monday.AddDate(0, 0, 7)), 2)
+}
+
+func TestExpand_TimeZone(t *testing.T) {
+	kyiv := plan()
+	kyiv.TimeZone = "Europe/Kyiv"
+	meals := Expand([]models.FeedingPlan{kyiv}, monday, monday.AddDate(0, 0, 1))
+	require.Len(t, meals, 2)
+	assert.Equal(t, time.Date(2026, 6, 15, 5, 0, 0, 0, time.UTC), meals[0].ScheduledAt.UTC())
+	assert.Equal(t, time.Date(2026, 6, 15, 15, 30, 0, 0, time.UTC), meals[1].ScheduledAt.UTC())
+}
+
+func TestAssess(t *testing.T) {
+	eatings := []models.Eating{
+		eating(20, monday.Add(7*time.Hour+50*time.Minute)),
+		eating(10, monday.Add(8*time.Hour+20*time.Minute)),
+		eating(10, monday.Add(13*time.Hour)),
+		eating(30, monday.Add(24*time.Hour+8*time.Hour+30*time.Minute)),
+	}
+	now := monday.Add(24*time.Hour + 19*time.Hour)
+	adherence := Assess([]models.FeedingPlan{plan()}, eatings, monday, monday.AddDate(0, 0, 2), now)
+
+	assert.Equal(t, 4, adherence.Expected)
+	require.Len(t, adherence.Meals, 4)
+	assert.Equal(t, StatusFed, adherence.Meals[0].Status)
+	assert.True(t, monday.Add(8*time.Hour+20*time.Minute).Equal(*adherence.Meals[0].EatenAt))
+	assert.Equal(t, StatusMissed, adherence.Meals[1].Status)
+	assert.Equal(t, StatusSubstituted, adherence.Meals[2].Status)
+	assert.Equal(t, 30, adherence.Meals[2].EatenFoodID)
+	assert.Equal(t, StatusPending, adherence.Meals[3].Status)
+
+	assert.Equal(t, 2, adherence.Fed)
+	assert.Equal(t, 1, adherence.Missed)
+	assert.Equal(t, 1, adherence.Pending)
+	assert.InDelta(t, 2.0/3.0, *adherence.Rate, 0.0001)
+	require.Len(t, adherence.Unplanned, 2)
+	assert.Equal(t, 20, adherence.Unplanned[0].FoodID)
+}
+
+func TestAssess_NothingDue(t *testing.T) {
+	adherence := Assess([]models.FeedingPlan{plan()}, nil, monday, monday.AddDate(0, 0, 1), monday)
+	assert.Equal(t, 2, adherence.Pending)
+	assert.Nil(t, adherence.Rate)
+	assert.NotNil(t, adherence.Unplanned)
+}
+
+func TestAssess_ServerTimeZone(t *testing.T) {
+	// The server runs in Kyiv, the eatings are stored with its wall clock and read back without the zone
+	local := time.Local
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	require.NoError(t, err)
+	time.Local = kyiv
+	defer func() { time.Local = local }()
+
+	utcPlan := plan()
+	stored := time.Date(2026, 6, 15, 11, 10, 0, 0, time.UTC)
+	adherence := Assess([]models.FeedingPlan{utcPlan}, []models.Eating{eating(10, stored)}, monday, monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 1))
+
+	// 11:10 in Kyiv is 08:10 UTC, the breakfast of the plan
+	require.Len(t, adherence.Meals, 2)
+	assert.Equal(t, StatusFed, adherence.Meals[0].Status)
+	assert.True(t, monday.Add(8*time.Hour+10*time.Minute).Equal(*adherence.Meals[0].EatenAt))
+	assert.Equal(t, StatusMissed, adherence.Meals[1].Status)
+	assert.Empty(t, adherence.Unplanned)
+}
@@ -0,0 +1,28 @@
+/*
+Package foodsearch turns the highlights of the food search into HTML.
+
+The database searches with trigrams and text search and wraps the matches of the name, manufacturer
+and description in the marks, Highlight escapes the text and replaces the marks with <mark> tags.
+*/
+package foodsearch
+
+import (
+	"html"
+	"strings"
+)
+
+// Marks wrap the matches in the highlights of the database, they are private use characters so the text
+// can be escaped before the marks become <mark> tags
+const (
+	StartMark = "\ue000"
+	StopMark  = "\ue001"
+)
+
+// Highlight escapes the text and turns the marks into <mark> tags, the text without marks has no highlight
+func Highlight(text string) string {
+	if !strings.Contains(text, StartMark) {
+		return ""
+	}
+	text = html.EscapeString(text)
+	return strings.NewReplacer(StartMark, "<mark>", StopMark, "</mark>").Replace(text)
+}
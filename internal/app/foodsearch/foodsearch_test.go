@@ -0,0 +1,12 @@
+package foodsearch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	assert.Equal(t, "", Highlight("Adult Chicken"))
+	assert.Equal(t, "Adult <mark>Chicken</mark> &amp; Rice", Highlight("Adult "+StartMark+"Chicken"+StopMark+" & Rice"))
+	assert.Equal(t, "<mark>&lt;b&gt;</mark>", Highlight(StartMark+"<b>"+StopMark))
+}
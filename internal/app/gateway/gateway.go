@@ -0,0 +1,343 @@
+/*
+Package gateway lets the collars report telemetry and receive config and commands over MQTT.
+
+The collar connects with its access secret as the password and the client identifier collar-{device id},
+so the collar can not take over the session of another one. It may only publish to
+pets/{id}/telemetry and pets/{id}/location and subscribe to pets/{id}/config and pets/{id}/commands of its pet.
+Config and commands are fanned out through the bus, so they reach the collar connected
+to any server instance.
+*/
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/mqtt"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	channelPrefix     = "storypet:mqtt:"
+	publishChannel    = channelPrefix + "publish"
+	disconnectChannel = channelPrefix + "disconnect"
+)
+
+// ErrPlainTextAddr is returned for the listener without TLS reachable from the network,
+// the collars would send their access secrets in the clear to it
+var ErrPlainTextAddr = errors.New("gateway: the MQTT listener without TLS must listen on the loopback address")
+
+// CheckPlainTextAddr refuses the address of the listener without TLS unless it is only reachable from the host,
+// e.g. behind the TLS terminating proxy
+func CheckPlainTextAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return ErrPlainTextAddr
+}
+
+func TelemetryTopic(petID int) string {
+	return fmt.Sprintf("pets/%d/telemetry", petID)
+}
+
+func LocationTopic(petID int) string {
+	return fmt.Sprintf("pets/%d/location", petID)
+}
+
+func ConfigTopic(petID int) string {
+	return fmt.Sprintf("pets/%d/config", petID)
+}
+
+func CommandsTopic(petID int) string {
+	return fmt.Sprintf("pets/%d/commands", petID)
+}
+
+// ActivityRecorder stores the activity reported by the collar
+type ActivityRecorder interface {
+	CreateActivityRecord(record *models.Activity) error
+}
+
+// TrackRecorder stores the GPS fixes reported by the collar
+type TrackRecorder interface {
+	CreateTrackPoints(points []models.TrackPoint) (int, error)
+}
+
+// Command is sent to the collars of the pet which are connected, it is not kept for the offline ones
+type Command struct {
+	Name     string          `json:"command"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	IssuedAt time.Time       `json:"issued_at"`
+}
+
+func (c *Command) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.Name, validation.Required, validation.Length(1, 64)),
+	)
+}
+
+// telemetryMessage is the payload of the telemetry topic, it is the same as of the HTTP endpoint
+type telemetryMessage struct {
+	Distance  float64 `json:"distance"`
+	MeanSpeed float64 `json:"mean_speed"`
+	models.DeviceTelemetry
+}
+
+// outbound is the message for the collars sent through the bus
+type outbound struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+	Retain  bool            `json:"retain"`
+}
+
+type Gateway struct {
+	devices    repos.IoTDevicesRepository
+	activities ActivityRecorder
+	locations  TrackRecorder
+	bus        realtime.Bus
+	logger     *log.Logger
+	broker     *mqtt.Server
+
+	// OnActivity is called after the activity reported by the collar is stored
+	OnActivity func(activity *models.Activity)
+	// OnLocation is called with the accepted fixes after they are stored
+	OnLocation func(petID int, points []tracks.Point)
+}
+
+func New(devices repos.IoTDevicesRepository, activities ActivityRecorder, locations TrackRecorder, bus realtime.Bus, logger *log.Logger) *Gateway {
+	g := &Gateway{
+		devices:    devices,
+		activities: activities,
+		locations:  locations,
+		bus:        bus,
+		logger:     logger,
+	}
+	g.broker = mqtt.NewServer(mqtt.Hooks{
+		Authenticate:   g.authenticate,
+		AllowPublish:   g.allowPublish,
+		AllowSubscribe: g.allowSubscribe,
+		OnPublish:      g.onPublish,
+	}, logger)
+	return g
+}
+
+// ListenAndServe accepts the collar connections on the TCP address until the gateway is closed
+func (g *Gateway) ListenAndServe(addr string) error {
+	return g.broker.ListenAndServe(addr)
+}
+
+// ListenAndServeTLS accepts the collar connections on the TCP address over TLS until the gateway is closed
+func (g *Gateway) ListenAndServeTLS(addr string, certFile string, keyFile string) error {
+	return g.broker.ListenAndServeTLS(addr, certFile, keyFile)
+}
+
+// Serve accepts the collar connections of the listener until the gateway is closed
+func (g *Gateway) Serve(listener net.Listener) error {
+	return g.broker.Serve(listener)
+}
+
+func (g *Gateway) Close() error {
+	return g.broker.Close()
+}
+
+// Run delivers the messages sent by all instances to the collars connected to this one until ctx is done,
+// the subscription is restored if the bus connection is lost
+func (g *Gateway) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := g.bus.Subscribe(ctx, channelPrefix+"*", g.dispatch); err != nil {
+			g.logger.Printf("MQTT gateway subscription error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// PublishConfig sends the config to the collars of the pet. The config is retained, so the collar receives
+// it when it subscribes later, as long as the instance it connects to was running when the config was sent.
+func (g *Gateway) PublishConfig(petID int, config json.RawMessage) error {
+	return g.send(outbound{Topic: ConfigTopic(petID), Payload: config, Retain: true})
+}
+
+func (g *Gateway) SendCommand(petID int, command *Command) error {
+	payload, err := json.Marshal(command)
+	if err != nil {
+		return err
+	}
+	return g.send(outbound{Topic: CommandsTopic(petID), Payload: payload})
+}
+
+// Disconnect closes the connection of the device on all instances, it is used when the device
+// is unpaired, revoked or its secret is rotated
+func (g *Gateway) Disconnect(deviceID int) error {
+	return g.bus.Publish(disconnectChannel, []byte(strconv.Itoa(deviceID)))
+}
+
+func (g *Gateway) send(message outbound) error {
+	raw, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return g.bus.Publish(publishChannel, raw)
+}
+
+func (g *Gateway) dispatch(channel string, message []byte) {
+	switch channel {
+	case publishChannel:
+		out := outbound{}
+		if err := json.Unmarshal(message, &out); err != nil {
+			g.logger.Printf("MQTT gateway message error: %v", err)
+			return
+		}
+		g.broker.Publish(mqtt.Message{Topic: out.Topic, Payload: out.Payload, QoS: 1, Retain: out.Retain})
+	case disconnectChannel:
+		deviceID, err := strconv.Atoi(string(message))
+		if err != nil {
+			g.logger.Printf("MQTT gateway message error: %v", err)
+			return
+		}
+		g.broker.Disconnect(func(client *mqtt.Client) bool {
+			return clientDevice(client).DeviceID == deviceID
+		})
+	}
+}
+
+// DeviceClientID is the MQTT client identifier of the device
+func DeviceClientID(deviceID int) string {
+	return fmt.Sprintf("collar-%d", deviceID)
+}
+
+// authenticate accepts the active device with the access secret given as the password and its own client
+// identifier, the user name is optional and must be the device ID when it is given
+func (g *Gateway) authenticate(clientID string, username string, password []byte) (interface{}, error) {
+	if len(password) == 0 {
+		return nil, mqtt.ErrBadCredentials
+	}
+	device, err := g.devices.GetByAccessSecret(auth.HashIoTDeviceSecret(string(password)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, mqtt.ErrBadCredentials
+		}
+		return nil, err
+	}
+	if username != "" && username != strconv.Itoa(device.DeviceID) {
+		return nil, mqtt.ErrBadCredentials
+	}
+	if !device.IsActive() {
+		return nil, mqtt.ErrNotAuthorized
+	}
+	if clientID != DeviceClientID(device.DeviceID) {
+		return nil, mqtt.ErrIdentifierRejected
+	}
+	return device, nil
+}
+
+func (g *Gateway) allowPublish(client *mqtt.Client, topic string) bool {
+	petID := clientDevice(client).PetID
+	return topic == TelemetryTopic(petID) || topic == LocationTopic(petID)
+}
+
+func (g *Gateway) allowSubscribe(client *mqtt.Client, filter string) bool {
+	petID := clientDevice(client).PetID
+	return filter == ConfigTopic(petID) || filter == CommandsTopic(petID)
+}
+
+// onPublish stores the telemetry. The device is checked again, so the collar which was unpaired
+// or revoked while connected is disconnected without its message being stored.
+func (g *Gateway) onPublish(client *mqtt.Client, message mqtt.Message) error {
+	connected := clientDevice(client)
+	device, err := g.devices.GetByID(connected.DeviceID)
+	if err != nil {
+		return err
+	}
+	if !device.IsActive() || device.PetID != connected.PetID {
+		return mqtt.ErrNotAuthorized
+	}
+	if message.Topic == LocationTopic(device.PetID) {
+		return g.recordLocation(device, message)
+	}
+
+	telemetry := &telemetryMessage{}
+	if err := json.Unmarshal(message.Payload, telemetry); err != nil {
+		// The message is dropped, sending it again would not fix it
+		g.logger.Printf("MQTT telemetry of device %v is malformed: %v", device.DeviceID, err)
+		return nil
+	}
+	if err := telemetry.DeviceTelemetry.Validate(); err != nil {
+		g.logger.Printf("MQTT telemetry of device %v is invalid: %v", device.DeviceID, err)
+		return nil
+	}
+
+	activity := &models.Activity{
+		PetID:           device.PetID,
+		RecordTimestamp: time.Now(),
+		Distance:        telemetry.Distance,
+		MeanSpeed:       telemetry.MeanSpeed,
+	}
+	if err := g.activities.CreateActivityRecord(activity); err != nil {
+		g.logger.Printf("MQTT telemetry of device %v is not stored: %v", device.DeviceID, err)
+		return err
+	}
+	if err := g.devices.Touch(device.DeviceID, &telemetry.DeviceTelemetry, time.Now()); err != nil {
+		g.logger.Printf("MQTT device %v is not touched: %v", device.DeviceID, err)
+	}
+	if g.OnActivity != nil {
+		g.OnActivity(activity)
+	}
+	return nil
+}
+
+// recordLocation stores the JSON array of the GPS fixes, the invalid fixes are dropped
+func (g *Gateway) recordLocation(device *models.IoTDevice, message mqtt.Message) error {
+	points, err := tracks.Decode(bytes.NewReader(message.Payload))
+	if err != nil {
+		g.logger.Printf("MQTT location of device %v is malformed: %v", device.DeviceID, err)
+		return nil
+	}
+	accepted, rejected, err := tracks.Normalize(points, nil, time.Now())
+	if err != nil {
+		g.logger.Printf("MQTT location of device %v is invalid: %v", device.DeviceID, err)
+		return nil
+	}
+	if len(rejected) > 0 {
+		g.logger.Printf("MQTT location of device %v has %d invalid fixes", device.DeviceID, len(rejected))
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+	if _, err := g.locations.CreateTrackPoints(tracks.ToModels(accepted, device.PetID, device.DeviceID)); err != nil {
+		g.logger.Printf("MQTT location of device %v is not stored: %v", device.DeviceID, err)
+		return err
+	}
+	if err := g.devices.Touch(device.DeviceID, &models.DeviceTelemetry{}, time.Now()); err != nil {
+		g.logger.Printf("MQTT device %v is not touched: %v", device.DeviceID, err)
+	}
+	if g.OnLocation != nil {
+		g.OnLocation(device.PetID, accepted)
+	}
+	return nil
+}
+
+func clientDevice(client *mqtt.Client) *models.IoTDevice {
+	return client.Identity.(*models.IoTDevice)
+}
@@ -0,0 +1,343 @@
+package gateway
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/mqtt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBus delivers messages synchronously to the subscribers of the matching prefix
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers map[string]func(channel string, message []byte)
+}
+
+func (m *memoryBus) Publish(channel string, message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for pattern, handler := range m.handlers {
+		if strings.HasPrefix(channel, strings.TrimSuffix(pattern, "*")) {
+			handler(channel, message)
+		}
+	}
+	return nil
+}
+
+func (m *memoryBus) Subscribe(ctx context.Context, pattern string, handler func(channel string, message []byte)) error {
+	m.mu.Lock()
+	m.handlers[pattern] = handler
+	m.mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
+
+func (m *memoryBus) subscribed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.handlers) > 0
+}
+
+type fakeDevices struct {
+	mu      sync.Mutex
+	devices map[int]*models.IoTDevice
+	touched map[int]models.DeviceTelemetry
+}
+
+func (f *fakeDevices) GetByID(deviceID int) (*models.IoTDevice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	device, ok := f.devices[deviceID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *device
+	return &copied, nil
+}
+
+func (f *fakeDevices) GetByAccessSecret(accessSecret string) (*models.IoTDevice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, device := range f.devices {
+		if device.AccessSecret == accessSecret {
+			copied := *device
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeDevices) Create(device *models.IoTDevice) (*models.IoTDevice, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeDevices) SelectByPetID(petID int) ([]models.IoTDevice, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeDevices) Update(device *models.IoTDevice) (*models.IoTDevice, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices[device.DeviceID] = device
+	return device, nil
+}
+
+func (f *fakeDevices) Touch(deviceID int, telemetry *models.DeviceTelemetry, _ time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.touched[deviceID] = *telemetry
+	return nil
+}
+
+type fakeActivities struct {
+	records chan *models.Activity
+}
+
+func (f *fakeActivities) CreateActivityRecord(record *models.Activity) error {
+	f.records <- record
+	return nil
+}
+
+type fakeLocations struct {
+	points chan []models.TrackPoint
+}
+
+func (f *fakeLocations) CreateTrackPoints(points []models.TrackPoint) (int, error) {
+	f.points <- points
+	return len(points), nil
+}
+
+type fixture struct {
+	gateway    *Gateway
+	devices    *fakeDevices
+	activities *fakeActivities
+	locations  *fakeLocations
+	located    chan []tracks.Point
+	addr       string
+}
+
+func start(t *testing.T) *fixture {
+	t.Helper()
+	devices := &fakeDevices{
+		devices: map[int]*models.IoTDevice{
+			7: {DeviceID: 7, PetID: 1, AccessSecret: auth.HashIoTDeviceSecret("collar-secret"), Status: models.IoTDeviceActive},
+			8: {DeviceID: 8, PetID: 2, AccessSecret: auth.HashIoTDeviceSecret("revoked-secret"), Status: models.IoTDeviceRevoked},
+			9: {DeviceID: 9, PetID: 3, AccessSecret: auth.HashIoTDeviceSecret("other-secret"), Status: models.IoTDeviceActive},
+		},
+		touched: make(map[int]models.DeviceTelemetry),
+	}
+	activities := &fakeActivities{records: make(chan *models.Activity, 8)}
+	locations := &fakeLocations{points: make(chan []models.TrackPoint, 8)}
+	bus := &memoryBus{handlers: make(map[string]func(channel string, message []byte))}
+	gateway := New(devices, activities, locations, bus, log.New(ioutil.Discard, "", 0))
+	located := make(chan []tracks.Point, 8)
+	gateway.OnLocation = func(petID int, points []tracks.Point) {
+		if petID == 1 {
+			located <- points
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { _ = gateway.Serve(listener) }()
+	go gateway.Run(ctx)
+	t.Cleanup(func() {
+		cancel()
+		_ = gateway.Close()
+	})
+	require.Eventually(t, bus.subscribed, time.Second, time.Millisecond)
+	return &fixture{gateway: gateway, devices: devices, activities: activities, locations: locations, located: located, addr: listener.Addr().String()}
+}
+
+func (f *fixture) connect(t *testing.T, secret string) *mqtt.ClientConn {
+	t.Helper()
+	client, err := mqtt.Dial(f.addr, mqtt.ClientOptions{ClientID: DeviceClientID(7), Password: []byte(secret)})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestGateway_Authenticate(t *testing.T) {
+	f := start(t)
+
+	for secret, code := range map[string]byte{
+		"wrong-secret":   mqtt.RefusedBadCredentials,
+		"revoked-secret": mqtt.RefusedNotAuthorized,
+	} {
+		_, err := mqtt.Dial(f.addr, mqtt.ClientOptions{ClientID: DeviceClientID(7), Password: []byte(secret)})
+		connectErr := &mqtt.ConnectError{}
+		require.True(t, errors.As(err, &connectErr), secret)
+		assert.Equal(t, code, connectErr.Code, secret)
+	}
+
+	_, err := mqtt.Dial(f.addr, mqtt.ClientOptions{ClientID: DeviceClientID(7), Username: "8", Password: []byte("collar-secret")})
+	assert.Error(t, err)
+	client, err := mqtt.Dial(f.addr, mqtt.ClientOptions{ClientID: DeviceClientID(7), Username: "7", Password: []byte("collar-secret")})
+	require.NoError(t, err)
+
+	// Another collar can not take over the session by its client identifier
+	for _, clientID := range []string{DeviceClientID(7), "collar", ""} {
+		_, err = mqtt.Dial(f.addr, mqtt.ClientOptions{ClientID: clientID, Password: []byte("other-secret")})
+		connectErr := &mqtt.ConnectError{}
+		require.True(t, errors.As(err, &connectErr), clientID)
+		assert.Equal(t, mqtt.RefusedIdentifierRejected, connectErr.Code, clientID)
+	}
+	select {
+	case <-client.Done():
+		t.Fatal("connection of the collar is closed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	_ = client.Close()
+}
+
+func TestGateway_SecretLifecycle(t *testing.T) {
+	f := start(t)
+	refused := func(secret string, code byte) {
+		t.Helper()
+		_, err := mqtt.Dial(f.addr, mqtt.ClientOptions{ClientID: DeviceClientID(7), Password: []byte(secret)})
+		connectErr := &mqtt.ConnectError{}
+		require.True(t, errors.As(err, &connectErr), secret)
+		assert.Equal(t, code, connectErr.Code, secret)
+	}
+	update := func(change func(device *models.IoTDevice) error) {
+		t.Helper()
+		device, err := f.devices.GetByID(7)
+		require.NoError(t, err)
+		require.NoError(t, change(device))
+		_, err = f.devices.Update(device)
+		require.NoError(t, err)
+	}
+
+	update(func(device *models.IoTDevice) error {
+		return device.RotateSecret(auth.HashIoTDeviceSecret("rotated-secret"))
+	})
+	refused("collar-secret", mqtt.RefusedBadCredentials)
+	client := f.connect(t, "rotated-secret")
+	_ = client.Close()
+
+	update(func(device *models.IoTDevice) error {
+		return device.Unpair(auth.HashIoTDeviceSecret("unpaired-secret"))
+	})
+	refused("rotated-secret", mqtt.RefusedBadCredentials)
+	refused("unpaired-secret", mqtt.RefusedNotAuthorized)
+
+	update(func(device *models.IoTDevice) error { return device.Pair(1, auth.HashIoTDeviceSecret("paired-secret")) })
+	refused("unpaired-secret", mqtt.RefusedBadCredentials)
+	client = f.connect(t, "paired-secret")
+	_ = client.Close()
+
+	update(func(device *models.IoTDevice) error {
+		return device.Revoke(auth.HashIoTDeviceSecret("revoked-collar-secret"), time.Now())
+	})
+	refused("paired-secret", mqtt.RefusedBadCredentials)
+	refused("revoked-collar-secret", mqtt.RefusedNotAuthorized)
+}
+
+func TestGateway_Telemetry(t *testing.T) {
+	f := start(t)
+	stored := make(chan *models.Activity, 1)
+	f.gateway.OnActivity = func(activity *models.Activity) { stored <- activity }
+	client := f.connect(t, "collar-secret")
+
+	// Telemetry of another pet is dropped
+	require.NoError(t, client.Publish(TelemetryTopic(2), []byte(`{"distance":1}`), 1, false))
+	require.NoError(t, client.Publish(TelemetryTopic(1), []byte(`{"distance":120.5,"mean_speed":1.4,"battery_level":80}`), 1, false))
+
+	record := <-f.activities.records
+	assert.Equal(t, 1, record.PetID)
+	assert.Equal(t, 120.5, record.Distance)
+	assert.Equal(t, 1.4, record.MeanSpeed)
+	assert.Same(t, record, <-stored)
+	assert.Empty(t, f.activities.records)
+
+	f.devices.mu.Lock()
+	assert.Equal(t, 80, *f.devices.touched[7].BatteryLevel)
+	f.devices.mu.Unlock()
+
+	// The collar unpaired while connected is disconnected on its next message
+	device, _ := f.devices.GetByID(7)
+	device.Status = models.IoTDeviceUnpaired
+	_, _ = f.devices.Update(device)
+	_ = client.Publish(TelemetryTopic(1), []byte(`{"distance":1}`), 1, false)
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("collar is not disconnected")
+	}
+	assert.Empty(t, f.activities.records)
+}
+
+func TestGateway_ConfigAndCommands(t *testing.T) {
+	f := start(t)
+	require.NoError(t, f.gateway.PublishConfig(1, json.RawMessage(`{"report_interval":60}`)))
+
+	client := f.connect(t, "collar-secret")
+	_, err := client.Subscribe(CommandsTopic(2), 1)
+	assert.Equal(t, mqtt.ErrSubscriptionRefused, err)
+	_, err = client.Subscribe(ConfigTopic(1), 1)
+	require.NoError(t, err)
+	_, err = client.Subscribe(CommandsTopic(1), 1)
+	require.NoError(t, err)
+
+	message := <-client.Messages()
+	assert.Equal(t, ConfigTopic(1), message.Topic)
+	assert.JSONEq(t, `{"report_interval":60}`, string(message.Payload))
+
+	require.NoError(t, f.gateway.SendCommand(2, &Command{Name: "beep"}))
+	require.NoError(t, f.gateway.SendCommand(1, &Command{Name: "locate", Params: json.RawMessage(`{"duration":30}`)}))
+	message = <-client.Messages()
+	assert.Equal(t, CommandsTopic(1), message.Topic)
+	command := &Command{}
+	require.NoError(t, json.Unmarshal(message.Payload, command))
+	assert.Equal(t, "locate", command.Name)
+
+	require.NoError(t, f.gateway.Disconnect(7))
+	select {
+	case <-client.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("collar is not disconnected")
+	}
+}
+
+func TestGateway_Location(t *testing.T) {
+	f := start(t)
+	client := f.connect(t, "collar-secret")
+
+	recordedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second).Format(time.RFC3339)
+	payload := `[{"recorded_at":"` + recordedAt + `","latitude":50.45,"longitude":30.52,"accuracy":5},
+		{"recorded_at":"` + recordedAt + `","latitude":95,"longitude":30.52}]`
+	require.NoError(t, client.Publish(LocationTopic(1), []byte(payload), 1, false))
+
+	points := <-f.locations.points
+	require.Len(t, points, 1)
+	assert.Equal(t, 1, points[0].PetID)
+	assert.Equal(t, int64(7), points[0].DeviceID.Int64)
+	assert.Equal(t, 5.0, points[0].Accuracy.Float64)
+
+	located := <-f.located
+	require.Len(t, located, 1)
+	assert.Equal(t, 50.45, located[0].Latitude)
+}
+
+func TestCheckPlainTextAddr(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1:1883", "localhost:1883", "[::1]:1883"} {
+		assert.NoError(t, CheckPlainTextAddr(addr), addr)
+	}
+	for _, addr := range []string{":1883", "0.0.0.0:1883", "10.0.0.5:1883", "collars.example.com:1883"} {
+		assert.Equal(t, ErrPlainTextAddr, CheckPlainTextAddr(addr), addr)
+	}
+}
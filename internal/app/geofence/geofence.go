@@ -0,0 +1,167 @@
+// Package geofence tells whether the pet is inside its zones and confirms the crossings of the zone boundaries
+package geofence
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"math"
+	"time"
+)
+
+const (
+	// MinMargin is the distance in meters to the boundary within which the fix tells nothing,
+	// the accuracy of the fix is used instead when it is larger
+	MinMargin = 10.0
+	// ConfirmFixes is the count of consecutive fixes on the other side of the boundary which confirms the crossing
+	ConfirmFixes = 2
+)
+
+type Side int
+
+const (
+	Unsure Side = iota
+	Inside
+	Outside
+)
+
+// Transition is the confirmed crossing, Point is the fix which has confirmed it
+type Transition struct {
+	Kind  string
+	Point tracks.Point
+}
+
+// Locate tells on which side of the zone boundary the fix is
+func Locate(fence *models.Geofence, point tracks.Point) Side {
+	margin := MinMargin
+	if point.Accuracy != nil && *point.Accuracy > margin {
+		margin = *point.Accuracy
+	}
+
+	switch fence.Shape {
+	case models.GeofenceCircle:
+		if fence.SpecifiedCenter == nil {
+			return Unsure
+		}
+		center := tracks.Point{Longitude: fence.SpecifiedCenter[0], Latitude: fence.SpecifiedCenter[1]}
+		distance := tracks.Distance(center, point)
+		switch {
+		case distance < fence.SpecifiedRadius-margin:
+			return Inside
+		case distance > fence.SpecifiedRadius+margin:
+			return Outside
+		}
+	case models.GeofencePolygon:
+		if len(fence.SpecifiedPolygon) < 3 || boundaryDistance(fence.SpecifiedPolygon, point) <= margin {
+			return Unsure
+		}
+		if containsPoint(fence.SpecifiedPolygon, point) {
+			return Inside
+		}
+		return Outside
+	}
+	return Unsure
+}
+
+/*
+Evaluate advances the debouncing state of the zone by the fixes ordered by time and returns the confirmed crossings.
+
+The first confident fix only sets where the pet is, the crossing is confirmed by ConfirmFixes consecutive
+fixes on the other side. Fixes not newer than the last evaluated one are skipped, so fixes uploaded again
+or late do not move the state back in time.
+*/
+func Evaluate(fence *models.Geofence, points []tracks.Point) []Transition {
+	transitions := make([]Transition, 0)
+	for _, point := range points {
+		if fence.LastFixAt != nil && fence.LastFixAt.Valid && !point.RecordedAt.After(fence.LastFixAt.Time) {
+			continue
+		}
+		fence.LastFixAt = &sql.NullTime{Time: point.RecordedAt, Valid: true}
+
+		side := Locate(fence, point)
+		if side == Unsure {
+			continue
+		}
+		inside := side == Inside
+		if fence.Inside == nil || !fence.Inside.Valid {
+			fence.Inside = &sql.NullBool{Bool: inside, Valid: true}
+			fence.PendingFixes = 0
+			continue
+		}
+		if fence.Inside.Bool == inside {
+			fence.PendingFixes = 0
+			continue
+		}
+
+		fence.PendingFixes++
+		if fence.PendingFixes < ConfirmFixes {
+			continue
+		}
+		fence.Inside = &sql.NullBool{Bool: inside, Valid: true}
+		fence.PendingFixes = 0
+		kind := models.GeofenceExit
+		if inside {
+			kind = models.GeofenceEnter
+		}
+		transitions = append(transitions, Transition{Kind: kind, Point: point})
+	}
+	return transitions
+}
+
+// NewEvent is the stored event of the transition
+func NewEvent(fence *models.Geofence, transition Transition) *models.GeofenceEvent {
+	return &models.GeofenceEvent{
+		GeofenceID:   fence.GeofenceID,
+		GeofenceName: fence.Name,
+		PetID:        fence.PetID,
+		Kind:         transition.Kind,
+		OccurredAt:   transition.Point.RecordedAt.UTC(),
+		Latitude:     transition.Point.Latitude,
+		Longitude:    transition.Point.Longitude,
+		CreatedAt:    time.Now().UTC(),
+	}
+}
+
+// containsPoint is the ray casting test, the polygon is small enough to treat longitude and latitude as plane coordinates
+func containsPoint(polygon [][2]float64, point tracks.Point) bool {
+	inside := false
+	x, y := point.Longitude, point.Latitude
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := polygon[i][0], polygon[i][1]
+		xj, yj := polygon[j][0], polygon[j][1]
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// boundaryDistance is the distance in meters from the fix to the closest edge of the polygon
+func boundaryDistance(polygon [][2]float64, point tracks.Point) float64 {
+	// Equirectangular projection around the fix is precise enough at the scale of the zones
+	metersPerDegree := math.Pi / 180 * 6371008.8
+	scaleX := metersPerDegree * math.Cos(point.Latitude*math.Pi/180)
+	project := func(position [2]float64) (float64, float64) {
+		return (position[0] - point.Longitude) * scaleX, (position[1] - point.Latitude) * metersPerDegree
+	}
+
+	closest := math.Inf(1)
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		ax, ay := project(polygon[j])
+		bx, by := project(polygon[i])
+		if distance := segmentDistance(ax, ay, bx, by); distance < closest {
+			closest = distance
+		}
+	}
+	return closest
+}
+
+// segmentDistance is the distance from the origin to the segment
+func segmentDistance(ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/length))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
@@ -0,0 +1,104 @@
+package geofence
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// A degree of latitude is about 111 km, so 0.001 is about 111 m
+var (
+	home = &models.Geofence{
+		GeofenceID:      1,
+		PetID:           1,
+		Name:            "Home",
+		Shape:           models.GeofenceCircle,
+		SpecifiedCenter: &[2]float64{30.52, 50.45},
+		SpecifiedRadius: 100,
+	}
+	park = &models.Geofence{
+		GeofenceID: 2,
+		PetID:      1,
+		Name:       "Park",
+		Shape:      models.GeofencePolygon,
+		SpecifiedPolygon: [][2]float64{
+			{30.50, 50.40}, {30.51, 50.40}, {30.51, 50.41}, {30.50, 50.41},
+		},
+	}
+)
+
+func fix(minute int, latitude float64, longitude float64) tracks.Point {
+	return tracks.Point{
+		RecordedAt: time.Date(2026, 5, 1, 10, minute, 0, 0, time.UTC),
+		Latitude:   latitude,
+		Longitude:  longitude,
+	}
+}
+
+func TestLocate_Circle(t *testing.T) {
+	assert.Equal(t, Inside, Locate(home, fix(0, 50.45, 30.52)))
+	assert.Equal(t, Inside, Locate(home, fix(0, 50.4505, 30.52)))
+	assert.Equal(t, Unsure, Locate(home, fix(0, 50.4509, 30.52)))
+	assert.Equal(t, Outside, Locate(home, fix(0, 50.452, 30.52)))
+
+	rough := fix(0, 50.4505, 30.52)
+	accuracy := 80.0
+	rough.Accuracy = &accuracy
+	assert.Equal(t, Unsure, Locate(home, rough))
+}
+
+func TestLocate_Polygon(t *testing.T) {
+	assert.Equal(t, Inside, Locate(park, fix(0, 50.405, 30.505)))
+	assert.Equal(t, Outside, Locate(park, fix(0, 50.415, 30.505)))
+	assert.Equal(t, Outside, Locate(park, fix(0, 50.405, 30.52)))
+	// 5 m from the southern edge
+	assert.Equal(t, Unsure, Locate(park, fix(0, 50.40005, 30.505)))
+	assert.Equal(t, Unsure, Locate(park, fix(0, 50.39995, 30.505)))
+}
+
+func TestEvaluate_Debounce(t *testing.T) {
+	fence := *home
+	points := []tracks.Point{
+		fix(0, 50.45, 30.52),
+		fix(1, 50.452, 30.52),
+		fix(2, 50.45, 30.52),
+		fix(3, 50.452, 30.52),
+		fix(4, 50.4509, 30.52),
+		fix(5, 50.453, 30.52),
+	}
+	transitions := Evaluate(&fence, points)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, models.GeofenceExit, transitions[0].Kind)
+	assert.Equal(t, points[5].RecordedAt, transitions[0].Point.RecordedAt)
+	assert.False(t, fence.Inside.Bool)
+	assert.Equal(t, 0, fence.PendingFixes)
+	assert.Equal(t, points[5].RecordedAt, fence.LastFixAt.Time)
+
+	transitions = Evaluate(&fence, []tracks.Point{fix(6, 50.45, 30.52), fix(7, 50.4501, 30.52)})
+	require.Len(t, transitions, 1)
+	assert.Equal(t, models.GeofenceEnter, transitions[0].Kind)
+}
+
+func TestEvaluate_InitialState(t *testing.T) {
+	fence := *home
+	transitions := Evaluate(&fence, []tracks.Point{fix(0, 50.4509, 30.52), fix(1, 50.46, 30.52), fix(2, 50.46, 30.52)})
+	assert.Empty(t, transitions)
+	require.True(t, fence.Inside.Valid)
+	assert.False(t, fence.Inside.Bool)
+}
+
+func TestEvaluate_SkipsOldFixes(t *testing.T) {
+	fence := *home
+	Evaluate(&fence, []tracks.Point{fix(10, 50.45, 30.52)})
+
+	transitions := Evaluate(&fence, []tracks.Point{fix(5, 50.46, 30.52), fix(10, 50.46, 30.52)})
+	assert.Empty(t, transitions)
+	assert.Equal(t, 0, fence.PendingFixes)
+
+	transitions = Evaluate(&fence, []tracks.Point{fix(11, 50.46, 30.52), fix(12, 50.46, 30.52)})
+	require.Len(t, transitions, 1)
+	assert.Equal(t, models.GeofenceExit, transitions[0].Kind)
+}
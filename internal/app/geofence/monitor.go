@@ -0,0 +1,103 @@
+package geofence
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"log"
+	"sort"
+)
+
+// Alerter tells the owner about the pet crossing the zone boundary, the notifier is used by the server
+type Alerter interface {
+	NotifyGeofenceEvent(pet *models.Pet, fence *models.Geofence, event *models.GeofenceEvent) error
+}
+
+// Monitor evaluates the pet zones on every location ingest, stores the events and alerts the owner
+type Monitor struct {
+	database store.DatabaseStore
+	alerter  Alerter
+	logger   *log.Logger
+
+	// OnEvent is called with the every stored event, e.g. to publish it to the pet stream
+	OnEvent func(event *models.GeofenceEvent)
+}
+
+// NewMonitor creates the monitor, alerter may be nil if the owners are not alerted
+func NewMonitor(database store.DatabaseStore, alerter Alerter, logger *log.Logger) *Monitor {
+	return &Monitor{
+		database: database,
+		alerter:  alerter,
+		logger:   logger,
+	}
+}
+
+// Process evaluates the active zones of the pet by the accepted fixes and returns the stored events
+func (m *Monitor) Process(petID int, points []tracks.Point) ([]models.GeofenceEvent, error) {
+	if len(points) == 0 {
+		return nil, nil
+	}
+	fences, err := m.database.Geofences().SelectActiveByPetID(petID)
+	if err != nil || len(fences) == 0 {
+		return nil, err
+	}
+
+	ordered := append([]tracks.Point(nil), points...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].RecordedAt.Before(ordered[j].RecordedAt)
+	})
+
+	var pet *models.Pet
+	stored := make([]models.GeofenceEvent, 0)
+	for idx := range fences {
+		fence := &fences[idx]
+		previousFixAt := fence.LastFixAt
+		transitions := Evaluate(fence, ordered)
+		if fence.LastFixAt == previousFixAt {
+			continue
+		}
+
+		events := make([]models.GeofenceEvent, len(transitions))
+		for tIdx, transition := range transitions {
+			events[tIdx] = *NewEvent(fence, transition)
+		}
+		events, saved, err := m.database.Geofences().SaveEvaluation(fence, previousFixAt, events)
+		if err != nil {
+			return stored, err
+		}
+		if !saved {
+			// The zone has been evaluated concurrently by the newer fixes
+			continue
+		}
+
+		for eIdx := range events {
+			event := &events[eIdx]
+			stored = append(stored, *event)
+			if m.OnEvent != nil {
+				m.OnEvent(event)
+			}
+			if !m.shouldAlert(fence, event) {
+				continue
+			}
+			if pet == nil {
+				if pet, err = m.database.Pets().FindByID(petID); err != nil {
+					return stored, err
+				}
+			}
+			if err := m.alerter.NotifyGeofenceEvent(pet, fence, event); err != nil {
+				m.logger.Printf("Geofence alert error: %v Event ID: %v", err, event.EventID)
+			}
+		}
+	}
+	return stored, nil
+}
+
+func (m *Monitor) shouldAlert(fence *models.Geofence, event *models.GeofenceEvent) bool {
+	if m.alerter == nil {
+		return false
+	}
+	if event.Kind == models.GeofenceEnter {
+		return fence.AlertOnEnter
+	}
+	return fence.AlertOnExit
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/filesutil"
+	"log"
+)
+
+const (
+	KindDatabaseDump      = "database.dump"
+	KindPurgeMissingDumps = "database.purge_missing_dumps"
+)
+
+// DatabaseDump makes the database dump into the folder
+func DatabaseDump(database store.DatabaseStore, dumpsFolder string) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		_, err := database.Dumps().Make(dumpsFolder)
+		return err
+	}
+}
+
+// PurgeMissingDumps deletes records of the dumps whose files do not exist anymore,
+// e.g. after the ephemeral filesystem of the server was reset
+func PurgeMissingDumps(database store.DatabaseStore, logger *log.Logger) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		dumps, err := database.Dumps().SelectAll()
+		if err != nil {
+			return err
+		}
+		for _, dump := range dumps {
+			if filesutil.Exist(dump.FilePath) {
+				continue
+			}
+			if _, err := database.Dumps().DeleteByName(dump.FileName); err != nil {
+				return err
+			}
+			logger.Printf("Purged the record of missing dump file %s", dump.FileName)
+		}
+		return nil
+	}
+}
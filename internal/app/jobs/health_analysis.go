@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/anomalies"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"time"
+)
+
+const KindAnalyzeHealth = "health.analyze"
+
+// AnalyzeHealth looks for the anomalies in the statistics of every pet and syncs the pet alerts with them
+func AnalyzeHealth(database store.DatabaseStore) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		pets, err := database.Pets().SelectAll()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for idx := range pets {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			foodCalories, rerCalories, anthropometry, activity, err := database.Pets().GetPetStatistics(pets[idx].PetID)
+			if err != nil {
+				return err
+			}
+			signals := anomalies.Analyze(&anomalies.Statistics{
+				FoodCalories:  foodCalories,
+				RERCalories:   rerCalories,
+				Anthropometry: anthropometry,
+				Activity:      activity,
+			}, now)
+
+			alerts := make([]models.HealthAlert, len(signals))
+			for sIdx := range signals {
+				alerts[sIdx] = signals[sIdx].Alert(pets[idx].PetID)
+			}
+			if _, err := database.HealthAlerts().Sync(pets[idx].PetID, alerts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/medication"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"time"
+)
+
+const KindMedicationReminders = "medications.reminders"
+
+// medicationLookback limits the doses the owner is told about after the reminders have not run for a while
+const medicationLookback = 24 * time.Hour
+
+// MedicationReminderNotifier delivers reminders about the pet medication doses to its owner
+type MedicationReminderNotifier interface {
+	NotifyDosesDue(pet *models.Pet, medication *models.Medication, doses []time.Time) error
+	NotifyDosesMissed(pet *models.Pet, medication *models.Medication, doses []time.Time) error
+}
+
+/*
+MedicationReminders reminds owners about the doses which have become due since the previous run
+and alerts them about the doses which have not been given within their window.
+
+The times the owner has been told about are stored with the medication, so every dose is reminded
+and reported missed once. They are not moved on the failed delivery, the next run retries it.
+*/
+func MedicationReminders(database store.DatabaseStore, notifier MedicationReminderNotifier) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		now := time.Now().UTC()
+		medications, err := database.Medications().SelectScheduled(now)
+		if err != nil {
+			return err
+		}
+		pets := make(map[int]*models.Pet)
+		var failed error
+		for idx := range medications {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			prescribed := &medications[idx]
+			remindFrom, checkFrom := reminderCursors(prescribed, now)
+			due := medication.Schedule(prescribed, remindFrom, now)
+			doses, err := database.Medications().SelectDoses(prescribed.MedicationID, checkFrom.Add(-2*prescribed.DoseWindow()), now)
+			if err != nil {
+				return err
+			}
+			missed := medication.Missed(prescribed, doses, checkFrom, now)
+
+			remindedUntil, checkedUntil := now, now
+			if len(due) != 0 || len(missed) != 0 {
+				pet, ok := pets[prescribed.PetID]
+				if !ok {
+					if pet, err = database.Pets().FindByID(prescribed.PetID); err != nil {
+						return err
+					}
+					pets[prescribed.PetID] = pet
+				}
+				// A single failed delivery should not stop reminding other owners
+				if len(due) != 0 {
+					if err := notifier.NotifyDosesDue(pet, prescribed, due); err != nil {
+						failed, remindedUntil = err, remindFrom
+					}
+				}
+				if len(missed) != 0 {
+					if err := notifier.NotifyDosesMissed(pet, prescribed, missed); err != nil {
+						failed, checkedUntil = err, checkFrom
+					}
+				}
+			}
+			if err := database.Medications().SaveReminders(prescribed.MedicationID, remindedUntil, checkedUntil); err != nil {
+				return err
+			}
+		}
+		return failed
+	}
+}
+
+// reminderCursors are the times the doses have not been reminded and checked from, a lookback ago at most
+func reminderCursors(prescribed *models.Medication, now time.Time) (time.Time, time.Time) {
+	earliest := now.Add(-medicationLookback)
+	cursor := func(until *sql.NullTime) time.Time {
+		// The doses scheduled before the medication was prescribed are not reminded
+		from := prescribed.StartsAt
+		if prescribed.CreatedAt.After(from) {
+			from = prescribed.CreatedAt
+		}
+		if until != nil && until.Valid {
+			from = until.Time
+		}
+		if from.Before(earliest) {
+			from = earliest
+		}
+		return from
+	}
+	return cursor(prescribed.RemindedUntil), cursor(prescribed.CheckedUntil)
+}
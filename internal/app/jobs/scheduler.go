@@ -0,0 +1,296 @@
+// Package jobs runs background work from the persistent queue stored in the database.
+//
+// Every server instance runs workers, which claim due jobs, so the work is shared
+// between instances. Periodic jobs are enqueued by cron schedules, and only the
+// instance holding the leader advisory lock enqueues them, so they are not duplicated.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/cron"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// leaderLockKey is the advisory lock key identifying the scheduler leader
+	leaderLockKey int64 = 0x53746f7279506574
+
+	workersCount   = 2
+	pollInterval   = 5 * time.Second
+	leaderInterval = 30 * time.Second
+	// staleTimeout is how long the job may stay running before its worker is considered dead
+	staleTimeout = 30 * time.Minute
+
+	DefaultMaxAttempts = 5
+)
+
+// Now is the clock of the queue. The time columns of the jobs are TIMESTAMP without the time zone,
+// so all of them are written and compared in UTC whatever the time zone of the server is.
+func Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Handler performs the job. Returned error makes the job retried with backoff until attempts are exhausted.
+type Handler func(ctx context.Context, job *models.Job) error
+
+type schedule struct {
+	name        string
+	kind        string
+	cron        *cron.Schedule
+	payload     []byte
+	maxAttempts int
+}
+
+type Scheduler struct {
+	database store.DatabaseStore
+	logger   *log.Logger
+
+	handlers  map[string]Handler
+	schedules []schedule
+	startedAt time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	lock   repos.AdvisoryLock
+}
+
+func NewScheduler(database store.DatabaseStore, logger *log.Logger) *Scheduler {
+	return &Scheduler{
+		database: database,
+		logger:   logger,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Handle registers the handler of the jobs kind
+func (s *Scheduler) Handle(kind string, handler Handler) {
+	s.handlers[kind] = handler
+}
+
+// Schedule makes the leader enqueue the job of the kind by the cron expression. Schedule times are UTC.
+func (s *Scheduler) Schedule(name string, expression string, kind string, payload interface{}) error {
+	parsed, err := cron.Parse(expression)
+	if err != nil {
+		return err
+	}
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	s.schedules = append(s.schedules, schedule{
+		name:        name,
+		kind:        kind,
+		cron:        parsed,
+		payload:     rawPayload,
+		maxAttempts: DefaultMaxAttempts,
+	})
+	return nil
+}
+
+// Enqueue adds the job to the queue to be run at runAt
+func (s *Scheduler) Enqueue(kind string, payload interface{}, runAt time.Time) (*models.Job, error) {
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.database.Jobs().Enqueue(kind, rawPayload, runAt.UTC(), DefaultMaxAttempts)
+}
+
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.startedAt = Now()
+
+	for i := 0; i < workersCount; i++ {
+		s.wg.Add(1)
+		go s.work(ctx)
+	}
+	s.wg.Add(1)
+	go s.lead(ctx)
+}
+
+// Stop waits for running jobs to finish and gives up the leadership
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.resign()
+}
+
+func (s *Scheduler) work(ctx context.Context) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		// Drain all due jobs before waiting for the next tick
+		for ctx.Err() == nil {
+			job, err := s.database.Jobs().ClaimNext(Now())
+			if err != nil {
+				if !errors.Is(err, sql.ErrNoRows) {
+					s.logger.Printf("Jobs error: %v", err)
+				}
+				break
+			}
+			s.run(ctx, job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job *models.Job) {
+	err := s.safeCall(ctx, job)
+	if err == nil {
+		if err := s.database.Jobs().Complete(job.JobID); err != nil {
+			s.logger.Printf("Jobs error: %v", err)
+		}
+		return
+	}
+
+	s.logger.Printf("Job %d %s attempt %d failed: %v", job.JobID, job.Kind, job.Attempts, err)
+	if job.HasAttemptsLeft() {
+		err = s.database.Jobs().Retry(job.JobID, err.Error(), Now().Add(Backoff(job.Attempts)))
+	} else {
+		err = s.database.Jobs().Fail(job.JobID, err.Error())
+	}
+	if err != nil {
+		s.logger.Printf("Jobs error: %v", err)
+	}
+}
+
+// safeCall runs the handler converting its panic into the job error, so the worker survives
+func (s *Scheduler) safeCall(ctx context.Context, job *models.Job) (err error) {
+	handler, ok := s.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func (s *Scheduler) lead(ctx context.Context) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(leaderInterval)
+	defer ticker.Stop()
+	for {
+		if s.elect() {
+			s.maintain(Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// elect checks the held leadership is still valid or tries to take it
+func (s *Scheduler) elect() bool {
+	if s.lock != nil {
+		if err := s.lock.Ping(); err == nil {
+			return true
+		}
+		s.logger.Println("Jobs scheduler lost the leadership")
+		s.resign()
+	}
+	lock, acquired, err := s.database.Jobs().TryAdvisoryLock(leaderLockKey)
+	if err != nil || !acquired {
+		return false
+	}
+	s.logger.Println("Jobs scheduler is elected as leader")
+	s.lock = lock
+	return true
+}
+
+func (s *Scheduler) resign() {
+	if s.lock == nil {
+		return
+	}
+	if err := s.lock.Release(); err != nil {
+		s.logger.Printf("Jobs error: %v", err)
+	}
+	s.lock = nil
+}
+
+// maintain requeues the jobs of dead workers and enqueues the due scheduled jobs
+func (s *Scheduler) maintain(now time.Time) {
+	if _, err := s.database.Jobs().RequeueStale(now.Add(-staleTimeout)); err != nil {
+		s.logger.Printf("Jobs error: %v", err)
+	}
+	for idx := range s.schedules {
+		if err := s.enqueueDue(&s.schedules[idx], now); err != nil {
+			s.logger.Printf("Jobs schedule %s error: %v", s.schedules[idx].name, err)
+		}
+	}
+}
+
+func (s *Scheduler) enqueueDue(sch *schedule, now time.Time) error {
+	lastRunAt := s.startedAt
+	stored, err := s.database.Jobs().FindSchedule(sch.name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if stored != nil {
+		lastRunAt = stored.LastRunAt
+	}
+
+	activation := latestActivation(sch.cron, lastRunAt, now)
+	if activation.IsZero() {
+		return nil
+	}
+	_, err = s.database.Jobs().EnqueueScheduled(
+		&models.JobSchedule{Name: sch.name, LastRunAt: activation},
+		sch.kind,
+		sch.payload,
+		sch.maxAttempts,
+	)
+	return err
+}
+
+// latestActivation returns the last activation of the schedule in (after, now],
+// so the activations missed while no leader was running produce the single job.
+// Zero time is returned if there is no activation in the interval.
+func latestActivation(sch *cron.Schedule, after time.Time, now time.Time) time.Time {
+	var latest time.Time
+	for next := sch.Next(after); !next.IsZero() && !next.After(now); next = sch.Next(next) {
+		latest = next
+	}
+	return latest
+}
+
+// Backoff is the delay before the next attempt of the job which failed attempt times
+func Backoff(attempt int) time.Duration {
+	const (
+		base    = 30 * time.Second
+		maximum = 6 * time.Hour
+	)
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maximum {
+			return maximum
+		}
+	}
+	return delay
+}
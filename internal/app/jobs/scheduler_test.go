@@ -0,0 +1,201 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/cron"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(0))
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 4*time.Minute, Backoff(4))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func TestLatestActivation(t *testing.T) {
+	daily := cron.MustParse("0 3 * * *")
+	after := time.Date(2021, 6, 1, 3, 0, 0, 0, time.UTC)
+
+	// Before the next activation nothing is due
+	assert.True(t, latestActivation(daily, after, time.Date(2021, 6, 2, 2, 59, 0, 0, time.UTC)).IsZero())
+	assert.Equal(t,
+		time.Date(2021, 6, 2, 3, 0, 0, 0, time.UTC),
+		latestActivation(daily, after, time.Date(2021, 6, 2, 3, 0, 0, 0, time.UTC)),
+	)
+	// Missed activations collapse into the latest one
+	assert.Equal(t,
+		time.Date(2021, 6, 5, 3, 0, 0, 0, time.UTC),
+		latestActivation(daily, after, time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC)),
+	)
+}
+
+func TestReminderDay(t *testing.T) {
+	today := calendarDay(time.Date(2021, 6, 10, 23, 30, 0, 0, time.FixedZone("EEST", 3*60*60)))
+	shotOn := func(status string, date time.Time) models.ScheduledVaccination {
+		return models.ScheduledVaccination{CatalogueID: 1, Status: status, Date: date}
+	}
+	testCases := []struct {
+		name       string
+		shot       models.ScheduledVaccination
+		daysBefore int
+		expected   bool
+	}{
+		{"week before", shotOn(models.VaccinationDue, today.AddDate(0, 0, 7)), 7, true},
+		{"day before", shotOn(models.VaccinationDue, today.AddDate(0, 0, 1)), 1, true},
+		{"due today", shotOn(models.VaccinationDue, time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)), 0, true},
+		{"three days before", shotOn(models.VaccinationDue, today.AddDate(0, 0, 3)), 0, false},
+		{"overdue for a week", shotOn(models.VaccinationOverdue, today.AddDate(0, 0, -7)), -7, true},
+		{"overdue for two days", shotOn(models.VaccinationOverdue, today.AddDate(0, 0, -2)), -2, false},
+		{"completed", shotOn(models.VaccinationCompleted, today), 0, false},
+		{"unscheduled", shotOn(models.VaccinationUnscheduled, time.Time{}), 0, false},
+		{"out of catalogue", models.ScheduledVaccination{Status: models.VaccinationDue, Date: today}, 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			daysBefore, ok := reminderDay(tc.shot, today)
+			assert.Equal(t, tc.expected, ok)
+			if ok {
+				assert.Equal(t, tc.daysBefore, daysBefore)
+			}
+		})
+	}
+}
+
+func TestReminderCursors(t *testing.T) {
+	now := time.Date(2021, 6, 10, 15, 0, 0, 0, time.UTC)
+	prescribed := &models.Medication{
+		StartsAt:  now.Add(-48 * time.Hour),
+		CreatedAt: now.Add(-2 * time.Hour),
+	}
+
+	// The doses scheduled before the prescription are skipped
+	remindFrom, checkFrom := reminderCursors(prescribed, now)
+	assert.Equal(t, now.Add(-2*time.Hour), remindFrom)
+	assert.Equal(t, now.Add(-2*time.Hour), checkFrom)
+
+	prescribed.RemindedUntil = &sql.NullTime{Time: now.Add(-15 * time.Minute), Valid: true}
+	prescribed.CheckedUntil = &sql.NullTime{Time: now.Add(-72 * time.Hour), Valid: true}
+	remindFrom, checkFrom = reminderCursors(prescribed, now)
+	assert.Equal(t, now.Add(-15*time.Minute), remindFrom)
+	// The reminders which have not run for long only look a day back
+	assert.Equal(t, now.Add(-medicationLookback), checkFrom)
+}
+
+// clockJobs records the times the scheduler passes to the queue
+type clockJobs struct {
+	repos.JobRepository
+
+	mu        sync.Mutex
+	claimed   bool
+	times     map[string]time.Time
+	retriedCh chan struct{}
+}
+
+func (j *clockJobs) record(name string, value time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.times[name] = value
+}
+
+func (j *clockJobs) Enqueue(_ string, _ []byte, runAt time.Time, _ int) (*models.Job, error) {
+	j.record("enqueue", runAt)
+	return &models.Job{}, nil
+}
+
+func (j *clockJobs) ClaimNext(now time.Time) (*models.Job, error) {
+	j.record("claim", now)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.claimed {
+		return nil, sql.ErrNoRows
+	}
+	j.claimed = true
+	return &models.Job{JobID: 1, Kind: "failing", Attempts: 1, MaxAttempts: 3}, nil
+}
+
+func (j *clockJobs) Retry(_ int, _ string, runAt time.Time) error {
+	j.record("retry", runAt)
+	close(j.retriedCh)
+	return nil
+}
+
+func (j *clockJobs) RequeueStale(lockedBefore time.Time) (int64, error) {
+	j.record("requeue", lockedBefore)
+	return 0, nil
+}
+
+func (j *clockJobs) FindSchedule(string) (*models.JobSchedule, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (j *clockJobs) EnqueueScheduled(schedule *models.JobSchedule, _ string, _ []byte, _ int) (*models.Job, error) {
+	j.record("schedule", schedule.LastRunAt)
+	return &models.Job{}, nil
+}
+
+func (j *clockJobs) TryAdvisoryLock(int64) (repos.AdvisoryLock, bool, error) {
+	return nil, false, nil
+}
+
+type clockStore struct {
+	store.DatabaseStore
+	jobs *clockJobs
+}
+
+func (s *clockStore) Jobs() repos.JobRepository {
+	return s.jobs
+}
+
+func TestScheduler_UTCClock(t *testing.T) {
+	// The server runs west of UTC, the queue must not see its local time
+	local := time.Local
+	time.Local = time.FixedZone("UTC-7", -7*60*60)
+	defer func() { time.Local = local }()
+
+	jobs := &clockJobs{times: make(map[string]time.Time), retriedCh: make(chan struct{})}
+	scheduler := NewScheduler(&clockStore{jobs: jobs}, log.New(ioutil.Discard, "", 0))
+	scheduler.Handle("failing", func(context.Context, *models.Job) error { return errors.New("failed") })
+	require.NoError(t, scheduler.Schedule("minutely", "* * * * *", "noop", nil))
+
+	scheduler.Start()
+	defer scheduler.Stop()
+	select {
+	case <-jobs.retriedCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job is not retried")
+	}
+	scheduler.startedAt = Now().Add(-time.Hour)
+	scheduler.maintain(Now())
+	_, err := scheduler.Enqueue("noop", nil, time.Now())
+	require.NoError(t, err)
+
+	now := time.Now()
+	expected := map[string]time.Time{
+		"claim":    now,
+		"retry":    now.Add(Backoff(1)),
+		"requeue":  now.Add(-staleTimeout),
+		"schedule": now.Truncate(time.Minute),
+		"enqueue":  now,
+	}
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	for name, value := range expected {
+		recorded, ok := jobs.times[name]
+		require.True(t, ok, name)
+		assert.Equal(t, time.UTC, recorded.Location(), name)
+		assert.WithinDuration(t, value, recorded, 5*time.Second, name)
+	}
+}
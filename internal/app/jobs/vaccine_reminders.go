@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"time"
+)
+
+const KindVaccineReminders = "vaccines.reminders"
+
+// reminderDaysBefore are the days before the due date the owner is reminded at,
+// overdue shots are reminded weekly after the due date
+var reminderDaysBefore = []int{7, 1, 0}
+
+// VaccineReminderNotifier delivers reminders about the pet vaccinations to its owner
+type VaccineReminderNotifier interface {
+	NotifyVaccinationsDue(pet *models.Pet, shots []models.ScheduledVaccination) error
+}
+
+/*
+VaccineReminders reminds owners about vaccinations of their pets which are due or overdue today.
+
+The sent reminders are recorded by the shot and its due date, so every reminder is sent once even
+when the job runs several times a day. They are not recorded on the failed delivery, the next run retries it.
+*/
+func VaccineReminders(database store.DatabaseStore, notifier VaccineReminderNotifier) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		pets, err := database.Pets().SelectAll()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		today := calendarDay(now)
+		catalogues := make(map[int][]models.VaccineCatalogueEntry)
+		var failed error
+		for idx := range pets {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			catalogue, ok := catalogues[pets[idx].PetType]
+			if !ok {
+				catalogue, err = database.Vaccines().SelectCatalogueByPetType(pets[idx].PetType)
+				if err != nil {
+					return err
+				}
+				catalogues[pets[idx].PetType] = catalogue
+			}
+			vaccines, err := database.Vaccines().SelectByPetID(pets[idx].PetID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			sent, err := database.Vaccines().SelectRemindersByPetID(pets[idx].PetID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			reminded := make(map[reminderKey]bool, len(sent))
+			for _, reminder := range sent {
+				reminded[keyOfReminder(&reminder)] = true
+			}
+
+			var due []models.ScheduledVaccination
+			var reminders []models.VaccineReminder
+			for _, shot := range models.VaccinationSchedule(&pets[idx], catalogue, vaccines, today) {
+				daysBefore, ok := reminderDay(shot, today)
+				if !ok {
+					continue
+				}
+				reminder := models.VaccineReminder{
+					PetID:       pets[idx].PetID,
+					CatalogueID: shot.CatalogueID,
+					DoseNumber:  shot.DoseNumber,
+					DueDate:     calendarDay(shot.Date),
+					DaysBefore:  daysBefore,
+					SentAt:      now,
+				}
+				if reminded[keyOfReminder(&reminder)] {
+					continue
+				}
+				due = append(due, shot)
+				reminders = append(reminders, reminder)
+			}
+			if len(due) == 0 {
+				continue
+			}
+			// A single failed delivery should not stop reminding other owners
+			if err := notifier.NotifyVaccinationsDue(&pets[idx], due); err != nil {
+				failed = err
+				continue
+			}
+			if err := database.Vaccines().CreateReminders(reminders); err != nil {
+				return err
+			}
+		}
+		return failed
+	}
+}
+
+// reminderKey identifies the reminder about the shot due at the date
+type reminderKey struct {
+	catalogueID int
+	doseNumber  int
+	dueDate     string
+	daysBefore  int
+}
+
+func keyOfReminder(reminder *models.VaccineReminder) reminderKey {
+	return reminderKey{
+		catalogueID: reminder.CatalogueID,
+		doseNumber:  reminder.DoseNumber,
+		dueDate:     reminder.DueDate.Format(models.DateLayout),
+		daysBefore:  reminder.DaysBefore,
+	}
+}
+
+// reminderDay reports whether the owner is reminded about the shot today and how many days before its due date.
+// Only the dated shots of the catalogue vaccines are reminded.
+func reminderDay(shot models.ScheduledVaccination, today time.Time) (int, bool) {
+	if shot.CatalogueID == 0 || (shot.Status != models.VaccinationDue && shot.Status != models.VaccinationOverdue) {
+		return 0, false
+	}
+	days := int(calendarDay(shot.Date).Sub(today).Hours() / 24)
+	if days < 0 {
+		return days, -days%7 == 0
+	}
+	for _, before := range reminderDaysBefore {
+		if days == before {
+			return days, true
+		}
+	}
+	return 0, false
+}
+
+/*
+calendarDay returns the midnight in UTC of the day of the time in its location. The vaccination
+dates are stored without the time zone, so today is taken in the server time zone and compared
+with them as the calendar day.
+*/
+func calendarDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
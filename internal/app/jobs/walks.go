@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"context"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"time"
+)
+
+const KindSegmentWalks = "walks.segment"
+
+/*
+SegmentWalks splits the GPS fixes of the pets into walks.
+
+Only closed segments are stored, the walk going on is segmented by one of the next runs.
+Segments which are too short to be walks are marked as segmented and are not drawn.
+*/
+func SegmentWalks(database store.DatabaseStore) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		petIDs, err := database.Walks().SelectPetsWithPendingPoints()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, petID := range petIDs {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			trackPoints, err := database.Walks().SelectPendingPoints(petID)
+			if err != nil {
+				return err
+			}
+			for _, segment := range tracks.Split(tracks.FromModels(trackPoints), now) {
+				if !segment.Closed {
+					continue
+				}
+				pointIDs := make([]int64, 0, len(segment.Points))
+				for _, idx := range segment.Points {
+					pointIDs = append(pointIDs, trackPoints[idx].PointID)
+				}
+				segmentedIDs := append([]int64{}, pointIDs...)
+				for _, idx := range segment.Dropped {
+					segmentedIDs = append(segmentedIDs, trackPoints[idx].PointID)
+				}
+
+				if !segment.IsWalk() {
+					if err := database.Walks().MarkSegmented(segmentedIDs); err != nil {
+						return err
+					}
+					continue
+				}
+				walk := &models.Walk{
+					PetID:      petID,
+					StartedAt:  segment.StartedAt,
+					EndedAt:    segment.EndedAt,
+					Distance:   segment.Distance,
+					Duration:   int(segment.Duration().Seconds()),
+					MeanSpeed:  segment.MeanSpeed(),
+					PointCount: len(segment.Points),
+				}
+				if _, err := database.Walks().CreateWalk(walk, pointIDs, segmentedIDs); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Package medication schedules the doses of the medications and matches the given doses against them.
+
+A scheduled dose is taken when the dose is given within the dose window of the medication around its time,
+the closest dose is preferred and every given dose counts for a single scheduled one. Doses not given are
+missed once their window has passed and pending until then.
+*/
+package medication
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
+)
+
+// Statuses of the scheduled doses
+const (
+	StatusTaken   = "taken"
+	StatusMissed  = "missed"
+	StatusPending = "pending"
+)
+
+// Outcome is the scheduled dose and the dose given for it
+type Outcome struct {
+	ScheduledAt    time.Time  `json:"scheduled_at"`
+	Status         string     `json:"status"`
+	DoseID         int        `json:"dose_id,omitempty"`
+	AdministeredAt *time.Time `json:"administered_at,omitempty"`
+}
+
+// Adherence to the medication schedule in [From, To), Rate is the share of the due doses that were taken
+type Adherence struct {
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Expected    int       `json:"expected"`
+	Taken       int       `json:"taken"`
+	Missed      int       `json:"missed"`
+	Pending     int       `json:"pending"`
+	Rate        *float64  `json:"rate"`
+	Doses       []Outcome `json:"doses"`
+	Unscheduled []int     `json:"unscheduled"`
+}
+
+// Schedule returns the times of the doses of the medication in [from, to)
+func Schedule(medication *models.Medication, from time.Time, to time.Time) []time.Time {
+	times := make([]time.Time, 0)
+	if !medication.IsScheduled() {
+		return times
+	}
+	if medication.EndsAt != nil && medication.EndsAt.Valid && medication.EndsAt.Time.Before(to) {
+		to = medication.EndsAt.Time
+	}
+	interval := medication.Interval()
+	next := medication.StartsAt
+	if from.After(next) {
+		// The first dose at or after from
+		skipped := (from.Sub(next) + interval - 1) / interval
+		next = next.Add(skipped * interval)
+	}
+	for ; next.Before(to); next = next.Add(interval) {
+		times = append(times, next)
+	}
+	return times
+}
+
+// Match matches the given doses with the scheduled times, the times are expected in order
+func Match(medication *models.Medication, scheduled []time.Time, doses []models.MedicationDose, now time.Time) ([]Outcome, []int) {
+	window := medication.DoseWindow()
+	used := make([]bool, len(doses))
+	outcomes := make([]Outcome, len(scheduled))
+	for idx, scheduledAt := range scheduled {
+		outcomes[idx] = Outcome{ScheduledAt: scheduledAt, Status: StatusPending}
+		best := -1
+		for dIdx := range doses {
+			if used[dIdx] || distance(doses[dIdx].AdministeredAt, scheduledAt) > window {
+				continue
+			}
+			if best == -1 || distance(doses[dIdx].AdministeredAt, scheduledAt) < distance(doses[best].AdministeredAt, scheduledAt) {
+				best = dIdx
+			}
+		}
+		if best != -1 {
+			used[best] = true
+			administeredAt := doses[best].AdministeredAt
+			outcomes[idx].Status = StatusTaken
+			outcomes[idx].DoseID = doses[best].DoseID
+			outcomes[idx].AdministeredAt = &administeredAt
+			continue
+		}
+		if now.After(scheduledAt.Add(window)) {
+			outcomes[idx].Status = StatusMissed
+		}
+	}
+
+	unscheduled := make([]int, 0)
+	for dIdx := range doses {
+		if !used[dIdx] {
+			unscheduled = append(unscheduled, doses[dIdx].DoseID)
+		}
+	}
+	return outcomes, unscheduled
+}
+
+// Assess is the adherence to the medication schedule in [from, to) as known at now,
+// doses are the ones given in the period widened by the dose window
+func Assess(medication *models.Medication, doses []models.MedicationDose, from time.Time, to time.Time, now time.Time) *Adherence {
+	outcomes, unscheduled := Match(medication, Schedule(medication, from, to), doses, now)
+	adherence := &Adherence{
+		From:        from,
+		To:          to,
+		Expected:    len(outcomes),
+		Doses:       outcomes,
+		Unscheduled: unscheduled,
+	}
+	for _, outcome := range outcomes {
+		switch outcome.Status {
+		case StatusTaken:
+			adherence.Taken++
+		case StatusMissed:
+			adherence.Missed++
+		case StatusPending:
+			adherence.Pending++
+		}
+	}
+	if due := adherence.Taken + adherence.Missed; due > 0 {
+		rate := float64(adherence.Taken) / float64(due)
+		adherence.Rate = &rate
+	}
+	return adherence
+}
+
+// Missed returns the times of the doses whose window passes in [from, to) without the dose given,
+// doses are the ones given in the period widened by the dose window twice
+func Missed(medication *models.Medication, doses []models.MedicationDose, from time.Time, to time.Time) []time.Time {
+	window := medication.DoseWindow()
+	outcomes, _ := Match(medication, Schedule(medication, from.Add(-window), to.Add(-window)), doses, to)
+	missed := make([]time.Time, 0)
+	for _, outcome := range outcomes {
+		if outcome.Status == StatusMissed {
+			missed = append(missed, outcome.ScheduledAt)
+		}
+	}
+	return missed
+}
+
+func distance(a time.Time, b time.Time) time.Duration {
+	if a.Before(b) {
+		return b.Sub(a)
+	}
+	return a.Sub(b)
+}
@@ -0,0 +1,86 @@
+package medication
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 6, 15, 8, 0, 0, 0, time.UTC)
+
+func antibiotic() *models.Medication {
+	return &models.Medication{
+		MedicationID:  1,
+		IntervalHours: 12,
+		StartsAt:      start,
+		EndsAt:        &sql.NullTime{Time: start.AddDate(0, 0, 3), Valid: true},
+	}
+}
+
+func dose(doseID int, at time.Time) models.MedicationDose {
+	return models.MedicationDose{DoseID: doseID, MedicationID: 1, AdministeredAt: at, Amount: 1}
+}
+
+func TestSchedule(t *testing.T) {
+	medication := antibiotic()
+
+	times := Schedule(medication, start.AddDate(0, 0, -1), start.AddDate(0, 0, 10))
+	// the course lasts three days, the end is exclusive
+	require.Len(t, times, 6)
+	assert.Equal(t, start, times[0])
+	assert.Equal(t, start.Add(60*time.Hour), times[5])
+
+	times = Schedule(medication, start.Add(time.Hour), start.Add(25*time.Hour))
+	require.Len(t, times, 2)
+	assert.Equal(t, start.Add(12*time.Hour), times[0])
+	assert.Equal(t, start.Add(24*time.Hour), times[1])
+
+	medication.IntervalHours = 0
+	assert.Empty(t, Schedule(medication, start, start.AddDate(0, 0, 1)))
+}
+
+func TestAssess(t *testing.T) {
+	medication := antibiotic()
+	doses := []models.MedicationDose{
+		dose(1, start.Add(30*time.Minute)),
+		// the second dose is late beyond the window, it is unscheduled and the dose is missed
+		dose(2, start.Add(15*time.Hour)),
+		dose(3, start.Add(24*time.Hour-90*time.Minute)),
+	}
+	now := start.Add(36*time.Hour + time.Hour)
+
+	adherence := Assess(medication, doses, start, start.AddDate(0, 0, 3), now)
+	assert.Equal(t, 6, adherence.Expected)
+	assert.Equal(t, 2, adherence.Taken)
+	assert.Equal(t, 1, adherence.Missed)
+	assert.Equal(t, 3, adherence.Pending)
+	require.NotNil(t, adherence.Rate)
+	assert.InDelta(t, 2.0/3.0, *adherence.Rate, 1e-9)
+	assert.Equal(t, []int{2}, adherence.Unscheduled)
+
+	assert.Equal(t, StatusTaken, adherence.Doses[0].Status)
+	assert.Equal(t, 1, adherence.Doses[0].DoseID)
+	assert.Equal(t, StatusMissed, adherence.Doses[1].Status)
+	assert.Equal(t, StatusTaken, adherence.Doses[2].Status)
+	// the dose at 36 hours is still within its window
+	assert.Equal(t, StatusPending, adherence.Doses[3].Status)
+}
+
+func TestMissed(t *testing.T) {
+	medication := antibiotic()
+	doses := []models.MedicationDose{dose(1, start.Add(time.Hour))}
+
+	// the window of the first dose passes at 10:00, the one of the second dose at 22:00
+	assert.Empty(t, Missed(medication, doses, start, start.Add(14*time.Hour)))
+	missed := Missed(medication, doses, start, start.Add(14*time.Hour+time.Minute))
+	assert.Equal(t, []time.Time{start.Add(12 * time.Hour)}, missed)
+
+	// the checks following each other report every dose once
+	first := Missed(medication, nil, start, start.Add(2*time.Hour))
+	second := Missed(medication, nil, start.Add(2*time.Hour), start.Add(3*time.Hour))
+	assert.Empty(t, first)
+	assert.Equal(t, []time.Time{start}, second)
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/twinj/uuid"
 	"golang.org/x/net/context"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -21,6 +24,13 @@ func (r *responseWriter) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Flush lets streaming handlers flush the wrapped writer
+func (r *responseWriter) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func NewInfoMiddleware(server server) *InfoMiddleware {
 	return &InfoMiddleware{server: server}
 }
@@ -36,7 +46,8 @@ func (m *InfoMiddleware) MarkRequest(next http.Handler) http.Handler {
 func (m *InfoMiddleware) LogRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rw := &responseWriter{w, http.StatusOK}
-		m.server.Logger().Printf("Request %s %s : ID: %s", r.Method, r.RequestURI, r.Header.Get(hdrReqUUID))
+		// The query may carry the stream tickets and the signatures of the public URLs, it is not logged
+		m.server.Logger().Printf("Request %s %s : ID: %s", r.Method, redactedURI(r), r.Header.Get(hdrReqUUID))
 		start := time.Now()
 		next.ServeHTTP(rw, r)
 		m.server.Logger().Printf("Request %s status=%v %v completed in %v, headers: %v",
@@ -48,6 +59,24 @@ func (m *InfoMiddleware) LogRequest(next http.Handler) http.Handler {
 	})
 }
 
+// redactedURI is the request path with the values of the query parameters masked
+func redactedURI(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return r.URL.EscapedPath()
+	}
+	query := r.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	masked := make([]string, len(keys))
+	for idx, key := range keys {
+		masked[idx] = url.QueryEscape(key) + "=REDACTED"
+	}
+	return r.URL.EscapedPath() + "?" + strings.Join(masked, "&")
+}
+
 func (m *InfoMiddleware) ProvideOptionsRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodOptions {
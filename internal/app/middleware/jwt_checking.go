@@ -18,8 +18,18 @@ func newAuthentication(server server) *AuthenticationMiddleware {
 var errUnauthorized = errors.New("unauthorized")
 
 func (m *AuthenticationMiddleware) IsAuthorised(next http.Handler) http.Handler {
+	return m.authorise(next, auth.ExtractAccessMeta)
+}
+
+// IsStreamAuthorised is like IsAuthorised but also accepts the stream ticket in the URL query,
+// it is used only by event streams, which browsers open without custom headers
+func (m *AuthenticationMiddleware) IsStreamAuthorised(next http.Handler) http.Handler {
+	return m.authorise(next, auth.ExtractStreamAccessMeta)
+}
+
+func (m *AuthenticationMiddleware) authorise(next http.Handler, extract func(r *http.Request) (*auth.AccessTokenMeta, error)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		accessInfo, err := auth.ExtractAccessMeta(r)
+		accessInfo, err := extract(r)
 		if err != nil {
 			m.server.RespondError(w, r, http.StatusUnauthorized, errUnauthorized)
 			return
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"time"
+)
+
+const (
+	AppointmentRequested = "requested"
+	AppointmentConfirmed = "confirmed"
+	AppointmentCancelled = "cancelled"
+	AppointmentCompleted = "completed"
+	AppointmentNoShow    = "no_show"
+)
+
+// appointmentTransitions lists statuses reachable from the given one.
+// Cancelled, completed and no-show appointments are final.
+var appointmentTransitions = map[string][]string{
+	AppointmentRequested: {AppointmentConfirmed, AppointmentCancelled},
+	AppointmentConfirmed: {AppointmentCancelled, AppointmentCompleted, AppointmentNoShow},
+}
+
+type Appointment struct {
+	AppointmentID     int            `json:"appointment_id" db:"appointment_id"`
+	PetID             int            `json:"pet_id" db:"pet_id"`
+	OwnerID           int            `json:"owner_id" db:"owner_id"`
+	VeterinarianID    int            `json:"veterinarian_id" db:"veterinarian_id"`
+	ClinicID          *sql.NullInt64 `json:"-" db:"clinic_id"`
+	StartsAt          time.Time      `json:"starts_at" db:"starts_at"`
+	EndsAt            time.Time      `json:"ends_at" db:"ends_at"`
+	Reason            string         `json:"reason" db:"reason"`
+	Status            string         `json:"status" db:"status"`
+	CreatedAt         time.Time      `json:"created_at" db:"created_at"`
+	SpecifiedClinicID int            `json:"clinic_id,omitempty"`
+}
+
+func (a *Appointment) Validate() error {
+	return validation.ValidateStruct(
+		a,
+		validation.Field(&a.PetID, validation.Required),
+		validation.Field(&a.OwnerID, validation.Required),
+		validation.Field(&a.VeterinarianID, validation.Required),
+		validation.Field(&a.StartsAt, validation.Required),
+		validation.Field(&a.EndsAt, validation.Required, validation.By(a.isAfterStart)),
+		validation.Field(&a.Reason, validation.Required, validation.Length(3, 500)),
+		validation.Field(
+			&a.Status,
+			validation.Required,
+			validation.In(AppointmentRequested, AppointmentConfirmed, AppointmentCancelled, AppointmentCompleted, AppointmentNoShow),
+		),
+	)
+}
+
+func (a *Appointment) BeforeCreate() {
+	if a.SpecifiedClinicID != 0 {
+		a.ClinicID = &sql.NullInt64{
+			Int64: int64(a.SpecifiedClinicID),
+			Valid: true,
+		}
+	}
+}
+
+func (a *Appointment) AfterCreate() {
+	if a.ClinicID != nil && a.ClinicID.Valid {
+		a.SpecifiedClinicID = int(a.ClinicID.Int64)
+	}
+}
+
+func (a *Appointment) SetSpecifiedClinicID(clinicID *int) {
+	if clinicID != nil {
+		a.SpecifiedClinicID = *clinicID
+	}
+}
+
+// CanTransitionTo reports whether the appointment status may be changed to the specified one
+func (a *Appointment) CanTransitionTo(status string) bool {
+	for _, allowed := range appointmentTransitions[a.Status] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the appointment still occupies the veterinarian time slot
+func (a *Appointment) IsActive() bool {
+	return a.Status == AppointmentRequested || a.Status == AppointmentConfirmed
+}
+
+func (a *Appointment) isAfterStart(value interface{}) error {
+	endsAt, _ := value.(time.Time)
+	if !endsAt.After(a.StartsAt) {
+		return errors.New("must be after appointment start")
+	}
+	return nil
+}
+
+// VeterinarianAvailability is the interval of the week day when
+// veterinarian accepts appointments. Weekday follows time.Weekday numbering.
+type VeterinarianAvailability struct {
+	VeterinarianID int    `json:"-" db:"veterinarian_id"`
+	Weekday        int    `json:"weekday" db:"weekday"`
+	StartsAt       string `json:"starts_at" db:"starts_at"`
+	EndsAt         string `json:"ends_at" db:"ends_at"`
+}
+
+func (v *VeterinarianAvailability) Validate() error {
+	return validation.ValidateStruct(
+		v,
+		validation.Field(&v.Weekday, validation.Min(0), validation.Max(6)),
+		validation.Field(&v.StartsAt, validation.Required, validation.By(isClockTime)),
+		validation.Field(&v.EndsAt, validation.Required, validation.By(isClockTime), validation.By(v.isAfterStart)),
+	)
+}
+
+func (v *VeterinarianAvailability) AfterCreate() {
+	v.StartsAt = fromClockTime(v.StartsAt)
+	v.EndsAt = fromClockTime(v.EndsAt)
+}
+
+// Covers reports whether the whole [start, end) interval lies inside the availability interval.
+// The bounds are converted to the location the availability hours are kept in, so the offset
+// the client has sent them with does not matter.
+func (v *VeterinarianAvailability) Covers(start time.Time, end time.Time, location *time.Location) bool {
+	start, end = start.In(location), end.In(location)
+	if int(start.Weekday()) != v.Weekday || !sameDay(start, end) {
+		return false
+	}
+	startsAt, err := time.Parse(ClockTimeLayout, v.StartsAt)
+	if err != nil {
+		return false
+	}
+	endsAt, err := time.Parse(ClockTimeLayout, v.EndsAt)
+	if err != nil {
+		return false
+	}
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+	return startMinutes >= startsAt.Hour()*60+startsAt.Minute() &&
+		endMinutes <= endsAt.Hour()*60+endsAt.Minute()
+}
+
+func (v *VeterinarianAvailability) isAfterStart(value interface{}) error {
+	startsAt, err := time.Parse(ClockTimeLayout, v.StartsAt)
+	if err != nil {
+		return nil
+	}
+	endsAt, err := time.Parse(ClockTimeLayout, value.(string))
+	if err != nil {
+		return nil
+	}
+	if !endsAt.After(startsAt) {
+		return errors.New("must be after availability start")
+	}
+	return nil
+}
+
+// AnyAvailabilityCovers reports whether the slot fits into one of availability intervals
+func AnyAvailabilityCovers(availability []VeterinarianAvailability, start time.Time, end time.Time, location *time.Location) bool {
+	for idx := range availability {
+		if availability[idx].Covers(start, end, location) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,73 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAppointment_CanTransitionTo(t *testing.T) {
+	testCases := []struct {
+		name    string
+		from    string
+		to      string
+		allowed bool
+	}{
+		{name: "Confirm requested", from: models.AppointmentRequested, to: models.AppointmentConfirmed, allowed: true},
+		{name: "Cancel requested", from: models.AppointmentRequested, to: models.AppointmentCancelled, allowed: true},
+		{name: "Complete requested", from: models.AppointmentRequested, to: models.AppointmentCompleted, allowed: false},
+		{name: "Complete confirmed", from: models.AppointmentConfirmed, to: models.AppointmentCompleted, allowed: true},
+		{name: "No-show confirmed", from: models.AppointmentConfirmed, to: models.AppointmentNoShow, allowed: true},
+		{name: "Reopen cancelled", from: models.AppointmentCancelled, to: models.AppointmentRequested, allowed: false},
+		{name: "Cancel completed", from: models.AppointmentCompleted, to: models.AppointmentCancelled, allowed: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &models.Appointment{Status: tc.from}
+			assert.Equal(t, tc.allowed, a.CanTransitionTo(tc.to))
+		})
+	}
+}
+
+func TestVeterinarianAvailability_Covers(t *testing.T) {
+	// 2021-06-07 is Monday
+	slot := func(start string, end string) (time.Time, time.Time) {
+		s, _ := time.Parse(time.RFC3339, "2021-06-07T"+start+":00Z")
+		e, _ := time.Parse(time.RFC3339, "2021-06-07T"+end+":00Z")
+		return s, e
+	}
+	availability := &models.VeterinarianAvailability{
+		Weekday:  int(time.Monday),
+		StartsAt: "09:00",
+		EndsAt:   "13:00",
+	}
+
+	testCases := []struct {
+		name   string
+		start  string
+		end    string
+		covers bool
+	}{
+		{name: "Inside", start: "10:00", end: "10:30", covers: true},
+		{name: "Whole interval", start: "09:00", end: "13:00", covers: true},
+		{name: "Starts before", start: "08:30", end: "09:30", covers: false},
+		{name: "Ends after", start: "12:30", end: "13:30", covers: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end := slot(tc.start, tc.end)
+			assert.Equal(t, tc.covers, availability.Covers(start, end, time.UTC))
+		})
+	}
+
+	// The slot at 07:00 UTC is sent with the offset making it look like 10:00
+	kyiv := time.FixedZone("UTC+3", 3*60*60)
+	start, end := slot("07:00", "07:30")
+	assert.False(t, availability.Covers(start.In(kyiv), end.In(kyiv), time.UTC))
+	assert.True(t, availability.Covers(start, end, kyiv))
+
+	start, end = slot("10:00", "10:30")
+	availability.Weekday = int(time.Tuesday)
+	assert.False(t, availability.Covers(start, end, time.UTC))
+}
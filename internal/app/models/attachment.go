@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Attachment variants which can be downloaded
+const (
+	AttachmentOriginal  = "original"
+	AttachmentThumbnail = "thumbnail"
+)
+
+/*
+Attachment is the file uploaded to the pet, e.g. its photo, or to its health report, e.g. the X-ray.
+
+The content is kept in the blob store by the StorageKey, images have the thumbnail by the ThumbnailKey.
+The URLs are signed for the response and expire, they are not stored.
+*/
+type Attachment struct {
+	AttachmentID      int             `json:"attachment_id" db:"attachment_id"`
+	PetID             int             `json:"pet_id" db:"pet_id"`
+	ReportID          *sql.NullInt64  `json:"-" db:"report_id"`
+	UploadedBy        int             `json:"uploaded_by" db:"uploaded_by"`
+	FileName          string          `json:"file_name" db:"file_name"`
+	ContentType       string          `json:"content_type" db:"content_type"`
+	Size              int64           `json:"size" db:"size"`
+	StorageKey        string          `json:"-" db:"storage_key"`
+	ThumbnailKey      *sql.NullString `json:"-" db:"thumbnail_key"`
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+	SpecifiedReportID int             `json:"report_id,omitempty"`
+
+	URL          string `json:"url,omitempty" db:"-"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty" db:"-"`
+}
+
+func (a *Attachment) BeforeCreate() {
+	a.ReportID = toNullInt64(a.SpecifiedReportID)
+}
+
+func (a *Attachment) AfterCreate() {
+	a.SpecifiedReportID = fromNullInt64(a.ReportID)
+}
+
+// HasThumbnail reports whether the thumbnail of the attachment is stored
+func (a *Attachment) HasThumbnail() bool {
+	return a.ThumbnailKey != nil && a.ThumbnailKey.Valid
+}
+
+// Key returns the blob store key of the variant, empty for the missing one
+func (a *Attachment) Key(variant string) string {
+	switch variant {
+	case AttachmentOriginal:
+		return a.StorageKey
+	case AttachmentThumbnail:
+		return fromNullString(a.ThumbnailKey)
+	}
+	return ""
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// CalendarFeed holds the key the user's iCalendar feed URL is signed with.
+// The key never leaves the server, only the signature is exposed in the URL.
+type CalendarFeed struct {
+	UserID    int       `json:"user_id" db:"user_id"`
+	FeedKey   string    `json:"-" db:"feed_key"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"time"
+)
+
+// Clinic-scoped roles of the clinic members. They are independent of the
+// system roles: a user with the veterinarian system role may be a simple
+// veterinarian in one clinic and an administrator of another one.
+const (
+	ClinicRoleAdministrator = "administrator"
+	ClinicRoleVeterinarian  = "veterinarian"
+	ClinicRoleReceptionist  = "receptionist"
+)
+
+type Clinic struct {
+	ClinicID                    int             `json:"clinic_id" db:"clinic_id"`
+	Name                        string          `json:"name" db:"name"`
+	RegistrationNumber          *sql.NullString `json:"-" db:"registration_number"`
+	Address                     string          `json:"address" db:"address"`
+	City                        string          `json:"city" db:"city"`
+	Phone                       *sql.NullString `json:"-" db:"phone"`
+	Email                       *sql.NullString `json:"-" db:"email"`
+	Website                     *sql.NullString `json:"-" db:"website"`
+	CreatedAt                   time.Time       `json:"created_at" db:"created_at"`
+	SpecifiedRegistrationNumber string          `json:"registration_number,omitempty"`
+	SpecifiedPhone              string          `json:"phone,omitempty"`
+	SpecifiedEmail              string          `json:"email,omitempty"`
+	SpecifiedWebsite            string          `json:"website,omitempty"`
+}
+
+func (c *Clinic) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.Name, validation.Required, validation.Length(2, 255)),
+		validation.Field(&c.Address, validation.Required, validation.Length(5, 255)),
+		validation.Field(&c.City, validation.Required, validation.Length(2, 100)),
+		validation.Field(&c.SpecifiedPhone, validation.Length(5, 30)),
+		validation.Field(&c.SpecifiedEmail, is.Email),
+		validation.Field(&c.SpecifiedWebsite, is.URL),
+	)
+}
+
+func (c *Clinic) BeforeCreate() {
+	c.RegistrationNumber = toNullString(c.SpecifiedRegistrationNumber)
+	c.Phone = toNullString(c.SpecifiedPhone)
+	c.Email = toNullString(c.SpecifiedEmail)
+	c.Website = toNullString(c.SpecifiedWebsite)
+}
+
+func (c *Clinic) AfterCreate() {
+	c.SpecifiedRegistrationNumber = fromNullString(c.RegistrationNumber)
+	c.SpecifiedPhone = fromNullString(c.Phone)
+	c.SpecifiedEmail = fromNullString(c.Email)
+	c.SpecifiedWebsite = fromNullString(c.Website)
+}
+
+func (c *Clinic) Update(other *Clinic) {
+	if other.Name != "" && other.Name != c.Name {
+		c.Name = other.Name
+	}
+	if other.Address != "" && other.Address != c.Address {
+		c.Address = other.Address
+	}
+	if other.City != "" && other.City != c.City {
+		c.City = other.City
+	}
+	if other.SpecifiedRegistrationNumber != c.SpecifiedRegistrationNumber {
+		c.SpecifiedRegistrationNumber = other.SpecifiedRegistrationNumber
+	}
+	if other.SpecifiedPhone != c.SpecifiedPhone {
+		c.SpecifiedPhone = other.SpecifiedPhone
+	}
+	if other.SpecifiedEmail != c.SpecifiedEmail {
+		c.SpecifiedEmail = other.SpecifiedEmail
+	}
+	if other.SpecifiedWebsite != c.SpecifiedWebsite {
+		c.SpecifiedWebsite = other.SpecifiedWebsite
+	}
+}
+
+func (c *Clinic) SetSpecifiedRegistrationNumber(number *string) {
+	if number != nil {
+		c.SpecifiedRegistrationNumber = *number
+	}
+}
+
+func (c *Clinic) SetSpecifiedPhone(phone *string) {
+	if phone != nil {
+		c.SpecifiedPhone = *phone
+	}
+}
+
+func (c *Clinic) SetSpecifiedEmail(email *string) {
+	if email != nil {
+		c.SpecifiedEmail = *email
+	}
+}
+
+func (c *Clinic) SetSpecifiedWebsite(website *string) {
+	if website != nil {
+		c.SpecifiedWebsite = *website
+	}
+}
+
+// ClinicOpeningHours is the working interval of the clinic on the specified
+// day of week. Weekday follows time.Weekday numbering, so Sunday is 0.
+type ClinicOpeningHours struct {
+	ClinicID int    `json:"-" db:"clinic_id"`
+	Weekday  int    `json:"weekday" db:"weekday"`
+	OpensAt  string `json:"opens_at" db:"opens_at"`
+	ClosesAt string `json:"closes_at" db:"closes_at"`
+}
+
+func (h *ClinicOpeningHours) Validate() error {
+	return validation.ValidateStruct(
+		h,
+		validation.Field(&h.Weekday, validation.Min(0), validation.Max(6)),
+		validation.Field(&h.OpensAt, validation.Required, validation.By(isClockTime)),
+		validation.Field(&h.ClosesAt, validation.Required, validation.By(isClockTime), validation.By(h.isAfterOpening)),
+	)
+}
+
+func (h *ClinicOpeningHours) AfterCreate() {
+	h.OpensAt = fromClockTime(h.OpensAt)
+	h.ClosesAt = fromClockTime(h.ClosesAt)
+}
+
+func (h *ClinicOpeningHours) isAfterOpening(value interface{}) error {
+	opens, err := time.Parse(ClockTimeLayout, h.OpensAt)
+	if err != nil {
+		return nil
+	}
+	closes, err := time.Parse(ClockTimeLayout, value.(string))
+	if err != nil {
+		return nil
+	}
+	if !closes.After(opens) {
+		return errors.New("must be after opening time")
+	}
+	return nil
+}
+
+type ClinicMember struct {
+	ClinicID   int       `json:"clinic_id" db:"clinic_id"`
+	UserID     int       `json:"user_id" db:"user_id"`
+	MemberRole string    `json:"member_role" db:"member_role"`
+	JoinedAt   time.Time `json:"joined_at" db:"joined_at"`
+}
+
+func (m *ClinicMember) Validate() error {
+	return validation.ValidateStruct(
+		m,
+		validation.Field(&m.UserID, validation.Required),
+		validation.Field(
+			&m.MemberRole,
+			validation.Required,
+			validation.In(ClinicRoleAdministrator, ClinicRoleVeterinarian, ClinicRoleReceptionist),
+		),
+	)
+}
+
+// CanManageClinic reports whether member may change clinic details and its staff
+func (m *ClinicMember) CanManageClinic() bool {
+	return m.MemberRole == ClinicRoleAdministrator
+}
+
+// CanManagePatients reports whether member may register and unregister clinic patients
+func (m *ClinicMember) CanManagePatients() bool {
+	return m.MemberRole == ClinicRoleAdministrator ||
+		m.MemberRole == ClinicRoleVeterinarian ||
+		m.MemberRole == ClinicRoleReceptionist
+}
+
+// IsVeterinarian reports whether member provides medical care in the clinic
+func (m *ClinicMember) IsVeterinarian() bool {
+	return m.MemberRole == ClinicRoleVeterinarian || m.MemberRole == ClinicRoleAdministrator
+}
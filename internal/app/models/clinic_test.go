@@ -0,0 +1,115 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClinic_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		clinic  models.Clinic
+		isValid bool
+	}{
+		{
+			name:    "Valid",
+			clinic:  models.Clinic{Name: "Happy Paws", Address: "Khreshchatyk 1", City: "Kyiv"},
+			isValid: true,
+		},
+		{
+			name: "Valid with contacts",
+			clinic: models.Clinic{
+				Name:             "Happy Paws",
+				Address:          "Khreshchatyk 1",
+				City:             "Kyiv",
+				SpecifiedPhone:   "+380441234567",
+				SpecifiedEmail:   "care@happypaws.example",
+				SpecifiedWebsite: "https://happypaws.example",
+			},
+			isValid: true,
+		},
+		{name: "No name", clinic: models.Clinic{Address: "Khreshchatyk 1", City: "Kyiv"}},
+		{name: "Short address", clinic: models.Clinic{Name: "Happy Paws", Address: "K 1", City: "Kyiv"}},
+		{name: "No city", clinic: models.Clinic{Name: "Happy Paws", Address: "Khreshchatyk 1"}},
+		{
+			name:   "Short phone",
+			clinic: models.Clinic{Name: "Happy Paws", Address: "Khreshchatyk 1", City: "Kyiv", SpecifiedPhone: "123"},
+		},
+		{
+			name:   "Invalid email",
+			clinic: models.Clinic{Name: "Happy Paws", Address: "Khreshchatyk 1", City: "Kyiv", SpecifiedEmail: "care"},
+		},
+		{
+			name:   "Invalid website",
+			clinic: models.Clinic{Name: "Happy Paws", Address: "Khreshchatyk 1", City: "Kyiv", SpecifiedWebsite: "happy paws"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.clinic.Validate())
+			} else {
+				assert.Error(t, tc.clinic.Validate())
+			}
+		})
+	}
+}
+
+func TestClinicOpeningHours_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		hours   models.ClinicOpeningHours
+		isValid bool
+	}{
+		{name: "Valid", hours: models.ClinicOpeningHours{Weekday: 1, OpensAt: "09:00", ClosesAt: "18:00"}, isValid: true},
+		{name: "Sunday", hours: models.ClinicOpeningHours{Weekday: 0, OpensAt: "10:00", ClosesAt: "14:30"}, isValid: true},
+		{name: "Unknown weekday", hours: models.ClinicOpeningHours{Weekday: 7, OpensAt: "09:00", ClosesAt: "18:00"}},
+		{name: "Closes before opening", hours: models.ClinicOpeningHours{Weekday: 1, OpensAt: "18:00", ClosesAt: "09:00"}},
+		{name: "Closes on opening", hours: models.ClinicOpeningHours{Weekday: 1, OpensAt: "09:00", ClosesAt: "09:00"}},
+		{name: "Not a clock time", hours: models.ClinicOpeningHours{Weekday: 1, OpensAt: "9am", ClosesAt: "18:00"}},
+		{name: "No closing time", hours: models.ClinicOpeningHours{Weekday: 1, OpensAt: "09:00"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.hours.Validate())
+			} else {
+				assert.Error(t, tc.hours.Validate())
+			}
+		})
+	}
+}
+
+func TestClinicMember_Roles(t *testing.T) {
+	testCases := []struct {
+		role           string
+		manageClinic   bool
+		managePatients bool
+		veterinarian   bool
+	}{
+		{role: models.ClinicRoleAdministrator, manageClinic: true, managePatients: true, veterinarian: true},
+		{role: models.ClinicRoleVeterinarian, managePatients: true, veterinarian: true},
+		{role: models.ClinicRoleReceptionist, managePatients: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.role, func(t *testing.T) {
+			member := &models.ClinicMember{UserID: 7, MemberRole: tc.role}
+			assert.NoError(t, member.Validate())
+			assert.Equal(t, tc.manageClinic, member.CanManageClinic())
+			assert.Equal(t, tc.managePatients, member.CanManagePatients())
+			assert.Equal(t, tc.veterinarian, member.IsVeterinarian())
+		})
+	}
+
+	assert.Error(t, (&models.ClinicMember{UserID: 7, MemberRole: "owner"}).Validate())
+	assert.Error(t, (&models.ClinicMember{MemberRole: models.ClinicRoleVeterinarian}).Validate())
+}
+
+func TestClinicOpeningHours_AfterCreate(t *testing.T) {
+	hours := &models.ClinicOpeningHours{Weekday: 1, OpensAt: "09:00:00", ClosesAt: "18:30"}
+	hours.AfterCreate()
+	assert.Equal(t, "09:00", hours.OpensAt)
+	assert.Equal(t, "18:30", hours.ClosesAt)
+	assert.NoError(t, hours.Validate())
+}
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	DefaultFeedingTolerance = 60
+	MaxFeedingTolerance     = 360
+	maxFeedingPlanMeals     = 24
+)
+
+/*
+FeedingPlan is the recurring schedule of the meals of the pet, it is valid from StartsOn until EndsOn inclusive
+or indefinitely. The times of day of the meals are in the TimeZone of the plan, a meal is fed when the eating
+is logged within ToleranceMinutes of its time.
+*/
+type FeedingPlan struct {
+	PlanID           int           `json:"plan_id" db:"plan_id"`
+	PetID            int           `json:"pet_id" db:"pet_id"`
+	Name             string        `json:"name" db:"name"`
+	TimeZone         string        `json:"time_zone" db:"time_zone"`
+	StartsOn         time.Time     `json:"-" db:"starts_on"`
+	EndsOn           *sql.NullTime `json:"-" db:"ends_on"`
+	ToleranceMinutes int           `json:"tolerance_minutes" db:"tolerance_minutes"`
+	CreatorID        int           `json:"creator_id" db:"creator_id"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+
+	Meals []FeedingPlanMeal `json:"meals" db:"-"`
+
+	SpecifiedStartsOn string `json:"starts_on"`
+	SpecifiedEndsOn   string `json:"ends_on,omitempty"`
+}
+
+func (p *FeedingPlan) Validate() error {
+	return validation.ValidateStruct(
+		p,
+		validation.Field(&p.PetID, validation.Required),
+		validation.Field(&p.Name, validation.Required, validation.Length(1, 64)),
+		validation.Field(&p.TimeZone, validation.Required, validation.By(isTimeZone)),
+		validation.Field(&p.SpecifiedStartsOn, validation.Required, validation.Date("2006-01-02")),
+		validation.Field(&p.SpecifiedEndsOn, validation.Date("2006-01-02"), validation.By(p.isAfterStart)),
+		validation.Field(&p.ToleranceMinutes, validation.Required, validation.Min(5), validation.Max(MaxFeedingTolerance)),
+		validation.Field(&p.Meals, validation.Required, validation.Length(1, maxFeedingPlanMeals)),
+	)
+}
+
+func (p *FeedingPlan) BeforeCreate() {
+	if p.TimeZone == "" {
+		p.TimeZone = "UTC"
+	}
+	if p.ToleranceMinutes == 0 {
+		p.ToleranceMinutes = DefaultFeedingTolerance
+	}
+	if startsOn, err := time.Parse("2006-01-02", p.SpecifiedStartsOn); err == nil {
+		p.StartsOn = startsOn
+	}
+	p.EndsOn = nil
+	if endsOn, err := time.Parse("2006-01-02", p.SpecifiedEndsOn); err == nil {
+		p.EndsOn = &sql.NullTime{Time: endsOn, Valid: true}
+	}
+	for idx := range p.Meals {
+		p.Meals[idx].PlanID = p.PlanID
+	}
+}
+
+func (p *FeedingPlan) AfterCreate() {
+	p.SpecifiedStartsOn = p.StartsOn.Format("2006-01-02")
+	p.SpecifiedEndsOn = ""
+	if p.EndsOn != nil && p.EndsOn.Valid {
+		p.SpecifiedEndsOn = p.EndsOn.Time.Format("2006-01-02")
+	}
+	if p.Meals == nil {
+		p.Meals = make([]FeedingPlanMeal, 0)
+	}
+	for idx := range p.Meals {
+		p.Meals[idx].AfterCreate()
+	}
+}
+
+// Location is the time zone of the plan, UTC if it is unknown
+func (p *FeedingPlan) Location() *time.Location {
+	location, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// IsValidOn reports whether the plan is valid on the date, the date is compared by the calendar day only
+func (p *FeedingPlan) IsValidOn(year int, month time.Month, day int) bool {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	startsOn := time.Date(p.StartsOn.Year(), p.StartsOn.Month(), p.StartsOn.Day(), 0, 0, 0, 0, time.UTC)
+	if date.Before(startsOn) {
+		return false
+	}
+	if p.EndsOn != nil && p.EndsOn.Valid {
+		endsOn := time.Date(p.EndsOn.Time.Year(), p.EndsOn.Time.Month(), p.EndsOn.Time.Day(), 0, 0, 0, 0, time.UTC)
+		return !date.After(endsOn)
+	}
+	return true
+}
+
+func (p *FeedingPlan) isAfterStart(value interface{}) error {
+	endsOn, err := time.Parse("2006-01-02", value.(string))
+	if err != nil {
+		return nil
+	}
+	startsOn, err := time.Parse("2006-01-02", p.SpecifiedStartsOn)
+	if err != nil {
+		return nil
+	}
+	if endsOn.Before(startsOn) {
+		return errors.New("must not be before the start of the plan")
+	}
+	return nil
+}
+
+func isTimeZone(value interface{}) error {
+	name, _ := value.(string)
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return fmt.Errorf("unknown time zone %s", name)
+	}
+	return nil
+}
+
+/*
+FeedingPlanMeal is the meal of the plan at the time of day, on the weekdays or every day when none are given.
+Weekdays follow time.Weekday numbering. The portion is the amount of the unit, PortionWeight is it in grams.
+*/
+type FeedingPlanMeal struct {
+	MealID        int           `json:"meal_id" db:"meal_id"`
+	PlanID        int           `json:"-" db:"plan_id"`
+	TimeOfDay     string        `json:"time_of_day" db:"time_of_day"`
+	Weekdays      pq.Int64Array `json:"weekdays" db:"weekdays"`
+	FoodID        int           `json:"food_id" db:"food_id"`
+	PortionAmount float64       `json:"portion_amount" db:"portion_amount"`
+	PortionUnit   string        `json:"portion_unit" db:"portion_unit"`
+	PortionWeight float64       `json:"portion_weight" db:"portion_weight"`
+}
+
+func (m FeedingPlanMeal) Validate() error {
+	return validation.ValidateStruct(
+		&m,
+		validation.Field(&m.TimeOfDay, validation.Required, validation.By(isClockTime)),
+		validation.Field(&m.Weekdays, validation.Each(validation.Min(int64(0)), validation.Max(int64(6)))),
+		validation.Field(&m.FoodID, validation.Required),
+		validation.Field(&m.PortionAmount, validation.Required, validation.Min(0.0)),
+		validation.Field(&m.PortionUnit, validation.Required),
+		validation.Field(&m.PortionWeight, validation.Required),
+	)
+}
+
+func (m *FeedingPlanMeal) AfterCreate() {
+	m.TimeOfDay = fromClockTime(m.TimeOfDay)
+	if m.Weekdays == nil {
+		m.Weekdays = make(pq.Int64Array, 0)
+	}
+}
+
+// IsServedOn reports whether the meal is served on the weekday
+func (m *FeedingPlanMeal) IsServedOn(weekday time.Weekday) bool {
+	if len(m.Weekdays) == 0 {
+		return true
+	}
+	for _, day := range m.Weekdays {
+		if day == int64(weekday) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPortion converts the amount of the unit to the portion weight of the food
+func (m *FeedingPlanMeal) SetPortion(food *Food, amount float64, unit string) error {
+	grams, err := food.PortionGrams(amount, unit)
+	if err != nil {
+		return err
+	}
+	m.FoodID = food.FoodID
+	m.PortionAmount = amount
+	m.PortionUnit = unit
+	m.PortionWeight = grams
+	return nil
+}
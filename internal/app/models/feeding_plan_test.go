@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFeedingPlan_Validate(t *testing.T) {
+	plan := &models.FeedingPlan{
+		PetID:             1,
+		Name:              "Weekdays",
+		TimeZone:          "Europe/Kiev",
+		SpecifiedStartsOn: "2021-03-01",
+		Meals: []models.FeedingPlanMeal{
+			{TimeOfDay: "08:00", Weekdays: pq.Int64Array{1, 2, 3, 4, 5}, FoodID: 1, PortionAmount: 1, PortionUnit: models.UnitCup, PortionWeight: 95},
+		},
+	}
+	plan.BeforeCreate()
+	assert.NoError(t, plan.Validate())
+	assert.Equal(t, models.DefaultFeedingTolerance, plan.ToleranceMinutes)
+
+	plan.SpecifiedEndsOn = "2021-02-28"
+	assert.Error(t, plan.Validate())
+	plan.SpecifiedEndsOn = "2021-03-01"
+	assert.NoError(t, plan.Validate())
+
+	plan.TimeZone = "Mars/Olympus"
+	assert.Error(t, plan.Validate())
+	plan.TimeZone = "UTC"
+
+	plan.Meals[0].TimeOfDay = "25:00"
+	assert.Error(t, plan.Validate())
+	plan.Meals[0].TimeOfDay = "08:00"
+	plan.Meals[0].Weekdays = pq.Int64Array{7}
+	assert.Error(t, plan.Validate())
+	plan.Meals = nil
+	assert.Error(t, plan.Validate())
+}
+
+func TestFeedingPlan_IsValidOn(t *testing.T) {
+	plan := &models.FeedingPlan{SpecifiedStartsOn: "2021-03-01", SpecifiedEndsOn: "2021-03-07"}
+	plan.BeforeCreate()
+
+	assert.False(t, plan.IsValidOn(2021, time.February, 28))
+	assert.True(t, plan.IsValidOn(2021, time.March, 1))
+	assert.True(t, plan.IsValidOn(2021, time.March, 7))
+	assert.False(t, plan.IsValidOn(2021, time.March, 8))
+
+	plan.SpecifiedEndsOn = ""
+	plan.BeforeCreate()
+	assert.True(t, plan.IsValidOn(2030, time.January, 1))
+}
+
+func TestFeedingPlanMeal_IsServedOn(t *testing.T) {
+	meal := &models.FeedingPlanMeal{}
+	assert.True(t, meal.IsServedOn(time.Sunday))
+
+	meal.Weekdays = pq.Int64Array{int64(time.Saturday), int64(time.Sunday)}
+	assert.True(t, meal.IsServedOn(time.Sunday))
+	assert.False(t, meal.IsServedOn(time.Monday))
+}
@@ -2,32 +2,115 @@ package models
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/gtin"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"time"
 )
 
+// Mass units, the portions measured in them need no conversion of the food
+const (
+	UnitGram     = "g"
+	UnitKilogram = "kg"
+	UnitOunce    = "oz"
+)
+
+// Household units, the food defines how many grams they hold
+const (
+	UnitCup   = "cup"
+	UnitCan   = "can"
+	UnitPouch = "pouch"
+	UnitPiece = "piece"
+)
+
+// Nutrients, the composition of the food is in grams per 100 g as fed
+const (
+	NutrientProtein      = "protein"
+	NutrientFat          = "fat"
+	NutrientCarbohydrate = "carbohydrate"
+	NutrientFibre        = "fibre"
+	NutrientMoisture     = "moisture"
+	NutrientCalcium      = "calcium"
+	NutrientPhosphorus   = "phosphorus"
+)
+
+// Modified Atwater factors of pet food, kcal per gram
+const (
+	atwaterProtein      = 3.5
+	atwaterFat          = 8.5
+	atwaterCarbohydrate = 3.5
+)
+
+const (
+	maxEnergyPer100g   = 900
+	maxPortionUnitMass = 5000
+)
+
+var ErrUnknownPortionUnit = errors.New("unit is neither a mass unit nor a portion unit of the food")
+
+var massUnits = map[string]float64{
+	UnitGram:     1,
+	UnitKilogram: 1000,
+	UnitOunce:    28.349523125,
+}
+
+var FoodPortionUnits = []interface{}{UnitCup, UnitCan, UnitPouch, UnitPiece}
+
 type Food struct {
-	FoodID                int             `json:"food_id" db:"food_id"`
-	FoodName              string          `json:"food_name" db:"food_name"`
-	Calories              float64         `json:"food_calories" db:"calories"`
-	Description           *sql.NullString `json:"-" db:"description"`
-	Manufacturer          *sql.NullString `json:"-" db:"manufacturer"`
-	CreatorID             *sql.NullInt64  `json:"-" db:"creator_id"`
-	SpecifiedDescription  string          `json:"description,omitempty"`
-	SpecifiedManufacturer string          `json:"manufacturer,omitempty"`
-	SpecifiedCreatorID    int             `json:"creator_id"`
+	FoodID        int     `json:"food_id" db:"food_id"`
+	FoodName      string  `json:"food_name" db:"food_name"`
+	EnergyPer100g float64 `json:"energy_per_100g" db:"energy_per_100g"`
+	// Calories are kcal per gram, the deprecated alias of EnergyPer100g kept for the old clients
+	Calories     float64          `json:"food_calories" db:"calories"`
+	Protein      *sql.NullFloat64 `json:"-" db:"protein"`
+	Fat          *sql.NullFloat64 `json:"-" db:"fat"`
+	Carbohydrate *sql.NullFloat64 `json:"-" db:"carbohydrate"`
+	Fibre        *sql.NullFloat64 `json:"-" db:"fibre"`
+	Moisture     *sql.NullFloat64 `json:"-" db:"moisture"`
+	Calcium      *sql.NullFloat64 `json:"-" db:"calcium"`
+	Phosphorus   *sql.NullFloat64 `json:"-" db:"phosphorus"`
+	Description  *sql.NullString  `json:"-" db:"description"`
+	Manufacturer *sql.NullString  `json:"-" db:"manufacturer"`
+	CreatorID    *sql.NullInt64   `json:"-" db:"creator_id"`
+	// PortionUnits are the household units of the food, the mass units are always known
+	PortionUnits []FoodPortionUnit `json:"portion_units" db:"-"`
+	// Barcodes are GTIN codes of the packages, normalized to 14 digits
+	Barcodes []string `json:"barcodes" db:"-"`
+
+	SpecifiedProtein      *float64 `json:"protein,omitempty"`
+	SpecifiedFat          *float64 `json:"fat,omitempty"`
+	SpecifiedCarbohydrate *float64 `json:"carbohydrate,omitempty"`
+	SpecifiedFibre        *float64 `json:"fibre,omitempty"`
+	SpecifiedMoisture     *float64 `json:"moisture,omitempty"`
+	SpecifiedCalcium      *float64 `json:"calcium,omitempty"`
+	SpecifiedPhosphorus   *float64 `json:"phosphorus,omitempty"`
+	SpecifiedDescription  string   `json:"description,omitempty"`
+	SpecifiedManufacturer string   `json:"manufacturer,omitempty"`
+	SpecifiedCreatorID    int      `json:"creator_id"`
 }
 
 func (f *Food) Validate() error {
 	return validation.ValidateStruct(
 		f,
 		validation.Field(&f.FoodName, validation.Required, validation.Length(3, 255)),
-		validation.Field(&f.Calories, validation.Required, validation.Min(0.0)),
+		validation.Field(&f.EnergyPer100g, validation.Required, validation.Min(0.0), validation.Max(float64(maxEnergyPer100g))),
+		validation.Field(&f.SpecifiedProtein, validation.By(isNutrientContent)),
+		validation.Field(&f.SpecifiedFat, validation.By(isNutrientContent)),
+		validation.Field(&f.SpecifiedCarbohydrate, validation.By(isNutrientContent)),
+		validation.Field(&f.SpecifiedFibre, validation.By(isNutrientContent)),
+		validation.Field(&f.SpecifiedMoisture, validation.By(isNutrientContent), validation.By(f.isComposition)),
+		validation.Field(&f.SpecifiedCalcium, validation.By(isNutrientContent)),
+		validation.Field(&f.SpecifiedPhosphorus, validation.By(isNutrientContent)),
 		validation.Field(&f.SpecifiedDescription, validation.Length(5, 255)),
 		validation.Field(&f.SpecifiedManufacturer, validation.Length(2, 255)),
+		validation.Field(&f.PortionUnits, validation.By(areUniquePortionUnits)),
+		validation.Field(&f.Barcodes, validation.By(areUniqueBarcodes)),
 	)
 }
 
+// BeforeCreate also derives the energy from the deprecated calories or from protein, fat and carbohydrate
+// when it is not specified
 func (f *Food) BeforeCreate() {
 	if f.SpecifiedDescription != "" {
 		f.Description = &sql.NullString{
@@ -47,6 +130,28 @@ func (f *Food) BeforeCreate() {
 			Valid: true,
 		}
 	}
+	f.Protein = toNullFloat64(f.SpecifiedProtein)
+	f.Fat = toNullFloat64(f.SpecifiedFat)
+	f.Carbohydrate = toNullFloat64(f.SpecifiedCarbohydrate)
+	f.Fibre = toNullFloat64(f.SpecifiedFibre)
+	f.Moisture = toNullFloat64(f.SpecifiedMoisture)
+	f.Calcium = toNullFloat64(f.SpecifiedCalcium)
+	f.Phosphorus = toNullFloat64(f.SpecifiedPhosphorus)
+	if f.EnergyPer100g == 0 && f.Calories > 0 {
+		f.EnergyPer100g = f.Calories * 100
+	}
+	if f.EnergyPer100g == 0 && f.SpecifiedProtein != nil && f.SpecifiedFat != nil && f.SpecifiedCarbohydrate != nil {
+		f.EnergyPer100g = atwaterProtein**f.SpecifiedProtein + atwaterFat**f.SpecifiedFat + atwaterCarbohydrate**f.SpecifiedCarbohydrate
+	}
+	f.Calories = f.EnergyPer100g / 100
+	for idx := range f.PortionUnits {
+		f.PortionUnits[idx].FoodID = f.FoodID
+	}
+	for idx := range f.Barcodes {
+		if code, err := gtin.Normalize(f.Barcodes[idx]); err == nil {
+			f.Barcodes[idx] = code
+		}
+	}
 }
 
 func (f *Food) AfterCreate() {
@@ -59,6 +164,19 @@ func (f *Food) AfterCreate() {
 	if f.CreatorID != nil && f.CreatorID.Valid {
 		f.SpecifiedCreatorID = int(f.CreatorID.Int64)
 	}
+	f.SpecifiedProtein = fromNullFloat64(f.Protein)
+	f.SpecifiedFat = fromNullFloat64(f.Fat)
+	f.SpecifiedCarbohydrate = fromNullFloat64(f.Carbohydrate)
+	f.SpecifiedFibre = fromNullFloat64(f.Fibre)
+	f.SpecifiedMoisture = fromNullFloat64(f.Moisture)
+	f.SpecifiedCalcium = fromNullFloat64(f.Calcium)
+	f.SpecifiedPhosphorus = fromNullFloat64(f.Phosphorus)
+	if f.PortionUnits == nil {
+		f.PortionUnits = make([]FoodPortionUnit, 0)
+	}
+	if f.Barcodes == nil {
+		f.Barcodes = make([]string, 0)
+	}
 }
 
 func (f *Food) SetSpecifiedDescription(description *string) {
@@ -73,11 +191,100 @@ func (f *Food) SetSpecifiedManufacturer(manufacturer *string) {
 	}
 }
 
+// PortionGrams converts the amount of the unit to grams of the food
+func (f *Food) PortionGrams(amount float64, unit string) (float64, error) {
+	if grams, ok := massUnits[unit]; ok {
+		return amount * grams, nil
+	}
+	for _, portionUnit := range f.PortionUnits {
+		if portionUnit.Unit == unit {
+			return amount * portionUnit.Grams, nil
+		}
+	}
+	return 0, ErrUnknownPortionUnit
+}
+
+// Energy is kcal in the grams of the food
+func (f *Food) Energy(grams float64) float64 {
+	return f.EnergyPer100g * grams / 100
+}
+
+// isComposition checks the nutrients fit in 100 g, half a gram is left for the rounding of the labels
+func (f *Food) isComposition(interface{}) error {
+	var total float64
+	for _, content := range []*float64{f.SpecifiedProtein, f.SpecifiedFat, f.SpecifiedCarbohydrate, f.SpecifiedFibre, f.SpecifiedMoisture} {
+		if content != nil {
+			total += *content
+		}
+	}
+	if total > 100.5 {
+		return fmt.Errorf("protein, fat, carbohydrate, fibre and moisture make %.1f g of 100 g", total)
+	}
+	return nil
+}
+
+func isNutrientContent(value interface{}) error {
+	content, _ := value.(*float64)
+	if content != nil && (*content < 0 || *content > 100) {
+		return errors.New("must be between 0 and 100 grams per 100 g")
+	}
+	return nil
+}
+
+func areUniquePortionUnits(value interface{}) error {
+	units, _ := value.([]FoodPortionUnit)
+	seen := make(map[string]bool, len(units))
+	for _, unit := range units {
+		if err := unit.Validate(); err != nil {
+			return err
+		}
+		if seen[unit.Unit] {
+			return fmt.Errorf("unit %s is specified twice", unit.Unit)
+		}
+		seen[unit.Unit] = true
+	}
+	return nil
+}
+
+func areUniqueBarcodes(value interface{}) error {
+	codes, _ := value.([]string)
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		normalized, err := gtin.Normalize(code)
+		if err != nil {
+			return fmt.Errorf("barcode %s is not a valid GTIN", code)
+		}
+		if seen[normalized] {
+			return fmt.Errorf("barcode %s is specified twice", code)
+		}
+		seen[normalized] = true
+	}
+	return nil
+}
+
+// FoodPortionUnit is how many grams of the food a household unit holds
+type FoodPortionUnit struct {
+	FoodID int     `json:"-" db:"food_id"`
+	Unit   string  `json:"unit" db:"unit"`
+	Grams  float64 `json:"grams" db:"grams"`
+}
+
+func (u FoodPortionUnit) Validate() error {
+	return validation.ValidateStruct(
+		&u,
+		validation.Field(&u.Unit, validation.Required, validation.In(FoodPortionUnits...)),
+		validation.Field(&u.Grams, validation.Required, validation.Min(0.0), validation.Max(float64(maxPortionUnitMass))),
+	)
+}
+
+// Eating is the portion eaten, PortionWeight is the portion in grams converted from the amount of the unit
 type Eating struct {
 	PetID         int       `json:"pet_id" db:"pet_id"`
 	FoodID        int       `json:"food_id" db:"food_id"`
 	Time          time.Time `json:"time" db:"eating_timestamp"`
 	PortionWeight float64   `json:"portion_weight" db:"portion_weight"`
+	PortionAmount float64   `json:"portion_amount" db:"portion_amount"`
+	PortionUnit   string    `json:"portion_unit" db:"portion_unit"`
 }
 
 func (e *Eating) Validate() error {
@@ -85,5 +292,71 @@ func (e *Eating) Validate() error {
 		e,
 		validation.Field(&e.PetID, validation.Required),
 		validation.Field(&e.FoodID, validation.Required),
+		validation.Field(&e.PortionAmount, validation.Required, validation.Min(0.0)),
+		validation.Field(&e.PortionUnit, validation.Required),
+		validation.Field(&e.PortionWeight, validation.Required, validation.Max(float64(maxPortionUnitMass*10))),
+	)
+}
+
+// SetPortion converts the amount of the unit to the portion weight of the food
+func (e *Eating) SetPortion(food *Food, amount float64, unit string) error {
+	grams, err := food.PortionGrams(amount, unit)
+	if err != nil {
+		return err
+	}
+	e.FoodID = food.FoodID
+	e.PortionAmount = amount
+	e.PortionUnit = unit
+	e.PortionWeight = grams
+	return nil
+}
+
+// FoodSearchQuery is the search of the foods, the text is matched with the name, manufacturer and description
+type FoodSearchQuery struct {
+	Text         string
+	Manufacturer string
+	MinEnergy    *float64
+	MaxEnergy    *float64
+	Limit        int
+	Offset       int
+}
+
+func (q *FoodSearchQuery) Validate() error {
+	return validation.ValidateStruct(
+		q,
+		validation.Field(&q.Text, validation.Length(0, 255)),
+		validation.Field(&q.MinEnergy, validation.By(isNonNegative)),
+		validation.Field(&q.MaxEnergy, validation.By(isNonNegative), validation.By(q.isEnergyRange)),
+		validation.Field(&q.Limit, validation.Required, validation.Min(1)),
+		validation.Field(&q.Offset, validation.Min(0)),
 	)
 }
+
+func (q *FoodSearchQuery) isEnergyRange(interface{}) error {
+	if q.MinEnergy != nil && q.MaxEnergy != nil && *q.MinEnergy > *q.MaxEnergy {
+		return errors.New("must not be less than the minimum energy")
+	}
+	return nil
+}
+
+func isNonNegative(value interface{}) error {
+	number, _ := value.(*float64)
+	if number != nil && *number < 0 {
+		return errors.New("must not be negative")
+	}
+	return nil
+}
+
+// FoodHighlights are the matched fields with the matches wrapped in <mark> tags
+type FoodHighlights struct {
+	FoodName     string `json:"food_name,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// FoodSearchResult is the found food, results with the greater rank match the text better
+type FoodSearchResult struct {
+	Food       *Food          `json:"food"`
+	Rank       float64        `json:"rank"`
+	Highlights FoodHighlights `json:"highlights"`
+}
@@ -0,0 +1,104 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func grams(value float64) *float64 {
+	return &value
+}
+
+func TestFood_Validate(t *testing.T) {
+	food := &models.Food{
+		FoodName:              "Adult kibble",
+		EnergyPer100g:         370,
+		SpecifiedProtein:      grams(26),
+		SpecifiedFat:          grams(16),
+		SpecifiedCarbohydrate: grams(40),
+		SpecifiedFibre:        grams(3),
+		SpecifiedMoisture:     grams(10),
+		PortionUnits:          []models.FoodPortionUnit{{Unit: models.UnitCup, Grams: 95}},
+	}
+	assert.NoError(t, food.Validate())
+
+	food.SpecifiedMoisture = grams(20)
+	assert.Error(t, food.Validate())
+	food.SpecifiedMoisture = grams(-1)
+	assert.Error(t, food.Validate())
+	food.SpecifiedMoisture = nil
+	assert.NoError(t, food.Validate())
+
+	food.PortionUnits = append(food.PortionUnits, models.FoodPortionUnit{Unit: models.UnitCup, Grams: 100})
+	assert.Error(t, food.Validate())
+	food.PortionUnits = []models.FoodPortionUnit{{Unit: models.UnitGram, Grams: 1}}
+	assert.Error(t, food.Validate())
+	food.PortionUnits = []models.FoodPortionUnit{{Unit: models.UnitCan}}
+	assert.Error(t, food.Validate())
+}
+
+func TestFood_BeforeCreateEnergy(t *testing.T) {
+	food := &models.Food{
+		FoodName:              "Wet food",
+		SpecifiedProtein:      grams(10),
+		SpecifiedFat:          grams(6),
+		SpecifiedCarbohydrate: grams(4),
+	}
+	food.BeforeCreate()
+	assert.InDelta(t, 100, food.EnergyPer100g, 0.001)
+	assert.True(t, food.Protein.Valid)
+	assert.Nil(t, food.Moisture)
+
+	food.AfterCreate()
+	assert.Nil(t, food.SpecifiedMoisture)
+	assert.NotNil(t, food.PortionUnits)
+
+	specified := &models.Food{EnergyPer100g: 85, SpecifiedProtein: grams(10), SpecifiedFat: grams(6), SpecifiedCarbohydrate: grams(4)}
+	specified.BeforeCreate()
+	assert.Equal(t, 85.0, specified.EnergyPer100g)
+	assert.Equal(t, 0.85, specified.Calories)
+
+	// The deprecated calories are kcal per gram
+	deprecated := &models.Food{FoodName: "Dry food", Calories: 3.7}
+	deprecated.BeforeCreate()
+	assert.InDelta(t, 370, deprecated.EnergyPer100g, 0.001)
+	assert.NoError(t, deprecated.Validate())
+}
+
+func TestEating_SetPortion(t *testing.T) {
+	food := &models.Food{
+		FoodID:        7,
+		EnergyPer100g: 350,
+		PortionUnits:  []models.FoodPortionUnit{{Unit: models.UnitCup, Grams: 95}},
+	}
+	eating := &models.Eating{PetID: 1}
+
+	require.NoError(t, eating.SetPortion(food, 1.5, models.UnitCup))
+	assert.InDelta(t, 142.5, eating.PortionWeight, 0.001)
+	assert.Equal(t, models.UnitCup, eating.PortionUnit)
+	assert.Equal(t, 7, eating.FoodID)
+	assert.InDelta(t, 498.75, food.Energy(eating.PortionWeight), 0.001)
+	assert.NoError(t, eating.Validate())
+
+	require.NoError(t, eating.SetPortion(food, 2, models.UnitOunce))
+	assert.InDelta(t, 56.699, eating.PortionWeight, 0.001)
+
+	assert.Equal(t, models.ErrUnknownPortionUnit, eating.SetPortion(food, 1, models.UnitCan))
+	assert.InDelta(t, 56.699, eating.PortionWeight, 0.001)
+}
+
+func TestFoodSearchQuery_Validate(t *testing.T) {
+	query := &models.FoodSearchQuery{Text: "chicken", MinEnergy: grams(300), MaxEnergy: grams(400), Limit: 20}
+	assert.NoError(t, query.Validate())
+
+	query.MaxEnergy = grams(250)
+	assert.Error(t, query.Validate())
+	query.MaxEnergy = nil
+	query.MinEnergy = grams(-1)
+	assert.Error(t, query.Validate())
+	query.MinEnergy = nil
+	query.Limit = 0
+	assert.Error(t, query.Validate())
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/lib/pq"
+	"time"
+)
+
+const (
+	GeofenceCircle  = "circle"
+	GeofencePolygon = "polygon"
+)
+
+// Geofence event kinds
+const (
+	GeofenceEnter = "enter"
+	GeofenceExit  = "exit"
+)
+
+const (
+	minGeofenceRadius   = 10
+	maxGeofenceRadius   = 50000
+	maxGeofenceVertices = 100
+)
+
+/*
+Geofence is the zone of the pet, e.g. its home, which the owner is alerted about leaving or entering.
+
+Positions are longitude and latitude pairs as in GeoJSON, the radius of the circle is in meters.
+Inside, PendingFixes and LastFixAt are the debouncing state of the zone, they are reset when the zone changes.
+*/
+type Geofence struct {
+	GeofenceID      int              `json:"geofence_id" db:"geofence_id"`
+	PetID           int              `json:"pet_id" db:"pet_id"`
+	Name            string           `json:"name" db:"name"`
+	Shape           string           `json:"shape" db:"shape"`
+	CenterLongitude *sql.NullFloat64 `json:"-" db:"center_longitude"`
+	CenterLatitude  *sql.NullFloat64 `json:"-" db:"center_latitude"`
+	Radius          *sql.NullFloat64 `json:"-" db:"radius"`
+	Vertices        pq.Float64Array  `json:"-" db:"vertices"`
+	AlertOnEnter    bool             `json:"alert_on_enter" db:"alert_on_enter"`
+	AlertOnExit     bool             `json:"alert_on_exit" db:"alert_on_exit"`
+	IsActive        bool             `json:"is_active" db:"is_active"`
+	CreatedAt       time.Time        `json:"created_at" db:"created_at"`
+
+	Inside       *sql.NullBool `json:"-" db:"inside"`
+	PendingFixes int           `json:"-" db:"pending_fixes"`
+	LastFixAt    *sql.NullTime `json:"-" db:"last_fix_at"`
+
+	SpecifiedCenter  *[2]float64  `json:"center,omitempty"`
+	SpecifiedRadius  float64      `json:"radius,omitempty"`
+	SpecifiedPolygon [][2]float64 `json:"polygon,omitempty"`
+	SpecifiedInside  *bool        `json:"inside,omitempty"`
+}
+
+func (g *Geofence) Validate() error {
+	return validation.ValidateStruct(
+		g,
+		validation.Field(&g.Name, validation.Required, validation.Length(1, 64)),
+		validation.Field(&g.Shape, validation.Required, validation.In(GeofenceCircle, GeofencePolygon)),
+		validation.Field(&g.SpecifiedCenter, validation.By(g.isCircleField), validation.By(isPosition)),
+		validation.Field(&g.SpecifiedRadius, validation.By(g.isCircleField), validation.By(isGeofenceRadius)),
+		validation.Field(&g.SpecifiedPolygon, validation.By(g.isPolygonField), validation.Each(validation.By(isPosition))),
+	)
+}
+
+// BeforeCreate stores the geometry of the shape, the geometry of the other shape is dropped
+func (g *Geofence) BeforeCreate() {
+	g.CenterLongitude, g.CenterLatitude, g.Radius, g.Vertices = nil, nil, nil, nil
+	switch g.Shape {
+	case GeofenceCircle:
+		if g.SpecifiedCenter != nil {
+			g.CenterLongitude = &sql.NullFloat64{Float64: g.SpecifiedCenter[0], Valid: true}
+			g.CenterLatitude = &sql.NullFloat64{Float64: g.SpecifiedCenter[1], Valid: true}
+		}
+		g.Radius = &sql.NullFloat64{Float64: g.SpecifiedRadius, Valid: true}
+	case GeofencePolygon:
+		g.Vertices = make(pq.Float64Array, 0, len(g.SpecifiedPolygon)*2)
+		for _, position := range g.SpecifiedPolygon {
+			g.Vertices = append(g.Vertices, position[0], position[1])
+		}
+	}
+}
+
+func (g *Geofence) AfterCreate() {
+	g.SpecifiedCenter, g.SpecifiedRadius, g.SpecifiedPolygon, g.SpecifiedInside = nil, 0, nil, nil
+	if g.CenterLongitude != nil && g.CenterLongitude.Valid && g.CenterLatitude != nil && g.CenterLatitude.Valid {
+		g.SpecifiedCenter = &[2]float64{g.CenterLongitude.Float64, g.CenterLatitude.Float64}
+	}
+	if g.Radius != nil && g.Radius.Valid {
+		g.SpecifiedRadius = g.Radius.Float64
+	}
+	for idx := 0; idx+1 < len(g.Vertices); idx += 2 {
+		g.SpecifiedPolygon = append(g.SpecifiedPolygon, [2]float64{g.Vertices[idx], g.Vertices[idx+1]})
+	}
+	if g.Inside != nil && g.Inside.Valid {
+		inside := g.Inside.Bool
+		g.SpecifiedInside = &inside
+	}
+}
+
+// ResetState forgets where the pet is relative to the zone, it is used when the zone geometry changes
+func (g *Geofence) ResetState() {
+	g.Inside, g.PendingFixes, g.LastFixAt = nil, 0, nil
+}
+
+// GeofenceEvent is the confirmed crossing of the zone boundary at the position of the fix
+type GeofenceEvent struct {
+	EventID      int       `json:"event_id" db:"event_id"`
+	GeofenceID   int       `json:"geofence_id" db:"geofence_id"`
+	GeofenceName string    `json:"geofence_name" db:"geofence_name"`
+	PetID        int       `json:"pet_id" db:"pet_id"`
+	Kind         string    `json:"kind" db:"kind"`
+	OccurredAt   time.Time `json:"occurred_at" db:"occurred_at"`
+	Latitude     float64   `json:"latitude" db:"latitude"`
+	Longitude    float64   `json:"longitude" db:"longitude"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// isCircleField requires the center and the radius of the circle
+func (g *Geofence) isCircleField(value interface{}) error {
+	if g.Shape == GeofenceCircle {
+		return validation.Validate(value, validation.Required)
+	}
+	return nil
+}
+
+// isPolygonField requires the vertices of the polygon
+func (g *Geofence) isPolygonField(value interface{}) error {
+	if g.Shape == GeofencePolygon {
+		return validation.Validate(value, validation.Required, validation.Length(3, maxGeofenceVertices))
+	}
+	return nil
+}
+
+func isGeofenceRadius(value interface{}) error {
+	radius, _ := value.(float64)
+	if radius != 0 && (radius < minGeofenceRadius || radius > maxGeofenceRadius) {
+		return fmt.Errorf("must be between %d and %d meters", minGeofenceRadius, maxGeofenceRadius)
+	}
+	return nil
+}
+
+func isPosition(value interface{}) error {
+	var position [2]float64
+	switch v := value.(type) {
+	case *[2]float64:
+		if v == nil {
+			return nil
+		}
+		position = *v
+	case [2]float64:
+		position = v
+	default:
+		return nil
+	}
+	if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+		return errors.New("must be longitude between -180 and 180 and latitude between -90 and 90")
+	}
+	return nil
+}
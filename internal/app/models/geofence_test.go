@@ -0,0 +1,55 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGeofence_Validate(t *testing.T) {
+	circle := &models.Geofence{
+		Name:            "Home",
+		Shape:           models.GeofenceCircle,
+		SpecifiedCenter: &[2]float64{30.52, 50.45},
+		SpecifiedRadius: 150,
+	}
+	assert.NoError(t, circle.Validate())
+
+	circle.SpecifiedRadius = 5
+	assert.Error(t, circle.Validate())
+	circle.SpecifiedRadius = 150
+	circle.SpecifiedCenter = &[2]float64{50.45, 130.52}
+	assert.Error(t, circle.Validate())
+	circle.SpecifiedCenter = nil
+	assert.Error(t, circle.Validate())
+
+	polygon := &models.Geofence{
+		Name:             "Park",
+		Shape:            models.GeofencePolygon,
+		SpecifiedPolygon: [][2]float64{{30.50, 50.40}, {30.51, 50.40}, {30.51, 50.41}},
+	}
+	assert.NoError(t, polygon.Validate())
+
+	polygon.SpecifiedPolygon = polygon.SpecifiedPolygon[:2]
+	assert.Error(t, polygon.Validate())
+	polygon.SpecifiedPolygon = [][2]float64{{30.50, 50.40}, {30.51, 50.40}, {190, 50.41}}
+	assert.Error(t, polygon.Validate())
+	polygon.SpecifiedPolygon = [][2]float64{{30.50, 50.40}, {30.51, 50.40}, {30.51, 50.41}}
+	polygon.Shape = "square"
+	assert.Error(t, polygon.Validate())
+}
+
+func TestGeofence_BeforeCreate(t *testing.T) {
+	polygon := &models.Geofence{
+		Shape:            models.GeofencePolygon,
+		SpecifiedCenter:  &[2]float64{30.52, 50.45},
+		SpecifiedPolygon: [][2]float64{{30.50, 50.40}, {30.51, 50.40}, {30.51, 50.41}},
+	}
+	polygon.BeforeCreate()
+	assert.Nil(t, polygon.CenterLongitude)
+	assert.Equal(t, []float64{30.50, 50.40, 30.51, 50.40, 30.51, 50.41}, []float64(polygon.Vertices))
+
+	polygon.AfterCreate()
+	assert.Nil(t, polygon.SpecifiedCenter)
+	assert.Equal(t, [][2]float64{{30.50, 50.40}, {30.51, 50.40}, {30.51, 50.41}}, polygon.SpecifiedPolygon)
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Health alert kinds
+const (
+	AlertWeightLoss     = "weight_loss"
+	AlertIntakeAboveMER = "intake_above_mer"
+	AlertIntakeBelowMER = "intake_below_mer"
+	AlertActivityDrop   = "activity_drop"
+)
+
+const (
+	AlertSeverityWarning  = "warning"
+	AlertSeverityCritical = "critical"
+)
+
+/*
+HealthAlert is the signal found by the health analysis job. The pet has at most one open alert of the kind,
+the job refreshes it while the signal holds and resolves it when the signal is gone.
+
+Value and Baseline are what the signal compares, e.g. the current and the peak weight.
+*/
+type HealthAlert struct {
+	AlertID    int           `json:"alert_id" db:"alert_id"`
+	PetID      int           `json:"pet_id" db:"pet_id"`
+	Kind       string        `json:"kind" db:"kind"`
+	Severity   string        `json:"severity" db:"severity"`
+	Message    string        `json:"message" db:"message"`
+	Value      float64       `json:"value" db:"value"`
+	Baseline   float64       `json:"baseline" db:"baseline"`
+	DetectedAt time.Time     `json:"detected_at" db:"detected_at"`
+	UpdatedAt  time.Time     `json:"updated_at" db:"updated_at"`
+	ResolvedAt *sql.NullTime `json:"-" db:"resolved_at"`
+
+	SpecifiedResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	IsResolved          bool       `json:"is_resolved"`
+}
+
+func (a *HealthAlert) AfterCreate() {
+	a.SpecifiedResolvedAt = nil
+	a.IsResolved = a.ResolvedAt != nil && a.ResolvedAt.Valid
+	if a.IsResolved {
+		resolvedAt := a.ResolvedAt.Time
+		a.SpecifiedResolvedAt = &resolvedAt
+	}
+}
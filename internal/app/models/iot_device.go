@@ -1,12 +1,153 @@
 package models
 
+import (
+	"database/sql"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"time"
+)
+
+const (
+	IoTDeviceActive   = "active"
+	IoTDeviceUnpaired = "unpaired"
+	IoTDeviceRevoked  = "revoked"
+)
+
+var (
+	ErrIoTDeviceIsPaired    = errors.New("device is already paired to a pet")
+	ErrIoTDeviceIsNotPaired = errors.New("device is not paired to a pet")
+	ErrIoTDeviceIsRevoked   = errors.New("device is revoked")
+)
+
+/*
+IoTDevice is the collar reporting the pet telemetry.
+
+Only the hash of the access secret is stored, the secret itself is shown once when the device
+is registered or its secret is rotated. Unpaired devices keep the last pet and may be paired
+to another pet of the owner, revoked devices can not be used anymore.
+*/
 type IoTDevice struct {
-	DeviceID     int    `json:"device_id" db:"device_id"`
-	PetID        int    `json:"pet_id" db:"pet_id"`
-	AccessSecret string `json:"access_secret" db:"access_secret"`
+	DeviceID        int             `json:"device_id" db:"device_id"`
+	PetID           int             `json:"pet_id" db:"pet_id"`
+	UserID          int             `json:"user_id" db:"user_id"`
+	AccessSecret    string          `json:"-" db:"access_secret"`
+	Name            string          `json:"name" db:"name"`
+	Status          string          `json:"status" db:"status"`
+	FirmwareVersion *sql.NullString `json:"-" db:"firmware_version"`
+	BatteryLevel    *sql.NullInt64  `json:"-" db:"battery_level"`
+	LastSeenAt      *sql.NullTime   `json:"-" db:"last_seen_at"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	RevokedAt       *sql.NullTime   `json:"-" db:"revoked_at"`
+
+	SpecifiedFirmwareVersion string     `json:"firmware_version,omitempty"`
+	SpecifiedBatteryLevel    *int       `json:"battery_level,omitempty"`
+	SpecifiedLastSeenAt      *time.Time `json:"last_seen_at,omitempty"`
+	SpecifiedRevokedAt       *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (i *IoTDevice) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Name, validation.Required, validation.Length(1, 64)),
+		validation.Field(&i.Status, validation.Required, validation.In(IoTDeviceActive, IoTDeviceUnpaired, IoTDeviceRevoked)),
+	)
+}
+
+func (i *IoTDevice) AfterCreate() {
+	i.SpecifiedFirmwareVersion = fromNullString(i.FirmwareVersion)
+	i.SpecifiedBatteryLevel = nil
+	if i.BatteryLevel != nil && i.BatteryLevel.Valid {
+		batteryLevel := int(i.BatteryLevel.Int64)
+		i.SpecifiedBatteryLevel = &batteryLevel
+	}
+	i.SpecifiedLastSeenAt = nil
+	if i.LastSeenAt != nil && i.LastSeenAt.Valid {
+		lastSeenAt := i.LastSeenAt.Time
+		i.SpecifiedLastSeenAt = &lastSeenAt
+	}
+	i.SpecifiedRevokedAt = nil
+	if i.RevokedAt != nil && i.RevokedAt.Valid {
+		revokedAt := i.RevokedAt.Time
+		i.SpecifiedRevokedAt = &revokedAt
+	}
 }
 
 func (i *IoTDevice) Update(device IoTDevice) {
 	i.PetID = device.PetID
 	i.AccessSecret = device.AccessSecret
 }
+
+// IsActive reports whether the device may authenticate and report telemetry of the pet
+func (i *IoTDevice) IsActive() bool {
+	return i.Status == IoTDeviceActive
+}
+
+// Pair pairs the unpaired device to the pet with the new secret, the devices in use are not taken over
+func (i *IoTDevice) Pair(petID int, secretHash string) error {
+	switch i.Status {
+	case IoTDeviceActive:
+		return ErrIoTDeviceIsPaired
+	case IoTDeviceRevoked:
+		return ErrIoTDeviceIsRevoked
+	}
+	i.PetID = petID
+	i.AccessSecret = secretHash
+	i.Status = IoTDeviceActive
+	return nil
+}
+
+// RotateSecret replaces the secret of the paired device
+func (i *IoTDevice) RotateSecret(secretHash string) error {
+	switch i.Status {
+	case IoTDeviceRevoked:
+		return ErrIoTDeviceIsRevoked
+	case IoTDeviceUnpaired:
+		return ErrIoTDeviceIsNotPaired
+	}
+	i.AccessSecret = secretHash
+	return nil
+}
+
+// Unpair unpairs the device from the pet, the secret is replaced so the device can not log in with the old one
+func (i *IoTDevice) Unpair(secretHash string) error {
+	if i.Status == IoTDeviceRevoked {
+		return ErrIoTDeviceIsRevoked
+	}
+	i.AccessSecret = secretHash
+	i.Status = IoTDeviceUnpaired
+	return nil
+}
+
+// Revoke retires the device for good, the secret is replaced so the device can not log in with the old one
+func (i *IoTDevice) Revoke(secretHash string, revokedAt time.Time) error {
+	if i.Status == IoTDeviceRevoked {
+		return ErrIoTDeviceIsRevoked
+	}
+	i.AccessSecret = secretHash
+	i.Status = IoTDeviceRevoked
+	i.RevokedAt = &sql.NullTime{Time: revokedAt, Valid: true}
+	return nil
+}
+
+// Rename renames the device which is still in use
+func (i *IoTDevice) Rename(name string) error {
+	if i.Status == IoTDeviceRevoked {
+		return ErrIoTDeviceIsRevoked
+	}
+	i.Name = name
+	return nil
+}
+
+// DeviceTelemetry is the device state reported along with the pet telemetry
+type DeviceTelemetry struct {
+	FirmwareVersion string `json:"firmware_version"`
+	BatteryLevel    *int   `json:"battery_level"`
+}
+
+func (t *DeviceTelemetry) Validate() error {
+	return validation.ValidateStruct(
+		t,
+		validation.Field(&t.FirmwareVersion, validation.Length(0, 64)),
+		validation.Field(&t.BatteryLevel, validation.Min(0), validation.Max(100)),
+	)
+}
@@ -0,0 +1,67 @@
+package models_test
+
+import (
+	"encoding/json"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestIoTDevice_Lifecycle(t *testing.T) {
+	device := &models.IoTDevice{DeviceID: 7, PetID: 1, AccessSecret: "first-hash", Name: "Collar", Status: models.IoTDeviceActive}
+
+	require.NoError(t, device.RotateSecret("rotated-hash"))
+	assert.Equal(t, "rotated-hash", device.AccessSecret)
+	assert.True(t, device.IsActive())
+
+	require.NoError(t, device.Unpair("unpaired-hash"))
+	assert.Equal(t, "unpaired-hash", device.AccessSecret)
+	assert.False(t, device.IsActive())
+	assert.Equal(t, models.ErrIoTDeviceIsNotPaired, device.RotateSecret("hash"))
+
+	require.NoError(t, device.Pair(2, "paired-hash"))
+	assert.Equal(t, 2, device.PetID)
+	assert.Equal(t, "paired-hash", device.AccessSecret)
+	assert.True(t, device.IsActive())
+
+	revokedAt := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, device.Revoke("revoked-hash", revokedAt))
+	assert.Equal(t, "revoked-hash", device.AccessSecret)
+	assert.False(t, device.IsActive())
+	device.AfterCreate()
+	require.NotNil(t, device.SpecifiedRevokedAt)
+	assert.Equal(t, revokedAt, *device.SpecifiedRevokedAt)
+
+	// The revoked device can not be used anymore
+	assert.Equal(t, models.ErrIoTDeviceIsRevoked, device.RotateSecret("hash"))
+	assert.Equal(t, models.ErrIoTDeviceIsRevoked, device.Unpair("hash"))
+	assert.Equal(t, models.ErrIoTDeviceIsRevoked, device.Revoke("hash", revokedAt))
+	assert.Equal(t, models.ErrIoTDeviceIsRevoked, device.Rename("Old collar"))
+	assert.Equal(t, "revoked-hash", device.AccessSecret)
+	assert.Equal(t, "Collar", device.Name)
+}
+
+func TestIoTDevice_Pair(t *testing.T) {
+	for status, expected := range map[string]error{
+		models.IoTDeviceActive:   models.ErrIoTDeviceIsPaired,
+		models.IoTDeviceRevoked:  models.ErrIoTDeviceIsRevoked,
+		models.IoTDeviceUnpaired: nil,
+	} {
+		device := &models.IoTDevice{PetID: 1, AccessSecret: "old-hash", Status: status}
+		assert.Equal(t, expected, device.Pair(2, "new-hash"), status)
+		if expected != nil {
+			assert.Equal(t, 1, device.PetID, status)
+			assert.Equal(t, "old-hash", device.AccessSecret, status)
+			assert.Equal(t, status, device.Status, status)
+		}
+	}
+}
+
+func TestIoTDevice_SecretIsNotExposed(t *testing.T) {
+	device := &models.IoTDevice{DeviceID: 7, AccessSecret: "secret-hash", Name: "Collar", Status: models.IoTDeviceActive}
+	raw, err := json.Marshal(device)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "secret-hash")
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is the unit of background work stored in the persistent queue
+type Job struct {
+	JobID       int             `json:"job_id" db:"job_id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Payload     []byte          `json:"-" db:"payload"`
+	Status      string          `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	LockedAt    *sql.NullTime   `json:"-" db:"locked_at"`
+	LastError   *sql.NullString `json:"-" db:"last_error"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	FinishedAt  *sql.NullTime   `json:"-" db:"finished_at"`
+
+	SpecifiedLastError string `json:"last_error,omitempty"`
+}
+
+func (j *Job) AfterCreate() {
+	j.SpecifiedLastError = fromNullString(j.LastError)
+}
+
+// HasAttemptsLeft reports whether the failed job may be retried
+func (j *Job) HasAttemptsLeft() bool {
+	return j.Attempts < j.MaxAttempts
+}
+
+// JobSchedule remembers when the periodic job was enqueued the last time,
+// so the schedule is not repeated after the leader changes
+type JobSchedule struct {
+	Name      string    `json:"name" db:"name"`
+	LastRunAt time.Time `json:"last_run_at" db:"last_run_at"`
+}
@@ -0,0 +1,345 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"time"
+)
+
+// Statuses of the conditions, all but resolved ones are on the problem list of the pet
+const (
+	ConditionActive      = "active"
+	ConditionChronic     = "chronic"
+	ConditionInRemission = "in_remission"
+	ConditionResolved    = "resolved"
+)
+
+// Categories of the allergens
+const (
+	AllergenFood          = "food"
+	AllergenDrug          = "drug"
+	AllergenEnvironmental = "environmental"
+	AllergenInsect        = "insect"
+	AllergenOther         = "other"
+)
+
+// Severities of the allergic reactions
+const (
+	AllergySeverityMild     = "mild"
+	AllergySeverityModerate = "moderate"
+	AllergySeveritySevere   = "severe"
+)
+
+// Kinds of the procedures
+const (
+	ProcedureSurgery    = "surgery"
+	ProcedureDental     = "dental"
+	ProcedureImaging    = "imaging"
+	ProcedureDiagnostic = "diagnostic"
+	ProcedureTherapy    = "therapy"
+	ProcedureOther      = "other"
+)
+
+// Flags of the lab results against their reference ranges, results without the range are not flagged
+const (
+	LabResultLow    = "low"
+	LabResultNormal = "normal"
+	LabResultHigh   = "high"
+)
+
+const maxLabPanelResults = 100
+
+var (
+	ConditionStatuses  = []interface{}{ConditionActive, ConditionChronic, ConditionInRemission, ConditionResolved}
+	AllergenCategories = []interface{}{AllergenFood, AllergenDrug, AllergenEnvironmental, AllergenInsect, AllergenOther}
+	AllergySeverities  = []interface{}{AllergySeverityMild, AllergySeverityModerate, AllergySeveritySevere}
+	ProcedureKinds     = []interface{}{
+		ProcedureSurgery, ProcedureDental, ProcedureImaging, ProcedureDiagnostic, ProcedureTherapy, ProcedureOther,
+	}
+)
+
+/*
+Condition is the diagnosis of the pet made by the veterinarian, optionally coded by the diagnosis terminology
+used by the clinic. Every change of its status is kept in the History.
+*/
+type Condition struct {
+	ConditionID    int             `json:"condition_id" db:"condition_id"`
+	PetID          int             `json:"pet_id" db:"pet_id"`
+	VeterinarianID int             `json:"veterinarian_id" db:"veterinarian_id"`
+	ReportID       *sql.NullInt64  `json:"-" db:"report_id"`
+	Name           string          `json:"name" db:"name"`
+	Code           *sql.NullString `json:"-" db:"code"`
+	Status         string          `json:"status" db:"status"`
+	DiagnosedOn    time.Time       `json:"-" db:"diagnosed_on"`
+	ResolvedOn     *sql.NullTime   `json:"-" db:"resolved_on"`
+	Notes          *sql.NullString `json:"-" db:"notes"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+
+	History []ConditionStatusChange `json:"history,omitempty" db:"-"`
+
+	SpecifiedReportID    int    `json:"report_id,omitempty"`
+	SpecifiedCode        string `json:"code,omitempty"`
+	SpecifiedDiagnosedOn string `json:"diagnosed_on"`
+	SpecifiedResolvedOn  string `json:"resolved_on,omitempty"`
+	SpecifiedNotes       string `json:"notes,omitempty"`
+}
+
+func (c *Condition) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.PetID, validation.Required),
+		validation.Field(&c.VeterinarianID, validation.Required),
+		validation.Field(&c.Name, validation.Required, validation.Length(1, 128)),
+		validation.Field(&c.SpecifiedCode, validation.Length(0, 32)),
+		validation.Field(&c.Status, validation.Required, validation.In(ConditionStatuses...)),
+		validation.Field(&c.SpecifiedDiagnosedOn, validation.Required, validation.Date(DateLayout)),
+		validation.Field(&c.SpecifiedResolvedOn, validation.Date(DateLayout), validation.By(c.isAfterDiagnosis)),
+		validation.Field(&c.SpecifiedNotes, validation.Length(0, 2000)),
+	)
+}
+
+func (c *Condition) BeforeCreate() {
+	c.ReportID = toNullInt64(c.SpecifiedReportID)
+	c.Code = toNullString(c.SpecifiedCode)
+	if diagnosedOn, err := time.Parse(DateLayout, c.SpecifiedDiagnosedOn); err == nil {
+		c.DiagnosedOn = diagnosedOn
+	}
+	c.ResolvedOn = toNullDate(c.SpecifiedResolvedOn)
+	c.Notes = toNullString(c.SpecifiedNotes)
+}
+
+func (c *Condition) AfterCreate() {
+	c.SpecifiedReportID = fromNullInt64(c.ReportID)
+	c.SpecifiedCode = fromNullString(c.Code)
+	c.SpecifiedDiagnosedOn = c.DiagnosedOn.Format(DateLayout)
+	c.SpecifiedResolvedOn = fromNullDate(c.ResolvedOn)
+	c.SpecifiedNotes = fromNullString(c.Notes)
+}
+
+// IsProblem reports whether the condition is on the problem list of the pet
+func (c *Condition) IsProblem() bool {
+	return c.Status != ConditionResolved
+}
+
+func (c *Condition) isAfterDiagnosis(value interface{}) error {
+	resolvedOn, err := time.Parse(DateLayout, value.(string))
+	if err != nil {
+		return nil
+	}
+	diagnosedOn, err := time.Parse(DateLayout, c.SpecifiedDiagnosedOn)
+	if err != nil {
+		return nil
+	}
+	if resolvedOn.Before(diagnosedOn) {
+		return errors.New("must not be before the diagnosis")
+	}
+	return nil
+}
+
+// ConditionStatusChange is the status the condition was given by the veterinarian at the time
+type ConditionStatusChange struct {
+	ChangeID    int       `json:"change_id" db:"change_id"`
+	ConditionID int       `json:"-" db:"condition_id"`
+	Status      string    `json:"status" db:"status"`
+	ChangedBy   int       `json:"changed_by" db:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// PatientCondition is the condition of the veterinarian patient
+type PatientCondition struct {
+	PetName string `json:"pet_name" db:"pet_name"`
+	Condition
+}
+
+// Allergy is the allergen the pet reacts to, inactive allergies are kept as the history
+type Allergy struct {
+	AllergyID         int             `json:"allergy_id" db:"allergy_id"`
+	PetID             int             `json:"pet_id" db:"pet_id"`
+	RecordedBy        int             `json:"recorded_by" db:"recorded_by"`
+	Allergen          string          `json:"allergen" db:"allergen"`
+	Category          string          `json:"category" db:"category"`
+	Severity          string          `json:"severity" db:"severity"`
+	Reaction          *sql.NullString `json:"-" db:"reaction"`
+	IsActive          bool            `json:"is_active" db:"is_active"`
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at" db:"updated_at"`
+	SpecifiedReaction string          `json:"reaction,omitempty"`
+}
+
+func (a *Allergy) Validate() error {
+	return validation.ValidateStruct(
+		a,
+		validation.Field(&a.PetID, validation.Required),
+		validation.Field(&a.RecordedBy, validation.Required),
+		validation.Field(&a.Allergen, validation.Required, validation.Length(1, 128)),
+		validation.Field(&a.Category, validation.Required, validation.In(AllergenCategories...)),
+		validation.Field(&a.Severity, validation.Required, validation.In(AllergySeverities...)),
+		validation.Field(&a.SpecifiedReaction, validation.Length(0, 500)),
+	)
+}
+
+func (a *Allergy) BeforeCreate() {
+	a.Reaction = toNullString(a.SpecifiedReaction)
+}
+
+func (a *Allergy) AfterCreate() {
+	a.SpecifiedReaction = fromNullString(a.Reaction)
+}
+
+// Procedure is the surgery or other procedure performed on the pet, optionally as the treatment of the condition
+type Procedure struct {
+	ProcedureID          int             `json:"procedure_id" db:"procedure_id"`
+	PetID                int             `json:"pet_id" db:"pet_id"`
+	VeterinarianID       int             `json:"veterinarian_id" db:"veterinarian_id"`
+	ReportID             *sql.NullInt64  `json:"-" db:"report_id"`
+	ConditionID          *sql.NullInt64  `json:"-" db:"condition_id"`
+	Kind                 string          `json:"kind" db:"kind"`
+	Name                 string          `json:"name" db:"name"`
+	PerformedOn          time.Time       `json:"-" db:"performed_on"`
+	Outcome              *sql.NullString `json:"-" db:"outcome"`
+	CreatedAt            time.Time       `json:"created_at" db:"created_at"`
+	SpecifiedReportID    int             `json:"report_id,omitempty"`
+	SpecifiedConditionID int             `json:"condition_id,omitempty"`
+	SpecifiedPerformedOn string          `json:"performed_on"`
+	SpecifiedOutcome     string          `json:"outcome,omitempty"`
+}
+
+func (p *Procedure) Validate() error {
+	return validation.ValidateStruct(
+		p,
+		validation.Field(&p.PetID, validation.Required),
+		validation.Field(&p.VeterinarianID, validation.Required),
+		validation.Field(&p.Kind, validation.Required, validation.In(ProcedureKinds...)),
+		validation.Field(&p.Name, validation.Required, validation.Length(1, 128)),
+		validation.Field(&p.SpecifiedPerformedOn, validation.Required, validation.Date(DateLayout)),
+		validation.Field(&p.SpecifiedOutcome, validation.Length(0, 2000)),
+	)
+}
+
+func (p *Procedure) BeforeCreate() {
+	p.ReportID = toNullInt64(p.SpecifiedReportID)
+	p.ConditionID = toNullInt64(p.SpecifiedConditionID)
+	if performedOn, err := time.Parse(DateLayout, p.SpecifiedPerformedOn); err == nil {
+		p.PerformedOn = performedOn
+	}
+	p.Outcome = toNullString(p.SpecifiedOutcome)
+}
+
+func (p *Procedure) AfterCreate() {
+	p.SpecifiedReportID = fromNullInt64(p.ReportID)
+	p.SpecifiedConditionID = fromNullInt64(p.ConditionID)
+	p.SpecifiedPerformedOn = p.PerformedOn.Format(DateLayout)
+	p.SpecifiedOutcome = fromNullString(p.Outcome)
+}
+
+// LabPanel is the set of the lab results of the sample collected from the pet
+type LabPanel struct {
+	PanelID             int             `json:"panel_id" db:"panel_id"`
+	PetID               int             `json:"pet_id" db:"pet_id"`
+	VeterinarianID      int             `json:"veterinarian_id" db:"veterinarian_id"`
+	ReportID            *sql.NullInt64  `json:"-" db:"report_id"`
+	Name                string          `json:"name" db:"name"`
+	Laboratory          *sql.NullString `json:"-" db:"laboratory"`
+	CollectedAt         time.Time       `json:"collected_at" db:"collected_at"`
+	CreatedAt           time.Time       `json:"created_at" db:"created_at"`
+	Results             []LabResult     `json:"results" db:"-"`
+	SpecifiedReportID   int             `json:"report_id,omitempty"`
+	SpecifiedLaboratory string          `json:"laboratory,omitempty"`
+}
+
+func (p *LabPanel) Validate() error {
+	return validation.ValidateStruct(
+		p,
+		validation.Field(&p.PetID, validation.Required),
+		validation.Field(&p.VeterinarianID, validation.Required),
+		validation.Field(&p.Name, validation.Required, validation.Length(1, 64)),
+		validation.Field(&p.SpecifiedLaboratory, validation.Length(0, 128)),
+		validation.Field(&p.CollectedAt, validation.Required),
+		validation.Field(&p.Results, validation.Required, validation.Length(1, maxLabPanelResults)),
+	)
+}
+
+func (p *LabPanel) BeforeCreate() {
+	p.CollectedAt = p.CollectedAt.UTC()
+	p.ReportID = toNullInt64(p.SpecifiedReportID)
+	p.Laboratory = toNullString(p.SpecifiedLaboratory)
+	for idx := range p.Results {
+		p.Results[idx].BeforeCreate()
+	}
+}
+
+func (p *LabPanel) AfterCreate() {
+	p.SpecifiedReportID = fromNullInt64(p.ReportID)
+	p.SpecifiedLaboratory = fromNullString(p.Laboratory)
+	if p.Results == nil {
+		p.Results = make([]LabResult, 0)
+	}
+	for idx := range p.Results {
+		p.Results[idx].AfterCreate()
+	}
+}
+
+// LabResult is the numeric result of the analyte with the reference range of the laboratory
+type LabResult struct {
+	ResultID               int              `json:"result_id" db:"result_id"`
+	PanelID                int              `json:"-" db:"panel_id"`
+	Analyte                string           `json:"analyte" db:"analyte"`
+	Value                  float64          `json:"value" db:"value"`
+	Unit                   string           `json:"unit" db:"unit"`
+	ReferenceLow           *sql.NullFloat64 `json:"-" db:"reference_low"`
+	ReferenceHigh          *sql.NullFloat64 `json:"-" db:"reference_high"`
+	SpecifiedReferenceLow  *float64         `json:"reference_low"`
+	SpecifiedReferenceHigh *float64         `json:"reference_high"`
+	Flag                   string           `json:"flag,omitempty" db:"-"`
+}
+
+func (r LabResult) Validate() error {
+	return validation.ValidateStruct(
+		&r,
+		validation.Field(&r.Analyte, validation.Required, validation.Length(1, 64)),
+		validation.Field(&r.Unit, validation.Length(0, 16)),
+		validation.Field(&r.SpecifiedReferenceHigh, validation.By(r.isAboveLow)),
+	)
+}
+
+func (r *LabResult) BeforeCreate() {
+	r.ReferenceLow = toNullFloat64(r.SpecifiedReferenceLow)
+	r.ReferenceHigh = toNullFloat64(r.SpecifiedReferenceHigh)
+}
+
+func (r *LabResult) AfterCreate() {
+	r.SpecifiedReferenceLow = fromNullFloat64(r.ReferenceLow)
+	r.SpecifiedReferenceHigh = fromNullFloat64(r.ReferenceHigh)
+	r.Flag = r.Interpret()
+}
+
+// Interpret flags the value against the reference range, the value without any bound of the range is not flagged
+func (r *LabResult) Interpret() string {
+	if r.SpecifiedReferenceLow == nil && r.SpecifiedReferenceHigh == nil {
+		return ""
+	}
+	if r.SpecifiedReferenceLow != nil && r.Value < *r.SpecifiedReferenceLow {
+		return LabResultLow
+	}
+	if r.SpecifiedReferenceHigh != nil && r.Value > *r.SpecifiedReferenceHigh {
+		return LabResultHigh
+	}
+	return LabResultNormal
+}
+
+func (r *LabResult) isAboveLow(value interface{}) error {
+	high, _ := value.(*float64)
+	if high != nil && r.SpecifiedReferenceLow != nil && *high < *r.SpecifiedReferenceLow {
+		return errors.New("must not be below the reference low")
+	}
+	return nil
+}
+
+// ProblemList is what the pet is being treated for or has to be treated with care because of
+type ProblemList struct {
+	Conditions  []Condition  `json:"conditions"`
+	Allergies   []Allergy    `json:"allergies"`
+	Medications []Medication `json:"medications"`
+}
@@ -0,0 +1,89 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCondition_Validate(t *testing.T) {
+	condition := &models.Condition{
+		PetID:                1,
+		VeterinarianID:       2,
+		Name:                 "Chronic kidney disease",
+		SpecifiedCode:        "N18",
+		Status:               models.ConditionChronic,
+		SpecifiedDiagnosedOn: "2021-03-10",
+	}
+	assert.NoError(t, condition.Validate())
+
+	condition.Status = models.ConditionResolved
+	condition.SpecifiedResolvedOn = "2021-03-01"
+	assert.Error(t, condition.Validate())
+	condition.SpecifiedResolvedOn = "2021-04-01"
+	assert.NoError(t, condition.Validate())
+
+	condition.Status = "cured"
+	assert.Error(t, condition.Validate())
+	condition.Status = models.ConditionActive
+	condition.SpecifiedDiagnosedOn = "10.03.2021"
+	assert.Error(t, condition.Validate())
+}
+
+func TestCondition_BeforeCreate(t *testing.T) {
+	condition := &models.Condition{SpecifiedDiagnosedOn: "2021-03-10", SpecifiedCode: "N18"}
+	condition.BeforeCreate()
+	assert.Equal(t, time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC), condition.DiagnosedOn)
+	assert.True(t, condition.Code.Valid)
+	assert.Nil(t, condition.ResolvedOn)
+	assert.Nil(t, condition.ReportID)
+
+	condition.AfterCreate()
+	assert.Equal(t, "2021-03-10", condition.SpecifiedDiagnosedOn)
+	assert.Equal(t, "", condition.SpecifiedResolvedOn)
+	assert.True(t, condition.IsProblem())
+}
+
+func TestLabResult_Interpret(t *testing.T) {
+	low, high := 0.5, 1.5
+	result := &models.LabResult{Analyte: "Creatinine", Value: 1.0}
+	assert.Equal(t, "", result.Interpret())
+
+	result.SpecifiedReferenceLow = &low
+	result.SpecifiedReferenceHigh = &high
+	assert.Equal(t, models.LabResultNormal, result.Interpret())
+	result.Value = 2.1
+	assert.Equal(t, models.LabResultHigh, result.Interpret())
+	result.Value = 0.2
+	assert.Equal(t, models.LabResultLow, result.Interpret())
+
+	result.SpecifiedReferenceLow = nil
+	assert.Equal(t, models.LabResultNormal, result.Interpret())
+	result.Value = high
+	assert.Equal(t, models.LabResultNormal, result.Interpret())
+}
+
+func TestLabPanel_Validate(t *testing.T) {
+	low, high := 0.5, 1.5
+	panel := &models.LabPanel{
+		PetID:          1,
+		VeterinarianID: 2,
+		Name:           "Renal panel",
+		CollectedAt:    time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC),
+		Results: []models.LabResult{
+			{Analyte: "Creatinine", Value: 1.0, Unit: "mg/dL", SpecifiedReferenceLow: &low, SpecifiedReferenceHigh: &high},
+		},
+	}
+	assert.NoError(t, panel.Validate())
+
+	panel.Results[0].SpecifiedReferenceLow, panel.Results[0].SpecifiedReferenceHigh = &high, &low
+	assert.Error(t, panel.Validate())
+	panel.Results[0].SpecifiedReferenceLow = nil
+	assert.NoError(t, panel.Validate())
+	panel.Results[0].Analyte = ""
+	assert.Error(t, panel.Validate())
+
+	panel.Results = nil
+	assert.Error(t, panel.Validate())
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"time"
+)
+
+// Administration routes of the medications
+const (
+	RouteOral          = "oral"
+	RouteTopical       = "topical"
+	RouteSubcutaneous  = "subcutaneous"
+	RouteIntramuscular = "intramuscular"
+	RouteIntravenous   = "intravenous"
+	RouteOphthalmic    = "ophthalmic"
+	RouteOtic          = "otic"
+	RouteInhaled       = "inhaled"
+	RouteRectal        = "rectal"
+	RouteTransdermal   = "transdermal"
+)
+
+const (
+	maxMedicationInterval = 24 * 28
+	// maxDoseWindow is how late or early the dose may be given at most to count for its scheduled time
+	maxDoseWindow = 2 * time.Hour
+)
+
+var MedicationRoutes = []interface{}{
+	RouteOral,
+	RouteTopical,
+	RouteSubcutaneous,
+	RouteIntramuscular,
+	RouteIntravenous,
+	RouteOphthalmic,
+	RouteOtic,
+	RouteInhaled,
+	RouteRectal,
+	RouteTransdermal,
+}
+
+/*
+Medication is the drug prescribed to the pet by the veterinarian, optionally as the treatment of the health report.
+
+The doses are scheduled every IntervalHours from StartsAt until EndsAt, the medication given as needed has
+no interval and no schedule. RemindedUntil and CheckedUntil are the times the owner has been reminded about
+the due doses and alerted about the missed ones until.
+*/
+type Medication struct {
+	MedicationID   int             `json:"medication_id" db:"medication_id"`
+	PetID          int             `json:"pet_id" db:"pet_id"`
+	VeterinarianID int             `json:"veterinarian_id" db:"veterinarian_id"`
+	ReportID       *sql.NullInt64  `json:"-" db:"report_id"`
+	Drug           string          `json:"drug" db:"drug"`
+	Dose           float64         `json:"dose" db:"dose"`
+	DoseUnit       string          `json:"dose_unit" db:"dose_unit"`
+	Route          string          `json:"route" db:"route"`
+	IntervalHours  int             `json:"interval_hours" db:"interval_hours"`
+	StartsAt       time.Time       `json:"starts_at" db:"starts_at"`
+	EndsAt         *sql.NullTime   `json:"-" db:"ends_at"`
+	Instructions   *sql.NullString `json:"-" db:"instructions"`
+	RemindedUntil  *sql.NullTime   `json:"-" db:"reminded_until"`
+	CheckedUntil   *sql.NullTime   `json:"-" db:"checked_until"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+
+	SpecifiedReportID     int        `json:"report_id,omitempty"`
+	SpecifiedEndsAt       *time.Time `json:"ends_at"`
+	SpecifiedInstructions string     `json:"instructions,omitempty"`
+}
+
+func (m *Medication) Validate() error {
+	return validation.ValidateStruct(
+		m,
+		validation.Field(&m.PetID, validation.Required),
+		validation.Field(&m.VeterinarianID, validation.Required),
+		validation.Field(&m.Drug, validation.Required, validation.Length(1, 128)),
+		validation.Field(&m.Dose, validation.Required, validation.Min(0.0)),
+		validation.Field(&m.DoseUnit, validation.Required, validation.Length(1, 16)),
+		validation.Field(&m.Route, validation.Required, validation.In(MedicationRoutes...)),
+		validation.Field(&m.IntervalHours, validation.Min(0), validation.Max(maxMedicationInterval)),
+		validation.Field(&m.StartsAt, validation.Required),
+		validation.Field(&m.SpecifiedEndsAt, validation.By(m.isAfterStart)),
+		validation.Field(&m.SpecifiedInstructions, validation.Length(0, 1000)),
+	)
+}
+
+func (m *Medication) BeforeCreate() {
+	m.StartsAt = m.StartsAt.UTC()
+	m.ReportID = nil
+	if m.SpecifiedReportID != 0 {
+		m.ReportID = &sql.NullInt64{Int64: int64(m.SpecifiedReportID), Valid: true}
+	}
+	m.EndsAt = nil
+	if m.SpecifiedEndsAt != nil {
+		m.EndsAt = &sql.NullTime{Time: m.SpecifiedEndsAt.UTC(), Valid: true}
+	}
+	m.Instructions = nil
+	if m.SpecifiedInstructions != "" {
+		m.Instructions = &sql.NullString{String: m.SpecifiedInstructions, Valid: true}
+	}
+}
+
+func (m *Medication) AfterCreate() {
+	m.SpecifiedReportID = 0
+	if m.ReportID != nil && m.ReportID.Valid {
+		m.SpecifiedReportID = int(m.ReportID.Int64)
+	}
+	m.SpecifiedEndsAt = nil
+	if m.EndsAt != nil && m.EndsAt.Valid {
+		endsAt := m.EndsAt.Time
+		m.SpecifiedEndsAt = &endsAt
+	}
+	m.SpecifiedInstructions = ""
+	if m.Instructions != nil && m.Instructions.Valid {
+		m.SpecifiedInstructions = m.Instructions.String
+	}
+}
+
+// IsScheduled reports whether the doses are given by the schedule rather than as needed
+func (m *Medication) IsScheduled() bool {
+	return m.IntervalHours > 0
+}
+
+// IsActive reports whether the medication is given at the time
+func (m *Medication) IsActive(at time.Time) bool {
+	if at.Before(m.StartsAt) {
+		return false
+	}
+	return m.EndsAt == nil || !m.EndsAt.Valid || at.Before(m.EndsAt.Time)
+}
+
+// Interval is the time between the scheduled doses
+func (m *Medication) Interval() time.Duration {
+	return time.Duration(m.IntervalHours) * time.Hour
+}
+
+// DoseWindow is how far from its scheduled time the dose may be given, half the interval and two hours at most
+func (m *Medication) DoseWindow() time.Duration {
+	window := m.Interval() / 2
+	if window > maxDoseWindow {
+		window = maxDoseWindow
+	}
+	return window
+}
+
+// SetSpecifiedReportID links the medication to the health report, zero removes the link
+func (m *Medication) SetSpecifiedReportID(reportID *int) {
+	if reportID != nil {
+		m.SpecifiedReportID = *reportID
+	}
+}
+
+func (m *Medication) SetSpecifiedInstructions(instructions *string) {
+	if instructions != nil {
+		m.SpecifiedInstructions = *instructions
+	}
+}
+
+func (m *Medication) isAfterStart(value interface{}) error {
+	endsAt, _ := value.(*time.Time)
+	if endsAt != nil && !endsAt.After(m.StartsAt) {
+		return errors.New("must be after the start of the medication")
+	}
+	return nil
+}
+
+// MedicationDose is the dose of the medication given to the pet by the user
+type MedicationDose struct {
+	DoseID           int             `json:"dose_id" db:"dose_id"`
+	MedicationID     int             `json:"medication_id" db:"medication_id"`
+	AdministeredAt   time.Time       `json:"administered_at" db:"administered_at"`
+	AdministeredBy   int             `json:"administered_by" db:"administered_by"`
+	Amount           float64         `json:"amount" db:"amount"`
+	Comment          *sql.NullString `json:"-" db:"comment"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	SpecifiedComment string          `json:"comment,omitempty"`
+}
+
+func (d *MedicationDose) Validate() error {
+	return validation.ValidateStruct(
+		d,
+		validation.Field(&d.MedicationID, validation.Required),
+		validation.Field(&d.AdministeredAt, validation.Required),
+		validation.Field(&d.AdministeredBy, validation.Required),
+		validation.Field(&d.Amount, validation.Required, validation.Min(0.0)),
+		validation.Field(&d.SpecifiedComment, validation.Length(0, 500)),
+	)
+}
+
+func (d *MedicationDose) BeforeCreate() {
+	d.AdministeredAt = d.AdministeredAt.UTC()
+	d.Comment = nil
+	if d.SpecifiedComment != "" {
+		d.Comment = &sql.NullString{String: d.SpecifiedComment, Valid: true}
+	}
+}
+
+func (d *MedicationDose) AfterCreate() {
+	if d.Comment != nil && d.Comment.Valid {
+		d.SpecifiedComment = d.Comment.String
+	}
+}
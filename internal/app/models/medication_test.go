@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMedication_Validate(t *testing.T) {
+	startsAt := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	medication := &models.Medication{
+		PetID:          1,
+		VeterinarianID: 2,
+		Drug:           "Amoxicillin",
+		Dose:           250,
+		DoseUnit:       "mg",
+		Route:          models.RouteOral,
+		IntervalHours:  12,
+		StartsAt:       startsAt,
+	}
+	assert.NoError(t, medication.Validate())
+
+	endsAt := startsAt.AddDate(0, 0, 7)
+	medication.SpecifiedEndsAt = &endsAt
+	assert.NoError(t, medication.Validate())
+	medication.SpecifiedEndsAt = &startsAt
+	assert.Error(t, medication.Validate())
+	medication.SpecifiedEndsAt = nil
+
+	medication.Route = "by mouth"
+	assert.Error(t, medication.Validate())
+	medication.Route = models.RouteOral
+	medication.Dose = -1
+	assert.Error(t, medication.Validate())
+}
+
+func TestMedication_DoseWindow(t *testing.T) {
+	medication := &models.Medication{IntervalHours: 2}
+	assert.True(t, medication.IsScheduled())
+	assert.Equal(t, time.Hour, medication.DoseWindow())
+
+	medication.IntervalHours = 24
+	assert.Equal(t, 2*time.Hour, medication.DoseWindow())
+
+	medication.IntervalHours = 0
+	assert.False(t, medication.IsScheduled())
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"time"
+)
+
+// Notification events
+const (
+	NotificationHealthReport         = "pet.health_report"
+	NotificationParentVerified       = "pet.parent_verified"
+	NotificationVeterinarianAssigned = "pet.veterinarian_assigned"
+	NotificationVaccinationDue       = "pet.vaccination_due"
+	NotificationGeofenceEnter        = "pet.geofence_enter"
+	NotificationGeofenceExit         = "pet.geofence_exit"
+	NotificationDoseDue              = "pet.dose_due"
+	NotificationDoseMissed           = "pet.dose_missed"
+)
+
+// Notification delivery channels. Every notification is stored in the in-app inbox,
+// these channels deliver its copy outside of the application.
+const (
+	ChannelEmail   = "email"
+	ChannelWebPush = "web_push"
+	ChannelWebhook = "webhook"
+)
+
+var (
+	NotificationEvents = []string{
+		NotificationHealthReport,
+		NotificationParentVerified,
+		NotificationVeterinarianAssigned,
+		NotificationVaccinationDue,
+		NotificationGeofenceEnter,
+		NotificationGeofenceExit,
+		NotificationDoseDue,
+		NotificationDoseMissed,
+	}
+	NotificationChannels = []string{ChannelEmail, ChannelWebPush, ChannelWebhook}
+)
+
+func IsNotificationEvent(event string) bool {
+	return contains(NotificationEvents, event)
+}
+
+func IsNotificationChannel(channel string) bool {
+	return contains(NotificationChannels, channel)
+}
+
+type Notification struct {
+	NotificationID int             `json:"notification_id" db:"notification_id"`
+	UserID         int             `json:"user_id" db:"user_id"`
+	Event          string          `json:"event" db:"event"`
+	Title          string          `json:"title" db:"title"`
+	Body           string          `json:"body" db:"body"`
+	Data           []byte          `json:"-" db:"data"`
+	ReadAt         *sql.NullTime   `json:"-" db:"read_at"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	SpecifiedData  json.RawMessage `json:"data,omitempty"`
+	IsRead         bool            `json:"is_read"`
+}
+
+func (n *Notification) AfterCreate() {
+	if len(n.Data) != 0 {
+		n.SpecifiedData = append(json.RawMessage(nil), n.Data...)
+	}
+	n.IsRead = n.ReadAt != nil && n.ReadAt.Valid
+}
+
+// NotificationPreference overrides whether the event is delivered to the user by the channel.
+// Events are delivered by all channels unless disabled, web push and webhooks only
+// reach the users who have registered their endpoints.
+type NotificationPreference struct {
+	UserID  int    `json:"-" db:"user_id"`
+	Event   string `json:"event" db:"event"`
+	Channel string `json:"channel" db:"channel"`
+	Enabled bool   `json:"enabled" db:"enabled"`
+}
+
+/*
+NotificationEndpoint is the address notifications are delivered to by the channel.
+
+For web push it is the push subscription: endpoint URL in Address,
+the subscription p256dh key in PublicKey and auth secret in Secret.
+For webhooks it is the URL requests are sent to and the secret they are signed with.
+*/
+type NotificationEndpoint struct {
+	EndpointID int             `json:"endpoint_id" db:"endpoint_id"`
+	UserID     int             `json:"user_id" db:"user_id"`
+	Channel    string          `json:"channel" db:"channel"`
+	Address    string          `json:"address" db:"address"`
+	PublicKey  *sql.NullString `json:"-" db:"public_key"`
+	Secret     string          `json:"-" db:"secret"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+
+	SpecifiedPublicKey string `json:"-"`
+}
+
+func (e *NotificationEndpoint) Validate() error {
+	return validation.ValidateStruct(
+		e,
+		validation.Field(&e.Channel, validation.Required, validation.In(ChannelWebPush, ChannelWebhook)),
+		validation.Field(&e.Address, validation.Required, validation.Length(1, 2048), is.URL, validation.By(isPublicURL)),
+		validation.Field(&e.SpecifiedPublicKey, validation.By(requiredIf(e.Channel != ChannelWebPush))),
+		validation.Field(&e.Secret, validation.Required),
+	)
+}
+
+func (e *NotificationEndpoint) BeforeCreate() {
+	e.PublicKey = toNullString(e.SpecifiedPublicKey)
+}
+
+func (e *NotificationEndpoint) AfterCreate() {
+	e.SpecifiedPublicKey = fromNullString(e.PublicKey)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
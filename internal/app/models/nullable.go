@@ -0,0 +1,138 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ClockTimeLayout is the layout of the time of day values, e.g. 09:30
+const ClockTimeLayout = "15:04"
+
+// fromClockTime trims the seconds part, which postgres adds to TIME values, so 09:30:00 is read as 09:30
+func fromClockTime(value string) string {
+	if len(value) > len(ClockTimeLayout) {
+		return value[:len(ClockTimeLayout)]
+	}
+	return value
+}
+
+// DateLayout is the layout of the calendar date values, e.g. 2021-06-01
+const DateLayout = "2006-01-02"
+
+/*
+NullableID is the reference field of the update request body, which tells the absent field from the explicit null.
+The absent reference is kept, the null one is removed.
+*/
+type NullableID struct {
+	Present bool
+	Value   *int
+}
+
+func (n *NullableID) UnmarshalJSON(data []byte) error {
+	n.Present = true
+	n.Value = nil
+	if string(data) == "null" {
+		return nil
+	}
+	var value int
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+	return nil
+}
+
+// Apply returns the reference the current one is replaced with, zero is no reference
+func (n NullableID) Apply(current int) int {
+	if !n.Present {
+		return current
+	}
+	if n.Value == nil {
+		return 0
+	}
+	return *n.Value
+}
+
+func toNullString(s string) *sql.NullString {
+	if s == "" {
+		return nil
+	}
+	return &sql.NullString{
+		String: s,
+		Valid:  true,
+	}
+}
+
+func fromNullString(s *sql.NullString) string {
+	if s != nil && s.Valid {
+		return s.String
+	}
+	return ""
+}
+
+func toNullFloat64(f *float64) *sql.NullFloat64 {
+	if f == nil {
+		return nil
+	}
+	return &sql.NullFloat64{
+		Float64: *f,
+		Valid:   true,
+	}
+}
+
+func fromNullFloat64(f *sql.NullFloat64) *float64 {
+	if f != nil && f.Valid {
+		value := f.Float64
+		return &value
+	}
+	return nil
+}
+
+func toNullInt64(i int) *sql.NullInt64 {
+	if i == 0 {
+		return nil
+	}
+	return &sql.NullInt64{
+		Int64: int64(i),
+		Valid: true,
+	}
+}
+
+func fromNullInt64(i *sql.NullInt64) int {
+	if i != nil && i.Valid {
+		return int(i.Int64)
+	}
+	return 0
+}
+
+// toNullDate parses the date, the empty or malformed one is NULL
+func toNullDate(s string) *sql.NullTime {
+	date, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return nil
+	}
+	return &sql.NullTime{
+		Time:  date,
+		Valid: true,
+	}
+}
+
+func fromNullDate(t *sql.NullTime) string {
+	if t != nil && t.Valid {
+		return t.Time.Format(DateLayout)
+	}
+	return ""
+}
+
+func isClockTime(value interface{}) error {
+	s, _ := value.(string)
+	if s == "" {
+		return nil
+	}
+	if _, err := time.Parse(ClockTimeLayout, s); err != nil {
+		return errors.New("must be a time in HH:MM format")
+	}
+	return nil
+}
@@ -2,14 +2,48 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"time"
 )
 
+// Species tell which feeding guidelines apply to the pet type
+const (
+	SpeciesDog   = "dog"
+	SpeciesCat   = "cat"
+	SpeciesOther = "other"
+)
+
+// Pet sex
+const (
+	SexMale   = "male"
+	SexFemale = "female"
+)
+
+const (
+	ActivityLow      = "low"
+	ActivityModerate = "moderate"
+	ActivityHigh     = "high"
+)
+
+// Feeding goals
+const (
+	GoalMaintain   = "maintain"
+	GoalLoseWeight = "lose_weight"
+	GoalGainWeight = "gain_weight"
+)
+
+const (
+	ReproductionNone      = "none"
+	ReproductionPregnant  = "pregnant"
+	ReproductionLactating = "lactating"
+)
+
 type PetType struct {
 	TypeID         int     `json:"type_id" db:"type_id"`
 	TypeName       string  `json:"type_name" db:"type_name"`
 	RERCoefficient float64 `json:"rer_coefficient" db:"rer_coefficient"`
+	Species        string  `json:"species" db:"species"`
 }
 
 func (p *PetType) Validate() error {
@@ -17,6 +51,7 @@ func (p *PetType) Validate() error {
 		p,
 		validation.Field(&p.TypeName, validation.Required),
 		validation.Field(&p.RERCoefficient, validation.Required),
+		validation.Field(&p.Species, validation.In(SpeciesDog, SpeciesCat, SpeciesOther)),
 	)
 }
 
@@ -24,6 +59,9 @@ func (p *PetType) Update(other *PetType) {
 	if other.RERCoefficient != 0 && other.RERCoefficient != p.RERCoefficient {
 		p.RERCoefficient = other.RERCoefficient
 	}
+	if other.Species != "" && other.Species != p.Species {
+		p.Species = other.Species
+	}
 	if other.TypeName != "" && other.TypeName != p.TypeName {
 		p.TypeName = other.TypeName
 	}
@@ -41,18 +79,69 @@ type Pet struct {
 	VeterinarianID *sql.NullInt64  `json:"-" db:"veterinarian_id"`
 	Breed          *sql.NullString `json:"-" db:"breed"`
 	FamilyName     *sql.NullString `json:"-" db:"family_name"`
+	ClinicID       *sql.NullInt64  `json:"-" db:"clinic_id"`
 
-	SpecifiedMotherID       int    `json:"mother_id,omitempty"`
-	SpecifiedFatherID       int    `json:"father_id,omitempty"`
-	SpecifiedVeterinarianID int    `json:"veterinarian_id,omitempty"`
-	SpecifiedBreed          string `json:"breed,omitempty"`
-	SpecifiedFamilyName     string `json:"family_name,omitempty"`
+	// Nutrition profile, the energy requirement of the pet is computed from it
+	BirthDate          *sql.NullTime   `json:"-" db:"birth_date"`
+	Sex                *sql.NullString `json:"-" db:"sex"`
+	IsNeutered         bool            `json:"is_neutered" db:"is_neutered"`
+	ActivityLevel      *sql.NullString `json:"-" db:"activity_level"`
+	Goal               *sql.NullString `json:"-" db:"goal"`
+	ReproductiveStatus *sql.NullString `json:"-" db:"reproductive_status"`
+
+	SpecifiedClinicID           int    `json:"clinic_id,omitempty"`
+	SpecifiedMotherID           int    `json:"mother_id,omitempty"`
+	SpecifiedFatherID           int    `json:"father_id,omitempty"`
+	SpecifiedVeterinarianID     int    `json:"veterinarian_id,omitempty"`
+	SpecifiedBreed              string `json:"breed,omitempty"`
+	SpecifiedFamilyName         string `json:"family_name,omitempty"`
+	SpecifiedBirthDate          string `json:"birth_date,omitempty"`
+	SpecifiedSex                string `json:"sex,omitempty"`
+	SpecifiedActivityLevel      string `json:"activity_level,omitempty"`
+	SpecifiedGoal               string `json:"goal,omitempty"`
+	SpecifiedReproductiveStatus string `json:"reproductive_status,omitempty"`
 }
 
 func (p *Pet) Validate() error {
 	return validation.ValidateStruct(p,
 		validation.Field(&p.Name, validation.Required, validation.Length(2, 30)),
-		validation.Field(&p.PetType, validation.Required))
+		validation.Field(&p.PetType, validation.Required),
+		validation.Field(&p.SpecifiedBirthDate, validation.Date("2006-01-02"), validation.By(isPastDate)),
+		validation.Field(&p.SpecifiedSex, validation.In(SexMale, SexFemale)),
+		validation.Field(&p.SpecifiedActivityLevel, validation.In(ActivityLow, ActivityModerate, ActivityHigh)),
+		validation.Field(&p.SpecifiedGoal, validation.In(GoalMaintain, GoalLoseWeight, GoalGainWeight)),
+		validation.Field(&p.SpecifiedReproductiveStatus,
+			validation.In(ReproductionNone, ReproductionPregnant, ReproductionLactating),
+			validation.By(p.isReproductiveStatusAllowed),
+		),
+	)
+}
+
+// isReproductiveStatusAllowed only allows pregnancy and lactation of intact females
+func (p *Pet) isReproductiveStatusAllowed(value interface{}) error {
+	status, _ := value.(string)
+	if status == "" || status == ReproductionNone {
+		return nil
+	}
+	if p.SpecifiedSex != SexFemale || p.IsNeutered {
+		return errors.New("is only allowed for intact females")
+	}
+	return nil
+}
+
+func isPastDate(value interface{}) error {
+	raw, _ := value.(string)
+	if raw == "" {
+		return nil
+	}
+	date, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil
+	}
+	if date.After(time.Now()) {
+		return errors.New("must not be in the future")
+	}
+	return nil
 }
 
 func (p *Pet) BeforeCreate() {
@@ -86,6 +175,15 @@ func (p *Pet) BeforeCreate() {
 			Valid:  true,
 		}
 	}
+	if p.SpecifiedBirthDate != "" {
+		if birthDate, err := time.Parse("2006-01-02", p.SpecifiedBirthDate); err == nil {
+			p.BirthDate = &sql.NullTime{Time: birthDate, Valid: true}
+		}
+	}
+	p.Sex = toNullString(p.SpecifiedSex)
+	p.ActivityLevel = toNullString(p.SpecifiedActivityLevel)
+	p.Goal = toNullString(p.SpecifiedGoal)
+	p.ReproductiveStatus = toNullString(p.SpecifiedReproductiveStatus)
 
 }
 
@@ -105,6 +203,16 @@ func (p *Pet) AfterCreate() {
 	if p.FatherID != nil && p.FatherID.Valid {
 		p.SpecifiedFatherID = int(p.FatherID.Int64)
 	}
+	if p.ClinicID != nil && p.ClinicID.Valid {
+		p.SpecifiedClinicID = int(p.ClinicID.Int64)
+	}
+	if p.BirthDate != nil && p.BirthDate.Valid {
+		p.SpecifiedBirthDate = p.BirthDate.Time.Format("2006-01-02")
+	}
+	p.SpecifiedSex = fromNullString(p.Sex)
+	p.SpecifiedActivityLevel = fromNullString(p.ActivityLevel)
+	p.SpecifiedGoal = fromNullString(p.Goal)
+	p.SpecifiedReproductiveStatus = fromNullString(p.ReproductiveStatus)
 }
 
 func (p *Pet) Update(other *Pet) {
@@ -162,6 +270,39 @@ func (p *Pet) Update(other *Pet) {
 		}
 		p.SpecifiedFamilyName = other.SpecifiedFamilyName
 	}
+	if other.SpecifiedBirthDate != p.SpecifiedBirthDate {
+		if other.SpecifiedBirthDate == "" {
+			p.BirthDate = nil
+		}
+		p.SpecifiedBirthDate = other.SpecifiedBirthDate
+	}
+	p.IsNeutered = other.IsNeutered
+	p.SpecifiedSex = other.SpecifiedSex
+	p.SpecifiedActivityLevel = other.SpecifiedActivityLevel
+	p.SpecifiedGoal = other.SpecifiedGoal
+	p.SpecifiedReproductiveStatus = other.SpecifiedReproductiveStatus
+}
+
+// SetNutritionProfile sets the profile fields given in the request
+func (p *Pet) SetNutritionProfile(birthDate *string, sex *string, isNeutered *bool, activityLevel *string, goal *string, reproductiveStatus *string) {
+	if birthDate != nil {
+		p.SpecifiedBirthDate = *birthDate
+	}
+	if sex != nil {
+		p.SpecifiedSex = *sex
+	}
+	if isNeutered != nil {
+		p.IsNeutered = *isNeutered
+	}
+	if activityLevel != nil {
+		p.SpecifiedActivityLevel = *activityLevel
+	}
+	if goal != nil {
+		p.SpecifiedGoal = *goal
+	}
+	if reproductiveStatus != nil {
+		p.SpecifiedReproductiveStatus = *reproductiveStatus
+	}
 }
 
 func (p *Pet) SetSpecifiedMotherID(id *int) {
@@ -211,22 +352,57 @@ func (a *Anthropometry) Validate() error {
 }
 
 type Activity struct {
-	PetID           int       `json:"pet_id" db:"pet_id"`
-	RecordTimestamp time.Time `json:"record_timestamp" db:"record_timestamp"`
-	Distance        float64   `json:"distance" db:"distance"`
-	MeanSpeed       float64   `json:"mean_speed" db:"mean_speed"`
+	PetID           int             `json:"pet_id" db:"pet_id"`
+	RecordTimestamp time.Time       `json:"record_timestamp" db:"record_timestamp"`
+	Distance        float64         `json:"distance" db:"distance"`
+	MeanSpeed       float64         `json:"mean_speed" db:"mean_speed"`
+	DeviceID        *sql.NullInt64  `json:"-" db:"device_id"`
+	SampleID        *sql.NullString `json:"-" db:"sample_id"`
+	WalkID          *sql.NullInt64  `json:"-" db:"walk_id"`
 }
 
+// Statuses of the health reports, only the drafts can be edited, the signed ones are amended
+const (
+	ReportDraft   = "draft"
+	ReportSigned  = "signed"
+	ReportAmended = "amended"
+)
+
 type PetHealthReport struct {
+	ReportID                int             `json:"report_id" db:"report_id"`
 	PetID                   int             `json:"pet_id" db:"pet_id"`
 	ReportTimestamp         time.Time       `json:"report_timestamp" db:"report_timestamp"`
 	VeterinarianID          int             `json:"creator_id" db:"veterinarian_id"`
 	ReportConclusion        string          `json:"report_conclusion" db:"report_conclusion"`
 	ReportComments          *sql.NullString `json:"-" db:"report_comments"`
 	SpecifiedReportComments string          `json:"report_comment"`
+
+	Version           int            `json:"version" db:"version"`
+	Status            string         `json:"status" db:"status"`
+	SignedBy          *sql.NullInt64 `json:"-" db:"signed_by"`
+	SignedAt          *sql.NullTime  `json:"-" db:"signed_at"`
+	UpdatedAt         time.Time      `json:"updated_at" db:"updated_at"`
+	SpecifiedSignedBy int            `json:"signed_by,omitempty"`
+	SpecifiedSignedAt *time.Time     `json:"signed_at,omitempty"`
+}
+
+func (p *PetHealthReport) Validate() error {
+	return validation.ValidateStruct(
+		p,
+		validation.Field(&p.PetID, validation.Required),
+		validation.Field(&p.VeterinarianID, validation.Required),
+		validation.Field(&p.ReportConclusion, validation.Required, validation.Length(1, 5000)),
+		validation.Field(&p.SpecifiedReportComments, validation.Length(0, 5000)),
+	)
+}
+
+// IsSigned reports whether the report is locked by signing, it can only be amended then
+func (p *PetHealthReport) IsSigned() bool {
+	return p.Status == ReportSigned || p.Status == ReportAmended
 }
 
 func (p *PetHealthReport) BeforeCreate() {
+	p.ReportComments = nil
 	if p.SpecifiedReportComments != "" {
 		p.ReportComments = &sql.NullString{
 			String: p.SpecifiedReportComments,
@@ -242,19 +418,67 @@ func (p *PetHealthReport) SetSpecifiedReportComments(reportComments *string) {
 }
 
 func (p *PetHealthReport) AfterCreate() {
-	if p.ReportComments != nil && p.ReportComments.Valid {
-		p.SpecifiedReportComments = p.ReportComments.String
+	p.SpecifiedReportComments = fromNullString(p.ReportComments)
+	p.SpecifiedSignedBy = fromNullInt64(p.SignedBy)
+	p.SpecifiedSignedAt = nil
+	if p.SignedAt != nil && p.SignedAt.Valid {
+		p.SpecifiedSignedAt = &p.SignedAt.Time
 	}
 }
 
+// PetHealthReportVersion is the content of the health report as it was after the edit
+type PetHealthReportVersion struct {
+	VersionID                int             `json:"-" db:"version_id"`
+	ReportID                 int             `json:"report_id" db:"report_id"`
+	Version                  int             `json:"version" db:"version"`
+	ReportConclusion         string          `json:"report_conclusion" db:"report_conclusion"`
+	ReportComments           *sql.NullString `json:"-" db:"report_comments"`
+	EditedBy                 int             `json:"edited_by" db:"edited_by"`
+	EditedAt                 time.Time       `json:"edited_at" db:"edited_at"`
+	IsAmendment              bool            `json:"is_amendment" db:"is_amendment"`
+	AmendmentReason          *sql.NullString `json:"-" db:"amendment_reason"`
+	SpecifiedReportComments  string          `json:"report_comment"`
+	SpecifiedAmendmentReason string          `json:"amendment_reason,omitempty"`
+}
+
+func (v *PetHealthReportVersion) AfterCreate() {
+	v.SpecifiedReportComments = fromNullString(v.ReportComments)
+	v.SpecifiedAmendmentReason = fromNullString(v.AmendmentReason)
+}
+
 type FoodCaloriesReport struct {
 	Date              time.Time `db:"date" json:"date"`
 	FoodTotalCalories float64   `db:"eat_ccal" json:"food_total_calories"`
+	NutrientTotals
+}
+
+// NutrientTotals are the grams of the nutrients eaten, the foods without the composition add nothing to them
+type NutrientTotals struct {
+	Protein      float64 `db:"protein" json:"protein"`
+	Fat          float64 `db:"fat" json:"fat"`
+	Carbohydrate float64 `db:"carbohydrate" json:"carbohydrate"`
+	Fibre        float64 `db:"fibre" json:"fibre"`
+	Moisture     float64 `db:"moisture" json:"moisture"`
+	Calcium      float64 `db:"calcium" json:"calcium"`
+	Phosphorus   float64 `db:"phosphorus" json:"phosphorus"`
+	// IsComplete is false when some of the foods eaten miss the composition
+	IsComplete bool `db:"is_complete" json:"nutrients_complete"`
+}
+
+// NutrientIntake is the grams of the nutrient eaten against the minimum of the species guideline
+type NutrientIntake struct {
+	Nutrient     string  `json:"nutrient"`
+	Total        float64 `json:"total"`
+	Minimum      float64 `json:"minimum"`
+	IsSufficient bool    `json:"is_sufficient"`
 }
 
+// RERCaloriesReport is the daily energy requirement of the pet, RER is the resting one
+// and MER is the maintenance one computed by the nutrition profile
 type RERCaloriesReport struct {
 	Date             time.Time `db:"date" json:"date"`
 	RERTotalCalories float64   `db:"rer_ccal" json:"rer_total_calories"`
+	MERTotalCalories float64   `db:"mer_ccal" json:"mer_total_calories"`
 }
 
 type AnthropometryReport struct {
@@ -272,6 +496,10 @@ type ActivityReport struct {
 type TodayReport struct {
 	FoodTotalCalories float64 `db:"eat_ccal" json:"food_total_calories"`
 	RERTotalCalories  float64 `db:"rer_ccal" json:"rer_total_calories"`
+	MERTotalCalories  float64 `db:"mer_ccal" json:"mer_total_calories"`
 	MeanSpeed         float64 `json:"mean_speed"`
 	TotalDistance     float64 `json:"total_distance"`
+	NutrientTotals
+	// Nutrients are empty for the species without guidelines
+	Nutrients []NutrientIntake `json:"nutrients"`
 }
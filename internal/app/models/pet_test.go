@@ -0,0 +1,72 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPet_ValidateNutritionProfile(t *testing.T) {
+	pet := &models.Pet{
+		Name:                   "Rex",
+		PetType:                1,
+		SpecifiedBirthDate:     "2020-04-01",
+		SpecifiedSex:           models.SexMale,
+		SpecifiedActivityLevel: models.ActivityHigh,
+		SpecifiedGoal:          models.GoalLoseWeight,
+	}
+	assert.NoError(t, pet.Validate())
+
+	pet.SpecifiedReproductiveStatus = models.ReproductionPregnant
+	assert.Error(t, pet.Validate())
+	pet.SpecifiedSex = models.SexFemale
+	assert.NoError(t, pet.Validate())
+	pet.IsNeutered = true
+	assert.Error(t, pet.Validate())
+	pet.SpecifiedReproductiveStatus = models.ReproductionNone
+	assert.NoError(t, pet.Validate())
+
+	pet.SpecifiedBirthDate = time.Now().AddDate(0, 1, 0).Format("2006-01-02")
+	assert.Error(t, pet.Validate())
+	pet.SpecifiedBirthDate = "01.04.2020"
+	assert.Error(t, pet.Validate())
+	pet.SpecifiedBirthDate = ""
+	pet.SpecifiedGoal = "bulk"
+	assert.Error(t, pet.Validate())
+}
+
+func TestPet_BeforeCreateNutritionProfile(t *testing.T) {
+	pet := &models.Pet{SpecifiedBirthDate: "2020-04-01", SpecifiedSex: models.SexFemale, SpecifiedGoal: models.GoalMaintain}
+	pet.BeforeCreate()
+	assert.Equal(t, time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC), pet.BirthDate.Time)
+	assert.Equal(t, models.SexFemale, pet.Sex.String)
+	assert.Nil(t, pet.ActivityLevel)
+
+	other := &models.Pet{}
+	pet.Update(other)
+	pet.BeforeCreate()
+	assert.Nil(t, pet.BirthDate)
+	assert.Nil(t, pet.Sex)
+	assert.Nil(t, pet.Goal)
+}
+
+func TestPetHealthReport_Validate(t *testing.T) {
+	report := &models.PetHealthReport{PetID: 1, VeterinarianID: 2, ReportConclusion: "Healthy"}
+	assert.NoError(t, report.Validate())
+
+	report.ReportConclusion = ""
+	assert.Error(t, report.Validate())
+	report.ReportConclusion = "Healthy"
+	report.VeterinarianID = 0
+	assert.Error(t, report.Validate())
+}
+
+func TestPetHealthReport_IsSigned(t *testing.T) {
+	report := &models.PetHealthReport{Status: models.ReportDraft}
+	assert.False(t, report.IsSigned())
+	report.Status = models.ReportSigned
+	assert.True(t, report.IsSigned())
+	report.Status = models.ReportAmended
+	assert.True(t, report.IsSigned())
+}
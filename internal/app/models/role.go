@@ -17,6 +17,53 @@ type Role struct {
 	AllowFoodCrud            bool            `db:"allow_food_crud" json:"allow_food_crud"`
 	AllowPetsCrud            bool            `db:"allow_pets_crud" json:"allow_pets_crud"`
 	AllowDatabaseDump        bool            `db:"allow_database_dump" json:"allow_database_dump"`
+	SystemKey                *sql.NullString `db:"system_key" json:"-"`
+	SpecifiedSystemKey       string          `json:"system_key,omitempty"`
+}
+
+// System role keys are stable identifiers of the roles the application
+// relies on. They are stored in public.roles.system_key and never change,
+// unlike the serial role_id which depends on the database seeding order.
+const (
+	SystemRoleAdministrator = "administrator"
+	SystemRoleSubscribed    = "subscribed_user"
+	SystemRoleUnsubscribed  = "unsubscribed_user"
+	SystemRoleVeterinarian  = "veterinarian"
+)
+
+// SystemRoles returns the roles, which must exist in any database
+// for application to work. Used for seeding of the fresh database.
+func SystemRoles() []Role {
+	return []Role{
+		{
+			RoleName:                 "Administrator",
+			RoleSpecifiedDescription: "System administrator with full access",
+			AllowRolesCrud:           true,
+			AllowUsersCrud:           true,
+			AllowVeterinariansCrud:   true,
+			AllowVaccinesCrud:        true,
+			AllowFoodCrud:            true,
+			AllowPetsCrud:            true,
+			AllowDatabaseDump:        true,
+			SpecifiedSystemKey:       SystemRoleAdministrator,
+		},
+		{
+			RoleName:                 "Subscribed User",
+			RoleSpecifiedDescription: "User with an active subscription",
+			SpecifiedSystemKey:       SystemRoleSubscribed,
+		},
+		{
+			RoleName:                 "Unsubscribed User",
+			RoleSpecifiedDescription: "Registered user without subscription",
+			SpecifiedSystemKey:       SystemRoleUnsubscribed,
+		},
+		{
+			RoleName:                 "Veterinarian",
+			RoleSpecifiedDescription: "Veterinarian, who can be assigned to pets",
+			AllowVaccinesCrud:        true,
+			SpecifiedSystemKey:       SystemRoleVeterinarian,
+		},
+	}
 }
 
 type Permission struct {
@@ -50,12 +97,21 @@ func (r *Role) BeforeCreate() {
 			Valid:  false,
 		}
 	}
+	if r.SpecifiedSystemKey != "" {
+		r.SystemKey = &sql.NullString{
+			String: r.SpecifiedSystemKey,
+			Valid:  true,
+		}
+	}
 }
 
 func (r *Role) CheckNullableData() {
 	if r.RoleDescription != nil && r.RoleDescription.Valid {
 		r.RoleSpecifiedDescription = r.RoleDescription.String
 	}
+	if r.SystemKey != nil && r.SystemKey.Valid {
+		r.SpecifiedSystemKey = r.SystemKey.String
+	}
 }
 
 func (r *Role) SetDescription(description *string) {
@@ -80,8 +136,28 @@ func (r *Role) Update(other *Role) {
 	r.BeforeCreate()
 }
 
+// IsSystem reports whether the role is one of the base system roles,
+// which can not be updated or deleted
+func (r *Role) IsSystem() bool {
+	return r.systemKey() != ""
+}
+
+// Is reports whether the role is the system role with specified key
+func (r *Role) Is(systemKey string) bool {
+	return r.IsSystem() && r.systemKey() == systemKey
+}
+
+// systemKey falls back to the specified key as roles restored
+// from the session storage have only json fields filled
+func (r *Role) systemKey() string {
+	if r.SystemKey != nil && r.SystemKey.Valid {
+		return r.SystemKey.String
+	}
+	return r.SpecifiedSystemKey
+}
+
 func (r *Role) IsVeterinarian() bool {
-	return r.RoleID == 4
+	return r.Is(SystemRoleVeterinarian)
 }
 
 func (r Role) HasAllPermission(permissions ...Permission) bool {
@@ -0,0 +1,58 @@
+package models_test
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRole_Is(t *testing.T) {
+	stored := &models.Role{SystemKey: &sql.NullString{String: models.SystemRoleVeterinarian, Valid: true}}
+	assert.True(t, stored.IsSystem())
+	assert.True(t, stored.Is(models.SystemRoleVeterinarian))
+	assert.True(t, stored.IsVeterinarian())
+	assert.False(t, stored.Is(models.SystemRoleAdministrator))
+
+	// The roles restored from the session storage have only the json fields
+	restored := &models.Role{SpecifiedSystemKey: models.SystemRoleAdministrator}
+	assert.True(t, restored.IsSystem())
+	assert.True(t, restored.Is(models.SystemRoleAdministrator))
+	assert.False(t, restored.IsVeterinarian())
+
+	// The custom role is not the system one even when it is named like it
+	custom := &models.Role{RoleName: "Veterinarian", SystemKey: &sql.NullString{}}
+	assert.False(t, custom.IsSystem())
+	assert.False(t, custom.Is(models.SystemRoleVeterinarian))
+	assert.False(t, custom.Is(""))
+	assert.False(t, (&models.Role{}).Is(""))
+}
+
+func TestSystemRoles(t *testing.T) {
+	roles := models.SystemRoles()
+	keys := make([]string, 0, len(roles))
+	for idx := range roles {
+		roles[idx].BeforeCreate()
+		assert.True(t, roles[idx].IsSystem(), roles[idx].RoleName)
+		assert.Equal(t, roles[idx].SpecifiedSystemKey, roles[idx].SystemKey.String, roles[idx].RoleName)
+		keys = append(keys, roles[idx].SpecifiedSystemKey)
+	}
+	assert.ElementsMatch(t, []string{
+		models.SystemRoleAdministrator,
+		models.SystemRoleSubscribed,
+		models.SystemRoleUnsubscribed,
+		models.SystemRoleVeterinarian,
+	}, keys)
+
+	for _, role := range roles {
+		switch {
+		case role.Is(models.SystemRoleAdministrator):
+			assert.True(t, role.HasAllPermission(models.RolePermissions().RolesPermission, models.RolePermissions().DatabasePermission))
+		case role.Is(models.SystemRoleVeterinarian):
+			assert.True(t, role.HasAllPermission(models.RolePermissions().VaccinesPermission))
+			assert.False(t, role.HasAnyPermission(models.RolePermissions().RolesPermission, models.RolePermissions().UsersPermission))
+		default:
+			assert.False(t, role.HasAnyPermission(models.RolePermissions().RolesPermission, models.RolePermissions().DatabasePermission))
+		}
+	}
+}
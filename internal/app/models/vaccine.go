@@ -6,28 +6,33 @@ import (
 	"time"
 )
 
+// VaccineBoosterInterval is the period in years after which vaccination
+// should be repeated, used for records made before the vaccine catalogue
+const VaccineBoosterInterval = 1
+
 type Vaccine struct {
 	VaccineID            int             `json:"vaccine_id" db:"vaccine_id"`
 	PetID                int             `json:"pet_id" db:"pet_id"`
+	CatalogueID          *sql.NullInt64  `json:"-" db:"catalogue_id"`
 	Name                 string          `json:"name" db:"name"`
 	VaccinationDate      time.Time       `json:"vaccination_date" db:"vaccination_date"`
 	Description          *sql.NullString `json:"-" db:"description"`
 	SpecifiedDescription string          `json:"specified_description,omitempty"`
+	SpecifiedCatalogueID int             `json:"catalogue_id,omitempty"`
 }
 
 func (v *Vaccine) BeforeCreate() {
-	if v.SpecifiedDescription != "" {
-		v.Description = &sql.NullString{
-			String: v.SpecifiedDescription,
-			Valid:  true,
-		}
-	}
+	v.Description = toNullString(v.SpecifiedDescription)
+	v.CatalogueID = toNullInt64(v.SpecifiedCatalogueID)
 }
 
 func (v *Vaccine) AfterCreate() {
 	if v.Description != nil && v.Description.Valid {
 		v.SpecifiedDescription = v.Description.String
 	}
+	if v.CatalogueID != nil && v.CatalogueID.Valid {
+		v.SpecifiedCatalogueID = int(v.CatalogueID.Int64)
+	}
 }
 
 func (v *Vaccine) Update(other *Vaccine) {
@@ -35,6 +40,12 @@ func (v *Vaccine) Update(other *Vaccine) {
 	if other.PetID != v.PetID {
 		v.PetID = other.PetID
 	}
+	if other.Name != "" && other.Name != v.Name {
+		v.Name = other.Name
+	}
+	if other.SpecifiedCatalogueID != v.SpecifiedCatalogueID {
+		v.SpecifiedCatalogueID = other.SpecifiedCatalogueID
+	}
 	if other.VaccinationDate != v.VaccinationDate {
 		v.VaccinationDate = other.VaccinationDate
 	}
@@ -48,7 +59,7 @@ func (v *Vaccine) Validate() error {
 	return validation.ValidateStruct(
 		v,
 		validation.Field(&v.PetID, validation.Required),
-		validation.Field(&v.Name, validation.Required, validation.Length(3, 20)),
+		validation.Field(&v.Name, validation.Required, validation.Length(3, 100)),
 		validation.Field(&v.VaccinationDate, validation.Required),
 		validation.Field(&v.SpecifiedDescription, validation.Length(3, 0)),
 	)
@@ -60,3 +71,26 @@ func (v *Vaccine) SetSpecifiedDescription(specifiedDescription *string) {
 		v.SpecifiedDescription = *specifiedDescription
 	}
 }
+
+func (v *Vaccine) SetSpecifiedCatalogueID(catalogueID *int) {
+	if catalogueID != nil {
+		v.SpecifiedCatalogueID = *catalogueID
+	}
+}
+
+// BoosterDueDate returns the date the next dose of the vaccine is due
+// for the records, which do not reference the vaccine catalogue
+func (v *Vaccine) BoosterDueDate() time.Time {
+	return v.VaccinationDate.AddDate(VaccineBoosterInterval, 0, 0)
+}
+
+// VaccineReminder is the reminder the owner has been sent about the shot of the catalogue vaccine
+// due at the date, DaysBefore is the number of days before the due date it has been sent at
+type VaccineReminder struct {
+	PetID       int       `json:"pet_id" db:"pet_id"`
+	CatalogueID int       `json:"catalogue_id" db:"catalogue_id"`
+	DoseNumber  int       `json:"dose_number" db:"dose_number"`
+	DueDate     time.Time `json:"due_date" db:"due_date"`
+	DaysBefore  int       `json:"days_before" db:"days_before"`
+	SentAt      time.Time `json:"sent_at" db:"sent_at"`
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"database/sql"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"sort"
+	"time"
+)
+
+const (
+	VaccinationCompleted   = "completed"
+	VaccinationDue         = "due"
+	VaccinationOverdue     = "overdue"
+	VaccinationUnscheduled = "unscheduled"
+)
+
+// VaccineCatalogueEntry describes the vaccine and its schedule for the target species.
+// The primary series consists of DosesCount doses given DoseIntervalDays apart,
+// then the vaccine is boosted every BoosterIntervalMonths. Zero booster interval
+// means the vaccine needs no boosters. The first dose is given at FirstDoseAgeWeeks
+// of the pet age.
+type VaccineCatalogueEntry struct {
+	CatalogueID           int             `json:"catalogue_id" db:"catalogue_id"`
+	Name                  string          `json:"name" db:"name"`
+	PetType               int             `json:"pet_type" db:"pet_type"`
+	Manufacturer          *sql.NullString `json:"-" db:"manufacturer"`
+	DosesCount            int             `json:"doses_count" db:"doses_count"`
+	DoseIntervalDays      int             `json:"dose_interval_days" db:"dose_interval_days"`
+	BoosterIntervalMonths int             `json:"booster_interval_months" db:"booster_interval_months"`
+	FirstDoseAgeWeeks     int             `json:"first_dose_age_weeks" db:"first_dose_age_weeks"`
+	SpecifiedManufacturer string          `json:"manufacturer,omitempty"`
+}
+
+func (e *VaccineCatalogueEntry) Validate() error {
+	return validation.ValidateStruct(
+		e,
+		validation.Field(&e.Name, validation.Required, validation.Length(3, 100)),
+		validation.Field(&e.PetType, validation.Required),
+		validation.Field(&e.SpecifiedManufacturer, validation.Length(2, 100)),
+		validation.Field(&e.DosesCount, validation.Required, validation.Min(1), validation.Max(10)),
+		validation.Field(&e.DoseIntervalDays, validation.By(requiredIf(e.DosesCount <= 1)), validation.Min(0)),
+		validation.Field(&e.BoosterIntervalMonths, validation.Min(0), validation.Max(120)),
+		validation.Field(&e.FirstDoseAgeWeeks, validation.Min(0), validation.Max(520)),
+	)
+}
+
+func (e *VaccineCatalogueEntry) BeforeCreate() {
+	e.Manufacturer = toNullString(e.SpecifiedManufacturer)
+}
+
+func (e *VaccineCatalogueEntry) AfterCreate() {
+	e.SpecifiedManufacturer = fromNullString(e.Manufacturer)
+}
+
+func (e *VaccineCatalogueEntry) SetSpecifiedManufacturer(manufacturer *string) {
+	if manufacturer != nil {
+		e.SpecifiedManufacturer = *manufacturer
+	}
+}
+
+// NextDoseDate returns the due date of the dose following the given number of
+// already made ones. The second return value is false when no more doses are needed.
+func (e *VaccineCatalogueEntry) NextDoseDate(dosesMade int, lastDose time.Time) (time.Time, bool) {
+	if dosesMade < e.DosesCount {
+		return lastDose.AddDate(0, 0, e.DoseIntervalDays), true
+	}
+	if e.BoosterIntervalMonths == 0 {
+		return time.Time{}, false
+	}
+	return lastDose.AddDate(0, e.BoosterIntervalMonths, 0), true
+}
+
+// ScheduledVaccination is the shot of the pet vaccination schedule: either
+// already made (completed), or expected in future (due) or missed (overdue).
+// The unscheduled shot can not be dated, its date is zero.
+type ScheduledVaccination struct {
+	PetID       int       `json:"pet_id"`
+	CatalogueID int       `json:"catalogue_id,omitempty"`
+	Name        string    `json:"name"`
+	DoseNumber  int       `json:"dose_number"`
+	IsBooster   bool      `json:"is_booster"`
+	Status      string    `json:"status"`
+	Date        time.Time `json:"date"`
+	VaccineID   int       `json:"vaccine_id,omitempty"`
+}
+
+/*
+VaccinationSchedule computes the schedule of the pet by the catalogue entries
+applicable to its species and vaccination records. Every record is reported as
+completed shot and for every catalogue entry the next needed shot is reported as
+due or overdue, depending on whether its date has passed by now. Vaccines the pet
+has never received are due at the first dose age from its birth date, they are
+unscheduled when the birth date is unknown.
+*/
+func VaccinationSchedule(pet *Pet, catalogue []VaccineCatalogueEntry, vaccines []Vaccine, now time.Time) []ScheduledVaccination {
+	petID := pet.PetID
+	sorted := make([]Vaccine, len(vaccines))
+	copy(sorted, vaccines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].VaccinationDate.Before(sorted[j].VaccinationDate)
+	})
+
+	entries := make(map[int]*VaccineCatalogueEntry, len(catalogue))
+	for idx := range catalogue {
+		entries[catalogue[idx].CatalogueID] = &catalogue[idx]
+	}
+
+	var schedule []ScheduledVaccination
+	doses := make(map[int]int)
+	lastDoses := make(map[int]time.Time)
+	for _, vaccine := range sorted {
+		shot := ScheduledVaccination{
+			PetID:      petID,
+			Name:       vaccine.Name,
+			DoseNumber: 1,
+			Status:     VaccinationCompleted,
+			Date:       vaccine.VaccinationDate,
+			VaccineID:  vaccine.VaccineID,
+		}
+		if entry, ok := entries[vaccine.SpecifiedCatalogueID]; ok {
+			doses[entry.CatalogueID]++
+			lastDoses[entry.CatalogueID] = vaccine.VaccinationDate
+			shot.CatalogueID = entry.CatalogueID
+			shot.DoseNumber = doses[entry.CatalogueID]
+			shot.IsBooster = shot.DoseNumber > entry.DosesCount
+		}
+		schedule = append(schedule, shot)
+	}
+
+	today := truncateToDay(now)
+	for idx := range catalogue {
+		entry := &catalogue[idx]
+		made := doses[entry.CatalogueID]
+		var dueDate time.Time
+		status := VaccinationDue
+		if made > 0 {
+			var needed bool
+			if dueDate, needed = entry.NextDoseDate(made, lastDoses[entry.CatalogueID]); !needed {
+				continue
+			}
+		} else if pet.BirthDate != nil && pet.BirthDate.Valid {
+			dueDate = pet.BirthDate.Time.AddDate(0, 0, 7*entry.FirstDoseAgeWeeks)
+		} else {
+			status = VaccinationUnscheduled
+		}
+		if status == VaccinationDue && truncateToDay(dueDate).Before(today) {
+			status = VaccinationOverdue
+		}
+		schedule = append(schedule, ScheduledVaccination{
+			PetID:       petID,
+			CatalogueID: entry.CatalogueID,
+			Name:        entry.Name,
+			DoseNumber:  made + 1,
+			IsBooster:   made+1 > entry.DosesCount,
+			Status:      status,
+			Date:        dueDate,
+		})
+	}
+	return schedule
+}
+
+func truncateToDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,96 @@
+package models_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVaccinationSchedule(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	catalogue := []models.VaccineCatalogueEntry{
+		{CatalogueID: 1, Name: "Rabies", DosesCount: 1, BoosterIntervalMonths: 12},
+		{CatalogueID: 2, Name: "DHPP", DosesCount: 3, DoseIntervalDays: 21, BoosterIntervalMonths: 36},
+		{CatalogueID: 3, Name: "Leptospirosis", DosesCount: 2, DoseIntervalDays: 28, FirstDoseAgeWeeks: 8},
+	}
+	vaccination := func(id int, catalogueID int, date time.Time) models.Vaccine {
+		return models.Vaccine{
+			VaccineID:            id,
+			Name:                 catalogue[catalogueID-1].Name,
+			VaccinationDate:      date,
+			CatalogueID:          &sql.NullInt64{Int64: int64(catalogueID), Valid: true},
+			SpecifiedCatalogueID: catalogueID,
+		}
+	}
+	vaccines := []models.Vaccine{
+		vaccination(1, 1, time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)),
+		vaccination(2, 2, time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)),
+		vaccination(3, 2, time.Date(2021, 4, 22, 0, 0, 0, 0, time.UTC)),
+		{VaccineID: 4, Name: "Legacy record", VaccinationDate: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	schedule := models.VaccinationSchedule(&models.Pet{PetID: 10}, catalogue, vaccines, now)
+	assert.Len(t, schedule, 7)
+
+	completed := 0
+	for _, shot := range schedule {
+		if shot.Status == models.VaccinationCompleted {
+			completed++
+		}
+	}
+	assert.Equal(t, 4, completed)
+
+	upcoming := schedule[4:]
+	// Rabies booster is due in a month after the first dose anniversary
+	assert.Equal(t, 1, upcoming[0].CatalogueID)
+	assert.Equal(t, models.VaccinationDue, upcoming[0].Status)
+	assert.True(t, upcoming[0].IsBooster)
+	assert.Equal(t, time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC), upcoming[0].Date)
+	// Third DHPP dose was expected three weeks after the second one
+	assert.Equal(t, 2, upcoming[1].CatalogueID)
+	assert.Equal(t, models.VaccinationOverdue, upcoming[1].Status)
+	assert.Equal(t, 3, upcoming[1].DoseNumber)
+	assert.False(t, upcoming[1].IsBooster)
+	// Never received vaccine can not be dated without the birth date
+	assert.Equal(t, 3, upcoming[2].CatalogueID)
+	assert.Equal(t, models.VaccinationUnscheduled, upcoming[2].Status)
+	assert.Equal(t, 1, upcoming[2].DoseNumber)
+	assert.True(t, upcoming[2].Date.IsZero())
+
+	// Never received vaccine is due at the first dose age
+	pet := &models.Pet{PetID: 10, BirthDate: &sql.NullTime{Time: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true}}
+	schedule = models.VaccinationSchedule(pet, catalogue, vaccines, now)
+	assert.Equal(t, models.VaccinationOverdue, schedule[6].Status)
+	assert.Equal(t, time.Date(2021, 4, 26, 0, 0, 0, 0, time.UTC), schedule[6].Date)
+
+	pet.BirthDate.Time = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	schedule = models.VaccinationSchedule(pet, catalogue, vaccines, now)
+	assert.Equal(t, models.VaccinationDue, schedule[6].Status)
+	assert.Equal(t, time.Date(2021, 6, 26, 0, 0, 0, 0, time.UTC), schedule[6].Date)
+}
+
+func TestNullableID_Apply(t *testing.T) {
+	var id models.NullableID
+	assert.Equal(t, 3, id.Apply(3))
+
+	assert.NoError(t, json.Unmarshal([]byte("null"), &id))
+	assert.Equal(t, 0, id.Apply(3))
+
+	assert.NoError(t, json.Unmarshal([]byte("5"), &id))
+	assert.Equal(t, 5, id.Apply(3))
+}
+
+func TestVaccineCatalogueEntry_NextDoseDate(t *testing.T) {
+	entry := &models.VaccineCatalogueEntry{DosesCount: 2, DoseIntervalDays: 14}
+	last := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	next, needed := entry.NextDoseDate(1, last)
+	assert.True(t, needed)
+	assert.Equal(t, time.Date(2021, 1, 15, 0, 0, 0, 0, time.UTC), next)
+
+	_, needed = entry.NextDoseDate(2, last)
+	assert.False(t, needed)
+}
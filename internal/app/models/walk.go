@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TrackPoint is the GPS fix reported by the collar. Fixes are segmented into walks by the job,
+// the fixes which are not part of any walk keep WalkID empty once they are segmented.
+type TrackPoint struct {
+	PointID     int64            `json:"-" db:"point_id"`
+	PetID       int              `json:"-" db:"pet_id"`
+	DeviceID    *sql.NullInt64   `json:"-" db:"device_id"`
+	WalkID      *sql.NullInt64   `json:"-" db:"walk_id"`
+	RecordedAt  time.Time        `json:"recorded_at" db:"recorded_at"`
+	Latitude    float64          `json:"latitude" db:"latitude"`
+	Longitude   float64          `json:"longitude" db:"longitude"`
+	Accuracy    *sql.NullFloat64 `json:"-" db:"accuracy"`
+	IsSegmented bool             `json:"-" db:"is_segmented"`
+}
+
+// Walk is the summary of the track computed from its fixes, Distance is in meters,
+// Duration in seconds and MeanSpeed in meters per second
+type Walk struct {
+	WalkID     int       `json:"walk_id" db:"walk_id"`
+	PetID      int       `json:"pet_id" db:"pet_id"`
+	StartedAt  time.Time `json:"started_at" db:"started_at"`
+	EndedAt    time.Time `json:"ended_at" db:"ended_at"`
+	Distance   float64   `json:"distance" db:"distance"`
+	Duration   int       `json:"duration" db:"duration"`
+	MeanSpeed  float64   `json:"mean_speed" db:"mean_speed"`
+	PointCount int       `json:"point_count" db:"point_count"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Events partner integrations may subscribe to
+const (
+	WebhookPetCreated       = "pet.created"
+	WebhookActivityRecorded = "activity.recorded"
+	WebhookVaccineAdded     = "vaccine.added"
+	WebhookReportCreated    = "report.created"
+)
+
+var WebhookEvents = []string{
+	WebhookPetCreated,
+	WebhookActivityRecorded,
+	WebhookVaccineAdded,
+	WebhookReportCreated,
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// webhookSecretMinLength keeps user provided secrets from being guessable
+const webhookSecretMinLength = 16
+
+func IsWebhookEvent(event string) bool {
+	return contains(WebhookEvents, event)
+}
+
+/*
+WebhookSubscription is the partner URL receiving the events of the subscriber pets.
+
+The subscriber receives events of the pets they own and the pets they are a veterinarian of.
+Requests are signed with the Secret, it is shown only when the subscription is created.
+*/
+type WebhookSubscription struct {
+	SubscriptionID int            `json:"subscription_id" db:"subscription_id"`
+	UserID         int            `json:"user_id" db:"user_id"`
+	URL            string         `json:"url" db:"url"`
+	Secret         string         `json:"-" db:"secret"`
+	Events         pq.StringArray `json:"events" db:"events"`
+	IsActive       bool           `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+}
+
+func (s *WebhookSubscription) Validate() error {
+	return validation.ValidateStruct(
+		s,
+		validation.Field(&s.URL, validation.Required, validation.Length(1, 2048), is.URL, validation.By(isPublicURL)),
+		validation.Field(&s.Secret, validation.Required, validation.Length(webhookSecretMinLength, 255)),
+		validation.Field(&s.Events, validation.Required, validation.Each(validation.In(
+			WebhookPetCreated, WebhookActivityRecorded, WebhookVaccineAdded, WebhookReportCreated,
+		))),
+	)
+}
+
+// isPublicURL checks the URL the server sends the requests to is https and does not point to the internal network
+func isPublicURL(value interface{}) error {
+	raw, _ := value.(string)
+	if raw == "" {
+		return nil
+	}
+	if err := netguard.ValidateURL(raw); err != nil {
+		if errors.Is(err, netguard.ErrInsecureURL) {
+			return errors.New("must be https")
+		}
+		return errors.New("must be the public address")
+	}
+	return nil
+}
+
+// Subscribed reports whether the subscription receives the event
+func (s *WebhookSubscription) Subscribed(event string) bool {
+	return s.IsActive && contains(s.Events, event)
+}
+
+// WebhookDelivery is the log entry of the event sent to the subscription.
+// Redelivery creates the new entry with the same payload referencing the original one.
+type WebhookDelivery struct {
+	DeliveryID     int             `json:"delivery_id" db:"delivery_id"`
+	SubscriptionID int             `json:"subscription_id" db:"subscription_id"`
+	Event          string          `json:"event" db:"event"`
+	Payload        []byte          `json:"-" db:"payload"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	ResponseStatus *sql.NullInt64  `json:"-" db:"response_status"`
+	LastError      *sql.NullString `json:"-" db:"last_error"`
+	RedeliveryOf   *sql.NullInt64  `json:"-" db:"redelivery_of"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *sql.NullTime   `json:"-" db:"delivered_at"`
+
+	SpecifiedPayload        json.RawMessage `json:"payload"`
+	SpecifiedResponseStatus int             `json:"response_status,omitempty"`
+	SpecifiedLastError      string          `json:"last_error,omitempty"`
+	SpecifiedRedeliveryOf   int             `json:"redelivery_of,omitempty"`
+	SpecifiedDeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// OriginalID is the ID of the first delivery of the payload, all its redeliveries share it
+func (d *WebhookDelivery) OriginalID() int {
+	if d.RedeliveryOf != nil && d.RedeliveryOf.Valid {
+		return int(d.RedeliveryOf.Int64)
+	}
+	return d.DeliveryID
+}
+
+func (d *WebhookDelivery) AfterCreate() {
+	if len(d.Payload) != 0 {
+		d.SpecifiedPayload = append(json.RawMessage(nil), d.Payload...)
+	}
+	if d.ResponseStatus != nil && d.ResponseStatus.Valid {
+		d.SpecifiedResponseStatus = int(d.ResponseStatus.Int64)
+	}
+	d.SpecifiedLastError = fromNullString(d.LastError)
+	if d.RedeliveryOf != nil && d.RedeliveryOf.Valid {
+		d.SpecifiedRedeliveryOf = int(d.RedeliveryOf.Int64)
+	}
+	if d.DeliveredAt != nil && d.DeliveredAt.Valid {
+		deliveredAt := d.DeliveredAt.Time
+		d.SpecifiedDeliveredAt = &deliveredAt
+	}
+}
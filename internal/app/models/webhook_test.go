@@ -0,0 +1,26 @@
+package models_test
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWebhookSubscription_Validate(t *testing.T) {
+	subscription := &models.WebhookSubscription{
+		URL:    "https://insurer.example/hooks/storypet",
+		Secret: "partner-shared-secret",
+		Events: []string{models.WebhookPetCreated, models.WebhookReportCreated},
+	}
+	assert.NoError(t, subscription.Validate())
+
+	subscription.Events = append(subscription.Events, "pet.deleted")
+	assert.Error(t, subscription.Validate())
+
+	subscription.Events = nil
+	assert.Error(t, subscription.Validate())
+
+	subscription.Events = []string{models.WebhookVaccineAdded}
+	subscription.Secret = "short"
+	assert.Error(t, subscription.Validate())
+}
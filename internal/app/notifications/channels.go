@@ -0,0 +1,154 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webhook"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webpush"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrEndpointGone is returned by channels when the endpoint does not accept notifications anymore
+var ErrEndpointGone = errors.New("notifications: endpoint is gone")
+
+// Channel delivers the notification outside of the application.
+// Endpoint is nil for channels addressing the user directly, like email.
+type Channel interface {
+	Send(notification *models.Notification, recipient *models.User, endpoint *models.NotificationEndpoint) error
+}
+
+// payload is the notification representation sent by web push and webhooks
+type payload struct {
+	NotificationID int             `json:"notification_id"`
+	Event          string          `json:"event"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Data           json.RawMessage `json:"data,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+func newPayload(notification *models.Notification) ([]byte, error) {
+	return json.Marshal(payload{
+		NotificationID: notification.NotificationID,
+		Event:          notification.Event,
+		Title:          notification.Title,
+		Body:           notification.Body,
+		Data:           notification.SpecifiedData,
+		CreatedAt:      notification.CreatedAt,
+	})
+}
+
+// Mailer sends the RFC 5322 message
+type Mailer interface {
+	SendMail(from string, to []string, message []byte) error
+}
+
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) SendMail(from string, to []string, message []byte) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, from, to, message)
+}
+
+type EmailChannel struct {
+	Mailer Mailer
+	From   string
+}
+
+func (c *EmailChannel) Send(notification *models.Notification, recipient *models.User, _ *models.NotificationEndpoint) error {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", c.From)
+	fmt.Fprintf(&message, "To: %s\r\n", recipient.AccountEmail)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Title))
+	fmt.Fprintf(&message, "Date: %s\r\n", notification.CreatedAt.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("\r\n")
+	message.WriteString(strings.ReplaceAll(notification.Body, "\n", "\r\n"))
+	message.WriteString("\r\n")
+	return c.Mailer.SendMail(c.From, []string{recipient.AccountEmail}, message.Bytes())
+}
+
+// Pusher sends the payload to the web push subscription
+type Pusher interface {
+	Send(subscription *webpush.Subscription, payload []byte) error
+}
+
+type WebPushChannel struct {
+	Pusher Pusher
+}
+
+func (c *WebPushChannel) Send(notification *models.Notification, _ *models.User, endpoint *models.NotificationEndpoint) error {
+	body, err := newPayload(notification)
+	if err != nil {
+		return err
+	}
+	err = c.Pusher.Send(&webpush.Subscription{
+		Endpoint: endpoint.Address,
+		P256DH:   endpoint.SpecifiedPublicKey,
+		Auth:     endpoint.Secret,
+	}, body)
+	if errors.Is(err, webpush.ErrSubscriptionGone) {
+		return ErrEndpointGone
+	}
+	return err
+}
+
+// WebhookChannel posts notifications to the user URL signed with the endpoint secret.
+// The default client posts them to the public https addresses only.
+type WebhookChannel struct {
+	Client *http.Client
+}
+
+func (c *WebhookChannel) Send(notification *models.Notification, _ *models.User, endpoint *models.NotificationEndpoint) error {
+	body, err := newPayload(notification)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, endpoint.Address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	webhook.SetHeaders(request.Header, endpoint.Secret, notification.Event, body, time.Now())
+
+	client := c.Client
+	if client == nil {
+		client = netguard.NewClient(10 * time.Second)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	switch {
+	case response.StatusCode == http.StatusGone:
+		return ErrEndpointGone
+	case response.StatusCode < 200 || response.StatusCode >= 300:
+		return fmt.Errorf("notifications: webhook responded %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package notifications
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/notifications/notificationstest"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testNotification() *models.Notification {
+	notification := &models.Notification{
+		NotificationID: 7,
+		UserID:         3,
+		Event:          models.NotificationHealthReport,
+		Title:          "New health report for Рекс",
+		Body:           "Dr. House has written a health report for Рекс:\nhealthy",
+		Data:           []byte(`{"pet_id":5}`),
+		CreatedAt:      time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+	notification.AfterCreate()
+	return notification
+}
+
+func TestEmailChannel_Send(t *testing.T) {
+	mailer := &notificationstest.FakeMailer{}
+	channel := &EmailChannel{Mailer: mailer, From: "noreply@storypet.test"}
+	recipient := &models.User{AccountEmail: "owner@storypet.test"}
+
+	require.NoError(t, channel.Send(testNotification(), recipient, nil))
+	require.Len(t, mailer.Sent, 1)
+	assert.Equal(t, []string{"owner@storypet.test"}, mailer.Sent[0].To)
+	message := string(mailer.Sent[0].Message)
+	assert.Contains(t, message, "To: owner@storypet.test\r\n")
+	assert.Contains(t, message, "Subject: =?utf-8?q?")
+	assert.True(t, strings.HasSuffix(message, "\r\n\r\nDr. House has written a health report for Рекс:\r\nhealthy\r\n"))
+}
+
+func TestWebPushChannel_Send(t *testing.T) {
+	pusher := &notificationstest.FakePusher{Gone: map[string]bool{"https://push.test/gone": true}}
+	channel := &WebPushChannel{Pusher: pusher}
+	endpoint := &models.NotificationEndpoint{
+		Channel:   models.ChannelWebPush,
+		Address:   "https://push.test/1",
+		PublicKey: &sql.NullString{String: "p256dh", Valid: true},
+		Secret:    "auth",
+	}
+	endpoint.AfterCreate()
+
+	require.NoError(t, channel.Send(testNotification(), nil, endpoint))
+	require.Len(t, pusher.Sent, 1)
+	assert.Equal(t, "p256dh", pusher.Sent[0].Subscription.P256DH)
+	received := &payload{}
+	require.NoError(t, json.Unmarshal(pusher.Sent[0].Payload, received))
+	assert.Equal(t, 7, received.NotificationID)
+	assert.JSONEq(t, `{"pet_id":5}`, string(received.Data))
+
+	endpoint.Address = "https://push.test/gone"
+	assert.ErrorIs(t, channel.Send(testNotification(), nil, endpoint), ErrEndpointGone)
+}
+
+func TestWebhookChannel_Send(t *testing.T) {
+	receiver := notificationstest.NewWebhookReceiver("endpoint-secret")
+	defer receiver.Close()
+	channel := &WebhookChannel{Client: receiver.Client()}
+	endpoint := &models.NotificationEndpoint{
+		Channel: models.ChannelWebhook,
+		Address: receiver.URL,
+		Secret:  "endpoint-secret",
+	}
+
+	require.NoError(t, channel.Send(testNotification(), nil, endpoint))
+	requests := receiver.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, models.NotificationHealthReport, requests[0].Event)
+	assert.True(t, requests[0].ValidSignature)
+
+	receiver.StatusCode = http.StatusInternalServerError
+	assert.Error(t, channel.Send(testNotification(), nil, endpoint))
+
+	receiver.StatusCode = http.StatusGone
+	assert.ErrorIs(t, channel.Send(testNotification(), nil, endpoint), ErrEndpointGone)
+
+	// The default client refuses the endpoints which are not public https ones
+	assert.ErrorIs(t, (&WebhookChannel{}).Send(testNotification(), nil, endpoint), netguard.ErrInsecureURL)
+	endpoint.Address = "https://127.0.0.1:1/hooks"
+	assert.ErrorIs(t, (&WebhookChannel{}).Send(testNotification(), nil, endpoint), netguard.ErrForbiddenAddress)
+	assert.Len(t, receiver.Requests(), 3)
+}
+
+func TestTemplates_Render(t *testing.T) {
+	templates := NewTemplates()
+	title, body, err := templates.Render(models.NotificationVaccinationDue, Data{
+		"pet_name":     "Rex",
+		"vaccinations": []string{"Rabies dose 1 is due on 2021-06-01"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Vaccinations due for Rex", title)
+	assert.Equal(t, "Rex needs the following vaccinations:\n- Rabies dose 1 is due on 2021-06-01", body)
+
+	for _, event := range models.NotificationEvents {
+		_, ok := templates.byEvent[event]
+		assert.True(t, ok, event)
+	}
+
+	_, _, err = templates.Render(models.NotificationHealthReport, Data{"pet_name": "Rex"})
+	assert.Error(t, err)
+	_, _, err = templates.Render("unknown", Data{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,15 @@
+package configs
+
+import "os"
+
+var (
+	// SMTPAddr is host:port of the mail server, email notifications are disabled when it is empty
+	SMTPAddr     = os.Getenv("SMTP_ADDR")
+	SMTPUsername = os.Getenv("SMTP_USERNAME")
+	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	EmailFrom    = os.Getenv("NOTIFICATIONS_EMAIL_FROM")
+
+	// VAPIDPrivateKey is base64url P-256 private key, web push notifications are disabled when it is empty
+	VAPIDPrivateKey = os.Getenv("VAPID_PRIVATE_KEY")
+	VAPIDSubject    = os.Getenv("VAPID_SUBJECT")
+)
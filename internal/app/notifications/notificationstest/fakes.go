@@ -0,0 +1,100 @@
+// Package notificationstest provides local fakes of the notification channels transports for tests
+package notificationstest
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webhook"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webpush"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+type Mail struct {
+	From    string
+	To      []string
+	Message []byte
+}
+
+// FakeMailer records sent emails instead of connecting to the mail server
+type FakeMailer struct {
+	mu   sync.Mutex
+	Sent []Mail
+	Err  error
+}
+
+func (m *FakeMailer) SendMail(from string, to []string, message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.Sent = append(m.Sent, Mail{From: from, To: to, Message: message})
+	return nil
+}
+
+type Push struct {
+	Subscription webpush.Subscription
+	Payload      []byte
+}
+
+// FakePusher records web push messages instead of sending them to push services.
+// Subscriptions with endpoints listed in Gone are reported expired.
+type FakePusher struct {
+	mu   sync.Mutex
+	Sent []Push
+	Gone map[string]bool
+}
+
+func (p *FakePusher) Send(subscription *webpush.Subscription, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Gone[subscription.Endpoint] {
+		return webpush.ErrSubscriptionGone
+	}
+	p.Sent = append(p.Sent, Push{Subscription: *subscription, Payload: payload})
+	return nil
+}
+
+type WebhookRequest struct {
+	Event          string
+	Body           []byte
+	ValidSignature bool
+}
+
+// WebhookReceiver is the local HTTP server recording received webhooks and checking their signatures
+type WebhookReceiver struct {
+	*httptest.Server
+	Secret string
+	// StatusCode is the response status, 200 by default
+	StatusCode int
+
+	mu       sync.Mutex
+	requests []WebhookRequest
+}
+
+func NewWebhookReceiver(secret string) *WebhookReceiver {
+	receiver := &WebhookReceiver{Secret: secret, StatusCode: http.StatusOK}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(receiver.serve))
+	return receiver
+}
+
+func (r *WebhookReceiver) serve(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.mu.Lock()
+	r.requests = append(r.requests, WebhookRequest{
+		Event:          req.Header.Get(webhook.EventHeader),
+		Body:           body,
+		ValidSignature: webhook.Verify(req.Header, r.Secret, body, 5*time.Minute, time.Now()),
+	})
+	statusCode := r.StatusCode
+	r.mu.Unlock()
+	w.WriteHeader(statusCode)
+}
+
+func (r *WebhookReceiver) Requests() []WebhookRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]WebhookRequest(nil), r.requests...)
+}
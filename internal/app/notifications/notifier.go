@@ -0,0 +1,238 @@
+// Package notifications stores notifications in users' inboxes and delivers them by the pluggable channels
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/jobs"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"log"
+	"time"
+)
+
+// KindDeliver is the kind of the job delivering the notification by the channel
+const KindDeliver = "notifications.deliver"
+
+type enqueuer interface {
+	Enqueue(kind string, payload interface{}, runAt time.Time) (*models.Job, error)
+}
+
+// broadcaster pushes events to the user streams
+type broadcaster interface {
+	PublishUser(userID int, eventType string, data interface{}) error
+}
+
+type deliveryPayload struct {
+	NotificationID int    `json:"notification_id"`
+	Channel        string `json:"channel"`
+	EndpointID     int    `json:"endpoint_id,omitempty"`
+}
+
+type Notifier struct {
+	database  store.DatabaseStore
+	queue     enqueuer
+	streams   broadcaster
+	logger    *log.Logger
+	templates *Templates
+	channels  map[string]Channel
+}
+
+// NewNotifier creates the notifier, streams may be nil if notifications are not pushed to the user streams
+func NewNotifier(database store.DatabaseStore, queue enqueuer, streams broadcaster, logger *log.Logger) *Notifier {
+	return &Notifier{
+		database:  database,
+		queue:     queue,
+		streams:   streams,
+		logger:    logger,
+		templates: NewTemplates(),
+		channels:  make(map[string]Channel),
+	}
+}
+
+// Register enables delivery by the channel, notifications are only stored in the inbox otherwise
+func (n *Notifier) Register(channelName string, channel Channel) {
+	n.channels[channelName] = channel
+}
+
+// Notify stores the event notification in the user inbox and schedules its delivery
+// by the channels the user has not disabled for the event
+func (n *Notifier) Notify(userID int, event string, data Data) (*models.Notification, error) {
+	title, body, err := n.templates.Render(event, data)
+	if err != nil {
+		return nil, err
+	}
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	notification, err := n.database.Notifications().Create(&models.Notification{
+		UserID: userID,
+		Event:  event,
+		Title:  title,
+		Body:   body,
+		Data:   rawData,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n.streams != nil {
+		// The inbox is the source of truth, the stream is only a hint for connected clients
+		if err := n.streams.PublishUser(userID, realtime.EventNotificationCreated, notification); err != nil {
+			n.logger.Printf("Notification stream error: %v", err)
+		}
+	}
+
+	preferences, err := n.database.Notifications().SelectPreferences(userID)
+	if err != nil {
+		return notification, err
+	}
+	enabled := func(channel string) bool {
+		if _, ok := n.channels[channel]; !ok {
+			return false
+		}
+		for _, preference := range preferences {
+			if preference.Event == event && preference.Channel == channel {
+				return preference.Enabled
+			}
+		}
+		return true
+	}
+
+	var deliveries []deliveryPayload
+	if enabled(models.ChannelEmail) {
+		deliveries = append(deliveries, deliveryPayload{NotificationID: notification.NotificationID, Channel: models.ChannelEmail})
+	}
+	if enabled(models.ChannelWebPush) || enabled(models.ChannelWebhook) {
+		endpoints, err := n.database.Notifications().SelectEndpoints(userID)
+		if err != nil {
+			return notification, err
+		}
+		for _, endpoint := range endpoints {
+			if enabled(endpoint.Channel) {
+				deliveries = append(deliveries, deliveryPayload{
+					NotificationID: notification.NotificationID,
+					Channel:        endpoint.Channel,
+					EndpointID:     endpoint.EndpointID,
+				})
+			}
+		}
+	}
+	for _, delivery := range deliveries {
+		if _, err := n.queue.Enqueue(KindDeliver, delivery, jobs.Now()); err != nil {
+			return notification, err
+		}
+	}
+	return notification, nil
+}
+
+// Deliver is the handler of the delivery jobs
+func (n *Notifier) Deliver(ctx context.Context, job *models.Job) error {
+	delivery := &deliveryPayload{}
+	if err := json.Unmarshal(job.Payload, delivery); err != nil {
+		return err
+	}
+	channel, ok := n.channels[delivery.Channel]
+	if !ok {
+		return fmt.Errorf("notifications: channel %q is not configured", delivery.Channel)
+	}
+
+	// The notification or endpoint may be deleted by the user before delivery, there is nothing to deliver then
+	notification, err := n.database.Notifications().FindByID(delivery.NotificationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	recipient, err := n.database.Users().FindByID(notification.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	var endpoint *models.NotificationEndpoint
+	if delivery.EndpointID != 0 {
+		endpoint, err = n.database.Notifications().FindEndpointByID(delivery.EndpointID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	err = channel.Send(notification, recipient, endpoint)
+	if errors.Is(err, ErrEndpointGone) {
+		n.logger.Printf("Notification endpoint %d is gone, deleting it", endpoint.EndpointID)
+		_, err = n.database.Notifications().DeleteEndpoint(endpoint.UserID, endpoint.EndpointID)
+	}
+	return err
+}
+
+// NotifyVaccinationsDue reminds the pet owner about vaccinations, it is used by the reminders job
+func (n *Notifier) NotifyVaccinationsDue(pet *models.Pet, shots []models.ScheduledVaccination) error {
+	vaccinations := make([]string, 0, len(shots))
+	for _, shot := range shots {
+		vaccinations = append(vaccinations, fmt.Sprintf(
+			"%s dose %d is %s on %s", shot.Name, shot.DoseNumber, shot.Status, shot.Date.Format("2006-01-02"),
+		))
+	}
+	_, err := n.Notify(pet.UserID, models.NotificationVaccinationDue, Data{
+		"pet_id":       pet.PetID,
+		"pet_name":     pet.Name,
+		"vaccinations": vaccinations,
+	})
+	return err
+}
+
+// NotifyGeofenceEvent alerts the pet owner about the pet crossing the zone boundary, it is used by the geofence monitor
+func (n *Notifier) NotifyGeofenceEvent(pet *models.Pet, fence *models.Geofence, event *models.GeofenceEvent) error {
+	notificationEvent := models.NotificationGeofenceExit
+	if event.Kind == models.GeofenceEnter {
+		notificationEvent = models.NotificationGeofenceEnter
+	}
+	_, err := n.Notify(pet.UserID, notificationEvent, Data{
+		"pet_id":        pet.PetID,
+		"pet_name":      pet.Name,
+		"geofence_id":   fence.GeofenceID,
+		"geofence_name": fence.Name,
+		"event_id":      event.EventID,
+		"occurred_at":   event.OccurredAt.UTC().Format(time.RFC3339),
+		"latitude":      event.Latitude,
+		"longitude":     event.Longitude,
+	})
+	return err
+}
+
+// NotifyDosesDue reminds the pet owner to give the doses of the medication, it is used by the medication reminders job
+func (n *Notifier) NotifyDosesDue(pet *models.Pet, medication *models.Medication, doses []time.Time) error {
+	return n.notifyDoses(pet, medication, models.NotificationDoseDue, doses)
+}
+
+// NotifyDosesMissed alerts the pet owner about the doses of the medication which have not been given
+func (n *Notifier) NotifyDosesMissed(pet *models.Pet, medication *models.Medication, doses []time.Time) error {
+	return n.notifyDoses(pet, medication, models.NotificationDoseMissed, doses)
+}
+
+func (n *Notifier) notifyDoses(pet *models.Pet, medication *models.Medication, event string, doses []time.Time) error {
+	scheduled := make([]string, 0, len(doses))
+	for _, dose := range doses {
+		scheduled = append(scheduled, dose.UTC().Format(time.RFC3339))
+	}
+	_, err := n.Notify(pet.UserID, event, Data{
+		"pet_id":        pet.PetID,
+		"pet_name":      pet.Name,
+		"medication_id": medication.MedicationID,
+		"drug":          medication.Drug,
+		"dose":          medication.Dose,
+		"dose_unit":     medication.DoseUnit,
+		"doses":         scheduled,
+	})
+	return err
+}
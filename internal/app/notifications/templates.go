@@ -0,0 +1,86 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"text/template"
+)
+
+// Data is the notification template data, it is stored with the notification for clients as well
+type Data map[string]interface{}
+
+type messageTemplate struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// Templates render titles and bodies of notifications by their events
+type Templates struct {
+	byEvent map[string]messageTemplate
+}
+
+var defaultTemplates = map[string][2]string{
+	models.NotificationHealthReport: {
+		`New health report for {{.pet_name}}`,
+		`{{.veterinarian_name}} has written a health report for {{.pet_name}}: {{.conclusion}}`,
+	},
+	models.NotificationParentVerified: {
+		`{{.pet_name}}'s {{.parent}} is verified`,
+		`The owner of {{.parent_name}} has confirmed {{.parent_name}} is the {{.parent}} of {{.pet_name}}.`,
+	},
+	models.NotificationVeterinarianAssigned: {
+		`Veterinarian assigned to {{.pet_name}}`,
+		`{{.veterinarian_name}} is now the veterinarian of {{.pet_name}}.`,
+	},
+	models.NotificationVaccinationDue: {
+		`Vaccinations due for {{.pet_name}}`,
+		`{{.pet_name}} needs the following vaccinations:{{range .vaccinations}}
+- {{.}}{{end}}`,
+	},
+	models.NotificationGeofenceEnter: {
+		`{{.pet_name}} is in {{.geofence_name}}`,
+		`{{.pet_name}} has entered {{.geofence_name}} at {{.occurred_at}}.`,
+	},
+	models.NotificationGeofenceExit: {
+		`{{.pet_name}} has left {{.geofence_name}}`,
+		`{{.pet_name}} has left {{.geofence_name}} at {{.occurred_at}}, the last known position is {{.latitude}}, {{.longitude}}.`,
+	},
+	models.NotificationDoseDue: {
+		`{{.drug}} for {{.pet_name}}`,
+		`{{.pet_name}} needs {{.dose}} {{.dose_unit}} of {{.drug}} at:{{range .doses}}
+- {{.}}{{end}}`,
+	},
+	models.NotificationDoseMissed: {
+		`Missed {{.drug}} for {{.pet_name}}`,
+		`The following doses of {{.drug}} have not been given to {{.pet_name}}:{{range .doses}}
+- {{.}}{{end}}`,
+	},
+}
+
+func NewTemplates() *Templates {
+	t := &Templates{byEvent: make(map[string]messageTemplate)}
+	for event, texts := range defaultTemplates {
+		t.byEvent[event] = messageTemplate{
+			title: template.Must(template.New(event + ".title").Option("missingkey=error").Parse(texts[0])),
+			body:  template.Must(template.New(event + ".body").Option("missingkey=error").Parse(texts[1])),
+		}
+	}
+	return t
+}
+
+// Render returns the title and the body of the event notification
+func (t *Templates) Render(event string, data Data) (string, string, error) {
+	tmpl, ok := t.byEvent[event]
+	if !ok {
+		return "", "", fmt.Errorf("notifications: no template for event %q", event)
+	}
+	var title, body bytes.Buffer
+	if err := tmpl.title.Execute(&title, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return title.String(), body.String(), nil
+}
@@ -0,0 +1,76 @@
+package nutrition
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
+)
+
+// nutrientMinimums are grams per 1000 kcal of the diet, after the AAFCO nutrient profiles
+type nutrientMinimums struct {
+	protein    float64
+	fat        float64
+	calcium    float64
+	phosphorus float64
+}
+
+// nutrientGuidelines of the species, growth ones also hold for pregnancy and lactation
+var nutrientGuidelines = map[string]struct {
+	adult  nutrientMinimums
+	growth nutrientMinimums
+}{
+	models.SpeciesDog: {
+		adult:  nutrientMinimums{protein: 45.0, fat: 13.8, calcium: 1.25, phosphorus: 1.0},
+		growth: nutrientMinimums{protein: 56.3, fat: 21.3, calcium: 3.0, phosphorus: 2.5},
+	},
+	models.SpeciesCat: {
+		adult:  nutrientMinimums{protein: 65.0, fat: 22.5, calcium: 1.5, phosphorus: 1.25},
+		growth: nutrientMinimums{protein: 75.0, fat: 22.5, calcium: 2.5, phosphorus: 2.0},
+	},
+}
+
+// LifeStage is the stage of the pet of the profile on the day
+func LifeStage(profile Profile, day time.Time) string {
+	species, ok := guidelines[profile.Species]
+	return lifeStage(species, ok, profile.BirthDate, day)
+}
+
+/*
+AssessNutrients compares the nutrients eaten on the day with the minimums of the species guideline.
+
+The minimums scale with energy, the energy requirement when it is known and the energy eaten otherwise.
+Species without guidelines get no assessment.
+*/
+func AssessNutrients(profile Profile, day time.Time, totals models.NutrientTotals, energy float64) []models.NutrientIntake {
+	assessment := make([]models.NutrientIntake, 0)
+	guideline, ok := nutrientGuidelines[profile.Species]
+	if !ok {
+		return assessment
+	}
+
+	minimums := guideline.adult
+	stage := LifeStage(profile, day)
+	if stage == StageGrowthEarly || stage == StageGrowth ||
+		profile.ReproductiveStatus == models.ReproductionPregnant || profile.ReproductiveStatus == models.ReproductionLactating {
+		minimums = guideline.growth
+	}
+
+	for _, nutrient := range []struct {
+		name    string
+		total   float64
+		minimum float64
+	}{
+		{models.NutrientProtein, totals.Protein, minimums.protein},
+		{models.NutrientFat, totals.Fat, minimums.fat},
+		{models.NutrientCalcium, totals.Calcium, minimums.calcium},
+		{models.NutrientPhosphorus, totals.Phosphorus, minimums.phosphorus},
+	} {
+		minimum := nutrient.minimum * energy / 1000
+		assessment = append(assessment, models.NutrientIntake{
+			Nutrient:     nutrient.name,
+			Total:        nutrient.total,
+			Minimum:      minimum,
+			IsSufficient: nutrient.total >= minimum,
+		})
+	}
+	return assessment
+}
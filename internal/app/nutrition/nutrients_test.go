@@ -0,0 +1,37 @@
+package nutrition
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAssessNutrients(t *testing.T) {
+	totals := models.NutrientTotals{Protein: 40, Fat: 20, Calcium: 1.5, Phosphorus: 0.5}
+	assessment := AssessNutrients(Profile{Species: models.SpeciesDog, BirthDate: born(3, 0)}, day, totals, 800)
+	require.Len(t, assessment, 4)
+
+	assert.Equal(t, models.NutrientProtein, assessment[0].Nutrient)
+	assert.InDelta(t, 36, assessment[0].Minimum, 0.001)
+	assert.True(t, assessment[0].IsSufficient)
+	assert.Equal(t, models.NutrientPhosphorus, assessment[3].Nutrient)
+	assert.InDelta(t, 0.8, assessment[3].Minimum, 0.001)
+	assert.False(t, assessment[3].IsSufficient)
+}
+
+func TestAssessNutrients_Growth(t *testing.T) {
+	totals := models.NutrientTotals{Protein: 40}
+	puppy := AssessNutrients(Profile{Species: models.SpeciesDog, BirthDate: born(0, 6)}, day, totals, 1000)
+	assert.InDelta(t, 56.3, puppy[0].Minimum, 0.001)
+	assert.False(t, puppy[0].IsSufficient)
+
+	lactating := AssessNutrients(Profile{Species: models.SpeciesCat, ReproductiveStatus: models.ReproductionLactating}, day, totals, 1000)
+	assert.InDelta(t, 75, lactating[0].Minimum, 0.001)
+}
+
+func TestAssessNutrients_NoGuideline(t *testing.T) {
+	assessment := AssessNutrients(Profile{Species: models.SpeciesOther}, day, models.NutrientTotals{Protein: 10}, 500)
+	assert.NotNil(t, assessment)
+	assert.Empty(t, assessment)
+}
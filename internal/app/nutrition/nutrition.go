@@ -0,0 +1,205 @@
+/*
+Package nutrition computes the daily energy requirement of the pet.
+
+RER (resting energy requirement) is 70 * weight^0.75 kcal multiplied by the RER coefficient of the pet type,
+MER (maintenance energy requirement) is RER multiplied by the factor of the pet life stage, neuter status,
+activity level, feeding goal and reproductive status, as feeding guidelines for dogs and cats suggest.
+*/
+package nutrition
+
+import (
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"math"
+	"time"
+)
+
+// Life stages
+const (
+	StageGrowthEarly = "growth_early"
+	StageGrowth      = "growth"
+	StageAdult       = "adult"
+	StageSenior      = "senior"
+)
+
+var ErrUnknownWeight = errors.New("nutrition: weight of the pet is unknown")
+
+// guideline is the set of factors of the species
+type guideline struct {
+	// growthEarlyMonths and growthMonths end the growth stages, seniorYears starts the senior one
+	growthEarlyMonths int
+	growthMonths      int
+	seniorYears       int
+
+	growthEarly float64
+	growth      float64
+	neutered    float64
+	intact      float64
+	senior      float64
+	loseWeight  float64
+	gainWeight  float64
+	pregnant    float64
+	lactating   float64
+}
+
+var guidelines = map[string]guideline{
+	models.SpeciesDog: {
+		growthEarlyMonths: 4, growthMonths: 12, seniorYears: 7,
+		growthEarly: 3.0, growth: 2.0, neutered: 1.6, intact: 1.8, senior: 1.4,
+		loseWeight: 1.0, gainWeight: 1.7, pregnant: 3.0, lactating: 4.0,
+	},
+	models.SpeciesCat: {
+		growthEarlyMonths: 4, growthMonths: 12, seniorYears: 11,
+		growthEarly: 3.0, growth: 2.5, neutered: 1.2, intact: 1.4, senior: 1.1,
+		loseWeight: 0.8, gainWeight: 1.8, pregnant: 2.0, lactating: 2.5,
+	},
+}
+
+// activityFactors adjust the maintenance factor of adult pets
+var activityFactors = map[string]float64{
+	models.ActivityLow:      0.85,
+	models.ActivityModerate: 1.0,
+	models.ActivityHigh:     1.25,
+}
+
+// Profile is what the energy requirement depends on, the zero values are the defaults
+type Profile struct {
+	Species            string
+	RERCoefficient     float64
+	Weight             float64
+	BirthDate          *time.Time
+	Sex                string
+	IsNeutered         bool
+	ActivityLevel      string
+	Goal               string
+	ReproductiveStatus string
+}
+
+// ProfileOf is the profile of the pet of the type weighing weight kilograms
+func ProfileOf(pet *models.Pet, petType *models.PetType, weight float64) Profile {
+	profile := Profile{
+		Species:            petType.Species,
+		RERCoefficient:     petType.RERCoefficient,
+		Weight:             weight,
+		Sex:                pet.SpecifiedSex,
+		IsNeutered:         pet.IsNeutered,
+		ActivityLevel:      pet.SpecifiedActivityLevel,
+		Goal:               pet.SpecifiedGoal,
+		ReproductiveStatus: pet.SpecifiedReproductiveStatus,
+	}
+	if pet.BirthDate != nil && pet.BirthDate.Valid {
+		birthDate := pet.BirthDate.Time
+		profile.BirthDate = &birthDate
+	}
+	return profile
+}
+
+// Factor is the multiplier applied and why
+type Factor struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// Requirement is the daily energy requirement in kcal
+type Requirement struct {
+	Weight    float64  `json:"weight"`
+	LifeStage string   `json:"life_stage"`
+	RER       float64  `json:"rer"`
+	MER       float64  `json:"mer"`
+	Factor    float64  `json:"factor"`
+	Factors   []Factor `json:"factors"`
+}
+
+/*
+Compute returns the energy requirement of the profile on the day.
+
+Growth, pregnancy and lactation factors replace the maintenance one, so neither the goal nor the activity level
+changes them. For species without guidelines MER is RER adjusted by the activity level.
+*/
+func Compute(profile Profile, day time.Time) (*Requirement, error) {
+	if profile.Weight <= 0 || math.IsNaN(profile.Weight) {
+		return nil, ErrUnknownWeight
+	}
+	coefficient := profile.RERCoefficient
+	if coefficient <= 0 {
+		coefficient = 1
+	}
+	requirement := &Requirement{
+		Weight:  profile.Weight,
+		RER:     70 * coefficient * math.Pow(profile.Weight, 0.75),
+		Factors: make([]Factor, 0),
+	}
+	apply := func(name string, value float64) {
+		requirement.Factors = append(requirement.Factors, Factor{Name: name, Value: value})
+	}
+
+	species, ok := guidelines[profile.Species]
+	requirement.LifeStage = lifeStage(species, ok, profile.BirthDate, day)
+	switch {
+	case !ok:
+		apply("activity_"+activityLevel(profile), activityFactors[activityLevel(profile)])
+	case requirement.LifeStage == StageGrowthEarly:
+		apply(StageGrowthEarly, species.growthEarly)
+	case requirement.LifeStage == StageGrowth:
+		apply(StageGrowth, species.growth)
+	case profile.ReproductiveStatus == models.ReproductionPregnant:
+		apply(models.ReproductionPregnant, species.pregnant)
+	case profile.ReproductiveStatus == models.ReproductionLactating:
+		apply(models.ReproductionLactating, species.lactating)
+	case profile.Goal == models.GoalLoseWeight:
+		apply(models.GoalLoseWeight, species.loseWeight)
+	case profile.Goal == models.GoalGainWeight:
+		apply(models.GoalGainWeight, species.gainWeight)
+	default:
+		switch {
+		case requirement.LifeStage == StageSenior:
+			apply(StageSenior, species.senior)
+		case profile.IsNeutered:
+			apply("neutered", species.neutered)
+		default:
+			apply("intact", species.intact)
+		}
+		if level := activityLevel(profile); level != models.ActivityModerate {
+			apply("activity_"+level, activityFactors[level])
+		}
+	}
+
+	requirement.Factor = 1
+	for _, factor := range requirement.Factors {
+		requirement.Factor *= factor.Value
+	}
+	requirement.MER = requirement.RER * requirement.Factor
+	return requirement, nil
+}
+
+func activityLevel(profile Profile) string {
+	if _, ok := activityFactors[profile.ActivityLevel]; ok {
+		return profile.ActivityLevel
+	}
+	return models.ActivityModerate
+}
+
+// lifeStage is the stage on the day, pets of unknown age and of species without guidelines are adults
+func lifeStage(species guideline, known bool, birthDate *time.Time, day time.Time) string {
+	if !known || birthDate == nil || day.Before(*birthDate) {
+		return StageAdult
+	}
+	months := monthsBetween(*birthDate, day)
+	switch {
+	case months < species.growthEarlyMonths:
+		return StageGrowthEarly
+	case months < species.growthMonths:
+		return StageGrowth
+	case months >= species.seniorYears*12:
+		return StageSenior
+	}
+	return StageAdult
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	return months
+}
@@ -0,0 +1,80 @@
+package nutrition
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+var day = time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+
+func born(years int, months int) *time.Time {
+	birthDate := day.AddDate(-years, -months, 0)
+	return &birthDate
+}
+
+func TestCompute_RER(t *testing.T) {
+	requirement, err := Compute(Profile{Species: models.SpeciesOther, RERCoefficient: 1, Weight: 16}, day)
+	require.NoError(t, err)
+	assert.InDelta(t, 560, requirement.RER, 0.001)
+	assert.InDelta(t, 560, requirement.MER, 0.001)
+	assert.Equal(t, StageAdult, requirement.LifeStage)
+
+	_, err = Compute(Profile{Species: models.SpeciesDog}, day)
+	assert.Equal(t, ErrUnknownWeight, err)
+}
+
+func TestCompute_Factors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		stage   string
+		factor  float64
+	}{
+		{"intact adult dog", Profile{Species: models.SpeciesDog, BirthDate: born(3, 0)}, StageAdult, 1.8},
+		{"neutered adult dog", Profile{Species: models.SpeciesDog, IsNeutered: true}, StageAdult, 1.6},
+		{"active neutered dog", Profile{Species: models.SpeciesDog, IsNeutered: true, ActivityLevel: models.ActivityHigh}, StageAdult, 2.0},
+		{"young puppy", Profile{Species: models.SpeciesDog, BirthDate: born(0, 2), Goal: models.GoalLoseWeight}, StageGrowthEarly, 3.0},
+		{"puppy", Profile{Species: models.SpeciesDog, BirthDate: born(0, 7)}, StageGrowth, 2.0},
+		{"senior dog", Profile{Species: models.SpeciesDog, BirthDate: born(9, 0), ActivityLevel: models.ActivityLow}, StageSenior, 1.4 * 0.85},
+		{"dog losing weight", Profile{Species: models.SpeciesDog, IsNeutered: true, Goal: models.GoalLoseWeight, ActivityLevel: models.ActivityHigh}, StageAdult, 1.0},
+		{"lactating dog", Profile{Species: models.SpeciesDog, Sex: models.SexFemale, ReproductiveStatus: models.ReproductionLactating}, StageAdult, 4.0},
+		{"neutered cat", Profile{Species: models.SpeciesCat, IsNeutered: true, BirthDate: born(5, 0)}, StageAdult, 1.2},
+		{"senior cat", Profile{Species: models.SpeciesCat, BirthDate: born(12, 0)}, StageSenior, 1.1},
+		{"kitten", Profile{Species: models.SpeciesCat, BirthDate: born(0, 6)}, StageGrowth, 2.5},
+		{"active rabbit", Profile{Species: models.SpeciesOther, BirthDate: born(0, 2), ActivityLevel: models.ActivityHigh}, StageAdult, 1.25},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.profile.Weight = 10
+			requirement, err := Compute(test.profile, day)
+			require.NoError(t, err)
+			assert.Equal(t, test.stage, requirement.LifeStage)
+			assert.InDelta(t, test.factor, requirement.Factor, 1e-9)
+			assert.InDelta(t, requirement.RER*test.factor, requirement.MER, 1e-6)
+			assert.InDelta(t, 70*math.Pow(10, 0.75), requirement.RER, 1e-6)
+		})
+	}
+}
+
+func TestCompute_LifeStageOnDay(t *testing.T) {
+	profile := Profile{Species: models.SpeciesDog, Weight: 8, BirthDate: born(1, 0)}
+	requirement, err := Compute(profile, day.AddDate(0, -9, 0))
+	require.NoError(t, err)
+	assert.Equal(t, StageGrowthEarly, requirement.LifeStage)
+
+	requirement, err = Compute(profile, day.AddDate(0, -6, 0))
+	require.NoError(t, err)
+	assert.Equal(t, StageGrowth, requirement.LifeStage)
+
+	requirement, err = Compute(profile, day.AddDate(0, 0, -1))
+	require.NoError(t, err)
+	assert.Equal(t, StageGrowth, requirement.LifeStage)
+
+	requirement, err = Compute(profile, day)
+	require.NoError(t, err)
+	assert.Equal(t, StageAdult, requirement.LifeStage)
+}
@@ -0,0 +1,177 @@
+// Package realtime fans out pet and user events to the streaming clients of all server instances
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	channelPrefix = "storypet:realtime:"
+
+	// subscriptionBuffer is the count of events a client may lag behind before it is disconnected
+	subscriptionBuffer = 64
+)
+
+const (
+	EventActivityCreated     = "activity.created"
+	EventEatingCreated       = "eating.created"
+	EventHealthReportCreated = "health_report.created"
+	EventHealthReportUpdated = "health_report.updated"
+	EventNotificationCreated = "notification.created"
+	EventGeofenceCrossed     = "geofence.crossed"
+	// EventActivityBatchCreated summarizes the records of the collar batch, which may be too many to send one by one
+	EventActivityBatchCreated = "activity.batch_created"
+)
+
+// Bus is the message broker shared by the server instances
+type Bus interface {
+	Publish(channel string, message []byte) error
+	Subscribe(ctx context.Context, pattern string, handler func(channel string, message []byte)) error
+}
+
+type Event struct {
+	Type   string          `json:"type"`
+	PetID  int             `json:"pet_id,omitempty"`
+	UserID int             `json:"user_id,omitempty"`
+	Data   json.RawMessage `json:"data"`
+	SentAt time.Time       `json:"sent_at"`
+}
+
+func PetTopic(petID int) string {
+	return fmt.Sprintf("pets:%d", petID)
+}
+
+func UserTopic(userID int) string {
+	return fmt.Sprintf("users:%d", userID)
+}
+
+type Broker struct {
+	bus    Bus
+	logger *log.Logger
+
+	mu            sync.Mutex
+	subscriptions map[string]map[*Subscription]struct{}
+}
+
+func NewBroker(bus Bus, logger *log.Logger) *Broker {
+	return &Broker{
+		bus:           bus,
+		logger:        logger,
+		subscriptions: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Run receives the events published by all instances until ctx is done,
+// the subscription is restored if the bus connection is lost
+func (b *Broker) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := b.bus.Subscribe(ctx, channelPrefix+"*", b.dispatch); err != nil {
+			b.logger.Printf("Realtime subscription error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// PublishPet sends the event to the streams following the pet
+func (b *Broker) PublishPet(petID int, eventType string, data interface{}) error {
+	return b.publish(PetTopic(petID), Event{Type: eventType, PetID: petID}, data)
+}
+
+// PublishUser sends the event to the streams of the user
+func (b *Broker) PublishUser(userID int, eventType string, data interface{}) error {
+	return b.publish(UserTopic(userID), Event{Type: eventType, UserID: userID}, data)
+}
+
+func (b *Broker) publish(topic string, event Event, data interface{}) error {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	event.Data = rawData
+	event.SentAt = time.Now().UTC()
+	message, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.bus.Publish(channelPrefix+topic, message)
+}
+
+// Subscribe starts receiving events of the topics, the subscription must be closed by the caller
+func (b *Broker) Subscribe(topics ...string) *Subscription {
+	subscription := &Subscription{
+		broker: b,
+		topics: topics,
+		events: make(chan Event, subscriptionBuffer),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		if b.subscriptions[topic] == nil {
+			b.subscriptions[topic] = make(map[*Subscription]struct{})
+		}
+		b.subscriptions[topic][subscription] = struct{}{}
+	}
+	return subscription
+}
+
+func (b *Broker) dispatch(channel string, message []byte) {
+	topic := strings.TrimPrefix(channel, channelPrefix)
+	var event Event
+	if err := json.Unmarshal(message, &event); err != nil {
+		b.logger.Printf("Realtime event error: %v", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for subscription := range b.subscriptions[topic] {
+		select {
+		case subscription.events <- event:
+		default:
+			// The client does not keep up, it has to reconnect and reload the state
+			b.unsubscribe(subscription)
+		}
+	}
+}
+
+// unsubscribe must be called with the lock held
+func (b *Broker) unsubscribe(subscription *Subscription) {
+	if subscription.closed {
+		return
+	}
+	subscription.closed = true
+	for _, topic := range subscription.topics {
+		delete(b.subscriptions[topic], subscription)
+		if len(b.subscriptions[topic]) == 0 {
+			delete(b.subscriptions, topic)
+		}
+	}
+	close(subscription.events)
+}
+
+type Subscription struct {
+	broker *Broker
+	topics []string
+	events chan Event
+	closed bool
+}
+
+// Events is closed when the subscription is closed or the client lags behind
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.unsubscribe(s)
+}
@@ -0,0 +1,115 @@
+package realtime
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryBus delivers messages synchronously to the subscribers of the matching prefix
+type memoryBus struct {
+	mu       sync.Mutex
+	handlers map[string]func(channel string, message []byte)
+}
+
+func newMemoryBus() *memoryBus {
+	return &memoryBus{handlers: make(map[string]func(channel string, message []byte))}
+}
+
+func (m *memoryBus) Publish(channel string, message []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for pattern, handler := range m.handlers {
+		if strings.HasPrefix(channel, strings.TrimSuffix(pattern, "*")) {
+			handler(channel, message)
+		}
+	}
+	return nil
+}
+
+func (m *memoryBus) Subscribe(ctx context.Context, pattern string, handler func(channel string, message []byte)) error {
+	m.mu.Lock()
+	m.handlers[pattern] = handler
+	m.mu.Unlock()
+	<-ctx.Done()
+	m.mu.Lock()
+	delete(m.handlers, pattern)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryBus) waitSubscribed(t *testing.T) {
+	require.Eventually(t, func() bool {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return len(m.handlers) > 0
+	}, time.Second, time.Millisecond)
+}
+
+func startBroker(t *testing.T) (*Broker, *memoryBus) {
+	bus := newMemoryBus()
+	broker := NewBroker(bus, log.New(ioutil.Discard, "", 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go broker.Run(ctx)
+	bus.waitSubscribed(t)
+	return broker, bus
+}
+
+func TestBroker_RoutesByTopic(t *testing.T) {
+	broker, _ := startBroker(t)
+	pets := broker.Subscribe(PetTopic(1))
+	defer pets.Close()
+	user := broker.Subscribe(UserTopic(7), PetTopic(2))
+	defer user.Close()
+
+	require.NoError(t, broker.PublishPet(1, EventActivityCreated, map[string]int{"record_id": 5}))
+	require.NoError(t, broker.PublishUser(7, EventNotificationCreated, nil))
+	require.NoError(t, broker.PublishPet(3, EventEatingCreated, nil))
+
+	event := <-pets.Events()
+	assert.Equal(t, EventActivityCreated, event.Type)
+	assert.Equal(t, 1, event.PetID)
+	assert.JSONEq(t, `{"record_id":5}`, string(event.Data))
+
+	event = <-user.Events()
+	assert.Equal(t, EventNotificationCreated, event.Type)
+	assert.Equal(t, 7, event.UserID)
+
+	assert.Len(t, pets.Events(), 0)
+	assert.Len(t, user.Events(), 0)
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	broker, _ := startBroker(t)
+	slow := broker.Subscribe(PetTopic(1))
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		require.NoError(t, broker.PublishPet(1, EventActivityCreated, i))
+	}
+
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, subscriptionBuffer, received)
+	// Closing the dropped subscription is harmless
+	slow.Close()
+}
+
+func TestSubscription_Close(t *testing.T) {
+	broker, _ := startBroker(t)
+	subscription := broker.Subscribe(PetTopic(1), UserTopic(1))
+	subscription.Close()
+	subscription.Close()
+
+	_, ok := <-subscription.Events()
+	assert.False(t, ok)
+	assert.Empty(t, broker.subscriptions)
+}
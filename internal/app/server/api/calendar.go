@@ -0,0 +1,297 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/ical"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	calendarProdID = "-//StoryPet//Pets Calendar//EN"
+	// calendarHistory is how long past events are kept in the feed
+	calendarHistory = 30 * 24 * time.Hour
+)
+
+type CalendarAPI struct {
+	server server
+}
+
+func NewCalendarAPI(server server) *CalendarAPI {
+	return &CalendarAPI{server: server}
+}
+
+func (a *CalendarAPI) ConfigureRouter(router *mux.Router) {
+	sb := router.PathPrefix("/api/calendar").Subrouter()
+	sb.Use(a.server.Middleware().Authentication.IsAuthorised)
+
+	sb.Path("/feed").
+		Name("Calendar feed Request").
+		Methods(http.MethodGet, http.MethodPost, http.MethodDelete).
+		HandlerFunc(a.ServeFeedRequest)
+
+	// Calendar applications can not send access tokens, so the feed itself
+	// is authorized by the signature in its URL
+	router.Path("/calendar/{user:[0-9]+}/{signature:[0-9a-f]+}.ics").
+		Name("Calendar feed export Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeFeedExportRequest)
+}
+
+func (a *CalendarAPI) ServeFeedRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	type responseEntity struct {
+		URL       string    `json:"url"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		feed, err := a.server.DatabaseStore().Users().FindCalendarFeed(session.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		feedURL, err := a.feedURL(r, feed)
+		if err != nil {
+			a.server.Logger().Printf("Calendar signing error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, responseEntity{
+			URL:       feedURL,
+			CreatedAt: feed.CreatedAt,
+		})
+
+	case http.MethodPost:
+		// Creating the feed again rotates its key and revokes the previous URL
+		feedKey, err := auth.NewCalendarFeedKey()
+		if err != nil {
+			a.server.Logger().Printf("Calendar feed key error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		feed, err := a.server.DatabaseStore().Users().SaveCalendarFeed(session.UserID, feedKey)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		feedURL, err := a.feedURL(r, feed)
+		if err != nil {
+			a.server.Logger().Printf("Calendar signing error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, responseEntity{
+			URL:       feedURL,
+			CreatedAt: feed.CreatedAt,
+		})
+
+	case http.MethodDelete:
+		if err := a.server.DatabaseStore().Users().DeleteCalendarFeed(session.UserID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (a *CalendarAPI) ServeFeedExportRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["user"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, nil)
+		return
+	}
+	requestedUserID := int(rawID)
+
+	feed, err := a.server.DatabaseStore().Users().FindCalendarFeed(requestedUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v", err)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if !auth.VerifyCalendarFeed(requestedUserID, feed.FeedKey, mux.Vars(r)["signature"]) {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	calendar, err := a.buildCalendar(requestedUserID)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v", err)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	w.Header().Set("Content-Type", ical.ContentType)
+	w.Header().Set("Content-Disposition", `inline; filename="storypet.ics"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := calendar.WriteTo(w); err != nil {
+		a.server.Logger().Printf("Calendar writing error: %v", err)
+	}
+}
+
+// buildCalendar collects appointments the user takes part in and vaccine boosters of the user's pets
+func (a *CalendarAPI) buildCalendar(userID int) (*ical.Calendar, error) {
+	now := time.Now()
+	from := now.Add(-calendarHistory)
+	calendar := &ical.Calendar{
+		ProdID: calendarProdID,
+		Name:   "StoryPet",
+	}
+	petNames := make(map[int]string)
+	petName := func(petID int) (string, error) {
+		if name, ok := petNames[petID]; ok {
+			return name, nil
+		}
+		pet, err := a.server.DatabaseStore().Pets().FindByID(petID)
+		if err != nil {
+			return "", err
+		}
+		petNames[petID] = pet.Name
+		return pet.Name, nil
+	}
+
+	appointments, err := a.server.DatabaseStore().Appointments().SelectByParticipantFrom(userID, from)
+	if err != nil {
+		return nil, err
+	}
+	for idx := range appointments {
+		name, err := petName(appointments[idx].PetID)
+		if err != nil {
+			return nil, err
+		}
+		event := ical.Event{
+			UID:         fmt.Sprintf("appointment-%d@storypet", appointments[idx].AppointmentID),
+			Stamp:       now,
+			Start:       appointments[idx].StartsAt,
+			End:         appointments[idx].EndsAt,
+			Summary:     fmt.Sprintf("Veterinary appointment: %s", name),
+			Description: appointments[idx].Reason,
+			Status:      appointmentEventStatus(appointments[idx].Status),
+		}
+		if appointments[idx].SpecifiedClinicID != 0 {
+			clinic, err := a.server.DatabaseStore().Clinics().FindByID(appointments[idx].SpecifiedClinicID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			if clinic != nil {
+				event.Location = fmt.Sprintf("%s, %s, %s", clinic.Name, clinic.Address, clinic.City)
+			}
+		}
+		calendar.Events = append(calendar.Events, event)
+	}
+
+	pets, err := a.server.DatabaseStore().Pets().SelectByUserID(userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for idx := range pets {
+		schedule, err := petVaccinationSchedule(a.server.DatabaseStore(), &pets[idx], now)
+		if err != nil {
+			return nil, err
+		}
+		for _, shot := range schedule {
+			if shot.Status == models.VaccinationCompleted || shot.Date.Before(from) {
+				continue
+			}
+			calendar.Events = append(calendar.Events, ical.Event{
+				UID:     fmt.Sprintf("pet-%d-vaccine-%d-dose-%d@storypet", shot.PetID, shot.CatalogueID, shot.DoseNumber),
+				Stamp:   now,
+				Start:   shot.Date,
+				AllDay:  true,
+				Summary: fmt.Sprintf("%s: %s dose %d is %s", pets[idx].Name, shot.Name, shot.DoseNumber, shot.Status),
+			})
+		}
+
+		// Records made before the vaccine catalogue are boosted with the default interval
+		vaccines, err := a.server.DatabaseStore().Vaccines().SelectByPetID(pets[idx].PetID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		for _, vaccine := range latestVaccinations(vaccines) {
+			dueDate := vaccine.BoosterDueDate()
+			if vaccine.SpecifiedCatalogueID != 0 || dueDate.Before(from) {
+				continue
+			}
+			calendar.Events = append(calendar.Events, ical.Event{
+				UID:     fmt.Sprintf("vaccine-%d-booster@storypet", vaccine.VaccineID),
+				Stamp:   now,
+				Start:   dueDate,
+				AllDay:  true,
+				Summary: fmt.Sprintf("%s: %s booster is due", pets[idx].Name, vaccine.Name),
+			})
+		}
+	}
+	return calendar, nil
+}
+
+func (a *CalendarAPI) feedURL(r *http.Request, feed *models.CalendarFeed) (string, error) {
+	signature, err := auth.SignCalendarFeed(feed.UserID, feed.FeedKey)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/calendar/%d/%s.ics", requestBaseURL(r), feed.UserID, signature), nil
+}
+
+// latestVaccinations keeps only the last vaccination with each vaccine, earlier ones are already boosted
+func latestVaccinations(vaccines []models.Vaccine) []models.Vaccine {
+	latest := make(map[string]int)
+	for idx := range vaccines {
+		if prev, ok := latest[vaccines[idx].Name]; !ok || vaccines[idx].VaccinationDate.After(vaccines[prev].VaccinationDate) {
+			latest[vaccines[idx].Name] = idx
+		}
+	}
+	result := make([]models.Vaccine, 0, len(latest))
+	for idx := range vaccines {
+		if latest[vaccines[idx].Name] == idx {
+			result = append(result, vaccines[idx])
+		}
+	}
+	return result
+}
+
+func appointmentEventStatus(status string) string {
+	switch status {
+	case models.AppointmentRequested:
+		return ical.StatusTentative
+	case models.AppointmentCancelled:
+		return ical.StatusCancelled
+	default:
+		return ical.StatusConfirmed
+	}
+}
+
+// requestBaseURL is the scheme and host the request was sent to, the public URLs given to the clients start with it
+func requestBaseURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil && r.Header.Get("X-Forwarded-Proto") != "https" {
+		scheme = "http"
+	}
+	return scheme + "://" + r.Host
+}
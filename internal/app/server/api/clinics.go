@@ -0,0 +1,720 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ClinicsAPI struct {
+	server server
+}
+
+func NewClinicsAPI(server server) *ClinicsAPI {
+	return &ClinicsAPI{server: server}
+}
+
+func (a *ClinicsAPI) ConfigureRouter(router *mux.Router) {
+	sb := router.PathPrefix("/api/clinics").Subrouter()
+	sb.Use(a.server.Middleware().Authentication.IsAuthorised)
+
+	sb.Path("").
+		Name("Clinics Root Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeRootRequest)
+
+	sb.Path("/{id:[0-9]+}").
+		Name("Clinics ID Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeIDRequest)
+
+	sb.Path("/{id:[0-9]+}/hours").
+		Name("Clinic opening hours Request").
+		Methods(http.MethodGet, http.MethodPut).
+		HandlerFunc(a.ServeOpeningHoursRequest)
+
+	sb.Path("/{id:[0-9]+}/members").
+		Name("Clinic members Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeMembersRequest)
+
+	sb.Path("/{id:[0-9]+}/members/{user:[0-9]+}").
+		Name("Clinic member Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeMemberRequest)
+
+	sb.Path("/{id:[0-9]+}/patients").
+		Name("Clinic patients Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServePatientsRequest)
+
+	sb.Path("/{id:[0-9]+}/patients/{pet:[0-9]+}").
+		Name("Clinic patient Request").
+		Methods(http.MethodDelete).
+		HandlerFunc(a.ServePatientRequest)
+
+	sb.Path("/{id:[0-9]+}/vaccines/overdue").
+		Name("Clinic overdue vaccinations Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeOverdueVaccinationsRequest)
+}
+
+func (a *ClinicsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var clinics []models.Clinic
+		if r.URL.Query().Get("member") == "me" {
+			clinics, err = a.server.DatabaseStore().Clinics().SelectByMemberID(session.UserID)
+		} else {
+			clinics, err = a.server.DatabaseStore().Clinics().SelectAll()
+		}
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, clinics)
+
+	case http.MethodPost:
+		type requestBody struct {
+			Name               string  `json:"name"`
+			RegistrationNumber *string `json:"registration_number"`
+			Address            string  `json:"address"`
+			City               string  `json:"city"`
+			Phone              *string `json:"phone"`
+			Email              *string `json:"email"`
+			Website            *string `json:"website"`
+		}
+		if !permissions.AnyRoleIsVeterinarian(session.Roles) &&
+			!permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VeterinariansPermission) {
+			a.server.RespondError(w, r, http.StatusForbidden, exceptions.UserIsNotVeterinarian)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		clinicModel := &models.Clinic{
+			Name:    rb.Name,
+			Address: rb.Address,
+			City:    rb.City,
+		}
+		clinicModel.SetSpecifiedRegistrationNumber(rb.RegistrationNumber)
+		clinicModel.SetSpecifiedPhone(rb.Phone)
+		clinicModel.SetSpecifiedEmail(rb.Email)
+		clinicModel.SetSpecifiedWebsite(rb.Website)
+		if err := clinicModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		clinicModel, err = a.server.DatabaseStore().Clinics().Create(clinicModel, session.UserID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, clinicModel)
+	}
+}
+
+func (a *ClinicsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	clinicModel, err := a.server.DatabaseStore().Clinics().FindByID(requestedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, clinicModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			Name               string  `json:"name"`
+			RegistrationNumber *string `json:"registration_number"`
+			Address            string  `json:"address"`
+			City               string  `json:"city"`
+			Phone              *string `json:"phone"`
+			Email              *string `json:"email"`
+			Website            *string `json:"website"`
+		}
+		if !a.canManageClinic(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		updatedModel := &models.Clinic{
+			ClinicID:                    requestedID,
+			Name:                        rb.Name,
+			Address:                     rb.Address,
+			City:                        rb.City,
+			SpecifiedRegistrationNumber: clinicModel.SpecifiedRegistrationNumber,
+			SpecifiedPhone:              clinicModel.SpecifiedPhone,
+			SpecifiedEmail:              clinicModel.SpecifiedEmail,
+			SpecifiedWebsite:            clinicModel.SpecifiedWebsite,
+		}
+		updatedModel.SetSpecifiedRegistrationNumber(rb.RegistrationNumber)
+		updatedModel.SetSpecifiedPhone(rb.Phone)
+		updatedModel.SetSpecifiedEmail(rb.Email)
+		updatedModel.SetSpecifiedWebsite(rb.Website)
+		clinicModel.Update(updatedModel)
+		if err := clinicModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		updatedModel, err = a.server.DatabaseStore().Clinics().Update(updatedModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, updatedModel)
+
+	case http.MethodDelete:
+		if !a.canManageClinic(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		if _, err := a.server.DatabaseStore().Clinics().DeleteByID(requestedID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (a *ClinicsAPI) ServeOpeningHoursRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	if _, err := a.server.DatabaseStore().Clinics().FindByID(requestedID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		hours, err := a.server.DatabaseStore().Clinics().SelectOpeningHours(requestedID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, hours)
+
+	case http.MethodPut:
+		if !a.canManageClinic(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		var rb []models.ClinicOpeningHours
+		if err := json.NewDecoder(r.Body).Decode(&rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		for idx := range rb {
+			if err := rb[idx].Validate(); err != nil {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+		hours, err := a.server.DatabaseStore().Clinics().ReplaceOpeningHours(requestedID, rb)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, hours)
+	}
+}
+
+func (a *ClinicsAPI) ServeMembersRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	if _, err := a.server.DatabaseStore().Clinics().FindByID(requestedID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		type memberResponseEntity struct {
+			Member *models.ClinicMember `json:"member"`
+			User   *models.User         `json:"user"`
+		}
+		members, err := a.server.DatabaseStore().Clinics().SelectMembers(requestedID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		responseEntities := make([]memberResponseEntity, len(members), len(members))
+		for idx := range members {
+			user, err := a.server.DatabaseStore().Users().FindByID(members[idx].UserID)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			responseEntities[idx] = memberResponseEntity{
+				Member: &members[idx],
+				User:   user,
+			}
+		}
+		a.server.Respond(w, r, http.StatusOK, responseEntities)
+
+	case http.MethodPost:
+		type requestBody struct {
+			UserID     int    `json:"user_id"`
+			MemberRole string `json:"member_role"`
+		}
+		if !a.canManageClinic(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		memberModel := &models.ClinicMember{
+			ClinicID:   requestedID,
+			UserID:     rb.UserID,
+			MemberRole: rb.MemberRole,
+			JoinedAt:   time.Now(),
+		}
+		if err := memberModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if memberModel.IsVeterinarian() {
+			userRoles, err := a.server.DatabaseStore().Roles().SelectUserRoles(rb.UserID)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			if !permissions.AnyRoleIsVeterinarian(userRoles) {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.UserIsNotVeterinarian)
+				return
+			}
+		}
+		if _, err := a.server.DatabaseStore().Clinics().FindMember(requestedID, rb.UserID); !errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusBadRequest, exceptions.RecordAlreadyExist)
+			return
+		}
+		memberModel, err = a.server.DatabaseStore().Clinics().AddMember(memberModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, memberModel)
+	}
+}
+
+func (a *ClinicsAPI) ServeMemberRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+	rawID, err = strconv.ParseInt(mux.Vars(r)["user"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedUserID := int(rawID)
+
+	memberModel, err := a.server.DatabaseStore().Clinics().FindMember(requestedID, requestedUserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, memberModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			MemberRole string `json:"member_role"`
+		}
+		if !a.canManageClinic(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		updatedModel := &models.ClinicMember{
+			ClinicID:   requestedID,
+			UserID:     requestedUserID,
+			MemberRole: rb.MemberRole,
+		}
+		if err := updatedModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if memberModel.CanManageClinic() && !updatedModel.CanManageClinic() {
+			if !a.hasAnotherAdministrator(w, r, requestID, requestedID) {
+				return
+			}
+		}
+		updatedModel, err = a.server.DatabaseStore().Clinics().UpdateMemberRole(requestedID, requestedUserID, rb.MemberRole)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, updatedModel)
+
+	case http.MethodDelete:
+		if requestedUserID != session.UserID && !a.canManageClinic(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		if memberModel.CanManageClinic() {
+			if !a.hasAnotherAdministrator(w, r, requestID, requestedID) {
+				return
+			}
+		}
+		if _, err := a.server.DatabaseStore().Clinics().RemoveMember(requestedID, requestedUserID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (a *ClinicsAPI) ServePatientsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	if _, err := a.server.DatabaseStore().Clinics().FindByID(requestedID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !a.canManagePatients(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, exceptions.UserIsNotClinicMember)
+			return
+		}
+		patients, err := a.server.DatabaseStore().Clinics().SelectPatients(requestedID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, patients)
+
+	case http.MethodPost:
+		type requestBody struct {
+			PetID int `json:"pet_id"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		petModel, err := a.server.DatabaseStore().Pets().FindByID(rb.PetID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		// The pet is enrolled by its owner only, the clinic staff can not register somebody else's pet
+		if petModel.UserID != session.UserID {
+			a.server.RespondError(w, r, http.StatusForbidden, exceptions.PetIsEnrolledByOwner)
+			return
+		}
+		if petModel.ClinicID != nil && petModel.ClinicID.Valid {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.PetHasClinic)
+			return
+		}
+		if err := a.server.DatabaseStore().Clinics().RegisterPatient(requestedID, petModel.PetID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, nil)
+	}
+}
+
+func (a *ClinicsAPI) ServePatientRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+	rawID, err = strconv.ParseInt(mux.Vars(r)["pet"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedPetID := int(rawID)
+
+	petModel, err := a.server.DatabaseStore().Pets().FindByID(requestedPetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if petModel.SpecifiedClinicID != requestedID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		if petModel.UserID != session.UserID && !a.canManagePatients(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		if err := a.server.DatabaseStore().Clinics().UnregisterPatient(requestedPetID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// ServeOverdueVaccinationsRequest reports the clinic patients having missed vaccinations
+func (a *ClinicsAPI) ServeOverdueVaccinationsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	switch r.Method {
+	case http.MethodGet:
+		type responseEntity struct {
+			Pet     models.Pet                    `json:"pet"`
+			Overdue []models.ScheduledVaccination `json:"overdue"`
+		}
+		if !a.isClinicVeterinarian(session, requestedID) {
+			a.server.RespondError(w, r, http.StatusForbidden, exceptions.UserIsNotClinicMember)
+			return
+		}
+		patients, err := a.server.DatabaseStore().Clinics().SelectPatients(requestedID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		now := time.Now()
+		responseEntities := make([]responseEntity, 0)
+		for idx := range patients {
+			schedule, err := petVaccinationSchedule(a.server.DatabaseStore(), &patients[idx], now)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			var overdue []models.ScheduledVaccination
+			for _, shot := range schedule {
+				if shot.Status == models.VaccinationOverdue {
+					overdue = append(overdue, shot)
+				}
+			}
+			if len(overdue) != 0 {
+				responseEntities = append(responseEntities, responseEntity{
+					Pet:     patients[idx],
+					Overdue: overdue,
+				})
+			}
+		}
+		a.server.Respond(w, r, http.StatusOK, responseEntities)
+	}
+}
+
+func (a *ClinicsAPI) canManageClinic(session *sessions.Session, clinicID int) bool {
+	if permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VeterinariansPermission) {
+		return true
+	}
+	member, err := a.server.DatabaseStore().Clinics().FindMember(clinicID, session.UserID)
+	if err != nil {
+		return false
+	}
+	return member.CanManageClinic()
+}
+
+func (a *ClinicsAPI) isClinicVeterinarian(session *sessions.Session, clinicID int) bool {
+	if permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VeterinariansPermission) {
+		return true
+	}
+	member, err := a.server.DatabaseStore().Clinics().FindMember(clinicID, session.UserID)
+	if err != nil {
+		return false
+	}
+	return member.IsVeterinarian()
+}
+
+func (a *ClinicsAPI) canManagePatients(session *sessions.Session, clinicID int) bool {
+	if permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VeterinariansPermission) {
+		return true
+	}
+	member, err := a.server.DatabaseStore().Clinics().FindMember(clinicID, session.UserID)
+	if err != nil {
+		return false
+	}
+	return member.CanManagePatients()
+}
+
+// hasAnotherAdministrator responds with error and returns false if the clinic
+// would be left without administrators after demoting or removing one of them
+func (a *ClinicsAPI) hasAnotherAdministrator(w http.ResponseWriter, r *http.Request, requestID string, clinicID int) bool {
+	count, err := a.server.DatabaseStore().Clinics().CountAdministrators(clinicID)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return false
+	}
+	if count < 2 {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.LastClinicAdministrator)
+		return false
+	}
+	return true
+}
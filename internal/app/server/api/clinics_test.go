@@ -0,0 +1,186 @@
+package api
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+const (
+	testClinicID      = 1
+	clinicAdminID     = 10
+	clinicVetID       = 11
+	clinicReceptionID = 12
+	petOwnerID        = 20
+	strangerID        = 30
+)
+
+// clinicStore keeps the single clinic with its staff and the pets, the rest of the store is not used by ClinicsAPI
+type clinicStore struct {
+	store.DatabaseStore
+
+	clinics *clinicRepository
+	pets    *clinicPetRepository
+}
+
+func (s *clinicStore) Clinics() repos.ClinicRepository {
+	return s.clinics
+}
+
+func (s *clinicStore) Pets() repos.PetRepository {
+	return s.pets
+}
+
+type clinicRepository struct {
+	repos.ClinicRepository
+
+	members    map[int]*models.ClinicMember
+	registered map[int]int
+	deleted    bool
+}
+
+func (r *clinicRepository) FindByID(clinicID int) (*models.Clinic, error) {
+	if clinicID != testClinicID {
+		return nil, sql.ErrNoRows
+	}
+	return &models.Clinic{ClinicID: testClinicID, Name: "Happy Paws", Address: "Khreshchatyk 1", City: "Kyiv"}, nil
+}
+
+func (r *clinicRepository) DeleteByID(clinicID int) (*models.Clinic, error) {
+	r.deleted = true
+	return r.FindByID(clinicID)
+}
+
+func (r *clinicRepository) FindMember(clinicID int, userID int) (*models.ClinicMember, error) {
+	member, ok := r.members[userID]
+	if clinicID != testClinicID || !ok {
+		return nil, sql.ErrNoRows
+	}
+	return member, nil
+}
+
+func (r *clinicRepository) AddMember(member *models.ClinicMember) (*models.ClinicMember, error) {
+	r.members[member.UserID] = member
+	return member, nil
+}
+
+func (r *clinicRepository) SelectPatients(clinicID int) ([]models.Pet, error) {
+	return []models.Pet{}, nil
+}
+
+func (r *clinicRepository) RegisterPatient(clinicID int, petID int) error {
+	r.registered[petID] = clinicID
+	return nil
+}
+
+func (r *clinicRepository) UnregisterPatient(petID int) error {
+	delete(r.registered, petID)
+	return nil
+}
+
+type clinicPetRepository struct {
+	repos.PetRepository
+
+	pets map[int]*models.Pet
+}
+
+func (r *clinicPetRepository) FindByID(petID int) (*models.Pet, error) {
+	pet, ok := r.pets[petID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *pet
+	return &copied, nil
+}
+
+func newTestClinicsAPI(userID int) (*ClinicsAPI, *clinicStore) {
+	database := &clinicStore{
+		clinics: &clinicRepository{
+			members: map[int]*models.ClinicMember{
+				clinicAdminID:     {ClinicID: testClinicID, UserID: clinicAdminID, MemberRole: models.ClinicRoleAdministrator},
+				clinicVetID:       {ClinicID: testClinicID, UserID: clinicVetID, MemberRole: models.ClinicRoleVeterinarian},
+				clinicReceptionID: {ClinicID: testClinicID, UserID: clinicReceptionID, MemberRole: models.ClinicRoleReceptionist},
+			},
+			registered: make(map[int]int),
+		},
+		pets: &clinicPetRepository{
+			pets: map[int]*models.Pet{
+				5: {PetID: 5, UserID: petOwnerID},
+				6: {PetID: 6, UserID: petOwnerID, ClinicID: &sql.NullInt64{Int64: 2, Valid: true}, SpecifiedClinicID: 2},
+				7: {PetID: 7, UserID: petOwnerID, ClinicID: &sql.NullInt64{Int64: testClinicID, Valid: true}, SpecifiedClinicID: testClinicID},
+			},
+		},
+	}
+	return NewClinicsAPI(&testServer{database: database, session: &sessions.Session{UserID: userID}}), database
+}
+
+func TestClinicsAPI_Members(t *testing.T) {
+	clinicVars := map[string]string{"id": "1"}
+	body := `{"user_id": 40, "member_role": "receptionist"}`
+
+	// Only the administrator of the clinic manages its staff and details
+	for _, userID := range []int{clinicVetID, clinicReceptionID, strangerID} {
+		api, database := newTestClinicsAPI(userID)
+		assert.Equal(t, http.StatusForbidden, serve(api.ServeMembersRequest, http.MethodPost, body, clinicVars).Code, userID)
+		assert.NotContains(t, database.clinics.members, 40, userID)
+		assert.Equal(t, http.StatusForbidden, serve(api.ServeIDRequest, http.MethodDelete, "", clinicVars).Code, userID)
+		assert.False(t, database.clinics.deleted, userID)
+	}
+
+	api, database := newTestClinicsAPI(clinicAdminID)
+	assert.Equal(t, http.StatusCreated, serve(api.ServeMembersRequest, http.MethodPost, body, clinicVars).Code)
+	assert.Contains(t, database.clinics.members, 40)
+	assert.Equal(t, http.StatusBadRequest, serve(api.ServeMembersRequest, http.MethodPost, body, clinicVars).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(api.ServeMembersRequest, http.MethodPost, `{"user_id": 41, "member_role": "owner"}`, clinicVars).Code)
+	assert.Equal(t, http.StatusNotFound, serve(api.ServeMembersRequest, http.MethodPost, body, map[string]string{"id": "2"}).Code)
+	assert.Equal(t, http.StatusNoContent, serve(api.ServeIDRequest, http.MethodDelete, "", clinicVars).Code)
+	assert.True(t, database.clinics.deleted)
+}
+
+func TestClinicsAPI_Patients(t *testing.T) {
+	clinicVars := map[string]string{"id": "1"}
+
+	// The patients are listed to the staff only
+	api, _ := newTestClinicsAPI(strangerID)
+	w := serve(api.ServePatientsRequest, http.MethodGet, "", clinicVars)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), exceptions.UserIsNotClinicMember.Error())
+	api, _ = newTestClinicsAPI(clinicReceptionID)
+	assert.Equal(t, http.StatusOK, serve(api.ServePatientsRequest, http.MethodGet, "", clinicVars).Code)
+
+	// The pet is enrolled by its owner only, even the clinic staff can not register somebody else's pet
+	for _, userID := range []int{clinicAdminID, clinicVetID, clinicReceptionID, strangerID} {
+		api, database := newTestClinicsAPI(userID)
+		w := serve(api.ServePatientsRequest, http.MethodPost, `{"pet_id": 5}`, clinicVars)
+		assert.Equal(t, http.StatusForbidden, w.Code, userID)
+		assert.Contains(t, w.Body.String(), exceptions.PetIsEnrolledByOwner.Error(), userID)
+		assert.Empty(t, database.clinics.registered, userID)
+	}
+	api, database := newTestClinicsAPI(petOwnerID)
+	assert.Equal(t, http.StatusCreated, serve(api.ServePatientsRequest, http.MethodPost, `{"pet_id": 5}`, clinicVars).Code)
+	assert.Equal(t, testClinicID, database.clinics.registered[5])
+	assert.Equal(t, http.StatusUnprocessableEntity, serve(api.ServePatientsRequest, http.MethodPost, `{"pet_id": 6}`, clinicVars).Code)
+	assert.Equal(t, http.StatusNotFound, serve(api.ServePatientsRequest, http.MethodPost, `{"pet_id": 8}`, clinicVars).Code)
+
+	// The patient is unregistered by its owner or the clinic staff
+	patientVars := map[string]string{"id": "1", "pet": "7"}
+	api, database = newTestClinicsAPI(strangerID)
+	database.clinics.registered[7] = testClinicID
+	assert.Equal(t, http.StatusForbidden, serve(api.ServePatientRequest, http.MethodDelete, "", patientVars).Code)
+	assert.Contains(t, database.clinics.registered, 7)
+	for _, userID := range []int{petOwnerID, clinicReceptionID} {
+		api, database = newTestClinicsAPI(userID)
+		database.clinics.registered[7] = testClinicID
+		assert.Equal(t, http.StatusNoContent, serve(api.ServePatientRequest, http.MethodDelete, "", patientVars).Code, userID)
+		assert.NotContains(t, database.clinics.registered, 7, userID)
+	}
+	// The pet of another clinic is not found
+	api, _ = newTestClinicsAPI(clinicAdminID)
+	assert.Equal(t, http.StatusNotFound, serve(api.ServePatientRequest, http.MethodDelete, "", map[string]string{"id": "1", "pet": "6"}).Code)
+}
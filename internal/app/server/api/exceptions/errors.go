@@ -19,11 +19,59 @@ var (
 
 	CanNotUpdatePrimaryRole = errors.New("could not update base system roles: administrator, subscribed/unsubscribed user and veterinarian")
 	CanNotDeletePrimaryRole = errors.New("could not delete base system roles: administrator, subscribed/unsubscribed user and veterinarian")
+	CanNotUseRoleAsDefault  = errors.New("administrator and veterinarian roles can not be used as default user role")
 
 	CanNotAssignPetToAnotherUser = errors.New("assigning new pet to other user is permitted")
 	PetHasVeterinarian           = errors.New("can not assign veterinarian to pet, pet currently has a veterinarian")
 	PetHasNoVeterinarian         = errors.New("pet has not a veterinarian")
 	NoParentsSpecified           = errors.New("no parents specified")
+	PetHasClinic                 = errors.New("pet is already registered to a clinic")
+	PetIsEnrolledByOwner         = errors.New("operation permitted, pet is registered to a clinic by its owner only")
+
+	VaccineNotInCatalogue = errors.New("no vaccine with requested id in catalogue")
+	VaccineNotForPetType  = errors.New("vaccine is not intended for the pet type")
+
+	UserIsNotClinicMember   = errors.New("operation permitted, user is not a member of the clinic")
+	LastClinicAdministrator = errors.New("can not remove or demote the last administrator of the clinic")
+
+	AppointmentInPast           = errors.New("appointment can not be booked in the past")
+	AppointmentSlotIsTaken      = errors.New("veterinarian already has an appointment at this time")
+	AppointmentStatusTransition = errors.New("appointment status can not be changed to the requested one")
+	VeterinarianIsNotAvailable  = errors.New("veterinarian does not accept appointments at this time")
 
 	UnprocessableURLQuery = errors.New("can not process provided URL query")
+
+	UnknownNotificationPreference = errors.New("unknown notification event or channel")
+	WebPushIsNotConfigured        = errors.New("web push notifications are not configured")
+
+	WebhookSubscriptionIsInactive = errors.New("webhook subscription is inactive")
+
+	UnprocessableDeviceTime = errors.New("device time must be in RFC 3339 format")
+
+	PetWeightIsUnknown = errors.New("pet has no anthropometry records, the weight must be recorded or provided")
+
+	FoodNotFound   = errors.New("no food with requested id")
+	InvalidBarcode = errors.New("barcode must be a valid GTIN: EAN-8, UPC-A, EAN-13 or GTIN-14")
+	BarcodeIsTaken = errors.New("barcode belongs to another food")
+
+	CatalogueFileNotFoundInRequest = errors.New("no file field found in form/multipart section of request")
+
+	HealthReportNotFound      = errors.New("no health report of the pet with requested id")
+	DoseInFuture              = errors.New("dose can not be administered in the future")
+	MedicationIsNotPrescribed = errors.New("only the prescribing veterinarian can change and delete the medication")
+
+	ConditionNotFound = errors.New("no condition of the pet with requested id")
+
+	AttachmentFileNotFoundInRequest = errors.New("no file field found in form/multipart section of request")
+	AttachmentIsTooLarge            = errors.New("file is too large, images up to 10 MB and documents up to 50 MB are accepted")
+	AttachmentTypeIsNotSupported    = errors.New("file type is not supported, images, PDF and DICOM files are accepted")
+
+	ReportIsSigned          = errors.New("health report is already signed, it can only be amended")
+	ReportIsNotAuthored     = errors.New("only the author can edit, sign and amend the health report")
+	ReportVersionConflict   = errors.New("health report has been changed meanwhile, the latest version must be edited")
+	ReportVersionRequired   = errors.New("version of the health report the edit is made from is required")
+	AmendmentReasonRequired = errors.New("amendment of the signed health report requires the reason")
+
+	VeterinarianHasNoClinic       = errors.New("veterinarian of the pet has not specified the clinic")
+	CertificateIsNotVeterinarians = errors.New("only the veterinarian of the pet can issue the vaccination certificate")
 )
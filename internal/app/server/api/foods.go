@@ -7,11 +7,16 @@ import (
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/gtin"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 	"net/http"
 	"strconv"
 )
 
+// uniqueViolation is the postgres error code raised when a barcode is saved for the second food
+const uniqueViolation = "23505"
+
 type FoodsAPI struct {
 	server server
 }
@@ -32,6 +37,62 @@ func (a *FoodsAPI) ConfigureRouter(router *mux.Router) {
 		Name("Foods ID Request").
 		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
 		HandlerFunc(a.ServeIDRequest)
+	sb.Path("/barcode/{code}").
+		Name("Foods Barcode Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeBarcodeRequest)
+	sb.Path("/search").
+		Name("Foods Search Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeSearchRequest)
+	sb.Path("/import").
+		Name("Foods Catalogue Import Request").
+		Methods(http.MethodPost).
+		HandlerFunc(a.ServeImportRequest)
+}
+
+/*
+foodRequestBody is the food of the POST and PUT requests.
+
+The composition is in grams per 100 g as fed, the energy in kcal per 100 g is derived from the deprecated
+food_calories in kcal per gram or from protein, fat and carbohydrate when it is omitted. Portion units and barcodes are kept when omitted, an empty list removes them.
+*/
+type foodRequestBody struct {
+	FoodName      string                    `json:"food_name"`
+	EnergyPer100g float64                   `json:"energy_per_100g"`
+	Calories      float64                   `json:"food_calories"`
+	Protein       *float64                  `json:"protein"`
+	Fat           *float64                  `json:"fat"`
+	Carbohydrate  *float64                  `json:"carbohydrate"`
+	Fibre         *float64                  `json:"fibre"`
+	Moisture      *float64                  `json:"moisture"`
+	Calcium       *float64                  `json:"calcium"`
+	Phosphorus    *float64                  `json:"phosphorus"`
+	Description   *string                   `json:"description"`
+	Manufacturer  *string                   `json:"manufacturer"`
+	PortionUnits  *[]models.FoodPortionUnit `json:"portion_units"`
+	Barcodes      *[]string                 `json:"barcodes"`
+}
+
+func (rb *foodRequestBody) apply(foodModel *models.Food) {
+	foodModel.FoodName = rb.FoodName
+	foodModel.EnergyPer100g = rb.EnergyPer100g
+	foodModel.Calories = rb.Calories
+	foodModel.SpecifiedProtein = rb.Protein
+	foodModel.SpecifiedFat = rb.Fat
+	foodModel.SpecifiedCarbohydrate = rb.Carbohydrate
+	foodModel.SpecifiedFibre = rb.Fibre
+	foodModel.SpecifiedMoisture = rb.Moisture
+	foodModel.SpecifiedCalcium = rb.Calcium
+	foodModel.SpecifiedPhosphorus = rb.Phosphorus
+	foodModel.SetSpecifiedDescription(rb.Description)
+	foodModel.SetSpecifiedManufacturer(rb.Manufacturer)
+	if rb.PortionUnits != nil {
+		foodModel.PortionUnits = *rb.PortionUnits
+	}
+	if rb.Barcodes != nil {
+		foodModel.Barcodes = *rb.Barcodes
+	}
 }
 
 func (a *FoodsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
@@ -46,13 +107,30 @@ func (a *FoodsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
+		// The foods found by the name are paged by limit and offset, foodsSearchMaxLimit of them by default.
+		// The body is the list of the foods as before, X-Next-Offset is the offset of the next page if there is one.
 		if queriedNamePattern := r.URL.Query().Get("name"); queriedNamePattern != "" {
-			foodModels, err := a.server.DatabaseStore().Foods().SelectByNameSimilarity(queriedNamePattern)
+			searchQuery := models.FoodSearchQuery{Text: queriedNamePattern, Limit: foodsSearchMaxLimit}
+			if !parseSearchPage(r.URL.Query(), &searchQuery) {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			pageSize := searchQuery.Limit
+			searchQuery.Limit++
+			results, err := a.server.DatabaseStore().Foods().Search(searchQuery)
 			if err != nil {
 				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
 				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 				return
 			}
+			if len(results) > pageSize {
+				results = results[:pageSize]
+				w.Header().Set("X-Next-Offset", strconv.Itoa(searchQuery.Offset+pageSize))
+			}
+			foodModels := make([]*models.Food, len(results))
+			for idx := range results {
+				foodModels[idx] = results[idx].Food
+			}
 			a.server.Respond(w, r, http.StatusOK, foodModels)
 			return
 		}
@@ -66,25 +144,18 @@ func (a *FoodsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusOK, foodModels)
 
 	case http.MethodPost:
-		type requestBody struct {
-			FoodName     string  `json:"food_name"`
-			Calories     float64 `json:"calories"`
-			Description  *string `json:"description"`
-			Manufacturer *string `json:"manufacturer"`
-		}
-		rb := &requestBody{}
+		rb := &foodRequestBody{}
 		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
 			a.server.RespondError(w, r, http.StatusBadRequest, err)
 			return
 		}
 
 		foodModel := &models.Food{
-			FoodName:           rb.FoodName,
-			Calories:           rb.Calories,
 			SpecifiedCreatorID: session.UserID,
+			PortionUnits:       make([]models.FoodPortionUnit, 0),
+			Barcodes:           make([]string, 0),
 		}
-		foodModel.SetSpecifiedDescription(rb.Description)
-		foodModel.SetSpecifiedManufacturer(rb.Manufacturer)
+		rb.apply(foodModel)
 		foodModel.BeforeCreate()
 		if err := foodModel.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -92,6 +163,11 @@ func (a *FoodsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 		}
 		foodModel, err = a.server.DatabaseStore().Foods().Create(foodModel)
 		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				a.server.RespondError(w, r, http.StatusConflict, exceptions.BarcodeIsTaken)
+				return
+			}
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
 			return
 		}
@@ -130,12 +206,6 @@ func (a *FoodsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusOK, requestedModel)
 
 	case http.MethodPut:
-		type requestBody struct {
-			FoodName     string  `json:"food_name"`
-			Calories     float64 `json:"calories"`
-			Description  *string `json:"description"`
-			Manufacturer *string `json:"manufacturer"`
-		}
 		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().FoodPermission) {
 			if requestedModel.CreatorID == nil || !requestedModel.CreatorID.Valid {
 				a.server.RespondError(w, r, http.StatusForbidden, nil)
@@ -146,21 +216,20 @@ func (a *FoodsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
-		rb := &requestBody{}
+		rb := &foodRequestBody{}
 		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
 			a.server.RespondError(w, r, http.StatusBadRequest, err)
 			return
 		}
 		foodModel := &models.Food{
 			FoodID:                requestedModel.FoodID,
-			FoodName:              rb.FoodName,
-			Calories:              rb.Calories,
 			SpecifiedDescription:  requestedModel.SpecifiedDescription,
 			SpecifiedManufacturer: requestedModel.SpecifiedManufacturer,
 			SpecifiedCreatorID:    requestedModel.SpecifiedCreatorID,
+			PortionUnits:          requestedModel.PortionUnits,
+			Barcodes:              requestedModel.Barcodes,
 		}
-		foodModel.SetSpecifiedManufacturer(rb.Manufacturer)
-		foodModel.SetSpecifiedDescription(rb.Description)
+		rb.apply(foodModel)
 		foodModel.BeforeCreate()
 		if err := foodModel.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -168,6 +237,11 @@ func (a *FoodsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		}
 		foodModel, err = a.server.DatabaseStore().Foods().Update(foodModel)
 		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+				a.server.RespondError(w, r, http.StatusConflict, exceptions.BarcodeIsTaken)
+				return
+			}
 			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
 			return
@@ -193,3 +267,32 @@ func (a *FoodsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusNoContent, nil)
 	}
 }
+
+// ServeBarcodeRequest finds the food by the GTIN barcode of its package
+func (a *FoodsAPI) ServeBarcodeRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, _, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	code, err := gtin.Normalize(mux.Vars(r)["code"])
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.InvalidBarcode)
+		return
+	}
+	foodModel, err := a.server.DatabaseStore().Foods().FindByBarcode(code)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, foodModel)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/catalogue"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/lib/pq"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+/*
+ServeImportRequest imports the manufacturer catalogue sent as the CSV or JSON body, or as the file field of the
+multipart form. With dry_run=true the report of what would be done is returned and nothing is saved, otherwise
+the foods are saved only when every row of the catalogue is valid. The manufacturer query parameter is used
+for the rows without one.
+*/
+func (a *FoodsAPI) ServeImportRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().FoodPermission) {
+		a.server.RespondError(w, r, http.StatusForbidden, nil)
+		return
+	}
+
+	dryRun := false
+	if rawDryRun := r.URL.Query().Get("dry_run"); rawDryRun != "" {
+		if dryRun, err = strconv.ParseBool(rawDryRun); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, catalogue.MaxFileSize)
+	entries, err := a.parseCatalogue(r)
+	if err != nil {
+		if errors.Is(err, catalogue.ErrUnsupportedFormat) {
+			a.server.RespondError(w, r, http.StatusUnsupportedMediaType, err)
+			return
+		}
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if manufacturer := r.URL.Query().Get("manufacturer"); manufacturer != "" {
+		for idx := range entries {
+			if entries[idx].Food.SpecifiedManufacturer == "" {
+				entries[idx].Food.SpecifiedManufacturer = manufacturer
+			}
+		}
+	}
+
+	existing, err := a.server.DatabaseStore().Foods().SelectByNames(catalogue.Names(entries))
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	barcodeOwners, err := a.server.DatabaseStore().Foods().SelectBarcodeOwners(catalogue.Barcodes(entries))
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	plan := catalogue.NewPlan(entries, existing, barcodeOwners)
+	if !plan.IsValid() {
+		a.server.Respond(w, r, http.StatusUnprocessableEntity, plan.Report(dryRun))
+		return
+	}
+	if dryRun {
+		a.server.Respond(w, r, http.StatusOK, plan.Report(dryRun))
+		return
+	}
+
+	if err := a.server.DatabaseStore().Foods().Import(plan.Creates, plan.Updates); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			a.server.RespondError(w, r, http.StatusConflict, exceptions.BarcodeIsTaken)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, plan.Report(dryRun))
+}
+
+func (a *FoodsAPI) parseCatalogue(r *http.Request) ([]catalogue.Entry, error) {
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "multipart/form-data" {
+		format, err := catalogue.FormatOf(contentType, "")
+		if err != nil {
+			return nil, err
+		}
+		return catalogue.Parse(format, r.Body)
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, exceptions.CatalogueFileNotFoundInRequest
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	format, err := catalogue.FormatOf(header.Header.Get("Content-Type"), header.Filename)
+	if err != nil {
+		return nil, err
+	}
+	return catalogue.Parse(format, file)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const (
+	foodsSearchDefaultLimit = 20
+	foodsSearchMaxLimit     = 200
+)
+
+/*
+ServeSearchRequest finds the foods by the q text with typos tolerated, the best matches first.
+The manufacturer, min_energy and max_energy parameters filter the foods, the energy is in kcal per 100 g.
+*/
+func (a *FoodsAPI) ServeSearchRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, _, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	query := r.URL.Query()
+	searchQuery := models.FoodSearchQuery{
+		Text:         query.Get("q"),
+		Manufacturer: query.Get("manufacturer"),
+		Limit:        foodsSearchDefaultLimit,
+	}
+	if !parseSearchPage(query, &searchQuery) {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+		return
+	}
+	for parameter, energy := range map[string]**float64{"min_energy": &searchQuery.MinEnergy, "max_energy": &searchQuery.MaxEnergy} {
+		if rawEnergy := query.Get(parameter); rawEnergy != "" {
+			parsedEnergy, err := strconv.ParseFloat(rawEnergy, 64)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			*energy = &parsedEnergy
+		}
+	}
+	if err := searchQuery.Validate(); err != nil {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	results, err := a.server.DatabaseStore().Foods().Search(searchQuery)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, results)
+}
+
+// parseSearchPage reads the limit and offset parameters, the limit is at most foodsSearchMaxLimit
+func parseSearchPage(query url.Values, searchQuery *models.FoodSearchQuery) bool {
+	if rawLimit := query.Get("limit"); rawLimit != "" {
+		parsedLimit, err := strconv.ParseInt(rawLimit, 10, 64)
+		if err != nil || parsedLimit <= 0 || parsedLimit > foodsSearchMaxLimit {
+			return false
+		}
+		searchQuery.Limit = int(parsedLimit)
+	}
+	if rawOffset := query.Get("offset"); rawOffset != "" {
+		parsedOffset, err := strconv.ParseInt(rawOffset, 10, 64)
+		if err != nil || parsedOffset < 0 {
+			return false
+		}
+		searchQuery.Offset = int(parsedOffset)
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/middleware"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/telemetry"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"net/http"
+	"time"
+)
+
+const (
+	// hdrDeviceTime is the time by the device clock when the batch was sent, RFC 3339
+	hdrDeviceTime = "X-Device-Time"
+
+	iotBatchMaxBytes = 2 << 20
+)
+
+// ServeIoTBatchRequest stores the samples the collar has collected, possibly while offline.
+// The batch is the JSON array or NDJSON of samples, samples uploaded again are reported as duplicates.
+func (a *SessionAPI) ServeIoTBatchRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID := r.Context().Value(middleware.CtxRequestUUID).(string)
+
+	switch r.Method {
+	case http.MethodPost:
+		authTokenMeta, err := auth.ExtractIoTAccessMeta(r)
+		if err != nil {
+			a.server.Respond(w, r, http.StatusUnauthorized, nil)
+			return
+		}
+		deviceModel, ok := a.authorisedDevice(w, r, authTokenMeta, &models.DeviceTelemetry{})
+		if !ok {
+			return
+		}
+
+		var deviceNow *time.Time
+		if rawDeviceTime := r.Header.Get(hdrDeviceTime); rawDeviceTime != "" {
+			parsed, err := time.Parse(time.RFC3339, rawDeviceTime)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableDeviceTime)
+				return
+			}
+			deviceNow = &parsed
+		}
+
+		samples, err := telemetry.Decode(http.MaxBytesReader(w, r.Body, iotBatchMaxBytes), r.Header.Get("Content-Type"))
+		if err != nil {
+			if errors.Is(err, telemetry.ErrUnsupportedContentType) {
+				a.server.RespondError(w, r, http.StatusUnsupportedMediaType, err)
+				return
+			}
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		accepted, rejected, err := telemetry.Normalize(samples, deviceNow, time.Now())
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		records := make([]models.Activity, len(accepted))
+		for idx := range accepted {
+			records[idx] = models.Activity{
+				PetID:           deviceModel.PetID,
+				RecordTimestamp: accepted[idx].RecordedAt,
+				Distance:        accepted[idx].Distance,
+				MeanSpeed:       accepted[idx].MeanSpeed,
+				DeviceID:        &sql.NullInt64{Int64: int64(deviceModel.DeviceID), Valid: true},
+				SampleID:        &sql.NullString{String: accepted[idx].SampleID, Valid: true},
+			}
+		}
+		var inserted []string
+		if len(records) > 0 {
+			inserted, err = a.server.DatabaseStore().Pets().CreateActivityRecords(records)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+		}
+
+		isInserted := make(map[string]bool, len(inserted))
+		for _, sampleID := range inserted {
+			isInserted[sampleID] = true
+		}
+		created := make([]models.Activity, 0, len(inserted))
+		for idx := range records {
+			if isInserted[records[idx].SampleID.String] {
+				created = append(created, records[idx])
+			}
+		}
+		if len(created) > 0 {
+			// The whole batch is sent as one event, so the partners and the streams are not flooded
+			// after the collar reconnects
+			publishPetEvent(a.server, requestID, deviceModel.PetID, realtime.EventActivityBatchCreated, summarizeActivityBatch(created))
+			dispatchWebhook(a.server, requestID, models.WebhookActivityRecorded, deviceModel.PetID, created)
+		}
+
+		type responseBody struct {
+			Accepted   int                   `json:"accepted"`
+			Duplicates int                   `json:"duplicates"`
+			Rejected   []telemetry.Rejection `json:"rejected"`
+		}
+		a.server.Respond(w, r, http.StatusOK, responseBody{
+			Accepted:   len(inserted),
+			Duplicates: len(samples) - len(inserted) - len(rejected),
+			Rejected:   rejected,
+		})
+	}
+}
+
+// activityBatchSummary is the streamed event of the stored collar batch, the records are fetched by the period
+type activityBatchSummary struct {
+	Count    int       `json:"count"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Distance float64   `json:"distance"`
+}
+
+func summarizeActivityBatch(records []models.Activity) activityBatchSummary {
+	summary := activityBatchSummary{
+		Count: len(records),
+		From:  records[0].RecordTimestamp,
+		To:    records[0].RecordTimestamp,
+	}
+	for _, record := range records {
+		if record.RecordTimestamp.Before(summary.From) {
+			summary.From = record.RecordTimestamp
+		}
+		if record.RecordTimestamp.After(summary.To) {
+			summary.To = record.RecordTimestamp
+		}
+		summary.Distance += record.Distance
+	}
+	return summary
+}
+
+// ServeIoTLocationRequest stores the GPS fixes of the collar and evaluates the pet zones by them,
+// the fixes are split into walks by the job. Fixes uploaded again are reported as duplicates.
+func (a *SessionAPI) ServeIoTLocationRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID := r.Context().Value(middleware.CtxRequestUUID).(string)
+
+	switch r.Method {
+	case http.MethodPost:
+		authTokenMeta, err := auth.ExtractIoTAccessMeta(r)
+		if err != nil {
+			a.server.Respond(w, r, http.StatusUnauthorized, nil)
+			return
+		}
+		deviceModel, ok := a.authorisedDevice(w, r, authTokenMeta, &models.DeviceTelemetry{})
+		if !ok {
+			return
+		}
+
+		var deviceNow *time.Time
+		if rawDeviceTime := r.Header.Get(hdrDeviceTime); rawDeviceTime != "" {
+			parsed, err := time.Parse(time.RFC3339, rawDeviceTime)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableDeviceTime)
+				return
+			}
+			deviceNow = &parsed
+		}
+
+		points, err := tracks.Decode(http.MaxBytesReader(w, r.Body, iotBatchMaxBytes))
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		accepted, rejected, err := tracks.Normalize(points, deviceNow, time.Now())
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		inserted := 0
+		if len(accepted) > 0 {
+			inserted, err = a.server.DatabaseStore().Walks().CreateTrackPoints(tracks.ToModels(accepted, deviceModel.PetID, deviceModel.DeviceID))
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			// The fixes are stored, so the failed evaluation is only logged
+			if _, err := a.server.GeofenceMonitor().Process(deviceModel.PetID, accepted); err != nil {
+				a.server.Logger().Printf("Geofence error: %v Request ID: %v", err, requestID)
+			}
+		}
+
+		type responseBody struct {
+			Accepted   int                   `json:"accepted"`
+			Duplicates int                   `json:"duplicates"`
+			Rejected   []telemetry.Rejection `json:"rejected"`
+		}
+		a.server.Respond(w, r, http.StatusOK, responseBody{
+			Accepted:   inserted,
+			Duplicates: len(accepted) - inserted,
+			Rejected:   rejected,
+		})
+	}
+}
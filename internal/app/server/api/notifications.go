@@ -0,0 +1,374 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const (
+	notificationsDefaultLimit = 50
+	notificationsMaxLimit     = 200
+)
+
+type NotificationsAPI struct {
+	server server
+}
+
+func NewNotificationsAPI(server server) *NotificationsAPI {
+	return &NotificationsAPI{server: server}
+}
+
+func (a *NotificationsAPI) ConfigureRouter(router *mux.Router) {
+	sb := router.PathPrefix("/api/notifications").Subrouter()
+	sb.Use(a.server.Middleware().Authentication.IsAuthorised)
+
+	sb.Path("").
+		Name("Notifications inbox Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeInboxRequest)
+
+	sb.Path("/read").
+		Name("Notifications mark all read Request").
+		Methods(http.MethodPost).
+		HandlerFunc(a.ServeReadAllRequest)
+
+	sb.Path("/{id:[0-9]+}").
+		Name("Notification ID Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeIDRequest)
+
+	sb.Path("/preferences").
+		Name("Notification preferences Request").
+		Methods(http.MethodGet, http.MethodPut).
+		HandlerFunc(a.ServePreferencesRequest)
+
+	sb.Path("/endpoints").
+		Name("Notification endpoints Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeEndpointsRequest)
+
+	sb.Path("/endpoints/{endpoint:[0-9]+}").
+		Name("Notification endpoint ID Request").
+		Methods(http.MethodDelete).
+		HandlerFunc(a.ServeEndpointIDRequest)
+
+	sb.Path("/web-push/key").
+		Name("Web push application server key Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeWebPushKeyRequest)
+}
+
+func (a *NotificationsAPI) ServeInboxRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		unreadOnly := query.Get("unread") == "true"
+		limit, offset := notificationsDefaultLimit, 0
+		if rawLimit := query.Get("limit"); rawLimit != "" {
+			parsedLimit, err := strconv.ParseInt(rawLimit, 10, 64)
+			if err != nil || parsedLimit <= 0 || parsedLimit > notificationsMaxLimit {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			limit = int(parsedLimit)
+		}
+		if rawOffset := query.Get("offset"); rawOffset != "" {
+			parsedOffset, err := strconv.ParseInt(rawOffset, 10, 64)
+			if err != nil || parsedOffset < 0 {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			offset = int(parsedOffset)
+		}
+
+		notifications, err := a.server.DatabaseStore().Notifications().SelectByUserID(session.UserID, unreadOnly, limit, offset)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		unreadCount, err := a.server.DatabaseStore().Notifications().CountUnread(session.UserID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		type responseEntity struct {
+			UnreadCount   int                   `json:"unread_count"`
+			Notifications []models.Notification `json:"notifications"`
+		}
+		a.server.Respond(w, r, http.StatusOK, responseEntity{
+			UnreadCount:   unreadCount,
+			Notifications: notifications,
+		})
+	}
+}
+
+func (a *NotificationsAPI) ServeReadAllRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if err := a.server.DatabaseStore().Notifications().MarkAllRead(session.UserID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (a *NotificationsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	respondNotification := func(notification *models.Notification, err error, statusCode int) {
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, statusCode, notification)
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		notification, err := a.server.DatabaseStore().Notifications().FindByID(requestedID)
+		if err == nil && notification.UserID != session.UserID {
+			err = sql.ErrNoRows
+		}
+		respondNotification(notification, err, http.StatusOK)
+
+	case http.MethodPut:
+		type requestBody struct {
+			IsRead bool `json:"is_read"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		notification, err := a.server.DatabaseStore().Notifications().SetRead(session.UserID, requestedID, rb.IsRead)
+		respondNotification(notification, err, http.StatusOK)
+
+	case http.MethodDelete:
+		_, err := a.server.DatabaseStore().Notifications().DeleteByID(session.UserID, requestedID)
+		respondNotification(nil, err, http.StatusNoContent)
+	}
+}
+
+func (a *NotificationsAPI) ServePreferencesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var rb []models.NotificationPreference
+		if err := json.NewDecoder(r.Body).Decode(&rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		for idx := range rb {
+			if !models.IsNotificationEvent(rb[idx].Event) || !models.IsNotificationChannel(rb[idx].Channel) {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.UnknownNotificationPreference)
+				return
+			}
+		}
+		if err := a.server.DatabaseStore().Notifications().SavePreferences(session.UserID, rb); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	// Both methods respond with the complete matrix of events and channels
+	stored, err := a.server.DatabaseStore().Notifications().SelectPreferences(session.UserID)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	preferences := make([]models.NotificationPreference, 0, len(models.NotificationEvents)*len(models.NotificationChannels))
+	for _, event := range models.NotificationEvents {
+		for _, channel := range models.NotificationChannels {
+			preference := models.NotificationPreference{UserID: session.UserID, Event: event, Channel: channel, Enabled: true}
+			for _, storedPreference := range stored {
+				if storedPreference.Event == event && storedPreference.Channel == channel {
+					preference.Enabled = storedPreference.Enabled
+				}
+			}
+			preferences = append(preferences, preference)
+		}
+	}
+	a.server.Respond(w, r, http.StatusOK, preferences)
+}
+
+func (a *NotificationsAPI) ServeEndpointsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		endpoints, err := a.server.DatabaseStore().Notifications().SelectEndpoints(session.UserID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, endpoints)
+
+	case http.MethodPost:
+		// Web push endpoints are browser PushSubscription objects, webhooks only need the URL
+		type requestBody struct {
+			Channel string `json:"channel"`
+			Address string `json:"endpoint"`
+			Keys    struct {
+				P256DH string `json:"p256dh"`
+				Auth   string `json:"auth"`
+			} `json:"keys"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		endpointModel := &models.NotificationEndpoint{
+			UserID:             session.UserID,
+			Channel:            rb.Channel,
+			Address:            rb.Address,
+			SpecifiedPublicKey: rb.Keys.P256DH,
+			Secret:             rb.Keys.Auth,
+		}
+		if rb.Channel == models.ChannelWebhook {
+			// The webhook secret is generated by the server and shown only once
+			secret, err := auth.NewSecret(32)
+			if err != nil {
+				a.server.Logger().Printf("Webhook secret error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			endpointModel.SpecifiedPublicKey = ""
+			endpointModel.Secret = secret
+		}
+		if err := endpointModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		// The endpoint host must not resolve to the internal network of the server
+		if err := netguard.ResolveURL(r.Context(), endpointModel.Address); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		endpointModel, err = a.server.DatabaseStore().Notifications().CreateEndpoint(endpointModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		type responseEntity struct {
+			*models.NotificationEndpoint
+			Secret string `json:"secret,omitempty"`
+		}
+		response := responseEntity{NotificationEndpoint: endpointModel}
+		if endpointModel.Channel == models.ChannelWebhook {
+			response.Secret = endpointModel.Secret
+		}
+		a.server.Respond(w, r, http.StatusCreated, response)
+	}
+}
+
+func (a *NotificationsAPI) ServeEndpointIDRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["endpoint"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		if _, err := a.server.DatabaseStore().Notifications().DeleteEndpoint(session.UserID, int(rawID)); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// ServeWebPushKeyRequest gives browsers the applicationServerKey to subscribe with
+func (a *NotificationsAPI) ServeWebPushKeyRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	publicKey := a.server.WebPushPublicKey()
+	if publicKey == "" {
+		a.server.RespondError(w, r, http.StatusNotFound, exceptions.WebPushIsNotConfigured)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, map[string]string{"public_key": publicKey})
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
 	"github.com/gorilla/mux"
 	jsontime "github.com/liamylian/jsontime/v2/v2"
@@ -41,6 +42,11 @@ func (a *PetsAPI) ConfigureRouter(router *mux.Router) {
 		Methods(http.MethodGet).
 		HandlerFunc(a.ServeVeterinarianSearchRequest)
 
+	sb.Path("/veterinarian/availability").
+		Name("Veterinarian availability Request").
+		Methods(http.MethodGet, http.MethodPut).
+		HandlerFunc(a.ServeVeterinarianAvailabilityRequest)
+
 	sb.Path("/types").
 		Name("Pets types Root Request").
 		Methods(http.MethodGet, http.MethodPost).
@@ -66,6 +72,21 @@ func (a *PetsAPI) ConfigureRouter(router *mux.Router) {
 		Methods(http.MethodGet, http.MethodPost).
 		HandlerFunc(a.ServeReportRequest)
 
+	sb.Path("/{id:[0-9]+}/reports/{report:[0-9]+}").
+		Name("Pets health report Request").
+		Methods(http.MethodGet, http.MethodPut).
+		HandlerFunc(a.ServeHealthReportRequest)
+
+	sb.Path("/{id:[0-9]+}/reports/{report:[0-9]+}/sign").
+		Name("Pets health report signing Request").
+		Methods(http.MethodPost).
+		HandlerFunc(a.ServeHealthReportSignRequest)
+
+	sb.Path("/{id:[0-9]+}/reports/{report:[0-9]+}/versions").
+		Name("Pets health report versions Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeHealthReportVersionsRequest)
+
 	sb.Path("/{id:[0-9]+}/parents/verify/{parent:father|mother}").
 		Name("Pets parent verification request").
 		Methods(http.MethodPost).
@@ -76,6 +97,11 @@ func (a *PetsAPI) ConfigureRouter(router *mux.Router) {
 		Methods(http.MethodGet, http.MethodPost).
 		HandlerFunc(a.ServePetsVaccinesRequest)
 
+	sb.Path("/{id:[0-9]+}/vaccines/schedule").
+		Name("Pets vaccination schedule Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServePetsVaccineScheduleRequest)
+
 	sb.Path("/{id:[0-9]+}/vaccines/{vaccine:[0-9]+}").
 		Name("Pets vaccines Request").
 		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
@@ -110,6 +136,190 @@ func (a *PetsAPI) ConfigureRouter(router *mux.Router) {
 		Name("Pet this day statistic").
 		Methods(http.MethodGet).
 		HandlerFunc(a.ServeTodayStatisticRequest)
+
+	sb.Path("/{id:[0-9]+}/appointments").
+		Name("Pet appointments Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeAppointmentsRequest)
+
+	sb.Path("/{id:[0-9]+}/appointments/{appointment:[0-9]+}").
+		Name("Pet appointment Request").
+		Methods(http.MethodGet, http.MethodPut).
+		HandlerFunc(a.ServeAppointmentRequest)
+
+	sb.Path("/{id:[0-9]+}/devices").
+		Name("Pet IoT devices Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeDevicesRequest)
+
+	sb.Path("/{id:[0-9]+}/devices/config").
+		Name("Pet IoT devices config Request").
+		Methods(http.MethodPut).
+		HandlerFunc(a.ServeDevicesConfigRequest)
+
+	sb.Path("/{id:[0-9]+}/devices/commands").
+		Name("Pet IoT devices command Request").
+		Methods(http.MethodPost).
+		HandlerFunc(a.ServeDevicesCommandRequest)
+
+	sb.Path("/{id:[0-9]+}/devices/{device:[0-9]+}").
+		Name("Pet IoT device Request").
+		Methods(http.MethodGet, http.MethodPut).
+		HandlerFunc(a.ServeDeviceRequest)
+
+	sb.Path("/{id:[0-9]+}/devices/{device:[0-9]+}/{action:rotate-secret|unpair|revoke}").
+		Name("Pet IoT device lifecycle Request").
+		Methods(http.MethodPost).
+		HandlerFunc(a.ServeDeviceActionRequest)
+
+	sb.Path("/{id:[0-9]+}/walks").
+		Name("Pet walks Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeWalksRequest)
+
+	sb.Path("/{id:[0-9]+}/walks/{walk:[0-9]+}").
+		Name("Pet walk Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeWalkRequest)
+
+	sb.Path("/{id:[0-9]+}/geofences").
+		Name("Pet geofences Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeGeofencesRequest)
+
+	sb.Path("/{id:[0-9]+}/geofences/{geofence:[0-9]+}").
+		Name("Pet geofence Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeGeofenceRequest)
+
+	sb.Path("/{id:[0-9]+}/geofence-events").
+		Name("Pet geofence events Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeGeofenceEventsRequest)
+
+	sb.Path("/{id:[0-9]+}/alerts").
+		Name("Pet health alerts Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeAlertsRequest)
+
+	sb.Path("/{id:[0-9]+}/nutrition/plan").
+		Name("Pet nutrition plan Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeNutritionPlanRequest)
+
+	sb.Path("/{id:[0-9]+}/feeding-plans").
+		Name("Pet feeding plans Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeFeedingPlansRequest)
+
+	sb.Path("/{id:[0-9]+}/feeding-plans/{plan:[0-9]+}").
+		Name("Pet feeding plan Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeFeedingPlanRequest)
+
+	sb.Path("/{id:[0-9]+}/feeding-adherence").
+		Name("Pet feeding adherence Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeFeedingAdherenceRequest)
+
+	sb.Path("/{id:[0-9]+}/medications").
+		Name("Pet medications Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeMedicationsRequest)
+
+	sb.Path("/{id:[0-9]+}/medications/{medication:[0-9]+}").
+		Name("Pet medication Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeMedicationRequest)
+
+	sb.Path("/{id:[0-9]+}/medications/{medication:[0-9]+}/doses").
+		Name("Pet medication doses Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeMedicationDosesRequest)
+
+	sb.Path("/veterinarian/conditions").
+		Name("Veterinarian patients conditions Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeVeterinarianConditionsRequest)
+
+	sb.Path("/{id:[0-9]+}/problems").
+		Name("Pet problem list Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeProblemListRequest)
+
+	sb.Path("/{id:[0-9]+}/conditions").
+		Name("Pet conditions Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeConditionsRequest)
+
+	sb.Path("/{id:[0-9]+}/conditions/{condition:[0-9]+}").
+		Name("Pet condition Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeConditionRequest)
+
+	sb.Path("/{id:[0-9]+}/allergies").
+		Name("Pet allergies Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeAllergiesRequest)
+
+	sb.Path("/{id:[0-9]+}/allergies/{allergy:[0-9]+}").
+		Name("Pet allergy Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeAllergyRequest)
+
+	sb.Path("/{id:[0-9]+}/procedures").
+		Name("Pet procedures Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeProceduresRequest)
+
+	sb.Path("/{id:[0-9]+}/procedures/{procedure:[0-9]+}").
+		Name("Pet procedure Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeProcedureRequest)
+
+	sb.Path("/{id:[0-9]+}/lab-panels").
+		Name("Pet lab panels Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeLabPanelsRequest)
+
+	sb.Path("/{id:[0-9]+}/lab-panels/{panel:[0-9]+}").
+		Name("Pet lab panel Request").
+		Methods(http.MethodGet, http.MethodDelete).
+		HandlerFunc(a.ServeLabPanelRequest)
+
+	sb.Path("/{id:[0-9]+}/attachments").
+		Name("Pet attachments Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeAttachmentsRequest)
+
+	sb.Path("/{id:[0-9]+}/attachments/{attachment:[0-9]+}").
+		Name("Pet attachment Request").
+		Methods(http.MethodGet, http.MethodDelete).
+		HandlerFunc(a.ServeAttachmentRequest)
+
+	// Browsers load the images and documents without the access tokens,
+	// so the downloads are authorized by the expiring signature in their URLs
+	router.Path("/attachments/{attachment:[0-9]+}/{variant:original|thumbnail}").
+		Name("Attachment download Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeAttachmentDownloadRequest)
+
+	sb.Path("/{id:[0-9]+}/documents/summary").
+		Name("Pet medical summary document Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeSummaryDocumentRequest)
+
+	sb.Path("/{id:[0-9]+}/documents/certificate").
+		Name("Pet vaccination certificate document Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeCertificateDocumentRequest)
+
+	// The certificates are verified by anyone scanning their QR codes,
+	// so the verification is authorized by the signature in its URL
+	router.Path("/certificates/{pet:[0-9]+}").
+		Name("Vaccination certificate verification Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeCertificateVerificationRequest)
 }
 
 func (a *PetsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
@@ -133,6 +343,13 @@ func (a *PetsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 		FatherVerified bool            `json:"father_verified"`
 		Breed          string          `json:"breed,omitempty"`
 		FamilyName     string          `json:"family_name,omitempty"`
+
+		BirthDate          string `json:"birth_date,omitempty"`
+		Sex                string `json:"sex,omitempty"`
+		IsNeutered         bool   `json:"is_neutered"`
+		ActivityLevel      string `json:"activity_level,omitempty"`
+		Goal               string `json:"goal,omitempty"`
+		ReproductiveStatus string `json:"reproductive_status,omitempty"`
 	}
 	responsePetEntitiesBuilder := func(pets []models.Pet) ([]responsePetEntity, error) {
 		responseEntities := make([]responsePetEntity, len(pets), len(pets))
@@ -168,6 +385,13 @@ func (a *PetsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 				FatherVerified: pets[idx].FatherVerified,
 				Breed:          breed,
 				FamilyName:     familyName,
+
+				BirthDate:          pets[idx].SpecifiedBirthDate,
+				Sex:                pets[idx].SpecifiedSex,
+				IsNeutered:         pets[idx].IsNeutered,
+				ActivityLevel:      pets[idx].SpecifiedActivityLevel,
+				Goal:               pets[idx].SpecifiedGoal,
+				ReproductiveStatus: pets[idx].SpecifiedReproductiveStatus,
 			}
 		}
 		return responseEntities, nil
@@ -221,6 +445,13 @@ func (a *PetsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 			UserID     *int    `json:"owner_id"`
 			MotherID   *int    `json:"mother_id"`
 			FatherID   *int    `json:"father_id"`
+
+			BirthDate          *string `json:"birth_date"`
+			Sex                *string `json:"sex"`
+			IsNeutered         *bool   `json:"is_neutered"`
+			ActivityLevel      *string `json:"activity_level"`
+			Goal               *string `json:"goal"`
+			ReproductiveStatus *string `json:"reproductive_status"`
 		}
 		rb := &requestBody{}
 		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
@@ -241,6 +472,7 @@ func (a *PetsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 		newPetModel.SetSpecifiedFamilyName(rb.FamilyName)
 		newPetModel.SetSpecifiedMotherID(rb.MotherID)
 		newPetModel.SetSpecifiedFatherID(rb.FatherID)
+		newPetModel.SetNutritionProfile(rb.BirthDate, rb.Sex, rb.IsNeutered, rb.ActivityLevel, rb.Goal, rb.ReproductiveStatus)
 		newPetModel.BeforeCreate()
 
 		if err := newPetModel.Validate(); err != nil {
@@ -253,6 +485,7 @@ func (a *PetsAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
 			return
 		}
+		dispatchWebhook(a.server, requestID, models.WebhookPetCreated, newPetModel.PetID, newPetModel)
 		a.server.Respond(w, r, http.StatusCreated, newPetModel)
 	}
 }
@@ -284,6 +517,15 @@ func (a *PetsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		FatherVerified bool            `json:"father_verified"`
 		Breed          string          `json:"breed,omitempty"`
 		FamilyName     string          `json:"family_name,omitempty"`
+
+		BirthDate          string `json:"birth_date,omitempty"`
+		Sex                string `json:"sex,omitempty"`
+		IsNeutered         bool   `json:"is_neutered"`
+		ActivityLevel      string `json:"activity_level,omitempty"`
+		Goal               string `json:"goal,omitempty"`
+		ReproductiveStatus string `json:"reproductive_status,omitempty"`
+
+		Problems *models.ProblemList `json:"problems,omitempty"`
 	}
 
 	responsePetEntityBuilder := func(pet *models.Pet) (*responsePetEntity, error) {
@@ -318,6 +560,13 @@ func (a *PetsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 			FatherVerified: pet.FatherVerified,
 			Breed:          breed,
 			FamilyName:     familyName,
+
+			BirthDate:          pet.SpecifiedBirthDate,
+			Sex:                pet.SpecifiedSex,
+			IsNeutered:         pet.IsNeutered,
+			ActivityLevel:      pet.SpecifiedActivityLevel,
+			Goal:               pet.SpecifiedGoal,
+			ReproductiveStatus: pet.SpecifiedReproductiveStatus,
 		}
 		return responseEntity, nil
 	}
@@ -340,6 +589,14 @@ func (a *PetsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 			return
 		}
+		// The medical records are shown to the ones treating and following the pet only
+		if canFollowPet(session, petModel) {
+			if responseEntity.Problems, err = a.problemList(petModel.PetID); err != nil {
+				a.server.Logger().Printf("Database err: %v, Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+		}
 		a.server.Respond(w, r, http.StatusOK, responseEntity)
 
 	case http.MethodPut:
@@ -351,6 +608,13 @@ func (a *PetsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 			UserID     *int    `json:"owner_id"`
 			FatherID   *int    `json:"father_id"`
 			MotherID   *int    `json:"mother_id"`
+
+			BirthDate          *string `json:"birth_date"`
+			Sex                *string `json:"sex"`
+			IsNeutered         *bool   `json:"is_neutered"`
+			ActivityLevel      *string `json:"activity_level"`
+			Goal               *string `json:"goal"`
+			ReproductiveStatus *string `json:"reproductive_status"`
 		}
 		updatingPet, err := a.server.DatabaseStore().Pets().FindByID(requestedID)
 		if err != nil {
@@ -395,6 +659,7 @@ func (a *PetsAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		newPetModel.SetSpecifiedFamilyName(rb.FamilyName)
 		newPetModel.SetSpecifiedFatherID(rb.FatherID)
 		newPetModel.SetSpecifiedMotherID(rb.MotherID)
+		newPetModel.SetNutritionProfile(rb.BirthDate, rb.Sex, rb.IsNeutered, rb.ActivityLevel, rb.Goal, rb.ReproductiveStatus)
 		newPetModel.BeforeCreate()
 		if err := newPetModel.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -463,6 +728,7 @@ func (a *PetsAPI) ServeTypesRootRequest(w http.ResponseWriter, r *http.Request)
 		type requestBody struct {
 			TypeName       string  `json:"type_name"`
 			RERCoefficient float64 `json:"rer_coefficient"`
+			Species        string  `json:"species"`
 		}
 		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().PetsPermission) {
 			a.server.RespondError(w, r, http.StatusForbidden, nil)
@@ -476,6 +742,7 @@ func (a *PetsAPI) ServeTypesRootRequest(w http.ResponseWriter, r *http.Request)
 		newPetType := &models.PetType{
 			TypeName:       rb.TypeName,
 			RERCoefficient: rb.RERCoefficient,
+			Species:        rb.Species,
 		}
 		if err := newPetType.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -525,6 +792,7 @@ func (a *PetsAPI) ServeTypesIDRequest(w http.ResponseWriter, r *http.Request) {
 		type requestBody struct {
 			TypeName       string  `json:"type_name"`
 			RERCoefficient float64 `json:"rer_coefficient"`
+			Species        string  `json:"species"`
 		}
 		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().PetsPermission) {
 			a.server.RespondError(w, r, http.StatusForbidden, nil)
@@ -539,6 +807,7 @@ func (a *PetsAPI) ServeTypesIDRequest(w http.ResponseWriter, r *http.Request) {
 			TypeID:         requestedID,
 			TypeName:       rb.TypeName,
 			RERCoefficient: rb.RERCoefficient,
+			Species:        rb.Species,
 		}
 		if err := updatedPetType.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -648,6 +917,7 @@ func (a *PetsAPI) ServeVeterinarianRequest(w http.ResponseWriter, r *http.Reques
 			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 			return
 		}
+		a.notifyVeterinarianAssigned(requestID, petModel, rb.VeterinarianID, session.UserID)
 		a.server.Respond(w, r, http.StatusOK, nil)
 
 	case http.MethodDelete:
@@ -769,17 +1039,15 @@ func (a *PetsAPI) ServeReportRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		return
 	}
-	requestID, _, err := a.server.GetAuthorizedRequestInfo(r)
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
 	if err != nil {
 		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 		return
 	}
-	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
-	if err != nil {
-		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
 		return
 	}
-	requestedID := int(rawID)
 
 	switch r.Method {
 	case http.MethodGet:
@@ -802,7 +1070,7 @@ func (a *PetsAPI) ServeReportRequest(w http.ResponseWriter, r *http.Request) {
 			return responseEntities, nil
 		}
 
-		reportModels, err := a.server.DatabaseStore().Pets().GetAllPetHealthReports(requestedID)
+		reportModels, err := a.server.DatabaseStore().Pets().GetAllPetHealthReports(petModel.PetID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				a.server.RespondError(w, r, http.StatusNotFound, nil)
@@ -827,8 +1095,11 @@ func (a *PetsAPI) ServeReportRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusOK, responseModels)
 
 	case http.MethodPost:
+		// The report is written by the veterinarian of the session, it is a draft until it is signed
+		if !a.canTreat(w, r, session) {
+			return
+		}
 		type requestBody struct {
-			UserID     int     `json:"creator_id"`
 			Conclusion string  `json:"conclusion"`
 			Comments   *string `json:"comment"`
 		}
@@ -840,19 +1111,26 @@ func (a *PetsAPI) ServeReportRequest(w http.ResponseWriter, r *http.Request) {
 		}
 
 		commentModel := &models.PetHealthReport{
-			PetID:            requestedID,
+			PetID:            petModel.PetID,
 			ReportTimestamp:  time.Now(),
-			VeterinarianID:   rb.UserID,
+			VeterinarianID:   session.UserID,
 			ReportConclusion: rb.Conclusion,
 		}
 		commentModel.SetSpecifiedReportComments(rb.Comments)
+		if err := commentModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
 		commentModel.BeforeCreate()
 		if err := a.server.DatabaseStore().Pets().CreatePetHealthReport(commentModel); err != nil {
 			a.server.Logger().Printf("DATABASE Error: %v RequestID: %v", err, requestID)
 			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 			return
 		}
-		a.server.Respond(w, r, http.StatusCreated, nil)
+		a.notifyHealthReport(requestID, commentModel)
+		publishPetEvent(a.server, requestID, commentModel.PetID, realtime.EventHealthReportCreated, commentModel)
+		dispatchWebhook(a.server, requestID, models.WebhookReportCreated, commentModel.PetID, commentModel)
+		a.server.Respond(w, r, http.StatusCreated, commentModel)
 	}
 }
 
@@ -913,6 +1191,7 @@ func (a *PetsAPI) ServeParentsVerificationRequest(w http.ResponseWriter, r *http
 			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 			return
 		}
+		a.notifyParentVerified(requestID, petModel, fatherPet, "father")
 		a.server.Respond(w, r, http.StatusOK, nil)
 
 	case "mother":
@@ -943,6 +1222,7 @@ func (a *PetsAPI) ServeParentsVerificationRequest(w http.ResponseWriter, r *http
 			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 			return
 		}
+		a.notifyParentVerified(requestID, petModel, fatherPet, "mother")
 		a.server.Respond(w, r, http.StatusOK, nil)
 	}
 }
@@ -990,6 +1270,7 @@ func (a *PetsAPI) ServePetsVaccinesRequest(w http.ResponseWriter, r *http.Reques
 
 	case http.MethodPost:
 		type requestBody struct {
+			CatalogueID        *int      `json:"catalogue_id"`
 			VaccineName        string    `json:"name"`
 			VaccinationDate    time.Time `json:"vaccination_date" time_format:"date"`
 			VaccineDescription *string   `json:"vaccine_description"`
@@ -1016,6 +1297,10 @@ func (a *PetsAPI) ServePetsVaccinesRequest(w http.ResponseWriter, r *http.Reques
 			VaccinationDate: rb.VaccinationDate,
 		}
 		vaccineModel.SetSpecifiedDescription(rb.VaccineDescription)
+		vaccineModel.SetSpecifiedCatalogueID(rb.CatalogueID)
+		if !a.applyVaccineCatalogue(w, r, requestID, petModel, vaccineModel) {
+			return
+		}
 		vaccineModel.BeforeCreate()
 		if err := vaccineModel.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -1027,6 +1312,7 @@ func (a *PetsAPI) ServePetsVaccinesRequest(w http.ResponseWriter, r *http.Reques
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
 			return
 		}
+		dispatchWebhook(a.server, requestID, models.WebhookVaccineAdded, vaccineModel.PetID, vaccineModel)
 		a.server.Respond(w, r, http.StatusCreated, vaccineModel)
 	}
 }
@@ -1080,9 +1366,10 @@ func (a *PetsAPI) ServePetsVaccineRequest(w http.ResponseWriter, r *http.Request
 
 	case http.MethodPut:
 		type requestBody struct {
-			VaccineName        string    `json:"name"`
-			VaccinationDate    time.Time `json:"vaccination_date" time_format:"date"`
-			VaccineDescription *string   `json:"vaccine_description"`
+			CatalogueID        models.NullableID `json:"catalogue_id"`
+			VaccineName        string            `json:"name"`
+			VaccinationDate    time.Time         `json:"vaccination_date" time_format:"date"`
+			VaccineDescription *string           `json:"vaccine_description"`
 		}
 		if petModel.VeterinarianID == nil ||
 			!petModel.VeterinarianID.Valid ||
@@ -1101,13 +1388,32 @@ func (a *PetsAPI) ServePetsVaccineRequest(w http.ResponseWriter, r *http.Request
 			a.server.RespondError(w, r, http.StatusBadRequest, err)
 			return
 		}
+		currentModel, err := a.server.DatabaseStore().Vaccines().FindByID(requestedVaccineID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		if currentModel.PetID != petModel.PetID {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		// The catalogue link is kept unless it is specified, the explicit null unlinks the record
 		vaccineModel := &models.Vaccine{
-			VaccineID:       requestedVaccineID,
-			PetID:           petModel.PetID,
-			Name:            rb.VaccineName,
-			VaccinationDate: rb.VaccinationDate,
+			VaccineID:            requestedVaccineID,
+			PetID:                petModel.PetID,
+			Name:                 rb.VaccineName,
+			VaccinationDate:      rb.VaccinationDate,
+			SpecifiedCatalogueID: rb.CatalogueID.Apply(currentModel.SpecifiedCatalogueID),
 		}
 		vaccineModel.SetSpecifiedDescription(rb.VaccineDescription)
+		if !a.applyVaccineCatalogue(w, r, requestID, petModel, vaccineModel) {
+			return
+		}
 		vaccineModel.BeforeCreate()
 		if err := vaccineModel.Validate(); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
@@ -1492,6 +1798,8 @@ func (a *PetsAPI) ServePetActivityRequest(w http.ResponseWriter, r *http.Request
 			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 			return
 		}
+		publishPetEvent(a.server, requestID, requestedPetID, realtime.EventActivityCreated, model)
+		dispatchWebhook(a.server, requestID, models.WebhookActivityRecorded, requestedPetID, model)
 		a.server.Respond(w, r, http.StatusCreated, nil)
 	}
 }
@@ -1514,6 +1822,9 @@ func (a *PetsAPI) ServeEatingRequest(w http.ResponseWriter, r *http.Request) {
 
 	type eatingsResponseEntity struct {
 		PortionWeight float64      `json:"portion_weight"`
+		PortionAmount float64      `json:"portion_amount"`
+		PortionUnit   string       `json:"portion_unit"`
+		Energy        float64      `json:"energy"`
 		EatingTime    time.Time    `json:"eating_time"`
 		Food          *models.Food `json:"food"`
 	}
@@ -1527,6 +1838,9 @@ func (a *PetsAPI) ServeEatingRequest(w http.ResponseWriter, r *http.Request) {
 			}
 			responseModels[idx] = eatingsResponseEntity{
 				PortionWeight: eatingModels[idx].PortionWeight,
+				PortionAmount: eatingModels[idx].PortionAmount,
+				PortionUnit:   eatingModels[idx].PortionUnit,
+				Energy:        foodModel.Energy(eatingModels[idx].PortionWeight),
 				EatingTime:    eatingModels[idx].Time,
 				Food:          foodModel,
 			}
@@ -1559,8 +1873,11 @@ func (a *PetsAPI) ServeEatingRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusOK, responseModels)
 
 	case http.MethodPost:
+		// The portion is the amount of the unit, grams by default, portion_weight is the amount in grams
 		type requestBody struct {
 			FoodID        int     `json:"food_id"`
+			PortionAmount float64 `json:"portion_amount"`
+			PortionUnit   string  `json:"portion_unit"`
 			PortionWeight float64 `json:"portion_weight"`
 		}
 		rb := &requestBody{}
@@ -1568,16 +1885,39 @@ func (a *PetsAPI) ServeEatingRequest(w http.ResponseWriter, r *http.Request) {
 			a.server.RespondError(w, r, http.StatusBadRequest, err)
 			return
 		}
+		if rb.PortionAmount == 0 && rb.PortionUnit == "" {
+			rb.PortionAmount = rb.PortionWeight
+		}
+		if rb.PortionUnit == "" {
+			rb.PortionUnit = models.UnitGram
+		}
+		foodModel, err := a.server.DatabaseStore().Foods().FindByID(rb.FoodID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.FoodNotFound)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
 		eatingModel := &models.Eating{
-			PetID:         requestedPetID,
-			FoodID:        rb.FoodID,
-			Time:          time.Now(),
-			PortionWeight: rb.PortionWeight,
+			PetID: requestedPetID,
+			Time:  time.Now(),
+		}
+		if err := eatingModel.SetPortion(foodModel, rb.PortionAmount, rb.PortionUnit); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if err := eatingModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
 		}
 		if err := a.server.DatabaseStore().Foods().AddPetEating(eatingModel); err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
 			return
 		}
+		publishPetEvent(a.server, requestID, requestedPetID, realtime.EventEatingCreated, eatingModel)
 		a.server.Respond(w, r, http.StatusCreated, nil)
 	}
 }
@@ -1665,6 +2005,8 @@ func (a *PetsAPI) ServeVeterinarianSearchRequest(w http.ResponseWriter, r *http.
 		FatherVerified bool            `json:"father_verified"`
 		Breed          string          `json:"breed,omitempty"`
 		FamilyName     string          `json:"family_name,omitempty"`
+		// Alerts are the open health alerts, critical first
+		Alerts []models.HealthAlert `json:"alerts"`
 	}
 
 	if r.Method == http.MethodOptions {
@@ -1672,6 +2014,19 @@ func (a *PetsAPI) ServeVeterinarianSearchRequest(w http.ResponseWriter, r *http.
 	}
 
 	responsePetEntitiesBuilder := func(pets []models.Pet) ([]responsePetEntity, error) {
+		petIDs := make([]int, len(pets))
+		for idx := range pets {
+			petIDs[idx] = pets[idx].PetID
+		}
+		openAlerts, err := a.server.DatabaseStore().HealthAlerts().SelectOpenByPetIDs(petIDs)
+		if err != nil {
+			return nil, err
+		}
+		petAlerts := make(map[int][]models.HealthAlert, len(pets))
+		for _, alert := range openAlerts {
+			petAlerts[alert.PetID] = append(petAlerts[alert.PetID], alert)
+		}
+
 		responseEntities := make([]responsePetEntity, len(pets), len(pets))
 		for idx := range pets {
 			owner, err := a.server.DatabaseStore().Users().FindByID(pets[idx].UserID)
@@ -1705,6 +2060,10 @@ func (a *PetsAPI) ServeVeterinarianSearchRequest(w http.ResponseWriter, r *http.
 				FatherVerified: pets[idx].FatherVerified,
 				Breed:          breed,
 				FamilyName:     familyName,
+				Alerts:         petAlerts[pets[idx].PetID],
+			}
+			if responseEntities[idx].Alerts == nil {
+				responseEntities[idx].Alerts = make([]models.HealthAlert, 0)
 			}
 		}
 		return responseEntities, nil
@@ -1715,6 +2074,47 @@ func (a *PetsAPI) ServeVeterinarianSearchRequest(w http.ResponseWriter, r *http.
 		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
 		return
 	}
+
+	// With date query parameter the veterinarian gets the appointments of that day instead of patients list
+	if date := r.URL.Query().Get("date"); date != "" {
+		type responseAppointmentEntity struct {
+			Appointment *models.Appointment `json:"appointment"`
+			Pet         *responsePetEntity  `json:"pet"`
+		}
+		day, err := time.ParseInLocation("2006-01-02", date, time.Local)
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+			return
+		}
+		appointments, err := a.server.DatabaseStore().Appointments().SelectByVeterinarianInInterval(session.UserID, day, day.AddDate(0, 0, 1))
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v RequestID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		responseEntities := make([]responseAppointmentEntity, len(appointments), len(appointments))
+		for idx := range appointments {
+			pet, err := a.server.DatabaseStore().Pets().FindByID(appointments[idx].PetID)
+			if err != nil {
+				a.server.Logger().Printf("Database err: %v, Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			petEntities, err := responsePetEntitiesBuilder([]models.Pet{*pet})
+			if err != nil {
+				a.server.Logger().Printf("Database err: %v, Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			responseEntities[idx] = responseAppointmentEntity{
+				Appointment: &appointments[idx],
+				Pet:         &petEntities[0],
+			}
+		}
+		a.server.Respond(w, r, http.StatusOK, responseEntities)
+		return
+	}
+
 	subscribedPets, err := a.server.DatabaseStore().Pets().SelectByVeterinarianID(session.UserID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+)
+
+// ServeAlertsRequest returns the health alerts of the pet, newest first. Resolved alerts are returned with ?resolved=true.
+func (a *PetsAPI) ServeAlertsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		withResolved := r.URL.Query().Get("resolved") == "true"
+		alerts, err := a.server.DatabaseStore().HealthAlerts().SelectByPetID(petModel.PetID, withResolved)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, alerts)
+	}
+}
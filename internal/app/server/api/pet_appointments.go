@@ -0,0 +1,283 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// exclusionViolation is the postgres error code raised by the appointments_no_double_booking constraint
+const exclusionViolation = "23P01"
+
+func (a *PetsAPI) ServeAppointmentsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedID := int(rawID)
+
+	petModel, err := a.server.DatabaseStore().Pets().FindByID(requestedID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database err: %v, Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		isAttendingVeterinarian := petModel.VeterinarianID != nil &&
+			petModel.VeterinarianID.Valid &&
+			int(petModel.VeterinarianID.Int64) == session.UserID
+		if petModel.UserID != session.UserID && !isAttendingVeterinarian {
+			if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().UsersPermission) {
+				a.server.RespondError(w, r, http.StatusForbidden, nil)
+				return
+			}
+		}
+		appointments, err := a.server.DatabaseStore().Appointments().SelectByPetID(requestedID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, appointments)
+
+	case http.MethodPost:
+		type requestBody struct {
+			VeterinarianID int       `json:"veterinarian_id"`
+			ClinicID       *int      `json:"clinic_id"`
+			StartsAt       time.Time `json:"starts_at"`
+			EndsAt         time.Time `json:"ends_at"`
+			Reason         string    `json:"reason"`
+		}
+		if petModel.UserID != session.UserID {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		appointmentModel := &models.Appointment{
+			PetID:          requestedID,
+			OwnerID:        petModel.UserID,
+			VeterinarianID: rb.VeterinarianID,
+			StartsAt:       rb.StartsAt,
+			EndsAt:         rb.EndsAt,
+			Reason:         rb.Reason,
+			Status:         models.AppointmentRequested,
+		}
+		appointmentModel.SetSpecifiedClinicID(rb.ClinicID)
+		if err := appointmentModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if !appointmentModel.StartsAt.After(time.Now()) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.AppointmentInPast)
+			return
+		}
+
+		userRoles, err := a.server.DatabaseStore().Roles().SelectUserRoles(rb.VeterinarianID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		if !permissions.AnyRoleIsVeterinarian(userRoles) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.UserIsNotVeterinarian)
+			return
+		}
+		if appointmentModel.SpecifiedClinicID != 0 {
+			member, err := a.server.DatabaseStore().Clinics().FindMember(appointmentModel.SpecifiedClinicID, rb.VeterinarianID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				a.server.Logger().Printf("Database error: %v Request ID %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			if member == nil || !member.IsVeterinarian() {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.UserIsNotClinicMember)
+				return
+			}
+		}
+		availability, err := a.server.DatabaseStore().Appointments().SelectAvailability(rb.VeterinarianID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		// The availability hours are kept in the server time zone
+		if !models.AnyAvailabilityCovers(availability, appointmentModel.StartsAt, appointmentModel.EndsAt, time.Local) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.VeterinarianIsNotAvailable)
+			return
+		}
+
+		appointmentModel, err = a.server.DatabaseStore().Appointments().Create(appointmentModel)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == exclusionViolation {
+				a.server.RespondError(w, r, http.StatusConflict, exceptions.AppointmentSlotIsTaken)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, appointmentModel)
+	}
+}
+
+func (a *PetsAPI) ServeAppointmentRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedPetID := int(rawID)
+	rawID, err = strconv.ParseInt(mux.Vars(r)["appointment"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedAppointmentID := int(rawID)
+
+	appointmentModel, err := a.server.DatabaseStore().Appointments().FindByID(requestedAppointmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if appointmentModel.PetID != requestedPetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+	isOwner := appointmentModel.OwnerID == session.UserID
+	isVeterinarian := appointmentModel.VeterinarianID == session.UserID
+	if !isOwner && !isVeterinarian {
+		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().UsersPermission) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, appointmentModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			Status string `json:"status"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		// Owners may only cancel their bookings, the rest of workflow belongs to veterinarian
+		if !isVeterinarian && rb.Status != models.AppointmentCancelled {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		if !appointmentModel.CanTransitionTo(rb.Status) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.AppointmentStatusTransition)
+			return
+		}
+		appointmentModel, err = a.server.DatabaseStore().Appointments().UpdateStatus(requestedAppointmentID, rb.Status)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, appointmentModel)
+	}
+}
+
+// ServeVeterinarianAvailabilityRequest manages the weekly schedule of the current veterinarian.
+// Any user may read the schedule of the specified veterinarian with veterinarian_id query parameter.
+func (a *PetsAPI) ServeVeterinarianAvailabilityRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		veterinarianID := session.UserID
+		if rawID := r.URL.Query().Get("veterinarian_id"); rawID != "" {
+			parsedID, err := strconv.ParseInt(rawID, 10, 64)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			veterinarianID = int(parsedID)
+		}
+		availability, err := a.server.DatabaseStore().Appointments().SelectAvailability(veterinarianID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, availability)
+
+	case http.MethodPut:
+		if !permissions.AnyRoleIsVeterinarian(session.Roles) {
+			a.server.RespondError(w, r, http.StatusForbidden, exceptions.UserIsNotVeterinarian)
+			return
+		}
+		var rb []models.VeterinarianAvailability
+		if err := json.NewDecoder(r.Body).Decode(&rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		for idx := range rb {
+			if err := rb[idx].Validate(); err != nil {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+		availability, err := a.server.DatabaseStore().Appointments().ReplaceAvailability(session.UserID, rb)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, availability)
+	}
+}
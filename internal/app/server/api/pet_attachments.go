@@ -0,0 +1,363 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/attachments"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/blob"
+	"github.com/gorilla/mux"
+	"github.com/twinj/uuid"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// attachmentURLLifetime is how long the signed download URLs are valid
+	attachmentURLLifetime = 15 * time.Minute
+	// attachmentFormMemory is how much of the upload is kept in memory, the rest is written to the temporary file
+	attachmentFormMemory = 8 << 20
+	// attachmentFormOverhead is the room for the multipart headers and the other form fields
+	attachmentFormOverhead = 1 << 20
+	maxAttachmentFileName  = 255
+)
+
+/*
+ServeAttachmentsRequest lists the pet attachments, the ones of the health report only with report_id,
+and uploads new ones as the file field of the multipart form. The pet followers may attach the files
+to the pet, only the veterinarians may attach them to its health reports.
+*/
+func (a *PetsAPI) ServeAttachmentsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		reportID := 0
+		if rawReportID := r.URL.Query().Get("report_id"); rawReportID != "" {
+			if reportID, err = strconv.Atoi(rawReportID); err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		}
+		petAttachments, err := a.server.DatabaseStore().Attachments().SelectByPetID(petModel.PetID, reportID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		for idx := range petAttachments {
+			signAttachmentURLs(r, &petAttachments[idx])
+		}
+		a.server.Respond(w, r, http.StatusOK, petAttachments)
+
+	case http.MethodPost:
+		attachment, ok := a.uploadAttachment(w, r, requestID, session, petModel)
+		if !ok {
+			return
+		}
+		signAttachmentURLs(r, attachment)
+		a.server.Respond(w, r, http.StatusCreated, attachment)
+	}
+}
+
+// ServeAttachmentRequest returns the pet attachment with its download URLs and deletes it with its contents
+func (a *PetsAPI) ServeAttachmentRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["attachment"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	attachment, err := a.server.DatabaseStore().Attachments().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if attachment.PetID != petModel.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		signAttachmentURLs(r, attachment)
+		a.server.Respond(w, r, http.StatusOK, attachment)
+
+	case http.MethodDelete:
+		// The files of the health reports are the medical records, the owner may delete only the pet ones
+		if attachment.SpecifiedReportID != 0 {
+			if !a.canTreat(w, r, session) {
+				return
+			}
+		} else if attachment.UploadedBy != session.UserID && petModel.UserID != session.UserID {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		attachment, err = a.server.DatabaseStore().Attachments().DeleteByID(attachment.AttachmentID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.deleteBlobs(r, requestID, attachment.Key(models.AttachmentOriginal), attachment.Key(models.AttachmentThumbnail))
+		a.server.Respond(w, r, http.StatusOK, attachment)
+	}
+}
+
+// ServeAttachmentDownloadRequest serves the attachment content, the request is authorized by the signature in its URL
+func (a *PetsAPI) ServeAttachmentDownloadRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["attachment"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, nil)
+		return
+	}
+	variant := mux.Vars(r)["variant"]
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || !auth.VerifyAttachment(int(rawID), variant, expires, query.Get("signature"), time.Now()) {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	attachment, err := a.server.DatabaseStore().Attachments().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v", err)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	key := attachment.Key(variant)
+	if key == "" {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+	content, err := a.server.BlobStore().Open(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Blob store error: %v", err)
+		a.server.RespondError(w, r, http.StatusBadGateway, nil)
+		return
+	}
+	defer func() {
+		_ = content.Close()
+	}()
+
+	contentType, disposition := attachments.ThumbnailType, "inline"
+	if variant == models.AttachmentOriginal {
+		contentType = attachment.ContentType
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+		if !attachments.IsInline(contentType) {
+			disposition = "attachment"
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	if contentDisposition := mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}); contentDisposition != "" {
+		disposition = contentDisposition
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", expires-time.Now().Unix()))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, content); err != nil {
+		a.server.Logger().Printf("Attachment writing error: %v", err)
+	}
+}
+
+/*
+uploadAttachment stores the uploaded file with the thumbnail of the image in the blob store and records it.
+The type is sniffed from the content and checked against its size limit, the declared one is ignored.
+*/
+func (a *PetsAPI) uploadAttachment(w http.ResponseWriter, r *http.Request, requestID string, session *sessions.Session, pet *models.Pet) (*models.Attachment, bool) {
+	maxRequestSize := int64(attachments.MaxDocumentSize + attachmentFormOverhead)
+	if r.ContentLength > maxRequestSize {
+		a.server.RespondError(w, r, http.StatusRequestEntityTooLarge, exceptions.AttachmentIsTooLarge)
+		return nil, false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+	if err := r.ParseMultipartForm(attachmentFormMemory); err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.AttachmentFileNotFoundInRequest)
+		return nil, false
+	}
+	defer func() {
+		_ = r.MultipartForm.RemoveAll()
+	}()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.AttachmentFileNotFoundInRequest)
+		return nil, false
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	attachment := &models.Attachment{
+		PetID:      pet.PetID,
+		UploadedBy: session.UserID,
+		FileName:   attachmentFileName(header.Filename),
+		Size:       header.Size,
+		StorageKey: fmt.Sprintf("pets/%d/%s", pet.PetID, uuid.NewV4().String()),
+	}
+	if rawReportID := r.FormValue("report_id"); rawReportID != "" {
+		if attachment.SpecifiedReportID, err = strconv.Atoi(rawReportID); err != nil || attachment.SpecifiedReportID <= 0 {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.HealthReportNotFound)
+			return nil, false
+		}
+		if !a.canTreat(w, r, session) || !a.validateHealthReport(w, r, requestID, pet.PetID, attachment.SpecifiedReportID) {
+			return nil, false
+		}
+	}
+
+	head := make([]byte, attachments.SniffLength)
+	read, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		a.server.RespondError(w, r, http.StatusBadRequest, err)
+		return nil, false
+	}
+	attachment.ContentType, err = attachments.Sniff(head[:read], attachment.Size)
+	if err != nil {
+		if errors.Is(err, attachments.ErrTooLarge) {
+			a.server.RespondError(w, r, http.StatusRequestEntityTooLarge, exceptions.AttachmentIsTooLarge)
+			return nil, false
+		}
+		a.server.RespondError(w, r, http.StatusUnsupportedMediaType, exceptions.AttachmentTypeIsNotSupported)
+		return nil, false
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if err := a.server.BlobStore().Put(r.Context(), attachment.StorageKey, file, attachment.Size, attachment.ContentType); err != nil {
+		a.server.Logger().Printf("Blob store error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusBadGateway, nil)
+		return nil, false
+	}
+	if attachments.HasThumbnail(attachment.ContentType) {
+		a.storeThumbnail(r, requestID, attachment, file)
+	}
+
+	created, err := a.server.DatabaseStore().Attachments().Create(attachment)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.deleteBlobs(r, requestID, attachment.Key(models.AttachmentOriginal), attachment.Key(models.AttachmentThumbnail))
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	return created, true
+}
+
+// storeThumbnail adds the thumbnail to the image attachment, the image is kept without it when it can not be made
+func (a *PetsAPI) storeThumbnail(r *http.Request, requestID string, attachment *models.Attachment, file io.ReadSeeker) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		a.server.Logger().Printf("Thumbnail error: %v Request ID: %v", err, requestID)
+		return
+	}
+	thumbnail, err := attachments.Thumbnail(file)
+	if err != nil {
+		a.server.Logger().Printf("Thumbnail error: %v Request ID: %v", err, requestID)
+		return
+	}
+	thumbnailKey := attachment.StorageKey + "-" + models.AttachmentThumbnail
+	if err := a.server.BlobStore().Put(r.Context(), thumbnailKey, bytes.NewReader(thumbnail), int64(len(thumbnail)), attachments.ThumbnailType); err != nil {
+		a.server.Logger().Printf("Blob store error: %v Request ID: %v", err, requestID)
+		return
+	}
+	attachment.ThumbnailKey = &sql.NullString{String: thumbnailKey, Valid: true}
+}
+
+// deleteBlobs removes the contents of the deleted attachment, the failures leave orphaned blobs and are only logged
+func (a *PetsAPI) deleteBlobs(r *http.Request, requestID string, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := a.server.BlobStore().Delete(r.Context(), key); err != nil {
+			a.server.Logger().Printf("Blob store error: %v Request ID: %v", err, requestID)
+		}
+	}
+}
+
+// signAttachmentURLs sets the download URLs of the attachment variants which expire after the URL lifetime
+func signAttachmentURLs(r *http.Request, attachment *models.Attachment) {
+	expires := time.Now().Add(attachmentURLLifetime).Unix()
+	signedURL := func(variant string) string {
+		return fmt.Sprintf(
+			"%s/attachments/%d/%s?expires=%d&signature=%s",
+			requestBaseURL(r),
+			attachment.AttachmentID,
+			variant,
+			expires,
+			auth.SignAttachment(attachment.AttachmentID, variant, expires),
+		)
+	}
+	attachment.URL = signedURL(models.AttachmentOriginal)
+	if attachment.HasThumbnail() {
+		attachment.ThumbnailURL = signedURL(models.AttachmentThumbnail)
+	}
+}
+
+// attachmentFileName keeps the base name of the uploaded file, which is shown to the users and used for downloads
+func attachmentFileName(fileName string) string {
+	fileName = path.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	fileName = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, fileName)
+	for len(fileName) > maxAttachmentFileName {
+		_, size := utf8.DecodeLastRuneInString(fileName)
+		fileName = fileName[:len(fileName)-size]
+	}
+	if fileName == "" || fileName == "." || fileName == "/" {
+		return "attachment"
+	}
+	return fileName
+}
@@ -0,0 +1,352 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/gateway"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// deviceWithSecret is the response carrying the access secret, it is shown only once
+type deviceWithSecret struct {
+	*models.IoTDevice
+	AccessSecret string `json:"access_secret"`
+}
+
+// ServeDevicesRequest lists the pet devices and registers new ones. Posting device_id of the owner's
+// unpaired device pairs it to the pet instead of registering a new one.
+func (a *PetsAPI) ServeDevicesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		devices, err := a.server.DatabaseStore().IoTDevicesRepository().SelectByPetID(petModel.PetID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, devices)
+
+	case http.MethodPost:
+		type requestBody struct {
+			Name     string `json:"name"`
+			DeviceID *int   `json:"device_id"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		secret, secretHash, err := auth.NewIoTDeviceSecret()
+		if err != nil {
+			a.server.Logger().Printf("Device secret error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+
+		if rb.DeviceID == nil {
+			deviceModel := &models.IoTDevice{
+				PetID:        petModel.PetID,
+				UserID:       petModel.UserID,
+				AccessSecret: secretHash,
+				Name:         rb.Name,
+				Status:       models.IoTDeviceActive,
+			}
+			if err := deviceModel.Validate(); err != nil {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+				return
+			}
+			deviceModel, err = a.server.DatabaseStore().IoTDevicesRepository().Create(deviceModel)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			a.server.Respond(w, r, http.StatusCreated, deviceWithSecret{IoTDevice: deviceModel, AccessSecret: secret})
+			return
+		}
+
+		deviceModel, err := a.server.DatabaseStore().IoTDevicesRepository().GetByID(*rb.DeviceID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		if deviceModel.UserID != petModel.UserID {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		if err := deviceModel.Pair(petModel.PetID, secretHash); err != nil {
+			a.server.RespondError(w, r, http.StatusConflict, err)
+			return
+		}
+		if rb.Name != "" {
+			deviceModel.Name = rb.Name
+		}
+		if err := deviceModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		deviceModel, err = a.server.DatabaseStore().IoTDevicesRepository().Update(deviceModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, deviceWithSecret{IoTDevice: deviceModel, AccessSecret: secret})
+	}
+}
+
+func (a *PetsAPI) ServeDeviceRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	deviceModel, ok := a.findPetDevice(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, deviceModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			Name string `json:"name"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if err := deviceModel.Rename(rb.Name); err != nil {
+			a.server.RespondError(w, r, http.StatusConflict, err)
+			return
+		}
+		if err := deviceModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		deviceModel, err = a.server.DatabaseStore().IoTDevicesRepository().Update(deviceModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, deviceModel)
+	}
+}
+
+// ServeDeviceActionRequest rotates the device secret, unpairs the device from the pet or revokes it.
+// Unpairing and revoking replace the secret, so the device can not log in with the old one.
+func (a *PetsAPI) ServeDeviceActionRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	deviceModel, ok := a.findPetDevice(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		secret, secretHash, err := auth.NewIoTDeviceSecret()
+		if err != nil {
+			a.server.Logger().Printf("Device secret error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		action := mux.Vars(r)["action"]
+		switch action {
+		case "rotate-secret":
+			err = deviceModel.RotateSecret(secretHash)
+		case "unpair":
+			err = deviceModel.Unpair(secretHash)
+		case "revoke":
+			err = deviceModel.Revoke(secretHash, time.Now())
+		}
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusConflict, err)
+			return
+		}
+		deviceModel, err = a.server.DatabaseStore().IoTDevicesRepository().Update(deviceModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		// The collar connected over MQTT with the old secret is dropped
+		if err := a.server.DeviceGateway().Disconnect(deviceModel.DeviceID); err != nil {
+			a.server.Logger().Printf("MQTT gateway error: %v Request ID: %v", err, requestID)
+		}
+		if action == "rotate-secret" {
+			a.server.Respond(w, r, http.StatusOK, deviceWithSecret{IoTDevice: deviceModel, AccessSecret: secret})
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, deviceModel)
+	}
+}
+
+// ServeDevicesConfigRequest sends the config to the collars of the pet over MQTT,
+// the config is any JSON object the collar firmware understands
+func (a *PetsAPI) ServeDevicesConfigRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		config := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		rawConfig, err := json.Marshal(config)
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if err := a.server.DeviceGateway().PublishConfig(petModel.PetID, rawConfig); err != nil {
+			a.server.Logger().Printf("MQTT gateway error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusAccepted, config)
+	}
+}
+
+// ServeDevicesCommandRequest sends the command to the collars of the pet which are connected over MQTT
+func (a *PetsAPI) ServeDevicesCommandRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		command := &gateway.Command{}
+		if err := json.NewDecoder(r.Body).Decode(command); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if err := command.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		command.IssuedAt = time.Now()
+		if err := a.server.DeviceGateway().SendCommand(petModel.PetID, command); err != nil {
+			a.server.Logger().Printf("MQTT gateway error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusAccepted, command)
+	}
+}
+
+// findDevicesPet responds with the error unless the user is the pet owner or manages pets
+func (a *PetsAPI) findDevicesPet(w http.ResponseWriter, r *http.Request, requestID string, session *sessions.Session) (*models.Pet, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	petModel, err := a.server.DatabaseStore().Pets().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database err: %v, Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if petModel.UserID != session.UserID &&
+		!permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().PetsPermission) {
+		a.server.RespondError(w, r, http.StatusForbidden, nil)
+		return nil, false
+	}
+	return petModel, true
+}
+
+func (a *PetsAPI) findPetDevice(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet) (*models.IoTDevice, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["device"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	deviceModel, err := a.server.DatabaseStore().IoTDevicesRepository().GetByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if deviceModel.PetID != pet.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return deviceModel, true
+}
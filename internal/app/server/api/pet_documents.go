@@ -0,0 +1,317 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/configs"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/documents"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/gorilla/mux"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ServeSummaryDocumentRequest renders the PDF medical summary of the pet for its followers
+func (a *PetsAPI) ServeSummaryDocumentRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	summary := &documents.Summary{IssuedAt: now}
+	if err := a.collectSummary(summary, petModel, now); err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	content, err := documents.RenderSummary(summary)
+	if err != nil {
+		a.server.Logger().Printf("Document rendering error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.respondDocument(w, fmt.Sprintf("%s-medical-summary.pdf", petModel.Name), content)
+}
+
+/*
+ServeCertificateDocumentRequest renders the PDF vaccination certificate of the pet issued by its veterinarian
+with the clinic the veterinarian works at. Only the veterinarian issues it, as the signed certificate vouches for
+the vaccinations listed, the owners may have recorded some of them. The QR code points at its public verification.
+*/
+func (a *PetsAPI) ServeCertificateDocumentRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	if petModel.SpecifiedVeterinarianID == 0 {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.PetHasNoVeterinarian)
+		return
+	}
+	if petModel.SpecifiedVeterinarianID != session.UserID {
+		a.server.RespondError(w, r, http.StatusForbidden, exceptions.CertificateIsNotVeterinarians)
+		return
+	}
+	clinic, err := a.server.DatabaseStore().Users().SelectClinicByUserID(petModel.SpecifiedVeterinarianID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.VeterinarianHasNoClinic)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	// The signature covers the whole seconds only, so the issue time is kept without the fraction
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	verificationURL, err := certificateVerificationURL(petModel.PetID, petModel.SpecifiedVeterinarianID, issuedAt)
+	if err != nil {
+		a.server.Logger().Printf("Certificate signing error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	certificate := &documents.Certificate{
+		Clinic:          clinic,
+		VerificationURL: verificationURL,
+		IssuedAt:        issuedAt,
+	}
+	if err := a.collectCertificate(certificate, petModel, petModel.SpecifiedVeterinarianID, issuedAt); err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	content, err := documents.RenderCertificate(certificate)
+	if err != nil {
+		a.server.Logger().Printf("Document rendering error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.respondDocument(w, fmt.Sprintf("%s-vaccination-certificate.pdf", petModel.Name), content)
+}
+
+/*
+ServeCertificateVerificationRequest confirms the vaccination certificate scanned by its QR code, the request is
+authorized by the signature in its URL. The vaccinations are the ones recorded by the time the certificate was
+issued, so the forged certificate can be told by them. The owner is not disclosed to the public.
+*/
+func (a *PetsAPI) ServeCertificateVerificationRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	petID, err := strconv.Atoi(mux.Vars(r)["pet"])
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, nil)
+		return
+	}
+	query := r.URL.Query()
+	veterinarianID, vetErr := strconv.Atoi(query.Get("veterinarian"))
+	issued, issuedErr := strconv.ParseInt(query.Get("issued"), 10, 64)
+	if vetErr != nil || issuedErr != nil || !auth.VerifyCertificate(petID, veterinarianID, issued, query.Get("signature")) {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	petModel, err := a.server.DatabaseStore().Pets().FindByID(petID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v", err)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	issuedAt := time.Unix(issued, 0).UTC()
+	certificate := &documents.Certificate{IssuedAt: issuedAt}
+	if err := a.collectCertificate(certificate, petModel, veterinarianID, issuedAt); err != nil {
+		a.server.Logger().Printf("Database error: %v", err)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	clinic, err := a.server.DatabaseStore().Users().SelectClinicByUserID(veterinarianID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		a.server.Logger().Printf("Database error: %v", err)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	type responsePetEntity struct {
+		Name       string `json:"name"`
+		PetType    string `json:"pet_type,omitempty"`
+		Breed      string `json:"breed,omitempty"`
+		FamilyName string `json:"family_name,omitempty"`
+		BirthDate  string `json:"birth_date,omitempty"`
+		Sex        string `json:"sex,omitempty"`
+	}
+	type responseEntity struct {
+		Valid        bool                       `json:"valid"`
+		IssuedAt     time.Time                  `json:"issued_at"`
+		Pet          responsePetEntity          `json:"pet"`
+		Veterinarian string                     `json:"veterinarian,omitempty"`
+		Clinic       *models.VetClinic          `json:"clinic,omitempty"`
+		Vaccinations []documents.VaccinationRow `json:"vaccinations"`
+	}
+	response := responseEntity{
+		Valid:    true,
+		IssuedAt: issuedAt,
+		Pet: responsePetEntity{
+			Name:       petModel.Name,
+			Breed:      petModel.SpecifiedBreed,
+			FamilyName: petModel.SpecifiedFamilyName,
+			BirthDate:  petModel.SpecifiedBirthDate,
+			Sex:        petModel.SpecifiedSex,
+		},
+		Clinic:       clinic,
+		Vaccinations: certificate.Vaccinations,
+	}
+	if certificate.PetType != nil {
+		response.Pet.PetType = certificate.PetType.TypeName
+	}
+	if certificate.Veterinarian != nil {
+		response.Veterinarian = certificate.Veterinarian.FullName
+	}
+	a.server.Respond(w, r, http.StatusOK, response)
+}
+
+// collectSummary loads the profile and the records of the pet the summary is made of
+func (a *PetsAPI) collectSummary(summary *documents.Summary, pet *models.Pet, now time.Time) error {
+	if err := collectProfile(a.server.DatabaseStore(), &summary.Profile, pet); err != nil {
+		return err
+	}
+	vaccinations, err := petVaccinationRows(a.server.DatabaseStore(), pet, now)
+	if err != nil {
+		return err
+	}
+	summary.Vaccinations = vaccinations
+	anthropometry, err := a.server.DatabaseStore().Pets().SelectPetAnthropometryRecords(pet.PetID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	summary.Anthropometry = anthropometry
+	reports, err := a.server.DatabaseStore().Pets().GetAllPetHealthReports(pet.PetID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	summary.Reports = reports
+	return nil
+}
+
+// collectCertificate loads the profile of the pet, the veterinarian and the vaccinations by the issue time
+func (a *PetsAPI) collectCertificate(certificate *documents.Certificate, pet *models.Pet, veterinarianID int, issuedAt time.Time) error {
+	if err := collectProfile(a.server.DatabaseStore(), &certificate.Profile, pet); err != nil {
+		return err
+	}
+	veterinarian, err := a.server.DatabaseStore().Users().FindByID(veterinarianID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	certificate.Veterinarian = veterinarian
+	vaccinations, err := petVaccinationRows(a.server.DatabaseStore(), pet, issuedAt)
+	if err != nil {
+		return err
+	}
+	certificate.Vaccinations = vaccinations
+	return nil
+}
+
+// collectProfile loads the type, the owner and the parents of the pet, the deleted parents are left out
+func collectProfile(database store.DatabaseStore, profile *documents.Profile, pet *models.Pet) error {
+	profile.Pet = pet
+	petType, err := database.Pets().FindTypeByID(pet.PetType)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	profile.PetType = petType
+	owner, err := database.Users().FindByID(pet.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	profile.Owner = owner
+	if pet.SpecifiedMotherID != 0 {
+		if profile.Mother, err = database.Pets().FindByID(pet.SpecifiedMotherID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	if pet.SpecifiedFatherID != 0 {
+		if profile.Father, err = database.Pets().FindByID(pet.SpecifiedFatherID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+	return nil
+}
+
+// petVaccinationRows lists the vaccinations of the pet made by the time with the next shots due as of it
+func petVaccinationRows(database store.DatabaseStore, pet *models.Pet, asOf time.Time) ([]documents.VaccinationRow, error) {
+	catalogue, err := database.Vaccines().SelectCatalogueByPetType(pet.PetType)
+	if err != nil {
+		return nil, err
+	}
+	vaccines, err := database.Vaccines().SelectByPetID(pet.PetID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	var made []models.Vaccine
+	for _, vaccine := range vaccines {
+		if !vaccine.VaccinationDate.After(asOf) {
+			made = append(made, vaccine)
+		}
+	}
+	return documents.Vaccinations(models.VaccinationSchedule(pet, catalogue, made, asOf)), nil
+}
+
+// errNoPublicBaseURL is returned when PUBLIC_BASE_URL is not set, the certificates are not issued without it
+var errNoPublicBaseURL = errors.New("PUBLIC_BASE_URL is not set")
+
+// certificateVerificationURL is the URL of the certificate QR code. It starts with the configured public URL
+// of the server, as the Host and X-Forwarded-Proto headers of the request can point it to any other site.
+func certificateVerificationURL(petID int, veterinarianID int, issuedAt time.Time) (string, error) {
+	if configs.PublicBaseURL == "" {
+		return "", errNoPublicBaseURL
+	}
+	issued := issuedAt.Unix()
+	signature, err := auth.SignCertificate(petID, veterinarianID, issued)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/certificates/%d?veterinarian=%d&issued=%d&signature=%s",
+		configs.PublicBaseURL, petID, veterinarianID, issued, signature), nil
+}
+
+// respondDocument sends the rendered PDF document as the download
+func (a *PetsAPI) respondDocument(w http.ResponseWriter, fileName string, content []byte) {
+	disposition := "attachment"
+	if contentDisposition := mime.FormatMediaType(disposition, map[string]string{"filename": attachmentFileName(fileName)}); contentDisposition != "" {
+		disposition = contentDisposition
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(content); err != nil {
+		a.server.Logger().Printf("Document writing error: %v", err)
+	}
+}
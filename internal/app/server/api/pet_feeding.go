@@ -0,0 +1,287 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/feeding"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	feedingAdherenceDefaultDays = 7
+	feedingAdherenceMaxDays     = 92
+)
+
+// feedingPlanRequestBody is the plan sent by the owner or the veterinarian, the portions are resolved by the foods
+type feedingPlanRequestBody struct {
+	Name             string `json:"name"`
+	TimeZone         string `json:"time_zone"`
+	StartsOn         string `json:"starts_on"`
+	EndsOn           string `json:"ends_on"`
+	ToleranceMinutes int    `json:"tolerance_minutes"`
+	Meals            []struct {
+		TimeOfDay     string        `json:"time_of_day"`
+		Weekdays      pq.Int64Array `json:"weekdays"`
+		FoodID        int           `json:"food_id"`
+		PortionAmount float64       `json:"portion_amount"`
+		PortionUnit   string        `json:"portion_unit"`
+	} `json:"meals"`
+}
+
+// applyFeedingPlan sets the plan and its meals, false is returned when the response has been written already
+func (a *PetsAPI) applyFeedingPlan(w http.ResponseWriter, r *http.Request, requestID string, rb *feedingPlanRequestBody, plan *models.FeedingPlan) bool {
+	plan.Name = rb.Name
+	plan.TimeZone = rb.TimeZone
+	plan.SpecifiedStartsOn = rb.StartsOn
+	plan.SpecifiedEndsOn = rb.EndsOn
+	plan.ToleranceMinutes = rb.ToleranceMinutes
+	plan.Meals = make([]models.FeedingPlanMeal, len(rb.Meals))
+
+	foods := make(map[int]*models.Food)
+	for idx, meal := range rb.Meals {
+		plan.Meals[idx] = models.FeedingPlanMeal{
+			PlanID:    plan.PlanID,
+			TimeOfDay: meal.TimeOfDay,
+			Weekdays:  meal.Weekdays,
+		}
+		food, ok := foods[meal.FoodID]
+		if !ok {
+			var err error
+			food, err = a.server.DatabaseStore().Foods().FindByID(meal.FoodID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.FoodNotFound)
+					return false
+				}
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return false
+			}
+			foods[meal.FoodID] = food
+		}
+		unit := meal.PortionUnit
+		if unit == "" {
+			unit = models.UnitGram
+		}
+		if err := plan.Meals[idx].SetPortion(food, meal.PortionAmount, unit); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return false
+		}
+	}
+	plan.BeforeCreate()
+	if err := plan.Validate(); err != nil {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	return true
+}
+
+// ServeFeedingPlansRequest lists the pet feeding plans and creates new ones, by the owner or the assigned veterinarian
+func (a *PetsAPI) ServeFeedingPlansRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		plans, err := a.server.DatabaseStore().FeedingPlans().SelectByPetID(petModel.PetID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, plans)
+
+	case http.MethodPost:
+		rb := &feedingPlanRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		plan := &models.FeedingPlan{PetID: petModel.PetID, CreatorID: session.UserID}
+		if !a.applyFeedingPlan(w, r, requestID, rb, plan) {
+			return
+		}
+		plan, err = a.server.DatabaseStore().FeedingPlans().Create(plan)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, plan)
+	}
+}
+
+// ServeFeedingPlanRequest returns, replaces and deletes the pet feeding plan
+func (a *PetsAPI) ServeFeedingPlanRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	plan, ok := a.findPetFeedingPlan(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, plan)
+
+	case http.MethodPut:
+		rb := &feedingPlanRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if !a.applyFeedingPlan(w, r, requestID, rb, plan) {
+			return
+		}
+		plan, err = a.server.DatabaseStore().FeedingPlans().Update(plan)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, plan)
+
+	case http.MethodDelete:
+		plan, err = a.server.DatabaseStore().FeedingPlans().DeleteByID(plan.PlanID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, plan)
+	}
+}
+
+/*
+ServeFeedingAdherenceRequest reports the meals expected by the pet plans between the from and to dates inclusive,
+matched with the logged eatings. The dates are days of the time_zone, the zone of the plan by default,
+the last week is reported when they are not given.
+*/
+func (a *PetsAPI) ServeFeedingAdherenceRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		now := time.Now().UTC()
+		location := time.UTC
+		if name := query.Get("time_zone"); name != "" {
+			if location, err = time.LoadLocation(name); err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		} else {
+			plans, err := a.server.DatabaseStore().FeedingPlans().SelectForPeriod(petModel.PetID, now, now)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			if len(plans) > 0 {
+				location = plans[0].Location()
+			}
+		}
+
+		today := now.In(location)
+		to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location).AddDate(0, 0, 1)
+		if rawTo := query.Get("to"); rawTo != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", rawTo, location)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			to = parsed.AddDate(0, 0, 1)
+		}
+		from := to.AddDate(0, 0, -feedingAdherenceDefaultDays)
+		if rawFrom := query.Get("from"); rawFrom != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", rawFrom, location)
+			if err != nil || !parsed.Before(to) || parsed.AddDate(0, 0, feedingAdherenceMaxDays).Before(to) {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			from = parsed
+		}
+
+		// The plans of other zones may have the days starting before or after the requested ones
+		plans, err := a.server.DatabaseStore().FeedingPlans().SelectForPeriod(petModel.PetID, from.AddDate(0, 0, -1), to)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		// The eatings are kept in the local time of the server
+		tolerance := models.MaxFeedingTolerance * time.Minute
+		eatings, err := a.server.DatabaseStore().Foods().GetPetsEatingsForPeriod(
+			petModel.PetID,
+			from.Add(-tolerance).In(time.Local),
+			to.Add(tolerance).In(time.Local),
+		)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, feeding.Assess(plans, eatings, from, to, now))
+	}
+}
+
+func (a *PetsAPI) findPetFeedingPlan(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet) (*models.FeedingPlan, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["plan"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	plan, err := a.server.DatabaseStore().FeedingPlans().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if plan.PetID != pet.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return plan, true
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const (
+	geofenceEventsDefaultLimit = 50
+	geofenceEventsMaxLimit     = 200
+)
+
+// geofenceRequestBody is the zone sent by the owner, omitted flags keep their current or default values
+type geofenceRequestBody struct {
+	Name         string       `json:"name"`
+	Shape        string       `json:"shape"`
+	Center       *[2]float64  `json:"center"`
+	Radius       float64      `json:"radius"`
+	Polygon      [][2]float64 `json:"polygon"`
+	AlertOnEnter *bool        `json:"alert_on_enter"`
+	AlertOnExit  *bool        `json:"alert_on_exit"`
+	IsActive     *bool        `json:"is_active"`
+}
+
+func (b *geofenceRequestBody) apply(fence *models.Geofence) {
+	fence.Name = b.Name
+	fence.Shape = b.Shape
+	fence.SpecifiedCenter = b.Center
+	fence.SpecifiedRadius = b.Radius
+	fence.SpecifiedPolygon = b.Polygon
+	if b.AlertOnEnter != nil {
+		fence.AlertOnEnter = *b.AlertOnEnter
+	}
+	if b.AlertOnExit != nil {
+		fence.AlertOnExit = *b.AlertOnExit
+	}
+	if b.IsActive != nil {
+		fence.IsActive = *b.IsActive
+	}
+}
+
+// ServeGeofencesRequest lists the pet zones and creates new ones, the owner is alerted about leaving the zone by default
+func (a *PetsAPI) ServeGeofencesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		fences, err := a.server.DatabaseStore().Geofences().SelectByPetID(petModel.PetID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, fences)
+
+	case http.MethodPost:
+		rb := &geofenceRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		fence := &models.Geofence{PetID: petModel.PetID, AlertOnExit: true, IsActive: true}
+		rb.apply(fence)
+		if err := fence.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		fence, err = a.server.DatabaseStore().Geofences().Create(fence)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, fence)
+	}
+}
+
+// ServeGeofenceRequest returns, replaces and deletes the pet zone, the changed zone forgets where the pet is
+func (a *PetsAPI) ServeGeofenceRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findDevicesPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	fence, ok := a.findPetGeofence(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, fence)
+
+	case http.MethodPut:
+		rb := &geofenceRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		rb.apply(fence)
+		if err := fence.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		fence, err = a.server.DatabaseStore().Geofences().Update(fence)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, fence)
+
+	case http.MethodDelete:
+		fence, err = a.server.DatabaseStore().Geofences().DeleteByID(fence.GeofenceID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, fence)
+	}
+}
+
+// ServeGeofenceEventsRequest returns the crossings of the pet zones, newest first
+func (a *PetsAPI) ServeGeofenceEventsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		limit, offset := geofenceEventsDefaultLimit, 0
+		if rawLimit := query.Get("limit"); rawLimit != "" {
+			parsedLimit, err := strconv.ParseInt(rawLimit, 10, 64)
+			if err != nil || parsedLimit <= 0 || parsedLimit > geofenceEventsMaxLimit {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			limit = int(parsedLimit)
+		}
+		if rawOffset := query.Get("offset"); rawOffset != "" {
+			parsedOffset, err := strconv.ParseInt(rawOffset, 10, 64)
+			if err != nil || parsedOffset < 0 {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			offset = int(parsedOffset)
+		}
+
+		events, err := a.server.DatabaseStore().Geofences().SelectEvents(petModel.PetID, limit, offset)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, events)
+	}
+}
+
+func (a *PetsAPI) findPetGeofence(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet) (*models.Geofence, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["geofence"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	fence, err := a.server.DatabaseStore().Geofences().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if fence.PetID != pet.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return fence, true
+}
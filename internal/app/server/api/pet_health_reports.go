@@ -0,0 +1,201 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+/*
+ServeHealthReportRequest returns and edits the pet health report. The draft is edited by its author,
+the signed report is amended by its author with the reason. Every edit is kept as the version,
+the version the edit is made from is required to refuse it when the report has been changed meanwhile.
+*/
+func (a *PetsAPI) ServeHealthReportRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	report, ok := a.findPetHealthReport(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, report)
+
+	case http.MethodPut:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		type requestBody struct {
+			Conclusion      string  `json:"conclusion"`
+			Comments        *string `json:"comment"`
+			Version         int     `json:"version"`
+			AmendmentReason string  `json:"amendment_reason"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if report.VeterinarianID != session.UserID {
+			a.server.RespondError(w, r, http.StatusForbidden, exceptions.ReportIsNotAuthored)
+			return
+		}
+		if report.IsSigned() && rb.AmendmentReason == "" {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.AmendmentReasonRequired)
+			return
+		}
+		if rb.Version == 0 {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.ReportVersionRequired)
+			return
+		}
+		if rb.Version != report.Version {
+			a.server.RespondError(w, r, http.StatusConflict, exceptions.ReportVersionConflict)
+			return
+		}
+
+		report.ReportConclusion = rb.Conclusion
+		report.SpecifiedReportComments = ""
+		report.SetSpecifiedReportComments(rb.Comments)
+		if err := report.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if err := a.server.DatabaseStore().Pets().UpdatePetHealthReport(report, session.UserID, rb.AmendmentReason); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusConflict, exceptions.ReportVersionConflict)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		publishPetEvent(a.server, requestID, report.PetID, realtime.EventHealthReportUpdated, report)
+		a.server.Respond(w, r, http.StatusOK, report)
+	}
+}
+
+// ServeHealthReportSignRequest locks the draft health report of the version by the signature of its author
+func (a *PetsAPI) ServeHealthReportSignRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if !a.canTreat(w, r, session) {
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	report, ok := a.findPetHealthReport(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+	if report.IsSigned() {
+		a.server.RespondError(w, r, http.StatusConflict, exceptions.ReportIsSigned)
+		return
+	}
+	if report.VeterinarianID != session.UserID {
+		a.server.RespondError(w, r, http.StatusForbidden, exceptions.ReportIsNotAuthored)
+		return
+	}
+
+	type requestBody struct {
+		Version int `json:"version"`
+	}
+	rb := &requestBody{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+	version := report.Version
+	if rb.Version != 0 {
+		version = rb.Version
+	}
+	report, err = a.server.DatabaseStore().Pets().SignPetHealthReport(report.ReportID, version, session.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusConflict, exceptions.ReportVersionConflict)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	publishPetEvent(a.server, requestID, report.PetID, realtime.EventHealthReportUpdated, report)
+	a.server.Respond(w, r, http.StatusOK, report)
+}
+
+// ServeHealthReportVersionsRequest returns all the versions of the pet health report, the first one first
+func (a *PetsAPI) ServeHealthReportVersionsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	report, ok := a.findPetHealthReport(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+	versions, err := a.server.DatabaseStore().Pets().SelectPetHealthReportVersions(report.ReportID)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, versions)
+}
+
+func (a *PetsAPI) findPetHealthReport(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet) (*models.PetHealthReport, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["report"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	report, err := a.server.DatabaseStore().Pets().FindPetHealthReport(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if report.PetID != pet.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return report, true
+}
@@ -0,0 +1,637 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ServeConditionsRequest lists the pet conditions, the problems only with problems=true, and diagnoses new ones
+func (a *PetsAPI) ServeConditionsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		problemsOnly := false
+		if rawProblems := r.URL.Query().Get("problems"); rawProblems != "" {
+			if problemsOnly, err = strconv.ParseBool(rawProblems); err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		}
+		conditions, err := a.server.DatabaseStore().MedicalRecords().SelectConditions(petModel.PetID, problemsOnly)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, conditions)
+
+	case http.MethodPost:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		condition := &models.Condition{}
+		if err := json.NewDecoder(r.Body).Decode(condition); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		condition.PetID = petModel.PetID
+		condition.VeterinarianID = session.UserID
+		if !a.validateCondition(w, r, requestID, condition) {
+			return
+		}
+		condition, err = a.server.DatabaseStore().MedicalRecords().CreateCondition(condition)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, condition)
+	}
+}
+
+// ServeConditionRequest returns the pet condition with the history of its status, changes and deletes it
+func (a *PetsAPI) ServeConditionRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	condition, ok := a.findPetCondition(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, condition)
+
+	case http.MethodPut:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		updated := &models.Condition{}
+		if err := json.NewDecoder(r.Body).Decode(updated); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		updated.ConditionID = condition.ConditionID
+		updated.PetID = condition.PetID
+		updated.VeterinarianID = condition.VeterinarianID
+		if !a.validateCondition(w, r, requestID, updated) {
+			return
+		}
+		updated, err = a.server.DatabaseStore().MedicalRecords().UpdateCondition(updated, session.UserID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		condition, err = a.server.DatabaseStore().MedicalRecords().DeleteCondition(condition.ConditionID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, condition)
+	}
+}
+
+// ServeAllergiesRequest lists the pet allergies, the active ones only with active=true, and records new ones
+func (a *PetsAPI) ServeAllergiesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		activeOnly := false
+		if rawActive := r.URL.Query().Get("active"); rawActive != "" {
+			if activeOnly, err = strconv.ParseBool(rawActive); err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		}
+		allergies, err := a.server.DatabaseStore().MedicalRecords().SelectAllergies(petModel.PetID, activeOnly)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, allergies)
+
+	case http.MethodPost:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		allergy := &models.Allergy{IsActive: true}
+		if err := json.NewDecoder(r.Body).Decode(allergy); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		allergy.PetID = petModel.PetID
+		allergy.RecordedBy = session.UserID
+		if err := allergy.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		allergy, err = a.server.DatabaseStore().MedicalRecords().CreateAllergy(allergy)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, allergy)
+	}
+}
+
+// ServeAllergyRequest returns, changes and deletes the pet allergy, the allergy is outgrown by making it inactive
+func (a *PetsAPI) ServeAllergyRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["allergy"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	allergy, err := a.server.DatabaseStore().MedicalRecords().FindAllergy(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if allergy.PetID != petModel.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, allergy)
+
+	case http.MethodPut:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		updated := &models.Allergy{IsActive: true}
+		if err := json.NewDecoder(r.Body).Decode(updated); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		updated.AllergyID = allergy.AllergyID
+		updated.PetID = allergy.PetID
+		updated.RecordedBy = allergy.RecordedBy
+		if err := updated.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		updated, err = a.server.DatabaseStore().MedicalRecords().UpdateAllergy(updated)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		allergy, err = a.server.DatabaseStore().MedicalRecords().DeleteAllergy(allergy.AllergyID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, allergy)
+	}
+}
+
+// ServeProceduresRequest lists the procedures performed on the pet and records new ones
+func (a *PetsAPI) ServeProceduresRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		procedures, err := a.server.DatabaseStore().MedicalRecords().SelectProcedures(petModel.PetID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, procedures)
+
+	case http.MethodPost:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		procedure := &models.Procedure{}
+		if err := json.NewDecoder(r.Body).Decode(procedure); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		procedure.PetID = petModel.PetID
+		procedure.VeterinarianID = session.UserID
+		if !a.validateProcedure(w, r, requestID, procedure) {
+			return
+		}
+		procedure, err = a.server.DatabaseStore().MedicalRecords().CreateProcedure(procedure)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, procedure)
+	}
+}
+
+// ServeProcedureRequest returns, changes and deletes the procedure performed on the pet
+func (a *PetsAPI) ServeProcedureRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["procedure"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	procedure, err := a.server.DatabaseStore().MedicalRecords().FindProcedure(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if procedure.PetID != petModel.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, procedure)
+
+	case http.MethodPut:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		updated := &models.Procedure{}
+		if err := json.NewDecoder(r.Body).Decode(updated); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		updated.ProcedureID = procedure.ProcedureID
+		updated.PetID = procedure.PetID
+		updated.VeterinarianID = procedure.VeterinarianID
+		if !a.validateProcedure(w, r, requestID, updated) {
+			return
+		}
+		updated, err = a.server.DatabaseStore().MedicalRecords().UpdateProcedure(updated)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		procedure, err = a.server.DatabaseStore().MedicalRecords().DeleteProcedure(procedure.ProcedureID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, procedure)
+	}
+}
+
+// ServeLabPanelsRequest lists the lab panels of the pet with their flagged results and records new ones
+func (a *PetsAPI) ServeLabPanelsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		panels, err := a.server.DatabaseStore().MedicalRecords().SelectLabPanels(petModel.PetID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, panels)
+
+	case http.MethodPost:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		panel := &models.LabPanel{}
+		if err := json.NewDecoder(r.Body).Decode(panel); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		panel.PetID = petModel.PetID
+		panel.VeterinarianID = session.UserID
+		if err := panel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if !a.validateHealthReport(w, r, requestID, panel.PetID, panel.SpecifiedReportID) {
+			return
+		}
+		panel, err = a.server.DatabaseStore().MedicalRecords().CreateLabPanel(panel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, panel)
+	}
+}
+
+// ServeLabPanelRequest returns and deletes the lab panel of the pet, wrong results are corrected by a new panel
+func (a *PetsAPI) ServeLabPanelRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["panel"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	panel, err := a.server.DatabaseStore().MedicalRecords().FindLabPanel(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if panel.PetID != petModel.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, panel)
+
+	case http.MethodDelete:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		panel, err = a.server.DatabaseStore().MedicalRecords().DeleteLabPanel(panel.PanelID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, panel)
+	}
+}
+
+// ServeProblemListRequest returns the unresolved conditions, active allergies and medications of the pet
+func (a *PetsAPI) ServeProblemListRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	problems, err := a.problemList(petModel.PetID)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, problems)
+}
+
+/*
+ServeVeterinarianConditionsRequest returns the conditions of all the veterinarian patients with their history,
+so the course of the condition can be followed across the patients. The conditions are filtered by the part
+of the name, the exact code and the status.
+*/
+func (a *PetsAPI) ServeVeterinarianConditionsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if !a.canTreat(w, r, session) {
+		return
+	}
+	query := r.URL.Query()
+	status := query.Get("status")
+	if err := validation.Validate(status, validation.In(models.ConditionStatuses...)); err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+		return
+	}
+	conditions, err := a.server.DatabaseStore().MedicalRecords().SelectPatientConditions(
+		session.UserID,
+		query.Get("name"),
+		query.Get("code"),
+		status,
+	)
+	if err != nil {
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	a.server.Respond(w, r, http.StatusOK, conditions)
+}
+
+func (a *PetsAPI) problemList(petID int) (*models.ProblemList, error) {
+	conditions, err := a.server.DatabaseStore().MedicalRecords().SelectConditions(petID, true)
+	if err != nil {
+		return nil, err
+	}
+	allergies, err := a.server.DatabaseStore().MedicalRecords().SelectAllergies(petID, true)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	medications, err := a.server.DatabaseStore().Medications().SelectByPetID(petID, &now)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ProblemList{
+		Conditions:  conditions,
+		Allergies:   allergies,
+		Medications: medications,
+	}, nil
+}
+
+// validateCondition validates the diagnosis and the health report it is linked to, resolved without the date is resolved today
+func (a *PetsAPI) validateCondition(w http.ResponseWriter, r *http.Request, requestID string, condition *models.Condition) bool {
+	if condition.Status == models.ConditionResolved && condition.SpecifiedResolvedOn == "" {
+		condition.SpecifiedResolvedOn = time.Now().UTC().Format(models.DateLayout)
+	}
+	if err := condition.Validate(); err != nil {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	return a.validateHealthReport(w, r, requestID, condition.PetID, condition.SpecifiedReportID)
+}
+
+// validateProcedure validates the procedure and the health report and condition it is linked to
+func (a *PetsAPI) validateProcedure(w http.ResponseWriter, r *http.Request, requestID string, procedure *models.Procedure) bool {
+	if err := procedure.Validate(); err != nil {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	if !a.validateHealthReport(w, r, requestID, procedure.PetID, procedure.SpecifiedReportID) {
+		return false
+	}
+	if procedure.SpecifiedConditionID == 0 {
+		return true
+	}
+	condition, err := a.server.DatabaseStore().MedicalRecords().FindCondition(procedure.SpecifiedConditionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.ConditionNotFound)
+			return false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return false
+	}
+	if condition.PetID != procedure.PetID {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.ConditionNotFound)
+		return false
+	}
+	return true
+}
+
+func (a *PetsAPI) findPetCondition(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet) (*models.Condition, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["condition"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	condition, err := a.server.DatabaseStore().MedicalRecords().FindCondition(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if condition.PetID != pet.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return condition, true
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/medication"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	medicationDosesDefaultDays = 7
+	medicationDosesMaxDays     = 92
+	// doseClockSkew is how far in the future the dose time of the client may be
+	doseClockSkew = 5 * time.Minute
+)
+
+// medicationRequestBody is the prescription sent by the veterinarian, omitted start keeps the current one or is now
+type medicationRequestBody struct {
+	Drug          string     `json:"drug"`
+	Dose          float64    `json:"dose"`
+	DoseUnit      string     `json:"dose_unit"`
+	Route         string     `json:"route"`
+	IntervalHours int        `json:"interval_hours"`
+	StartsAt      *time.Time `json:"starts_at"`
+	EndsAt        *time.Time `json:"ends_at"`
+	Instructions  *string    `json:"instructions"`
+	ReportID      *int       `json:"report_id"`
+}
+
+func (b *medicationRequestBody) apply(prescribed *models.Medication) {
+	prescribed.Drug = b.Drug
+	prescribed.Dose = b.Dose
+	prescribed.DoseUnit = b.DoseUnit
+	prescribed.Route = b.Route
+	prescribed.IntervalHours = b.IntervalHours
+	if b.StartsAt != nil {
+		prescribed.StartsAt = *b.StartsAt
+	}
+	prescribed.SpecifiedEndsAt = b.EndsAt
+	prescribed.SpecifiedInstructions = ""
+	prescribed.SetSpecifiedInstructions(b.Instructions)
+	prescribed.SpecifiedReportID = 0
+	prescribed.SetSpecifiedReportID(b.ReportID)
+}
+
+// ServeMedicationsRequest lists the pet medications, active ones only with active=true, and prescribes new ones
+func (a *PetsAPI) ServeMedicationsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		var activeAt *time.Time
+		if rawActive := r.URL.Query().Get("active"); rawActive != "" {
+			active, err := strconv.ParseBool(rawActive)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			if active {
+				now := time.Now().UTC()
+				activeAt = &now
+			}
+		}
+		medications, err := a.server.DatabaseStore().Medications().SelectByPetID(petModel.PetID, activeAt)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, medications)
+
+	case http.MethodPost:
+		if !a.canTreat(w, r, session) {
+			return
+		}
+		rb := &medicationRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		prescribed := &models.Medication{
+			PetID:          petModel.PetID,
+			VeterinarianID: session.UserID,
+			StartsAt:       time.Now().UTC(),
+		}
+		rb.apply(prescribed)
+		if !a.validateMedication(w, r, requestID, prescribed) {
+			return
+		}
+		prescribed, err = a.server.DatabaseStore().Medications().Create(prescribed)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, prescribed)
+	}
+}
+
+// ServeMedicationRequest returns, changes and deletes the pet medication, only the prescribing veterinarian
+// changes and deletes it. The medication is ended by its end time
+func (a *PetsAPI) ServeMedicationRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	prescribed, ok := a.findPetMedication(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, prescribed)
+
+	case http.MethodPut:
+		if !a.canChangeMedication(w, r, session, prescribed) {
+			return
+		}
+		rb := &medicationRequestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		rb.apply(prescribed)
+		if !a.validateMedication(w, r, requestID, prescribed) {
+			return
+		}
+		prescribed, err = a.server.DatabaseStore().Medications().Update(prescribed)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, prescribed)
+
+	case http.MethodDelete:
+		if !a.canChangeMedication(w, r, session, prescribed) {
+			return
+		}
+		prescribed, err = a.server.DatabaseStore().Medications().DeleteByID(prescribed.MedicationID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, prescribed)
+	}
+}
+
+/*
+ServeMedicationDosesRequest logs the doses given to the pet and reports them against the medication schedule
+between the from and to dates inclusive, the last week by default. The dates are UTC days.
+*/
+func (a *PetsAPI) ServeMedicationDosesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	prescribed, ok := a.findPetMedication(w, r, requestID, petModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		now := time.Now().UTC()
+		to := now.Truncate(24*time.Hour).AddDate(0, 0, 1)
+		if rawTo := query.Get("to"); rawTo != "" {
+			parsed, err := time.Parse("2006-01-02", rawTo)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			to = parsed.AddDate(0, 0, 1)
+		}
+		from := to.AddDate(0, 0, -medicationDosesDefaultDays)
+		if rawFrom := query.Get("from"); rawFrom != "" {
+			parsed, err := time.Parse("2006-01-02", rawFrom)
+			if err != nil || !parsed.Before(to) || parsed.AddDate(0, 0, medicationDosesMaxDays).Before(to) {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			from = parsed
+		}
+
+		window := prescribed.DoseWindow()
+		doses, err := a.server.DatabaseStore().Medications().SelectDoses(prescribed.MedicationID, from.Add(-window), to.Add(window))
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		type responseBody struct {
+			*medication.Adherence
+			Given []models.MedicationDose `json:"given"`
+		}
+		a.server.Respond(w, r, http.StatusOK, &responseBody{
+			Adherence: medication.Assess(prescribed, doses, from, to, now),
+			Given:     doses,
+		})
+
+	case http.MethodPost:
+		type requestBody struct {
+			AdministeredAt *time.Time `json:"administered_at"`
+			Amount         float64    `json:"amount"`
+			Comment        string     `json:"comment"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		dose := &models.MedicationDose{
+			MedicationID:     prescribed.MedicationID,
+			AdministeredAt:   time.Now().UTC(),
+			AdministeredBy:   session.UserID,
+			Amount:           rb.Amount,
+			SpecifiedComment: rb.Comment,
+		}
+		if rb.AdministeredAt != nil {
+			if rb.AdministeredAt.After(time.Now().Add(doseClockSkew)) {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.DoseInFuture)
+				return
+			}
+			dose.AdministeredAt = *rb.AdministeredAt
+		}
+		if dose.Amount == 0 {
+			dose.Amount = prescribed.Dose
+		}
+		if err := dose.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		dose, err = a.server.DatabaseStore().Medications().CreateDose(dose)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, dose)
+	}
+}
+
+// canTreat reports whether the user may prescribe the medications and keep the medical records, only the veterinarians may
+func (a *PetsAPI) canTreat(w http.ResponseWriter, r *http.Request, session *sessions.Session) bool {
+	if !permissions.AnyRoleIsVeterinarian(session.Roles) {
+		a.server.RespondError(w, r, http.StatusForbidden, exceptions.UserIsNotVeterinarian)
+		return false
+	}
+	return true
+}
+
+// canChangeMedication lets only the veterinarian who prescribed the medication change and delete it
+func (a *PetsAPI) canChangeMedication(w http.ResponseWriter, r *http.Request, session *sessions.Session, prescribed *models.Medication) bool {
+	if !a.canTreat(w, r, session) {
+		return false
+	}
+	if prescribed.VeterinarianID != session.UserID {
+		a.server.RespondError(w, r, http.StatusForbidden, exceptions.MedicationIsNotPrescribed)
+		return false
+	}
+	return true
+}
+
+// validateMedication validates the prescription and the health report it is linked to
+func (a *PetsAPI) validateMedication(w http.ResponseWriter, r *http.Request, requestID string, prescribed *models.Medication) bool {
+	if err := prescribed.Validate(); err != nil {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+		return false
+	}
+	return a.validateHealthReport(w, r, requestID, prescribed.PetID, prescribed.SpecifiedReportID)
+}
+
+// validateHealthReport checks the health report the record is linked to is of the pet, zero report is no link
+func (a *PetsAPI) validateHealthReport(w http.ResponseWriter, r *http.Request, requestID string, petID int, reportID int) bool {
+	if reportID == 0 {
+		return true
+	}
+	report, err := a.server.DatabaseStore().Pets().FindPetHealthReport(reportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.HealthReportNotFound)
+			return false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return false
+	}
+	if report.PetID != petID {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.HealthReportNotFound)
+		return false
+	}
+	return true
+}
+
+func (a *PetsAPI) findPetMedication(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet) (*models.Medication, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["medication"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	prescribed, err := a.server.DatabaseStore().Medications().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if prescribed.PetID != pet.PetID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return prescribed, true
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/notifications"
+)
+
+// notify sends the notification to the user. The request has already succeeded by then,
+// so failures are only logged.
+func (a *PetsAPI) notify(requestID string, userID int, event string, data notifications.Data) {
+	if _, err := a.server.Notifier().Notify(userID, event, data); err != nil {
+		a.server.Logger().Printf("Notification error: %v Request ID: %v", err, requestID)
+	}
+}
+
+// notifyHealthReport tells the pet owner the veterinarian has written the report
+func (a *PetsAPI) notifyHealthReport(requestID string, report *models.PetHealthReport) {
+	pet, err := a.server.DatabaseStore().Pets().FindByID(report.PetID)
+	if err != nil {
+		a.server.Logger().Printf("Notification error: %v Request ID: %v", err, requestID)
+		return
+	}
+	veterinarian, err := a.server.DatabaseStore().Users().FindByID(report.VeterinarianID)
+	if err != nil {
+		a.server.Logger().Printf("Notification error: %v Request ID: %v", err, requestID)
+		return
+	}
+	a.notify(requestID, pet.UserID, models.NotificationHealthReport, notifications.Data{
+		"pet_id":            pet.PetID,
+		"pet_name":          pet.Name,
+		"veterinarian_id":   veterinarian.UserID,
+		"veterinarian_name": veterinarian.FullName,
+		"conclusion":        report.ReportConclusion,
+	})
+}
+
+// notifyParentVerified tells the pet owner the owner of the parent has confirmed the relation
+func (a *PetsAPI) notifyParentVerified(requestID string, pet *models.Pet, parentPet *models.Pet, parent string) {
+	a.notify(requestID, pet.UserID, models.NotificationParentVerified, notifications.Data{
+		"pet_id":      pet.PetID,
+		"pet_name":    pet.Name,
+		"parent_id":   parentPet.PetID,
+		"parent_name": parentPet.Name,
+		"parent":      parent,
+	})
+}
+
+// notifyVeterinarianAssigned tells the pet owner and the veterinarian about the assignment,
+// except the one who has made it
+func (a *PetsAPI) notifyVeterinarianAssigned(requestID string, pet *models.Pet, veterinarianID int, assignedBy int) {
+	veterinarian, err := a.server.DatabaseStore().Users().FindByID(veterinarianID)
+	if err != nil {
+		a.server.Logger().Printf("Notification error: %v Request ID: %v", err, requestID)
+		return
+	}
+	data := notifications.Data{
+		"pet_id":            pet.PetID,
+		"pet_name":          pet.Name,
+		"veterinarian_id":   veterinarian.UserID,
+		"veterinarian_name": veterinarian.FullName,
+	}
+	if pet.UserID != assignedBy {
+		a.notify(requestID, pet.UserID, models.NotificationVeterinarianAssigned, data)
+	}
+	if veterinarianID != assignedBy && veterinarianID != pet.UserID {
+		a.notify(requestID, veterinarianID, models.NotificationVeterinarianAssigned, data)
+	}
+}
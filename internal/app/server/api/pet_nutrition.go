@@ -0,0 +1,102 @@
+package api
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/nutrition"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+ServeNutritionPlanRequest returns the daily energy requirement of the pet computed by its nutrition profile
+and the latest recorded weight.
+
+The weight and the date may be given with ?weight= and ?date= to plan for the target weight or another day.
+*/
+func (a *PetsAPI) ServeNutritionPlanRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		day := time.Now().UTC()
+		if rawDate := query.Get("date"); rawDate != "" {
+			day, err = time.Parse("2006-01-02", rawDate)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		}
+		var weight float64
+		if rawWeight := query.Get("weight"); rawWeight != "" {
+			weight, err = strconv.ParseFloat(rawWeight, 64)
+			if err != nil || weight <= 0 {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		} else {
+			records, err := a.server.DatabaseStore().Pets().SelectPetAnthropometryRecords(petModel.PetID)
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			var weighedAt time.Time
+			for _, record := range records {
+				if record.Time.After(weighedAt) {
+					weighedAt, weight = record.Time, record.Weight
+				}
+			}
+		}
+
+		petType, err := a.server.DatabaseStore().Pets().FindTypeByID(petModel.PetType)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		profile := nutrition.ProfileOf(petModel, petType, weight)
+		requirement, err := nutrition.Compute(profile, day)
+		if err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.PetWeightIsUnknown)
+			return
+		}
+
+		type responseBody struct {
+			PetID              int    `json:"pet_id"`
+			Date               string `json:"date"`
+			Species            string `json:"species"`
+			BirthDate          string `json:"birth_date,omitempty"`
+			Sex                string `json:"sex,omitempty"`
+			IsNeutered         bool   `json:"is_neutered"`
+			ActivityLevel      string `json:"activity_level,omitempty"`
+			Goal               string `json:"goal,omitempty"`
+			ReproductiveStatus string `json:"reproductive_status,omitempty"`
+			*nutrition.Requirement
+		}
+		a.server.Respond(w, r, http.StatusOK, responseBody{
+			PetID:              petModel.PetID,
+			Date:               day.Format("2006-01-02"),
+			Species:            petType.Species,
+			BirthDate:          petModel.SpecifiedBirthDate,
+			Sex:                petModel.SpecifiedSex,
+			IsNeutered:         petModel.IsNeutered,
+			ActivityLevel:      petModel.SpecifiedActivityLevel,
+			Goal:               petModel.SpecifiedGoal,
+			ReproductiveStatus: petModel.SpecifiedReproductiveStatus,
+			Requirement:        requirement,
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (a *PetsAPI) ServePetsVaccineScheduleRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedPetID := int(rawID)
+
+	petModel, err := a.server.DatabaseStore().Pets().FindByID(requestedPetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database err: %v, Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		isAttendingVeterinarian := petModel.VeterinarianID != nil &&
+			petModel.VeterinarianID.Valid &&
+			int(petModel.VeterinarianID.Int64) == session.UserID
+		if petModel.UserID != session.UserID && !isAttendingVeterinarian {
+			if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VaccinesPermission) {
+				a.server.RespondError(w, r, http.StatusForbidden, nil)
+				return
+			}
+		}
+		schedule, err := petVaccinationSchedule(a.server.DatabaseStore(), petModel, time.Now())
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, schedule)
+	}
+}
+
+// applyVaccineCatalogue checks the catalogue entry referenced by vaccination record
+// targets the pet species and names the record after it, if the name is not specified.
+// On failure it responds with error and returns false.
+func (a *PetsAPI) applyVaccineCatalogue(w http.ResponseWriter, r *http.Request, requestID string, pet *models.Pet, vaccine *models.Vaccine) bool {
+	if vaccine.SpecifiedCatalogueID == 0 {
+		return true
+	}
+	entry, err := a.server.DatabaseStore().Vaccines().FindCatalogueEntryByID(vaccine.SpecifiedCatalogueID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.VaccineNotInCatalogue)
+			return false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return false
+	}
+	if entry.PetType != pet.PetType {
+		a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.VaccineNotForPetType)
+		return false
+	}
+	if vaccine.Name == "" {
+		vaccine.Name = entry.Name
+	}
+	return true
+}
+
+// petVaccinationSchedule computes the schedule of the pet by the catalogue of its species
+func petVaccinationSchedule(database store.DatabaseStore, pet *models.Pet, now time.Time) ([]models.ScheduledVaccination, error) {
+	catalogue, err := database.Vaccines().SelectCatalogueByPetType(pet.PetType)
+	if err != nil {
+		return nil, err
+	}
+	vaccines, err := database.Vaccines().SelectByPetID(pet.PetID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return models.VaccinationSchedule(pet, catalogue, vaccines, now), nil
+}
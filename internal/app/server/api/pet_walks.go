@@ -0,0 +1,169 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultWalksInterval is the interval of the walks returned when the start date is not given
+const defaultWalksInterval = 30 * 24 * time.Hour
+
+// ServeWalksRequest returns the walks of the pet started in the interval as the GeoJSON FeatureCollection,
+// start and end are dates, end is inclusive
+func (a *PetsAPI) ServeWalksRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		end := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+		if rawEnd := r.URL.Query().Get("end"); rawEnd != "" {
+			parsed, err := time.Parse("2006-01-02", rawEnd)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			end = parsed.AddDate(0, 0, 1)
+		}
+		start := end.Add(-defaultWalksInterval)
+		if rawStart := r.URL.Query().Get("start"); rawStart != "" {
+			parsed, err := time.Parse("2006-01-02", rawStart)
+			if err != nil || !parsed.Before(end) {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			start = parsed
+		}
+
+		walks, err := a.server.DatabaseStore().Walks().SelectByPetID(petModel.PetID, start, end)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		features, err := a.walkFeatures(walks)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, tracks.NewFeatureCollection(features))
+	}
+}
+
+// ServeWalkRequest returns the walk as the GeoJSON Feature with the LineString of its track
+func (a *PetsAPI) ServeWalkRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	petModel, ok := a.findFollowedPet(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	rawID, err := strconv.ParseInt(mux.Vars(r)["walk"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		walk, err := a.server.DatabaseStore().Walks().FindByID(int(rawID))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		if walk.PetID != petModel.PetID {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		features, err := a.walkFeatures([]models.Walk{*walk})
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, features[0])
+	}
+}
+
+// walkFeatures renders the walks with their tracks
+func (a *PetsAPI) walkFeatures(walks []models.Walk) ([]tracks.Feature, error) {
+	if len(walks) == 0 {
+		return nil, nil
+	}
+	walkIDs := make([]int, len(walks))
+	for idx := range walks {
+		walkIDs[idx] = walks[idx].WalkID
+	}
+	points, err := a.server.DatabaseStore().Walks().SelectPoints(walkIDs)
+	if err != nil {
+		return nil, err
+	}
+	walkPoints := make(map[int][]models.TrackPoint, len(walks))
+	for _, point := range points {
+		if point.WalkID != nil && point.WalkID.Valid {
+			walkID := int(point.WalkID.Int64)
+			walkPoints[walkID] = append(walkPoints[walkID], point)
+		}
+	}
+
+	features := make([]tracks.Feature, len(walks))
+	for idx := range walks {
+		geometry := tracks.LineString(tracks.FromModels(walkPoints[walks[idx].WalkID]))
+		features[idx] = tracks.NewFeature(walks[idx].WalkID, geometry, walks[idx])
+	}
+	return features, nil
+}
+
+// findFollowedPet responds with the error unless the user may follow the pet
+func (a *PetsAPI) findFollowedPet(w http.ResponseWriter, r *http.Request, requestID string, session *sessions.Session) (*models.Pet, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	petModel, err := a.server.DatabaseStore().Pets().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if !canFollowPet(session, petModel) {
+		a.server.RespondError(w, r, http.StatusForbidden, nil)
+		return nil, false
+	}
+	return petModel, true
+}
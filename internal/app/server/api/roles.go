@@ -34,6 +34,11 @@ func (a *RolesAPI) ConfigureRouter(router *mux.Router) {
 		Name("Roles by ID").
 		Methods(http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodOptions).
 		HandlerFunc(a.ServeIDRequest)
+
+	sb.Path("/default").
+		Name("Default user role").
+		Methods(http.MethodGet, http.MethodPut, http.MethodOptions).
+		HandlerFunc(a.ServeDefaultRoleRequest)
 }
 
 func (a *RolesAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
@@ -127,7 +132,15 @@ func (a *RolesAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 			AllowDatabaseDump      bool    `json:"allow_database_dump"`
 		}
 
-		if roleID > 0 && roleID < 5 {
+		if isSystem, err := a.isSystemRole(roleID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Println(err)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		} else if isSystem {
 			a.server.RespondError(w, r, http.StatusForbidden, exceptions.CanNotUpdatePrimaryRole)
 			return
 		}
@@ -163,7 +176,15 @@ func (a *RolesAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusOK, newModel)
 
 	case http.MethodDelete:
-		if roleID > 0 && roleID < 5 {
+		if isSystem, err := a.isSystemRole(roleID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Println(err)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		} else if isSystem {
 			a.server.RespondError(w, r, http.StatusForbidden, exceptions.CanNotDeletePrimaryRole)
 			return
 		}
@@ -180,3 +201,62 @@ func (a *RolesAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
 		a.server.Respond(w, r, http.StatusNoContent, nil)
 	}
 }
+
+func (a *RolesAPI) ServeDefaultRoleRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		roleModel, err := a.server.DatabaseStore().Roles().SelectDefaultRole()
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Println(err)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, roleModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			RoleID int `json:"role_id"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		roleModel, err := a.server.DatabaseStore().Roles().FindByID(rb.RoleID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				a.server.RespondError(w, r, http.StatusNotFound, nil)
+				return
+			}
+			a.server.Logger().Println(err)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		if roleModel.Is(models.SystemRoleAdministrator) || roleModel.Is(models.SystemRoleVeterinarian) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, exceptions.CanNotUseRoleAsDefault)
+			return
+		}
+		roleModel, err = a.server.DatabaseStore().Roles().SetDefaultRole(roleModel.RoleID)
+		if err != nil {
+			a.server.Logger().Println(err)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, roleModel)
+	}
+}
+
+func (a *RolesAPI) isSystemRole(roleID int) (bool, error) {
+	roleModel, err := a.server.DatabaseStore().Roles().FindByID(roleID)
+	if err != nil {
+		return false, err
+	}
+	return roleModel.IsSystem(), nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// roleStore keeps the system roles and the single custom one, the rest of the store is not used by RolesAPI
+type roleStore struct {
+	store.DatabaseStore
+	repos.RoleRepository
+
+	roles       map[int]*models.Role
+	defaultRole int
+	updated     bool
+	deleted     bool
+}
+
+func (s *roleStore) Roles() repos.RoleRepository {
+	return s
+}
+
+func (s *roleStore) FindByID(roleID int) (*models.Role, error) {
+	role, ok := s.roles[roleID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *role
+	return &copied, nil
+}
+
+func (s *roleStore) Update(role *models.Role) (*models.Role, error) {
+	s.updated = true
+	return role, nil
+}
+
+func (s *roleStore) DeleteByID(roleID int) (*models.Role, error) {
+	s.deleted = true
+	return s.FindByID(roleID)
+}
+
+func (s *roleStore) SetDefaultRole(roleID int) (*models.Role, error) {
+	s.defaultRole = roleID
+	return s.FindByID(roleID)
+}
+
+func newTestRolesAPI() (*RolesAPI, *roleStore) {
+	database := &roleStore{roles: make(map[int]*models.Role), defaultRole: 3}
+	for idx, role := range models.SystemRoles() {
+		role := role
+		role.RoleID = idx + 1
+		role.BeforeCreate()
+		database.roles[role.RoleID] = &role
+	}
+	database.roles[10] = &models.Role{RoleID: 10, RoleName: "Trial User"}
+	return NewRolesAPI(&testServer{database: database}), database
+}
+
+func TestRolesAPI_DefaultRole(t *testing.T) {
+	api, database := newTestRolesAPI()
+
+	// The users registered with the administrator or veterinarian role would be granted it by anybody
+	for idx, role := range models.SystemRoles() {
+		if role.SpecifiedSystemKey != models.SystemRoleAdministrator && role.SpecifiedSystemKey != models.SystemRoleVeterinarian {
+			continue
+		}
+		w := serve(api.ServeDefaultRoleRequest, http.MethodPut, `{"role_id": `+strconv.Itoa(idx+1)+`}`, nil)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code, role.RoleName)
+		assert.Contains(t, w.Body.String(), exceptions.CanNotUseRoleAsDefault.Error(), role.RoleName)
+		assert.Equal(t, 3, database.defaultRole, role.RoleName)
+	}
+
+	assert.Equal(t, http.StatusOK, serve(api.ServeDefaultRoleRequest, http.MethodPut, `{"role_id": 10}`, nil).Code)
+	assert.Equal(t, 10, database.defaultRole)
+	assert.Equal(t, http.StatusOK, serve(api.ServeDefaultRoleRequest, http.MethodPut, `{"role_id": 2}`, nil).Code)
+	assert.Equal(t, 2, database.defaultRole)
+	assert.Equal(t, http.StatusNotFound, serve(api.ServeDefaultRoleRequest, http.MethodPut, `{"role_id": 11}`, nil).Code)
+}
+
+func TestRolesAPI_SystemRoles(t *testing.T) {
+	api, database := newTestRolesAPI()
+	body := `{"role_name": "Renamed", "allow_roles_crud": true}`
+
+	for roleID := range models.SystemRoles() {
+		vars := map[string]string{"id": strconv.Itoa(roleID + 1)}
+		assert.Equal(t, http.StatusForbidden, serve(api.ServeIDRequest, http.MethodPut, body, vars).Code, roleID)
+		assert.Equal(t, http.StatusForbidden, serve(api.ServeIDRequest, http.MethodDelete, "", vars).Code, roleID)
+	}
+	assert.False(t, database.updated)
+	assert.False(t, database.deleted)
+
+	vars := map[string]string{"id": "10"}
+	assert.Equal(t, http.StatusOK, serve(api.ServeIDRequest, http.MethodPut, body, vars).Code)
+	assert.True(t, database.updated)
+	assert.Equal(t, http.StatusNoContent, serve(api.ServeIDRequest, http.MethodDelete, "", vars).Code)
+	assert.True(t, database.deleted)
+}
@@ -1,9 +1,15 @@
 package api
 
 import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/gateway"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/geofence"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/middleware"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/notifications"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/webhooks"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/blob"
 	"log"
 	"net/http"
 )
@@ -20,6 +26,15 @@ type server interface {
 	Middleware() middleware.Middleware
 
 	DumpFilesFolder() string
+	BlobStore() blob.BlobStore
+
+	Notifier() *notifications.Notifier
+	WebPushPublicKey() string
+
+	Realtime() *realtime.Broker
+	Webhooks() *webhooks.Dispatcher
+	DeviceGateway() *gateway.Gateway
+	GeofenceMonitor() *geofence.Monitor
 
 	GetAuthorizedRequestInfo(r *http.Request) (string, *sessions.Session, error)
 }
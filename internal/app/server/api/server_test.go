@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/gorilla/mux"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// testServer responds like the server and authorizes every request with the session,
+// the rest of the server is not used by the handlers under test
+type testServer struct {
+	server
+
+	database store.DatabaseStore
+	session  *sessions.Session
+}
+
+func (s *testServer) Respond(w http.ResponseWriter, _ *http.Request, code int, data interface{}) {
+	w.WriteHeader(code)
+	if data != nil {
+		_ = json.NewEncoder(w).Encode(data)
+	}
+}
+
+func (s *testServer) RespondError(w http.ResponseWriter, r *http.Request, code int, err error) {
+	if err != nil {
+		s.Respond(w, r, code, map[string]string{"error": err.Error()})
+	} else {
+		s.Respond(w, r, code, nil)
+	}
+}
+
+func (s *testServer) Logger() *log.Logger {
+	return log.New(ioutil.Discard, "", 0)
+}
+
+func (s *testServer) DatabaseStore() store.DatabaseStore {
+	return s.database
+}
+
+func (s *testServer) GetAuthorizedRequestInfo(_ *http.Request) (string, *sessions.Session, error) {
+	return "test-request", s.session, nil
+}
+
+// serve sends the request with the route variables to the handler and returns the recorded response
+func serve(handler http.HandlerFunc, method string, body string, vars map[string]string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := mux.SetURLVars(httptest.NewRequest(method, "/", reader), vars)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
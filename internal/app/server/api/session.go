@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/middleware"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
@@ -67,6 +69,20 @@ func (a SessionAPI) ConfigureRoutes(router *mux.Router) {
 		Handler(
 			http.HandlerFunc(a.ServeIoTDataRequest),
 		)
+
+	router.Path("/api/session/iot/data/batch").
+		Name("IoT Device Data Batch").
+		Methods(http.MethodPost).
+		Handler(
+			http.HandlerFunc(a.ServeIoTBatchRequest),
+		)
+
+	router.Path("/api/session/iot/location").
+		Name("IoT Device Location").
+		Methods(http.MethodPost).
+		Handler(
+			http.HandlerFunc(a.ServeIoTLocationRequest),
+		)
 }
 
 func (a *SessionAPI) ServeLoginRequest(w http.ResponseWriter, r *http.Request) {
@@ -252,6 +268,7 @@ func (a *SessionAPI) ServeIoTLoginRequest(w http.ResponseWriter, r *http.Request
 	case http.MethodPost:
 		type requestBody struct {
 			AccessSecret string `json:"access_secret"`
+			models.DeviceTelemetry
 		}
 
 		rb := &requestBody{}
@@ -259,8 +276,12 @@ func (a *SessionAPI) ServeIoTLoginRequest(w http.ResponseWriter, r *http.Request
 			a.server.RespondError(w, r, http.StatusBadRequest, err)
 			return
 		}
+		if err := rb.DeviceTelemetry.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
 
-		deviceModel, err := a.server.DatabaseStore().IoTDevicesRepository().GetByAccessSecret(rb.AccessSecret)
+		deviceModel, err := a.server.DatabaseStore().IoTDevicesRepository().GetByAccessSecret(auth.HashIoTDeviceSecret(rb.AccessSecret))
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				a.server.RespondError(w, r, http.StatusNotFound, nil)
@@ -269,8 +290,16 @@ func (a *SessionAPI) ServeIoTLoginRequest(w http.ResponseWriter, r *http.Request
 			a.server.Respond(w, r, http.StatusUnauthorized, nil)
 			return
 		}
+		if !deviceModel.IsActive() {
+			a.server.Respond(w, r, http.StatusUnauthorized, nil)
+			return
+		}
+		if err := a.server.DatabaseStore().IoTDevicesRepository().Touch(deviceModel.DeviceID, &rb.DeviceTelemetry, time.Now()); err != nil {
+			a.server.RespondError(w, r, http.StatusInternalServerError, err)
+			return
+		}
 
-		token, err := auth.CreateIoTToken(deviceModel.PetID)
+		token, err := auth.CreateIoTToken(deviceModel.PetID, deviceModel.DeviceID)
 		if err != nil {
 			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
 			return
@@ -291,6 +320,7 @@ func (a *SessionAPI) ServeIoTDataRequest(w http.ResponseWriter, r *http.Request)
 		type requestBody struct {
 			Distance  float64 `json:"distance"`
 			MeanSpeed float64 `json:"mean_speed"`
+			models.DeviceTelemetry
 		}
 
 		rb := &requestBody{}
@@ -298,12 +328,19 @@ func (a *SessionAPI) ServeIoTDataRequest(w http.ResponseWriter, r *http.Request)
 			a.server.RespondError(w, r, http.StatusBadRequest, err)
 			return
 		}
+		if err := rb.DeviceTelemetry.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
 
 		authTokenMeta, err := auth.ExtractIoTAccessMeta(r)
 		if err != nil {
 			a.server.Respond(w, r, http.StatusUnauthorized, nil)
 			return
 		}
+		if _, ok := a.authorisedDevice(w, r, authTokenMeta, &rb.DeviceTelemetry); !ok {
+			return
+		}
 
 		activity := &models.Activity{
 			PetID:           authTokenMeta.PetID,
@@ -315,6 +352,31 @@ func (a *SessionAPI) ServeIoTDataRequest(w http.ResponseWriter, r *http.Request)
 			a.server.RespondError(w, r, http.StatusInternalServerError, err)
 			return
 		}
+		requestID := r.Context().Value(middleware.CtxRequestUUID).(string)
+		publishPetEvent(a.server, requestID, activity.PetID, realtime.EventActivityCreated, activity)
+		dispatchWebhook(a.server, requestID, models.WebhookActivityRecorded, activity.PetID, activity)
 		a.server.Respond(w, r, http.StatusCreated, nil)
 	}
 }
+
+// authorisedDevice checks the device of the IoT token is still paired to the pet and records it has been seen
+func (a *SessionAPI) authorisedDevice(w http.ResponseWriter, r *http.Request, meta *auth.IoTAccessTokenMeta, telemetry *models.DeviceTelemetry) (*models.IoTDevice, bool) {
+	deviceModel, err := a.server.DatabaseStore().IoTDevicesRepository().GetByID(meta.DeviceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.Respond(w, r, http.StatusUnauthorized, nil)
+			return nil, false
+		}
+		a.server.RespondError(w, r, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if !deviceModel.IsActive() || deviceModel.PetID != meta.PetID {
+		a.server.Respond(w, r, http.StatusUnauthorized, nil)
+		return nil, false
+	}
+	if err := a.server.DatabaseStore().IoTDevicesRepository().Touch(deviceModel.DeviceID, telemetry, time.Now()); err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	return deviceModel, true
+}
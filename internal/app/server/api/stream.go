@@ -0,0 +1,221 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/middleware"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/sse"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// streamHeartbeatInterval keeps idle connections open through proxies
+	streamHeartbeatInterval = 25 * time.Second
+	// streamSessionCheckInterval is how often the stream checks the user has not logged out
+	streamSessionCheckInterval = 30 * time.Second
+	streamRetryMilliseconds    = 3000
+)
+
+const streamSessionExpired = "session.expired"
+
+type StreamAPI struct {
+	server server
+}
+
+func NewStreamAPI(server server) *StreamAPI {
+	return &StreamAPI{server: server}
+}
+
+func (a *StreamAPI) ConfigureRouter(router *mux.Router) {
+	router.Path("/api/stream/ticket").
+		Name("Realtime events stream ticket Request").
+		Methods(http.MethodPost).
+		Handler(
+			a.server.Middleware().Authentication.IsAuthorised(
+				http.HandlerFunc(a.ServeTicketRequest),
+			),
+		)
+
+	sb := router.PathPrefix("/api/stream").Subrouter()
+	// EventSource can not send headers, so the stream ticket may also be passed in the query
+	sb.Use(a.server.Middleware().Authentication.IsStreamAuthorised)
+
+	sb.Path("").
+		Name("Realtime events stream Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeStreamRequest)
+}
+
+// ServeTicketRequest issues the short-lived ticket the browser opens the stream of the session with,
+// the access token is never put in the stream URL
+func (a *StreamAPI) ServeTicketRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	_, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	accessID := r.Context().Value(middleware.CtxAccessUUID).(string)
+	ticket, expires := auth.CreateStreamTicket(&auth.AccessTokenMeta{UserID: session.UserID, AccessUUID: accessID}, time.Now())
+
+	type responseEntity struct {
+		Ticket    string `json:"ticket"`
+		ExpiresAt int64  `json:"expires_at"`
+	}
+	a.server.Respond(w, r, http.StatusCreated, responseEntity{Ticket: ticket, ExpiresAt: expires})
+}
+
+// ServeStreamRequest streams the events of the user and the pets the user follows.
+// By default the pets are the user's own ones and the ones the user is a veterinarian of,
+// the pets query parameter narrows them down or adds other pets for the pets administrators.
+func (a *StreamAPI) ServeStreamRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	var petIDs []int
+	if rawPets := r.URL.Query().Get("pets"); rawPets != "" {
+		for _, rawID := range strings.Split(rawPets, ",") {
+			petID, err := strconv.ParseInt(strings.TrimSpace(rawID), 10, 64)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			petModel, err := a.server.DatabaseStore().Pets().FindByID(int(petID))
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					a.server.RespondError(w, r, http.StatusNotFound, nil)
+					return
+				}
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			if !canFollowPet(session, petModel) {
+				a.server.RespondError(w, r, http.StatusForbidden, nil)
+				return
+			}
+			petIDs = append(petIDs, petModel.PetID)
+		}
+	} else {
+		petIDs, err = a.followedPets(session)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	topics := []string{realtime.UserTopic(session.UserID)}
+	for _, petID := range petIDs {
+		topics = append(topics, realtime.PetTopic(petID))
+	}
+	subscription := a.server.Realtime().Subscribe(topics...)
+	defer subscription.Close()
+
+	stream, err := sse.NewWriter(w)
+	if err != nil {
+		a.server.Logger().Printf("Stream error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	if err := stream.Retry(streamRetryMilliseconds); err != nil {
+		return
+	}
+
+	accessID := r.Context().Value(middleware.CtxAccessUUID).(string)
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	sessionCheck := time.NewTicker(streamSessionCheckInterval)
+	defer sessionCheck.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// The client lags behind, it reconnects and reloads the state
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				a.server.Logger().Printf("Stream error: %v Request ID: %v", err, requestID)
+				continue
+			}
+			if err := stream.Event("", event.Type, data); err != nil {
+				return
+			}
+
+		case <-heartbeat.C:
+			if err := stream.Comment("ping"); err != nil {
+				return
+			}
+
+		case <-sessionCheck.C:
+			if _, err := a.server.PersistentStore().GetSessionInfo(accessID); err != nil {
+				_ = stream.Event("", streamSessionExpired, []byte("{}"))
+				return
+			}
+		}
+	}
+}
+
+// followedPets are the pets the user owns or is a veterinarian of
+func (a *StreamAPI) followedPets(session *sessions.Session) ([]int, error) {
+	owned, err := a.server.DatabaseStore().Pets().SelectByUserID(session.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	var petIDs []int
+	for idx := range owned {
+		petIDs = append(petIDs, owned[idx].PetID)
+	}
+	if !permissions.AnyRoleIsVeterinarian(session.Roles) {
+		return petIDs, nil
+	}
+	patients, err := a.server.DatabaseStore().Pets().SelectByVeterinarianID(session.UserID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	for idx := range patients {
+		if patients[idx].UserID != session.UserID {
+			petIDs = append(petIDs, patients[idx].PetID)
+		}
+	}
+	return petIDs, nil
+}
+
+func canFollowPet(session *sessions.Session, pet *models.Pet) bool {
+	if pet.UserID == session.UserID || pet.SpecifiedVeterinarianID == session.UserID {
+		return true
+	}
+	return permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().PetsPermission)
+}
+
+// publishPetEvent pushes the event to the streams following the pet. The change is already stored by then,
+// so failures are only logged.
+func publishPetEvent(server server, requestID string, petID int, eventType string, data interface{}) {
+	if err := server.Realtime().PublishPet(petID, eventType, data); err != nil {
+		server.Logger().Printf("Stream error: %v Request ID: %v", err, requestID)
+	}
+}
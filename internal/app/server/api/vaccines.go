@@ -0,0 +1,220 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/permissions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+// VaccinesAPI serves the vaccine catalogue, pets vaccination records are served by PetsAPI
+type VaccinesAPI struct {
+	server server
+}
+
+func NewVaccinesAPI(server server) *VaccinesAPI {
+	return &VaccinesAPI{server: server}
+}
+
+func (a *VaccinesAPI) ConfigureRouter(router *mux.Router) {
+	sb := router.PathPrefix("/api/vaccines").Subrouter()
+	sb.Use(a.server.Middleware().Authentication.IsAuthorised)
+
+	sb.Path("").
+		Name("Vaccine catalogue Root Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeRootRequest)
+
+	sb.Path("/{id:[0-9]+}").
+		Name("Vaccine catalogue ID Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeIDRequest)
+}
+
+func (a *VaccinesAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if queriedPetType := r.URL.Query().Get("pet_type"); queriedPetType != "" {
+			petType, err := strconv.ParseInt(queriedPetType, 10, 64)
+			if err != nil {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+			entries, err := a.server.DatabaseStore().Vaccines().SelectCatalogueByPetType(int(petType))
+			if err != nil {
+				a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+			a.server.Respond(w, r, http.StatusOK, entries)
+			return
+		}
+
+		entries, err := a.server.DatabaseStore().Vaccines().SelectCatalogue()
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, entries)
+
+	case http.MethodPost:
+		type requestBody struct {
+			Name                  string  `json:"name"`
+			PetType               int     `json:"pet_type"`
+			Manufacturer          *string `json:"manufacturer"`
+			DosesCount            int     `json:"doses_count"`
+			DoseIntervalDays      int     `json:"dose_interval_days"`
+			BoosterIntervalMonths int     `json:"booster_interval_months"`
+			FirstDoseAgeWeeks     int     `json:"first_dose_age_weeks"`
+		}
+		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VaccinesPermission) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		entryModel := &models.VaccineCatalogueEntry{
+			Name:                  rb.Name,
+			PetType:               rb.PetType,
+			DosesCount:            rb.DosesCount,
+			DoseIntervalDays:      rb.DoseIntervalDays,
+			BoosterIntervalMonths: rb.BoosterIntervalMonths,
+			FirstDoseAgeWeeks:     rb.FirstDoseAgeWeeks,
+		}
+		entryModel.SetSpecifiedManufacturer(rb.Manufacturer)
+		if err := entryModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if !a.petTypeExists(w, r, requestID, rb.PetType) {
+			return
+		}
+		entryModel, err = a.server.DatabaseStore().Vaccines().CreateCatalogueEntry(entryModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusCreated, entryModel)
+	}
+}
+
+func (a *VaccinesAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	requestedID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return
+	}
+	requestedModel, err := a.server.DatabaseStore().Vaccines().FindCatalogueEntryByID(int(requestedID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, requestedModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			Name                  string  `json:"name"`
+			PetType               int     `json:"pet_type"`
+			Manufacturer          *string `json:"manufacturer"`
+			DosesCount            int     `json:"doses_count"`
+			DoseIntervalDays      int     `json:"dose_interval_days"`
+			BoosterIntervalMonths int     `json:"booster_interval_months"`
+			FirstDoseAgeWeeks     int     `json:"first_dose_age_weeks"`
+		}
+		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VaccinesPermission) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		entryModel := &models.VaccineCatalogueEntry{
+			CatalogueID:           requestedModel.CatalogueID,
+			Name:                  rb.Name,
+			PetType:               rb.PetType,
+			DosesCount:            rb.DosesCount,
+			DoseIntervalDays:      rb.DoseIntervalDays,
+			BoosterIntervalMonths: rb.BoosterIntervalMonths,
+			FirstDoseAgeWeeks:     rb.FirstDoseAgeWeeks,
+			SpecifiedManufacturer: requestedModel.SpecifiedManufacturer,
+		}
+		entryModel.SetSpecifiedManufacturer(rb.Manufacturer)
+		if err := entryModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if !a.petTypeExists(w, r, requestID, rb.PetType) {
+			return
+		}
+		entryModel, err = a.server.DatabaseStore().Vaccines().UpdateCatalogueEntry(entryModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, entryModel)
+
+	case http.MethodDelete:
+		if !permissions.AnyRoleHavePermissions(session.Roles, permissions.Permissions().VaccinesPermission) {
+			a.server.RespondError(w, r, http.StatusForbidden, nil)
+			return
+		}
+		if _, err := a.server.DatabaseStore().Vaccines().DeleteCatalogueEntryByID(requestedModel.CatalogueID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+// petTypeExists checks the pet type the catalogue entry targets is present
+func (a *VaccinesAPI) petTypeExists(w http.ResponseWriter, r *http.Request, requestID string, petType int) bool {
+	if _, err := a.server.DatabaseStore().Pets().FindTypeByID(petType); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, nil)
+			return false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,359 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api/exceptions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+const (
+	webhookDeliveriesDefaultLimit = 50
+	webhookDeliveriesMaxLimit     = 200
+)
+
+type WebhooksAPI struct {
+	server server
+}
+
+func NewWebhooksAPI(server server) *WebhooksAPI {
+	return &WebhooksAPI{server: server}
+}
+
+func (a *WebhooksAPI) ConfigureRouter(router *mux.Router) {
+	sb := router.PathPrefix("/api/webhooks").Subrouter()
+	sb.Use(a.server.Middleware().Authentication.IsAuthorised)
+
+	sb.Path("").
+		Name("Webhook subscriptions Request").
+		Methods(http.MethodGet, http.MethodPost).
+		HandlerFunc(a.ServeRootRequest)
+
+	sb.Path("/{id:[0-9]+}").
+		Name("Webhook subscription ID Request").
+		Methods(http.MethodGet, http.MethodPut, http.MethodDelete).
+		HandlerFunc(a.ServeIDRequest)
+
+	sb.Path("/{id:[0-9]+}/deliveries").
+		Name("Webhook deliveries log Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeDeliveriesRequest)
+
+	sb.Path("/{id:[0-9]+}/deliveries/{delivery:[0-9]+}").
+		Name("Webhook delivery ID Request").
+		Methods(http.MethodGet).
+		HandlerFunc(a.ServeDeliveryIDRequest)
+
+	sb.Path("/{id:[0-9]+}/deliveries/{delivery:[0-9]+}/redeliver").
+		Name("Webhook redelivery Request").
+		Methods(http.MethodPost).
+		HandlerFunc(a.ServeRedeliveryRequest)
+}
+
+func (a *WebhooksAPI) ServeRootRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		subscriptions, err := a.server.DatabaseStore().Webhooks().SelectByUserID(session.UserID)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, subscriptions)
+
+	case http.MethodPost:
+		type requestBody struct {
+			URL    string   `json:"url"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		// The partner may share its own secret, otherwise it is generated by the server
+		if rb.Secret == "" {
+			rb.Secret, err = auth.NewSecret(32)
+			if err != nil {
+				a.server.Logger().Printf("Webhook secret error: %v Request ID: %v", err, requestID)
+				a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+				return
+			}
+		}
+		subscriptionModel := &models.WebhookSubscription{
+			UserID:   session.UserID,
+			URL:      rb.URL,
+			Secret:   rb.Secret,
+			Events:   rb.Events,
+			IsActive: true,
+		}
+		if err := subscriptionModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		// The receiver host must not resolve to the internal network of the server
+		if err := netguard.ResolveURL(r.Context(), subscriptionModel.URL); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		subscriptionModel, err = a.server.DatabaseStore().Webhooks().Create(subscriptionModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		// The secret is shown only once
+		type responseEntity struct {
+			*models.WebhookSubscription
+			Secret string `json:"secret"`
+		}
+		a.server.Respond(w, r, http.StatusCreated, responseEntity{
+			WebhookSubscription: subscriptionModel,
+			Secret:              subscriptionModel.Secret,
+		})
+	}
+}
+
+func (a *WebhooksAPI) ServeIDRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	subscriptionModel, ok := a.findSubscription(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, subscriptionModel)
+
+	case http.MethodPut:
+		type requestBody struct {
+			URL      *string   `json:"url"`
+			Secret   *string   `json:"secret"`
+			Events   *[]string `json:"events"`
+			IsActive *bool     `json:"is_active"`
+		}
+		rb := &requestBody{}
+		if err := json.NewDecoder(r.Body).Decode(rb); err != nil {
+			a.server.RespondError(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if rb.URL != nil {
+			subscriptionModel.URL = *rb.URL
+		}
+		if rb.Secret != nil {
+			subscriptionModel.Secret = *rb.Secret
+		}
+		if rb.Events != nil {
+			subscriptionModel.Events = *rb.Events
+		}
+		if rb.IsActive != nil {
+			subscriptionModel.IsActive = *rb.IsActive
+		}
+		if err := subscriptionModel.Validate(); err != nil {
+			a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if rb.URL != nil {
+			if err := netguard.ResolveURL(r.Context(), subscriptionModel.URL); err != nil {
+				a.server.RespondError(w, r, http.StatusUnprocessableEntity, err)
+				return
+			}
+		}
+		subscriptionModel, err = a.server.DatabaseStore().Webhooks().Update(subscriptionModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, subscriptionModel)
+
+	case http.MethodDelete:
+		if _, err := a.server.DatabaseStore().Webhooks().DeleteByID(session.UserID, subscriptionModel.SubscriptionID); err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusNoContent, nil)
+	}
+}
+
+func (a *WebhooksAPI) ServeDeliveriesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	subscriptionModel, ok := a.findSubscription(w, r, requestID, session)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		limit := webhookDeliveriesDefaultLimit
+		offset := 0
+		if rawLimit := query.Get("limit"); rawLimit != "" {
+			limit, err = strconv.Atoi(rawLimit)
+			if err != nil || limit < 1 || limit > webhookDeliveriesMaxLimit {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		}
+		if rawOffset := query.Get("offset"); rawOffset != "" {
+			offset, err = strconv.Atoi(rawOffset)
+			if err != nil || offset < 0 {
+				a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURLQuery)
+				return
+			}
+		}
+		deliveries, err := a.server.DatabaseStore().Webhooks().SelectDeliveries(subscriptionModel.SubscriptionID, limit, offset)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusOK, deliveries)
+	}
+}
+
+func (a *WebhooksAPI) ServeDeliveryIDRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	subscriptionModel, ok := a.findSubscription(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	deliveryModel, ok := a.findDelivery(w, r, requestID, subscriptionModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		a.server.Respond(w, r, http.StatusOK, deliveryModel)
+	}
+}
+
+// ServeRedeliveryRequest sends the logged payload again, the redelivery is logged as the new delivery
+func (a *WebhooksAPI) ServeRedeliveryRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+	requestID, session, err := a.server.GetAuthorizedRequestInfo(r)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return
+	}
+	subscriptionModel, ok := a.findSubscription(w, r, requestID, session)
+	if !ok {
+		return
+	}
+	deliveryModel, ok := a.findDelivery(w, r, requestID, subscriptionModel)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if !subscriptionModel.IsActive {
+			a.server.RespondError(w, r, http.StatusConflict, exceptions.WebhookSubscriptionIsInactive)
+			return
+		}
+		redelivery, err := a.server.Webhooks().Redeliver(deliveryModel)
+		if err != nil {
+			a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+			a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+			return
+		}
+		a.server.Respond(w, r, http.StatusAccepted, redelivery)
+	}
+}
+
+// findSubscription responds 404 unless the subscription from the path belongs to the user
+func (a *WebhooksAPI) findSubscription(w http.ResponseWriter, r *http.Request, requestID string, session *sessions.Session) (*models.WebhookSubscription, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	subscriptionModel, err := a.server.DatabaseStore().Webhooks().FindByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if subscriptionModel.UserID != session.UserID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return subscriptionModel, true
+}
+
+func (a *WebhooksAPI) findDelivery(w http.ResponseWriter, r *http.Request, requestID string, subscription *models.WebhookSubscription) (*models.WebhookDelivery, bool) {
+	rawID, err := strconv.ParseInt(mux.Vars(r)["delivery"], 10, 64)
+	if err != nil {
+		a.server.RespondError(w, r, http.StatusBadRequest, exceptions.UnprocessableURIParam)
+		return nil, false
+	}
+	deliveryModel, err := a.server.DatabaseStore().Webhooks().FindDeliveryByID(int(rawID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			a.server.RespondError(w, r, http.StatusNotFound, nil)
+			return nil, false
+		}
+		a.server.Logger().Printf("Database error: %v Request ID: %v", err, requestID)
+		a.server.RespondError(w, r, http.StatusInternalServerError, nil)
+		return nil, false
+	}
+	if deliveryModel.SubscriptionID != subscription.SubscriptionID {
+		a.server.RespondError(w, r, http.StatusNotFound, nil)
+		return nil, false
+	}
+	return deliveryModel, true
+}
+
+// dispatchWebhook sends the pet event to the partner integrations. The change is already stored by then,
+// so failures are only logged.
+func dispatchWebhook(server server, requestID string, event string, petID int, data interface{}) {
+	if err := server.Webhooks().Dispatch(event, petID, data); err != nil {
+		server.Logger().Printf("Webhooks error: %v Request ID: %v", err, requestID)
+	}
+}
@@ -1,16 +1,31 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/configs"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/gateway"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/geofence"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/jobs"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/middleware"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/notifications"
+	notificationsConfigs "github.com/ArtemVovchenko/storypet-backend/internal/app/notifications/configs"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/realtime"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/server/api"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/tracks"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/webhooks"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/blob"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webpush"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"log"
 	"net/http"
+	"time"
 )
 
 type Server struct {
@@ -22,14 +37,29 @@ type Server struct {
 	databaseStore   store.DatabaseStore
 	persistentStore store.PersistentStore
 
+	scheduler   *jobs.Scheduler
+	notifier    *notifications.Notifier
+	webPushKeys *webpush.VAPIDKeys
+	realtime    *realtime.Broker
+	webhooks    *webhooks.Dispatcher
+	gateway     *gateway.Gateway
+	geofences   *geofence.Monitor
+	blobs       blob.BlobStore
+
 	middleware middleware.Middleware
 
-	databaseAPI *api.DatabaseAPI
-	sessionAPI  *api.SessionAPI
-	userAPI     *api.UserAPI
-	rolesAPI    *api.RolesAPI
-	petsAPI     *api.PetsAPI
-	foodsAPI    *api.FoodsAPI
+	databaseAPI      *api.DatabaseAPI
+	sessionAPI       *api.SessionAPI
+	userAPI          *api.UserAPI
+	rolesAPI         *api.RolesAPI
+	petsAPI          *api.PetsAPI
+	foodsAPI         *api.FoodsAPI
+	clinicsAPI       *api.ClinicsAPI
+	calendarAPI      *api.CalendarAPI
+	vaccinesAPI      *api.VaccinesAPI
+	notificationsAPI *api.NotificationsAPI
+	streamAPI        *api.StreamAPI
+	webhooksAPI      *api.WebhooksAPI
 }
 
 func New() *Server {
@@ -47,6 +77,12 @@ func New() *Server {
 	server.rolesAPI = api.NewRolesAPI(server)
 	server.petsAPI = api.NewPetsAPI(server)
 	server.foodsAPI = api.NewFoodsAPI(server)
+	server.clinicsAPI = api.NewClinicsAPI(server)
+	server.calendarAPI = api.NewCalendarAPI(server)
+	server.vaccinesAPI = api.NewVaccinesAPI(server)
+	server.notificationsAPI = api.NewNotificationsAPI(server)
+	server.streamAPI = api.NewStreamAPI(server)
+	server.webhooksAPI = api.NewWebhooksAPI(server)
 	return server
 }
 
@@ -55,6 +91,35 @@ func (s *Server) Start() error {
 	if err := s.configureStore(); err != nil {
 		return err
 	}
+	if err := s.configureBlobStore(); err != nil {
+		return err
+	}
+	if err := s.configureJobs(); err != nil {
+		return err
+	}
+	s.scheduler.Start()
+	defer s.scheduler.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.realtime.Run(ctx)
+	if s.config.MQTTAddr != "" {
+		if err := s.checkMQTTConfig(); err != nil {
+			return err
+		}
+		go s.gateway.Run(ctx)
+		go func() {
+			var err error
+			if s.config.MQTTTLSCert != "" {
+				err = s.gateway.ListenAndServeTLS(s.config.MQTTAddr, s.config.MQTTTLSCert, s.config.MQTTTLSKey)
+			} else {
+				err = s.gateway.ListenAndServe(s.config.MQTTAddr)
+			}
+			if err != nil {
+				s.logger.Printf("MQTT gateway error: %v", err)
+			}
+		}()
+		defer s.gateway.Close()
+	}
 	s.logger.Println("starting server")
 	if s.config.BindAddr == "" {
 		log.Fatalln("$PORT is not specified")
@@ -78,6 +143,37 @@ func (s *Server) DumpFilesFolder() string {
 	return s.config.DatabaseDumpsDir
 }
 
+func (s *Server) Notifier() *notifications.Notifier {
+	return s.notifier
+}
+
+func (s *Server) Realtime() *realtime.Broker {
+	return s.realtime
+}
+
+func (s *Server) Webhooks() *webhooks.Dispatcher {
+	return s.webhooks
+}
+
+func (s *Server) DeviceGateway() *gateway.Gateway {
+	return s.gateway
+}
+
+func (s *Server) GeofenceMonitor() *geofence.Monitor {
+	return s.geofences
+}
+
+func (s *Server) BlobStore() blob.BlobStore {
+	return s.blobs
+}
+
+func (s *Server) WebPushPublicKey() string {
+	if s.webPushKeys == nil {
+		return ""
+	}
+	return s.webPushKeys.PublicKey
+}
+
 func (s *Server) Logger() *log.Logger {
 	return s.logger
 }
@@ -110,6 +206,9 @@ func (s *Server) configureRouter() {
 		"Content-Disposition",
 		"X-Requested-With",
 	})
+	exposedOK := handlers.ExposedHeaders([]string{
+		"X-Next-Offset",
+	})
 	originsOK := handlers.AllowedOrigins([]string{"*"})
 	methodsOK := handlers.AllowedMethods([]string{
 		"GET",
@@ -124,7 +223,7 @@ func (s *Server) configureRouter() {
 	s.router.Methods(http.MethodOptions)
 	s.router.Use(s.middleware.InfoMiddleware.MarkRequest)
 	s.router.Use(s.middleware.InfoMiddleware.LogRequest)
-	s.router.Use(handlers.CORS(originsOK, headersOK, methodsOK))
+	s.router.Use(handlers.CORS(originsOK, headersOK, methodsOK, exposedOK))
 	s.router.Use(s.middleware.InfoMiddleware.ProvideOptionsRequest)
 	s.router.Use(s.middleware.ResponseWriting.JSONBody)
 
@@ -134,6 +233,12 @@ func (s *Server) configureRouter() {
 	s.rolesAPI.ConfigureRouter(s.router)
 	s.petsAPI.ConfigureRouter(s.router)
 	s.foodsAPI.ConfigureRouter(s.router)
+	s.clinicsAPI.ConfigureRouter(s.router)
+	s.calendarAPI.ConfigureRouter(s.router)
+	s.vaccinesAPI.ConfigureRouter(s.router)
+	s.notificationsAPI.ConfigureRouter(s.router)
+	s.streamAPI.ConfigureRouter(s.router)
+	s.webhooksAPI.ConfigureRouter(s.router)
 }
 
 func (s *Server) configureStore() error {
@@ -141,6 +246,9 @@ func (s *Server) configureStore() error {
 	if err := database.Open(); err != nil {
 		return err
 	}
+	if err := database.Roles().SeedSystemRoles(); err != nil {
+		return err
+	}
 	s.databaseStore = database
 
 	persistentDatabase := store.NewPersistentStore()
@@ -148,6 +256,141 @@ func (s *Server) configureStore() error {
 		return err
 	}
 	s.persistentStore = persistentDatabase
+	s.realtime = realtime.NewBroker(persistentDatabase, s.logger)
+	return nil
+}
+
+func (s *Server) configureJobs() error {
+	s.scheduler = jobs.NewScheduler(s.databaseStore, s.logger)
+	if err := s.configureNotifications(); err != nil {
+		return err
+	}
+	s.webhooks = webhooks.NewDispatcher(s.databaseStore, s.scheduler, s.logger)
+	s.configureGeofences()
+	s.configureGateway()
+	s.scheduler.Handle(notifications.KindDeliver, s.notifier.Deliver)
+	s.scheduler.Handle(webhooks.KindDeliver, s.webhooks.Deliver)
+	s.scheduler.Handle(jobs.KindVaccineReminders, jobs.VaccineReminders(s.databaseStore, s.notifier))
+	s.scheduler.Handle(jobs.KindDatabaseDump, jobs.DatabaseDump(s.databaseStore, s.config.DatabaseDumpsDir))
+	s.scheduler.Handle(jobs.KindPurgeMissingDumps, jobs.PurgeMissingDumps(s.databaseStore, s.logger))
+	s.scheduler.Handle(jobs.KindSegmentWalks, jobs.SegmentWalks(s.databaseStore))
+	s.scheduler.Handle(jobs.KindAnalyzeHealth, jobs.AnalyzeHealth(s.databaseStore))
+	s.scheduler.Handle(jobs.KindMedicationReminders, jobs.MedicationReminders(s.databaseStore, s.notifier))
+
+	if err := s.scheduler.Schedule("daily-vaccine-reminders", "0 8 * * *", jobs.KindVaccineReminders, nil); err != nil {
+		return err
+	}
+	if err := s.scheduler.Schedule("walks-segmentation", "*/5 * * * *", jobs.KindSegmentWalks, nil); err != nil {
+		return err
+	}
+	if err := s.scheduler.Schedule("medication-reminders", "*/15 * * * *", jobs.KindMedicationReminders, nil); err != nil {
+		return err
+	}
+	if err := s.scheduler.Schedule("daily-health-analysis", "0 5 * * *", jobs.KindAnalyzeHealth, nil); err != nil {
+		return err
+	}
+	if err := s.scheduler.Schedule("nightly-database-dump", "0 2 * * *", jobs.KindDatabaseDump, nil); err != nil {
+		return err
+	}
+	return s.scheduler.Schedule("nightly-missing-dumps-purge", "30 2 * * *", jobs.KindPurgeMissingDumps, nil)
+}
+
+// checkMQTTConfig refuses to start the MQTT listener reachable from the network without TLS,
+// the collars send their access secrets in the CONNECT packet
+func (s *Server) checkMQTTConfig() error {
+	if (s.config.MQTTTLSCert == "") != (s.config.MQTTTLSKey == "") {
+		return errors.New("both $MQTT_TLS_CERT and $MQTT_TLS_KEY must be specified")
+	}
+	if s.config.MQTTTLSCert != "" {
+		return nil
+	}
+	return gateway.CheckPlainTextAddr(s.config.MQTTAddr)
+}
+
+// configureBlobStore keeps the attachments in the S3 compatible storage when it is configured, on the local disk otherwise
+func (s *Server) configureBlobStore() error {
+	if s.config.S3Endpoint != "" {
+		s.blobs = &blob.S3Store{
+			Endpoint:  s.config.S3Endpoint,
+			Region:    s.config.S3Region,
+			Bucket:    s.config.S3Bucket,
+			AccessKey: s.config.S3AccessKeyID,
+			SecretKey: s.config.S3SecretAccessKey,
+		}
+		return nil
+	}
+	blobs, err := blob.NewLocalStore(s.config.AttachmentsDir)
+	if err != nil {
+		return err
+	}
+	s.blobs = blobs
+	return nil
+}
+
+// configureGateway creates the MQTT gateway of the collars. It is created even when the listener is disabled,
+// so the instance can send config and commands to the collars connected to the other instances.
+func (s *Server) configureGateway() {
+	s.gateway = gateway.New(
+		s.databaseStore.IoTDevicesRepository(),
+		s.databaseStore.Pets(),
+		s.databaseStore.Walks(),
+		s.persistentStore,
+		s.logger,
+	)
+	s.gateway.OnActivity = func(activity *models.Activity) {
+		if err := s.realtime.PublishPet(activity.PetID, realtime.EventActivityCreated, activity); err != nil {
+			s.logger.Printf("Stream error: %v", err)
+		}
+		if err := s.webhooks.Dispatch(models.WebhookActivityRecorded, activity.PetID, activity); err != nil {
+			s.logger.Printf("Webhooks error: %v", err)
+		}
+	}
+	s.gateway.OnLocation = func(petID int, points []tracks.Point) {
+		if _, err := s.geofences.Process(petID, points); err != nil {
+			s.logger.Printf("Geofence error: %v", err)
+		}
+	}
+}
+
+// configureGeofences creates the monitor of the pet zones, the owners are alerted by the notifier
+func (s *Server) configureGeofences() {
+	s.geofences = geofence.NewMonitor(s.databaseStore, s.notifier, s.logger)
+	s.geofences.OnEvent = func(event *models.GeofenceEvent) {
+		if err := s.realtime.PublishPet(event.PetID, realtime.EventGeofenceCrossed, event); err != nil {
+			s.logger.Printf("Stream error: %v", err)
+		}
+	}
+}
+
+// configureNotifications enables the delivery channels which are configured by the environment
+func (s *Server) configureNotifications() error {
+	s.notifier = notifications.NewNotifier(s.databaseStore, s.scheduler, s.realtime, s.logger)
+	if notificationsConfigs.SMTPAddr != "" {
+		s.notifier.Register(models.ChannelEmail, &notifications.EmailChannel{
+			Mailer: &notifications.SMTPMailer{
+				Addr:     notificationsConfigs.SMTPAddr,
+				Username: notificationsConfigs.SMTPUsername,
+				Password: notificationsConfigs.SMTPPassword,
+			},
+			From: notificationsConfigs.EmailFrom,
+		})
+	}
+	if notificationsConfigs.VAPIDPrivateKey != "" {
+		keys, err := webpush.ParseVAPIDKeys(notificationsConfigs.VAPIDPrivateKey)
+		if err != nil {
+			return err
+		}
+		s.webPushKeys = keys
+		s.notifier.Register(models.ChannelWebPush, &notifications.WebPushChannel{
+			// The push service endpoints are given by the browsers, they are kept off the internal network too
+			Pusher: &webpush.Sender{
+				Keys:    keys,
+				Subject: notificationsConfigs.VAPIDSubject,
+				Client:  netguard.NewClient(10 * time.Second),
+			},
+		})
+	}
+	s.notifier.Register(models.ChannelWebhook, &notifications.WebhookChannel{})
 	return nil
 }
 
@@ -1,7 +1,9 @@
 package persistentstore
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/persistentstore/configs"
 	"github.com/go-redis/redis/v7"
@@ -94,3 +96,29 @@ func (s *RedisStore) GetUserIDByRefreshUUID(refreshUUID string) (int, error) {
 func (s *RedisStore) DeleteRefreshByUUID(refreshUUID string) error {
 	return s.db.Del(refreshUUID).Err()
 }
+
+func (s *RedisStore) Publish(channel string, message []byte) error {
+	return s.db.Publish(channel, message).Err()
+}
+
+// Subscribe passes messages of the channels matching the pattern to the handler until ctx is done.
+// The client reconnects by itself, an error is returned only if the subscription can not be made.
+func (s *RedisStore) Subscribe(ctx context.Context, pattern string, handler func(channel string, message []byte)) error {
+	pubSub := s.db.PSubscribe(pattern)
+	defer pubSub.Close()
+	if _, err := pubSub.Receive(); err != nil {
+		return err
+	}
+	messages := pubSub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return errors.New("redis subscription is closed")
+			}
+			handler(message.Channel, []byte(message.Payload))
+		}
+	}
+}
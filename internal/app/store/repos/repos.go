@@ -1,6 +1,7 @@
 package repos
 
 import (
+	"database/sql"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
 	"time"
 )
@@ -22,6 +23,10 @@ type UserRepository interface {
 	UpdateClinic(clinic *models.VetClinic) (*models.VetClinic, error)
 	DeleteClinic(userID int) (*models.VetClinic, error)
 
+	FindCalendarFeed(userID int) (*models.CalendarFeed, error)
+	SaveCalendarFeed(userID int, feedKey string) (*models.CalendarFeed, error)
+	DeleteCalendarFeed(userID int) error
+
 	GetStatistics() ([]models.RegisterStatistics, []models.SubscribeStatistics, []models.User, error)
 }
 
@@ -33,6 +38,10 @@ type RoleRepository interface {
 	Create(role *models.Role) (*models.Role, error)
 	Update(newRole *models.Role) (*models.Role, error)
 	DeleteByID(roleID int) (*models.Role, error)
+
+	SeedSystemRoles() error
+	SelectDefaultRole() (*models.Role, error)
+	SetDefaultRole(roleID int) (*models.Role, error)
 }
 
 type PetRepository interface {
@@ -65,6 +74,7 @@ type PetRepository interface {
 	DeleteAnthropometryByID(aID int) (*models.Anthropometry, error)
 
 	CreateActivityRecord(record *models.Activity) error
+	CreateActivityRecords(records []models.Activity) ([]string, error)
 	SelectPetActivityRecords(petID int) ([]models.Activity, error)
 	SelectPetActivityRecordsInInterval(petID int, start time.Time, end time.Time) ([]models.Activity, error)
 	SelectPetActivityRecordsToTime(petID int, start time.Time) ([]models.Activity, error)
@@ -79,6 +89,10 @@ type PetRepository interface {
 
 	CreatePetHealthReport(report *models.PetHealthReport) error
 	GetAllPetHealthReports(petID int) ([]models.PetHealthReport, error)
+	FindPetHealthReport(reportID int) (*models.PetHealthReport, error)
+	UpdatePetHealthReport(report *models.PetHealthReport, editedBy int, amendmentReason string) error
+	SignPetHealthReport(reportID int, version int, signedBy int) (*models.PetHealthReport, error)
+	SelectPetHealthReportVersions(reportID int) ([]models.PetHealthReportVersion, error)
 }
 
 type VaccineRepository interface {
@@ -87,18 +101,33 @@ type VaccineRepository interface {
 	Create(vaccine *models.Vaccine) (*models.Vaccine, error)
 	Update(vaccine *models.Vaccine) (*models.Vaccine, error)
 	DeleteByID(vaccineID int) (*models.Vaccine, error)
+
+	SelectCatalogue() ([]models.VaccineCatalogueEntry, error)
+	SelectCatalogueByPetType(petType int) ([]models.VaccineCatalogueEntry, error)
+	FindCatalogueEntryByID(catalogueID int) (*models.VaccineCatalogueEntry, error)
+	CreateCatalogueEntry(entry *models.VaccineCatalogueEntry) (*models.VaccineCatalogueEntry, error)
+	UpdateCatalogueEntry(entry *models.VaccineCatalogueEntry) (*models.VaccineCatalogueEntry, error)
+	DeleteCatalogueEntryByID(catalogueID int) (*models.VaccineCatalogueEntry, error)
+
+	SelectRemindersByPetID(petID int) ([]models.VaccineReminder, error)
+	CreateReminders(reminders []models.VaccineReminder) error
 }
 
 type FoodRepository interface {
 	SelectAll() ([]models.Food, error)
 	FindByID(foodID int) (*models.Food, error)
-	SelectByNameSimilarity(namePattern string) ([]models.Food, error)
+	FindByBarcode(code string) (*models.Food, error)
+	Search(query models.FoodSearchQuery) ([]models.FoodSearchResult, error)
+	SelectByNames(names []string) ([]models.Food, error)
+	SelectBarcodeOwners(codes []string) (map[string]int, error)
 	Create(foodModel *models.Food) (*models.Food, error)
 	Update(foodModel *models.Food) (*models.Food, error)
 	DeleteByID(foodID int) (*models.Food, error)
+	Import(creates []*models.Food, updates []*models.Food) error
 
 	AddPetEating(eating *models.Eating) error
 	GetPetsEatingsForDate(petID int, date time.Time) ([]models.Eating, error)
+	GetPetsEatingsForPeriod(petID int, from time.Time, to time.Time) ([]models.Eating, error)
 	GetPetsEatings(petID int) ([]models.Eating, error)
 }
 
@@ -114,4 +143,181 @@ type DumpRepository interface {
 type IoTDevicesRepository interface {
 	GetByID(deviceID int) (*models.IoTDevice, error)
 	GetByAccessSecret(accessSecret string) (*models.IoTDevice, error)
+	Create(device *models.IoTDevice) (*models.IoTDevice, error)
+	SelectByPetID(petID int) ([]models.IoTDevice, error)
+	Update(device *models.IoTDevice) (*models.IoTDevice, error)
+	Touch(deviceID int, telemetry *models.DeviceTelemetry, seenAt time.Time) error
+}
+
+type ClinicRepository interface {
+	SelectAll() ([]models.Clinic, error)
+	SelectByMemberID(userID int) ([]models.Clinic, error)
+	FindByID(clinicID int) (*models.Clinic, error)
+	Create(clinic *models.Clinic, creatorID int) (*models.Clinic, error)
+	Update(clinic *models.Clinic) (*models.Clinic, error)
+	DeleteByID(clinicID int) (*models.Clinic, error)
+
+	SelectOpeningHours(clinicID int) ([]models.ClinicOpeningHours, error)
+	ReplaceOpeningHours(clinicID int, hours []models.ClinicOpeningHours) ([]models.ClinicOpeningHours, error)
+
+	SelectMembers(clinicID int) ([]models.ClinicMember, error)
+	FindMember(clinicID int, userID int) (*models.ClinicMember, error)
+	AddMember(member *models.ClinicMember) (*models.ClinicMember, error)
+	UpdateMemberRole(clinicID int, userID int, memberRole string) (*models.ClinicMember, error)
+	RemoveMember(clinicID int, userID int) (*models.ClinicMember, error)
+	CountAdministrators(clinicID int) (int, error)
+
+	SelectPatients(clinicID int) ([]models.Pet, error)
+	RegisterPatient(clinicID int, petID int) error
+	UnregisterPatient(petID int) error
+}
+
+type AppointmentRepository interface {
+	SelectByPetID(petID int) ([]models.Appointment, error)
+	SelectByVeterinarianInInterval(veterinarianID int, start time.Time, end time.Time) ([]models.Appointment, error)
+	SelectByParticipantFrom(userID int, from time.Time) ([]models.Appointment, error)
+	FindByID(appointmentID int) (*models.Appointment, error)
+	Create(appointment *models.Appointment) (*models.Appointment, error)
+	UpdateStatus(appointmentID int, status string) (*models.Appointment, error)
+
+	SelectAvailability(veterinarianID int) ([]models.VeterinarianAvailability, error)
+	ReplaceAvailability(veterinarianID int, availability []models.VeterinarianAvailability) ([]models.VeterinarianAvailability, error)
+}
+
+type JobRepository interface {
+	Enqueue(kind string, payload []byte, runAt time.Time, maxAttempts int) (*models.Job, error)
+	ClaimNext(now time.Time) (*models.Job, error)
+	Complete(jobID int) error
+	Retry(jobID int, lastError string, runAt time.Time) error
+	Fail(jobID int, lastError string) error
+	RequeueStale(lockedBefore time.Time) (int64, error)
+
+	FindSchedule(name string) (*models.JobSchedule, error)
+	EnqueueScheduled(schedule *models.JobSchedule, kind string, payload []byte, maxAttempts int) (*models.Job, error)
+
+	TryAdvisoryLock(key int64) (AdvisoryLock, bool, error)
+}
+
+// AdvisoryLock is the database lock held by the server instance, e.g. to be elected as leader
+type AdvisoryLock interface {
+	Ping() error
+	Release() error
+}
+
+type NotificationRepository interface {
+	Create(notification *models.Notification) (*models.Notification, error)
+	FindByID(notificationID int) (*models.Notification, error)
+	SelectByUserID(userID int, unreadOnly bool, limit int, offset int) ([]models.Notification, error)
+	CountUnread(userID int) (int, error)
+	SetRead(userID int, notificationID int, read bool) (*models.Notification, error)
+	MarkAllRead(userID int) error
+	DeleteByID(userID int, notificationID int) (*models.Notification, error)
+
+	SelectPreferences(userID int) ([]models.NotificationPreference, error)
+	SavePreferences(userID int, preferences []models.NotificationPreference) error
+
+	SelectEndpoints(userID int) ([]models.NotificationEndpoint, error)
+	FindEndpointByID(endpointID int) (*models.NotificationEndpoint, error)
+	CreateEndpoint(endpoint *models.NotificationEndpoint) (*models.NotificationEndpoint, error)
+	DeleteEndpoint(userID int, endpointID int) (*models.NotificationEndpoint, error)
+}
+
+type WebhookRepository interface {
+	Create(subscription *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	FindByID(subscriptionID int) (*models.WebhookSubscription, error)
+	SelectByUserID(userID int) ([]models.WebhookSubscription, error)
+	SelectSubscribed(userIDs []int, event string) ([]models.WebhookSubscription, error)
+	Update(subscription *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	DeleteByID(userID int, subscriptionID int) (*models.WebhookSubscription, error)
+
+	CreateDelivery(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
+	FindDeliveryByID(deliveryID int) (*models.WebhookDelivery, error)
+	SelectDeliveries(subscriptionID int, limit int, offset int) ([]models.WebhookDelivery, error)
+	RecordAttempt(deliveryID int, status string, responseStatus int, lastError string) error
+}
+
+type WalkRepository interface {
+	CreateTrackPoints(points []models.TrackPoint) (int, error)
+	SelectPetsWithPendingPoints() ([]int, error)
+	SelectPendingPoints(petID int) ([]models.TrackPoint, error)
+	CreateWalk(walk *models.Walk, pointIDs []int64, segmentedIDs []int64) (*models.Walk, error)
+	MarkSegmented(pointIDs []int64) error
+
+	FindByID(walkID int) (*models.Walk, error)
+	SelectByPetID(petID int, start time.Time, end time.Time) ([]models.Walk, error)
+	SelectPoints(walkIDs []int) ([]models.TrackPoint, error)
+}
+
+type GeofenceRepository interface {
+	Create(fence *models.Geofence) (*models.Geofence, error)
+	FindByID(geofenceID int) (*models.Geofence, error)
+	SelectByPetID(petID int) ([]models.Geofence, error)
+	SelectActiveByPetID(petID int) ([]models.Geofence, error)
+	Update(fence *models.Geofence) (*models.Geofence, error)
+	DeleteByID(geofenceID int) (*models.Geofence, error)
+
+	SaveEvaluation(fence *models.Geofence, previousFixAt *sql.NullTime, events []models.GeofenceEvent) ([]models.GeofenceEvent, bool, error)
+	SelectEvents(petID int, limit int, offset int) ([]models.GeofenceEvent, error)
+}
+
+type HealthAlertRepository interface {
+	Sync(petID int, alerts []models.HealthAlert) ([]models.HealthAlert, error)
+	SelectByPetID(petID int, withResolved bool) ([]models.HealthAlert, error)
+	SelectOpenByPetIDs(petIDs []int) ([]models.HealthAlert, error)
+}
+
+type FeedingPlanRepository interface {
+	Create(plan *models.FeedingPlan) (*models.FeedingPlan, error)
+	FindByID(planID int) (*models.FeedingPlan, error)
+	SelectByPetID(petID int) ([]models.FeedingPlan, error)
+	SelectForPeriod(petID int, from time.Time, to time.Time) ([]models.FeedingPlan, error)
+	Update(plan *models.FeedingPlan) (*models.FeedingPlan, error)
+	DeleteByID(planID int) (*models.FeedingPlan, error)
+}
+
+type MedicationRepository interface {
+	Create(medication *models.Medication) (*models.Medication, error)
+	FindByID(medicationID int) (*models.Medication, error)
+	SelectByPetID(petID int, activeAt *time.Time) ([]models.Medication, error)
+	SelectScheduled(now time.Time) ([]models.Medication, error)
+	Update(medication *models.Medication) (*models.Medication, error)
+	DeleteByID(medicationID int) (*models.Medication, error)
+	SaveReminders(medicationID int, remindedUntil time.Time, checkedUntil time.Time) error
+
+	CreateDose(dose *models.MedicationDose) (*models.MedicationDose, error)
+	SelectDoses(medicationID int, from time.Time, to time.Time) ([]models.MedicationDose, error)
+	DeleteDose(medicationID int, doseID int) (*models.MedicationDose, error)
+}
+
+type MedicalRecordRepository interface {
+	CreateCondition(condition *models.Condition) (*models.Condition, error)
+	FindCondition(conditionID int) (*models.Condition, error)
+	SelectConditions(petID int, problemsOnly bool) ([]models.Condition, error)
+	SelectPatientConditions(veterinarianID int, name string, code string, status string) ([]models.PatientCondition, error)
+	UpdateCondition(condition *models.Condition, changedBy int) (*models.Condition, error)
+	DeleteCondition(conditionID int) (*models.Condition, error)
+
+	CreateAllergy(allergy *models.Allergy) (*models.Allergy, error)
+	FindAllergy(allergyID int) (*models.Allergy, error)
+	SelectAllergies(petID int, activeOnly bool) ([]models.Allergy, error)
+	UpdateAllergy(allergy *models.Allergy) (*models.Allergy, error)
+	DeleteAllergy(allergyID int) (*models.Allergy, error)
+
+	CreateProcedure(procedure *models.Procedure) (*models.Procedure, error)
+	FindProcedure(procedureID int) (*models.Procedure, error)
+	SelectProcedures(petID int) ([]models.Procedure, error)
+	UpdateProcedure(procedure *models.Procedure) (*models.Procedure, error)
+	DeleteProcedure(procedureID int) (*models.Procedure, error)
+
+	CreateLabPanel(panel *models.LabPanel) (*models.LabPanel, error)
+	FindLabPanel(panelID int) (*models.LabPanel, error)
+	SelectLabPanels(petID int) ([]models.LabPanel, error)
+	DeleteLabPanel(panelID int) (*models.LabPanel, error)
+}
+
+type AttachmentRepository interface {
+	Create(attachment *models.Attachment) (*models.Attachment, error)
+	FindByID(attachmentID int) (*models.Attachment, error)
+	SelectByPetID(petID int, reportID int) ([]models.Attachment, error)
+	DeleteByID(attachmentID int) (*models.Attachment, error)
 }
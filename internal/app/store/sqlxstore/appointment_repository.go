@@ -0,0 +1,164 @@
+package sqlxstore
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
+)
+
+type AppointmentRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *AppointmentRepository) SelectByPetID(petID int) ([]models.Appointment, error) {
+	query := `SELECT * FROM public.appointments WHERE pet_id = $1 ORDER BY starts_at DESC;`
+	var appointments []models.Appointment
+	if err := r.store.db.Select(&appointments, query, petID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range appointments {
+		appointments[idx].AfterCreate()
+	}
+	return appointments, nil
+}
+
+// SelectByVeterinarianInInterval returns veterinarian appointments starting within [start, end)
+func (r *AppointmentRepository) SelectByVeterinarianInInterval(veterinarianID int, start time.Time, end time.Time) ([]models.Appointment, error) {
+	query := `
+		SELECT * FROM public.appointments
+		WHERE veterinarian_id = $1 AND starts_at >= $2 AND starts_at < $3
+		ORDER BY starts_at;`
+	var appointments []models.Appointment
+	if err := r.store.db.Select(&appointments, query, veterinarianID, start, end); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range appointments {
+		appointments[idx].AfterCreate()
+	}
+	return appointments, nil
+}
+
+// SelectByParticipantFrom returns appointments starting after from, where user is either pet owner or veterinarian
+func (r *AppointmentRepository) SelectByParticipantFrom(userID int, from time.Time) ([]models.Appointment, error) {
+	query := `
+		SELECT * FROM public.appointments
+		WHERE (owner_id = $1 OR veterinarian_id = $1) AND starts_at >= $2
+		ORDER BY starts_at;`
+	var appointments []models.Appointment
+	if err := r.store.db.Select(&appointments, query, userID, from); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range appointments {
+		appointments[idx].AfterCreate()
+	}
+	return appointments, nil
+}
+
+func (r *AppointmentRepository) FindByID(appointmentID int) (*models.Appointment, error) {
+	query := `SELECT * FROM public.appointments WHERE appointment_id = $1;`
+	appointment := &models.Appointment{}
+	if err := r.store.db.Get(appointment, query, appointmentID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			r.store.logger.Println(err)
+		}
+		return nil, err
+	}
+	appointment.AfterCreate()
+	return appointment, nil
+}
+
+/*
+Create books the appointment. Overlapping active appointments of the same
+veterinarian are rejected by the appointments_no_double_booking exclusion
+constraint, in this case the returned error is *pq.Error with 23P01 code.
+*/
+func (r *AppointmentRepository) Create(appointment *models.Appointment) (*models.Appointment, error) {
+	query := `
+		INSERT INTO public.appointments
+			(pet_id, owner_id, veterinarian_id, clinic_id, starts_at, ends_at, reason, status, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING appointment_id;`
+
+	appointment.BeforeCreate()
+	var appointmentID int
+	if err := r.store.db.QueryRowx(
+		query,
+		appointment.PetID,
+		appointment.OwnerID,
+		appointment.VeterinarianID,
+		appointment.ClinicID,
+		appointment.StartsAt,
+		appointment.EndsAt,
+		appointment.Reason,
+		appointment.Status,
+		time.Now(),
+	).Scan(&appointmentID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindByID(appointmentID)
+}
+
+func (r *AppointmentRepository) UpdateStatus(appointmentID int, status string) (*models.Appointment, error) {
+	query := `UPDATE public.appointments SET status = $1 WHERE appointment_id = $2;`
+	if _, err := r.store.db.Exec(query, status, appointmentID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindByID(appointmentID)
+}
+
+func (r *AppointmentRepository) SelectAvailability(veterinarianID int) ([]models.VeterinarianAvailability, error) {
+	query := `
+		SELECT * FROM public.veterinarian_availability
+		WHERE veterinarian_id = $1
+		ORDER BY weekday, starts_at;`
+	var availability []models.VeterinarianAvailability
+	if err := r.store.db.Select(&availability, query, veterinarianID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range availability {
+		availability[idx].AfterCreate()
+	}
+	return availability, nil
+}
+
+// ReplaceAvailability overwrites the whole veterinarian weekly schedule with the specified one
+func (r *AppointmentRepository) ReplaceAvailability(veterinarianID int, availability []models.VeterinarianAvailability) ([]models.VeterinarianAvailability, error) {
+	deleteQuery := `DELETE FROM public.veterinarian_availability WHERE veterinarian_id = $1;`
+	insertQuery := `
+		INSERT INTO public.veterinarian_availability (veterinarian_id, weekday, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4);`
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(deleteQuery, veterinarianID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for _, interval := range availability {
+		if _, err := transaction.Exec(insertQuery, veterinarianID, interval.Weekday, interval.StartsAt, interval.EndsAt); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.SelectAvailability(veterinarianID)
+}
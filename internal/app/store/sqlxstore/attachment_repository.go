@@ -0,0 +1,81 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+)
+
+type AttachmentRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *AttachmentRepository) Create(attachment *models.Attachment) (*models.Attachment, error) {
+	attachment.BeforeCreate()
+	if err := r.store.db.Get(
+		attachment,
+		`INSERT INTO public.attachments
+			(pet_id, report_id, uploaded_by, file_name, content_type, size, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING *;`,
+		attachment.PetID,
+		attachment.ReportID,
+		attachment.UploadedBy,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.StorageKey,
+		attachment.ThumbnailKey,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	attachment.AfterCreate()
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) FindByID(attachmentID int) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	if err := r.store.db.Get(
+		attachment,
+		`SELECT * FROM public.attachments WHERE attachment_id = $1;`,
+		attachmentID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	attachment.AfterCreate()
+	return attachment, nil
+}
+
+// SelectByPetID returns the pet attachments, the latest first, only the ones of the health report when reportID is set
+func (r *AttachmentRepository) SelectByPetID(petID int, reportID int) ([]models.Attachment, error) {
+	attachments := make([]models.Attachment, 0)
+	if err := r.store.db.Select(
+		&attachments,
+		`SELECT * FROM public.attachments
+		WHERE pet_id = $1 AND ($2 = 0 OR report_id = $2)
+		ORDER BY created_at DESC, attachment_id DESC;`,
+		petID,
+		reportID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range attachments {
+		attachments[idx].AfterCreate()
+	}
+	return attachments, nil
+}
+
+func (r *AttachmentRepository) DeleteByID(attachmentID int) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	if err := r.store.db.Get(
+		attachment,
+		`DELETE FROM public.attachments WHERE attachment_id = $1 RETURNING *;`,
+		attachmentID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	attachment.AfterCreate()
+	return attachment, nil
+}
@@ -0,0 +1,396 @@
+package sqlxstore
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
+)
+
+type ClinicRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *ClinicRepository) SelectAll() ([]models.Clinic, error) {
+	query := `SELECT * FROM public.clinics ORDER BY name;`
+	var clinics []models.Clinic
+	if err := r.store.db.Select(&clinics, query); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range clinics {
+		clinics[idx].AfterCreate()
+	}
+	return clinics, nil
+}
+
+func (r *ClinicRepository) SelectByMemberID(userID int) ([]models.Clinic, error) {
+	query := `
+		SELECT c.* FROM public.clinics c
+		INNER JOIN public.clinic_members m ON m.clinic_id = c.clinic_id
+		WHERE m.user_id = $1
+		ORDER BY c.name;`
+	var clinics []models.Clinic
+	if err := r.store.db.Select(&clinics, query, userID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range clinics {
+		clinics[idx].AfterCreate()
+	}
+	return clinics, nil
+}
+
+func (r *ClinicRepository) FindByID(clinicID int) (*models.Clinic, error) {
+	query := `SELECT * FROM public.clinics WHERE clinic_id = $1;`
+	clinic := &models.Clinic{}
+	if err := r.store.db.Get(clinic, query, clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	clinic.AfterCreate()
+	return clinic, nil
+}
+
+/*
+Create inserts the new clinic and registers the creator as its
+administrator within the same transaction, so that any clinic
+always has at least one member who can manage it.
+*/
+func (r *ClinicRepository) Create(clinic *models.Clinic, creatorID int) (*models.Clinic, error) {
+	insertQuery := `
+		INSERT INTO public.clinics
+			(name, registration_number, address, city, phone, email, website, created_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING clinic_id;`
+	memberQuery := `
+		INSERT INTO public.clinic_members (clinic_id, user_id, member_role, joined_at)
+		VALUES ($1, $2, $3, $4);`
+
+	clinic.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	now := time.Now()
+	var clinicID int
+	if err := transaction.QueryRowx(
+		insertQuery,
+		clinic.Name,
+		clinic.RegistrationNumber,
+		clinic.Address,
+		clinic.City,
+		clinic.Phone,
+		clinic.Email,
+		clinic.Website,
+		now,
+	).Scan(&clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if _, err := transaction.Exec(memberQuery, clinicID, creatorID, models.ClinicRoleAdministrator, now); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindByID(clinicID)
+}
+
+func (r *ClinicRepository) Update(clinic *models.Clinic) (*models.Clinic, error) {
+	updateQuery := `
+		UPDATE public.clinics
+		SET
+			name = :name,
+			registration_number = :registration_number,
+			address = :address,
+			city = :city,
+			phone = :phone,
+			email = :email,
+			website = :website
+		WHERE clinic_id = :clinic_id;`
+
+	updatingClinic, err := r.FindByID(clinic.ClinicID)
+	if err != nil {
+		return nil, err
+	}
+	updatingClinic.Update(clinic)
+	updatingClinic.BeforeCreate()
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.NamedExec(updateQuery, updatingClinic); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindByID(clinic.ClinicID)
+}
+
+func (r *ClinicRepository) DeleteByID(clinicID int) (*models.Clinic, error) {
+	deleteQuery := `DELETE FROM public.clinics WHERE clinic_id = $1;`
+	deletingClinic, err := r.FindByID(clinicID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(deleteQuery, clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return deletingClinic, nil
+}
+
+func (r *ClinicRepository) SelectOpeningHours(clinicID int) ([]models.ClinicOpeningHours, error) {
+	query := `SELECT * FROM public.clinic_opening_hours WHERE clinic_id = $1 ORDER BY weekday, opens_at;`
+	var hours []models.ClinicOpeningHours
+	if err := r.store.db.Select(&hours, query, clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range hours {
+		hours[idx].AfterCreate()
+	}
+	return hours, nil
+}
+
+// ReplaceOpeningHours overwrites the whole clinic schedule with the specified one
+func (r *ClinicRepository) ReplaceOpeningHours(clinicID int, hours []models.ClinicOpeningHours) ([]models.ClinicOpeningHours, error) {
+	deleteQuery := `DELETE FROM public.clinic_opening_hours WHERE clinic_id = $1;`
+	insertQuery := `
+		INSERT INTO public.clinic_opening_hours (clinic_id, weekday, opens_at, closes_at)
+		VALUES ($1, $2, $3, $4);`
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(deleteQuery, clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for _, interval := range hours {
+		if _, err := transaction.Exec(insertQuery, clinicID, interval.Weekday, interval.OpensAt, interval.ClosesAt); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.SelectOpeningHours(clinicID)
+}
+
+func (r *ClinicRepository) SelectMembers(clinicID int) ([]models.ClinicMember, error) {
+	query := `SELECT * FROM public.clinic_members WHERE clinic_id = $1 ORDER BY joined_at;`
+	var members []models.ClinicMember
+	if err := r.store.db.Select(&members, query, clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *ClinicRepository) FindMember(clinicID int, userID int) (*models.ClinicMember, error) {
+	query := `SELECT * FROM public.clinic_members WHERE clinic_id = $1 AND user_id = $2;`
+	member := &models.ClinicMember{}
+	if err := r.store.db.Get(member, query, clinicID, userID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			r.store.logger.Println(err)
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *ClinicRepository) AddMember(member *models.ClinicMember) (*models.ClinicMember, error) {
+	query := `
+		INSERT INTO public.clinic_members (clinic_id, user_id, member_role, joined_at)
+		VALUES (:clinic_id, :user_id, :member_role, :joined_at);`
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.NamedExec(query, member); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindMember(member.ClinicID, member.UserID)
+}
+
+func (r *ClinicRepository) UpdateMemberRole(clinicID int, userID int, memberRole string) (*models.ClinicMember, error) {
+	query := `UPDATE public.clinic_members SET member_role = $1 WHERE clinic_id = $2 AND user_id = $3;`
+	if _, err := r.FindMember(clinicID, userID); err != nil {
+		return nil, err
+	}
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(query, memberRole, clinicID, userID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindMember(clinicID, userID)
+}
+
+func (r *ClinicRepository) RemoveMember(clinicID int, userID int) (*models.ClinicMember, error) {
+	query := `DELETE FROM public.clinic_members WHERE clinic_id = $1 AND user_id = $2;`
+	member, err := r.FindMember(clinicID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(query, clinicID, userID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *ClinicRepository) CountAdministrators(clinicID int) (int, error) {
+	query := `SELECT COUNT(*) FROM public.clinic_members WHERE clinic_id = $1 AND member_role = $2;`
+	var count int
+	if err := r.store.db.Get(&count, query, clinicID, models.ClinicRoleAdministrator); err != nil {
+		r.store.logger.Println(err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *ClinicRepository) SelectPatients(clinicID int) ([]models.Pet, error) {
+	query := `SELECT * FROM public.pets WHERE clinic_id = $1 ORDER BY name;`
+	var pets []models.Pet
+	if err := r.store.db.Select(&pets, query, clinicID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range pets {
+		pets[idx].AfterCreate()
+	}
+	return pets, nil
+}
+
+func (r *ClinicRepository) RegisterPatient(clinicID int, petID int) error {
+	query := `UPDATE public.pets SET clinic_id = $1 WHERE pet_id = $2;`
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(query, clinicID, petID); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (r *ClinicRepository) UnregisterPatient(petID int) error {
+	query := `UPDATE public.pets SET clinic_id = NULL WHERE pet_id = $1;`
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if _, err := transaction.Exec(query, petID); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
@@ -21,6 +21,7 @@ Make creates the .sql dump file for the database
 with all public schema definitions and stored data.
 
 It accepts the folder, where created dump would be saved.
+The call blocks until pg_dump finishes, the record is stored only for the complete file.
 */
 func (r *DumpRepository) Make(savePath string) (*models.Dump, error) {
 	psqlConnectionAddr := r.store.config.ConnectionString
@@ -42,16 +43,13 @@ func (r *DumpRepository) Make(savePath string) (*models.Dump, error) {
 	}
 
 	var stderr bytes.Buffer
-	var stdout bytes.Buffer
 	cmd := exec.Command("pg_dump", psqlConnectionAddr, "--column-inserts", "-f", migrationFilePath)
 	cmd.Stderr = &stderr
-	cmd.Stdout = &stdout
-	go func() {
-		err := cmd.Run()
-		if err != nil {
-			r.store.logger.Println(err)
-		}
-	}()
+	if err := cmd.Run(); err != nil {
+		r.store.logger.Println(err, stderr.String())
+		filesutil.Delete(migrationFilePath)
+		return nil, fmt.Errorf("pg_dump: %w: %s", err, stderr.String())
+	}
 
 	dumpFile := models.Dump{
 		FilePath:  migrationFilePath,
@@ -61,11 +59,11 @@ func (r *DumpRepository) Make(savePath string) (*models.Dump, error) {
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
 		r.store.logger.Println(err)
+		filesutil.Delete(migrationFilePath)
 		return nil, err
 	}
 	defer func(tx *sqlx.Tx) {
-		filesutil.Delete(migrationFilePath)
-		_ = transaction.Rollback()
+		_ = tx.Rollback()
 	}(transaction)
 
 	if _, err := transaction.NamedExec(
@@ -73,11 +71,13 @@ func (r *DumpRepository) Make(savePath string) (*models.Dump, error) {
 		dumpFile,
 	); err != nil {
 		r.store.logger.Println(err)
+		filesutil.Delete(migrationFilePath)
 		return nil, err
 	}
 
 	if err := transaction.Commit(); err != nil {
 		r.store.logger.Println(err)
+		filesutil.Delete(migrationFilePath)
 		return nil, err
 	}
 	dumpFile.AfterCreate()
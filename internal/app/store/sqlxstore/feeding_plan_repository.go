@@ -0,0 +1,212 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+type FeedingPlanRepository struct {
+	store *PostgreDatabaseStore
+}
+
+// Create stores the plan with its meals in one transaction
+func (r *FeedingPlanRepository) Create(plan *models.FeedingPlan) (*models.FeedingPlan, error) {
+	plan.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	meals := plan.Meals
+	if err := transaction.Get(
+		plan,
+		`INSERT INTO public.feeding_plans (pet_id, name, time_zone, starts_on, ends_on, tolerance_minutes, creator_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *;`,
+		plan.PetID,
+		plan.Name,
+		plan.TimeZone,
+		plan.StartsOn,
+		plan.EndsOn,
+		plan.ToleranceMinutes,
+		plan.CreatorID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if plan.Meals, err = r.insertMeals(transaction, plan.PlanID, meals); err != nil {
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	plan.AfterCreate()
+	return plan, nil
+}
+
+func (r *FeedingPlanRepository) FindByID(planID int) (*models.FeedingPlan, error) {
+	plan := &models.FeedingPlan{}
+	if err := r.store.db.Get(plan, `SELECT * FROM public.feeding_plans WHERE plan_id = $1;`, planID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	plans := []models.FeedingPlan{*plan}
+	if err := r.attachMeals(plans); err != nil {
+		return nil, err
+	}
+	return &plans[0], nil
+}
+
+func (r *FeedingPlanRepository) SelectByPetID(petID int) ([]models.FeedingPlan, error) {
+	return r.selectPlans(`SELECT * FROM public.feeding_plans WHERE pet_id = $1 ORDER BY starts_on, plan_id;`, petID)
+}
+
+// SelectForPeriod returns the plans of the pet valid on any day of [from, to]
+func (r *FeedingPlanRepository) SelectForPeriod(petID int, from time.Time, to time.Time) ([]models.FeedingPlan, error) {
+	return r.selectPlans(
+		`SELECT * FROM public.feeding_plans
+		WHERE pet_id = $1 AND starts_on <= $3::DATE AND (ends_on IS NULL OR ends_on >= $2::DATE)
+		ORDER BY starts_on, plan_id;`,
+		petID,
+		from,
+		to,
+	)
+}
+
+// Update stores the plan settings and replaces its meals, the creator of the plan is kept
+func (r *FeedingPlanRepository) Update(plan *models.FeedingPlan) (*models.FeedingPlan, error) {
+	plan.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	meals := plan.Meals
+	if err := transaction.Get(
+		plan,
+		`UPDATE public.feeding_plans
+		SET name = $2, time_zone = $3, starts_on = $4, ends_on = $5, tolerance_minutes = $6, updated_at = now()
+		WHERE plan_id = $1
+		RETURNING *;`,
+		plan.PlanID,
+		plan.Name,
+		plan.TimeZone,
+		plan.StartsOn,
+		plan.EndsOn,
+		plan.ToleranceMinutes,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if _, err := transaction.Exec(`DELETE FROM public.feeding_plan_meals WHERE plan_id = $1;`, plan.PlanID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if plan.Meals, err = r.insertMeals(transaction, plan.PlanID, meals); err != nil {
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	plan.AfterCreate()
+	return plan, nil
+}
+
+// DeleteByID deletes the plan, its meals are deleted by the cascade
+func (r *FeedingPlanRepository) DeleteByID(planID int) (*models.FeedingPlan, error) {
+	plan, err := r.FindByID(planID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.store.db.Exec(`DELETE FROM public.feeding_plans WHERE plan_id = $1;`, planID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (r *FeedingPlanRepository) insertMeals(transaction *sqlx.Tx, planID int, meals []models.FeedingPlanMeal) ([]models.FeedingPlanMeal, error) {
+	inserted := make([]models.FeedingPlanMeal, len(meals))
+	for idx, meal := range meals {
+		if meal.Weekdays == nil {
+			meal.Weekdays = make(pq.Int64Array, 0)
+		}
+		if err := transaction.Get(
+			&inserted[idx],
+			`INSERT INTO public.feeding_plan_meals
+				(plan_id, time_of_day, weekdays, food_id, portion_amount, portion_unit, portion_weight)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING *;`,
+			planID,
+			meal.TimeOfDay,
+			meal.Weekdays,
+			meal.FoodID,
+			meal.PortionAmount,
+			meal.PortionUnit,
+			meal.PortionWeight,
+		); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+	return inserted, nil
+}
+
+func (r *FeedingPlanRepository) selectPlans(query string, args ...interface{}) ([]models.FeedingPlan, error) {
+	plans := make([]models.FeedingPlan, 0)
+	if err := r.store.db.Select(&plans, query, args...); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := r.attachMeals(plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// attachMeals loads the meals of all the plans with a single query
+func (r *FeedingPlanRepository) attachMeals(plans []models.FeedingPlan) error {
+	if len(plans) == 0 {
+		return nil
+	}
+	planIDs := make(pq.Int64Array, len(plans))
+	byID := make(map[int]*models.FeedingPlan, len(plans))
+	for idx := range plans {
+		planIDs[idx] = int64(plans[idx].PlanID)
+		plans[idx].Meals = make([]models.FeedingPlanMeal, 0)
+		byID[plans[idx].PlanID] = &plans[idx]
+	}
+
+	meals := make([]models.FeedingPlanMeal, 0)
+	if err := r.store.db.Select(
+		&meals,
+		`SELECT * FROM public.feeding_plan_meals WHERE plan_id = ANY($1) ORDER BY time_of_day, meal_id;`,
+		planIDs,
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	for _, meal := range meals {
+		if plan, ok := byID[meal.PlanID]; ok {
+			plan.Meals = append(plan.Meals, meal)
+		}
+	}
+	for idx := range plans {
+		plans[idx].AfterCreate()
+	}
+	return nil
+}
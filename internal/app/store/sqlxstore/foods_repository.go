@@ -3,7 +3,11 @@ package sqlxstore
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/foodsearch"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"strings"
 	"time"
 )
@@ -19,6 +23,9 @@ func (r *FoodRepository) SelectAll() ([]models.Food, error) {
 		r.store.logger.Println(err)
 		return nil, err
 	}
+	if err := r.attachDetails(foodModels); err != nil {
+		return nil, err
+	}
 	for idx := range foodModels {
 		foodModels[idx].AfterCreate()
 	}
@@ -27,44 +34,158 @@ func (r *FoodRepository) SelectAll() ([]models.Food, error) {
 
 func (r *FoodRepository) FindByID(foodID int) (*models.Food, error) {
 	query := `SELECT * FROM public.food WHERE food_id = $1;`
-	foodModel := &models.Food{}
-	if err := r.store.db.Get(foodModel, query, foodID); err != nil {
+	foodModels := make([]models.Food, 1)
+	if err := r.store.db.Get(&foodModels[0], query, foodID); err != nil {
 		r.store.logger.Println(err)
 		return nil, err
 	}
-	foodModel.AfterCreate()
-	return foodModel, nil
+	if err := r.attachDetails(foodModels); err != nil {
+		return nil, err
+	}
+	foodModels[0].AfterCreate()
+	return &foodModels[0], nil
 }
 
-func (r *FoodRepository) SelectByNameSimilarity(namePattern string) ([]models.Food, error) {
-	query := `SELECT * FROM public.food WHERE LOWER(food_name) LIKE $1;`
-	var foodModels []models.Food
-	pattern := "%" + strings.ToLower(namePattern) + "%"
-
-	if err := r.store.db.Select(&foodModels, query, pattern); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return foodModels, nil
+// FindByBarcode returns the food of the normalized GTIN code
+func (r *FoodRepository) FindByBarcode(code string) (*models.Food, error) {
+	var foodID int
+	if err := r.store.db.Get(&foodID, `SELECT food_id FROM public.food_barcodes WHERE code = $1;`, code); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			r.store.logger.Println(err)
 		}
+		return nil, err
+	}
+	return r.FindByID(foodID)
+}
+
+// foodSearchVector is the expression of the food_search_idx index
+const foodSearchVector = `(
+	setweight(to_tsvector('simple', coalesce(f.food_name, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(f.manufacturer, '')), 'B') ||
+	setweight(to_tsvector('simple', coalesce(f.description, '')), 'C'))`
+
+// foodSearchSimilarityThreshold is lower than the default of pg_trgm to find the names with typos
+const foodSearchSimilarityThreshold = 0.3
+
+/*
+Search finds the foods by text search over name, manufacturer and description and by trigram similarity of
+the name and manufacturer, which also matches words with typos. The rank sums the text search rank and the
+word similarities, the manufacturer one counting half. Without the text the filtered foods are sorted by name.
+*/
+func (r *FoodRepository) Search(searchQuery models.FoodSearchQuery) ([]models.FoodSearchResult, error) {
+	query := `
+		SELECT f.*,
+			ts_rank(` + foodSearchVector + `, q.query) + word_similarity($1, f.food_name)
+				+ 0.5 * word_similarity($1, coalesce(f.manufacturer, '')) AS "rank",
+			ts_headline('simple', f.food_name, q.query, $7) AS "name_highlight",
+			ts_headline('simple', coalesce(f.manufacturer, ''), q.query, $7) AS "manufacturer_highlight",
+			ts_headline('simple', coalesce(f.description, ''), q.query, $8) AS "description_highlight"
+		FROM public.food f, websearch_to_tsquery('simple', $1) AS q(query)
+		WHERE ($1 = '' OR ` + foodSearchVector + ` @@ q.query OR $1 <% f.food_name OR $1 <% f.manufacturer)
+			AND ($2 = '' OR LOWER(f.manufacturer) = LOWER($2))
+			AND ($3::DOUBLE PRECISION IS NULL OR f.energy_per_100g >= $3)
+			AND ($4::DOUBLE PRECISION IS NULL OR f.energy_per_100g <= $4)
+		ORDER BY "rank" DESC, f.food_name
+		LIMIT $5 OFFSET $6;`
+	marks := "StartSel=" + foodsearch.StartMark + ", StopSel=" + foodsearch.StopMark
+	var rows []struct {
+		models.Food
+		Rank                  float64 `db:"rank"`
+		NameHighlight         string  `db:"name_highlight"`
+		ManufacturerHighlight string  `db:"manufacturer_highlight"`
+		DescriptionHighlight  string  `db:"description_highlight"`
+	}
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
 		r.store.logger.Println(err)
 		return nil, err
 	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
 
+	if _, err := transaction.Exec(fmt.Sprintf(`SET LOCAL pg_trgm.word_similarity_threshold = %v;`, foodSearchSimilarityThreshold)); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := transaction.Select(&rows, query,
+		strings.TrimSpace(searchQuery.Text),
+		strings.TrimSpace(searchQuery.Manufacturer),
+		searchQuery.MinEnergy,
+		searchQuery.MaxEnergy,
+		searchQuery.Limit,
+		searchQuery.Offset,
+		marks+", HighlightAll=true",
+		marks+", MaxFragments=2",
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	foodModels := make([]models.Food, len(rows))
+	for idx := range rows {
+		foodModels[idx] = rows[idx].Food
+	}
+	if err := r.attachDetails(foodModels); err != nil {
+		return nil, err
+	}
+	results := make([]models.FoodSearchResult, len(rows))
+	for idx := range rows {
+		foodModels[idx].AfterCreate()
+		results[idx] = models.FoodSearchResult{
+			Food: &foodModels[idx],
+			Rank: rows[idx].Rank,
+			Highlights: models.FoodHighlights{
+				FoodName:     foodsearch.Highlight(rows[idx].NameHighlight),
+				Manufacturer: foodsearch.Highlight(rows[idx].ManufacturerHighlight),
+				Description:  foodsearch.Highlight(rows[idx].DescriptionHighlight),
+			},
+		}
+	}
+	return results, nil
+}
+
+// SelectByNames returns the foods named as any of the names, the names are compared case insensitively
+func (r *FoodRepository) SelectByNames(names []string) ([]models.Food, error) {
+	query := `SELECT * FROM public.food WHERE LOWER(food_name) = ANY($1) ORDER BY food_id;`
+	lowered := make(pq.StringArray, len(names))
+	for idx := range names {
+		lowered[idx] = strings.ToLower(strings.TrimSpace(names[idx]))
+	}
+	var foodModels []models.Food
+	if err := r.store.db.Select(&foodModels, query, lowered); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := r.attachDetails(foodModels); err != nil {
+		return nil, err
+	}
 	for idx := range foodModels {
 		foodModels[idx].AfterCreate()
 	}
 	return foodModels, nil
 }
 
-func (r *FoodRepository) Create(foodModel *models.Food) (*models.Food, error) {
-	query := `
-		INSERT INTO public.food 
-			(food_name, calories, description, manufacturer, creator_id) 
-		VALUES 
-			($1, $2, $3, $4, $5) 
-		RETURNING food_id;`
-	var newModelID int
-	foodModel.BeforeCreate()
+// SelectBarcodeOwners maps the normalized codes to the foods they belong to, unknown codes are omitted
+func (r *FoodRepository) SelectBarcodeOwners(codes []string) (map[string]int, error) {
+	type barcode struct {
+		Code   string `db:"code"`
+		FoodID int    `db:"food_id"`
+	}
+	var barcodes []barcode
+	if err := r.store.db.Select(&barcodes, `SELECT code, food_id FROM public.food_barcodes WHERE code = ANY($1);`, pq.StringArray(codes)); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	owners := make(map[string]int, len(barcodes))
+	for _, b := range barcodes {
+		owners[b.Code] = b.FoodID
+	}
+	return owners, nil
+}
 
+func (r *FoodRepository) Create(foodModel *models.Food) (*models.Food, error) {
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
 		r.store.logger.Println(err)
@@ -74,13 +195,7 @@ func (r *FoodRepository) Create(foodModel *models.Food) (*models.Food, error) {
 		_ = transaction.Rollback()
 	}()
 
-	if err := transaction.QueryRowx(query,
-		foodModel.FoodName,
-		foodModel.Calories,
-		foodModel.Description,
-		foodModel.Manufacturer,
-		foodModel.CreatorID,
-	).Scan(&newModelID); err != nil {
+	if err := r.insert(transaction, foodModel); err != nil {
 		return nil, err
 	}
 
@@ -88,21 +203,11 @@ func (r *FoodRepository) Create(foodModel *models.Food) (*models.Food, error) {
 		r.store.logger.Println(err)
 		return nil, err
 	}
-	foodModel.FoodID = newModelID
+	foodModel.AfterCreate()
 	return foodModel, nil
 }
 
 func (r *FoodRepository) Update(foodModel *models.Food) (*models.Food, error) {
-	query := `
-		UPDATE public.food 
-		SET 
-			food_name = :food_name, 
-			calories = :calories, 
-			description = :description, 
-			manufacturer = :manufacturer,
-		    creator_id = :creator_id
-		WHERE food_id = :food_id;`
-	foodModel.BeforeCreate()
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
 		r.store.logger.Println(err)
@@ -112,8 +217,7 @@ func (r *FoodRepository) Update(foodModel *models.Food) (*models.Food, error) {
 		_ = transaction.Rollback()
 	}()
 
-	if _, err := transaction.NamedExec(query, foodModel); err != nil {
-		r.store.logger.Println(err)
+	if err := r.update(transaction, foodModel); err != nil {
 		return nil, err
 	}
 
@@ -125,6 +229,93 @@ func (r *FoodRepository) Update(foodModel *models.Food) (*models.Food, error) {
 	return r.FindByID(foodModel.FoodID)
 }
 
+// Import creates and updates the foods of the catalogue in a single transaction, either all of them are saved or none
+func (r *FoodRepository) Import(creates []*models.Food, updates []*models.Food) error {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	for _, foodModel := range creates {
+		if err := r.insert(transaction, foodModel); err != nil {
+			return err
+		}
+	}
+	for _, foodModel := range updates {
+		if err := r.update(transaction, foodModel); err != nil {
+			return err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	for _, foodModel := range creates {
+		foodModel.AfterCreate()
+	}
+	return nil
+}
+
+func (r *FoodRepository) insert(transaction *sqlx.Tx, foodModel *models.Food) error {
+	query := `
+		INSERT INTO public.food 
+			(food_name, energy_per_100g, calories, protein, fat, carbohydrate, fibre, moisture, calcium, phosphorus, description, manufacturer, creator_id) 
+		VALUES 
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) 
+		RETURNING food_id;`
+	foodModel.BeforeCreate()
+	if err := transaction.QueryRowx(query,
+		foodModel.FoodName,
+		foodModel.EnergyPer100g,
+		foodModel.Calories,
+		foodModel.Protein,
+		foodModel.Fat,
+		foodModel.Carbohydrate,
+		foodModel.Fibre,
+		foodModel.Moisture,
+		foodModel.Calcium,
+		foodModel.Phosphorus,
+		foodModel.Description,
+		foodModel.Manufacturer,
+		foodModel.CreatorID,
+	).Scan(&foodModel.FoodID); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return r.saveDetails(transaction, foodModel)
+}
+
+func (r *FoodRepository) update(transaction *sqlx.Tx, foodModel *models.Food) error {
+	query := `
+		UPDATE public.food 
+		SET 
+			food_name = :food_name, 
+			energy_per_100g = :energy_per_100g,
+			calories = :calories,
+			protein = :protein,
+			fat = :fat,
+			carbohydrate = :carbohydrate,
+			fibre = :fibre,
+			moisture = :moisture,
+			calcium = :calcium,
+			phosphorus = :phosphorus,
+			description = :description, 
+			manufacturer = :manufacturer,
+		    creator_id = :creator_id
+		WHERE food_id = :food_id;`
+	foodModel.BeforeCreate()
+	if _, err := transaction.NamedExec(query, foodModel); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return r.saveDetails(transaction, foodModel)
+}
+
 func (r *FoodRepository) DeleteByID(foodID int) (*models.Food, error) {
 	query := `DELETE FROM public.food WHERE food_id = $1;`
 	foodModel, err := r.FindByID(foodID)
@@ -152,8 +343,82 @@ func (r *FoodRepository) DeleteByID(foodID int) (*models.Food, error) {
 	return foodModel, nil
 }
 
+// saveDetails replaces the portion units and the barcodes of the food with the ones of the model
+func (r *FoodRepository) saveDetails(transaction *sqlx.Tx, foodModel *models.Food) error {
+	if _, err := transaction.Exec(`DELETE FROM public.food_portion_units WHERE food_id = $1;`, foodModel.FoodID); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	for idx := range foodModel.PortionUnits {
+		foodModel.PortionUnits[idx].FoodID = foodModel.FoodID
+		if _, err := transaction.NamedExec(
+			`INSERT INTO public.food_portion_units (food_id, unit, grams) VALUES (:food_id, :unit, :grams);`,
+			foodModel.PortionUnits[idx],
+		); err != nil {
+			r.store.logger.Println(err)
+			return err
+		}
+	}
+
+	if _, err := transaction.Exec(`DELETE FROM public.food_barcodes WHERE food_id = $1;`, foodModel.FoodID); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	for _, code := range foodModel.Barcodes {
+		if _, err := transaction.Exec(`INSERT INTO public.food_barcodes (code, food_id) VALUES ($1, $2);`, code, foodModel.FoodID); err != nil {
+			r.store.logger.Println(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// attachDetails loads the portion units and the barcodes of the foods, a query for each
+func (r *FoodRepository) attachDetails(foodModels []models.Food) error {
+	if len(foodModels) == 0 {
+		return nil
+	}
+	ids := make(pq.Int64Array, len(foodModels))
+	for idx := range foodModels {
+		ids[idx] = int64(foodModels[idx].FoodID)
+	}
+
+	var units []models.FoodPortionUnit
+	query := `SELECT * FROM public.food_portion_units WHERE food_id = ANY($1) ORDER BY food_id, unit;`
+	if err := r.store.db.Select(&units, query, ids); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	unitsByFood := make(map[int][]models.FoodPortionUnit)
+	for _, unit := range units {
+		unitsByFood[unit.FoodID] = append(unitsByFood[unit.FoodID], unit)
+	}
+
+	var barcodes []struct {
+		Code   string `db:"code"`
+		FoodID int    `db:"food_id"`
+	}
+	query = `SELECT code, food_id FROM public.food_barcodes WHERE food_id = ANY($1) ORDER BY food_id, code;`
+	if err := r.store.db.Select(&barcodes, query, ids); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	barcodesByFood := make(map[int][]string)
+	for _, barcode := range barcodes {
+		barcodesByFood[barcode.FoodID] = append(barcodesByFood[barcode.FoodID], barcode.Code)
+	}
+
+	for idx := range foodModels {
+		foodModels[idx].PortionUnits = unitsByFood[foodModels[idx].FoodID]
+		foodModels[idx].Barcodes = barcodesByFood[foodModels[idx].FoodID]
+	}
+	return nil
+}
+
 func (r *FoodRepository) AddPetEating(eating *models.Eating) error {
-	query := `INSERT INTO public.eatings (eating_timestamp, pet_id, food_id, portion_weight) VALUES (:eating_timestamp, :pet_id, :food_id, :portion_weight);`
+	query := `
+		INSERT INTO public.eatings (eating_timestamp, pet_id, food_id, portion_weight, portion_amount, portion_unit) 
+		VALUES (:eating_timestamp, :pet_id, :food_id, :portion_weight, :portion_amount, :portion_unit);`
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
 		r.store.logger.Println(err)
@@ -185,6 +450,18 @@ func (r *FoodRepository) GetPetsEatingsForDate(petID int, date time.Time) ([]mod
 	return eatings, nil
 }
 
+// GetPetsEatingsForPeriod returns the eatings of the pet in [from, to) ordered by time,
+// the eatings keep the local time of the server, so the bounds are expected in it
+func (r *FoodRepository) GetPetsEatingsForPeriod(petID int, from time.Time, to time.Time) ([]models.Eating, error) {
+	query := `SELECT * FROM eatings WHERE pet_id = $1 AND eating_timestamp >= $2 AND eating_timestamp < $3 ORDER BY eating_timestamp;`
+	eatings := make([]models.Eating, 0)
+	if err := r.store.db.Select(&eatings, query, petID, from, to); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return eatings, nil
+}
+
 func (r *FoodRepository) GetPetsEatings(petID int) ([]models.Eating, error) {
 	query := `SELECT * FROM eatings WHERE pet_id = $1 ORDER BY eating_timestamp DESC;`
 	var eatings []models.Eating
@@ -0,0 +1,189 @@
+package sqlxstore
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+)
+
+type GeofenceRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *GeofenceRepository) Create(fence *models.Geofence) (*models.Geofence, error) {
+	fence.BeforeCreate()
+	if err := r.store.db.Get(
+		fence,
+		`INSERT INTO public.geofences
+			(pet_id, name, shape, center_longitude, center_latitude, radius, vertices, alert_on_enter, alert_on_exit, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING *;`,
+		fence.PetID,
+		fence.Name,
+		fence.Shape,
+		fence.CenterLongitude,
+		fence.CenterLatitude,
+		fence.Radius,
+		fence.Vertices,
+		fence.AlertOnEnter,
+		fence.AlertOnExit,
+		fence.IsActive,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	fence.AfterCreate()
+	return fence, nil
+}
+
+func (r *GeofenceRepository) FindByID(geofenceID int) (*models.Geofence, error) {
+	fence := &models.Geofence{}
+	if err := r.store.db.Get(fence, `SELECT * FROM public.geofences WHERE geofence_id = $1;`, geofenceID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	fence.AfterCreate()
+	return fence, nil
+}
+
+func (r *GeofenceRepository) SelectByPetID(petID int) ([]models.Geofence, error) {
+	return r.selectFences(`SELECT * FROM public.geofences WHERE pet_id = $1 ORDER BY geofence_id;`, petID)
+}
+
+// SelectActiveByPetID returns the zones evaluated on the pet fixes
+func (r *GeofenceRepository) SelectActiveByPetID(petID int) ([]models.Geofence, error) {
+	return r.selectFences(`SELECT * FROM public.geofences WHERE pet_id = $1 AND is_active ORDER BY geofence_id;`, petID)
+}
+
+func (r *GeofenceRepository) selectFences(query string, args ...interface{}) ([]models.Geofence, error) {
+	fences := make([]models.Geofence, 0)
+	if err := r.store.db.Select(&fences, query, args...); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range fences {
+		fences[idx].AfterCreate()
+	}
+	return fences, nil
+}
+
+// Update stores the zone settings and geometry, the debouncing state is reset since the zone has changed
+func (r *GeofenceRepository) Update(fence *models.Geofence) (*models.Geofence, error) {
+	fence.BeforeCreate()
+	if err := r.store.db.Get(
+		fence,
+		`UPDATE public.geofences
+		SET name = $2, shape = $3, center_longitude = $4, center_latitude = $5, radius = $6, vertices = $7,
+			alert_on_enter = $8, alert_on_exit = $9, is_active = $10,
+			inside = NULL, pending_fixes = 0, last_fix_at = NULL
+		WHERE geofence_id = $1
+		RETURNING *;`,
+		fence.GeofenceID,
+		fence.Name,
+		fence.Shape,
+		fence.CenterLongitude,
+		fence.CenterLatitude,
+		fence.Radius,
+		fence.Vertices,
+		fence.AlertOnEnter,
+		fence.AlertOnExit,
+		fence.IsActive,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	fence.AfterCreate()
+	return fence, nil
+}
+
+/*
+SaveEvaluation stores the debouncing state of the zone with the events it has confirmed in one transaction.
+
+The state is only saved if the zone has not been evaluated by the newer fixes meanwhile, false is returned otherwise
+and the events are dropped, since the other evaluation has seen the same crossings.
+*/
+func (r *GeofenceRepository) SaveEvaluation(fence *models.Geofence, previousFixAt *sql.NullTime, events []models.GeofenceEvent) ([]models.GeofenceEvent, bool, error) {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, false, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	result, err := transaction.Exec(
+		`UPDATE public.geofences SET inside = $2, pending_fixes = $3, last_fix_at = $4
+		WHERE geofence_id = $1 AND last_fix_at IS NOT DISTINCT FROM $5::TIMESTAMP;`,
+		fence.GeofenceID,
+		fence.Inside,
+		fence.PendingFixes,
+		fence.LastFixAt,
+		previousFixAt,
+	)
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, false, err
+	}
+	if affected == 0 {
+		return nil, false, nil
+	}
+
+	for idx := range events {
+		if err := transaction.QueryRowx(
+			`INSERT INTO public.geofence_events (geofence_id, pet_id, kind, occurred_at, latitude, longitude)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING event_id, created_at;`,
+			events[idx].GeofenceID,
+			events[idx].PetID,
+			events[idx].Kind,
+			events[idx].OccurredAt,
+			events[idx].Latitude,
+			events[idx].Longitude,
+		).Scan(&events[idx].EventID, &events[idx].CreatedAt); err != nil {
+			r.store.logger.Println(err)
+			return nil, false, err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, false, err
+	}
+	return events, true, nil
+}
+
+func (r *GeofenceRepository) DeleteByID(geofenceID int) (*models.Geofence, error) {
+	fence := &models.Geofence{}
+	if err := r.store.db.Get(fence, `DELETE FROM public.geofences WHERE geofence_id = $1 RETURNING *;`, geofenceID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	fence.AfterCreate()
+	return fence, nil
+}
+
+// SelectEvents returns the crossings of the pet zones, newest first
+func (r *GeofenceRepository) SelectEvents(petID int, limit int, offset int) ([]models.GeofenceEvent, error) {
+	events := make([]models.GeofenceEvent, 0)
+	if err := r.store.db.Select(
+		&events,
+		`SELECT e.*, g.name AS geofence_name
+		FROM public.geofence_events e
+		JOIN public.geofences g ON g.geofence_id = e.geofence_id
+		WHERE e.pet_id = $1
+		ORDER BY e.occurred_at DESC, e.event_id DESC
+		LIMIT $2 OFFSET $3;`,
+		petID,
+		limit,
+		offset,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,118 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/lib/pq"
+)
+
+type HealthAlertRepository struct {
+	store *PostgreDatabaseStore
+}
+
+/*
+Sync makes the alerts the open alerts of the pet in one transaction.
+
+The open alert of the same kind is refreshed and keeps its detection time,
+the open alerts of the kinds not given are resolved. The open alerts are returned.
+*/
+func (r *HealthAlertRepository) Sync(petID int, alerts []models.HealthAlert) ([]models.HealthAlert, error) {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	kinds := make(pq.StringArray, 0, len(alerts))
+	for _, alert := range alerts {
+		kinds = append(kinds, alert.Kind)
+		if _, err := transaction.Exec(`
+			INSERT INTO public.health_alerts (pet_id, kind, severity, message, value, baseline)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (pet_id, kind) WHERE resolved_at IS NULL
+			DO UPDATE SET severity = excluded.severity, message = excluded.message,
+				value = excluded.value, baseline = excluded.baseline, updated_at = now();`,
+			petID,
+			alert.Kind,
+			alert.Severity,
+			alert.Message,
+			alert.Value,
+			alert.Baseline,
+		); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+	if _, err := transaction.Exec(
+		`UPDATE public.health_alerts SET resolved_at = now()
+		WHERE pet_id = $1 AND resolved_at IS NULL AND NOT (kind = ANY($2));`,
+		petID,
+		kinds,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	open := make([]models.HealthAlert, 0)
+	if err := transaction.Select(
+		&open,
+		`SELECT * FROM public.health_alerts WHERE pet_id = $1 AND resolved_at IS NULL ORDER BY detected_at, alert_id;`,
+		petID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range open {
+		open[idx].AfterCreate()
+	}
+	return open, nil
+}
+
+// SelectByPetID returns the alerts of the pet, newest first, the resolved ones only when asked
+func (r *HealthAlertRepository) SelectByPetID(petID int, withResolved bool) ([]models.HealthAlert, error) {
+	alerts := make([]models.HealthAlert, 0)
+	if err := r.store.db.Select(
+		&alerts,
+		`SELECT * FROM public.health_alerts
+		WHERE pet_id = $1 AND ($2 OR resolved_at IS NULL)
+		ORDER BY detected_at DESC, alert_id DESC;`,
+		petID,
+		withResolved,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range alerts {
+		alerts[idx].AfterCreate()
+	}
+	return alerts, nil
+}
+
+// SelectOpenByPetIDs returns the open alerts of the pets, critical first
+func (r *HealthAlertRepository) SelectOpenByPetIDs(petIDs []int) ([]models.HealthAlert, error) {
+	ids := make(pq.Int64Array, len(petIDs))
+	for idx, petID := range petIDs {
+		ids[idx] = int64(petID)
+	}
+	alerts := make([]models.HealthAlert, 0)
+	if err := r.store.db.Select(
+		&alerts,
+		`SELECT * FROM public.health_alerts
+		WHERE pet_id = ANY($1) AND resolved_at IS NULL
+		ORDER BY pet_id, severity = 'critical' DESC, detected_at;`,
+		ids,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range alerts {
+		alerts[idx].AfterCreate()
+	}
+	return alerts, nil
+}
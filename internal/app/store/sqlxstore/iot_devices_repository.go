@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
 )
 
 type IoTDevicesRepository struct {
@@ -17,12 +18,14 @@ func (r *IoTDevicesRepository) GetByID(deviceID int) (*models.IoTDevice, error)
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
 		}
-		r.store.logger.Println("Database Error: %v: ", err)
+		r.store.logger.Println(err)
 		return nil, err
 	}
+	deviceModel.AfterCreate()
 	return deviceModel, nil
 }
 
+// GetByAccessSecret finds the device by the hash of its access secret
 func (r *IoTDevicesRepository) GetByAccessSecret(accessSecret string) (*models.IoTDevice, error) {
 	query := `SELECT * FROM public.iot_devices WHERE access_secret = $1;`
 	deviceModel := &models.IoTDevice{}
@@ -30,8 +33,95 @@ func (r *IoTDevicesRepository) GetByAccessSecret(accessSecret string) (*models.I
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
 		}
-		r.store.logger.Println("Database Error: %v: ", err)
+		r.store.logger.Println(err)
 		return nil, err
 	}
+	deviceModel.AfterCreate()
 	return deviceModel, nil
 }
+
+func (r *IoTDevicesRepository) Create(device *models.IoTDevice) (*models.IoTDevice, error) {
+	device.CreatedAt = time.Now()
+	query := `
+		INSERT INTO public.iot_devices (pet_id, user_id, access_secret, name, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING device_id;`
+	if err := r.store.db.QueryRowx(
+		query,
+		device.PetID,
+		device.UserID,
+		device.AccessSecret,
+		device.Name,
+		device.Status,
+		device.CreatedAt,
+	).Scan(&device.DeviceID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	device.AfterCreate()
+	return device, nil
+}
+
+// SelectByPetID returns the devices paired to the pet and the ones unpaired from it
+func (r *IoTDevicesRepository) SelectByPetID(petID int) ([]models.IoTDevice, error) {
+	devices := make([]models.IoTDevice, 0)
+	if err := r.store.db.Select(
+		&devices,
+		`SELECT * FROM public.iot_devices WHERE pet_id = $1 ORDER BY device_id;`,
+		petID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range devices {
+		devices[idx].AfterCreate()
+	}
+	return devices, nil
+}
+
+func (r *IoTDevicesRepository) Update(device *models.IoTDevice) (*models.IoTDevice, error) {
+	query := `
+		UPDATE public.iot_devices
+		SET pet_id = $2, access_secret = $3, name = $4, status = $5, revoked_at = $6
+		WHERE device_id = $1
+		RETURNING *;`
+	updated := &models.IoTDevice{}
+	if err := r.store.db.Get(
+		updated,
+		query,
+		device.DeviceID,
+		device.PetID,
+		device.AccessSecret,
+		device.Name,
+		device.Status,
+		device.RevokedAt,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	updated.AfterCreate()
+	return updated, nil
+}
+
+// Touch records the device has reported at seenAt, unreported firmware and battery keep their last values
+func (r *IoTDevicesRepository) Touch(deviceID int, telemetry *models.DeviceTelemetry, seenAt time.Time) error {
+	var firmwareVersion *sql.NullString
+	if telemetry.FirmwareVersion != "" {
+		firmwareVersion = &sql.NullString{String: telemetry.FirmwareVersion, Valid: true}
+	}
+	var batteryLevel *sql.NullInt64
+	if telemetry.BatteryLevel != nil {
+		batteryLevel = &sql.NullInt64{Int64: int64(*telemetry.BatteryLevel), Valid: true}
+	}
+	query := `
+		UPDATE public.iot_devices
+		SET last_seen_at = greatest(coalesce(last_seen_at, $2), $2),
+			firmware_version = coalesce($3, firmware_version),
+			battery_level = coalesce($4, battery_level)
+		WHERE device_id = $1;`
+	if _, err := r.store.db.Exec(query, deviceID, seenAt, firmwareVersion, batteryLevel); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
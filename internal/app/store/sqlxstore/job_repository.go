@@ -0,0 +1,197 @@
+package sqlxstore
+
+import (
+	"context"
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"time"
+)
+
+// JobRepository keeps the times of the jobs in UTC, the columns are TIMESTAMP without the time zone
+type JobRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *JobRepository) Enqueue(kind string, payload []byte, runAt time.Time, maxAttempts int) (*models.Job, error) {
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	query := `
+		INSERT INTO public.jobs (kind, payload, run_at, max_attempts, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;`
+	job := &models.Job{}
+	if err := r.store.db.Get(job, query, kind, payload, runAt.UTC(), maxAttempts, time.Now().UTC()); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	job.AfterCreate()
+	return job, nil
+}
+
+// ClaimNext marks the earliest due pending job as running and returns it.
+// Rows locked by other workers are skipped, sql.ErrNoRows is returned when nothing is due.
+func (r *JobRepository) ClaimNext(now time.Time) (*models.Job, error) {
+	query := `
+		UPDATE public.jobs SET status = 'running', locked_at = $1, attempts = attempts + 1
+		WHERE job_id = (
+			SELECT job_id FROM public.jobs
+			WHERE status = 'pending' AND run_at <= $1
+			ORDER BY run_at
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING *;`
+	job := &models.Job{}
+	if err := r.store.db.Get(job, query, now.UTC()); err != nil {
+		if err != sql.ErrNoRows {
+			r.store.logger.Println(err)
+		}
+		return nil, err
+	}
+	job.AfterCreate()
+	return job, nil
+}
+
+func (r *JobRepository) Complete(jobID int) error {
+	query := `
+		UPDATE public.jobs SET status = 'succeeded', locked_at = NULL, last_error = NULL, finished_at = $2
+		WHERE job_id = $1;`
+	if _, err := r.store.db.Exec(query, jobID, time.Now().UTC()); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+// Retry returns the failed job to the queue to be run again at runAt
+func (r *JobRepository) Retry(jobID int, lastError string, runAt time.Time) error {
+	query := `
+		UPDATE public.jobs SET status = 'pending', locked_at = NULL, last_error = $2, run_at = $3
+		WHERE job_id = $1;`
+	if _, err := r.store.db.Exec(query, jobID, lastError, runAt.UTC()); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+// Fail marks the job which has no attempts left as failed permanently
+func (r *JobRepository) Fail(jobID int, lastError string) error {
+	query := `
+		UPDATE public.jobs SET status = 'failed', locked_at = NULL, last_error = $2, finished_at = $3
+		WHERE job_id = $1;`
+	if _, err := r.store.db.Exec(query, jobID, lastError, time.Now().UTC()); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+// RequeueStale returns to the queue running jobs locked before lockedBefore,
+// their workers are considered dead. Jobs without attempts left are failed.
+func (r *JobRepository) RequeueStale(lockedBefore time.Time) (int64, error) {
+	query := `
+		UPDATE public.jobs
+		SET status = CASE WHEN attempts < max_attempts THEN 'pending' ELSE 'failed' END,
+		    locked_at = NULL,
+		    last_error = 'worker did not finish the job',
+		    finished_at = CASE WHEN attempts < max_attempts THEN NULL ELSE $2 END
+		WHERE status = 'running' AND locked_at < $1;`
+	result, err := r.store.db.Exec(query, lockedBefore.UTC(), time.Now().UTC())
+	if err != nil {
+		r.store.logger.Println(err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (r *JobRepository) FindSchedule(name string) (*models.JobSchedule, error) {
+	schedule := &models.JobSchedule{}
+	if err := r.store.db.Get(schedule, `SELECT * FROM public.job_schedules WHERE name = $1;`, name); err != nil {
+		if err != sql.ErrNoRows {
+			r.store.logger.Println(err)
+		}
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// EnqueueScheduled enqueues the job of the schedule and records the run time in the same transaction
+func (r *JobRepository) EnqueueScheduled(schedule *models.JobSchedule, kind string, payload []byte, maxAttempts int) (*models.Job, error) {
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	schedule.LastRunAt = schedule.LastRunAt.UTC()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer transaction.Rollback()
+
+	job := &models.Job{}
+	if err := transaction.Get(
+		job,
+		`INSERT INTO public.jobs (kind, payload, run_at, max_attempts, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;`,
+		kind, payload, schedule.LastRunAt, maxAttempts, time.Now().UTC(),
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if _, err := transaction.NamedExec(
+		`INSERT INTO public.job_schedules (name, last_run_at) VALUES (:name, :last_run_at)
+		ON CONFLICT (name) DO UPDATE SET last_run_at = EXCLUDED.last_run_at;`,
+		schedule,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	job.AfterCreate()
+	return job, nil
+}
+
+// TryAdvisoryLock tries to take the session level advisory lock on the dedicated connection.
+// The lock is held until it is released or the connection is lost.
+func (r *JobRepository) TryAdvisoryLock(key int64) (repos.AdvisoryLock, bool, error) {
+	ctx := context.Background()
+	conn, err := r.store.db.Conn(ctx)
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1);`, key).Scan(&acquired); err != nil {
+		r.store.logger.Println(err)
+		_ = conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		_ = conn.Close()
+		return nil, false, nil
+	}
+	return &advisoryLock{conn: conn, key: key}, true, nil
+}
+
+type advisoryLock struct {
+	conn *sql.Conn
+	key  int64
+}
+
+// Ping checks the connection holding the lock is still alive, otherwise the lock is lost
+func (l *advisoryLock) Ping() error {
+	return l.conn.PingContext(context.Background())
+}
+
+func (l *advisoryLock) Release() error {
+	defer l.conn.Close()
+	_, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, l.key)
+	return err
+}
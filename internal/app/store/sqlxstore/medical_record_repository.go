@@ -0,0 +1,550 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/lib/pq"
+	"strings"
+)
+
+type MedicalRecordRepository struct {
+	store *PostgreDatabaseStore
+}
+
+// CreateCondition stores the diagnosis with its first status in the history
+func (r *MedicalRecordRepository) CreateCondition(condition *models.Condition) (*models.Condition, error) {
+	condition.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if err := transaction.Get(
+		condition,
+		`INSERT INTO public.conditions
+			(pet_id, veterinarian_id, report_id, name, code, status, diagnosed_on, resolved_on, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING *;`,
+		condition.PetID,
+		condition.VeterinarianID,
+		condition.ReportID,
+		condition.Name,
+		condition.Code,
+		condition.Status,
+		condition.DiagnosedOn,
+		condition.ResolvedOn,
+		condition.Notes,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	change := models.ConditionStatusChange{}
+	if err := transaction.Get(
+		&change,
+		`INSERT INTO public.condition_status_changes (condition_id, status, changed_by)
+		VALUES ($1, $2, $3)
+		RETURNING *;`,
+		condition.ConditionID,
+		condition.Status,
+		condition.VeterinarianID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	condition.History = []models.ConditionStatusChange{change}
+	condition.AfterCreate()
+	return condition, nil
+}
+
+func (r *MedicalRecordRepository) FindCondition(conditionID int) (*models.Condition, error) {
+	condition := &models.Condition{}
+	if err := r.store.db.Get(condition, `SELECT * FROM public.conditions WHERE condition_id = $1;`, conditionID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	conditions := []models.Condition{*condition}
+	if err := r.attachHistory(conditions); err != nil {
+		return nil, err
+	}
+	return &conditions[0], nil
+}
+
+// SelectConditions returns the conditions of the pet, the latest diagnosed first, problemsOnly skips resolved ones
+func (r *MedicalRecordRepository) SelectConditions(petID int, problemsOnly bool) ([]models.Condition, error) {
+	conditions := make([]models.Condition, 0)
+	if err := r.store.db.Select(
+		&conditions,
+		`SELECT * FROM public.conditions
+		WHERE pet_id = $1 AND (NOT $2 OR status <> 'resolved')
+		ORDER BY diagnosed_on DESC, condition_id DESC;`,
+		petID,
+		problemsOnly,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := r.attachHistory(conditions); err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
+
+/*
+SelectPatientConditions returns the conditions of the pets the veterinarian is assigned to with their history.
+
+The name matches the conditions containing it case-insensitively, the code matches exactly, empty filters match all.
+*/
+func (r *MedicalRecordRepository) SelectPatientConditions(veterinarianID int, name string, code string, status string) ([]models.PatientCondition, error) {
+	patientConditions := make([]models.PatientCondition, 0)
+	if err := r.store.db.Select(
+		&patientConditions,
+		`SELECT conditions.*, pets.name AS pet_name
+		FROM public.conditions
+			JOIN public.pets ON pets.pet_id = conditions.pet_id
+		WHERE pets.veterinarian_id = $1
+			AND ($2 = '' OR strpos(lower(conditions.name), $2) > 0)
+			AND ($3 = '' OR conditions.code = $3)
+			AND ($4 = '' OR conditions.status = $4)
+		ORDER BY conditions.diagnosed_on DESC, conditions.condition_id DESC;`,
+		veterinarianID,
+		strings.ToLower(name),
+		code,
+		status,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	conditions := make([]models.Condition, len(patientConditions))
+	for idx := range patientConditions {
+		conditions[idx] = patientConditions[idx].Condition
+	}
+	if err := r.attachHistory(conditions); err != nil {
+		return nil, err
+	}
+	for idx := range patientConditions {
+		patientConditions[idx].Condition = conditions[idx]
+	}
+	return patientConditions, nil
+}
+
+// UpdateCondition stores the condition, the change of its status by the veterinarian is added to the history
+func (r *MedicalRecordRepository) UpdateCondition(condition *models.Condition, changedBy int) (*models.Condition, error) {
+	condition.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	var previousStatus string
+	if err := transaction.Get(
+		&previousStatus,
+		`SELECT status FROM public.conditions WHERE condition_id = $1 FOR UPDATE;`,
+		condition.ConditionID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := transaction.Get(
+		condition,
+		`UPDATE public.conditions
+		SET report_id = $2, name = $3, code = $4, status = $5, diagnosed_on = $6, resolved_on = $7, notes = $8,
+			updated_at = now()
+		WHERE condition_id = $1
+		RETURNING *;`,
+		condition.ConditionID,
+		condition.ReportID,
+		condition.Name,
+		condition.Code,
+		condition.Status,
+		condition.DiagnosedOn,
+		condition.ResolvedOn,
+		condition.Notes,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if previousStatus != condition.Status {
+		if _, err := transaction.Exec(
+			`INSERT INTO public.condition_status_changes (condition_id, status, changed_by) VALUES ($1, $2, $3);`,
+			condition.ConditionID,
+			condition.Status,
+			changedBy,
+		); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	conditions := []models.Condition{*condition}
+	if err := r.attachHistory(conditions); err != nil {
+		return nil, err
+	}
+	return &conditions[0], nil
+}
+
+func (r *MedicalRecordRepository) DeleteCondition(conditionID int) (*models.Condition, error) {
+	condition := &models.Condition{}
+	if err := r.store.db.Get(
+		condition,
+		`DELETE FROM public.conditions WHERE condition_id = $1 RETURNING *;`,
+		conditionID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	condition.AfterCreate()
+	return condition, nil
+}
+
+// attachHistory loads the status changes of all the conditions with a single query
+func (r *MedicalRecordRepository) attachHistory(conditions []models.Condition) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+	conditionIDs := make(pq.Int64Array, len(conditions))
+	byID := make(map[int]*models.Condition, len(conditions))
+	for idx := range conditions {
+		conditionIDs[idx] = int64(conditions[idx].ConditionID)
+		conditions[idx].History = make([]models.ConditionStatusChange, 0)
+		byID[conditions[idx].ConditionID] = &conditions[idx]
+	}
+
+	changes := make([]models.ConditionStatusChange, 0)
+	if err := r.store.db.Select(
+		&changes,
+		`SELECT * FROM public.condition_status_changes WHERE condition_id = ANY($1) ORDER BY changed_at, change_id;`,
+		conditionIDs,
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	for _, change := range changes {
+		if condition, ok := byID[change.ConditionID]; ok {
+			condition.History = append(condition.History, change)
+		}
+	}
+	for idx := range conditions {
+		conditions[idx].AfterCreate()
+	}
+	return nil
+}
+
+func (r *MedicalRecordRepository) CreateAllergy(allergy *models.Allergy) (*models.Allergy, error) {
+	allergy.BeforeCreate()
+	if err := r.store.db.Get(
+		allergy,
+		`INSERT INTO public.allergies (pet_id, recorded_by, allergen, category, severity, reaction, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *;`,
+		allergy.PetID,
+		allergy.RecordedBy,
+		allergy.Allergen,
+		allergy.Category,
+		allergy.Severity,
+		allergy.Reaction,
+		allergy.IsActive,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	allergy.AfterCreate()
+	return allergy, nil
+}
+
+func (r *MedicalRecordRepository) FindAllergy(allergyID int) (*models.Allergy, error) {
+	allergy := &models.Allergy{}
+	if err := r.store.db.Get(allergy, `SELECT * FROM public.allergies WHERE allergy_id = $1;`, allergyID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	allergy.AfterCreate()
+	return allergy, nil
+}
+
+// SelectAllergies returns the allergies of the pet, the most severe first, activeOnly skips the inactive ones
+func (r *MedicalRecordRepository) SelectAllergies(petID int, activeOnly bool) ([]models.Allergy, error) {
+	allergies := make([]models.Allergy, 0)
+	if err := r.store.db.Select(
+		&allergies,
+		`SELECT * FROM public.allergies
+		WHERE pet_id = $1 AND (NOT $2 OR is_active)
+		ORDER BY is_active DESC, array_position(ARRAY ['severe', 'moderate', 'mild']::VARCHAR[], severity), allergy_id;`,
+		petID,
+		activeOnly,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range allergies {
+		allergies[idx].AfterCreate()
+	}
+	return allergies, nil
+}
+
+func (r *MedicalRecordRepository) UpdateAllergy(allergy *models.Allergy) (*models.Allergy, error) {
+	allergy.BeforeCreate()
+	if err := r.store.db.Get(
+		allergy,
+		`UPDATE public.allergies
+		SET allergen = $2, category = $3, severity = $4, reaction = $5, is_active = $6, updated_at = now()
+		WHERE allergy_id = $1
+		RETURNING *;`,
+		allergy.AllergyID,
+		allergy.Allergen,
+		allergy.Category,
+		allergy.Severity,
+		allergy.Reaction,
+		allergy.IsActive,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	allergy.AfterCreate()
+	return allergy, nil
+}
+
+func (r *MedicalRecordRepository) DeleteAllergy(allergyID int) (*models.Allergy, error) {
+	allergy := &models.Allergy{}
+	if err := r.store.db.Get(
+		allergy,
+		`DELETE FROM public.allergies WHERE allergy_id = $1 RETURNING *;`,
+		allergyID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	allergy.AfterCreate()
+	return allergy, nil
+}
+
+func (r *MedicalRecordRepository) CreateProcedure(procedure *models.Procedure) (*models.Procedure, error) {
+	procedure.BeforeCreate()
+	if err := r.store.db.Get(
+		procedure,
+		`INSERT INTO public.procedures
+			(pet_id, veterinarian_id, report_id, condition_id, kind, name, performed_on, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING *;`,
+		procedure.PetID,
+		procedure.VeterinarianID,
+		procedure.ReportID,
+		procedure.ConditionID,
+		procedure.Kind,
+		procedure.Name,
+		procedure.PerformedOn,
+		procedure.Outcome,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	procedure.AfterCreate()
+	return procedure, nil
+}
+
+func (r *MedicalRecordRepository) FindProcedure(procedureID int) (*models.Procedure, error) {
+	procedure := &models.Procedure{}
+	if err := r.store.db.Get(procedure, `SELECT * FROM public.procedures WHERE procedure_id = $1;`, procedureID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	procedure.AfterCreate()
+	return procedure, nil
+}
+
+func (r *MedicalRecordRepository) SelectProcedures(petID int) ([]models.Procedure, error) {
+	procedures := make([]models.Procedure, 0)
+	if err := r.store.db.Select(
+		&procedures,
+		`SELECT * FROM public.procedures WHERE pet_id = $1 ORDER BY performed_on DESC, procedure_id DESC;`,
+		petID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range procedures {
+		procedures[idx].AfterCreate()
+	}
+	return procedures, nil
+}
+
+func (r *MedicalRecordRepository) UpdateProcedure(procedure *models.Procedure) (*models.Procedure, error) {
+	procedure.BeforeCreate()
+	if err := r.store.db.Get(
+		procedure,
+		`UPDATE public.procedures
+		SET report_id = $2, condition_id = $3, kind = $4, name = $5, performed_on = $6, outcome = $7
+		WHERE procedure_id = $1
+		RETURNING *;`,
+		procedure.ProcedureID,
+		procedure.ReportID,
+		procedure.ConditionID,
+		procedure.Kind,
+		procedure.Name,
+		procedure.PerformedOn,
+		procedure.Outcome,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	procedure.AfterCreate()
+	return procedure, nil
+}
+
+func (r *MedicalRecordRepository) DeleteProcedure(procedureID int) (*models.Procedure, error) {
+	procedure := &models.Procedure{}
+	if err := r.store.db.Get(
+		procedure,
+		`DELETE FROM public.procedures WHERE procedure_id = $1 RETURNING *;`,
+		procedureID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	procedure.AfterCreate()
+	return procedure, nil
+}
+
+// CreateLabPanel stores the panel with its results in one transaction
+func (r *MedicalRecordRepository) CreateLabPanel(panel *models.LabPanel) (*models.LabPanel, error) {
+	panel.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	results := panel.Results
+	if err := transaction.Get(
+		panel,
+		`INSERT INTO public.lab_panels (pet_id, veterinarian_id, report_id, name, laboratory, collected_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *;`,
+		panel.PetID,
+		panel.VeterinarianID,
+		panel.ReportID,
+		panel.Name,
+		panel.Laboratory,
+		panel.CollectedAt,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	panel.Results = make([]models.LabResult, len(results))
+	for idx, result := range results {
+		if err := transaction.Get(
+			&panel.Results[idx],
+			`INSERT INTO public.lab_results (panel_id, analyte, value, unit, reference_low, reference_high)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING *;`,
+			panel.PanelID,
+			result.Analyte,
+			result.Value,
+			result.Unit,
+			result.ReferenceLow,
+			result.ReferenceHigh,
+		); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	panel.AfterCreate()
+	return panel, nil
+}
+
+func (r *MedicalRecordRepository) FindLabPanel(panelID int) (*models.LabPanel, error) {
+	panel := &models.LabPanel{}
+	if err := r.store.db.Get(panel, `SELECT * FROM public.lab_panels WHERE panel_id = $1;`, panelID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	panels := []models.LabPanel{*panel}
+	if err := r.attachResults(panels); err != nil {
+		return nil, err
+	}
+	return &panels[0], nil
+}
+
+func (r *MedicalRecordRepository) SelectLabPanels(petID int) ([]models.LabPanel, error) {
+	panels := make([]models.LabPanel, 0)
+	if err := r.store.db.Select(
+		&panels,
+		`SELECT * FROM public.lab_panels WHERE pet_id = $1 ORDER BY collected_at DESC, panel_id DESC;`,
+		petID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if err := r.attachResults(panels); err != nil {
+		return nil, err
+	}
+	return panels, nil
+}
+
+func (r *MedicalRecordRepository) DeleteLabPanel(panelID int) (*models.LabPanel, error) {
+	panel, err := r.FindLabPanel(panelID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.store.db.Exec(`DELETE FROM public.lab_panels WHERE panel_id = $1;`, panelID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return panel, nil
+}
+
+// attachResults loads the results of all the panels with a single query
+func (r *MedicalRecordRepository) attachResults(panels []models.LabPanel) error {
+	if len(panels) == 0 {
+		return nil
+	}
+	panelIDs := make(pq.Int64Array, len(panels))
+	byID := make(map[int]*models.LabPanel, len(panels))
+	for idx := range panels {
+		panelIDs[idx] = int64(panels[idx].PanelID)
+		panels[idx].Results = make([]models.LabResult, 0)
+		byID[panels[idx].PanelID] = &panels[idx]
+	}
+
+	results := make([]models.LabResult, 0)
+	if err := r.store.db.Select(
+		&results,
+		`SELECT * FROM public.lab_results WHERE panel_id = ANY($1) ORDER BY result_id;`,
+		panelIDs,
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	for _, result := range results {
+		if panel, ok := byID[result.PanelID]; ok {
+			panel.Results = append(panel.Results, result)
+		}
+	}
+	for idx := range panels {
+		panels[idx].AfterCreate()
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
+)
+
+type MedicationRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *MedicationRepository) Create(medication *models.Medication) (*models.Medication, error) {
+	medication.BeforeCreate()
+	if err := r.store.db.Get(
+		medication,
+		`INSERT INTO public.medications
+			(pet_id, veterinarian_id, report_id, drug, dose, dose_unit, route, interval_hours, starts_at, ends_at, instructions)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING *;`,
+		medication.PetID,
+		medication.VeterinarianID,
+		medication.ReportID,
+		medication.Drug,
+		medication.Dose,
+		medication.DoseUnit,
+		medication.Route,
+		medication.IntervalHours,
+		medication.StartsAt,
+		medication.EndsAt,
+		medication.Instructions,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	medication.AfterCreate()
+	return medication, nil
+}
+
+func (r *MedicationRepository) FindByID(medicationID int) (*models.Medication, error) {
+	medication := &models.Medication{}
+	if err := r.store.db.Get(medication, `SELECT * FROM public.medications WHERE medication_id = $1;`, medicationID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	medication.AfterCreate()
+	return medication, nil
+}
+
+// SelectByPetID returns the medications of the pet, the latest first, activeAt filters the ones given at the time
+func (r *MedicationRepository) SelectByPetID(petID int, activeAt *time.Time) ([]models.Medication, error) {
+	if activeAt != nil {
+		return r.selectMedications(
+			`SELECT * FROM public.medications
+			WHERE pet_id = $1 AND starts_at <= $2 AND (ends_at IS NULL OR ends_at > $2)
+			ORDER BY starts_at DESC, medication_id DESC;`,
+			petID,
+			activeAt,
+		)
+	}
+	return r.selectMedications(
+		`SELECT * FROM public.medications WHERE pet_id = $1 ORDER BY starts_at DESC, medication_id DESC;`,
+		petID,
+	)
+}
+
+// SelectScheduled returns the scheduled medications which have doses to be reminded or checked at now
+func (r *MedicationRepository) SelectScheduled(now time.Time) ([]models.Medication, error) {
+	return r.selectMedications(
+		`SELECT * FROM public.medications
+		WHERE interval_hours > 0 AND starts_at <= $1
+			AND (ends_at IS NULL OR checked_until IS NULL OR checked_until < ends_at + INTERVAL '2 hours')
+		ORDER BY medication_id;`,
+		now,
+	)
+}
+
+func (r *MedicationRepository) selectMedications(query string, args ...interface{}) ([]models.Medication, error) {
+	medications := make([]models.Medication, 0)
+	if err := r.store.db.Select(&medications, query, args...); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range medications {
+		medications[idx].AfterCreate()
+	}
+	return medications, nil
+}
+
+// Update stores the prescription, the reminders continue from where they are
+func (r *MedicationRepository) Update(medication *models.Medication) (*models.Medication, error) {
+	medication.BeforeCreate()
+	if err := r.store.db.Get(
+		medication,
+		`UPDATE public.medications
+		SET report_id = $2, drug = $3, dose = $4, dose_unit = $5, route = $6, interval_hours = $7,
+			starts_at = $8, ends_at = $9, instructions = $10, updated_at = now()
+		WHERE medication_id = $1
+		RETURNING *;`,
+		medication.MedicationID,
+		medication.ReportID,
+		medication.Drug,
+		medication.Dose,
+		medication.DoseUnit,
+		medication.Route,
+		medication.IntervalHours,
+		medication.StartsAt,
+		medication.EndsAt,
+		medication.Instructions,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	medication.AfterCreate()
+	return medication, nil
+}
+
+func (r *MedicationRepository) DeleteByID(medicationID int) (*models.Medication, error) {
+	medication := &models.Medication{}
+	if err := r.store.db.Get(
+		medication,
+		`DELETE FROM public.medications WHERE medication_id = $1 RETURNING *;`,
+		medicationID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	medication.AfterCreate()
+	return medication, nil
+}
+
+// SaveReminders moves the times the owner has been reminded and alerted about the doses until
+func (r *MedicationRepository) SaveReminders(medicationID int, remindedUntil time.Time, checkedUntil time.Time) error {
+	if _, err := r.store.db.Exec(
+		`UPDATE public.medications SET reminded_until = $2, checked_until = $3 WHERE medication_id = $1;`,
+		medicationID,
+		remindedUntil,
+		checkedUntil,
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (r *MedicationRepository) CreateDose(dose *models.MedicationDose) (*models.MedicationDose, error) {
+	dose.BeforeCreate()
+	if err := r.store.db.Get(
+		dose,
+		`INSERT INTO public.medication_doses (medication_id, administered_at, administered_by, amount, comment)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING *;`,
+		dose.MedicationID,
+		dose.AdministeredAt,
+		dose.AdministeredBy,
+		dose.Amount,
+		dose.Comment,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	dose.AfterCreate()
+	return dose, nil
+}
+
+// SelectDoses returns the doses of the medication given in [from, to) ordered by time
+func (r *MedicationRepository) SelectDoses(medicationID int, from time.Time, to time.Time) ([]models.MedicationDose, error) {
+	doses := make([]models.MedicationDose, 0)
+	if err := r.store.db.Select(
+		&doses,
+		`SELECT * FROM public.medication_doses
+		WHERE medication_id = $1 AND administered_at >= $2 AND administered_at < $3
+		ORDER BY administered_at;`,
+		medicationID,
+		from,
+		to,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range doses {
+		doses[idx].AfterCreate()
+	}
+	return doses, nil
+}
+
+func (r *MedicationRepository) DeleteDose(medicationID int, doseID int) (*models.MedicationDose, error) {
+	dose := &models.MedicationDose{}
+	if err := r.store.db.Get(
+		dose,
+		`DELETE FROM public.medication_doses WHERE medication_id = $1 AND dose_id = $2 RETURNING *;`,
+		medicationID,
+		doseID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	dose.AfterCreate()
+	return dose, nil
+}
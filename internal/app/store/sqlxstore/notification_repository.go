@@ -0,0 +1,232 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"time"
+)
+
+type NotificationRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *NotificationRepository) Create(notification *models.Notification) (*models.Notification, error) {
+	if len(notification.Data) == 0 {
+		notification.Data = []byte("{}")
+	}
+	notification.CreatedAt = time.Now()
+	query := `
+		INSERT INTO public.notifications (user_id, event, title, body, data, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING notification_id;`
+	if err := r.store.db.QueryRowx(
+		query,
+		notification.UserID,
+		notification.Event,
+		notification.Title,
+		notification.Body,
+		notification.Data,
+		notification.CreatedAt,
+	).Scan(&notification.NotificationID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	notification.AfterCreate()
+	return notification, nil
+}
+
+func (r *NotificationRepository) FindByID(notificationID int) (*models.Notification, error) {
+	notification := &models.Notification{}
+	if err := r.store.db.Get(
+		notification,
+		`SELECT * FROM public.notifications WHERE notification_id = $1;`,
+		notificationID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	notification.AfterCreate()
+	return notification, nil
+}
+
+// SelectByUserID returns the user inbox, newest first, optionally only unread notifications
+func (r *NotificationRepository) SelectByUserID(userID int, unreadOnly bool, limit int, offset int) ([]models.Notification, error) {
+	query := `
+		SELECT * FROM public.notifications
+		WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL)
+		ORDER BY created_at DESC, notification_id DESC
+		LIMIT $3 OFFSET $4;`
+	notifications := make([]models.Notification, 0)
+	if err := r.store.db.Select(&notifications, query, userID, unreadOnly, limit, offset); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range notifications {
+		notifications[idx].AfterCreate()
+	}
+	return notifications, nil
+}
+
+func (r *NotificationRepository) CountUnread(userID int) (int, error) {
+	var count int
+	if err := r.store.db.Get(
+		&count,
+		`SELECT count(*) FROM public.notifications WHERE user_id = $1 AND read_at IS NULL;`,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// SetRead marks the user notification as read or unread again
+func (r *NotificationRepository) SetRead(userID int, notificationID int, read bool) (*models.Notification, error) {
+	query := `
+		UPDATE public.notifications SET read_at = CASE WHEN $3 THEN coalesce(read_at, $4) END
+		WHERE notification_id = $1 AND user_id = $2
+		RETURNING *;`
+	notification := &models.Notification{}
+	if err := r.store.db.Get(notification, query, notificationID, userID, read, time.Now()); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	notification.AfterCreate()
+	return notification, nil
+}
+
+func (r *NotificationRepository) MarkAllRead(userID int) error {
+	if _, err := r.store.db.Exec(
+		`UPDATE public.notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL;`,
+		userID,
+		time.Now(),
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (r *NotificationRepository) DeleteByID(userID int, notificationID int) (*models.Notification, error) {
+	notification := &models.Notification{}
+	if err := r.store.db.Get(
+		notification,
+		`DELETE FROM public.notifications WHERE notification_id = $1 AND user_id = $2 RETURNING *;`,
+		notificationID,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	notification.AfterCreate()
+	return notification, nil
+}
+
+func (r *NotificationRepository) SelectPreferences(userID int) ([]models.NotificationPreference, error) {
+	preferences := make([]models.NotificationPreference, 0)
+	if err := r.store.db.Select(
+		&preferences,
+		`SELECT * FROM public.notification_preferences WHERE user_id = $1 ORDER BY event, channel;`,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return preferences, nil
+}
+
+func (r *NotificationRepository) SavePreferences(userID int, preferences []models.NotificationPreference) error {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer transaction.Rollback()
+
+	for idx := range preferences {
+		preferences[idx].UserID = userID
+		if _, err := transaction.NamedExec(
+			`INSERT INTO public.notification_preferences (user_id, event, channel, enabled)
+			VALUES (:user_id, :event, :channel, :enabled)
+			ON CONFLICT (user_id, event, channel) DO UPDATE SET enabled = EXCLUDED.enabled;`,
+			preferences[idx],
+		); err != nil {
+			r.store.logger.Println(err)
+			return err
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (r *NotificationRepository) SelectEndpoints(userID int) ([]models.NotificationEndpoint, error) {
+	endpoints := make([]models.NotificationEndpoint, 0)
+	if err := r.store.db.Select(
+		&endpoints,
+		`SELECT * FROM public.notification_endpoints WHERE user_id = $1 ORDER BY endpoint_id;`,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range endpoints {
+		endpoints[idx].AfterCreate()
+	}
+	return endpoints, nil
+}
+
+func (r *NotificationRepository) FindEndpointByID(endpointID int) (*models.NotificationEndpoint, error) {
+	endpoint := &models.NotificationEndpoint{}
+	if err := r.store.db.Get(
+		endpoint,
+		`SELECT * FROM public.notification_endpoints WHERE endpoint_id = $1;`,
+		endpointID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	endpoint.AfterCreate()
+	return endpoint, nil
+}
+
+// CreateEndpoint stores the endpoint, registering the same address again replaces its keys
+func (r *NotificationRepository) CreateEndpoint(endpoint *models.NotificationEndpoint) (*models.NotificationEndpoint, error) {
+	endpoint.BeforeCreate()
+	endpoint.CreatedAt = time.Now()
+	query := `
+		INSERT INTO public.notification_endpoints (user_id, channel, address, public_key, secret, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, channel, address) DO UPDATE
+		SET public_key = EXCLUDED.public_key, secret = EXCLUDED.secret, created_at = EXCLUDED.created_at
+		RETURNING endpoint_id;`
+	if err := r.store.db.QueryRowx(
+		query,
+		endpoint.UserID,
+		endpoint.Channel,
+		endpoint.Address,
+		endpoint.PublicKey,
+		endpoint.Secret,
+		endpoint.CreatedAt,
+	).Scan(&endpoint.EndpointID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (r *NotificationRepository) DeleteEndpoint(userID int, endpointID int) (*models.NotificationEndpoint, error) {
+	endpoint := &models.NotificationEndpoint{}
+	if err := r.store.db.Get(
+		endpoint,
+		`DELETE FROM public.notification_endpoints WHERE endpoint_id = $1 AND user_id = $2 RETURNING *;`,
+		endpointID,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	endpoint.AfterCreate()
+	return endpoint, nil
+}
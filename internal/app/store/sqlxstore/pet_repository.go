@@ -3,7 +3,11 @@ package sqlxstore
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/nutrition"
+	"github.com/jmoiron/sqlx"
+	"strings"
 	"time"
 )
 
@@ -60,9 +64,11 @@ func (r *PetRepository) FindByID(petID int) (*models.Pet, error) {
 func (r *PetRepository) CreatePet(pet *models.Pet) (*models.Pet, error) {
 	createQuery := `
 		INSERT INTO 
-			public.pets (name, user_id, veterinarian_id, pet_type, breed, family_name, mother_id, father_id) 
+			public.pets (name, user_id, veterinarian_id, pet_type, breed, family_name, mother_id, father_id,
+				birth_date, sex, is_neutered, activity_level, goal, reproductive_status) 
 		VALUES 
-			(:name, :user_id, :veterinarian_id, :pet_type, :breed, :family_name, :mother_id, :father_id);`
+			(:name, :user_id, :veterinarian_id, :pet_type, :breed, :family_name, :mother_id, :father_id,
+				:birth_date, :sex, :is_neutered, :activity_level, :goal, :reproductive_status);`
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
 		r.store.logger.Println(err)
@@ -97,7 +103,13 @@ func (r *PetRepository) UpdatePet(pet *models.Pet) (*models.Pet, error) {
 			mother_id = :mother_id,
 			mother_verified = :mother_verified,
 			father_id = :father_id,
-			father_verified = :father_verified
+			father_verified = :father_verified,
+			birth_date = :birth_date,
+			sex = :sex,
+			is_neutered = :is_neutered,
+			activity_level = :activity_level,
+			goal = :goal,
+			reproductive_status = :reproductive_status
 		WHERE public.pets.pet_id = :pet_id`
 
 	updatingPet, err := r.FindByID(pet.PetID)
@@ -356,9 +368,9 @@ func (r *PetRepository) FindTypeByID(typeID int) (*models.PetType, error) {
 func (r *PetRepository) CreatePetType(petType *models.PetType) (*models.PetType, error) {
 	insertQuery := `
 		INSERT INTO public.pet_types 
-			(type_name, rer_coefficient) 
+			(type_name, rer_coefficient, species) 
 		VALUES 
-			(:type_name, :rer_coefficient)`
+			(:type_name, :rer_coefficient, COALESCE(NULLIF(:species, ''), 'other'))`
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
 		r.store.logger.Println(err)
@@ -385,7 +397,8 @@ func (r *PetRepository) UpdatePetType(other *models.PetType) (*models.PetType, e
 		UPDATE public.pet_types
 		SET 
 			type_name = :type_name,
-			rer_coefficient = :rer_coefficient
+			rer_coefficient = :rer_coefficient,
+			species = COALESCE(NULLIF(:species, ''), species)
 		WHERE type_id = :type_id`
 
 	transaction, err := r.store.db.Beginx()
@@ -570,8 +583,69 @@ func (r *PetRepository) CreateActivityRecord(record *models.Activity) error {
 	return nil
 }
 
+// activityInsertChunk keeps the count of query parameters of the multi-row insert under the postgres limit
+const activityInsertChunk = 1000
+
+/*
+CreateActivityRecords inserts the device samples with the multi-row inserts in one transaction.
+
+Samples the device has already uploaded are skipped, the sample IDs of the inserted records are returned.
+*/
+func (r *PetRepository) CreateActivityRecords(records []models.Activity) ([]string, error) {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	inserted := make([]string, 0, len(records))
+	for start := 0; start < len(records); start += activityInsertChunk {
+		end := start + activityInsertChunk
+		if end > len(records) {
+			end = len(records)
+		}
+		var query strings.Builder
+		query.WriteString(`INSERT INTO public.activity (record_timestamp, pet_id, distance, mean_speed, device_id, sample_id) VALUES `)
+		args := make([]interface{}, 0, (end-start)*6)
+		for idx, record := range records[start:end] {
+			if idx > 0 {
+				query.WriteString(", ")
+			}
+			n := len(args)
+			fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+			args = append(args, record.RecordTimestamp, record.PetID, record.Distance, record.MeanSpeed, record.DeviceID, record.SampleID)
+		}
+		query.WriteString(` ON CONFLICT (device_id, sample_id) DO NOTHING RETURNING sample_id;`)
+
+		var sampleIDs []string
+		if err := transaction.Select(&sampleIDs, query.String(), args...); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+		inserted = append(inserted, sampleIDs...)
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// countedActivity skips the walk records covered by the samples uploaded after the walk was segmented,
+// the distance of the walk is counted by the samples then
+const countedActivity = `
+		(walk_id IS NULL OR NOT EXISTS (
+			SELECT 1 FROM public.activity samples
+			INNER JOIN public.walks w ON w.walk_id = activity.walk_id
+			WHERE samples.pet_id = activity.pet_id AND samples.walk_id IS NULL
+				AND samples.record_timestamp BETWEEN w.started_at AND w.ended_at))`
+
 func (r *PetRepository) SelectPetActivityRecords(petID int) ([]models.Activity, error) {
-	query := `SELECT * FROM public.activity WHERE pet_id = $1;`
+	query := `SELECT * FROM public.activity WHERE pet_id = $1 AND ` + countedActivity + `;`
 	var petActivityModels []models.Activity
 	if err := r.store.db.Select(&petActivityModels, query, petID); err != nil {
 		r.store.logger.Println(err)
@@ -581,7 +655,7 @@ func (r *PetRepository) SelectPetActivityRecords(petID int) ([]models.Activity,
 }
 
 func (r *PetRepository) SelectPetActivityRecordsInInterval(petID int, start time.Time, end time.Time) ([]models.Activity, error) {
-	query := `SELECT * FROM public.activity WHERE pet_id = $1 AND record_timestamp::date >= $2 AND record_timestamp::date <= $3;`
+	query := `SELECT * FROM public.activity WHERE pet_id = $1 AND record_timestamp::date >= $2 AND record_timestamp::date <= $3 AND ` + countedActivity + `;`
 	var petActivityModels []models.Activity
 	if err := r.store.db.Select(&petActivityModels, query, petID, start, end); err != nil {
 		r.store.logger.Println(err)
@@ -591,7 +665,7 @@ func (r *PetRepository) SelectPetActivityRecordsInInterval(petID int, start time
 }
 
 func (r *PetRepository) SelectPetActivityRecordsToTime(petID int, start time.Time) ([]models.Activity, error) {
-	query := `SELECT * FROM public.activity WHERE pet_id = $1 AND record_timestamp::date <= $2;`
+	query := `SELECT * FROM public.activity WHERE pet_id = $1 AND record_timestamp::date <= $2 AND ` + countedActivity + `;`
 	var petActivityModels []models.Activity
 	if err := r.store.db.Select(&petActivityModels, query, petID, start); err != nil {
 		r.store.logger.Println(err)
@@ -600,6 +674,20 @@ func (r *PetRepository) SelectPetActivityRecordsToTime(petID int, start time.Tim
 	return petActivityModels, nil
 }
 
+// eatenNutrientsColumns sum the energy and the nutrients of the eatings, portions are in grams
+// and the composition of the food is per 100 g
+const eatenNutrientsColumns = `
+			SUM(f.energy_per_100g * eatings.portion_weight / 100) AS "eat_ccal",
+			COALESCE(SUM(f.protein * eatings.portion_weight / 100), 0) AS "protein",
+			COALESCE(SUM(f.fat * eatings.portion_weight / 100), 0) AS "fat",
+			COALESCE(SUM(f.carbohydrate * eatings.portion_weight / 100), 0) AS "carbohydrate",
+			COALESCE(SUM(f.fibre * eatings.portion_weight / 100), 0) AS "fibre",
+			COALESCE(SUM(f.moisture * eatings.portion_weight / 100), 0) AS "moisture",
+			COALESCE(SUM(f.calcium * eatings.portion_weight / 100), 0) AS "calcium",
+			COALESCE(SUM(f.phosphorus * eatings.portion_weight / 100), 0) AS "phosphorus",
+			BOOL_AND(f.protein IS NOT NULL AND f.fat IS NOT NULL AND f.carbohydrate IS NOT NULL AND f.fibre IS NOT NULL
+				AND f.moisture IS NOT NULL AND f.calcium IS NOT NULL AND f.phosphorus IS NOT NULL) AS "is_complete"`
+
 func (r *PetRepository) GetPetStatistics(petID int) (
 	[]models.FoodCaloriesReport,
 	[]models.RERCaloriesReport,
@@ -607,20 +695,15 @@ func (r *PetRepository) GetPetStatistics(petID int) (
 	[]models.ActivityReport,
 	error) {
 	foodQuery := `
-		SELECT date(eating_timestamp), SUM(calories * eatings.portion_weight) AS "eat_ccal" FROM eatings
+		SELECT date(eating_timestamp) AS date, ` + eatenNutrientsColumns + ` FROM eatings
 		INNER JOIN food f ON f.food_id = eatings.food_id
 		WHERE pet_id = $1
 		GROUP BY date(eating_timestamp);`
-	RERQuery := `
-		SELECT date(a.record_time), 70 * pt.rer_coefficient * POWER(a.weight, 0.75) as rer_ccal FROM pets
-		INNER JOIN anthropometries a on pets.pet_id = a.pet_id
-		INNER JOIN pet_types pt on pt.type_id = pets.pet_type
-		WHERE a.pet_id = $1;`
 	anthropometryQuery := `
 		SELECT DATE(record_time) as date, height, weight FROM anthropometries WHERE pet_id = $1;`
 	activityQuery := `
 		SELECT date(record_timestamp) as date, SUM(distance) as distance, AVG(mean_speed) as mean_speed FROM public.activity
-		WHERE pet_id = $1
+		WHERE pet_id = $1 AND ` + countedActivity + `
 		GROUP BY date(record_timestamp);`
 
 	var foodModels []models.FoodCaloriesReport
@@ -628,12 +711,12 @@ func (r *PetRepository) GetPetStatistics(petID int) (
 	var anthropometryModels []models.AnthropometryReport
 	var activityModels []models.ActivityReport
 
-	if err := r.store.db.Select(&foodModels, foodQuery, petID); err != nil && !errors.Is(err, sql.ErrNoRows) {
-		r.store.logger.Println(err)
+	petModel, petType, err := r.findWithType(petID)
+	if err != nil {
 		return nil, nil, nil, nil, err
 	}
 
-	if err := r.store.db.Select(&rerModels, RERQuery, petID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+	if err := r.store.db.Select(&foodModels, foodQuery, petID); err != nil && !errors.Is(err, sql.ErrNoRows) {
 		r.store.logger.Println(err)
 		return nil, nil, nil, nil, err
 	}
@@ -642,6 +725,18 @@ func (r *PetRepository) GetPetStatistics(petID int) (
 		r.store.logger.Println(err)
 		return nil, nil, nil, nil, err
 	}
+	// The target of the day is the energy requirement of the pet at the age it had when it was weighed
+	for _, record := range anthropometryModels {
+		requirement, err := nutrition.Compute(nutrition.ProfileOf(petModel, petType, record.Weight), record.Date)
+		if err != nil {
+			continue
+		}
+		rerModels = append(rerModels, models.RERCaloriesReport{
+			Date:             record.Date,
+			RERTotalCalories: requirement.RER,
+			MERTotalCalories: requirement.MER,
+		})
+	}
 
 	if err := r.store.db.Select(&activityModels, activityQuery, petID); err != nil && !errors.Is(err, sql.ErrNoRows) {
 		r.store.logger.Println(err)
@@ -656,53 +751,65 @@ func (r *PetRepository) GetPetDateStatistics(petID int, day time.Time) (*models.
 		MeanSpeed float64 `db:"mean_speed"`
 	}
 
-	currentFoodCaloriesQuery := `
-		SELECT SUM(calories * eatings.portion_weight) AS "eat_ccal" FROM eatings
+	currentFoodQuery := `
+		SELECT ` + eatenNutrientsColumns + ` FROM eatings
 		INNER JOIN food f ON f.food_id = eatings.food_id
 		WHERE pet_id = $1 AND eating_timestamp::date = $2
 		GROUP BY date(eating_timestamp);`
-	currentRERCaloriesQuery := `
-		SELECT 70 * (
-			SELECT rer_coefficient FROM pet_types INNER JOIN pets p on pet_types.type_id = p.pet_type WHERE pet_id = $1
-			) *
-			power(
-				(SELECT weight FROM anthropometries WHERE pet_id = $1 ORDER BY record_time DESC LIMIT 1),
-				0.75
-			) AS "rer_ccal";`
+	currentWeightQuery := `
+		SELECT weight FROM anthropometries WHERE pet_id = $1 AND record_time::date <= $2 ORDER BY record_time DESC LIMIT 1;`
 	currentActivityQuery := `
-		SELECT SUM(distance) as distance, AVG(mean_speed) as mean_speed FROM public.activity 
-		WHERE pet_id = $1 AND record_timestamp::date = $2
+		SELECT SUM(distance) as distance, AVG(mean_speed) as mean_speed FROM public.activity
+		WHERE pet_id = $1 AND record_timestamp::date = $2 AND ` + countedActivity + `
 		GROUP BY date(record_timestamp);`
 
-	var currentFoodCal float64
-	var currentRERCal float64
+	currentFood := &models.FoodCaloriesReport{NutrientTotals: models.NutrientTotals{IsComplete: true}}
+	var currentWeight float64
+	var currentRERCal, currentMERCal float64
 	currentActivity := &activityResult{}
 
-	if err := r.store.db.Get(&currentFoodCal, currentFoodCaloriesQuery, petID, day); err != nil && !errors.Is(err, sql.ErrNoRows) {
+	petModel, petType, err := r.findWithType(petID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.store.db.Get(currentFood, currentFoodQuery, petID, day); err != nil && !errors.Is(err, sql.ErrNoRows) {
 		r.store.logger.Println(err)
 		return nil, err
 	}
-	if err := r.store.db.Get(&currentRERCal, currentRERCaloriesQuery, petID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+	if err := r.store.db.Get(&currentWeight, currentWeightQuery, petID, day); err != nil && !errors.Is(err, sql.ErrNoRows) {
 		r.store.logger.Println(err)
 		return nil, err
 	}
+	profile := nutrition.ProfileOf(petModel, petType, currentWeight)
+	if requirement, err := nutrition.Compute(profile, day); err == nil {
+		currentRERCal, currentMERCal = requirement.RER, requirement.MER
+	}
+	// Without the weight the minimums scale with the energy eaten
+	nutrientsEnergy := currentMERCal
+	if nutrientsEnergy == 0 {
+		nutrientsEnergy = currentFood.FoodTotalCalories
+	}
 	if err := r.store.db.Get(currentActivity, currentActivityQuery, petID, day); err != nil && !errors.Is(err, sql.ErrNoRows) {
 		r.store.logger.Println(err)
 		return nil, err
 	}
 
 	return &models.TodayReport{
-		FoodTotalCalories: currentFoodCal,
+		FoodTotalCalories: currentFood.FoodTotalCalories,
 		RERTotalCalories:  currentRERCal,
+		MERTotalCalories:  currentMERCal,
 		MeanSpeed:         currentActivity.MeanSpeed,
 		TotalDistance:     currentActivity.Distance,
+		NutrientTotals:    currentFood.NutrientTotals,
+		Nutrients:         nutrition.AssessNutrients(profile, day, currentFood.NutrientTotals, nutrientsEnergy),
 	}, nil
 }
 
 func (r *PetRepository) CreatePetHealthReport(report *models.PetHealthReport) error {
 	insertQuery := `
 		INSERT INTO public.pet_health_reports (pet_id, veterinarian_id, report_timestamp, report_conclusion, report_comments)
-		VALUES (:pet_id, :veterinarian_id, :report_timestamp, :report_conclusion, :report_comments);`
+		VALUES (:pet_id, :veterinarian_id, :report_timestamp, :report_conclusion, :report_comments)
+		RETURNING *;`
 
 	transaction, err := r.store.db.Beginx()
 	if err != nil {
@@ -713,7 +820,19 @@ func (r *PetRepository) CreatePetHealthReport(report *models.PetHealthReport) er
 		_ = transaction.Rollback()
 	}()
 
-	if _, err := transaction.NamedExec(insertQuery, report); err != nil {
+	statement, err := transaction.PrepareNamed(insertQuery)
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = statement.Close()
+	}()
+	if err := statement.Get(report, report); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	if err := r.insertHealthReportVersion(transaction, report, report.VeterinarianID, nil); err != nil {
 		r.store.logger.Println(err)
 		return err
 	}
@@ -722,9 +841,111 @@ func (r *PetRepository) CreatePetHealthReport(report *models.PetHealthReport) er
 		r.store.logger.Println(err)
 		return err
 	}
+	report.AfterCreate()
 	return nil
 }
 
+/*
+UpdatePetHealthReport stores the edit of the report as its next version, the edit of the signed report is
+the amendment with the reason. The report is changed only if it is still of the version it was edited from,
+sql.ErrNoRows is returned when it has been changed meanwhile.
+*/
+func (r *PetRepository) UpdatePetHealthReport(report *models.PetHealthReport, editedBy int, amendmentReason string) error {
+	report.BeforeCreate()
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if err := transaction.Get(
+		report,
+		`UPDATE public.pet_health_reports
+		SET report_conclusion = $3, report_comments = $4, version = version + 1,
+			status = CASE WHEN status = 'draft' THEN 'draft' ELSE 'amended' END, updated_at = now()
+		WHERE report_id = $1 AND version = $2
+		RETURNING *;`,
+		report.ReportID,
+		report.Version,
+		report.ReportConclusion,
+		report.ReportComments,
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	var reason *sql.NullString
+	if report.IsSigned() {
+		reason = &sql.NullString{String: amendmentReason, Valid: true}
+	}
+	if err := r.insertHealthReportVersion(transaction, report, editedBy, reason); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	report.AfterCreate()
+	return nil
+}
+
+// SignPetHealthReport locks the draft report of the version, sql.ErrNoRows is returned when it has been changed meanwhile
+func (r *PetRepository) SignPetHealthReport(reportID int, version int, signedBy int) (*models.PetHealthReport, error) {
+	report := &models.PetHealthReport{}
+	if err := r.store.db.Get(
+		report,
+		`UPDATE public.pet_health_reports
+		SET status = 'signed', signed_by = $3, signed_at = now(), updated_at = now()
+		WHERE report_id = $1 AND version = $2 AND status = 'draft'
+		RETURNING *;`,
+		reportID,
+		version,
+		signedBy,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	report.AfterCreate()
+	return report, nil
+}
+
+func (r *PetRepository) SelectPetHealthReportVersions(reportID int) ([]models.PetHealthReportVersion, error) {
+	versions := make([]models.PetHealthReportVersion, 0)
+	if err := r.store.db.Select(
+		&versions,
+		`SELECT * FROM public.pet_health_report_versions WHERE report_id = $1 ORDER BY version;`,
+		reportID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range versions {
+		versions[idx].AfterCreate()
+	}
+	return versions, nil
+}
+
+// insertHealthReportVersion keeps the current content of the report as its version, the amendments have the reason
+func (r *PetRepository) insertHealthReportVersion(transaction *sqlx.Tx, report *models.PetHealthReport, editedBy int, amendmentReason *sql.NullString) error {
+	_, err := transaction.Exec(
+		`INSERT INTO public.pet_health_report_versions
+			(report_id, version, report_conclusion, report_comments, edited_by, is_amendment, amendment_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7);`,
+		report.ReportID,
+		report.Version,
+		report.ReportConclusion,
+		report.ReportComments,
+		editedBy,
+		amendmentReason != nil,
+		amendmentReason,
+	)
+	return err
+}
+
 func (r *PetRepository) GetAllPetHealthReports(petID int) ([]models.PetHealthReport, error) {
 	query := `SELECT * FROM public.pet_health_reports WHERE pet_id = $1;`
 	var reports []models.PetHealthReport
@@ -737,3 +958,26 @@ func (r *PetRepository) GetAllPetHealthReports(petID int) ([]models.PetHealthRep
 	}
 	return reports, nil
 }
+
+func (r *PetRepository) FindPetHealthReport(reportID int) (*models.PetHealthReport, error) {
+	report := &models.PetHealthReport{}
+	if err := r.store.db.Get(report, `SELECT * FROM public.pet_health_reports WHERE report_id = $1;`, reportID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	report.AfterCreate()
+	return report, nil
+}
+
+// findWithType returns the pet with its type, the energy requirement of the pet depends on both
+func (r *PetRepository) findWithType(petID int) (*models.Pet, *models.PetType, error) {
+	petModel, err := r.FindByID(petID)
+	if err != nil {
+		return nil, nil, err
+	}
+	petType, err := r.FindTypeByID(petModel.PetType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return petModel, petType, nil
+}
@@ -14,13 +14,25 @@ type PostgreDatabaseStore struct {
 	db     *sqlx.DB
 	logger *log.Logger
 
-	userRepository       *UserRepository
-	roleRepository       *RoleRepository
-	petRepository        *PetRepository
-	vaccineRepository    *VaccineRepository
-	foodRepository       *FoodRepository
-	dumpRepository       *DumpRepository
-	ioTDevicesRepository *IoTDevicesRepository
+	userRepository          *UserRepository
+	roleRepository          *RoleRepository
+	petRepository           *PetRepository
+	vaccineRepository       *VaccineRepository
+	foodRepository          *FoodRepository
+	dumpRepository          *DumpRepository
+	ioTDevicesRepository    *IoTDevicesRepository
+	clinicRepository        *ClinicRepository
+	appointmentRepository   *AppointmentRepository
+	jobRepository           *JobRepository
+	notificationRepository  *NotificationRepository
+	webhookRepository       *WebhookRepository
+	walkRepository          *WalkRepository
+	geofenceRepository      *GeofenceRepository
+	healthAlertRepository   *HealthAlertRepository
+	feedingPlanRepository   *FeedingPlanRepository
+	medicationRepository    *MedicationRepository
+	medicalRecordRepository *MedicalRecordRepository
+	attachmentRepository    *AttachmentRepository
 }
 
 func NewPostgreDatabaseStore(logger *log.Logger) *PostgreDatabaseStore {
@@ -123,3 +135,123 @@ func (s *PostgreDatabaseStore) IoTDevicesRepository() repos.IoTDevicesRepository
 	}
 	return s.ioTDevicesRepository
 }
+
+func (s *PostgreDatabaseStore) Clinics() repos.ClinicRepository {
+	if s.clinicRepository != nil {
+		return s.clinicRepository
+	}
+	s.clinicRepository = &ClinicRepository{
+		store: s,
+	}
+	return s.clinicRepository
+}
+
+func (s *PostgreDatabaseStore) Appointments() repos.AppointmentRepository {
+	if s.appointmentRepository != nil {
+		return s.appointmentRepository
+	}
+	s.appointmentRepository = &AppointmentRepository{
+		store: s,
+	}
+	return s.appointmentRepository
+}
+
+func (s *PostgreDatabaseStore) Jobs() repos.JobRepository {
+	if s.jobRepository != nil {
+		return s.jobRepository
+	}
+	s.jobRepository = &JobRepository{
+		store: s,
+	}
+	return s.jobRepository
+}
+
+func (s *PostgreDatabaseStore) Notifications() repos.NotificationRepository {
+	if s.notificationRepository != nil {
+		return s.notificationRepository
+	}
+	s.notificationRepository = &NotificationRepository{
+		store: s,
+	}
+	return s.notificationRepository
+}
+
+func (s *PostgreDatabaseStore) Webhooks() repos.WebhookRepository {
+	if s.webhookRepository != nil {
+		return s.webhookRepository
+	}
+	s.webhookRepository = &WebhookRepository{
+		store: s,
+	}
+	return s.webhookRepository
+}
+
+func (s *PostgreDatabaseStore) Walks() repos.WalkRepository {
+	if s.walkRepository != nil {
+		return s.walkRepository
+	}
+	s.walkRepository = &WalkRepository{
+		store: s,
+	}
+	return s.walkRepository
+}
+
+func (s *PostgreDatabaseStore) Geofences() repos.GeofenceRepository {
+	if s.geofenceRepository != nil {
+		return s.geofenceRepository
+	}
+	s.geofenceRepository = &GeofenceRepository{
+		store: s,
+	}
+	return s.geofenceRepository
+}
+
+func (s *PostgreDatabaseStore) HealthAlerts() repos.HealthAlertRepository {
+	if s.healthAlertRepository != nil {
+		return s.healthAlertRepository
+	}
+	s.healthAlertRepository = &HealthAlertRepository{
+		store: s,
+	}
+	return s.healthAlertRepository
+}
+
+func (s *PostgreDatabaseStore) FeedingPlans() repos.FeedingPlanRepository {
+	if s.feedingPlanRepository != nil {
+		return s.feedingPlanRepository
+	}
+	s.feedingPlanRepository = &FeedingPlanRepository{
+		store: s,
+	}
+	return s.feedingPlanRepository
+}
+
+func (s *PostgreDatabaseStore) Medications() repos.MedicationRepository {
+	if s.medicationRepository != nil {
+		return s.medicationRepository
+	}
+	s.medicationRepository = &MedicationRepository{
+		store: s,
+	}
+	return s.medicationRepository
+}
+
+func (s *PostgreDatabaseStore) MedicalRecords() repos.MedicalRecordRepository {
+	if s.medicalRecordRepository != nil {
+		return s.medicalRecordRepository
+	}
+	s.medicalRecordRepository = &MedicalRecordRepository{
+		store: s,
+	}
+	return s.medicalRecordRepository
+}
+
+func (s *PostgreDatabaseStore) Attachments() repos.AttachmentRepository {
+	if s.attachmentRepository != nil {
+		return s.attachmentRepository
+	}
+	s.attachmentRepository = &AttachmentRepository{
+		store: s,
+	}
+	return s.attachmentRepository
+}
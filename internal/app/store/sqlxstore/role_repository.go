@@ -65,6 +65,101 @@ func (r *RoleRepository) FindByID(roleID int) (*models.Role, error) {
 	return role, nil
 }
 
+/*
+SeedSystemRoles creates the base system roles if they are not present
+in the database and specifies the default user role, if it is not configured yet.
+
+Existing roles are matched by their system key, so the method is safe
+to be called on every server start.
+*/
+func (r *RoleRepository) SeedSystemRoles() error {
+	insertQuery := `
+		INSERT INTO public.roles (
+                name, description, 
+                allow_roles_crud, allow_users_crud, allow_veterinarians_crud, 
+                allow_vaccines_crud, allow_food_crud, allow_pets_crud, allow_database_dump, system_key)
+		VALUES (:name, :description, :allow_roles_crud, :allow_users_crud, :allow_veterinarians_crud, 
+                :allow_vaccines_crud, :allow_food_crud, :allow_pets_crud, :allow_database_dump, :system_key)
+		ON CONFLICT (system_key) DO NOTHING;`
+	configQuery := `
+		INSERT INTO public.config (default_user_role_id)
+		SELECT role_id FROM public.roles WHERE system_key = $1
+		AND NOT EXISTS (SELECT 1 FROM public.config);`
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	for _, role := range models.SystemRoles() {
+		role.BeforeCreate()
+		if _, err := transaction.NamedExec(insertQuery, role); err != nil {
+			r.store.logger.Println(err)
+			return err
+		}
+	}
+	if _, err := transaction.Exec(configQuery, models.SystemRoleUnsubscribed); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (r *RoleRepository) SelectDefaultRole() (*models.Role, error) {
+	var defaultRoleID int
+	if err := r.store.db.Get(
+		&defaultRoleID,
+		`SELECT DISTINCT default_user_role_id FROM public.config LIMIT 1`,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindByID(defaultRoleID)
+}
+
+func (r *RoleRepository) SetDefaultRole(roleID int) (*models.Role, error) {
+	role, err := r.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	result, err := transaction.Exec(`UPDATE public.config SET default_user_role_id = $1`, roleID)
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		if _, err := transaction.Exec(`INSERT INTO public.config (default_user_role_id) VALUES ($1)`, roleID); err != nil {
+			r.store.logger.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return role, nil
+}
+
 func (r *RoleRepository) Create(role *models.Role) (*models.Role, error) {
 	insertQuery := `
 		INSERT INTO public.roles (
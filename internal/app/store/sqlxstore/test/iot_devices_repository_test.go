@@ -0,0 +1,68 @@
+package sqlxstore
+
+import (
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestIoTDevicesTouch(t *testing.T) {
+	owner, err := store.Users().Create(&models.User{
+		AccountEmail:      "collar.owner@example.com",
+		Password:          "qwerty123",
+		Username:          "collarOwner",
+		FullName:          "Collar Owner",
+		SpecifiedLocation: "Kyiv, Ukraine",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = store.Users().DeleteByID(owner.UserID) })
+	petType, err := store.Pets().CreatePetType(&models.PetType{TypeName: "Collar test dog", RERCoefficient: 1.6, Species: "dog"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = store.Pets().DeleteTypeByID(petType.TypeID) })
+	pet := &models.Pet{Name: "Rex", UserID: owner.UserID, PetType: petType.TypeID}
+	pet.BeforeCreate()
+	pet, err = store.Pets().CreatePet(pet)
+	require.NoError(t, err)
+	t.Cleanup(func() { _, _ = store.Pets().DeleteByID(pet.PetID) })
+
+	secret, secretHash, err := auth.NewIoTDeviceSecret()
+	require.NoError(t, err)
+	device, err := store.IoTDevicesRepository().Create(&models.IoTDevice{
+		PetID:        pet.PetID,
+		UserID:       owner.UserID,
+		AccessSecret: secretHash,
+		Name:         "Collar",
+		Status:       models.IoTDeviceActive,
+	})
+	require.NoError(t, err)
+
+	// Only the hash of the secret is stored and the device is found by it
+	found, err := store.IoTDevicesRepository().GetByAccessSecret(auth.HashIoTDeviceSecret(secret))
+	require.NoError(t, err)
+	assert.Equal(t, device.DeviceID, found.DeviceID)
+	assert.NotEqual(t, secret, found.AccessSecret)
+	_, err = store.IoTDevicesRepository().GetByAccessSecret(secret)
+	assert.Error(t, err)
+
+	battery := 80
+	seenAt := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, store.IoTDevicesRepository().Touch(device.DeviceID, &models.DeviceTelemetry{
+		FirmwareVersion: "1.2.0",
+		BatteryLevel:    &battery,
+	}, seenAt))
+	// The telemetry without the device state keeps the last reported one
+	require.NoError(t, store.IoTDevicesRepository().Touch(device.DeviceID, &models.DeviceTelemetry{}, seenAt.Add(time.Minute)))
+	// The late message does not move the last seen time back
+	require.NoError(t, store.IoTDevicesRepository().Touch(device.DeviceID, &models.DeviceTelemetry{}, seenAt.Add(-time.Hour)))
+
+	touched, err := store.IoTDevicesRepository().GetByID(device.DeviceID)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", touched.SpecifiedFirmwareVersion)
+	require.NotNil(t, touched.SpecifiedBatteryLevel)
+	assert.Equal(t, 80, *touched.SpecifiedBatteryLevel)
+	require.NotNil(t, touched.SpecifiedLastSeenAt)
+	assert.True(t, touched.SpecifiedLastSeenAt.Equal(seenAt.Add(time.Minute)))
+}
@@ -226,10 +226,10 @@ func (r *UserRepository) ChangePassword(userID int, newPassword string) error {
 }
 
 func (r *UserRepository) AssignRole(userID int, roleID int) error {
-	var userCurrentRoleID int
+	var userCurrentRoleKey sql.NullString
 	if err := r.store.db.Get(
-		&userCurrentRoleID,
-		`SELECT role_id FROM public.user_roles WHERE user_id = $1`,
+		&userCurrentRoleKey,
+		`SELECT r.system_key FROM public.user_roles ur INNER JOIN public.roles r ON r.role_id = ur.role_id WHERE ur.user_id = $1`,
 		userID,
 	); err != nil {
 		r.store.logger.Println(err)
@@ -258,7 +258,7 @@ func (r *UserRepository) AssignRole(userID int, roleID int) error {
 		r.store.logger.Println(err)
 		return err
 	}
-	if userCurrentRoleID == 3 {
+	if userCurrentRoleKey.Valid && userCurrentRoleKey.String == models.SystemRoleUnsubscribed {
 		if _, err := r.store.db.Exec(
 			`UPDATE public.users SET subscription_date = $1 WHERE user_id = $2;`,
 			time.Now(),
@@ -400,6 +400,37 @@ func (r *UserRepository) DeleteClinic(userID int) (*models.VetClinic, error) {
 	return deletedModel, nil
 }
 
+func (r *UserRepository) FindCalendarFeed(userID int) (*models.CalendarFeed, error) {
+	selectQuery := `SELECT * FROM public.calendar_feeds WHERE user_id = $1;`
+	model := &models.CalendarFeed{}
+	if err := r.store.db.Get(model, selectQuery, userID); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// SaveCalendarFeed stores the new feed key of the user, replacing the previous one
+func (r *UserRepository) SaveCalendarFeed(userID int, feedKey string) (*models.CalendarFeed, error) {
+	upsertQuery := `
+		INSERT INTO public.calendar_feeds (user_id, feed_key, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET feed_key = excluded.feed_key, created_at = excluded.created_at;`
+	if _, err := r.store.db.Exec(upsertQuery, userID, feedKey, time.Now()); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindCalendarFeed(userID)
+}
+
+func (r *UserRepository) DeleteCalendarFeed(userID int) error {
+	deleteQuery := `DELETE FROM public.calendar_feeds WHERE user_id = $1;`
+	if _, err := r.store.db.Exec(deleteQuery, userID); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
 func (r *UserRepository) GetStatistics() ([]models.RegisterStatistics, []models.SubscribeStatistics, []models.User, error) {
 	var registers []models.RegisterStatistics
 	var subscriptions []models.SubscribeStatistics
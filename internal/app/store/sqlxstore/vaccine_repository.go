@@ -1,6 +1,10 @@
 package sqlxstore
 
-import "github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+import (
+	"database/sql"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+)
 
 type VaccineRepository struct {
 	store *PostgreDatabaseStore
@@ -33,9 +37,9 @@ func (r *VaccineRepository) SelectByPetID(petID int) ([]models.Vaccine, error) {
 func (r *VaccineRepository) Create(vaccine *models.Vaccine) (*models.Vaccine, error) {
 	insertQuery := `
 		INSERT INTO public.vaccines 
-			(pet_id, name, vaccination_date, description) 
+			(pet_id, catalogue_id, name, vaccination_date, description) 
 		VALUES
-			($1, $2, $3, $4)
+			($1, $2, $3, $4, $5)
 		RETURNING vaccine_id;`
 	selectQuery := `
 		SELECT * FROM public.vaccines
@@ -53,6 +57,7 @@ func (r *VaccineRepository) Create(vaccine *models.Vaccine) (*models.Vaccine, er
 	if err := transaction.QueryRowx(
 		insertQuery,
 		vaccine.PetID,
+		vaccine.CatalogueID,
 		vaccine.Name,
 		vaccine.VaccinationDate,
 		vaccine.Description,
@@ -79,6 +84,7 @@ func (r *VaccineRepository) Update(vaccine *models.Vaccine) (*models.Vaccine, er
 		UPDATE public.vaccines 
 		SET 
 			pet_id = :pet_id, 
+			catalogue_id = :catalogue_id, 
 			name = :name, 
 			vaccination_date = :vaccination_date, 
 			description = :description 
@@ -102,6 +108,10 @@ func (r *VaccineRepository) Update(vaccine *models.Vaccine) (*models.Vaccine, er
 		r.store.logger.Println(err)
 		return nil, err
 	}
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
 	return updatingModel, nil
 }
 
@@ -132,3 +142,150 @@ func (r *VaccineRepository) DeleteByID(vaccineID int) (*models.Vaccine, error) {
 	deletingModel.AfterCreate()
 	return deletingModel, nil
 }
+
+func (r *VaccineRepository) SelectCatalogue() ([]models.VaccineCatalogueEntry, error) {
+	query := `SELECT * FROM public.vaccine_catalogue ORDER BY pet_type, name;`
+	var entries []models.VaccineCatalogueEntry
+	if err := r.store.db.Select(&entries, query); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range entries {
+		entries[idx].AfterCreate()
+	}
+	return entries, nil
+}
+
+func (r *VaccineRepository) SelectCatalogueByPetType(petType int) ([]models.VaccineCatalogueEntry, error) {
+	query := `SELECT * FROM public.vaccine_catalogue WHERE pet_type = $1 ORDER BY name;`
+	var entries []models.VaccineCatalogueEntry
+	if err := r.store.db.Select(&entries, query, petType); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range entries {
+		entries[idx].AfterCreate()
+	}
+	return entries, nil
+}
+
+func (r *VaccineRepository) FindCatalogueEntryByID(catalogueID int) (*models.VaccineCatalogueEntry, error) {
+	query := `SELECT * FROM public.vaccine_catalogue WHERE catalogue_id = $1;`
+	entry := &models.VaccineCatalogueEntry{}
+	if err := r.store.db.Get(entry, query, catalogueID); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			r.store.logger.Println(err)
+		}
+		return nil, err
+	}
+	entry.AfterCreate()
+	return entry, nil
+}
+
+func (r *VaccineRepository) CreateCatalogueEntry(entry *models.VaccineCatalogueEntry) (*models.VaccineCatalogueEntry, error) {
+	insertQuery := `
+		INSERT INTO public.vaccine_catalogue
+			(name, pet_type, manufacturer, doses_count, dose_interval_days, booster_interval_months, first_dose_age_weeks)
+		VALUES
+			($1, $2, $3, $4, $5, $6, $7)
+		RETURNING catalogue_id;`
+
+	entry.BeforeCreate()
+	var catalogueID int
+	if err := r.store.db.QueryRowx(
+		insertQuery,
+		entry.Name,
+		entry.PetType,
+		entry.Manufacturer,
+		entry.DosesCount,
+		entry.DoseIntervalDays,
+		entry.BoosterIntervalMonths,
+		entry.FirstDoseAgeWeeks,
+	).Scan(&catalogueID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindCatalogueEntryByID(catalogueID)
+}
+
+func (r *VaccineRepository) UpdateCatalogueEntry(entry *models.VaccineCatalogueEntry) (*models.VaccineCatalogueEntry, error) {
+	updateQuery := `
+		UPDATE public.vaccine_catalogue
+		SET
+			name = :name,
+			pet_type = :pet_type,
+			manufacturer = :manufacturer,
+			doses_count = :doses_count,
+			dose_interval_days = :dose_interval_days,
+			booster_interval_months = :booster_interval_months,
+			first_dose_age_weeks = :first_dose_age_weeks
+		WHERE catalogue_id = :catalogue_id;`
+
+	entry.BeforeCreate()
+	if _, err := r.store.db.NamedExec(updateQuery, entry); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return r.FindCatalogueEntryByID(entry.CatalogueID)
+}
+
+func (r *VaccineRepository) DeleteCatalogueEntryByID(catalogueID int) (*models.VaccineCatalogueEntry, error) {
+	deleteQuery := `DELETE FROM public.vaccine_catalogue WHERE catalogue_id = $1;`
+	deletingModel, err := r.FindCatalogueEntryByID(catalogueID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.store.db.Exec(deleteQuery, catalogueID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return deletingModel, nil
+}
+
+func (r *VaccineRepository) SelectRemindersByPetID(petID int) ([]models.VaccineReminder, error) {
+	query := `SELECT * FROM public.vaccine_reminders WHERE pet_id = $1;`
+	var reminders []models.VaccineReminder
+	if err := r.store.db.Select(&reminders, query, petID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// CreateReminders records the sent reminders, the ones recorded already are skipped
+func (r *VaccineRepository) CreateReminders(reminders []models.VaccineReminder) error {
+	insertQuery := `
+		INSERT INTO public.vaccine_reminders
+			(pet_id, catalogue_id, dose_number, due_date, days_before, sent_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING;`
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	for _, reminder := range reminders {
+		if _, err := transaction.Exec(
+			insertQuery,
+			reminder.PetID,
+			reminder.CatalogueID,
+			reminder.DoseNumber,
+			reminder.DueDate,
+			reminder.DaysBefore,
+			reminder.SentAt,
+		); err != nil {
+			r.store.logger.Println(err)
+			return err
+		}
+	}
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
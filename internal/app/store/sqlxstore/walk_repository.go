@@ -0,0 +1,221 @@
+package sqlxstore
+
+import (
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+type WalkRepository struct {
+	store *PostgreDatabaseStore
+}
+
+// trackPointInsertChunk keeps the count of query parameters of the multi-row insert under the postgres limit
+const trackPointInsertChunk = 1000
+
+// CreateTrackPoints inserts the fixes in one transaction, the fixes already stored are skipped.
+// The count of the inserted fixes is returned.
+func (r *WalkRepository) CreateTrackPoints(points []models.TrackPoint) (int, error) {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return 0, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	inserted := 0
+	for start := 0; start < len(points); start += trackPointInsertChunk {
+		end := start + trackPointInsertChunk
+		if end > len(points) {
+			end = len(points)
+		}
+		var query strings.Builder
+		query.WriteString(`INSERT INTO public.track_points (pet_id, device_id, recorded_at, latitude, longitude, accuracy) VALUES `)
+		args := make([]interface{}, 0, (end-start)*6)
+		for idx, point := range points[start:end] {
+			if idx > 0 {
+				query.WriteString(", ")
+			}
+			n := len(args)
+			fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+			args = append(args, point.PetID, point.DeviceID, point.RecordedAt, point.Latitude, point.Longitude, point.Accuracy)
+		}
+		query.WriteString(` ON CONFLICT (pet_id, recorded_at) DO NOTHING;`)
+
+		result, err := transaction.Exec(query.String(), args...)
+		if err != nil {
+			r.store.logger.Println(err)
+			return 0, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			r.store.logger.Println(err)
+			return 0, err
+		}
+		inserted += int(affected)
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// SelectPetsWithPendingPoints returns the pets which have the fixes not segmented yet
+func (r *WalkRepository) SelectPetsWithPendingPoints() ([]int, error) {
+	petIDs := make([]int, 0)
+	if err := r.store.db.Select(
+		&petIDs,
+		`SELECT DISTINCT pet_id FROM public.track_points WHERE NOT is_segmented ORDER BY pet_id;`,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return petIDs, nil
+}
+
+func (r *WalkRepository) SelectPendingPoints(petID int) ([]models.TrackPoint, error) {
+	points := make([]models.TrackPoint, 0)
+	if err := r.store.db.Select(
+		&points,
+		`SELECT * FROM public.track_points WHERE pet_id = $1 AND NOT is_segmented ORDER BY recorded_at;`,
+		petID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return points, nil
+}
+
+/*
+CreateWalk stores the walk, assigns the fixes drawn on its map to it and adds the walk activity record
+unless the activity samples of the pet cover the walk.
+
+segmentedIDs are all fixes of the segment including the dropped ones, they are marked as segmented.
+*/
+func (r *WalkRepository) CreateWalk(walk *models.Walk, pointIDs []int64, segmentedIDs []int64) (*models.Walk, error) {
+	transaction, err := r.store.db.Beginx()
+	if err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	defer func() {
+		_ = transaction.Rollback()
+	}()
+
+	if err := transaction.QueryRowx(`
+		INSERT INTO public.walks (pet_id, started_at, ended_at, distance, duration, mean_speed, point_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING walk_id;`,
+		walk.PetID,
+		walk.StartedAt,
+		walk.EndedAt,
+		walk.Distance,
+		walk.Duration,
+		walk.MeanSpeed,
+		walk.PointCount,
+	).Scan(&walk.WalkID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if _, err := transaction.Exec(
+		`UPDATE public.track_points SET is_segmented = TRUE WHERE point_id = ANY($1);`,
+		pq.Int64Array(segmentedIDs),
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	if _, err := transaction.Exec(
+		`UPDATE public.track_points SET walk_id = $1 WHERE point_id = ANY($2);`,
+		walk.WalkID,
+		pq.Int64Array(pointIDs),
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	// The walk covered by the samples is already counted by them
+	if _, err := transaction.Exec(`
+		INSERT INTO public.activity (record_timestamp, pet_id, distance, mean_speed, walk_id)
+		SELECT $1::timestamp, $2::integer, $3::double precision, $4::double precision, $5::integer
+		WHERE NOT EXISTS (
+			SELECT 1 FROM public.activity
+			WHERE pet_id = $2 AND walk_id IS NULL AND record_timestamp BETWEEN $1 AND $6
+		);`,
+		walk.StartedAt,
+		walk.PetID,
+		walk.Distance,
+		walk.MeanSpeed,
+		walk.WalkID,
+		walk.EndedAt,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+
+	if err := transaction.Commit(); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return walk, nil
+}
+
+// MarkSegmented marks the fixes which do not form the walk, so they are not segmented again
+func (r *WalkRepository) MarkSegmented(pointIDs []int64) error {
+	if _, err := r.store.db.Exec(
+		`UPDATE public.track_points SET is_segmented = TRUE WHERE point_id = ANY($1);`,
+		pq.Int64Array(pointIDs),
+	); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
+
+func (r *WalkRepository) FindByID(walkID int) (*models.Walk, error) {
+	walk := &models.Walk{}
+	if err := r.store.db.Get(walk, `SELECT * FROM public.walks WHERE walk_id = $1;`, walkID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return walk, nil
+}
+
+// SelectByPetID returns the walks of the pet started in the interval
+func (r *WalkRepository) SelectByPetID(petID int, start time.Time, end time.Time) ([]models.Walk, error) {
+	walks := make([]models.Walk, 0)
+	if err := r.store.db.Select(
+		&walks,
+		`SELECT * FROM public.walks WHERE pet_id = $1 AND started_at >= $2 AND started_at < $3 ORDER BY started_at;`,
+		petID,
+		start,
+		end,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return walks, nil
+}
+
+// SelectPoints returns the fixes of the walks ordered by time
+func (r *WalkRepository) SelectPoints(walkIDs []int) ([]models.TrackPoint, error) {
+	ids := make(pq.Int64Array, len(walkIDs))
+	for idx, walkID := range walkIDs {
+		ids[idx] = int64(walkID)
+	}
+	points := make([]models.TrackPoint, 0)
+	if err := r.store.db.Select(
+		&points,
+		`SELECT * FROM public.track_points WHERE walk_id = ANY($1) ORDER BY walk_id, recorded_at;`,
+		ids,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return points, nil
+}
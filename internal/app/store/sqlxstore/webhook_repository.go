@@ -0,0 +1,193 @@
+package sqlxstore
+
+import (
+	"database/sql"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/lib/pq"
+	"time"
+)
+
+type WebhookRepository struct {
+	store *PostgreDatabaseStore
+}
+
+func (r *WebhookRepository) Create(subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	subscription.CreatedAt = time.Now()
+	query := `
+		INSERT INTO public.webhook_subscriptions (user_id, url, secret, events, is_active, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING subscription_id;`
+	if err := r.store.db.QueryRowx(
+		query,
+		subscription.UserID,
+		subscription.URL,
+		subscription.Secret,
+		subscription.Events,
+		subscription.IsActive,
+		subscription.CreatedAt,
+	).Scan(&subscription.SubscriptionID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *WebhookRepository) FindByID(subscriptionID int) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	if err := r.store.db.Get(
+		subscription,
+		`SELECT * FROM public.webhook_subscriptions WHERE subscription_id = $1;`,
+		subscriptionID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *WebhookRepository) SelectByUserID(userID int) ([]models.WebhookSubscription, error) {
+	subscriptions := make([]models.WebhookSubscription, 0)
+	if err := r.store.db.Select(
+		&subscriptions,
+		`SELECT * FROM public.webhook_subscriptions WHERE user_id = $1 ORDER BY subscription_id;`,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// SelectSubscribed returns the active subscriptions of the users receiving the event
+func (r *WebhookRepository) SelectSubscribed(userIDs []int, event string) ([]models.WebhookSubscription, error) {
+	query := `
+		SELECT * FROM public.webhook_subscriptions
+		WHERE user_id = ANY($1) AND is_active AND $2 = ANY(events)
+		ORDER BY subscription_id;`
+	ids := make(pq.Int64Array, len(userIDs))
+	for idx := range userIDs {
+		ids[idx] = int64(userIDs[idx])
+	}
+	subscriptions := make([]models.WebhookSubscription, 0)
+	if err := r.store.db.Select(&subscriptions, query, ids, event); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) Update(subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	query := `
+		UPDATE public.webhook_subscriptions SET url = $3, secret = $4, events = $5, is_active = $6
+		WHERE subscription_id = $1 AND user_id = $2
+		RETURNING *;`
+	updated := &models.WebhookSubscription{}
+	if err := r.store.db.Get(
+		updated,
+		query,
+		subscription.SubscriptionID,
+		subscription.UserID,
+		subscription.URL,
+		subscription.Secret,
+		subscription.Events,
+		subscription.IsActive,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *WebhookRepository) DeleteByID(userID int, subscriptionID int) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	if err := r.store.db.Get(
+		subscription,
+		`DELETE FROM public.webhook_subscriptions WHERE subscription_id = $1 AND user_id = $2 RETURNING *;`,
+		subscriptionID,
+		userID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (r *WebhookRepository) CreateDelivery(delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.CreatedAt = time.Now()
+	query := `
+		INSERT INTO public.webhook_deliveries (subscription_id, event, payload, status, redelivery_of, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING delivery_id;`
+	if err := r.store.db.QueryRowx(
+		query,
+		delivery.SubscriptionID,
+		delivery.Event,
+		delivery.Payload,
+		delivery.Status,
+		delivery.RedeliveryOf,
+		delivery.CreatedAt,
+	).Scan(&delivery.DeliveryID); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	delivery.AfterCreate()
+	return delivery, nil
+}
+
+func (r *WebhookRepository) FindDeliveryByID(deliveryID int) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	if err := r.store.db.Get(
+		delivery,
+		`SELECT * FROM public.webhook_deliveries WHERE delivery_id = $1;`,
+		deliveryID,
+	); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	delivery.AfterCreate()
+	return delivery, nil
+}
+
+// SelectDeliveries returns the delivery log of the subscription, newest first
+func (r *WebhookRepository) SelectDeliveries(subscriptionID int, limit int, offset int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT * FROM public.webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC, delivery_id DESC
+		LIMIT $2 OFFSET $3;`
+	deliveries := make([]models.WebhookDelivery, 0)
+	if err := r.store.db.Select(&deliveries, query, subscriptionID, limit, offset); err != nil {
+		r.store.logger.Println(err)
+		return nil, err
+	}
+	for idx := range deliveries {
+		deliveries[idx].AfterCreate()
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt stores the outcome of the delivery attempt, responseStatus is 0 if no response was received
+func (r *WebhookRepository) RecordAttempt(deliveryID int, status string, responseStatus int, lastError string) error {
+	var response *sql.NullInt64
+	if responseStatus != 0 {
+		response = &sql.NullInt64{Int64: int64(responseStatus), Valid: true}
+	}
+	var failure *sql.NullString
+	if lastError != "" {
+		failure = &sql.NullString{String: lastError, Valid: true}
+	}
+	var deliveredAt *sql.NullTime
+	if status == models.WebhookDeliverySucceeded {
+		deliveredAt = &sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	query := `
+		UPDATE public.webhook_deliveries
+		SET status = $2, attempts = attempts + 1, response_status = $3, last_error = $4, delivered_at = $5
+		WHERE delivery_id = $1;`
+	if _, err := r.store.db.Exec(query, deliveryID, status, response, failure, deliveredAt); err != nil {
+		r.store.logger.Println(err)
+		return err
+	}
+	return nil
+}
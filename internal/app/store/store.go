@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/sessions"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/persistentstore"
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
@@ -19,6 +20,18 @@ type DatabaseStore interface {
 	Foods() repos.FoodRepository
 	Dumps() repos.DumpRepository
 	IoTDevicesRepository() repos.IoTDevicesRepository
+	Clinics() repos.ClinicRepository
+	Appointments() repos.AppointmentRepository
+	Jobs() repos.JobRepository
+	Notifications() repos.NotificationRepository
+	Webhooks() repos.WebhookRepository
+	Walks() repos.WalkRepository
+	Geofences() repos.GeofenceRepository
+	HealthAlerts() repos.HealthAlertRepository
+	FeedingPlans() repos.FeedingPlanRepository
+	Medications() repos.MedicationRepository
+	MedicalRecords() repos.MedicalRecordRepository
+	Attachments() repos.AttachmentRepository
 }
 
 type PersistentStore interface {
@@ -30,6 +43,9 @@ type PersistentStore interface {
 	DeleteSessionInfo(accessUUID string) (*sessions.Session, error)
 	GetUserIDByRefreshUUID(refreshUUID string) (int, error)
 	DeleteRefreshByUUID(refreshUUID string) error
+
+	Publish(channel string, message []byte) error
+	Subscribe(ctx context.Context, pattern string, handler func(channel string, message []byte)) error
 }
 
 func NewDatabaseStore(logger *log.Logger) DatabaseStore {
@@ -0,0 +1,162 @@
+// Package telemetry decodes and validates the activity samples collars upload in batches
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeJSON   = "application/json"
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// MaxBatchSize is the count of samples accepted in one request
+	MaxBatchSize = 1000
+	// MaxSampleIDLength is the length of the sample_id column
+	MaxSampleIDLength = 64
+
+	// MaxFutureSkew is how far ahead of the server clock the sample may be
+	MaxFutureSkew = 2 * time.Minute
+	// MaxSampleAge is how long the collar may keep samples while offline
+	MaxSampleAge = 30 * 24 * time.Hour
+	// MaxClockCorrection is the largest offset of the device clock which is corrected,
+	// the clock which is off further is considered broken and the batch is rejected
+	MaxClockCorrection = 24 * time.Hour
+)
+
+var (
+	ErrUnsupportedContentType = errors.New("telemetry: batch must be a JSON array or NDJSON")
+	ErrEmptyBatch             = errors.New("telemetry: batch has no samples")
+	ErrBatchTooLarge          = fmt.Errorf("telemetry: batch has more than %d samples", MaxBatchSize)
+	ErrDeviceClockSkew        = fmt.Errorf("telemetry: device clock is off by more than %v", MaxClockCorrection)
+)
+
+// Sample is the activity measured by the collar at RecordedAt by its clock.
+// SampleID is unique per device, samples uploaded again are skipped.
+type Sample struct {
+	SampleID   string    `json:"sample_id"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Distance   float64   `json:"distance"`
+	MeanSpeed  float64   `json:"mean_speed"`
+}
+
+// Rejection explains why the sample at Index of the batch has not been accepted
+type Rejection struct {
+	Index    int    `json:"index"`
+	SampleID string `json:"sample_id,omitempty"`
+	Error    string `json:"error"`
+}
+
+// Decode reads the batch which is either the JSON array of samples or the NDJSON stream
+func Decode(r io.Reader, contentType string) ([]Sample, error) {
+	mediaType := ContentTypeJSON
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return nil, ErrUnsupportedContentType
+		}
+	}
+
+	var samples []Sample
+	switch mediaType {
+	case ContentTypeJSON:
+		if err := json.NewDecoder(r).Decode(&samples); err != nil {
+			return nil, err
+		}
+	case ContentTypeNDJSON:
+		scanner := bufio.NewScanner(r)
+		line := 0
+		for scanner.Scan() {
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+			if len(samples) == MaxBatchSize {
+				return nil, ErrBatchTooLarge
+			}
+			sample := Sample{}
+			if err := json.Unmarshal(raw, &sample); err != nil {
+				return nil, fmt.Errorf("telemetry: line %d: %w", line, err)
+			}
+			samples = append(samples, sample)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupportedContentType
+	}
+
+	switch {
+	case len(samples) == 0:
+		return nil, ErrEmptyBatch
+	case len(samples) > MaxBatchSize:
+		return nil, ErrBatchTooLarge
+	}
+	return samples, nil
+}
+
+/*
+Normalize corrects the sample timestamps by the device clock offset and validates the samples.
+
+deviceNow is the time by the device clock when the batch was sent, if it is known the samples are
+shifted by its difference with now. Samples which are invalid, from the future or too old are rejected,
+samples repeating the sample ID of the previous one in the batch are skipped.
+*/
+func Normalize(samples []Sample, deviceNow *time.Time, now time.Time) ([]Sample, []Rejection, error) {
+	var offset time.Duration
+	if deviceNow != nil {
+		offset = now.Sub(*deviceNow)
+		if offset > MaxClockCorrection || offset < -MaxClockCorrection {
+			return nil, nil, ErrDeviceClockSkew
+		}
+	}
+
+	accepted := make([]Sample, 0, len(samples))
+	rejected := make([]Rejection, 0)
+	seen := make(map[string]bool, len(samples))
+	for idx, sample := range samples {
+		reject := func(reason string) {
+			rejected = append(rejected, Rejection{Index: idx, SampleID: sample.SampleID, Error: reason})
+		}
+		sample.SampleID = strings.TrimSpace(sample.SampleID)
+		switch {
+		case sample.SampleID == "":
+			reject("sample_id is required")
+			continue
+		case len(sample.SampleID) > MaxSampleIDLength:
+			reject(fmt.Sprintf("sample_id is longer than %d characters", MaxSampleIDLength))
+			continue
+		case sample.RecordedAt.IsZero():
+			reject("recorded_at is required")
+			continue
+		case sample.Distance < 0 || sample.MeanSpeed < 0:
+			reject("distance and mean_speed can not be negative")
+			continue
+		}
+		if seen[sample.SampleID] {
+			continue
+		}
+		seen[sample.SampleID] = true
+
+		sample.RecordedAt = sample.RecordedAt.Add(offset).UTC()
+		switch {
+		case sample.RecordedAt.After(now.Add(MaxFutureSkew)):
+			reject("recorded_at is in the future")
+			continue
+		case sample.RecordedAt.Before(now.Add(-MaxSampleAge)):
+			reject("recorded_at is too old")
+			continue
+		}
+		accepted = append(accepted, sample)
+	}
+	return accepted, rejected, nil
+}
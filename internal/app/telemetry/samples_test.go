@@ -0,0 +1,86 @@
+package telemetry
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	array := `[{"sample_id":"a","recorded_at":"2021-06-01T12:00:00Z","distance":10,"mean_speed":1.5},
+		{"sample_id":"b","recorded_at":"2021-06-01T12:01:00Z","distance":12,"mean_speed":1.2}]`
+	samples, err := Decode(strings.NewReader(array), "application/json; charset=utf-8")
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, "b", samples[1].SampleID)
+	assert.Equal(t, 12.0, samples[1].Distance)
+
+	ndjson := "{\"sample_id\":\"a\",\"recorded_at\":\"2021-06-01T12:00:00Z\",\"distance\":10}\n\n" +
+		"{\"sample_id\":\"b\",\"recorded_at\":\"2021-06-01T12:01:00Z\",\"distance\":12}\n"
+	samples, err = Decode(strings.NewReader(ndjson), ContentTypeNDJSON)
+	require.NoError(t, err)
+	require.Len(t, samples, 2)
+	assert.Equal(t, time.Date(2021, 6, 1, 12, 1, 0, 0, time.UTC), samples[1].RecordedAt)
+
+	_, err = Decode(strings.NewReader("{\"sample_id\":\"a\"}\nnot json\n"), ContentTypeNDJSON)
+	assert.EqualError(t, err, "telemetry: line 2: invalid character 'o' in literal null (expecting 'u')")
+
+	_, err = Decode(strings.NewReader("[]"), ContentTypeJSON)
+	assert.Equal(t, ErrEmptyBatch, err)
+
+	_, err = Decode(strings.NewReader("a,b"), "text/csv")
+	assert.Equal(t, ErrUnsupportedContentType, err)
+
+	var tooLarge strings.Builder
+	for i := 0; i <= MaxBatchSize; i++ {
+		fmt.Fprintf(&tooLarge, "{\"sample_id\":\"%d\"}\n", i)
+	}
+	_, err = Decode(strings.NewReader(tooLarge.String()), ContentTypeNDJSON)
+	assert.Equal(t, ErrBatchTooLarge, err)
+}
+
+func TestNormalize(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	samples := []Sample{
+		{SampleID: "ok", RecordedAt: now.Add(-time.Hour), Distance: 10},
+		{SampleID: "ok", RecordedAt: now.Add(-time.Hour), Distance: 10},
+		{SampleID: "", RecordedAt: now},
+		{SampleID: "future", RecordedAt: now.Add(10 * time.Minute)},
+		{SampleID: "old", RecordedAt: now.Add(-31 * 24 * time.Hour)},
+		{SampleID: "negative", RecordedAt: now, Distance: -1},
+		{SampleID: "no-time"},
+		{SampleID: strings.Repeat("x", MaxSampleIDLength+1), RecordedAt: now},
+	}
+
+	accepted, rejected, err := Normalize(samples, nil, now)
+	require.NoError(t, err)
+	require.Len(t, accepted, 1)
+	assert.Equal(t, "ok", accepted[0].SampleID)
+
+	indexes := make([]int, 0, len(rejected))
+	for _, rejection := range rejected {
+		indexes = append(indexes, rejection.Index)
+	}
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7}, indexes)
+	assert.Equal(t, "recorded_at is in the future", rejected[1].Error)
+}
+
+func TestNormalize_DeviceClock(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	// The collar clock is 10 minutes behind, so its samples are shifted forward
+	deviceNow := now.Add(-10 * time.Minute)
+	samples := []Sample{{SampleID: "a", RecordedAt: deviceNow.Add(-time.Minute)}}
+
+	accepted, rejected, err := Normalize(samples, &deviceNow, now)
+	require.NoError(t, err)
+	assert.Empty(t, rejected)
+	require.Len(t, accepted, 1)
+	assert.Equal(t, now.Add(-time.Minute), accepted[0].RecordedAt)
+
+	broken := now.Add(-48 * time.Hour)
+	_, _, err = Normalize(samples, &broken, now)
+	assert.Equal(t, ErrDeviceClockSkew, err)
+}
@@ -0,0 +1,39 @@
+package tracks
+
+// Feature is the GeoJSON feature, Properties are rendered as the object
+type Feature struct {
+	Type       string      `json:"type"`
+	ID         int         `json:"id,omitempty"`
+	Geometry   *Geometry   `json:"geometry"`
+	Properties interface{} `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Geometry is the GeoJSON LineString, the positions are longitude and latitude
+type Geometry struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+func LineString(points []Point) *Geometry {
+	coordinates := make([][2]float64, len(points))
+	for idx, point := range points {
+		coordinates[idx] = [2]float64{point.Longitude, point.Latitude}
+	}
+	return &Geometry{Type: "LineString", Coordinates: coordinates}
+}
+
+func NewFeature(id int, geometry *Geometry, properties interface{}) Feature {
+	return Feature{Type: "Feature", ID: id, Geometry: geometry, Properties: properties}
+}
+
+func NewFeatureCollection(features []Feature) *FeatureCollection {
+	if features == nil {
+		features = make([]Feature, 0)
+	}
+	return &FeatureCollection{Type: "FeatureCollection", Features: features}
+}
@@ -0,0 +1,143 @@
+// Package tracks validates the GPS fixes of the collars, splits them into walks and renders walks as GeoJSON
+package tracks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/telemetry"
+	"io"
+	"math"
+	"time"
+)
+
+// MaxBatchSize is the count of points accepted in one upload
+const MaxBatchSize = 1000
+
+var (
+	ErrEmptyBatch    = errors.New("tracks: batch has no points")
+	ErrBatchTooLarge = fmt.Errorf("tracks: batch has more than %d points", MaxBatchSize)
+)
+
+// Point is the GPS fix, Accuracy is the radius of the fix in meters when the collar reports it
+type Point struct {
+	RecordedAt time.Time `json:"recorded_at"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy,omitempty"`
+}
+
+// FromModels converts the stored fixes
+func FromModels(trackPoints []models.TrackPoint) []Point {
+	points := make([]Point, len(trackPoints))
+	for idx := range trackPoints {
+		points[idx] = Point{
+			RecordedAt: trackPoints[idx].RecordedAt,
+			Latitude:   trackPoints[idx].Latitude,
+			Longitude:  trackPoints[idx].Longitude,
+		}
+		if accuracy := trackPoints[idx].Accuracy; accuracy != nil && accuracy.Valid {
+			value := accuracy.Float64
+			points[idx].Accuracy = &value
+		}
+	}
+	return points
+}
+
+// ToModels converts the fixes reported by the device of the pet
+func ToModels(points []Point, petID int, deviceID int) []models.TrackPoint {
+	trackPoints := make([]models.TrackPoint, len(points))
+	for idx, point := range points {
+		trackPoints[idx] = models.TrackPoint{
+			PetID:      petID,
+			DeviceID:   &sql.NullInt64{Int64: int64(deviceID), Valid: true},
+			RecordedAt: point.RecordedAt,
+			Latitude:   point.Latitude,
+			Longitude:  point.Longitude,
+		}
+		if point.Accuracy != nil {
+			trackPoints[idx].Accuracy = &sql.NullFloat64{Float64: *point.Accuracy, Valid: true}
+		}
+	}
+	return trackPoints
+}
+
+// Decode reads the JSON array of points
+func Decode(r io.Reader) ([]Point, error) {
+	var points []Point
+	if err := json.NewDecoder(r).Decode(&points); err != nil {
+		return nil, err
+	}
+	switch {
+	case len(points) == 0:
+		return nil, ErrEmptyBatch
+	case len(points) > MaxBatchSize:
+		return nil, ErrBatchTooLarge
+	}
+	return points, nil
+}
+
+/*
+Normalize corrects the point timestamps by the device clock offset and validates the points
+the same way the activity samples are validated.
+
+Points with coordinates out of range, from the future or too old are rejected.
+*/
+func Normalize(points []Point, deviceNow *time.Time, now time.Time) ([]Point, []telemetry.Rejection, error) {
+	var offset time.Duration
+	if deviceNow != nil {
+		offset = now.Sub(*deviceNow)
+		if offset > telemetry.MaxClockCorrection || offset < -telemetry.MaxClockCorrection {
+			return nil, nil, telemetry.ErrDeviceClockSkew
+		}
+	}
+
+	accepted := make([]Point, 0, len(points))
+	rejected := make([]telemetry.Rejection, 0)
+	for idx, point := range points {
+		reject := func(reason string) {
+			rejected = append(rejected, telemetry.Rejection{Index: idx, Error: reason})
+		}
+		switch {
+		case point.RecordedAt.IsZero():
+			reject("recorded_at is required")
+			continue
+		case point.Latitude < -90 || point.Latitude > 90 || math.IsNaN(point.Latitude):
+			reject("latitude must be between -90 and 90")
+			continue
+		case point.Longitude < -180 || point.Longitude > 180 || math.IsNaN(point.Longitude):
+			reject("longitude must be between -180 and 180")
+			continue
+		case point.Accuracy != nil && *point.Accuracy < 0:
+			reject("accuracy can not be negative")
+			continue
+		}
+
+		point.RecordedAt = point.RecordedAt.Add(offset).UTC()
+		switch {
+		case point.RecordedAt.After(now.Add(telemetry.MaxFutureSkew)):
+			reject("recorded_at is in the future")
+			continue
+		case point.RecordedAt.Before(now.Add(-telemetry.MaxSampleAge)):
+			reject("recorded_at is too old")
+			continue
+		}
+		accepted = append(accepted, point)
+	}
+	return accepted, rejected, nil
+}
+
+// earthRadius is the mean radius of the Earth in meters
+const earthRadius = 6371008.8
+
+// Distance is the great-circle distance between the points in meters
+func Distance(a Point, b Point) float64 {
+	lat1 := a.Latitude * math.Pi / 180
+	lat2 := b.Latitude * math.Pi / 180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
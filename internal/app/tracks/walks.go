@@ -0,0 +1,144 @@
+package tracks
+
+import "time"
+
+const (
+	// MaxGap is the pause between the fixes which ends the walk
+	MaxGap = 10 * time.Minute
+	// MaxSpeed is the speed in m/s no pet keeps up, the fix reached faster is the GPS jump and is dropped
+	MaxSpeed = 15.0
+	// MaxAccuracy is the radius in meters above which the fix is too rough to draw
+	MaxAccuracy = 100.0
+	// AnchorFixes is how many fixes in a row reached too fast from the first fix of the segment
+	// make the first fix the jump instead of them
+	AnchorFixes = 2
+
+	// MinWalkPoints, MinWalkDistance and MinWalkDuration tell the walk from the pet moving around the house
+	MinWalkPoints   = 3
+	MinWalkDistance = 50.0
+	MinWalkDuration = time.Minute
+)
+
+/*
+Segment is the run of fixes without pauses longer than MaxGap.
+
+Points are the indexes of the fixes drawn on the map, Dropped are the indexes of the jumps and rough fixes.
+The segment is closed when its last fix is older than MaxGap, the open one may still grow.
+*/
+type Segment struct {
+	Points    []int
+	Dropped   []int
+	StartedAt time.Time
+	EndedAt   time.Time
+	Distance  float64
+	Closed    bool
+}
+
+func (s *Segment) Duration() time.Duration {
+	return s.EndedAt.Sub(s.StartedAt)
+}
+
+// MeanSpeed is the speed of the walk in m/s
+func (s *Segment) MeanSpeed() float64 {
+	if s.Duration() <= 0 {
+		return 0
+	}
+	return s.Distance / s.Duration().Seconds()
+}
+
+// IsWalk reports whether the segment is long enough to be kept as the walk
+func (s *Segment) IsWalk() bool {
+	return len(s.Points) >= MinWalkPoints && s.Distance >= MinWalkDistance && s.Duration() >= MinWalkDuration
+}
+
+// Split divides the fixes ordered by time into segments, now tells the segments which are closed
+func Split(points []Point, now time.Time) []Segment {
+	segments := make([]Segment, 0)
+	var last Point
+	// suspects are the fixes in a row dropped as too fast from the only fix of the segment
+	var suspects []int
+	for idx, point := range points {
+		if idx == 0 || point.RecordedAt.Sub(points[idx-1].RecordedAt) > MaxGap {
+			segments = append(segments, Segment{})
+			suspects = nil
+		}
+		current := &segments[len(segments)-1]
+
+		if point.Accuracy != nil && *point.Accuracy > MaxAccuracy {
+			current.Dropped = append(current.Dropped, idx)
+			continue
+		}
+		if len(current.Points) == 0 {
+			current.Points = append(current.Points, idx)
+			current.StartedAt, current.EndedAt = point.RecordedAt, point.RecordedAt
+			last = point
+			continue
+		}
+		if !reachable(last, point) {
+			current.Dropped = append(current.Dropped, idx)
+			if len(current.Points) == 1 {
+				if len(suspects) > 0 && !reachable(points[suspects[len(suspects)-1]], point) {
+					suspects = suspects[:0]
+				}
+				suspects = append(suspects, idx)
+				if len(suspects) >= AnchorFixes {
+					current.reanchor(points, suspects)
+					last = points[idx]
+					suspects = nil
+				}
+			}
+			continue
+		}
+		suspects = nil
+		current.Points = append(current.Points, idx)
+		current.Distance += Distance(last, point)
+		current.EndedAt = point.RecordedAt
+		last = point
+	}
+
+	for idx := range segments {
+		lastIdx := segments[idx].lastIndex()
+		segments[idx].Closed = now.Sub(points[lastIdx].RecordedAt) > MaxGap
+	}
+	return segments
+}
+
+// reachable reports whether the pet could get from one fix to the other without exceeding MaxSpeed
+func reachable(from Point, to Point) bool {
+	elapsed := to.RecordedAt.Sub(from.RecordedAt).Seconds()
+	return elapsed > 0 && Distance(from, to)/elapsed <= MaxSpeed
+}
+
+// reanchor drops the only fix of the segment and starts it over from the suspects, which all jumped away
+// from that fix but are in the reach of each other, so it is the fix the GPS was wrong about
+func (s *Segment) reanchor(points []Point, suspects []int) {
+	isSuspect := make(map[int]bool, len(suspects))
+	for _, idx := range suspects {
+		isSuspect[idx] = true
+	}
+	dropped := []int{s.Points[0]}
+	for _, idx := range s.Dropped {
+		if !isSuspect[idx] {
+			dropped = append(dropped, idx)
+		}
+	}
+	s.Dropped = dropped
+	s.Points = append([]int{}, suspects...)
+	s.StartedAt = points[suspects[0]].RecordedAt
+	s.EndedAt = points[suspects[len(suspects)-1]].RecordedAt
+	s.Distance = 0
+	for idx := 1; idx < len(suspects); idx++ {
+		s.Distance += Distance(points[suspects[idx-1]], points[suspects[idx]])
+	}
+}
+
+func (s *Segment) lastIndex() int {
+	lastIdx := -1
+	if len(s.Points) > 0 {
+		lastIdx = s.Points[len(s.Points)-1]
+	}
+	if len(s.Dropped) > 0 && s.Dropped[len(s.Dropped)-1] > lastIdx {
+		lastIdx = s.Dropped[len(s.Dropped)-1]
+	}
+	return lastIdx
+}
@@ -0,0 +1,113 @@
+package tracks
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+// walkPoints heads north from the start by about 1.1 m/s, one fix every 10 seconds
+func walkPoints(start time.Time, latitude float64, count int) []Point {
+	points := make([]Point, count)
+	for idx := range points {
+		points[idx] = Point{
+			RecordedAt: start.Add(time.Duration(idx) * 10 * time.Second),
+			Latitude:   latitude + float64(idx)*0.0001,
+			Longitude:  30.5,
+		}
+	}
+	return points
+}
+
+func TestDistance(t *testing.T) {
+	kyiv := Point{Latitude: 50.4501, Longitude: 30.5234}
+	lviv := Point{Latitude: 49.8397, Longitude: 24.0297}
+	assert.InDelta(t, 468000, Distance(kyiv, lviv), 2000)
+	assert.Equal(t, 0.0, Distance(kyiv, kyiv))
+}
+
+func TestSplit(t *testing.T) {
+	start := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	morning := walkPoints(start, 50.45, 31)
+	// The GPS jump in the middle of the walk is dropped
+	morning[10].Latitude += 0.05
+	// The short run after the long pause is not a walk
+	idle := walkPoints(start.Add(time.Hour), 50.46, 2)
+	evening := walkPoints(start.Add(10*time.Hour), 50.47, 10)
+
+	points := append(append(append([]Point{}, morning...), idle...), evening...)
+	segments := Split(points, start.Add(10*time.Hour+2*time.Minute))
+	require.Len(t, segments, 3)
+
+	walk := segments[0]
+	assert.True(t, walk.Closed)
+	assert.True(t, walk.IsWalk())
+	assert.Equal(t, []int{10}, walk.Dropped)
+	assert.Len(t, walk.Points, 30)
+	assert.Equal(t, 5*time.Minute, walk.Duration())
+	assert.InDelta(t, 333.6, walk.Distance, 1)
+	assert.InDelta(t, 1.11, walk.MeanSpeed(), 0.01)
+
+	assert.True(t, segments[1].Closed)
+	assert.False(t, segments[1].IsWalk())
+
+	// The walk going on right now is left open
+	assert.False(t, segments[2].Closed)
+	assert.Equal(t, []int{33, 34, 35, 36, 37, 38, 39, 40, 41, 42}, segments[2].Points)
+}
+
+func TestSplit_FirstFixJump(t *testing.T) {
+	start := time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	points := walkPoints(start, 50.45, 10)
+	// The collar reports the stale fix from far away right after it is turned on
+	points[0].Latitude += 0.05
+	// The rough fix among the suspects stays dropped
+	rough := MaxAccuracy * 2
+	points[2].Accuracy = &rough
+
+	segments := Split(points, start.Add(time.Hour))
+	require.Len(t, segments, 1)
+	walk := segments[0]
+	assert.Equal(t, []int{0, 2}, walk.Dropped)
+	assert.Equal(t, []int{1, 3, 4, 5, 6, 7, 8, 9}, walk.Points)
+	assert.Equal(t, start.Add(10*time.Second), walk.StartedAt)
+	assert.Equal(t, 80*time.Second, walk.Duration())
+	assert.InDelta(t, 88.96, walk.Distance, 1)
+	assert.True(t, walk.IsWalk())
+
+	// The single jump after the first fix is still dropped
+	points = walkPoints(start, 50.45, 10)
+	points[1].Latitude += 0.05
+	walk = Split(points, start.Add(time.Hour))[0]
+	assert.Equal(t, []int{1}, walk.Dropped)
+	assert.Len(t, walk.Points, 9)
+}
+
+func TestNormalize(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	rough := -5.0
+	points, err := Decode(strings.NewReader(`[
+		{"recorded_at":"2021-06-01T11:59:00Z","latitude":50.45,"longitude":30.52,"accuracy":8},
+		{"recorded_at":"2021-06-01T11:59:10Z","latitude":91,"longitude":30.52},
+		{"recorded_at":"2021-06-01T11:59:20Z","latitude":50.45,"longitude":-181},
+		{"recorded_at":"2021-06-01T13:00:00Z","latitude":50.45,"longitude":30.52},
+		{"latitude":50.45,"longitude":30.52}
+	]`))
+	require.NoError(t, err)
+	points = append(points, Point{RecordedAt: now, Accuracy: &rough})
+
+	accepted, rejected, err := Normalize(points, nil, now)
+	require.NoError(t, err)
+	require.Len(t, accepted, 1)
+	assert.Equal(t, 8.0, *accepted[0].Accuracy)
+	indexes := make([]int, 0, len(rejected))
+	for _, rejection := range rejected {
+		indexes = append(indexes, rejection.Index)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, indexes)
+
+	_, err = Decode(strings.NewReader(`[]`))
+	assert.Equal(t, ErrEmptyBatch, err)
+}
@@ -0,0 +1,170 @@
+// Package webhooks delivers pet events to the partner integrations subscribed by users
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/jobs"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"log"
+	"time"
+)
+
+// KindDeliver is the kind of the job sending the delivery, failed attempts are retried by the jobs backoff
+const KindDeliver = "webhooks.deliver"
+
+type enqueuer interface {
+	Enqueue(kind string, payload interface{}, runAt time.Time) (*models.Job, error)
+}
+
+type deliveryPayload struct {
+	DeliveryID int `json:"delivery_id"`
+}
+
+// Payload is the body of the webhook request
+type Payload struct {
+	Event      string          `json:"event"`
+	PetID      int             `json:"pet_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func newPayload(event string, petID int, data interface{}, occurredAt time.Time) ([]byte, error) {
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(Payload{
+		Event:      event,
+		PetID:      petID,
+		OccurredAt: occurredAt.UTC(),
+		Data:       rawData,
+	})
+}
+
+type Dispatcher struct {
+	database store.DatabaseStore
+	queue    enqueuer
+	logger   *log.Logger
+	Sender   *Sender
+}
+
+func NewDispatcher(database store.DatabaseStore, queue enqueuer, logger *log.Logger) *Dispatcher {
+	return &Dispatcher{
+		database: database,
+		queue:    queue,
+		logger:   logger,
+		Sender:   &Sender{},
+	}
+}
+
+// Dispatch logs the deliveries of the pet event to the subscriptions of the pet owner
+// and its veterinarian and schedules sending them
+func (d *Dispatcher) Dispatch(event string, petID int, data interface{}) error {
+	pet, err := d.database.Pets().FindByID(petID)
+	if err != nil {
+		return err
+	}
+	userIDs := []int{pet.UserID}
+	if pet.SpecifiedVeterinarianID != 0 && pet.SpecifiedVeterinarianID != pet.UserID {
+		userIDs = append(userIDs, pet.SpecifiedVeterinarianID)
+	}
+	subscriptions, err := d.database.Webhooks().SelectSubscribed(userIDs, event)
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := newPayload(event, petID, data, time.Now())
+	if err != nil {
+		return err
+	}
+	for idx := range subscriptions {
+		delivery, err := d.database.Webhooks().CreateDelivery(&models.WebhookDelivery{
+			SubscriptionID: subscriptions[idx].SubscriptionID,
+			Event:          event,
+			Payload:        payload,
+		})
+		if err != nil {
+			return err
+		}
+		if err := d.enqueue(delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Redeliver sends the payload of the logged delivery again as the new delivery,
+// the redeliveries of the redelivery reference the first delivery
+func (d *Dispatcher) Redeliver(original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery, err := d.database.Webhooks().CreateDelivery(&models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		Event:          original.Event,
+		Payload:        original.Payload,
+		RedeliveryOf:   &sql.NullInt64{Int64: int64(original.OriginalID()), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return delivery, d.enqueue(delivery)
+}
+
+func (d *Dispatcher) enqueue(delivery *models.WebhookDelivery) error {
+	_, err := d.queue.Enqueue(KindDeliver, deliveryPayload{DeliveryID: delivery.DeliveryID}, jobs.Now())
+	return err
+}
+
+// Deliver is the handler of the delivery jobs
+func (d *Dispatcher) Deliver(ctx context.Context, job *models.Job) error {
+	payload := &deliveryPayload{}
+	if err := json.Unmarshal(job.Payload, payload); err != nil {
+		return err
+	}
+	// The subscription may be deleted by the user before delivery, there is nothing to deliver then
+	delivery, err := d.database.Webhooks().FindDeliveryByID(payload.DeliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	subscription, err := d.database.Webhooks().FindByID(delivery.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if !subscription.IsActive {
+		return d.database.Webhooks().RecordAttempt(delivery.DeliveryID, models.WebhookDeliveryFailed, 0, "subscription is inactive")
+	}
+
+	responseStatus, sendErr := d.Sender.Send(ctx, subscription, delivery)
+	switch {
+	case sendErr == nil:
+		return d.database.Webhooks().RecordAttempt(delivery.DeliveryID, models.WebhookDeliverySucceeded, responseStatus, "")
+
+	case errors.Is(sendErr, ErrSubscriptionGone):
+		// The receiver has been shut down, the user may activate the subscription again
+		subscription.IsActive = false
+		if _, err := d.database.Webhooks().Update(subscription); err != nil {
+			return err
+		}
+		return d.database.Webhooks().RecordAttempt(delivery.DeliveryID, models.WebhookDeliveryFailed, responseStatus, sendErr.Error())
+	}
+
+	status := models.WebhookDeliveryPending
+	if !job.HasAttemptsLeft() {
+		status = models.WebhookDeliveryFailed
+	}
+	if err := d.database.Webhooks().RecordAttempt(delivery.DeliveryID, status, responseStatus, sendErr.Error()); err != nil {
+		d.logger.Printf("Webhooks error: %v", err)
+	}
+	return sendErr
+}
@@ -0,0 +1,142 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/jobs"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/store/repos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type recordedAttempt struct {
+	Status         string
+	ResponseStatus int
+	LastError      string
+}
+
+// webhookStore keeps the single subscription with its delivery, the rest of the store is not used by Deliver
+type webhookStore struct {
+	store.DatabaseStore
+	repos.WebhookRepository
+
+	subscription *models.WebhookSubscription
+	delivery     *models.WebhookDelivery
+	attempts     []recordedAttempt
+}
+
+func (s *webhookStore) Webhooks() repos.WebhookRepository {
+	return s
+}
+
+func (s *webhookStore) FindDeliveryByID(deliveryID int) (*models.WebhookDelivery, error) {
+	if deliveryID != s.delivery.DeliveryID {
+		return nil, sql.ErrNoRows
+	}
+	return s.delivery, nil
+}
+
+func (s *webhookStore) FindByID(subscriptionID int) (*models.WebhookSubscription, error) {
+	if subscriptionID != s.subscription.SubscriptionID {
+		return nil, sql.ErrNoRows
+	}
+	return s.subscription, nil
+}
+
+func (s *webhookStore) Update(subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	s.subscription = subscription
+	return subscription, nil
+}
+
+func (s *webhookStore) RecordAttempt(_ int, status string, responseStatus int, lastError string) error {
+	s.attempts = append(s.attempts, recordedAttempt{Status: status, ResponseStatus: responseStatus, LastError: lastError})
+	return nil
+}
+
+func newTestDispatcher(t *testing.T, rec *receiver) (*Dispatcher, *webhookStore) {
+	delivery := testDelivery(t)
+	delivery.SubscriptionID = 7
+	database := &webhookStore{
+		subscription: &models.WebhookSubscription{SubscriptionID: 7, URL: rec.URL, Secret: testSecret, IsActive: true},
+		delivery:     delivery,
+	}
+	dispatcher := NewDispatcher(database, nil, log.New(ioutil.Discard, "", 0))
+	dispatcher.Sender.Client = rec.Client()
+	return dispatcher, database
+}
+
+// runDelivery runs the delivery job the way the scheduler does: the failed attempt is retried after the backoff
+// while the job has attempts left. It returns the delays the attempts have been retried after.
+func runDelivery(t *testing.T, dispatcher *Dispatcher, maxAttempts int) []time.Duration {
+	payload, err := json.Marshal(deliveryPayload{DeliveryID: 42})
+	require.NoError(t, err)
+	job := &models.Job{Kind: KindDeliver, Payload: payload, MaxAttempts: maxAttempts}
+
+	var delays []time.Duration
+	for job.Attempts = 1; ; job.Attempts++ {
+		if err := dispatcher.Deliver(context.Background(), job); err == nil || !job.HasAttemptsLeft() {
+			return delays
+		}
+		delays = append(delays, jobs.Backoff(job.Attempts))
+	}
+}
+
+func TestDispatcher_DeliverRetried(t *testing.T) {
+	rec := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	dispatcher, database := newTestDispatcher(t, rec)
+
+	delays := runDelivery(t, dispatcher, 5)
+	assert.Equal(t, []time.Duration{jobs.Backoff(1), jobs.Backoff(2)}, delays)
+	assert.Len(t, rec.Requests(), 3)
+	require.Len(t, database.attempts, 3)
+	assert.Equal(t, recordedAttempt{
+		Status:         models.WebhookDeliveryPending,
+		ResponseStatus: http.StatusInternalServerError,
+		LastError:      "webhooks: receiver responded 500 Internal Server Error",
+	}, database.attempts[0])
+	assert.Equal(t, models.WebhookDeliveryPending, database.attempts[1].Status)
+	assert.Equal(t, http.StatusBadGateway, database.attempts[1].ResponseStatus)
+	assert.Equal(t, recordedAttempt{Status: models.WebhookDeliverySucceeded, ResponseStatus: http.StatusOK}, database.attempts[2])
+}
+
+func TestDispatcher_DeliverFailed(t *testing.T) {
+	rec := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	dispatcher, database := newTestDispatcher(t, rec)
+
+	delays := runDelivery(t, dispatcher, 3)
+	assert.Equal(t, []time.Duration{jobs.Backoff(1), jobs.Backoff(2)}, delays)
+	require.Len(t, database.attempts, 3)
+	assert.Equal(t, models.WebhookDeliveryPending, database.attempts[0].Status)
+	assert.Equal(t, models.WebhookDeliveryPending, database.attempts[1].Status)
+	// The last attempt fails the delivery
+	assert.Equal(t, models.WebhookDeliveryFailed, database.attempts[2].Status)
+	assert.Equal(t, http.StatusServiceUnavailable, database.attempts[2].ResponseStatus)
+	assert.True(t, database.subscription.IsActive)
+}
+
+func TestDispatcher_DeliverGone(t *testing.T) {
+	rec := newReceiver(t, http.StatusGone)
+	dispatcher, database := newTestDispatcher(t, rec)
+
+	// The receiver asked to stop, the delivery is not retried and the subscription is deactivated
+	delays := runDelivery(t, dispatcher, 5)
+	assert.Empty(t, delays)
+	require.Len(t, database.attempts, 1)
+	assert.Equal(t, models.WebhookDeliveryFailed, database.attempts[0].Status)
+	assert.Equal(t, http.StatusGone, database.attempts[0].ResponseStatus)
+	assert.False(t, database.subscription.IsActive)
+
+	// Deliveries of the inactive subscription fail without sending
+	runDelivery(t, dispatcher, 5)
+	assert.Len(t, rec.Requests(), 1)
+	require.Len(t, database.attempts, 2)
+	assert.Equal(t, recordedAttempt{Status: models.WebhookDeliveryFailed, LastError: "subscription is inactive"}, database.attempts[1])
+}
@@ -0,0 +1,61 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webhook"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeliveryHeader identifies the payload delivered, it is the ID of the first delivery for the redeliveries,
+// so receivers can skip the redelivered ones they have processed
+const DeliveryHeader = "X-StoryPet-Delivery"
+
+const sendTimeout = 10 * time.Second
+
+// ErrSubscriptionGone is returned when the receiver asks to stop sending the events
+var ErrSubscriptionGone = errors.New("webhooks: receiver responded 410 Gone")
+
+// Sender posts the deliveries signed with the subscription secret.
+// The default client sends them to the public https addresses only.
+type Sender struct {
+	Client *http.Client
+}
+
+// Send delivers the payload and returns the response status, it is 0 if the receiver did not respond.
+// Responses out of 2xx are errors.
+func (s *Sender) Send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(DeliveryHeader, strconv.Itoa(delivery.OriginalID()))
+	webhook.SetHeaders(request.Header, subscription.Secret, delivery.Event, delivery.Payload, time.Now())
+
+	client := s.Client
+	if client == nil {
+		client = netguard.NewClient(sendTimeout)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	switch {
+	case response.StatusCode == http.StatusGone:
+		return response.StatusCode, ErrSubscriptionGone
+	case response.StatusCode < 200 || response.StatusCode >= 300:
+		return response.StatusCode, fmt.Errorf("webhooks: receiver responded %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
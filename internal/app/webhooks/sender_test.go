@@ -0,0 +1,150 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/ArtemVovchenko/storypet-backend/internal/app/models"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/netguard"
+	"github.com/ArtemVovchenko/storypet-backend/internal/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSecret = "partner-shared-secret"
+
+type receivedRequest struct {
+	Event          string
+	DeliveryID     string
+	Body           []byte
+	ValidSignature bool
+}
+
+// receiver is the partner endpoint answering with the queued status codes, 200 once they run out
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	rec := &receiver{statuses: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, receivedRequest{
+			Event:          r.Header.Get(webhook.EventHeader),
+			DeliveryID:     r.Header.Get(DeliveryHeader),
+			Body:           body,
+			ValidSignature: webhook.Verify(r.Header, testSecret, body, time.Minute, time.Now()),
+		})
+		status := http.StatusOK
+		if len(rec.statuses) > 0 {
+			status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+		}
+		rec.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (r *receiver) Requests() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func testDelivery(t *testing.T) *models.WebhookDelivery {
+	payload, err := newPayload(
+		models.WebhookActivityRecorded,
+		5,
+		map[string]float64{"distance": 1200},
+		time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+	)
+	require.NoError(t, err)
+	return &models.WebhookDelivery{DeliveryID: 42, Event: models.WebhookActivityRecorded, Payload: payload}
+}
+
+func TestNewPayload(t *testing.T) {
+	delivery := testDelivery(t)
+	assert.JSONEq(t,
+		`{"event":"activity.recorded","pet_id":5,"occurred_at":"2021-06-01T12:00:00Z","data":{"distance":1200}}`,
+		string(delivery.Payload),
+	)
+}
+
+func TestSender_Send(t *testing.T) {
+	rec := newReceiver(t)
+	subscription := &models.WebhookSubscription{URL: rec.URL, Secret: testSecret, IsActive: true}
+	delivery := testDelivery(t)
+
+	status, err := (&Sender{Client: rec.Client()}).Send(context.Background(), subscription, delivery)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	requests := rec.Requests()
+	require.Len(t, requests, 1)
+	assert.True(t, requests[0].ValidSignature)
+	assert.Equal(t, models.WebhookActivityRecorded, requests[0].Event)
+	assert.Equal(t, "42", requests[0].DeliveryID)
+	assert.JSONEq(t, string(delivery.Payload), string(requests[0].Body))
+	assert.True(t, json.Valid(requests[0].Body))
+
+	// The redelivery is identified as the delivery it repeats
+	redelivery := testDelivery(t)
+	redelivery.DeliveryID = 43
+	redelivery.RedeliveryOf = &sql.NullInt64{Int64: 42, Valid: true}
+	_, err = (&Sender{Client: rec.Client()}).Send(context.Background(), subscription, redelivery)
+	require.NoError(t, err)
+	requests = rec.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "42", requests[1].DeliveryID)
+}
+
+func TestSender_SendFailures(t *testing.T) {
+	rec := newReceiver(t, http.StatusInternalServerError, http.StatusGone)
+	subscription := &models.WebhookSubscription{URL: rec.URL, Secret: testSecret, IsActive: true}
+	sender := &Sender{Client: rec.Client()}
+
+	status, err := sender.Send(context.Background(), subscription, testDelivery(t))
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrSubscriptionGone))
+	assert.Equal(t, http.StatusInternalServerError, status)
+
+	status, err = sender.Send(context.Background(), subscription, testDelivery(t))
+	assert.True(t, errors.Is(err, ErrSubscriptionGone))
+	assert.Equal(t, http.StatusGone, status)
+
+	rec.Close()
+	status, err = sender.Send(context.Background(), subscription, testDelivery(t))
+	assert.Error(t, err)
+	assert.Equal(t, 0, status)
+}
+
+func TestSender_SendToInternalNetwork(t *testing.T) {
+	rec := newReceiver(t)
+	subscription := &models.WebhookSubscription{URL: rec.URL, Secret: testSecret, IsActive: true}
+
+	// The default client refuses the receivers which are not public https ones
+	status, err := (&Sender{}).Send(context.Background(), subscription, testDelivery(t))
+	assert.True(t, errors.Is(err, netguard.ErrInsecureURL))
+	assert.Equal(t, 0, status)
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+	subscription.URL = tlsServer.URL
+	status, err = (&Sender{}).Send(context.Background(), subscription, testDelivery(t))
+	assert.True(t, errors.Is(err, netguard.ErrForbiddenAddress))
+	assert.Equal(t, 0, status)
+	assert.Empty(t, rec.Requests())
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SignAttachment signs the download URL of the attachment variant which is valid until the expiry unix time
+func SignAttachment(attachmentID int, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ACCESS_SECRET")))
+	mac.Write([]byte(fmt.Sprintf("attachment:%d:%s:%d", attachmentID, variant, expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifyAttachment(attachmentID int, variant string, expires int64, signature string, now time.Time) bool {
+	if now.Unix() > expires {
+		return false
+	}
+	expected := SignAttachment(attachmentID, variant, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
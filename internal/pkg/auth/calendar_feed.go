@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoCalendarSecret is returned when CALENDAR_SECRET is not set, the feed URLs are not signed without it
+var ErrNoCalendarSecret = errors.New("auth: CALENDAR_SECRET is not set")
+
+// NewCalendarFeedKey generates random per-user key. Replacing or deleting
+// the stored key revokes all feed URLs signed with the previous one.
+func NewCalendarFeedKey() (string, error) {
+	return NewSecret(16)
+}
+
+// calendarSecret is the key of the feed URL signatures. It is not ACCESS_SECRET, as the URLs are pasted
+// into the calendar applications and must not be revoked by the rotation of the token key.
+func calendarSecret() ([]byte, error) {
+	secret := os.Getenv("CALENDAR_SECRET")
+	if secret == "" {
+		return nil, ErrNoCalendarSecret
+	}
+	return []byte(secret), nil
+}
+
+// SignCalendarFeed signs the feed URL of the user with the current feed key
+func SignCalendarFeed(userID int, feedKey string) (string, error) {
+	secret, err := calendarSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(fmt.Sprintf("calendar:%d:%s", userID, feedKey)))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func VerifyCalendarFeed(userID int, feedKey string, signature string) bool {
+	expected, err := SignCalendarFeed(userID, feedKey)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
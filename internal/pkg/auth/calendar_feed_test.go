@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCalendarFeedSignature(t *testing.T) {
+	setEnv(t, "ACCESS_SECRET", "shared-secret")
+	setEnv(t, "CERTIFICATE_SECRET", "shared-secret")
+	setEnv(t, "CALENDAR_SECRET", "")
+	_, err := SignCalendarFeed(3, "feed-key")
+	assert.Equal(t, ErrNoCalendarSecret, err)
+
+	setEnv(t, "CALENDAR_SECRET", "shared-secret")
+	signature, err := SignCalendarFeed(3, "feed-key")
+	require.NoError(t, err)
+	assert.True(t, VerifyCalendarFeed(3, "feed-key", signature))
+	assert.False(t, VerifyCalendarFeed(4, "feed-key", signature))
+	assert.False(t, VerifyCalendarFeed(3, "rotated-key", signature))
+
+	// The signature is bound to the calendar domain even when the keys are shared
+	certificate, err := SignCertificate(3, 0, 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, certificate, signature)
+
+	setEnv(t, "CALENDAR_SECRET", "")
+	assert.False(t, VerifyCalendarFeed(3, "feed-key", signature))
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoCertificateSecret is returned when CERTIFICATE_SECRET is not set, the certificates are not signed without it
+var ErrNoCertificateSecret = errors.New("auth: CERTIFICATE_SECRET is not set")
+
+// certificateSecret is the key of the certificate signatures. It is not ACCESS_SECRET, as the signatures
+// are printed in the QR codes and stay valid for years, so the key must not be rotated with the tokens.
+func certificateSecret() ([]byte, error) {
+	secret := os.Getenv("CERTIFICATE_SECRET")
+	if secret == "" {
+		return nil, ErrNoCertificateSecret
+	}
+	return []byte(secret), nil
+}
+
+// SignCertificate signs the vaccination certificate of the pet issued by the veterinarian at the unix time
+func SignCertificate(petID int, veterinarianID int, issued int64) (string, error) {
+	secret, err := certificateSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(fmt.Sprintf("certificate:%d:%d:%d", petID, veterinarianID, issued)))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func VerifyCertificate(petID int, veterinarianID int, issued int64, signature string) bool {
+	expected, err := SignCertificate(petID, veterinarianID, issued)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
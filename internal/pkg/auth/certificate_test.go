@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func setEnv(t *testing.T, key string, value string) {
+	previous, ok := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func TestCertificateSignature(t *testing.T) {
+	setEnv(t, "ACCESS_SECRET", "access-secret")
+	setEnv(t, "CERTIFICATE_SECRET", "")
+	_, err := SignCertificate(3, 7, 1622548800)
+	assert.Equal(t, ErrNoCertificateSecret, err)
+
+	setEnv(t, "CERTIFICATE_SECRET", "certificate-secret")
+	signature, err := SignCertificate(3, 7, 1622548800)
+	require.NoError(t, err)
+	assert.True(t, VerifyCertificate(3, 7, 1622548800, signature))
+	assert.False(t, VerifyCertificate(3, 8, 1622548800, signature))
+	assert.False(t, VerifyCertificate(3, 7, 1622548801, signature))
+
+	// The signatures do not depend on the access token key and are not made without the certificate key
+	setEnv(t, "ACCESS_SECRET", "rotated-access-secret")
+	assert.True(t, VerifyCertificate(3, 7, 1622548800, signature))
+	setEnv(t, "CERTIFICATE_SECRET", "")
+	assert.False(t, VerifyCertificate(3, 7, 1622548800, signature))
+}
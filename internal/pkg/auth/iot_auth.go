@@ -12,19 +12,24 @@ import (
 )
 
 type IoTAccessTokenMeta struct {
-	PetID   int
-	Expires int64
-	Token   string
+	PetID    int
+	DeviceID int
+	Expires  int64
+	Token    string
 }
 
-func CreateIoTToken(petID int) (*IoTAccessTokenMeta, error) {
+// CreateIoTToken issues the token of the device, the device is checked on every request,
+// so unpairing or revoking it takes effect before the token expires
+func CreateIoTToken(petID int, deviceID int) (*IoTAccessTokenMeta, error) {
 	tInfo := &IoTAccessTokenMeta{
-		PetID:   petID,
-		Expires: time.Now().Add(time.Hour * 24).Unix(),
+		PetID:    petID,
+		DeviceID: deviceID,
+		Expires:  time.Now().Add(time.Hour * 24).Unix(),
 	}
 
 	accessClaims := jwt.MapClaims{}
 	accessClaims["pet_id"] = petID
+	accessClaims["device_id"] = deviceID
 	accessClaims["expires_at"] = tInfo.Expires
 	rawAccessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
 	accessToken, err := rawAccessToken.SignedString([]byte(os.Getenv("ACCESS_SECRET")))
@@ -74,13 +79,18 @@ func ExtractIoTAccessMeta(r *http.Request) (*IoTAccessTokenMeta, error) {
 		if err != nil {
 			return nil, err
 		}
+		deviceID, err := strconv.ParseInt(fmt.Sprintf("%.f", accessClaims["device_id"]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
 		expiresAt, err := strconv.ParseUint(fmt.Sprintf("%.f", accessClaims["expires_at"]), 10, 64)
 		if err != nil {
 			return nil, err
 		}
 		return &IoTAccessTokenMeta{
-			PetID:   int(petID),
-			Expires: int64(expiresAt),
+			PetID:    int(petID),
+			DeviceID: int(deviceID),
+			Expires:  int64(expiresAt),
 		}, nil
 	}
 	return nil, err
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// NewIoTDeviceSecret generates the device access secret and the hash it is stored by.
+// The secret has enough entropy for the plain hash to resist guessing, so devices are looked up by it.
+func NewIoTDeviceSecret() (secret string, hash string, err error) {
+	secret, err = NewSecret(32)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, HashIoTDeviceSecret(secret), nil
+}
+
+func HashIoTDeviceSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewIoTDeviceSecret(t *testing.T) {
+	secret, hash, err := NewIoTDeviceSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, hash)
+	assert.NotContains(t, hash, secret)
+	assert.Equal(t, hash, HashIoTDeviceSecret(secret))
+
+	other, otherHash, err := NewIoTDeviceSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+	assert.NotEqual(t, hash, otherHash)
+}
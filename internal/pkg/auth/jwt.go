@@ -77,12 +77,7 @@ func extractToken(r *http.Request) (string, error) {
 	return "", errors.New("request's authorization field is unprocessable")
 }
 
-func verifyAccessToken(r *http.Request) (*jwt.Token, error) {
-	tokenStr, err := extractToken(r)
-	if err != nil {
-		return nil, err
-	}
-
+func verifyAccessToken(tokenStr string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("token singing method is unverified")
@@ -109,7 +104,29 @@ func verifyRefreshToken(tokenStr string) (*jwt.Token, error) {
 }
 
 func ExtractAccessMeta(r *http.Request) (*AccessTokenMeta, error) {
-	token, err := verifyAccessToken(r)
+	tokenStr, err := extractToken(r)
+	if err != nil {
+		return nil, err
+	}
+	return extractAccessMeta(tokenStr)
+}
+
+// ExtractStreamAccessMeta also accepts the stream ticket in ticket URL query parameter,
+// because browsers can not set headers of EventSource requests
+func ExtractStreamAccessMeta(r *http.Request) (*AccessTokenMeta, error) {
+	tokenStr, err := extractToken(r)
+	if err != nil {
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			return nil, err
+		}
+		return ExtractStreamTicketMeta(ticket, time.Now())
+	}
+	return extractAccessMeta(tokenStr)
+}
+
+func extractAccessMeta(tokenStr string) (*AccessTokenMeta, error) {
+	token, err := verifyAccessToken(tokenStr)
 	if err != nil {
 		return nil, err
 	}
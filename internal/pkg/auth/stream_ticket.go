@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamTicketLifetime is how long the ticket may be used to open the event stream after it has been issued
+const StreamTicketLifetime = time.Minute
+
+var errInvalidStreamTicket = errors.New("stream ticket is invalid or expired")
+
+/*
+CreateStreamTicket issues the ticket opening the event stream of the session. Browsers can not set headers of
+EventSource requests, so the stream is opened with the ticket in the URL query instead of the access token:
+it opens only the stream and expires shortly, so it is of no use when it leaks from the URL.
+*/
+func CreateStreamTicket(meta *AccessTokenMeta, now time.Time) (string, int64) {
+	expires := now.Add(StreamTicketLifetime).Unix()
+	claims := fmt.Sprintf("%d.%s.%d", meta.UserID, meta.AccessUUID, expires)
+	return claims + "." + signStreamTicket(claims), expires
+}
+
+// ExtractStreamTicketMeta verifies the ticket and returns the session it has been issued for
+func ExtractStreamTicketMeta(ticket string, now time.Time) (*AccessTokenMeta, error) {
+	separator := strings.LastIndex(ticket, ".")
+	if separator < 0 {
+		return nil, errInvalidStreamTicket
+	}
+	claims, signature := ticket[:separator], ticket[separator+1:]
+	if !hmac.Equal([]byte(signStreamTicket(claims)), []byte(signature)) {
+		return nil, errInvalidStreamTicket
+	}
+	parts := strings.Split(claims, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidStreamTicket
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, errInvalidStreamTicket
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return nil, errInvalidStreamTicket
+	}
+	return &AccessTokenMeta{
+		Authorized: true,
+		AccessUUID: parts[1],
+		UserID:     userID,
+		Expires:    expires,
+	}, nil
+}
+
+func signStreamTicket(claims string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("ACCESS_SECRET")))
+	mac.Write([]byte("stream:" + claims))
+	return hex.EncodeToString(mac.Sum(nil))
+}
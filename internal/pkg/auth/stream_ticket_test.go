@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestStreamTicket(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	ticket, expires := CreateStreamTicket(&AccessTokenMeta{UserID: 5, AccessUUID: "4a0c0c5e-6f1e-4f4c-9f0e-3d1c1c1c1c1c"}, now)
+	assert.Equal(t, now.Add(StreamTicketLifetime).Unix(), expires)
+
+	meta, err := ExtractStreamTicketMeta(ticket, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 5, meta.UserID)
+	assert.Equal(t, "4a0c0c5e-6f1e-4f4c-9f0e-3d1c1c1c1c1c", meta.AccessUUID)
+
+	_, err = ExtractStreamTicketMeta(ticket, now.Add(2*StreamTicketLifetime))
+	assert.Error(t, err)
+	_, err = ExtractStreamTicketMeta("6"+ticket[1:], now)
+	assert.Error(t, err)
+	_, err = ExtractStreamTicketMeta("not a ticket", now)
+	assert.Error(t, err)
+
+	// The ticket is not accepted as the access token
+	_, err = extractAccessMeta(ticket)
+	assert.Error(t, err)
+}
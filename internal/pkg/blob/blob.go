@@ -0,0 +1,39 @@
+// Package blob keeps the uploaded files by their keys on the local disk or in the S3 compatible object storage
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob: not found")
+	ErrInvalidKey = errors.New("blob: invalid key")
+	// ErrSizeMismatch is returned when the content is shorter or longer than the size it was put with
+	ErrSizeMismatch = errors.New("blob: content size does not match")
+)
+
+// BlobStore keeps the file contents by the slash separated keys, e.g. pets/12/5e1c...
+type BlobStore interface {
+	// Put stores the content of the size, replacing the one stored by the key
+	Put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error
+	// Open returns the stored content, ErrNotFound when there is nothing stored by the key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the stored content, deleting the missing key is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey reports whether the key is the relative path which can not escape the store root
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
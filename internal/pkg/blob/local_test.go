@@ -0,0 +1,40 @@
+package blob
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, store.Put(ctx, "pets/1/report.pdf", strings.NewReader("%PDF-1.4"), 8, "application/pdf"))
+	content, err := store.Open(ctx, "pets/1/report.pdf")
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(content)
+	require.NoError(t, err)
+	assert.NoError(t, content.Close())
+	assert.Equal(t, "%PDF-1.4", string(data))
+
+	assert.Equal(t, ErrSizeMismatch, store.Put(ctx, "pets/1/short.pdf", strings.NewReader("%PDF"), 8, ""))
+	_, err = store.Open(ctx, "pets/1/short.pdf")
+	assert.Equal(t, ErrNotFound, err)
+
+	require.NoError(t, store.Delete(ctx, "pets/1/report.pdf"))
+	_, err = store.Open(ctx, "pets/1/report.pdf")
+	assert.Equal(t, ErrNotFound, err)
+	assert.NoError(t, store.Delete(ctx, "pets/1/report.pdf"))
+}
+
+func TestValidKey(t *testing.T) {
+	assert.True(t, validKey("pets/1/5e1c.jpg"))
+	for _, key := range []string{"", "/etc/passwd", "pets/../../etc/passwd", "pets//1", "pets/./1", "pets\\1", "pets/1/"} {
+		assert.False(t, validKey(key), key)
+	}
+}
@@ -0,0 +1,196 @@
+// Package cron parses standard five field cron expressions and computes their activation times
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLookahead bounds the search of the next activation,
+// so that impossible expressions like "0 0 30 2 *" terminate
+const maxLookahead = 5 * 366 * 24 * time.Hour
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Schedule is the parsed expression: minute, hour, day of month, month and day of week
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// anyDay is true when either day field is "*", then day matches by the other field only,
+	// otherwise the day matches if any of fields matches
+	anyDay bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var (
+	minuteBounds     = bounds{0, 59}
+	hourBounds       = bounds{0, 23}
+	dayOfMonthBounds = bounds{1, 31}
+	monthBounds      = bounds{1, 12}
+	dayOfWeekBounds  = bounds{0, 7}
+)
+
+func Parse(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if descriptor, ok := descriptors[expression]; ok {
+		expression = descriptor
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d in %q", len(fields), expression)
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dayOfMonth, err = parseField(fields[2], dayOfMonthBounds); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dayOfWeek, err = parseField(fields[4], dayOfWeekBounds); err != nil {
+		return nil, err
+	}
+	// Both 0 and 7 stand for Sunday
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	s.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the expression can not be parsed
+func MustParse(expression string) *Schedule {
+	s, err := Parse(expression)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Next returns the first activation time strictly after t in the location of t.
+// Zero time is returned if the schedule never activates.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxLookahead)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	domMatch := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dowMatch := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDay {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// parseField converts the comma separated list of values, ranges and steps into the bit set
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		bits, err := parsePart(part, b)
+		if err != nil {
+			return 0, err
+		}
+		set |= bits
+	}
+	return set, nil
+}
+
+func parsePart(part string, b bounds) (uint64, error) {
+	step := 1
+	if idx := strings.Index(part, "/"); idx != -1 {
+		parsedStep, err := strconv.Atoi(part[idx+1:])
+		if err != nil || parsedStep <= 0 {
+			return 0, fmt.Errorf("cron: invalid step in %q", part)
+		}
+		step = parsedStep
+		part = part[:idx]
+	}
+
+	start, end := b.min, b.max
+	switch {
+	case part == "*":
+	case strings.Contains(part, "-"):
+		rangeBounds := strings.SplitN(part, "-", 2)
+		var err error
+		if start, err = parseValue(rangeBounds[0], b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(rangeBounds[1], b); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("cron: invalid range %q", part)
+		}
+	default:
+		value, err := parseValue(part, b)
+		if err != nil {
+			return 0, err
+		}
+		start = value
+		// "5/10" means starting from 5 with step 10 up to the maximum
+		if step == 1 {
+			end = value
+		}
+	}
+
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << uint(value)
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cron: invalid value %q", s)
+	}
+	if value < b.min || value > b.max {
+		return 0, fmt.Errorf("cron: value %d out of range [%d, %d]", value, b.min, b.max)
+	}
+	return value, nil
+}
@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	testCases := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+	}
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			_, err := Parse(tc)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// 2021-06-07 is Monday
+	from := time.Date(2021, 6, 7, 10, 17, 30, 0, time.UTC)
+	testCases := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2021, 6, 7, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 6, 7, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2021, 6, 7, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, 6, 8, 0, 0, 0, 0, time.UTC)},
+		{"30 3 * * *", time.Date(2021, 6, 8, 3, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2021, 6, 7, 13, 0, 0, 0, time.UTC)},
+		{"0 8 * * 6,7", time.Date(2021, 6, 12, 8, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Restricted day of month and day of week match either of them
+		{"0 0 13 * 5", time.Date(2021, 6, 11, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expression, func(t *testing.T) {
+			s, err := Parse(tc.expression)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, s.Next(from))
+		})
+	}
+}
+
+func TestSchedule_NextNever(t *testing.T) {
+	s := MustParse("0 0 30 2 *")
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
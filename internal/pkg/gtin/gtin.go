@@ -0,0 +1,52 @@
+// Package gtin validates the GTIN barcodes of the products: EAN-8, UPC-A, EAN-13 and GTIN-14
+package gtin
+
+import (
+	"errors"
+	"strings"
+)
+
+// Length is the length of the normalized code
+const Length = 14
+
+var (
+	ErrInvalidLength     = errors.New("gtin: code must have 8, 12, 13 or 14 digits")
+	ErrInvalidCharacter  = errors.New("gtin: code must contain digits only")
+	ErrInvalidCheckDigit = errors.New("gtin: check digit does not match")
+)
+
+/*
+Normalize validates the code and pads it with zeros to GTIN-14, so the UPC-A and EAN-13 forms of the same
+product are equal. Spaces and dashes printed between the digit groups are ignored.
+*/
+func Normalize(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(code)
+	switch len(code) {
+	case 8, 12, 13, 14:
+	default:
+		return "", ErrInvalidLength
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", ErrInvalidCharacter
+		}
+	}
+	code = strings.Repeat("0", Length-len(code)) + code
+	if CheckDigit(code[:Length-1]) != code[Length-1] {
+		return "", ErrInvalidCheckDigit
+	}
+	return code, nil
+}
+
+// CheckDigit is the check digit of the digits, weights 3 and 1 alternate from the rightmost digit
+func CheckDigit(digits string) byte {
+	sum := 0
+	for idx := 0; idx < len(digits); idx++ {
+		digit := int(digits[len(digits)-1-idx] - '0')
+		if idx%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,31 @@
+package gtin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		code       string
+		normalized string
+		err        error
+	}{
+		{"4006381333931", "04006381333931", nil},
+		{"4 006381 333931", "04006381333931", nil},
+		{"036000291452", "00036000291452", nil},
+		{"0036000291452", "00036000291452", nil},
+		{"96385074", "00000096385074", nil},
+		{"10012345678902", "10012345678902", nil},
+		{"4006381333932", "", ErrInvalidCheckDigit},
+		{"40063813339", "", ErrInvalidLength},
+		{"40063813339a1", "", ErrInvalidCharacter},
+	}
+	for _, test := range tests {
+		t.Run(test.code, func(t *testing.T) {
+			normalized, err := Normalize(test.code)
+			assert.Equal(t, test.err, err)
+			assert.Equal(t, test.normalized, normalized)
+		})
+	}
+}
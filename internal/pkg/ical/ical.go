@@ -0,0 +1,149 @@
+// Package ical implements encoding of iCalendar (RFC 5545) calendars with VEVENT components
+package ical
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ContentType = "text/calendar; charset=utf-8"
+
+	dateTimeLayout = "20060102T150405Z"
+	dateLayout     = "20060102"
+	maxLineOctets  = 75
+)
+
+const (
+	StatusTentative = "TENTATIVE"
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is the VEVENT component. All-day events use only dates of Start and End,
+// End is exclusive, so one day event ends on the next day.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Summary     string
+	Description string
+	Location    string
+	Status      string
+}
+
+func (c *Calendar) Encode() []byte {
+	b := &bytes.Buffer{}
+	_, _ = c.WriteTo(b)
+	return b.Bytes()
+}
+
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{w: w}
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", c.ProdID)
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	for idx := range c.Events {
+		c.Events[idx].encode(e)
+	}
+	e.line("END", "VCALENDAR")
+	return e.n, e.err
+}
+
+func (ev *Event) encode(e *encoder) {
+	e.line("BEGIN", "VEVENT")
+	e.line("UID", ev.UID)
+	e.line("DTSTAMP", ev.Stamp.UTC().Format(dateTimeLayout))
+	if ev.AllDay {
+		e.line("DTSTART;VALUE=DATE", ev.Start.Format(dateLayout))
+		end := ev.End
+		if !end.After(ev.Start) {
+			end = ev.Start.AddDate(0, 0, 1)
+		}
+		e.line("DTEND;VALUE=DATE", end.Format(dateLayout))
+	} else {
+		e.line("DTSTART", ev.Start.UTC().Format(dateTimeLayout))
+		e.line("DTEND", ev.End.UTC().Format(dateTimeLayout))
+	}
+	e.line("SUMMARY", escapeText(ev.Summary))
+	if ev.Description != "" {
+		e.line("DESCRIPTION", escapeText(ev.Description))
+	}
+	if ev.Location != "" {
+		e.line("LOCATION", escapeText(ev.Location))
+	}
+	if ev.Status != "" {
+		e.line("STATUS", ev.Status)
+	}
+	e.line("END", "VEVENT")
+}
+
+type encoder struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// line writes the content line terminated with CRLF, folding it
+// so that no physical line exceeds 75 octets (RFC 5545 section 3.1)
+func (e *encoder) line(name string, value string) {
+	if e.err != nil {
+		return
+	}
+	e.write(fold(name + ":" + value))
+}
+
+func (e *encoder) write(s string) {
+	n, err := io.WriteString(e.w, s)
+	e.n += int64(n)
+	e.err = err
+}
+
+func fold(s string) string {
+	b := &strings.Builder{}
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		// Never split multi-octet UTF-8 sequence
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with the space, which counts to the line length
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
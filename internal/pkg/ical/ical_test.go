@@ -0,0 +1,72 @@
+package ical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendar_Encode(t *testing.T) {
+	start := time.Date(2021, 6, 7, 10, 0, 0, 0, time.FixedZone("EEST", 3*60*60))
+	c := &Calendar{
+		ProdID: "-//StoryPet//Calendar//EN",
+		Name:   "Pets",
+		Events: []Event{
+			{
+				UID:     "appointment-1@storypet",
+				Stamp:   start,
+				Start:   start,
+				End:     start.Add(30 * time.Minute),
+				Summary: "Visit; check-up, vaccines",
+				Status:  StatusConfirmed,
+			},
+			{
+				UID:     "booster-2@storypet",
+				Stamp:   start,
+				Start:   time.Date(2022, 6, 7, 0, 0, 0, 0, time.UTC),
+				AllDay:  true,
+				Summary: "Rabies booster",
+			},
+		},
+	}
+	encoded := string(c.Encode())
+
+	assert.True(t, strings.HasPrefix(encoded, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(encoded, "END:VCALENDAR\r\n"))
+	assert.Contains(t, encoded, "DTSTART:20210607T070000Z\r\n")
+	assert.Contains(t, encoded, "DTEND:20210607T073000Z\r\n")
+	assert.Contains(t, encoded, `SUMMARY:Visit\; check-up\, vaccines`+"\r\n")
+	assert.Contains(t, encoded, "DTSTART;VALUE=DATE:20220607\r\nDTEND;VALUE=DATE:20220608\r\n")
+	assert.Equal(t, 2, strings.Count(encoded, "BEGIN:VEVENT"))
+	assert.NotContains(t, strings.ReplaceAll(encoded, "\r\n", ""), "\n")
+}
+
+func TestFold(t *testing.T) {
+	testCases := []struct {
+		name  string
+		value string
+	}{
+		{name: "Short", value: "SUMMARY:Walk"},
+		{name: "ASCII", value: "DESCRIPTION:" + strings.Repeat("a", 200)},
+		{name: "Multi-octet", value: "DESCRIPTION:" + strings.Repeat("вакцина ", 40)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			folded := fold(tc.value)
+			lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+			for idx, line := range lines {
+				assert.LessOrEqual(t, len(line), maxLineOctets)
+				if idx > 0 {
+					assert.True(t, strings.HasPrefix(line, " "))
+				}
+			}
+			unfolded := strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", "")
+			assert.Equal(t, tc.value, unfolded)
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	assert.Equal(t, `a\\b\;c\,d\ne`, escapeText("a\\b;c,d\ne"))
+}
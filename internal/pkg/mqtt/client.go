@@ -0,0 +1,308 @@
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	dialTimeout = 10 * time.Second
+	ackTimeout  = 10 * time.Second
+
+	clientMessagesBuffer = 64
+)
+
+var (
+	ErrClientClosed         = errors.New("mqtt: client closed")
+	ErrAckTimeout           = errors.New("mqtt: acknowledgement timed out")
+	ErrSubscriptionRefused  = errors.New("mqtt: subscription refused")
+	ErrUnsupportedClientQoS = errors.New("mqtt: client publishes with QoS 0 and 1 only")
+)
+
+// ConnectError is the refusal of the connection by the broker
+type ConnectError struct {
+	Code byte
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("mqtt: connection refused, code %d", e.Code)
+}
+
+type ClientOptions struct {
+	ClientID string
+	Username string
+	Password []byte
+	// KeepAlive is the interval of the pings, zero disables them
+	KeepAlive time.Duration
+	Will      *Message
+}
+
+// ClientConn is the client connection to the broker
+type ClientConn struct {
+	conn     net.Conn
+	writeMu  sync.Mutex
+	messages chan Message
+	done     chan struct{}
+	quit     chan struct{}
+	quitOnce sync.Once
+
+	mu       sync.Mutex
+	packetID uint16
+	pending  map[uint16]chan *packet
+	err      error
+}
+
+// Dial connects to the broker on the TCP address
+func Dial(addr string, options ClientOptions) (*ClientConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClientConn(conn, options)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewClientConn connects to the broker over conn, the error is ConnectError if the broker refuses the connection
+func NewClientConn(conn net.Conn, options ClientOptions) (*ClientConn, error) {
+	connect := &connectPacket{
+		ClientID:     options.ClientID,
+		Username:     options.Username,
+		Password:     options.Password,
+		HasUsername:  options.Username != "",
+		HasPassword:  options.Password != nil,
+		CleanSession: true,
+		KeepAlive:    uint16(options.KeepAlive / time.Second),
+		Will:         options.Will,
+	}
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write(encodeConnect(connect)); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	p, err := readPacket(reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	if p.kind != typeConnack || len(p.body) != 2 {
+		return nil, ErrMalformedPacket
+	}
+	if p.body[1] != Accepted {
+		return nil, &ConnectError{Code: p.body[1]}
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	client := &ClientConn{
+		conn:     conn,
+		messages: make(chan Message, clientMessagesBuffer),
+		done:     make(chan struct{}),
+		quit:     make(chan struct{}),
+		pending:  make(map[uint16]chan *packet),
+	}
+	go client.readLoop(reader)
+	if connect.KeepAlive > 0 {
+		go client.pingLoop(time.Duration(connect.KeepAlive) * time.Second / 2)
+	}
+	return client, nil
+}
+
+// Messages returns the messages of the subscriptions, the channel is closed when the connection is lost.
+// The connection stops reading while the messages are not received.
+func (c *ClientConn) Messages() <-chan Message {
+	return c.messages
+}
+
+// Done is closed when the connection is lost
+func (c *ClientConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason the connection was lost
+func (c *ClientConn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Publish sends the message, with QoS 1 it waits for the acknowledgement
+func (c *ClientConn) Publish(topic string, payload []byte, qos byte, retain bool) error {
+	message := Message{Topic: topic, Payload: payload, QoS: qos, Retain: retain}
+	switch qos {
+	case 0:
+		return c.write(encodePublish(message, 0, false))
+	case 1:
+		packetID, ack := c.await()
+		if err := c.write(encodePublish(message, packetID, false)); err != nil {
+			return err
+		}
+		_, err := c.wait(packetID, ack)
+		return err
+	}
+	return ErrUnsupportedClientQoS
+}
+
+// Subscribe subscribes to the filter and returns the QoS granted by the broker
+func (c *ClientConn) Subscribe(filter string, qos byte) (byte, error) {
+	packetID, ack := c.await()
+	if err := c.write(encodeSubscribe(packetID, []Subscription{{Filter: filter, QoS: qos}})); err != nil {
+		return 0, err
+	}
+	p, err := c.wait(packetID, ack)
+	if err != nil {
+		return 0, err
+	}
+	if len(p.body) != 3 {
+		return 0, ErrMalformedPacket
+	}
+	if p.body[2] == subscriptionFailure {
+		return 0, ErrSubscriptionRefused
+	}
+	return p.body[2], nil
+}
+
+func (c *ClientConn) Unsubscribe(filter string) error {
+	packetID, ack := c.await()
+	if err := c.write(encodeUnsubscribe(packetID, []string{filter})); err != nil {
+		return err
+	}
+	_, err := c.wait(packetID, ack)
+	return err
+}
+
+// Close sends DISCONNECT and closes the connection
+func (c *ClientConn) Close() error {
+	_ = c.write(encodePacket(typeDisconnect, 0, nil))
+	c.quitOnce.Do(func() { close(c.quit) })
+	return c.conn.Close()
+}
+
+func (c *ClientConn) write(packet []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	select {
+	case <-c.done:
+		return ErrClientClosed
+	default:
+	}
+	_ = c.conn.SetWriteDeadline(time.Now().Add(ackTimeout))
+	_, err := c.conn.Write(packet)
+	return err
+}
+
+func (c *ClientConn) await() (uint16, chan *packet) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		c.packetID++
+		if _, ok := c.pending[c.packetID]; c.packetID != 0 && !ok {
+			break
+		}
+	}
+	ack := make(chan *packet, 1)
+	c.pending[c.packetID] = ack
+	return c.packetID, ack
+}
+
+func (c *ClientConn) wait(packetID uint16, ack chan *packet) (*packet, error) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, packetID)
+		c.mu.Unlock()
+	}()
+	select {
+	case p := <-ack:
+		return p, nil
+	case <-c.done:
+		return nil, ErrClientClosed
+	case <-time.After(ackTimeout):
+		return nil, ErrAckTimeout
+	}
+}
+
+func (c *ClientConn) readLoop(reader *bufio.Reader) {
+	defer close(c.messages)
+	for {
+		p, err := readPacket(reader, 0)
+		if err == nil {
+			err = c.handle(p)
+		}
+		if err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			close(c.done)
+			_ = c.conn.Close()
+			return
+		}
+	}
+}
+
+func (c *ClientConn) handle(p *packet) error {
+	switch p.kind {
+	case typePublish:
+		publish, err := decodePublish(p)
+		if err != nil {
+			return err
+		}
+		switch publish.QoS {
+		case 1:
+			err = c.write(encodeAck(typePuback, publish.PacketID))
+		case 2:
+			err = c.write(encodeAck(typePubrec, publish.PacketID))
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case c.messages <- publish.Message:
+		case <-c.quit:
+			return ErrClientClosed
+		}
+	case typePubrel:
+		packetID, err := decodePacketID(p)
+		if err != nil {
+			return err
+		}
+		return c.write(encodeAck(typePubcomp, packetID))
+	case typePuback, typeSuback, typeUnsuback:
+		packetID, err := decodePacketID(p)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		ack, ok := c.pending[packetID]
+		c.mu.Unlock()
+		if ok {
+			select {
+			case ack <- p:
+			default:
+			}
+		}
+	case typePingresp:
+	default:
+		return ErrMalformedPacket
+	}
+	return nil
+}
+
+func (c *ClientConn) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.write(encodePacket(typePingreq, 0, nil)); err != nil {
+				return
+			}
+		}
+	}
+}
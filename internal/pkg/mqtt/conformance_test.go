@@ -0,0 +1,236 @@
+package mqtt
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// The tests speak the raw MQTT 3.1.1 protocol to the broker and check the normative statements
+// of the specification the broker is bound to, the statements are referenced by their identifiers.
+
+type rawConn struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialRaw(t *testing.T, addr string) *rawConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &rawConn{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *rawConn) send(packet []byte) {
+	c.t.Helper()
+	_, err := c.conn.Write(packet)
+	require.NoError(c.t, err)
+}
+
+func (c *rawConn) read() *packet {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	p, err := readPacket(c.reader, 0)
+	require.NoError(c.t, err)
+	return p
+}
+
+// connect sends CONNECT and returns the return code of CONNACK
+func (c *rawConn) connect(connect *connectPacket) byte {
+	c.t.Helper()
+	c.send(encodeConnect(connect))
+	p := c.read()
+	require.Equal(c.t, typeConnack, p.kind)
+	require.Len(c.t, p.body, 2)
+	// Session present is zero, the sessions are clean [MQTT-3.2.2-1]
+	assert.Equal(c.t, byte(0), p.body[0])
+	return p.body[1]
+}
+
+// assertClosed checks the broker closes the connection without sending anything,
+// the connection is reset when the broker has not read all the data
+func (c *rawConn) assertClosed() {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := c.reader.ReadByte()
+	require.Error(c.t, err)
+	if netErr, ok := err.(net.Error); ok {
+		assert.False(c.t, netErr.Timeout(), "connection is not closed")
+	} else {
+		assert.Equal(c.t, io.EOF, err)
+	}
+}
+
+func connectBody(level byte, flags byte, clientID string) []byte {
+	body := appendBytes(nil, []byte(protocolName))
+	body = append(body, level, flags)
+	body = appendUint16(body, 0)
+	return appendBytes(body, []byte(clientID))
+}
+
+func TestConformance_Connect(t *testing.T) {
+	_, addr := startServer(t, Hooks{})
+
+	t.Run("first packet is CONNECT [MQTT-3.1.0-1]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		conn.send(encodePacket(typePingreq, 0, nil))
+		conn.assertClosed()
+	})
+	t.Run("second CONNECT is the protocol violation [MQTT-3.1.0-2]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		require.Equal(t, Accepted, conn.connect(&connectPacket{ClientID: "second", CleanSession: true}))
+		conn.send(encodeConnect(&connectPacket{ClientID: "second", CleanSession: true}))
+		conn.assertClosed()
+	})
+	t.Run("unsupported protocol level [MQTT-3.1.2-2]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		conn.send(encodePacket(typeConnect, 0, connectBody(3, 0x02, "level")))
+		p := conn.read()
+		assert.Equal(t, typeConnack, p.kind)
+		assert.Equal(t, []byte{0, RefusedProtocolVersion}, p.body)
+		conn.assertClosed()
+	})
+	t.Run("reserved flag is zero [MQTT-3.1.2-3]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		conn.send(encodePacket(typeConnect, 0, connectBody(protocolLevel, 0x03, "reserved")))
+		conn.assertClosed()
+	})
+	t.Run("will QoS is zero without the will [MQTT-3.1.2-13]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		conn.send(encodePacket(typeConnect, 0, connectBody(protocolLevel, 0x0a, "will")))
+		conn.assertClosed()
+	})
+	t.Run("empty client identifier of the persistent session is rejected [MQTT-3.1.3-8]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		assert.Equal(t, RefusedIdentifierRejected, conn.connect(&connectPacket{}))
+		conn.assertClosed()
+	})
+	t.Run("empty client identifier of the clean session is assigned [MQTT-3.1.3-6]", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		assert.Equal(t, Accepted, conn.connect(&connectPacket{CleanSession: true}))
+	})
+}
+
+func TestConformance_FixedHeader(t *testing.T) {
+	_, addr := startServer(t, Hooks{})
+
+	// The reserved flags of the fixed header are checked [MQTT-2.2.2-2]
+	for name, packet := range map[string][]byte{
+		"SUBSCRIBE":   encodePacket(typeSubscribe, 0, append(appendBytes(appendUint16(nil, 1), []byte("a")), 0)),
+		"UNSUBSCRIBE": encodePacket(typeUnsubscribe, 0, appendBytes(appendUint16(nil, 1), []byte("a"))),
+		"PUBREL":      encodePacket(typePubrel, 0, appendUint16(nil, 1)),
+		"PINGREQ":     encodePacket(typePingreq, 0x01, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn := dialRaw(t, addr)
+			require.Equal(t, Accepted, conn.connect(&connectPacket{CleanSession: true}))
+			conn.send(packet)
+			conn.assertClosed()
+		})
+	}
+
+	t.Run("remaining length takes four bytes at most", func(t *testing.T) {
+		conn := dialRaw(t, addr)
+		require.Equal(t, Accepted, conn.connect(&connectPacket{CleanSession: true}))
+		conn.send([]byte{typePingreq << 4, 0xff, 0xff, 0xff, 0xff, 0x01})
+		conn.assertClosed()
+	})
+}
+
+func TestConformance_Publish(t *testing.T) {
+	_, addr := startServer(t, Hooks{})
+	conn := dialRaw(t, addr)
+	require.Equal(t, Accepted, conn.connect(&connectPacket{ClientID: "publisher", CleanSession: true}))
+
+	// QoS 1 is acknowledged with PUBACK of the same packet identifier [MQTT-4.3.2-2]
+	conn.send(encodePublish(Message{Topic: "pets/1/telemetry", Payload: []byte("1"), QoS: 1}, 10, false))
+	p := conn.read()
+	assert.Equal(t, typePuback, p.kind)
+	assert.Equal(t, appendUint16(nil, 10), p.body)
+
+	// QoS 2 is acknowledged with PUBREC, then PUBREL is answered with PUBCOMP [MQTT-4.3.3-2]
+	conn.send(encodePublish(Message{Topic: "pets/1/telemetry", Payload: []byte("2"), QoS: 2}, 11, false))
+	p = conn.read()
+	assert.Equal(t, typePubrec, p.kind)
+	assert.Equal(t, appendUint16(nil, 11), p.body)
+	conn.send(encodeAck(typePubrel, 11))
+	p = conn.read()
+	assert.Equal(t, typePubcomp, p.kind)
+	assert.Equal(t, appendUint16(nil, 11), p.body)
+
+	// PINGREQ is answered with PINGRESP [MQTT-3.12.4-1]
+	conn.send(encodePacket(typePingreq, 0, nil))
+	assert.Equal(t, typePingresp, conn.read().kind)
+
+	// The topic name of PUBLISH has no wildcards [MQTT-3.3.2-2]
+	conn.send(encodePublish(Message{Topic: "pets/+/telemetry", Payload: []byte("3")}, 0, false))
+	conn.assertClosed()
+}
+
+func TestConformance_Subscribe(t *testing.T) {
+	server, addr := startServer(t, Hooks{})
+	server.Publish(Message{Topic: "pets/1/config", Payload: []byte("retained"), QoS: 1, Retain: true})
+	conn := dialRaw(t, addr)
+	require.Equal(t, Accepted, conn.connect(&connectPacket{ClientID: "subscriber", CleanSession: true}))
+
+	// SUBACK has the return code for every filter in order, QoS is granted up to 1 [MQTT-3.8.4-5] [MQTT-3.9.3-1]
+	conn.send(encodeSubscribe(3, []Subscription{
+		{Filter: "pets/1/config", QoS: 2},
+		{Filter: "pets/#/config", QoS: 0},
+		{Filter: "pets/1/commands", QoS: 0},
+	}))
+	p := conn.read()
+	assert.Equal(t, typeSuback, p.kind)
+	assert.Equal(t, []byte{0, 3, 1, subscriptionFailure, 0}, p.body)
+
+	// The retained message is sent with the retain flag on subscription [MQTT-3.3.1-6] [MQTT-3.3.1-8]
+	p = conn.read()
+	require.Equal(t, typePublish, p.kind)
+	retained, err := decodePublish(p)
+	require.NoError(t, err)
+	assert.True(t, retained.Retain)
+	assert.Equal(t, []byte("retained"), retained.Payload)
+	conn.send(encodeAck(typePuback, retained.PacketID))
+
+	// The messages routed to the subscribers have no retain flag [MQTT-3.3.1-9]
+	server.Publish(Message{Topic: "pets/1/commands", Payload: []byte("reboot"), QoS: 1, Retain: true})
+	p = conn.read()
+	require.Equal(t, typePublish, p.kind)
+	routed, err := decodePublish(p)
+	require.NoError(t, err)
+	assert.False(t, routed.Retain)
+	// The message is delivered with the granted QoS of the subscription [MQTT-3.8.4-6]
+	assert.Equal(t, byte(0), routed.QoS)
+
+	// UNSUBACK has the packet identifier of UNSUBSCRIBE [MQTT-3.10.4-4]
+	conn.send(encodeUnsubscribe(4, []string{"pets/1/commands"}))
+	p = conn.read()
+	assert.Equal(t, typeUnsuback, p.kind)
+	assert.Equal(t, appendUint16(nil, 4), p.body)
+
+	// SUBSCRIBE without filters is the protocol violation [MQTT-3.8.3-3]
+	conn.send(encodePacket(typeSubscribe, 0x02, appendUint16(nil, 5)))
+	conn.assertClosed()
+}
+
+func TestConformance_KeepAlive(t *testing.T) {
+	_, addr := startServer(t, Hooks{})
+	conn := dialRaw(t, addr)
+	body := appendBytes(nil, []byte(protocolName))
+	body = append(body, protocolLevel, 0x02)
+	body = appendUint16(body, 1)
+	body = appendBytes(body, []byte("idle"))
+	conn.send(encodePacket(typeConnect, 0, body))
+	require.Equal(t, typeConnack, conn.read().kind)
+
+	// The client silent for one and a half keep alive periods is disconnected [MQTT-3.1.2-24]
+	start := time.Now()
+	conn.assertClosed()
+	assert.True(t, time.Since(start) >= time.Second)
+}
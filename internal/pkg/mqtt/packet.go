@@ -0,0 +1,396 @@
+// Package mqtt is the minimal MQTT 3.1.1 broker and client.
+//
+// Sessions are always clean, so messages are not queued for offline clients and unacknowledged
+// messages are not retransmitted. Messages are delivered with QoS 0 and 1, QoS 2 publications
+// are accepted from clients and delivered with QoS 1. The broker is checked against the normative
+// statements of the MQTT 3.1.1 specification it is bound to by the conformance tests.
+package mqtt
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	typeConnect     byte = 1
+	typeConnack     byte = 2
+	typePublish     byte = 3
+	typePuback      byte = 4
+	typePubrec      byte = 5
+	typePubrel      byte = 6
+	typePubcomp     byte = 7
+	typeSubscribe   byte = 8
+	typeSuback      byte = 9
+	typeUnsubscribe byte = 10
+	typeUnsuback    byte = 11
+	typePingreq     byte = 12
+	typePingresp    byte = 13
+	typeDisconnect  byte = 14
+)
+
+// Return codes of CONNACK
+const (
+	Accepted                  byte = 0
+	RefusedProtocolVersion    byte = 1
+	RefusedIdentifierRejected byte = 2
+	RefusedServerUnavailable  byte = 3
+	RefusedBadCredentials     byte = 4
+	RefusedNotAuthorized      byte = 5
+)
+
+// subscriptionFailure is the SUBACK return code of the refused subscription
+const subscriptionFailure byte = 0x80
+
+const (
+	protocolName  = "MQTT"
+	protocolLevel = 4
+)
+
+var (
+	ErrMalformedPacket = errors.New("mqtt: malformed packet")
+	ErrPacketTooLarge  = errors.New("mqtt: packet is too large")
+
+	errProtocolVersion = errors.New("mqtt: unsupported protocol version")
+)
+
+// Message is the application message published to the topic
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(r *bufio.Reader, maxSize int) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return nil, ErrMalformedPacket
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		length += int(b&127) * multiplier
+		if b&128 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	if maxSize > 0 && length > maxSize {
+		return nil, ErrPacketTooLarge
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// reservedFlags are the fixed header flags of the packets other than PUBLISH
+func reservedFlags(kind byte) byte {
+	switch kind {
+	case typePubrel, typeSubscribe, typeUnsubscribe:
+		return 0x02
+	}
+	return 0
+}
+
+func encodePacket(kind byte, flags byte, body []byte) []byte {
+	buf := make([]byte, 0, len(body)+5)
+	buf = append(buf, kind<<4|flags)
+	length := len(body)
+	for {
+		b := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			b |= 128
+		}
+		buf = append(buf, b)
+		if length == 0 {
+			break
+		}
+	}
+	return append(buf, body...)
+}
+
+// decoder reads the fields of the packet body, the first error is kept and stops reading
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.buf) < 1 {
+		d.err = ErrMalformedPacket
+		return 0
+	}
+	b := d.buf[0]
+	d.buf = d.buf[1:]
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.buf) < 2 {
+		d.err = ErrMalformedPacket
+		return 0
+	}
+	v := uint16(d.buf[0])<<8 | uint16(d.buf[1])
+	d.buf = d.buf[2:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	length := int(d.uint16())
+	if d.err != nil || len(d.buf) < length {
+		d.err = ErrMalformedPacket
+		return nil
+	}
+	b := append([]byte(nil), d.buf[:length]...)
+	d.buf = d.buf[length:]
+	return b
+}
+
+func (d *decoder) string() string {
+	b := d.bytes()
+	if d.err == nil && !utf8.Valid(b) {
+		d.err = ErrMalformedPacket
+	}
+	return string(b)
+}
+
+func (d *decoder) rest() []byte {
+	b := append([]byte(nil), d.buf...)
+	d.buf = nil
+	return b
+}
+
+func (d *decoder) empty() bool {
+	return len(d.buf) == 0
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendBytes(b []byte, v []byte) []byte {
+	return append(appendUint16(b, uint16(len(v))), v...)
+}
+
+type connectPacket struct {
+	ClientID     string
+	Username     string
+	Password     []byte
+	HasUsername  bool
+	HasPassword  bool
+	CleanSession bool
+	KeepAlive    uint16
+	Will         *Message
+}
+
+func decodeConnect(p *packet) (*connectPacket, error) {
+	d := &decoder{buf: p.body}
+	name := d.string()
+	level := d.byte()
+	flags := d.byte()
+	connect := &connectPacket{KeepAlive: d.uint16()}
+	if d.err != nil || name != protocolName {
+		return nil, ErrMalformedPacket
+	}
+	if level != protocolLevel {
+		return nil, errProtocolVersion
+	}
+	// The reserved flag is zero and the will QoS and retain are zero without the will
+	if flags&0x01 != 0 || (flags&0x04 == 0 && flags&0x38 != 0) {
+		return nil, ErrMalformedPacket
+	}
+	connect.CleanSession = flags&0x02 != 0
+	connect.ClientID = d.string()
+	if flags&0x04 != 0 {
+		connect.Will = &Message{
+			Topic:   d.string(),
+			Payload: d.bytes(),
+			QoS:     flags >> 3 & 0x03,
+			Retain:  flags&0x20 != 0,
+		}
+		if connect.Will.QoS > 2 {
+			return nil, ErrMalformedPacket
+		}
+	}
+	connect.HasUsername = flags&0x80 != 0
+	if connect.HasUsername {
+		connect.Username = d.string()
+	}
+	connect.HasPassword = flags&0x40 != 0
+	if connect.HasPassword {
+		connect.Password = d.bytes()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return connect, nil
+}
+
+func encodeConnect(connect *connectPacket) []byte {
+	var flags byte
+	if connect.CleanSession {
+		flags |= 0x02
+	}
+	if connect.Will != nil {
+		flags |= 0x04 | connect.Will.QoS<<3
+		if connect.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if connect.HasPassword {
+		flags |= 0x40
+	}
+	if connect.HasUsername {
+		flags |= 0x80
+	}
+	body := appendBytes(nil, []byte(protocolName))
+	body = append(body, protocolLevel, flags)
+	body = appendUint16(body, connect.KeepAlive)
+	body = appendBytes(body, []byte(connect.ClientID))
+	if connect.Will != nil {
+		body = appendBytes(body, []byte(connect.Will.Topic))
+		body = appendBytes(body, connect.Will.Payload)
+	}
+	if connect.HasUsername {
+		body = appendBytes(body, []byte(connect.Username))
+	}
+	if connect.HasPassword {
+		body = appendBytes(body, connect.Password)
+	}
+	return encodePacket(typeConnect, 0, body)
+}
+
+func encodeConnack(code byte) []byte {
+	return encodePacket(typeConnack, 0, []byte{0, code})
+}
+
+type publishPacket struct {
+	Message
+	PacketID uint16
+	Dup      bool
+}
+
+func decodePublish(p *packet) (*publishPacket, error) {
+	publish := &publishPacket{
+		Message: Message{QoS: p.flags >> 1 & 0x03, Retain: p.flags&0x01 != 0},
+		Dup:     p.flags&0x08 != 0,
+	}
+	if publish.QoS > 2 {
+		return nil, ErrMalformedPacket
+	}
+	d := &decoder{buf: p.body}
+	publish.Topic = d.string()
+	if publish.QoS > 0 {
+		publish.PacketID = d.uint16()
+	}
+	publish.Payload = d.rest()
+	if d.err != nil {
+		return nil, d.err
+	}
+	return publish, nil
+}
+
+func encodePublish(message Message, packetID uint16, dup bool) []byte {
+	flags := message.QoS << 1
+	if message.Retain {
+		flags |= 0x01
+	}
+	if dup {
+		flags |= 0x08
+	}
+	body := appendBytes(make([]byte, 0, len(message.Topic)+len(message.Payload)+4), []byte(message.Topic))
+	if message.QoS > 0 {
+		body = appendUint16(body, packetID)
+	}
+	return encodePacket(typePublish, flags, append(body, message.Payload...))
+}
+
+// encodeAck encodes the packets carrying only the packet identifier
+func encodeAck(kind byte, packetID uint16) []byte {
+	var flags byte
+	if kind == typePubrel {
+		flags = 0x02
+	}
+	return encodePacket(kind, flags, appendUint16(nil, packetID))
+}
+
+func decodePacketID(p *packet) (uint16, error) {
+	d := &decoder{buf: p.body}
+	packetID := d.uint16()
+	return packetID, d.err
+}
+
+// Subscription is the topic filter with the maximum QoS of the delivered messages
+type Subscription struct {
+	Filter string
+	QoS    byte
+}
+
+func decodeSubscribe(p *packet) (uint16, []Subscription, error) {
+	if p.flags != 0x02 {
+		return 0, nil, ErrMalformedPacket
+	}
+	d := &decoder{buf: p.body}
+	packetID := d.uint16()
+	var subscriptions []Subscription
+	for d.err == nil && !d.empty() {
+		subscriptions = append(subscriptions, Subscription{Filter: d.string(), QoS: d.byte()})
+	}
+	if d.err != nil || len(subscriptions) == 0 {
+		return 0, nil, ErrMalformedPacket
+	}
+	return packetID, subscriptions, nil
+}
+
+func encodeSubscribe(packetID uint16, subscriptions []Subscription) []byte {
+	body := appendUint16(nil, packetID)
+	for _, subscription := range subscriptions {
+		body = append(appendBytes(body, []byte(subscription.Filter)), subscription.QoS)
+	}
+	return encodePacket(typeSubscribe, 0x02, body)
+}
+
+func encodeSuback(packetID uint16, codes []byte) []byte {
+	return encodePacket(typeSuback, 0, append(appendUint16(nil, packetID), codes...))
+}
+
+func decodeUnsubscribe(p *packet) (uint16, []string, error) {
+	if p.flags != 0x02 {
+		return 0, nil, ErrMalformedPacket
+	}
+	d := &decoder{buf: p.body}
+	packetID := d.uint16()
+	var filters []string
+	for d.err == nil && !d.empty() {
+		filters = append(filters, d.string())
+	}
+	if d.err != nil || len(filters) == 0 {
+		return 0, nil, ErrMalformedPacket
+	}
+	return packetID, filters, nil
+}
+
+func encodeUnsubscribe(packetID uint16, filters []string) []byte {
+	body := appendUint16(nil, packetID)
+	for _, filter := range filters {
+		body = appendBytes(body, []byte(filter))
+	}
+	return encodePacket(typeUnsubscribe, 0x02, body)
+}
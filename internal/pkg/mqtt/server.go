@@ -0,0 +1,513 @@
+package mqtt
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// connectTimeout is how long the new connection may take to send CONNECT
+	connectTimeout = 10 * time.Second
+	// writeTimeout is how long the client may take to receive the packet before it is disconnected
+	writeTimeout = 10 * time.Second
+
+	defaultMaxPacketSize = 256 << 10
+)
+
+var (
+	// ErrBadCredentials is returned by Authenticate to refuse the connection with the bad credentials code
+	ErrBadCredentials = errors.New("mqtt: bad user name or password")
+	// ErrNotAuthorized is returned by Authenticate to refuse the connection with the not authorized code
+	ErrNotAuthorized = errors.New("mqtt: not authorized")
+	// ErrIdentifierRejected is returned by Authenticate to refuse the client identifier the client may not use
+	ErrIdentifierRejected = errors.New("mqtt: identifier rejected")
+
+	ErrServerClosed = errors.New("mqtt: server closed")
+)
+
+// Hooks customise the broker, the hooks which are nil allow everything
+type Hooks struct {
+	// Authenticate checks the credentials of the connecting client, the returned identity is kept
+	// as Client.Identity. The connection of the client with the same identifier is taken over by
+	// the new one, so the hook should bind the identifiers to the identities. Errors other than
+	// ErrBadCredentials, ErrNotAuthorized and ErrIdentifierRejected refuse the connection with
+	// the server unavailable code.
+	Authenticate func(clientID string, username string, password []byte) (identity interface{}, err error)
+	// AllowPublish reports whether the client may publish to the topic,
+	// the messages which are not allowed are acknowledged and dropped
+	AllowPublish func(client *Client, topic string) bool
+	// AllowSubscribe reports whether the client may subscribe to the filter
+	AllowSubscribe func(client *Client, filter string) bool
+	// OnPublish is called for the allowed messages of the clients before they are routed to the subscribers.
+	// When it fails the connection is closed without the acknowledgement, so the client sends the message again.
+	OnPublish func(client *Client, message Message) error
+	// OnDisconnect is called when the authenticated client is gone
+	OnDisconnect func(client *Client)
+}
+
+// Server is the MQTT broker
+type Server struct {
+	hooks  Hooks
+	logger *log.Logger
+
+	// MaxPacketSize limits the remaining length of the packets sent by the clients
+	MaxPacketSize int
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	clients   map[string]*Client
+	retained  map[string]Message
+	anonymous uint64
+}
+
+func NewServer(hooks Hooks, logger *log.Logger) *Server {
+	return &Server{
+		hooks:         hooks,
+		logger:        logger,
+		MaxPacketSize: defaultMaxPacketSize,
+		listeners:     make(map[net.Listener]struct{}),
+		clients:       make(map[string]*Client),
+		retained:      make(map[string]Message),
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves the connections until the server is closed
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// ListenAndServeTLS listens on the TCP address and serves the TLS connections with the certificate
+// and the key from the PEM files until the server is closed
+func (s *Server) ListenAndServeTLS(addr string, certFile string, keyFile string) error {
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(tls.NewListener(listener, &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}))
+}
+
+// Serve accepts the connections of the listener until the server is closed
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = listener.Close()
+		return ErrServerClosed
+	}
+	s.listeners[listener] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, listener)
+		s.mu.Unlock()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// Close stops the listeners and disconnects all clients
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for listener := range s.listeners {
+		_ = listener.Close()
+	}
+	for _, client := range s.clients {
+		client.close()
+	}
+	return nil
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Publish routes the message to the subscribers of its topic and keeps it if it is retained,
+// the retained message with empty payload deletes the message retained for the topic
+func (s *Server) Publish(message Message) {
+	if message.QoS > 1 {
+		message.QoS = 1
+	}
+
+	type delivery struct {
+		client *Client
+		qos    byte
+	}
+	s.mu.Lock()
+	if message.Retain {
+		if len(message.Payload) == 0 {
+			delete(s.retained, message.Topic)
+		} else {
+			s.retained[message.Topic] = message
+		}
+	}
+	deliveries := make([]delivery, 0)
+	for _, client := range s.clients {
+		if qos, ok := client.matches(message.Topic); ok {
+			if qos > message.QoS {
+				qos = message.QoS
+			}
+			deliveries = append(deliveries, delivery{client: client, qos: qos})
+		}
+	}
+	s.mu.Unlock()
+
+	// Retain flag is only set for the messages sent on subscription
+	message.Retain = false
+	for _, d := range deliveries {
+		d.client.deliver(message, d.qos)
+	}
+}
+
+// Client returns the connected client with the client identifier
+func (s *Server) Client(clientID string) (*Client, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[clientID]
+	return client, ok
+}
+
+// Disconnect closes the connection of the clients for which match returns true, match must not call the server
+func (s *Server) Disconnect(match func(client *Client) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, client := range s.clients {
+		if match(client) {
+			client.close()
+		}
+	}
+}
+
+// ServeConn serves the client connected over conn until it disconnects
+func (s *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	_ = conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	p, err := readPacket(reader, s.MaxPacketSize)
+	if err != nil || p.kind != typeConnect {
+		return
+	}
+	connect, err := decodeConnect(p)
+	if err != nil {
+		if errors.Is(err, errProtocolVersion) {
+			_, _ = conn.Write(encodeConnack(RefusedProtocolVersion))
+		}
+		return
+	}
+	if connect.ClientID == "" {
+		if !connect.CleanSession {
+			_, _ = conn.Write(encodeConnack(RefusedIdentifierRejected))
+			return
+		}
+		connect.ClientID = fmt.Sprintf("anonymous-%d", atomic.AddUint64(&s.anonymous, 1))
+	}
+
+	client := &Client{
+		ID:            connect.ClientID,
+		Username:      connect.Username,
+		server:        s,
+		conn:          conn,
+		subscriptions: make(map[string]byte),
+		received:      make(map[uint16]bool),
+	}
+	if s.hooks.Authenticate != nil {
+		identity, err := s.hooks.Authenticate(connect.ClientID, connect.Username, connect.Password)
+		if err != nil {
+			code := RefusedServerUnavailable
+			switch {
+			case errors.Is(err, ErrBadCredentials):
+				code = RefusedBadCredentials
+			case errors.Is(err, ErrNotAuthorized):
+				code = RefusedNotAuthorized
+			case errors.Is(err, ErrIdentifierRejected):
+				code = RefusedIdentifierRejected
+			}
+			_, _ = conn.Write(encodeConnack(code))
+			return
+		}
+		client.Identity = identity
+	}
+
+	if !s.register(client) {
+		_, _ = conn.Write(encodeConnack(RefusedServerUnavailable))
+		return
+	}
+	err = client.write(encodeConnack(Accepted))
+	if err == nil {
+		err = client.serve(reader, time.Duration(connect.KeepAlive)*time.Second)
+	}
+	s.unregister(client)
+
+	// The will is published when the connection is lost without DISCONNECT
+	if err != nil && connect.Will != nil && ValidTopic(connect.Will.Topic) &&
+		(s.hooks.AllowPublish == nil || s.hooks.AllowPublish(client, connect.Will.Topic)) {
+		s.Publish(*connect.Will)
+	}
+	if s.hooks.OnDisconnect != nil {
+		s.hooks.OnDisconnect(client)
+	}
+}
+
+// register adds the client, the connection of the client with the same identifier is taken over
+func (s *Server) register(client *Client) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if existing, ok := s.clients[client.ID]; ok {
+		existing.close()
+	}
+	s.clients[client.ID] = client
+	return true
+}
+
+func (s *Server) unregister(client *Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.clients[client.ID] == client {
+		delete(s.clients, client.ID)
+	}
+}
+
+// Client is the connection of the client to the broker
+type Client struct {
+	ID       string
+	Username string
+	// Identity is returned by the Authenticate hook
+	Identity interface{}
+
+	server *Server
+	conn   net.Conn
+
+	writeMu  sync.Mutex
+	packetID uint16
+
+	// subscriptions map the filters to the granted QoS, guarded by server.mu
+	subscriptions map[string]byte
+	// received are the identifiers of the QoS 2 messages waiting for PUBREL
+	received map[uint16]bool
+}
+
+// Close disconnects the client
+func (c *Client) Close() {
+	c.close()
+}
+
+func (c *Client) close() {
+	_ = c.conn.Close()
+}
+
+func (c *Client) matches(topic string) (byte, bool) {
+	var granted byte
+	matched := false
+	for filter, qos := range c.subscriptions {
+		if MatchTopic(filter, topic) {
+			if !matched || qos > granted {
+				granted = qos
+			}
+			matched = true
+		}
+	}
+	return granted, matched
+}
+
+func (c *Client) write(packet []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write(packet); err != nil {
+		c.close()
+		return err
+	}
+	return nil
+}
+
+func (c *Client) deliver(message Message, qos byte) {
+	message.QoS = qos
+	var packetID uint16
+	if qos > 0 {
+		c.writeMu.Lock()
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID++
+		}
+		packetID = c.packetID
+		c.writeMu.Unlock()
+	}
+	if err := c.write(encodePublish(message, packetID, false)); err != nil && c.server.logger != nil {
+		c.server.logger.Printf("MQTT delivery to %v failed: %v", c.ID, err)
+	}
+}
+
+func (c *Client) serve(reader *bufio.Reader, keepAlive time.Duration) error {
+	for {
+		if keepAlive > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(keepAlive * 3 / 2))
+		} else {
+			_ = c.conn.SetReadDeadline(time.Time{})
+		}
+		p, err := readPacket(reader, c.server.MaxPacketSize)
+		if err != nil {
+			return err
+		}
+		// The flags of the fixed header are reserved for all packets but PUBLISH
+		if p.kind != typePublish && p.flags != reservedFlags(p.kind) {
+			return ErrMalformedPacket
+		}
+
+		switch p.kind {
+		case typePublish:
+			err = c.handlePublish(p)
+		case typePuback:
+			// Sessions are clean, so the acknowledged messages are not tracked
+		case typePubrel:
+			var packetID uint16
+			if packetID, err = decodePacketID(p); err == nil {
+				delete(c.received, packetID)
+				err = c.write(encodeAck(typePubcomp, packetID))
+			}
+		case typeSubscribe:
+			err = c.handleSubscribe(p)
+		case typeUnsubscribe:
+			err = c.handleUnsubscribe(p)
+		case typePingreq:
+			err = c.write(encodePacket(typePingresp, 0, nil))
+		case typeDisconnect:
+			return nil
+		default:
+			err = ErrMalformedPacket
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) handlePublish(p *packet) error {
+	publish, err := decodePublish(p)
+	if err != nil {
+		return err
+	}
+	if !ValidTopic(publish.Topic) {
+		return ErrMalformedPacket
+	}
+
+	duplicate := publish.QoS == 2 && c.received[publish.PacketID]
+	hooks := c.server.hooks
+	if !duplicate && (hooks.AllowPublish == nil || hooks.AllowPublish(c, publish.Topic)) {
+		if hooks.OnPublish != nil {
+			if err := hooks.OnPublish(c, publish.Message); err != nil {
+				return err
+			}
+		}
+		c.server.Publish(publish.Message)
+	}
+
+	switch publish.QoS {
+	case 1:
+		return c.write(encodeAck(typePuback, publish.PacketID))
+	case 2:
+		c.received[publish.PacketID] = true
+		return c.write(encodeAck(typePubrec, publish.PacketID))
+	}
+	return nil
+}
+
+func (c *Client) handleSubscribe(p *packet) error {
+	packetID, subscriptions, err := decodeSubscribe(p)
+	if err != nil {
+		return err
+	}
+
+	codes := make([]byte, len(subscriptions))
+	hooks := c.server.hooks
+	for idx, subscription := range subscriptions {
+		if subscription.QoS > 2 {
+			return ErrMalformedPacket
+		}
+		if !ValidFilter(subscription.Filter) ||
+			(hooks.AllowSubscribe != nil && !hooks.AllowSubscribe(c, subscription.Filter)) {
+			codes[idx] = subscriptionFailure
+			continue
+		}
+		codes[idx] = subscription.QoS
+		if codes[idx] > 1 {
+			codes[idx] = 1
+		}
+	}
+
+	retained := make([]Message, 0)
+	c.server.mu.Lock()
+	for idx, subscription := range subscriptions {
+		if codes[idx] != subscriptionFailure {
+			c.subscriptions[subscription.Filter] = codes[idx]
+		}
+	}
+	for topic, message := range c.server.retained {
+		for idx, subscription := range subscriptions {
+			if codes[idx] != subscriptionFailure && MatchTopic(subscription.Filter, topic) {
+				if qos, _ := c.matches(topic); qos < message.QoS {
+					message.QoS = qos
+				}
+				retained = append(retained, message)
+				break
+			}
+		}
+	}
+	c.server.mu.Unlock()
+
+	if err := c.write(encodeSuback(packetID, codes)); err != nil {
+		return err
+	}
+	for _, message := range retained {
+		c.deliver(message, message.QoS)
+	}
+	return nil
+}
+
+func (c *Client) handleUnsubscribe(p *packet) error {
+	packetID, filters, err := decodeUnsubscribe(p)
+	if err != nil {
+		return err
+	}
+	c.server.mu.Lock()
+	for _, filter := range filters {
+		delete(c.subscriptions, filter)
+	}
+	c.server.mu.Unlock()
+	return c.write(encodeAck(typeUnsuback, packetID))
+}
@@ -0,0 +1,255 @@
+package mqtt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, hooks Hooks) (*Server, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := NewServer(hooks, nil)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return server, listener.Addr().String()
+}
+
+func dial(t *testing.T, addr string, clientID string) *ClientConn {
+	t.Helper()
+	client, err := Dial(addr, ClientOptions{ClientID: clientID})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func receive(t *testing.T, client *ClientConn) Message {
+	t.Helper()
+	select {
+	case message := <-client.Messages():
+		return message
+	case <-time.After(2 * time.Second):
+		t.Fatal("message is not received")
+		return Message{}
+	}
+}
+
+func assertNoMessage(t *testing.T, client *ClientConn) {
+	t.Helper()
+	select {
+	case message := <-client.Messages():
+		t.Fatalf("unexpected message on %v", message.Topic)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestServer_PublishSubscribe(t *testing.T) {
+	_, addr := startServer(t, Hooks{})
+	subscriber := dial(t, addr, "subscriber")
+	publisher := dial(t, addr, "publisher")
+
+	granted, err := subscriber.Subscribe("pets/+/telemetry", 2)
+	require.NoError(t, err)
+	assert.Equal(t, byte(1), granted)
+
+	require.NoError(t, publisher.Publish("pets/1/telemetry", []byte("qos0"), 0, false))
+	require.NoError(t, publisher.Publish("pets/2/telemetry", []byte("qos1"), 1, false))
+	require.NoError(t, publisher.Publish("pets/2/config", []byte("other"), 1, false))
+
+	message := receive(t, subscriber)
+	assert.Equal(t, "pets/1/telemetry", message.Topic)
+	assert.Equal(t, []byte("qos0"), message.Payload)
+	assert.Equal(t, byte(0), message.QoS)
+	message = receive(t, subscriber)
+	assert.Equal(t, "pets/2/telemetry", message.Topic)
+	assert.Equal(t, byte(1), message.QoS)
+	assertNoMessage(t, subscriber)
+
+	require.NoError(t, subscriber.Unsubscribe("pets/+/telemetry"))
+	require.NoError(t, publisher.Publish("pets/1/telemetry", []byte("late"), 1, false))
+	assertNoMessage(t, subscriber)
+}
+
+func TestServer_Retained(t *testing.T) {
+	server, addr := startServer(t, Hooks{})
+	server.Publish(Message{Topic: "pets/1/config", Payload: []byte(`{"interval":60}`), QoS: 1, Retain: true})
+
+	client := dial(t, addr, "collar")
+	_, err := client.Subscribe("pets/1/config", 1)
+	require.NoError(t, err)
+	message := receive(t, client)
+	assert.True(t, message.Retain)
+	assert.Equal(t, []byte(`{"interval":60}`), message.Payload)
+
+	server.Publish(Message{Topic: "pets/1/config", Payload: []byte(`{"interval":30}`), QoS: 1, Retain: true})
+	message = receive(t, client)
+	assert.False(t, message.Retain)
+
+	// The empty retained message clears the topic
+	server.Publish(Message{Topic: "pets/1/config", QoS: 1, Retain: true})
+	receive(t, client)
+	late := dial(t, addr, "late")
+	_, err = late.Subscribe("pets/1/config", 1)
+	require.NoError(t, err)
+	assertNoMessage(t, late)
+}
+
+func TestServer_Hooks(t *testing.T) {
+	received := make(chan Message, 1)
+	_, addr := startServer(t, Hooks{
+		Authenticate: func(clientID string, username string, password []byte) (interface{}, error) {
+			switch string(password) {
+			case "secret":
+				return strings.TrimPrefix(clientID, "collar-"), nil
+			case "revoked":
+				return nil, ErrNotAuthorized
+			}
+			return nil, ErrBadCredentials
+		},
+		AllowPublish: func(client *Client, topic string) bool {
+			return topic == "pets/"+client.Identity.(string)+"/telemetry"
+		},
+		AllowSubscribe: func(client *Client, filter string) bool {
+			return filter == "pets/"+client.Identity.(string)+"/commands"
+		},
+		OnPublish: func(client *Client, message Message) error {
+			received <- message
+			return nil
+		},
+	})
+
+	_, err := Dial(addr, ClientOptions{ClientID: "collar-1", Password: []byte("wrong")})
+	connectErr := &ConnectError{}
+	require.True(t, errors.As(err, &connectErr))
+	assert.Equal(t, RefusedBadCredentials, connectErr.Code)
+	_, err = Dial(addr, ClientOptions{ClientID: "collar-1", Password: []byte("revoked")})
+	require.True(t, errors.As(err, &connectErr))
+	assert.Equal(t, RefusedNotAuthorized, connectErr.Code)
+
+	client, err := Dial(addr, ClientOptions{ClientID: "collar-1", Password: []byte("secret")})
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Subscribe("pets/2/commands", 1)
+	assert.Equal(t, ErrSubscriptionRefused, err)
+	_, err = client.Subscribe("pets/1/commands", 1)
+	assert.NoError(t, err)
+
+	// The message which is not allowed is acknowledged and dropped
+	require.NoError(t, client.Publish("pets/2/telemetry", []byte("{}"), 1, false))
+	require.NoError(t, client.Publish("pets/1/telemetry", []byte(`{"distance":10}`), 1, false))
+	select {
+	case message := <-received:
+		assert.Equal(t, "pets/1/telemetry", message.Topic)
+	case <-time.After(2 * time.Second):
+		t.Fatal("message is not received")
+	}
+	assert.Empty(t, received)
+}
+
+func TestServer_Takeover(t *testing.T) {
+	server, addr := startServer(t, Hooks{})
+	first := dial(t, addr, "collar")
+	dial(t, addr, "collar")
+
+	select {
+	case <-first.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("first connection is not closed")
+	}
+	_, ok := server.Client("collar")
+	assert.True(t, ok)
+}
+
+func TestServer_Will(t *testing.T) {
+	_, addr := startServer(t, Hooks{})
+	subscriber := dial(t, addr, "subscriber")
+	_, err := subscriber.Subscribe("pets/1/status", 0)
+	require.NoError(t, err)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	_, err = NewClientConn(conn, ClientOptions{
+		ClientID: "collar",
+		Will:     &Message{Topic: "pets/1/status", Payload: []byte("offline")},
+	})
+	require.NoError(t, err)
+	// The connection is lost without DISCONNECT
+	require.NoError(t, conn.Close())
+
+	message := receive(t, subscriber)
+	assert.Equal(t, []byte("offline"), message.Payload)
+}
+
+// writeCertificate writes the self-signed certificate of 127.0.0.1 and its key to the PEM files
+func writeCertificate(t *testing.T) (*x509.CertPool, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	pool := x509.NewCertPool()
+	pool.AddCert(certificate)
+	return pool, certFile, keyFile
+}
+
+func TestServer_ListenAndServeTLS(t *testing.T) {
+	pool, certFile, keyFile := writeCertificate(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	var password []byte
+	server := NewServer(Hooks{Authenticate: func(clientID string, username string, secret []byte) (interface{}, error) {
+		password = secret
+		return nil, nil
+	}}, nil)
+	t.Cleanup(func() { _ = server.Close() })
+	go func() { _ = server.ListenAndServeTLS(addr, certFile, keyFile) }()
+
+	var conn *tls.Conn
+	require.Eventually(t, func() bool {
+		conn, err = tls.Dial("tcp", addr, &tls.Config{RootCAs: pool})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	client, err := NewClientConn(conn, ClientOptions{ClientID: "collar", Password: []byte("collar-secret")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	assert.Equal(t, []byte("collar-secret"), password)
+
+	// The listener does not accept the connections in the clear
+	_, err = Dial(addr, ClientOptions{ClientID: "plain"})
+	assert.Error(t, err)
+}
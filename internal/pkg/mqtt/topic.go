@@ -0,0 +1,50 @@
+package mqtt
+
+import "strings"
+
+const maxTopicLength = 65535
+
+// ValidTopic reports whether the topic name may be published to
+func ValidTopic(topic string) bool {
+	return topic != "" && len(topic) <= maxTopicLength && !strings.ContainsAny(topic, "+#\x00")
+}
+
+// ValidFilter reports whether the topic filter may be subscribed to.
+// The multi-level wildcard # must be the last level, the single-level wildcard + must fill the whole level.
+func ValidFilter(filter string) bool {
+	if filter == "" || len(filter) > maxTopicLength || strings.ContainsRune(filter, 0) {
+		return false
+	}
+	levels := strings.Split(filter, "/")
+	for idx, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || idx != len(levels)-1) {
+			return false
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchTopic reports whether the topic name matches the filter.
+// Topics starting with $ are not matched by filters starting with wildcards.
+func MatchTopic(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == "+" || filterLevels[0] == "#") {
+		return false
+	}
+	for idx, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if idx >= len(topicLevels) {
+			return false
+		}
+		if level != "+" && level != topicLevels[idx] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
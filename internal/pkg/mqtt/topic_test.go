@@ -0,0 +1,39 @@
+package mqtt
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMatchTopic(t *testing.T) {
+	testCases := []struct {
+		filter  string
+		topic   string
+		matches bool
+	}{
+		{"pets/1/telemetry", "pets/1/telemetry", true},
+		{"pets/1/telemetry", "pets/2/telemetry", false},
+		{"pets/+/telemetry", "pets/2/telemetry", true},
+		{"pets/+", "pets/2/telemetry", false},
+		{"pets/#", "pets/2/telemetry", true},
+		{"pets/#", "pets", true},
+		{"#", "pets/2/telemetry", true},
+		{"#", "$SYS/uptime", false},
+		{"+/uptime", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.matches, MatchTopic(testCase.filter, testCase.topic), "%v %v", testCase.filter, testCase.topic)
+	}
+}
+
+func TestValidFilter(t *testing.T) {
+	for _, filter := range []string{"pets/1/config", "pets/+/config", "pets/#", "#", "+"} {
+		assert.True(t, ValidFilter(filter), filter)
+	}
+	for _, filter := range []string{"", "pets/#/config", "pets/1#", "pets/+1/config"} {
+		assert.False(t, ValidFilter(filter), filter)
+	}
+	assert.True(t, ValidTopic("pets/1/telemetry"))
+	assert.False(t, ValidTopic("pets/+/telemetry"))
+}
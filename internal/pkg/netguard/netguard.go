@@ -0,0 +1,201 @@
+/*
+Package netguard keeps the requests to the URLs given by the users, like the webhook receivers, off the internal
+network of the server: the URLs must be https and their hosts must resolve to the public unicast addresses.
+
+The URL is checked when the user gives it, and the address is checked again when the connection is dialed,
+as the name may resolve to the other address by then.
+*/
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	dialTimeout   = 5 * time.Second
+	lookupTimeout = 5 * time.Second
+	maxRedirects  = 5
+)
+
+var (
+	// ErrInsecureURL is returned for the URLs which are not https
+	ErrInsecureURL = errors.New("netguard: URL must be https")
+	// ErrForbiddenAddress is returned for the hosts out of the public network
+	ErrForbiddenAddress = errors.New("netguard: address is not public")
+)
+
+// reservedNetworks are the loopback, private, link-local (the cloud metadata services included),
+// shared, documentation, multicast and other special purpose networks
+var reservedNetworks = parseNetworks(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"64:ff9b:1::/48",
+	"100::/64",
+	"2001::/23",
+	"2001:db8::/32",
+	"2002::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"fec0::/10",
+	"ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for idx, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[idx] = network
+	}
+	return networks
+}
+
+// IsPublicIP reports whether the address is out of the reserved networks,
+// the IPv4 addresses mapped to IPv6 are checked as IPv4 ones
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL checks the URL is the absolute https one, its host is not the local name and,
+// when it is the IP address, the address is public. The names are not resolved, see ResolveURL.
+func ValidateURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(parsed.Scheme, "https") {
+		return ErrInsecureURL
+	}
+	return validateHost(parsed.Hostname())
+}
+
+// ResolveURL validates the URL and checks all the addresses its host resolves to are public
+func ResolveURL(ctx context.Context, rawURL string) error {
+	if err := ValidateURL(rawURL); err != nil {
+		return err
+	}
+	parsed, _ := url.Parse(rawURL)
+	if net.ParseIP(parsed.Hostname()) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("netguard: host %q can not be resolved", parsed.Hostname())
+	}
+	for _, address := range addresses {
+		if !IsPublicIP(address.IP) {
+			return ErrForbiddenAddress
+		}
+	}
+	return nil
+}
+
+func validateHost(host string) error {
+	if host == "" {
+		return errors.New("netguard: URL has no host")
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// control refuses the connection to the resolved address out of the public network
+func control(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// secureTransport refuses the requests which are not https, the redirected ones included
+type secureTransport struct {
+	base http.RoundTripper
+}
+
+func (t *secureTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.URL.Scheme != "https" {
+		return nil, ErrInsecureURL
+	}
+	return t.base.RoundTrip(request)
+}
+
+/*
+NewClient returns the client sending the requests to the public https addresses only.
+The address is checked when the connection is dialed, so the name resolving to the internal address
+after it has been validated is refused too. The environment proxy is not used, as the client
+would dial the proxy instead of the checked address.
+*/
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Transport: &secureTransport{base: transport},
+		Timeout:   timeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return errors.New("netguard: too many redirects")
+			}
+			return nil
+		},
+	}
+}
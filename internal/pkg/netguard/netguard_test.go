@@ -0,0 +1,65 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	testCases := []struct {
+		address  string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.20.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.1.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsPublicIP(net.ParseIP(tc.address)))
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://partner.example.com/hooks"))
+	assert.NoError(t, ValidateURL("https://93.184.216.34:8443/hooks"))
+	assert.True(t, errors.Is(ValidateURL("http://partner.example.com/hooks"), ErrInsecureURL))
+	assert.True(t, errors.Is(ValidateURL("https://localhost/hooks"), ErrForbiddenAddress))
+	assert.True(t, errors.Is(ValidateURL("https://api.localhost./hooks"), ErrForbiddenAddress))
+	assert.True(t, errors.Is(ValidateURL("https://169.254.169.254/latest/meta-data"), ErrForbiddenAddress))
+	assert.True(t, errors.Is(ValidateURL("https://[::1]:8080/"), ErrForbiddenAddress))
+	assert.Error(t, ValidateURL("https:///hooks"))
+
+	assert.True(t, errors.Is(ResolveURL(context.Background(), "https://10.0.0.1/hooks"), ErrForbiddenAddress))
+}
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	client := NewClient(time.Second)
+
+	// The test server listens on the loopback address, the dial is refused
+	_, err := client.Get(server.URL)
+	assert.True(t, errors.Is(err, ErrForbiddenAddress))
+
+	_, err = client.Get("http://93.184.216.34/")
+	assert.True(t, errors.Is(err, ErrInsecureURL))
+}
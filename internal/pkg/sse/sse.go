@@ -0,0 +1,73 @@
+// Package sse writes Server-Sent Events streams
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const ContentType = "text/event-stream"
+
+var ErrStreamingUnsupported = errors.New("sse: response writer does not support flushing")
+
+type Writer struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewWriter sends the stream headers, the response is committed afterwards
+func NewWriter(w http.ResponseWriter) (*Writer, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrStreamingUnsupported
+	}
+	header := w.Header()
+	header.Set("Content-Type", ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Disables response buffering of nginx based proxies
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &Writer{w: w, flusher: flusher}, nil
+}
+
+// Event writes the event with the type and data, every data line is sent as separate data field
+func (s *Writer) Event(id string, event string, data []byte) error {
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", sanitize(id))
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", sanitize(event))
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Retry tells the client how long to wait before reconnecting
+func (s *Writer) Retry(milliseconds int) error {
+	return s.write(fmt.Sprintf("retry: %d\n\n", milliseconds))
+}
+
+// Comment writes the line ignored by clients, it keeps idle connections alive through proxies
+func (s *Writer) Comment(text string) error {
+	return s.write(": " + sanitize(text) + "\n\n")
+}
+
+func (s *Writer) write(chunk string) error {
+	if _, err := s.w.Write([]byte(chunk)); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func sanitize(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
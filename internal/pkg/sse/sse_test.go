@@ -0,0 +1,29 @@
+package sse
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer, err := NewWriter(recorder)
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Retry(3000))
+	require.NoError(t, writer.Event("1", "activity.created", []byte(`{"distance":1}`)))
+	require.NoError(t, writer.Event("", "multi\nline", []byte("first\r\nsecond")))
+	require.NoError(t, writer.Comment("ping"))
+
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.True(t, recorder.Flushed)
+	assert.Equal(t,
+		"retry: 3000\n\n"+
+			"id: 1\nevent: activity.created\ndata: {\"distance\":1}\n\n"+
+			"event: multiline\ndata: first\ndata: second\n\n"+
+			": ping\n\n",
+		recorder.Body.String(),
+	)
+}
@@ -0,0 +1,55 @@
+// Package webhook signs outgoing webhook requests so receivers can check their origin and freshness
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-StoryPet-Signature"
+	TimestampHeader = "X-StoryPet-Timestamp"
+	EventHeader     = "X-StoryPet-Event"
+
+	signaturePrefix = "sha256="
+)
+
+// Sign computes the signature of the body sent at timestamp: hex HMAC-SHA256 of "timestamp.body"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetHeaders sets the signature and timestamp headers of the request with the body
+func SetHeaders(header http.Header, secret string, event string, body []byte, now time.Time) {
+	timestamp := now.Unix()
+	header.Set(EventHeader, event)
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, Sign(secret, timestamp, body))
+}
+
+// Verify checks the signature headers of the received request.
+// Requests older than tolerance are rejected to prevent replaying.
+func Verify(header http.Header, secret string, body []byte, tolerance time.Duration, now time.Time) bool {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+	sentAt := time.Unix(timestamp, 0)
+	if now.Sub(sentAt) > tolerance || sentAt.Sub(now) > tolerance {
+		return false
+	}
+	signature := header.Get(SignatureHeader)
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
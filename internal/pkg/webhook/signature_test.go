@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"event":"pet.created"}`)
+	header := http.Header{}
+	SetHeaders(header, "secret", "pet.created", body, now)
+
+	assert.Equal(t, "pet.created", header.Get(EventHeader))
+	assert.True(t, Verify(header, "secret", body, 5*time.Minute, now.Add(time.Minute)))
+	assert.False(t, Verify(header, "other", body, 5*time.Minute, now))
+	assert.False(t, Verify(header, "secret", []byte(`{}`), 5*time.Minute, now))
+	assert.False(t, Verify(header, "secret", body, 5*time.Minute, now.Add(10*time.Minute)))
+
+	header.Del(TimestampHeader)
+	assert.False(t, Verify(header, "secret", body, 5*time.Minute, now))
+}
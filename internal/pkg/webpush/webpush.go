@@ -0,0 +1,237 @@
+/*
+Package webpush sends Web Push messages: the payload is encrypted for the
+subscription by RFC 8291 (aes128gcm content encoding) and the request is
+authorized by the application server VAPID key by RFC 8292.
+*/
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// recordSize is the size of the single encrypted record, the payload must fit in it
+	recordSize = 4096
+	// MaxPayloadSize is the payload limit: the record without the padding delimiter and the AEAD tag
+	MaxPayloadSize = recordSize - 1 - 16
+
+	defaultTTL = 24 * time.Hour
+)
+
+var (
+	ErrPayloadTooLarge = errors.New("webpush: payload is too large")
+	// ErrSubscriptionGone is returned when the push service reports the subscription expired or unsubscribed
+	ErrSubscriptionGone = errors.New("webpush: subscription is gone")
+)
+
+var encoding = base64.RawURLEncoding
+
+// Subscription is the PushSubscription of the browser, keys are base64url encoded
+type Subscription struct {
+	Endpoint string
+	P256DH   string
+	Auth     string
+}
+
+// VAPIDKeys is the application server key pair the push services identify the sender by
+type VAPIDKeys struct {
+	private *ecdsa.PrivateKey
+	// PublicKey is base64url uncompressed P-256 point, it is given to browsers as applicationServerKey
+	PublicKey string
+}
+
+// GenerateVAPIDKeys creates a new application server key pair,
+// returning it and the base64url private key to be stored in the configuration
+func GenerateVAPIDKeys() (*VAPIDKeys, string, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	keys := newVAPIDKeys(private)
+	return keys, encoding.EncodeToString(private.D.FillBytes(make([]byte, 32))), nil
+}
+
+// ParseVAPIDKeys restores the key pair from the base64url private key
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	d, err := encoding.DecodeString(privateKey)
+	if err != nil || len(d) != 32 {
+		return nil, errors.New("webpush: invalid VAPID private key")
+	}
+	curve := elliptic.P256()
+	private := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	private.PublicKey.Curve = curve
+	private.PublicKey.X, private.PublicKey.Y = curve.ScalarBaseMult(d)
+	return newVAPIDKeys(private), nil
+}
+
+func newVAPIDKeys(private *ecdsa.PrivateKey) *VAPIDKeys {
+	public := elliptic.Marshal(elliptic.P256(), private.PublicKey.X, private.PublicKey.Y)
+	return &VAPIDKeys{
+		private:   private,
+		PublicKey: encoding.EncodeToString(public),
+	}
+}
+
+// authorization builds the VAPID Authorization header value for the push service of the endpoint
+func (k *VAPIDKeys) authorization(endpoint string, subject string, now time.Time) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	signed, err := token.SignedString(k.private)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, k.PublicKey), nil
+}
+
+// Sender delivers push messages on behalf of the application server
+type Sender struct {
+	Keys *VAPIDKeys
+	// Subject is the contact of the application server, mailto: or https: URL
+	Subject string
+	Client  *http.Client
+	TTL     time.Duration
+}
+
+func (s *Sender) Send(subscription *Subscription, payload []byte) error {
+	body, err := Encrypt(subscription, payload)
+	if err != nil {
+		return err
+	}
+	authorization, err := s.Keys.authorization(subscription.Endpoint, s.Subject, time.Now())
+	if err != nil {
+		return err
+	}
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+
+	request, err := http.NewRequest(http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", authorization)
+	request.Header.Set("Content-Encoding", "aes128gcm")
+	request.Header.Set("Content-Type", "application/octet-stream")
+	request.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	switch {
+	case response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case response.StatusCode >= 300:
+		return fmt.Errorf("webpush: push service responded %s", response.Status)
+	}
+	return nil
+}
+
+// Encrypt produces aes128gcm encoded body of the single record with the payload for the subscription
+func Encrypt(subscription *Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	userAgentPublic, err := encoding.DecodeString(subscription.P256DH)
+	if err != nil {
+		return nil, errors.New("webpush: invalid subscription p256dh key")
+	}
+	authSecret, err := encoding.DecodeString(subscription.Auth)
+	if err != nil || len(authSecret) == 0 {
+		return nil, errors.New("webpush: invalid subscription auth secret")
+	}
+	curve := elliptic.P256()
+	uaX, uaY := elliptic.Unmarshal(curve, userAgentPublic)
+	if uaX == nil {
+		return nil, errors.New("webpush: invalid subscription p256dh key")
+	}
+
+	serverPrivate, serverX, serverY, err := elliptic.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serverPublic := elliptic.Marshal(curve, serverX, serverY)
+	sharedX, _ := curve.ScalarMult(uaX, uaY, serverPrivate)
+	sharedSecret := sharedX.FillBytes(make([]byte, 32))
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	contentKey, nonce, err := deriveKeys(sharedSecret, authSecret, userAgentPublic, serverPublic, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(contentKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// The last record is delimited by 0x02 followed by optional zero padding
+	plaintext := append(append([]byte(nil), payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(serverPublic))
+	header = append(header, salt...)
+	header = append(header, make([]byte, 4)...)
+	binary.BigEndian.PutUint32(header[16:20], recordSize)
+	header = append(header, byte(len(serverPublic)))
+	header = append(header, serverPublic...)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+// deriveKeys derives the content encryption key and nonce from the ECDH shared secret
+func deriveKeys(sharedSecret, authSecret, userAgentPublic, serverPublic, salt []byte) ([]byte, []byte, error) {
+	keyInfo := append([]byte("WebPush: info\x00"), userAgentPublic...)
+	keyInfo = append(keyInfo, serverPublic...)
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, authSecret, keyInfo), ikm); err != nil {
+		return nil, nil, err
+	}
+
+	contentKey := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), contentKey); err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, nil, err
+	}
+	return contentKey, nonce, nil
+}
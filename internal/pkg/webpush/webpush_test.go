@@ -0,0 +1,123 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeUserAgent holds the subscription keys a browser would generate
+type fakeUserAgent struct {
+	private []byte
+	public  []byte
+	auth    []byte
+}
+
+func newFakeUserAgent(t *testing.T) *fakeUserAgent {
+	private, x, y, err := elliptic.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	_, err = rand.Read(auth)
+	require.NoError(t, err)
+	return &fakeUserAgent{private: private, public: elliptic.Marshal(elliptic.P256(), x, y), auth: auth}
+}
+
+func (ua *fakeUserAgent) subscription(endpoint string) *Subscription {
+	return &Subscription{
+		Endpoint: endpoint,
+		P256DH:   encoding.EncodeToString(ua.public),
+		Auth:     encoding.EncodeToString(ua.auth),
+	}
+}
+
+// decrypt reverses Encrypt as the browser does
+func (ua *fakeUserAgent) decrypt(t *testing.T, body []byte) []byte {
+	salt := body[:16]
+	assert.Equal(t, uint32(recordSize), binary.BigEndian.Uint32(body[16:20]))
+	keyIDLength := int(body[20])
+	serverPublic := body[21 : 21+keyIDLength]
+	ciphertext := body[21+keyIDLength:]
+
+	curve := elliptic.P256()
+	serverX, serverY := elliptic.Unmarshal(curve, serverPublic)
+	require.NotNil(t, serverX)
+	sharedX, _ := curve.ScalarMult(serverX, serverY, ua.private)
+	contentKey, nonce, err := deriveKeys(sharedX.FillBytes(make([]byte, 32)), ua.auth, ua.public, serverPublic, salt)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(contentKey)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plaintext[len(plaintext)-1])
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestSender_Send(t *testing.T) {
+	keys, privateKey, err := GenerateVAPIDKeys()
+	require.NoError(t, err)
+	parsedKeys, err := ParseVAPIDKeys(privateKey)
+	require.NoError(t, err)
+	assert.Equal(t, keys.PublicKey, parsedKeys.PublicKey)
+
+	ua := newFakeUserAgent(t)
+	var received []byte
+	pushService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+		assert.NotEmpty(t, r.Header.Get("TTL"))
+
+		authorization := strings.TrimPrefix(r.Header.Get("Authorization"), "vapid ")
+		parts := strings.Split(authorization, ", ")
+		require.Len(t, parts, 2)
+		assert.Equal(t, "k="+keys.PublicKey, parts[1])
+		token, err := jwt.Parse(strings.TrimPrefix(parts[0], "t="), func(token *jwt.Token) (interface{}, error) {
+			return &keys.private.PublicKey, nil
+		})
+		require.NoError(t, err)
+		claims := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, "mailto:admin@storypet.test", claims["sub"])
+		assert.True(t, strings.HasPrefix(claims["aud"].(string), "http://127.0.0.1"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		received = ua.decrypt(t, body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer pushService.Close()
+
+	sender := &Sender{Keys: parsedKeys, Subject: "mailto:admin@storypet.test", Client: pushService.Client()}
+	payload := []byte(`{"title":"Health report"}`)
+	assert.NoError(t, sender.Send(ua.subscription(pushService.URL+"/push/1"), payload))
+	assert.Equal(t, payload, received)
+}
+
+func TestSender_SendGone(t *testing.T) {
+	keys, _, err := GenerateVAPIDKeys()
+	require.NoError(t, err)
+	pushService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer pushService.Close()
+
+	sender := &Sender{Keys: keys, Subject: "mailto:admin@storypet.test", Client: pushService.Client()}
+	err = sender.Send(newFakeUserAgent(t).subscription(pushService.URL), []byte("{}"))
+	assert.ErrorIs(t, err, ErrSubscriptionGone)
+}
+
+func TestEncrypt_PayloadTooLarge(t *testing.T) {
+	ua := newFakeUserAgent(t)
+	_, err := Encrypt(ua.subscription("https://push.test"), make([]byte, MaxPayloadSize+1))
+	assert.ErrorIs(t, err, ErrPayloadTooLarge)
+}
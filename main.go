@@ -3,6 +3,8 @@ package main
 import (
 	"github.com/ArtemVovchenko/storypet-backend/internal/app/server"
 	"log"
+	// Feeding plans are in the time zones of the owners, the hosts do not always have the zone database
+	_ "time/tzdata"
 )
 
 func main() {
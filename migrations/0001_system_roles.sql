@@ -0,0 +1,15 @@
+-- System roles are identified by a stable key instead of their serial id.
+ALTER TABLE public.roles ADD COLUMN IF NOT EXISTS system_key VARCHAR(64);
+ALTER TABLE public.roles DROP CONSTRAINT IF EXISTS roles_system_key_key;
+ALTER TABLE public.roles ADD CONSTRAINT roles_system_key_key UNIQUE (system_key);
+
+-- Existing databases were seeded in this order.
+UPDATE public.roles SET system_key = 'administrator' WHERE role_id = 1 AND system_key IS NULL;
+UPDATE public.roles SET system_key = 'subscribed_user' WHERE role_id = 2 AND system_key IS NULL;
+UPDATE public.roles SET system_key = 'unsubscribed_user' WHERE role_id = 3 AND system_key IS NULL;
+UPDATE public.roles SET system_key = 'veterinarian' WHERE role_id = 4 AND system_key IS NULL;
+
+CREATE TABLE IF NOT EXISTS public.config
+(
+    default_user_role_id INTEGER NOT NULL REFERENCES public.roles (role_id)
+);
@@ -0,0 +1,47 @@
+-- Multi-tenant clinics with their own members and registered patients.
+CREATE TABLE IF NOT EXISTS public.clinics
+(
+    clinic_id           SERIAL PRIMARY KEY,
+    name                VARCHAR(255) NOT NULL,
+    registration_number VARCHAR(255) UNIQUE,
+    address             VARCHAR(255) NOT NULL,
+    city                VARCHAR(100) NOT NULL,
+    phone               VARCHAR(30),
+    email               VARCHAR(255),
+    website             VARCHAR(255),
+    created_at          TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.clinic_members
+(
+    clinic_id   INTEGER     NOT NULL REFERENCES public.clinics (clinic_id) ON DELETE CASCADE,
+    user_id     INTEGER     NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    member_role VARCHAR(32) NOT NULL CHECK (member_role IN ('administrator', 'veterinarian', 'receptionist')),
+    joined_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    PRIMARY KEY (clinic_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS public.clinic_opening_hours
+(
+    clinic_id INTEGER  NOT NULL REFERENCES public.clinics (clinic_id) ON DELETE CASCADE,
+    weekday   SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens_at  TIME     NOT NULL,
+    closes_at TIME     NOT NULL CHECK (closes_at > opens_at),
+    PRIMARY KEY (clinic_id, weekday, opens_at)
+);
+
+ALTER TABLE public.pets
+    ADD COLUMN IF NOT EXISTS clinic_id INTEGER REFERENCES public.clinics (clinic_id) ON DELETE SET NULL;
+
+-- Every veterinarian clinic record becomes a clinic administered by its veterinarian.
+-- The records have no address, so the placeholders are kept until the administrator specifies it.
+INSERT INTO public.clinics (name, registration_number, address, city)
+SELECT vc.clinic_name, vc.clinic_id, 'Not specified', 'Not specified'
+FROM public.veterinarians_clinic vc
+ON CONFLICT (registration_number) DO NOTHING;
+
+INSERT INTO public.clinic_members (clinic_id, user_id, member_role)
+SELECT c.clinic_id, vc.user_id, 'administrator'
+FROM public.veterinarians_clinic vc
+         INNER JOIN public.clinics c ON c.registration_number = vc.clinic_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,33 @@
+-- Appointments of pets with veterinarians and veterinarians weekly availability.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+CREATE TABLE IF NOT EXISTS public.appointments
+(
+    appointment_id  SERIAL PRIMARY KEY,
+    pet_id          INTEGER      NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    owner_id        INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    veterinarian_id INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    clinic_id       INTEGER REFERENCES public.clinics (clinic_id) ON DELETE SET NULL,
+    starts_at       TIMESTAMPTZ  NOT NULL,
+    ends_at         TIMESTAMPTZ  NOT NULL CHECK (ends_at > starts_at),
+    reason          VARCHAR(500) NOT NULL,
+    status          VARCHAR(16)  NOT NULL DEFAULT 'requested'
+        CHECK (status IN ('requested', 'confirmed', 'cancelled', 'completed', 'no_show')),
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT now(),
+    -- A veterinarian can not have two active appointments at the same time.
+    CONSTRAINT appointments_no_double_booking EXCLUDE USING gist (
+        veterinarian_id WITH =,
+        tstzrange(starts_at, ends_at) WITH &&
+        ) WHERE (status IN ('requested', 'confirmed'))
+);
+
+CREATE INDEX IF NOT EXISTS appointments_pet_id_idx ON public.appointments (pet_id);
+
+CREATE TABLE IF NOT EXISTS public.veterinarian_availability
+(
+    veterinarian_id INTEGER  NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    weekday         SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    starts_at       TIME     NOT NULL,
+    ends_at         TIME     NOT NULL CHECK (ends_at > starts_at),
+    PRIMARY KEY (veterinarian_id, weekday, starts_at)
+);
@@ -0,0 +1,7 @@
+-- Keys of users' iCalendar feed URLs. Deleting or replacing the key revokes the URL.
+CREATE TABLE IF NOT EXISTS public.calendar_feeds
+(
+    user_id    INTEGER PRIMARY KEY REFERENCES public.users (user_id) ON DELETE CASCADE,
+    feed_key   VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP   NOT NULL DEFAULT now()
+);
@@ -0,0 +1,19 @@
+-- Catalogue of vaccines with their schedules, referenced by pets vaccination records.
+CREATE TABLE IF NOT EXISTS public.vaccine_catalogue
+(
+    catalogue_id            SERIAL PRIMARY KEY,
+    name                    VARCHAR(100) NOT NULL,
+    pet_type                INTEGER      NOT NULL REFERENCES public.pet_types (type_id) ON DELETE CASCADE,
+    manufacturer            VARCHAR(100),
+    doses_count             SMALLINT     NOT NULL DEFAULT 1 CHECK (doses_count > 0),
+    dose_interval_days      SMALLINT     NOT NULL DEFAULT 0 CHECK (dose_interval_days >= 0),
+    booster_interval_months SMALLINT     NOT NULL DEFAULT 0 CHECK (booster_interval_months >= 0),
+    UNIQUE (name, pet_type, manufacturer)
+);
+
+ALTER TABLE public.vaccines
+    ADD COLUMN IF NOT EXISTS catalogue_id INTEGER REFERENCES public.vaccine_catalogue (catalogue_id) ON DELETE SET NULL;
+ALTER TABLE public.vaccines
+    ALTER COLUMN name TYPE VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS vaccines_pet_id_idx ON public.vaccines (pet_id);
@@ -0,0 +1,26 @@
+-- Persistent queue of background jobs. Workers claim pending jobs with FOR UPDATE SKIP LOCKED,
+-- so the same job is never run by two server instances at once.
+CREATE TABLE IF NOT EXISTS public.jobs
+(
+    job_id       SERIAL PRIMARY KEY,
+    kind         VARCHAR(64) NOT NULL,
+    payload      JSONB       NOT NULL DEFAULT '{}',
+    status       VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    max_attempts INTEGER     NOT NULL DEFAULT 5 CHECK (max_attempts > 0),
+    run_at       TIMESTAMP   NOT NULL DEFAULT now(),
+    locked_at    TIMESTAMP,
+    last_error   TEXT,
+    created_at   TIMESTAMP   NOT NULL DEFAULT now(),
+    finished_at  TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS jobs_pending_run_at_idx ON public.jobs (run_at) WHERE status = 'pending';
+
+-- Last enqueue time of every cron schedule, only the elected leader updates it
+CREATE TABLE IF NOT EXISTS public.job_schedules
+(
+    name        VARCHAR(64) PRIMARY KEY,
+    last_run_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,38 @@
+-- In-app inbox. Every notification is stored here, other channels deliver its copy.
+CREATE TABLE IF NOT EXISTS public.notifications
+(
+    notification_id SERIAL PRIMARY KEY,
+    user_id         INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    event           VARCHAR(64)  NOT NULL,
+    title           VARCHAR(255) NOT NULL,
+    body            TEXT         NOT NULL,
+    data            JSONB        NOT NULL DEFAULT '{}',
+    read_at         TIMESTAMP,
+    created_at      TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_created_idx ON public.notifications (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS notifications_user_unread_idx ON public.notifications (user_id) WHERE read_at IS NULL;
+
+-- Per-user overrides of the event delivery by the channel, missing rows mean enabled
+CREATE TABLE IF NOT EXISTS public.notification_preferences
+(
+    user_id INTEGER     NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    event   VARCHAR(64) NOT NULL,
+    channel VARCHAR(16) NOT NULL,
+    enabled BOOLEAN     NOT NULL,
+    PRIMARY KEY (user_id, event, channel)
+);
+
+-- Web push subscriptions and webhook URLs of the users
+CREATE TABLE IF NOT EXISTS public.notification_endpoints
+(
+    endpoint_id SERIAL PRIMARY KEY,
+    user_id     INTEGER       NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    channel     VARCHAR(16)   NOT NULL CHECK (channel IN ('web_push', 'webhook')),
+    address     VARCHAR(2048) NOT NULL,
+    public_key  VARCHAR(255),
+    secret      VARCHAR(255)  NOT NULL,
+    created_at  TIMESTAMP     NOT NULL DEFAULT now(),
+    UNIQUE (user_id, channel, address)
+);
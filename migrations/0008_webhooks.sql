@@ -0,0 +1,31 @@
+-- Partner integrations subscribed to the events of the user pets
+CREATE TABLE IF NOT EXISTS public.webhook_subscriptions
+(
+    subscription_id SERIAL PRIMARY KEY,
+    user_id         INTEGER       NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    url             VARCHAR(2048) NOT NULL,
+    secret          VARCHAR(255)  NOT NULL,
+    events          TEXT[]        NOT NULL,
+    is_active       BOOLEAN       NOT NULL DEFAULT TRUE,
+    created_at      TIMESTAMP     NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_user_idx ON public.webhook_subscriptions (user_id);
+
+-- Delivery log, retries are driven by the jobs queue
+CREATE TABLE IF NOT EXISTS public.webhook_deliveries
+(
+    delivery_id     SERIAL PRIMARY KEY,
+    subscription_id INTEGER     NOT NULL REFERENCES public.webhook_subscriptions (subscription_id) ON DELETE CASCADE,
+    event           VARCHAR(64) NOT NULL,
+    payload         JSONB       NOT NULL,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    last_error      TEXT,
+    redelivery_of   INTEGER REFERENCES public.webhook_deliveries (delivery_id) ON DELETE SET NULL,
+    created_at      TIMESTAMP   NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON public.webhook_deliveries (subscription_id, created_at DESC);
@@ -0,0 +1,25 @@
+-- Devices are registered by pet owners through the API, only hashes of the access secrets are stored
+ALTER TABLE public.iot_devices
+    ALTER COLUMN access_secret TYPE VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS user_id          INTEGER REFERENCES public.users (user_id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS name             VARCHAR(64) NOT NULL DEFAULT 'Collar',
+    ADD COLUMN IF NOT EXISTS status           VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'unpaired', 'revoked')),
+    ADD COLUMN IF NOT EXISTS firmware_version VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS battery_level    SMALLINT CHECK (battery_level BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS last_seen_at     TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS created_at       TIMESTAMP   NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS revoked_at       TIMESTAMP;
+
+UPDATE public.iot_devices d
+SET user_id       = p.user_id,
+    access_secret = encode(sha256(convert_to(d.access_secret, 'UTF8')), 'hex')
+FROM public.pets p
+WHERE p.pet_id = d.pet_id
+  AND d.user_id IS NULL;
+
+ALTER TABLE public.iot_devices
+    ALTER COLUMN user_id SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS iot_devices_access_secret_idx ON public.iot_devices (access_secret);
+CREATE INDEX IF NOT EXISTS iot_devices_pet_idx ON public.iot_devices (pet_id);
@@ -0,0 +1,7 @@
+-- Samples uploaded by collars in batches keep the device and the sample id,
+-- so the batch sent again after a lost response does not duplicate the activity
+ALTER TABLE public.activity
+    ADD COLUMN IF NOT EXISTS device_id INTEGER REFERENCES public.iot_devices (device_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS sample_id VARCHAR(64);
+
+CREATE UNIQUE INDEX IF NOT EXISTS activity_device_sample_idx ON public.activity (device_id, sample_id);
@@ -0,0 +1,37 @@
+-- GPS fixes of the collars, the fix is identified by the pet and its time, so the upload sent again is skipped
+CREATE TABLE IF NOT EXISTS public.walks
+(
+    walk_id     SERIAL PRIMARY KEY,
+    pet_id      INTEGER          NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    started_at  TIMESTAMP        NOT NULL,
+    ended_at    TIMESTAMP        NOT NULL,
+    distance    DOUBLE PRECISION NOT NULL,
+    duration    INTEGER          NOT NULL,
+    mean_speed  DOUBLE PRECISION NOT NULL,
+    point_count INTEGER          NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS walks_pet_started_idx ON public.walks (pet_id, started_at);
+
+CREATE TABLE IF NOT EXISTS public.track_points
+(
+    point_id     BIGSERIAL PRIMARY KEY,
+    pet_id       INTEGER          NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    device_id    INTEGER REFERENCES public.iot_devices (device_id) ON DELETE SET NULL,
+    walk_id      INTEGER REFERENCES public.walks (walk_id) ON DELETE SET NULL,
+    recorded_at  TIMESTAMP        NOT NULL,
+    latitude     DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude    DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    accuracy     REAL,
+    is_segmented BOOLEAN          NOT NULL DEFAULT FALSE,
+    UNIQUE (pet_id, recorded_at)
+);
+
+CREATE INDEX IF NOT EXISTS track_points_walk_idx ON public.track_points (walk_id);
+CREATE INDEX IF NOT EXISTS track_points_pending_idx ON public.track_points (pet_id, recorded_at) WHERE NOT is_segmented;
+
+-- Every walk not covered by the activity samples adds one activity record, so the walks are counted by the activity statistics
+ALTER TABLE public.activity
+    ADD COLUMN IF NOT EXISTS walk_id INTEGER REFERENCES public.walks (walk_id) ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS activity_walk_idx ON public.activity (walk_id);
@@ -0,0 +1,38 @@
+-- Zones of the pets, positions are longitude and latitude pairs, the polygon vertices are stored flattened
+CREATE TABLE IF NOT EXISTS public.geofences
+(
+    geofence_id      SERIAL PRIMARY KEY,
+    pet_id           INTEGER     NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    name             VARCHAR(64) NOT NULL,
+    shape            VARCHAR(16) NOT NULL CHECK (shape IN ('circle', 'polygon')),
+    center_longitude DOUBLE PRECISION,
+    center_latitude  DOUBLE PRECISION,
+    radius           DOUBLE PRECISION,
+    vertices         DOUBLE PRECISION[],
+    alert_on_enter   BOOLEAN     NOT NULL DEFAULT FALSE,
+    alert_on_exit    BOOLEAN     NOT NULL DEFAULT TRUE,
+    is_active        BOOLEAN     NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMP   NOT NULL DEFAULT now(),
+    -- Debouncing state, where the pet is and how many fixes on the other side are seen in a row
+    inside           BOOLEAN,
+    pending_fixes    INTEGER     NOT NULL DEFAULT 0,
+    last_fix_at      TIMESTAMP,
+    CHECK (shape <> 'circle' OR (center_longitude IS NOT NULL AND center_latitude IS NOT NULL AND radius > 0)),
+    CHECK (shape <> 'polygon' OR array_length(vertices, 1) >= 6)
+);
+
+CREATE INDEX IF NOT EXISTS geofences_pet_idx ON public.geofences (pet_id);
+
+CREATE TABLE IF NOT EXISTS public.geofence_events
+(
+    event_id    SERIAL PRIMARY KEY,
+    geofence_id INTEGER          NOT NULL REFERENCES public.geofences (geofence_id) ON DELETE CASCADE,
+    pet_id      INTEGER          NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    kind        VARCHAR(8)       NOT NULL CHECK (kind IN ('enter', 'exit')),
+    occurred_at TIMESTAMP        NOT NULL,
+    latitude    DOUBLE PRECISION NOT NULL,
+    longitude   DOUBLE PRECISION NOT NULL,
+    created_at  TIMESTAMP        NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS geofence_events_pet_idx ON public.geofence_events (pet_id, occurred_at DESC);
@@ -0,0 +1,17 @@
+-- Signals of the health analysis job, the pet has at most one open alert of the kind
+CREATE TABLE IF NOT EXISTS public.health_alerts
+(
+    alert_id    SERIAL PRIMARY KEY,
+    pet_id      INTEGER          NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    kind        VARCHAR(32)      NOT NULL,
+    severity    VARCHAR(16)      NOT NULL CHECK (severity IN ('warning', 'critical')),
+    message     TEXT             NOT NULL,
+    value       DOUBLE PRECISION NOT NULL,
+    baseline    DOUBLE PRECISION NOT NULL,
+    detected_at TIMESTAMP        NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP        NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS health_alerts_open_idx ON public.health_alerts (pet_id, kind) WHERE resolved_at IS NULL;
+CREATE INDEX IF NOT EXISTS health_alerts_pet_idx ON public.health_alerts (pet_id, detected_at DESC);
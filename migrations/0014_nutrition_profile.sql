@@ -0,0 +1,14 @@
+-- Nutrition profile of the pets, the energy requirement multiplies RER by the factors of the profile
+ALTER TABLE public.pet_types
+    ADD COLUMN IF NOT EXISTS species VARCHAR(16) NOT NULL DEFAULT 'other' CHECK (species IN ('dog', 'cat', 'other'));
+
+UPDATE public.pet_types SET species = 'dog' WHERE species = 'other' AND (type_name ILIKE '%dog%' OR type_name ILIKE '%puppy%');
+UPDATE public.pet_types SET species = 'cat' WHERE species = 'other' AND (type_name ILIKE '%cat%' OR type_name ILIKE '%kitten%');
+
+ALTER TABLE public.pets
+    ADD COLUMN IF NOT EXISTS birth_date          DATE,
+    ADD COLUMN IF NOT EXISTS sex                 VARCHAR(8) CHECK (sex IN ('male', 'female')),
+    ADD COLUMN IF NOT EXISTS is_neutered         BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS activity_level      VARCHAR(16) CHECK (activity_level IN ('low', 'moderate', 'high')),
+    ADD COLUMN IF NOT EXISTS goal                VARCHAR(16) CHECK (goal IN ('maintain', 'lose_weight', 'gain_weight')),
+    ADD COLUMN IF NOT EXISTS reproductive_status VARCHAR(16) CHECK (reproductive_status IN ('none', 'pregnant', 'lactating'));
@@ -0,0 +1,36 @@
+-- Composition of the foods in grams per 100 g as fed, the energy in kcal per 100 g
+ALTER TABLE public.food
+    ADD COLUMN IF NOT EXISTS energy_per_100g DOUBLE PRECISION CHECK (energy_per_100g >= 0),
+    ADD COLUMN IF NOT EXISTS protein         DOUBLE PRECISION CHECK (protein BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS fat             DOUBLE PRECISION CHECK (fat BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS carbohydrate    DOUBLE PRECISION CHECK (carbohydrate BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS fibre           DOUBLE PRECISION CHECK (fibre BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS moisture        DOUBLE PRECISION CHECK (moisture BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS calcium         DOUBLE PRECISION CHECK (calcium BETWEEN 0 AND 100),
+    ADD COLUMN IF NOT EXISTS phosphorus      DOUBLE PRECISION CHECK (phosphorus BETWEEN 0 AND 100);
+
+-- Calories were multiplied by the portion weight in grams, so they were kcal per gram. The column is kept
+-- and written along with the energy for the deprecated food_calories until the clients move to energy_per_100g
+UPDATE public.food SET energy_per_100g = COALESCE(calories, 0) * 100 WHERE energy_per_100g IS NULL;
+
+ALTER TABLE public.food
+    ALTER COLUMN energy_per_100g SET NOT NULL;
+
+-- Household units of the foods, mass units need no conversion
+CREATE TABLE IF NOT EXISTS public.food_portion_units
+(
+    food_id INTEGER          NOT NULL REFERENCES public.food (food_id) ON DELETE CASCADE,
+    unit    VARCHAR(16)      NOT NULL CHECK (unit IN ('cup', 'can', 'pouch', 'piece')),
+    grams   DOUBLE PRECISION NOT NULL CHECK (grams > 0),
+    PRIMARY KEY (food_id, unit)
+);
+
+-- The portion as it was given, the portion weight keeps it in grams converted when it was eaten
+ALTER TABLE public.eatings
+    ADD COLUMN IF NOT EXISTS portion_amount DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS portion_unit   VARCHAR(16) NOT NULL DEFAULT 'g';
+
+UPDATE public.eatings SET portion_amount = portion_weight WHERE portion_amount IS NULL;
+
+ALTER TABLE public.eatings
+    ALTER COLUMN portion_amount SET NOT NULL;
@@ -0,0 +1,11 @@
+-- GTIN barcodes of the food packages, normalized to 14 digits, a barcode belongs to a single food
+CREATE TABLE IF NOT EXISTS public.food_barcodes
+(
+    code    VARCHAR(14) PRIMARY KEY CHECK (code ~ '^[0-9]{14}$'),
+    food_id INTEGER     NOT NULL REFERENCES public.food (food_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS food_barcodes_food_idx ON public.food_barcodes (food_id);
+
+-- Catalogue imports match the foods by name and manufacturer
+CREATE INDEX IF NOT EXISTS food_name_manufacturer_idx ON public.food (LOWER(food_name), LOWER(COALESCE(manufacturer, '')));
@@ -0,0 +1,13 @@
+-- Food search, trigrams match the names with typos and the text search vector ranks name, manufacturer and description.
+-- The simple configuration does not stem, the names of the foods are not words of a single language.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS food_name_trgm_idx ON public.food USING GIN (food_name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS food_manufacturer_trgm_idx ON public.food USING GIN (manufacturer gin_trgm_ops);
+
+-- The expression must be the same as the one of the search query to use the index
+CREATE INDEX IF NOT EXISTS food_search_idx ON public.food USING GIN ((
+    setweight(to_tsvector('simple', coalesce(food_name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(manufacturer, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+));
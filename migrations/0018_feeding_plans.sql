@@ -0,0 +1,31 @@
+-- Recurring feeding plans of the pets, the times of day of the meals are in the time zone of the plan
+CREATE TABLE IF NOT EXISTS public.feeding_plans
+(
+    plan_id           SERIAL PRIMARY KEY,
+    pet_id            INTEGER     NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    name              VARCHAR(64) NOT NULL,
+    time_zone         VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    starts_on         DATE        NOT NULL,
+    ends_on           DATE CHECK (ends_on >= starts_on),
+    tolerance_minutes INTEGER     NOT NULL DEFAULT 60 CHECK (tolerance_minutes BETWEEN 5 AND 360),
+    creator_id        INTEGER     NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    created_at        TIMESTAMP   NOT NULL DEFAULT now(),
+    updated_at        TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS feeding_plans_pet_idx ON public.feeding_plans (pet_id, starts_on);
+
+-- Weekdays follow the Go numbering, Sunday is 0, the meal is served every day when they are empty
+CREATE TABLE IF NOT EXISTS public.feeding_plan_meals
+(
+    meal_id        SERIAL PRIMARY KEY,
+    plan_id        INTEGER          NOT NULL REFERENCES public.feeding_plans (plan_id) ON DELETE CASCADE,
+    time_of_day    TIME             NOT NULL,
+    weekdays       SMALLINT[]       NOT NULL DEFAULT '{}',
+    food_id        INTEGER          NOT NULL REFERENCES public.food (food_id) ON DELETE CASCADE,
+    portion_amount DOUBLE PRECISION NOT NULL CHECK (portion_amount > 0),
+    portion_unit   VARCHAR(16)      NOT NULL,
+    portion_weight DOUBLE PRECISION NOT NULL CHECK (portion_weight > 0)
+);
+
+CREATE INDEX IF NOT EXISTS feeding_plan_meals_plan_idx ON public.feeding_plan_meals (plan_id, time_of_day);
@@ -0,0 +1,45 @@
+-- Health reports get their own key, so the medications can be prescribed by them
+ALTER TABLE public.pet_health_reports
+    ADD COLUMN IF NOT EXISTS report_id SERIAL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS pet_health_reports_id_idx ON public.pet_health_reports (report_id);
+
+-- Medications prescribed by the veterinarians, the doses are scheduled every interval_hours from starts_at,
+-- the ones given as needed have no interval
+CREATE TABLE IF NOT EXISTS public.medications
+(
+    medication_id   SERIAL PRIMARY KEY,
+    pet_id          INTEGER          NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    veterinarian_id INTEGER          NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    report_id       INTEGER REFERENCES public.pet_health_reports (report_id) ON DELETE SET NULL,
+    drug            VARCHAR(128)     NOT NULL,
+    dose            DOUBLE PRECISION NOT NULL CHECK (dose > 0),
+    dose_unit       VARCHAR(16)      NOT NULL,
+    route           VARCHAR(16)      NOT NULL CHECK (route IN ('oral', 'topical', 'subcutaneous', 'intramuscular',
+                                                               'intravenous', 'ophthalmic', 'otic', 'inhaled',
+                                                               'rectal', 'transdermal')),
+    interval_hours  INTEGER          NOT NULL DEFAULT 0 CHECK (interval_hours BETWEEN 0 AND 672),
+    starts_at       TIMESTAMP        NOT NULL,
+    ends_at         TIMESTAMP CHECK (ends_at > starts_at),
+    instructions    TEXT,
+    reminded_until  TIMESTAMP,
+    checked_until   TIMESTAMP,
+    created_at      TIMESTAMP        NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMP        NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS medications_pet_idx ON public.medications (pet_id, starts_at DESC);
+CREATE INDEX IF NOT EXISTS medications_scheduled_idx ON public.medications (ends_at) WHERE interval_hours > 0;
+
+CREATE TABLE IF NOT EXISTS public.medication_doses
+(
+    dose_id         SERIAL PRIMARY KEY,
+    medication_id   INTEGER          NOT NULL REFERENCES public.medications (medication_id) ON DELETE CASCADE,
+    administered_at TIMESTAMP        NOT NULL,
+    administered_by INTEGER          NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    amount          DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    comment         VARCHAR(500),
+    created_at      TIMESTAMP        NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS medication_doses_medication_idx ON public.medication_doses (medication_id, administered_at);
@@ -0,0 +1,90 @@
+-- Diagnoses of the pets, all but resolved ones are on the problem list
+CREATE TABLE IF NOT EXISTS public.conditions
+(
+    condition_id    SERIAL PRIMARY KEY,
+    pet_id          INTEGER      NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    veterinarian_id INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    report_id       INTEGER REFERENCES public.pet_health_reports (report_id) ON DELETE SET NULL,
+    name            VARCHAR(128) NOT NULL,
+    code            VARCHAR(32),
+    status          VARCHAR(16)  NOT NULL CHECK (status IN ('active', 'chronic', 'in_remission', 'resolved')),
+    diagnosed_on    DATE         NOT NULL,
+    resolved_on     DATE CHECK (resolved_on >= diagnosed_on),
+    notes           TEXT,
+    created_at      TIMESTAMP    NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS conditions_pet_idx ON public.conditions (pet_id, diagnosed_on DESC);
+CREATE INDEX IF NOT EXISTS conditions_name_idx ON public.conditions (lower(name));
+CREATE INDEX IF NOT EXISTS conditions_code_idx ON public.conditions (code) WHERE code IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.condition_status_changes
+(
+    change_id    SERIAL PRIMARY KEY,
+    condition_id INTEGER     NOT NULL REFERENCES public.conditions (condition_id) ON DELETE CASCADE,
+    status       VARCHAR(16) NOT NULL,
+    changed_by   INTEGER     NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    changed_at   TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS condition_status_changes_condition_idx ON public.condition_status_changes (condition_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS public.allergies
+(
+    allergy_id  SERIAL PRIMARY KEY,
+    pet_id      INTEGER      NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    recorded_by INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    allergen    VARCHAR(128) NOT NULL,
+    category    VARCHAR(16)  NOT NULL CHECK (category IN ('food', 'drug', 'environmental', 'insect', 'other')),
+    severity    VARCHAR(16)  NOT NULL CHECK (severity IN ('mild', 'moderate', 'severe')),
+    reaction    VARCHAR(500),
+    is_active   BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMP    NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS allergies_pet_idx ON public.allergies (pet_id);
+
+CREATE TABLE IF NOT EXISTS public.procedures
+(
+    procedure_id    SERIAL PRIMARY KEY,
+    pet_id          INTEGER      NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    veterinarian_id INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    report_id       INTEGER REFERENCES public.pet_health_reports (report_id) ON DELETE SET NULL,
+    condition_id    INTEGER REFERENCES public.conditions (condition_id) ON DELETE SET NULL,
+    kind            VARCHAR(16)  NOT NULL CHECK (kind IN ('surgery', 'dental', 'imaging', 'diagnostic', 'therapy', 'other')),
+    name            VARCHAR(128) NOT NULL,
+    performed_on    DATE         NOT NULL,
+    outcome         TEXT,
+    created_at      TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS procedures_pet_idx ON public.procedures (pet_id, performed_on DESC);
+
+CREATE TABLE IF NOT EXISTS public.lab_panels
+(
+    panel_id        SERIAL PRIMARY KEY,
+    pet_id          INTEGER     NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    veterinarian_id INTEGER     NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    report_id       INTEGER REFERENCES public.pet_health_reports (report_id) ON DELETE SET NULL,
+    name            VARCHAR(64) NOT NULL,
+    laboratory      VARCHAR(128),
+    collected_at    TIMESTAMP   NOT NULL,
+    created_at      TIMESTAMP   NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS lab_panels_pet_idx ON public.lab_panels (pet_id, collected_at DESC);
+
+CREATE TABLE IF NOT EXISTS public.lab_results
+(
+    result_id      SERIAL PRIMARY KEY,
+    panel_id       INTEGER          NOT NULL REFERENCES public.lab_panels (panel_id) ON DELETE CASCADE,
+    analyte        VARCHAR(64)      NOT NULL,
+    value          DOUBLE PRECISION NOT NULL,
+    unit           VARCHAR(16)      NOT NULL DEFAULT '',
+    reference_low  DOUBLE PRECISION,
+    reference_high DOUBLE PRECISION CHECK (reference_high >= reference_low)
+);
+
+CREATE INDEX IF NOT EXISTS lab_results_panel_idx ON public.lab_results (panel_id);
@@ -0,0 +1,17 @@
+-- Files uploaded to the pets and their health reports, the contents are kept in the blob store by the keys
+CREATE TABLE IF NOT EXISTS public.attachments
+(
+    attachment_id SERIAL PRIMARY KEY,
+    pet_id        INTEGER      NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    report_id     INTEGER REFERENCES public.pet_health_reports (report_id) ON DELETE SET NULL,
+    uploaded_by   INTEGER      NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    file_name     VARCHAR(255) NOT NULL,
+    content_type  VARCHAR(64)  NOT NULL,
+    size          BIGINT       NOT NULL CHECK (size >= 0),
+    storage_key   VARCHAR(255) NOT NULL UNIQUE,
+    thumbnail_key VARCHAR(255),
+    created_at    TIMESTAMP    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS attachments_pet_idx ON public.attachments (pet_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS attachments_report_idx ON public.attachments (report_id) WHERE report_id IS NOT NULL;
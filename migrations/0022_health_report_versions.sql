@@ -0,0 +1,29 @@
+-- Health reports are edited as drafts, locked by signing and changed by tracked amendments afterwards
+ALTER TABLE public.pet_health_reports
+    ADD COLUMN IF NOT EXISTS version    INTEGER     NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS status     VARCHAR(16) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'signed', 'amended')),
+    ADD COLUMN IF NOT EXISTS signed_by  INTEGER REFERENCES public.users (user_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS signed_at  TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP   NOT NULL DEFAULT now();
+
+-- Every version of the report is kept, the latest one is also the report itself
+CREATE TABLE IF NOT EXISTS public.pet_health_report_versions
+(
+    version_id        SERIAL PRIMARY KEY,
+    report_id         INTEGER   NOT NULL REFERENCES public.pet_health_reports (report_id) ON DELETE CASCADE,
+    version           INTEGER   NOT NULL,
+    report_conclusion TEXT      NOT NULL,
+    report_comments   TEXT,
+    edited_by         INTEGER   NOT NULL REFERENCES public.users (user_id) ON DELETE CASCADE,
+    edited_at         TIMESTAMP NOT NULL DEFAULT now(),
+    is_amendment      BOOLEAN   NOT NULL DEFAULT FALSE,
+    amendment_reason  TEXT,
+    UNIQUE (report_id, version)
+);
+
+INSERT INTO public.pet_health_report_versions
+    (report_id, version, report_conclusion, report_comments, edited_by, edited_at)
+SELECT report_id, 1, report_conclusion, report_comments, veterinarian_id, report_timestamp
+FROM public.pet_health_reports
+ON CONFLICT (report_id, version) DO NOTHING;
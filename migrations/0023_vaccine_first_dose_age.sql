@@ -0,0 +1,4 @@
+-- Age of the pet the first dose of the catalogue vaccine is given at, the never received
+-- vaccines are scheduled by the birth date of the pet with it.
+ALTER TABLE public.vaccine_catalogue
+    ADD COLUMN IF NOT EXISTS first_dose_age_weeks SMALLINT NOT NULL DEFAULT 0 CHECK (first_dose_age_weeks >= 0);
@@ -0,0 +1,13 @@
+-- Reminders the owners have been sent about the vaccinations of their pets, every reminder of the shot
+-- due at the date is sent once. The days before are negative for the reminders about overdue shots.
+CREATE TABLE IF NOT EXISTS public.vaccine_reminders
+(
+    pet_id       INTEGER   NOT NULL REFERENCES public.pets (pet_id) ON DELETE CASCADE,
+    catalogue_id INTEGER   NOT NULL REFERENCES public.vaccine_catalogue (catalogue_id) ON DELETE CASCADE,
+    dose_number  SMALLINT  NOT NULL,
+    due_date     DATE      NOT NULL,
+    days_before  SMALLINT  NOT NULL,
+    sent_at      TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (pet_id, catalogue_id, dose_number, due_date, days_before)
+);
+